@@ -0,0 +1,117 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"movieapp.com/analytics/pkg/model"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+)
+
+// ErrTooManyEvents is returned when tracking more than MaxBatchSize
+// events at once.
+var ErrTooManyEvents = problem.Register(errors.New("too many events"), problem.BadRequest)
+
+// MaxBatchSize defines the maximum number of events tracked at once.
+const MaxBatchSize = 100
+
+const (
+	maxIDLength       = 255
+	maxPropertyLength = 255
+	// Events are dated at most maxClockSkew after their receipt,
+	// and at most maxDelay before, e.g. sent by a client coming
+	// back online.
+	maxClockSkew = 5 * time.Minute
+	maxDelay     = 24 * time.Hour
+)
+
+type eventPublisher interface {
+	Publish(ctx context.Context, events []*model.Event) error
+}
+
+// Controller defines an analytics service controller.
+type Controller struct {
+	publisher eventPublisher
+}
+
+// New creates an analytics service controller publishing the
+// tracked events.
+func New(publisher eventPublisher) *Controller {
+	return &Controller{publisher}
+}
+
+// Track validates and publishes events of a user, anonymous if the
+// user id is empty. Events are tracked only if all are valid.
+func (c *Controller) Track(ctx context.Context, userID string, events []*model.Event) error {
+	if len(events) > MaxBatchSize {
+		return ErrTooManyEvents
+	}
+	now := time.Now().UTC()
+	var fields []request.FieldError
+	for i, e := range events {
+		if e.OccurredAt.IsZero() {
+			e.OccurredAt = now
+		}
+		fields = append(fields, validate(fmt.Sprintf("events[%d].", i), e, now)...)
+		e.UserID = userID
+		e.ReceivedAt = now
+	}
+	if len(fields) > 0 {
+		return &request.Error{Fields: fields}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return c.publisher.Publish(ctx, events)
+}
+
+// validate checks an event against the schema of its type.
+func validate(prefix string, e *model.Event, now time.Time) []request.FieldError {
+	var fields []request.FieldError
+	schema, ok := model.Schemas[e.Type]
+	if !ok {
+		fields = append(fields, request.FieldError{Field: prefix + "type", Message: "must be MovieViewed, MovieClicked or PlayStarted"})
+	}
+	if e.MovieID == "" || len(e.MovieID) > maxIDLength {
+		fields = append(fields, request.FieldError{Field: prefix + "movieId", Message: "must be 1 to 255 bytes long"})
+	}
+	if e.SessionID == "" || len(e.SessionID) > maxIDLength {
+		fields = append(fields, request.FieldError{Field: prefix + "sessionId", Message: "must be 1 to 255 bytes long"})
+	}
+	if e.OccurredAt.After(now.Add(maxClockSkew)) || e.OccurredAt.Before(now.Add(-maxDelay)) {
+		fields = append(fields, request.FieldError{Field: prefix + "occurredAt", Message: "must be within the last 24 hours"})
+	}
+	if !ok {
+		return fields
+	}
+	names := make([]string, 0, len(e.Properties))
+	for name := range e.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := e.Properties[name]
+		p, known := schema[name]
+		switch {
+		case !known:
+			fields = append(fields, request.FieldError{Field: prefix + "properties." + name, Message: "not defined for " + string(e.Type)})
+		case len(v) > maxPropertyLength:
+			fields = append(fields, request.FieldError{Field: prefix + "properties." + name, Message: "must be at most 255 bytes long"})
+		case p.Kind == model.PropertyCount:
+			if n, err := strconv.Atoi(v); err != nil || n < 0 {
+				fields = append(fields, request.FieldError{Field: prefix + "properties." + name, Message: "must be a non-negative integer"})
+			}
+		}
+	}
+	for name, p := range schema {
+		if _, set := e.Properties[name]; p.Required && !set {
+			fields = append(fields, request.FieldError{Field: prefix + "properties." + name, Message: "required for " + string(e.Type)})
+		}
+	}
+	return fields
+}
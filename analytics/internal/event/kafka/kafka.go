@@ -0,0 +1,77 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"movieapp.com/analytics/pkg/model"
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("kafka")
+
+var stats = expvar.NewMap("analytics_events")
+
+// Publisher defines a Kafka analytics event publisher.
+type Publisher struct {
+	writer *kafka.Writer
+}
+
+// NewPublisher creates a Kafka publisher writing events to the
+// topic in the background, in batches of up to batchSize events
+// sent at least every batchTimeout. Events are keyed by session id,
+// so the events of a session land on the same partition and are
+// consumed in order. Connections authenticate with the credentials
+// unless nil.
+//
+// Analytics tolerate losses, so events are acknowledged by the
+// leader only and those failing to be written are dropped and
+// counted.
+func NewPublisher(brokers []string, topic string, batchSize int, batchTimeout time.Duration, creds *kafkautil.Credentials) *Publisher {
+	return &Publisher{&kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		BatchSize:    batchSize,
+		BatchTimeout: batchTimeout,
+		Async:        true,
+		Compression:  kafka.Snappy,
+		Transport:    creds.Transport(),
+		Completion: func(msgs []kafka.Message, err error) {
+			if err != nil {
+				stats.Add("dropped", int64(len(msgs)))
+				logger.Error("Analytics event write error", "events", len(msgs), "error", err)
+				return
+			}
+			stats.Add("published", int64(len(msgs)))
+		},
+	}}
+}
+
+// Publish queues the events to be written to Kafka.
+func (p *Publisher) Publish(ctx context.Context, events []*model.Event) error {
+	msgs := make([]kafka.Message, 0, len(events))
+	for _, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:     []byte(e.SessionID),
+			Value:   value,
+			Headers: tracing.InjectKafka(ctx, []kafka.Header{{Key: "type", Value: []byte(e.Type)}}),
+		})
+	}
+	return p.writer.WriteMessages(ctx, msgs...)
+}
+
+// Close flushes pending writes and closes the publisher.
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/analytics/internal/controller/analytics"
+	"movieapp.com/analytics/pkg/model"
+	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
+)
+
+// Handler defines a gRPC analytics API handler.
+type Handler struct {
+	gen.UnimplementedAnalyticsServiceServer
+	ctrl *analytics.Controller
+}
+
+// New creates a new analytics gRPC handler.
+func New(ctrl *analytics.Controller) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// TrackEvents tracks the events of the caller. The user id is the
+// subject of the bearer token of the caller if verified, and empty
+// for anonymous callers.
+func (h *Handler) TrackEvents(ctx context.Context, req *gen.TrackEventsRequest) (*gen.TrackEventsResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	userID := req.UserId
+	if p := auth.FromContext(ctx); p != nil && userID == "" {
+		userID = p.Subject
+	}
+	if userID != "" {
+		if err := auth.RequireUser(ctx, userID); err != nil {
+			return nil, auth.Status(err)
+		}
+	}
+	events := make([]*model.Event, 0, len(req.Events))
+	for _, e := range req.Events {
+		events = append(events, model.EventFromProto(e))
+	}
+	if err := h.ctrl.Track(ctx, userID, events); err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.TrackEventsResponse{Accepted: int32(len(events))}, nil
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// EventType defines the type of an analytics event.
+type EventType string
+
+// Existing analytics event types.
+const (
+	EventTypeViewed      = EventType("MovieViewed")
+	EventTypeClicked     = EventType("MovieClicked")
+	EventTypePlayStarted = EventType("PlayStarted")
+)
+
+// Event defines an interaction of a client with a movie, published
+// for ranking and trending to learn from behavior beyond ratings.
+type Event struct {
+	Type       EventType         `json:"type"`
+	UserID     string            `json:"userId,omitempty"`
+	SessionID  string            `json:"sessionId"`
+	MovieID    string            `json:"movieId"`
+	Properties map[string]string `json:"properties,omitempty"`
+	// OccurredAt is the time reported by the client and ReceivedAt
+	// the time the event was received, telling delayed events.
+	OccurredAt time.Time `json:"occurredAt"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// PropertyKind defines the values a property may take.
+type PropertyKind int
+
+// Existing property kinds.
+const (
+	PropertyString PropertyKind = iota
+	// PropertyCount takes non-negative integers.
+	PropertyCount
+)
+
+// Property defines a property of an event type.
+type Property struct {
+	Kind     PropertyKind
+	Required bool
+}
+
+// Schemas defines the properties of each event type. Properties
+// not in the schema of their type are rejected.
+var Schemas = map[EventType]map[string]Property{
+	EventTypeViewed: {
+		// source is the list the movie was shown in, such as
+		// search or recommendations.
+		"source": {Kind: PropertyString},
+	},
+	EventTypeClicked: {
+		"source": {Kind: PropertyString},
+		// target is the element clicked, such as poster or
+		// trailer.
+		"target": {Kind: PropertyString, Required: true},
+		// position is the rank of the movie in the source.
+		"position": {Kind: PropertyCount},
+	},
+	EventTypePlayStarted: {
+		"source":          {Kind: PropertyString},
+		"positionSeconds": {Kind: PropertyCount},
+	},
+}
@@ -0,0 +1,13 @@
+package model
+
+import "movieapp.com/gen"
+
+// EventFromProto converts a generated proto counterpart into an
+// event. The user and the times are set on receipt.
+func EventFromProto(e *gen.AnalyticsEvent) *Event {
+	res := &Event{Type: EventType(e.Type), SessionID: e.SessionId, MovieID: e.MovieId, Properties: e.Properties}
+	if e.OccurredAt != nil {
+		res.OccurredAt = e.OccurredAt.AsTime()
+	}
+	return res
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Bounds of the latencies told apart by a histogram. Bucket bounds
+// grow geometrically between them, keeping the error of quantiles
+// within the growth factor.
+const (
+	minLatency    = 50 * time.Microsecond
+	maxLatency    = time.Minute
+	bucketGrowth  = 1.1
+	percentFactor = 100
+)
+
+var bucketCount = 1 + int(math.Ceil(math.Log(float64(maxLatency)/float64(minLatency))/math.Log(bucketGrowth)))
+
+// histogram records the latencies and errors of an operation. It is
+// safe for concurrent use.
+type histogram struct {
+	mu      sync.Mutex
+	buckets []int64
+	count   int64
+	errors  int64
+	sum     time.Duration
+	max     time.Duration
+}
+
+func newHistogram() *histogram {
+	return &histogram{buckets: make([]int64, bucketCount)}
+}
+
+// record adds the latency of a call, failed if err is not nil.
+func (h *histogram) record(d time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buckets[bucketOf(d)]++
+	h.count++
+	h.sum += d
+	h.max = max(h.max, d)
+	if err != nil {
+		h.errors++
+	}
+}
+
+// summary defines the latency statistics of a histogram.
+type summary struct {
+	Count  int64
+	Errors int64
+	Mean   time.Duration
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// summary returns the statistics of the recorded latencies.
+func (h *histogram) summary() summary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := summary{Count: h.count, Errors: h.errors, Max: h.max}
+	if h.count == 0 {
+		return s
+	}
+	s.Mean = h.sum / time.Duration(h.count)
+	s.P50 = min(h.quantile(50), h.max)
+	s.P90 = min(h.quantile(90), h.max)
+	s.P99 = min(h.quantile(99), h.max)
+	return s
+}
+
+// quantile returns the upper bound of the bucket holding the given
+// percentile.
+func (h *histogram) quantile(percent int64) time.Duration {
+	rank := (h.count*percent + percentFactor - 1) / percentFactor
+	var seen int64
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			return bucketBound(i)
+		}
+	}
+	return h.max
+}
+
+func bucketOf(d time.Duration) int {
+	if d <= minLatency {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(minLatency)) / math.Log(bucketGrowth)))
+	return min(i, bucketCount-1)
+}
+
+func bucketBound(i int) time.Duration {
+	return time.Duration(float64(minLatency) * math.Pow(bucketGrowth, float64(i)))
+}
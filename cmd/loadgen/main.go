@@ -0,0 +1,215 @@
+// Command loadgen drives a mix of requests against the movie API,
+// ramping the number of concurrent clients step by step and
+// reporting the throughput and latency histogram of each operation
+// at each step, so that regressions of the gateways are measured
+// before a release.
+//
+// Movie and user ids are those of the data of cmd/seed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/discovery/consul"
+	"movieapp.com/pkg/lifecycle"
+)
+
+func main() {
+	var (
+		registryAddr string
+		mixFlag      string
+		start        int
+		maxClients   int
+		step         int
+		stepDuration time.Duration
+		timeout      time.Duration
+		movies       int
+		users        int
+		batchSize    int
+		token        string
+		maxP99       time.Duration
+		maxErrorRate float64
+	)
+	flag.StringVar(&registryAddr, "registry-addr", "localhost:8500", "address of the Consul service registry")
+	flag.StringVar(&mixFlag, "mix", "details=70,batch=20,put-rating=10", "comma separated weights of the operations details, batch and put-rating")
+	flag.IntVar(&start, "start-concurrency", 1, "number of concurrent clients of the first step")
+	flag.IntVar(&maxClients, "max-concurrency", 64, "number of concurrent clients of the last step")
+	flag.IntVar(&step, "ramp-step", 8, "number of clients added at each step")
+	flag.DurationVar(&stepDuration, "step-duration", 30*time.Second, "duration of each step")
+	flag.DurationVar(&timeout, "timeout", 5*time.Second, "timeout of each request")
+	flag.IntVar(&movies, "movies", 1000, "number of the seeded movies requested")
+	flag.IntVar(&users, "users", 200, "number of the seeded users rating")
+	flag.IntVar(&batchSize, "batch-size", 20, "number of movies of each batch request")
+	flag.StringVar(&token, "token", "", "bearer token of the requests, none if empty")
+	flag.DurationVar(&maxP99, "max-p99", 0, "99th percentile latency of an operation at the last step above which the run fails, not checked if 0")
+	flag.Float64Var(&maxErrorRate, "max-error-rate", 0, "fraction of failed requests of an operation at the last step above which the run fails, not checked if 0")
+	flag.Parse()
+	switch {
+	case start < 1 || maxClients < start || step < 1:
+		log.Fatal("concurrency must ramp up from a positive start by a positive step")
+	case stepDuration <= 0 || timeout <= 0:
+		log.Fatal("step-duration and timeout must be positive")
+	case movies < 1 || users < 1:
+		log.Fatal("movies and users must be positive")
+	case batchSize < 1 || batchSize > 100:
+		log.Fatal("batch-size must be between 1 and 100")
+	}
+	mix, err := parseMix(mixFlag)
+	if err != nil {
+		log.Fatalf("invalid mix: %v", err)
+	}
+
+	registry, err := consul.NewRegistry(registryAddr)
+	if err != nil {
+		log.Fatalf("failed to connect to the registry: %v", err)
+	}
+	movieConn, err := grpcutil.NewClient("movie", registry)
+	if err != nil {
+		log.Fatalf("failed to connect to the movie service: %v", err)
+	}
+	defer movieConn.Close()
+	ratingConn, err := grpcutil.NewClient("rating", registry)
+	if err != nil {
+		log.Fatalf("failed to connect to the rating service: %v", err)
+	}
+	defer ratingConn.Close()
+	c := &clients{
+		movie:     gen.NewMovieServiceClient(movieConn),
+		rating:    gen.NewRatingServiceClient(ratingConn),
+		batchSize: batchSize,
+	}
+	for i := 1; i <= movies; i++ {
+		c.movieIDs = append(c.movieIDs, fmt.Sprintf("seed-movie-%06d", i))
+	}
+	for i := 1; i <= users; i++ {
+		c.userIDs = append(c.userIDs, fmt.Sprintf("seed-user-%05d", i))
+	}
+	ops, err := c.operations(mix)
+	if err != nil {
+		log.Fatalf("invalid mix: %v", err)
+	}
+
+	ctx, stop := lifecycle.Context()
+	defer stop()
+	if token != "" {
+		ctx = auth.NewContext(ctx, nil, token)
+	}
+	last := ramp(ctx, ops, start, maxClients, step, stepDuration, timeout)
+	if failed := check(last, maxP99, maxErrorRate); failed {
+		os.Exit(1)
+	}
+}
+
+// ramp runs the steps, adding clients at each one, and returns the
+// summaries of the last step run.
+func ramp(ctx context.Context, ops []operation, start, maxClients, step int, stepDuration, timeout time.Duration) map[string]summary {
+	var current atomic.Pointer[map[string]*histogram]
+	newStep := func() map[string]*histogram {
+		hs := map[string]*histogram{}
+		for _, op := range ops {
+			hs[op.name] = newHistogram()
+		}
+		current.Store(&hs)
+		return hs
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	workersCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "CLIENTS\tOPERATION\tREQUESTS\tRPS\tERRORS\tMEAN\tP50\tP90\tP99\tMAX\t")
+	w.Flush()
+	var last map[string]summary
+	clients := 0
+	hs := newStep()
+	for n := start; n <= maxClients; n = nextStep(n, step, maxClients) {
+		for ; clients < n; clients++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				work(workersCtx, rand.New(rand.NewSource(seed)), ops, &current, timeout)
+			}(time.Now().UnixNano() + int64(clients))
+		}
+		began := time.Now()
+		select {
+		case <-ctx.Done():
+		case <-time.After(stepDuration):
+		}
+		elapsed := time.Since(began)
+		// Calls completing from now on count towards the next step.
+		done := hs
+		hs = newStep()
+		last = map[string]summary{}
+		for _, op := range ops {
+			s := done[op.name].summary()
+			last[op.name] = s
+			fmt.Fprintf(w, "%d\t%s\t%d\t%.1f\t%d\t%v\t%v\t%v\t%v\t%v\t\n", n, op.name, s.Count, float64(s.Count)/elapsed.Seconds(), s.Errors,
+				round(s.Mean), round(s.P50), round(s.P90), round(s.P99), round(s.Max))
+		}
+		w.Flush()
+		if ctx.Err() != nil || n == maxClients {
+			break
+		}
+	}
+	return last
+}
+
+// nextStep returns the number of clients of the step after n, the
+// last step running exactly maxClients.
+func nextStep(n, step, maxClients int) int {
+	return min(n+step, maxClients)
+}
+
+// work calls operations drawn from the mix until ctx is done,
+// recording them in the histograms of the current step.
+func work(ctx context.Context, r *rand.Rand, ops []operation, current *atomic.Pointer[map[string]*histogram], timeout time.Duration) {
+	for ctx.Err() == nil {
+		op := pickOperation(r, ops)
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		began := time.Now()
+		err := op.call(callCtx, r)
+		elapsed := time.Since(began)
+		cancel()
+		if ctx.Err() != nil {
+			// Calls cut short by the end of the run are not measured.
+			return
+		}
+		(*current.Load())[op.name].record(elapsed, err)
+	}
+}
+
+// check reports the operations of the last step over the latency or
+// error rate thresholds, returning whether any is.
+func check(last map[string]summary, maxP99 time.Duration, maxErrorRate float64) bool {
+	failed := false
+	for name, s := range last {
+		if maxP99 > 0 && s.P99 > maxP99 {
+			fmt.Fprintf(os.Stderr, "%s: p99 latency %v above %v\n", name, round(s.P99), maxP99)
+			failed = true
+		}
+		if maxErrorRate > 0 && s.Count > 0 && float64(s.Errors)/float64(s.Count) > maxErrorRate {
+			fmt.Fprintf(os.Stderr, "%s: error rate %.4f above %.4f\n", name, float64(s.Errors)/float64(s.Count), maxErrorRate)
+			failed = true
+		}
+	}
+	return failed
+}
+
+func round(d time.Duration) time.Duration {
+	if d >= time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
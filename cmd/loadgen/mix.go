@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"movieapp.com/gen"
+	"movieapp.com/rating/pkg/model"
+)
+
+// Names of the operations of a request mix.
+const (
+	opDetails   = "details"
+	opBatch     = "batch"
+	opPutRating = "put-rating"
+)
+
+// operation defines a kind of request of the mix, drawn in
+// proportion to its weight.
+type operation struct {
+	name   string
+	weight int
+	call   func(ctx context.Context, r *rand.Rand) error
+}
+
+// clients defines the services the operations call and the ids
+// they draw from.
+type clients struct {
+	movie     gen.MovieServiceClient
+	rating    gen.RatingServiceClient
+	movieIDs  []string
+	userIDs   []string
+	batchSize int
+}
+
+// parseMix parses comma separated name=weight pairs, such as
+// details=70,batch=20,put-rating=10.
+func parseMix(s string) (map[string]int, error) {
+	res := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=weight", pair)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non negative integer", name)
+		}
+		res[name] = w
+	}
+	return res, nil
+}
+
+// operations returns the operations of a mix, sorted by name.
+func (c *clients) operations(mix map[string]int) ([]operation, error) {
+	calls := map[string]func(context.Context, *rand.Rand) error{
+		opDetails:   c.details,
+		opBatch:     c.batch,
+		opPutRating: c.putRating,
+	}
+	var res []operation
+	total := 0
+	for name, weight := range mix {
+		call, ok := calls[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown operation %q, must be one of %s, %s or %s", name, opDetails, opBatch, opPutRating)
+		}
+		if weight > 0 {
+			res = append(res, operation{name, weight, call})
+			total += weight
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("mix has no operation of positive weight")
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].name < res[j].name })
+	return res, nil
+}
+
+// pickOperation draws an operation in proportion to the weights.
+func pickOperation(r *rand.Rand, ops []operation) *operation {
+	total := 0
+	for _, op := range ops {
+		total += op.weight
+	}
+	n := r.Intn(total)
+	for i := range ops {
+		if n < ops[i].weight {
+			return &ops[i]
+		}
+		n -= ops[i].weight
+	}
+	return &ops[len(ops)-1]
+}
+
+func (c *clients) details(ctx context.Context, r *rand.Rand) error {
+	_, err := c.movie.GetMovieDetails(ctx, &gen.GetMovieDetailsRequest{MovieId: pick(r, c.movieIDs)})
+	return err
+}
+
+func (c *clients) batch(ctx context.Context, r *rand.Rand) error {
+	ids := make([]string, c.batchSize)
+	for i := range ids {
+		ids[i] = pick(r, c.movieIDs)
+	}
+	_, err := c.movie.GetManyMovieDetails(ctx, &gen.GetManyMovieDetailsRequest{MovieIds: ids})
+	return err
+}
+
+func (c *clients) putRating(ctx context.Context, r *rand.Rand) error {
+	_, err := c.rating.PutRating(ctx, &gen.PutRatingRequest{
+		UserId:      pick(r, c.userIDs),
+		RecordId:    pick(r, c.movieIDs),
+		RecordType:  string(model.RecordTypeMovie),
+		RatingValue: int32(1 + r.Intn(5)),
+	})
+	return err
+}
+
+func pick(r *rand.Rand, ids []string) string {
+	return ids[r.Intn(len(ids))]
+}
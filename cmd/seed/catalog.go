@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"movieapp.com/metadata/pkg/model"
+)
+
+// Word lists titles, descriptions and names are drawn from.
+var (
+	titleAdjectives = []string{"Silent", "Broken", "Last", "Hidden", "Crimson", "Endless", "Golden", "Lost", "Midnight", "Savage", "Distant", "Burning", "Frozen", "Hollow", "Iron", "Velvet", "Wild", "Forgotten", "Electric", "Quiet"}
+	titleNouns      = []string{"River", "Empire", "Horizon", "Garden", "Signal", "Harbor", "Kingdom", "Mirror", "Frontier", "Orchard", "Circuit", "Lighthouse", "Station", "Valley", "Winter", "Carnival", "Archive", "Covenant", "Passage", "Summit"}
+	titlePatterns   = []string{"The %s %s", "%s %s", "A %s %s", "Beyond the %[2]s", "Return to the %[2]s", "The %[2]s of %[1]s Hearts"}
+	protagonists    = []string{"a retired detective", "two estranged sisters", "a young pilot", "a small-town mayor", "a disgraced chef", "an aging rock band", "a lonely astronaut", "a family of farmers", "a rookie lawyer", "a street magician"}
+	conflicts       = []string{"uncover a conspiracy", "race against time", "confront their past", "fight for survival", "search for a missing friend", "plan one last heist", "chase an impossible dream", "protect a dangerous secret"}
+	settings        = []string{"in a city that never sleeps", "on a remote island", "across a war-torn continent", "in the near future", "during a record heatwave", "aboard a failing space station", "in a forgotten mountain village", "behind the scenes of a talent show"}
+	firstNames      = []string{"Ana", "Bruno", "Chen", "Dara", "Elena", "Felix", "Grace", "Hugo", "Ines", "Jonas", "Kai", "Lena", "Marco", "Nadia", "Omar", "Priya", "Rafael", "Sofia", "Tomas", "Yuki"}
+	lastNames       = []string{"Almeida", "Becker", "Castro", "Dubois", "Eriksen", "Fischer", "Garcia", "Haddad", "Ito", "Jensen", "Kowalski", "Larsen", "Moreau", "Novak", "Okafor", "Petrov", "Rossi", "Silva", "Tanaka", "Weber"}
+	tagWords        = []string{"based-on-novel", "twist-ending", "ensemble-cast", "slow-burn", "cult-classic", "coming-of-age", "heist", "road-trip", "time-travel", "small-town"}
+	certifications  = []string{"G", "PG", "PG-13", "R"}
+	languages       = []string{"en", "en", "en", "fr", "es", "ja", "ko", "pt", "de", "it"}
+)
+
+// newCatalog generates n movies with unique titles. Release years
+// skew towards recent decades, as in real catalogs.
+func newCatalog(r *rand.Rand, n int) []*model.Metadata {
+	genres := model.Genres()
+	titles := map[string]bool{}
+	res := make([]*model.Metadata, 0, n)
+	thisYear := time.Now().Year()
+	for i := 0; i < n; i++ {
+		title := uniqueTitle(r, titles)
+		// The square root of a uniform variable skews towards 1.
+		year := 1950 + int(float64(thisYear-1950)*sqrtUniform(r))
+		released := time.Date(year, time.Month(1+r.Intn(12)), 1+r.Intn(28), 0, 0, 0, 0, time.UTC)
+		m := &model.Metadata{
+			ID:               fmt.Sprintf("seed-movie-%06d", i+1),
+			Title:            title,
+			Description:      fmt.Sprintf("%s %s %s.", capitalize(pick(r, protagonists)), pick(r, conflicts), pick(r, settings)),
+			Director:         pick(r, firstNames) + " " + pick(r, lastNames),
+			ReleaseDate:      released.Format(model.ReleaseDateLayout),
+			RuntimeMinutes:   80 + r.Intn(80),
+			Certification:    pick(r, certifications),
+			OriginalLanguage: pick(r, languages),
+		}
+		for _, j := range r.Perm(len(genres))[:1+r.Intn(3)] {
+			m.Genres = append(m.Genres, genres[j])
+		}
+		for _, j := range r.Perm(len(tagWords))[:r.Intn(3)] {
+			m.Tags = append(m.Tags, tagWords[j])
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// uniqueTitle draws a title not drawn yet, numbering sequels once
+// the combinations run out.
+func uniqueTitle(r *rand.Rand, titles map[string]bool) string {
+	title := fmt.Sprintf(pick(r, titlePatterns), pick(r, titleAdjectives), pick(r, titleNouns))
+	base := title
+	for n := 2; titles[title]; n++ {
+		title = fmt.Sprintf("%s %d", base, n)
+	}
+	titles[title] = true
+	return title
+}
+
+func pick(r *rand.Rand, words []string) string {
+	return words[r.Intn(len(words))]
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func sqrtUniform(r *rand.Rand) float64 {
+	return math.Sqrt(r.Float64())
+}
@@ -0,0 +1,155 @@
+// Command seed fills an environment with a generated catalog, users
+// and ratings through the public APIs of the services. Ids are
+// stable for a given seed, so that reruns overwrite the data of
+// earlier ones rather than adding to it.
+//
+// Ratings and users are written on behalf of many users, so seeded
+// environments must not verify bearer tokens, or accept the token
+// passed with -token for all of them. Movies are written with the
+// metadata admin token passed with -admin-token.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"math/rand"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/discovery/consul"
+	ratingmodel "movieapp.com/rating/pkg/model"
+	usermodel "movieapp.com/user/pkg/model"
+)
+
+func main() {
+	var (
+		registryAddr   string
+		movies         int
+		users          int
+		ratingsPerUser float64
+		skew           float64
+		seed           int64
+		concurrency    int
+		token          string
+		adminToken     string
+		author         string
+	)
+	flag.StringVar(&registryAddr, "registry-addr", "localhost:8500", "address of the Consul service registry")
+	flag.IntVar(&movies, "movies", 1000, "number of generated movies")
+	flag.IntVar(&users, "users", 200, "number of generated users")
+	flag.Float64Var(&ratingsPerUser, "ratings-per-user", 20, "mean number of ratings of a user, exponentially distributed")
+	flag.Float64Var(&skew, "skew", 1.2, "exponent of the Zipf distribution of the rated movies, greater than 1, concentrating ratings on fewer movies as it grows")
+	flag.Int64Var(&seed, "seed", 1, "seed of the generated data, the same seed generating the same data")
+	flag.IntVar(&concurrency, "concurrency", 8, "number of parallel writes")
+	flag.StringVar(&token, "token", "", "bearer token of the calls, none if empty")
+	flag.StringVar(&adminToken, "admin-token", "", "bearer token of the metadata admin calls writing the movies")
+	flag.StringVar(&author, "author", "seed", "author of the metadata changes")
+	flag.Parse()
+	switch {
+	case movies < 1 || users < 0:
+		log.Fatal("movies must be positive and users not negative")
+	case ratingsPerUser < 0:
+		log.Fatal("ratings-per-user must not be negative")
+	case skew <= 1:
+		log.Fatal("skew must be greater than 1")
+	case concurrency < 1:
+		log.Fatal("concurrency must be positive")
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	catalog := newCatalog(r, movies)
+	population := newUsers(r, users)
+	movieIDs := make([]string, len(catalog))
+	for i, m := range catalog {
+		movieIDs[i] = m.ID
+	}
+	userIDs := make([]string, len(population))
+	for i, u := range population {
+		userIDs[i] = u.ID
+	}
+	ratings := newRatings(r, userIDs, movieIDs, ratingsPerUser, skew)
+
+	registry, err := consul.NewRegistry(registryAddr)
+	if err != nil {
+		log.Fatalf("failed to connect to the registry: %v", err)
+	}
+	ctx := context.Background()
+	if token != "" {
+		ctx = auth.NewContext(ctx, nil, token)
+	}
+	metadataConn, err := grpcutil.NewClient("metadata", registry)
+	if err != nil {
+		log.Fatalf("failed to connect to the metadata service: %v", err)
+	}
+	defer metadataConn.Close()
+	userConn, err := grpcutil.NewClient("user", registry)
+	if err != nil {
+		log.Fatalf("failed to connect to the user service: %v", err)
+	}
+	defer userConn.Close()
+	ratingConn, err := grpcutil.NewClient("rating", registry)
+	if err != nil {
+		log.Fatalf("failed to connect to the rating service: %v", err)
+	}
+	defer ratingConn.Close()
+	metadataClient := gen.NewMetadataServiceClient(metadataConn)
+	userClient := gen.NewUserServiceClient(userConn)
+	ratingClient := gen.NewRatingServiceClient(ratingConn)
+
+	slog.Info("Seeding movies", "count", len(catalog))
+	if err := forEach(auth.WithToken(ctx, adminToken), catalog, concurrency, func(ctx context.Context, m *metadatamodel.Metadata) error {
+		_, err := metadataClient.PutMetadata(ctx, &gen.PutMetadataRequest{Metadata: metadatamodel.MetadataToProto(m), Author: author})
+		return err
+	}); err != nil {
+		log.Fatalf("failed to seed movies: %v", err)
+	}
+	slog.Info("Seeding users", "count", len(population))
+	var existing atomic.Int64
+	if err := forEach(ctx, population, concurrency, func(ctx context.Context, u *usermodel.User) error {
+		_, err := userClient.RegisterUser(ctx, &gen.RegisterUserRequest{UserId: u.ID, Email: u.Email, DisplayName: u.DisplayName})
+		if status.Code(err) == codes.AlreadyExists {
+			// Registered by an earlier run.
+			existing.Add(1)
+			return nil
+		}
+		return err
+	}); err != nil {
+		log.Fatalf("failed to seed users: %v", err)
+	}
+	slog.Info("Seeding ratings", "count", len(ratings))
+	if err := forEach(ctx, ratings, concurrency, func(ctx context.Context, rt rating) error {
+		_, err := ratingClient.PutRating(ctx, &gen.PutRatingRequest{
+			UserId:      rt.UserID,
+			RecordId:    rt.MovieID,
+			RecordType:  string(ratingmodel.RecordTypeMovie),
+			RatingValue: int32(rt.Value),
+		})
+		return err
+	}); err != nil {
+		log.Fatalf("failed to seed ratings: %v", err)
+	}
+	slog.Info("Seeded", "movies", len(catalog), "users", len(population), "existingUsers", existing.Load(), "ratings", len(ratings))
+}
+
+// forEach calls fn for each item with at most concurrency calls in
+// flight, stopping at the first error.
+func forEach[T any](ctx context.Context, items []T, concurrency int, fn func(context.Context, T) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		item := item
+		g.Go(func() error { return fn(ctx, item) })
+	}
+	return g.Wait()
+}
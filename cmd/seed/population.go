@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	ratingmodel "movieapp.com/rating/pkg/model"
+	usermodel "movieapp.com/user/pkg/model"
+)
+
+// Shape of the generated rating distribution.
+const (
+	meanQuality   = 3.4
+	qualitySpread = 0.7
+	userBias      = 0.4
+	ratingNoise   = 0.8
+	minRating     = 1
+	maxRating     = 5
+)
+
+// rating defines a generated rating of a movie by a user.
+type rating struct {
+	UserID  string
+	MovieID string
+	Value   ratingmodel.RatingValue
+}
+
+// newUsers generates n users.
+func newUsers(r *rand.Rand, n int) []*usermodel.User {
+	res := make([]*usermodel.User, 0, n)
+	for i := 0; i < n; i++ {
+		first, last := pick(r, firstNames), pick(r, lastNames)
+		id := fmt.Sprintf("seed-user-%05d", i+1)
+		res = append(res, &usermodel.User{
+			ID:          id,
+			Email:       fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			DisplayName: first + " " + last,
+		})
+	}
+	return res
+}
+
+// newRatings generates the ratings of the users. The number of
+// ratings per user is exponentially distributed around the mean,
+// and the movies rated follow a Zipf distribution of the given skew
+// over a random popularity order, so that a few movies collect most
+// ratings. Values combine the latent quality of the movie with the
+// bias of the user and noise.
+func newRatings(r *rand.Rand, userIDs []string, movieIDs []string, mean float64, skew float64) []rating {
+	if len(movieIDs) == 0 {
+		return nil
+	}
+	popularity := r.Perm(len(movieIDs))
+	quality := make([]float64, len(movieIDs))
+	for i := range quality {
+		quality[i] = meanQuality + r.NormFloat64()*qualitySpread
+	}
+	zipf := rand.NewZipf(r, skew, 1, uint64(len(movieIDs)-1))
+	var res []rating
+	for _, userID := range userIDs {
+		bias := r.NormFloat64() * userBias
+		n := min(1+int(r.ExpFloat64()*mean), len(movieIDs))
+		rated := map[int]bool{}
+		// Popular movies are drawn repeatedly, so the draws are
+		// bounded for users rating most of the catalog.
+		for draws := 0; len(rated) < n && draws < 10*n; draws++ {
+			m := popularity[zipf.Uint64()]
+			if rated[m] {
+				continue
+			}
+			rated[m] = true
+			v := math.Round(quality[m] + bias + r.NormFloat64()*ratingNoise)
+			res = append(res, rating{userID, movieIDs[m], ratingmodel.RatingValue(min(max(v, minRating), maxRating))})
+		}
+	}
+	return res
+}
@@ -0,0 +1,585 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.12.4
+// source: metadata.proto
+
+package gen
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MetadataService_GetMetadata_FullMethodName             = "/MetadataService/GetMetadata"
+	MetadataService_GetManyMetadata_FullMethodName         = "/MetadataService/GetManyMetadata"
+	MetadataService_PutMetadata_FullMethodName             = "/MetadataService/PutMetadata"
+	MetadataService_GetMetadataByExternalId_FullMethodName = "/MetadataService/GetMetadataByExternalId"
+	MetadataService_PutMetadataByExternalId_FullMethodName = "/MetadataService/PutMetadataByExternalId"
+	MetadataService_GetSimilarMetadata_FullMethodName      = "/MetadataService/GetSimilarMetadata"
+	MetadataService_ListMetadata_FullMethodName            = "/MetadataService/ListMetadata"
+	MetadataService_ExportMetadata_FullMethodName          = "/MetadataService/ExportMetadata"
+	MetadataService_SearchMetadata_FullMethodName          = "/MetadataService/SearchMetadata"
+	MetadataService_CheckDuplicateMetadata_FullMethodName  = "/MetadataService/CheckDuplicateMetadata"
+	MetadataService_MergeMetadata_FullMethodName           = "/MetadataService/MergeMetadata"
+	MetadataService_DeleteMetadata_FullMethodName          = "/MetadataService/DeleteMetadata"
+	MetadataService_RestoreMetadata_FullMethodName         = "/MetadataService/RestoreMetadata"
+)
+
+// MetadataServiceClient is the client API for MetadataService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MetadataServiceClient interface {
+	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error)
+	GetManyMetadata(ctx context.Context, in *GetManyMetadataRequest, opts ...grpc.CallOption) (*GetManyMetadataResponse, error)
+	PutMetadata(ctx context.Context, in *PutMetadataRequest, opts ...grpc.CallOption) (*PutMetadataResponse, error)
+	GetMetadataByExternalId(ctx context.Context, in *GetMetadataByExternalIdRequest, opts ...grpc.CallOption) (*GetMetadataByExternalIdResponse, error)
+	PutMetadataByExternalId(ctx context.Context, in *PutMetadataByExternalIdRequest, opts ...grpc.CallOption) (*PutMetadataByExternalIdResponse, error)
+	GetSimilarMetadata(ctx context.Context, in *GetSimilarMetadataRequest, opts ...grpc.CallOption) (*GetSimilarMetadataResponse, error)
+	ListMetadata(ctx context.Context, in *ListMetadataRequest, opts ...grpc.CallOption) (*ListMetadataResponse, error)
+	// ExportMetadata streams all metadata matching the filter in
+	// batches, for snapshots of the whole catalog.
+	ExportMetadata(ctx context.Context, in *ExportMetadataRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportMetadataResponse], error)
+	SearchMetadata(ctx context.Context, in *SearchMetadataRequest, opts ...grpc.CallOption) (*SearchMetadataResponse, error)
+	CheckDuplicateMetadata(ctx context.Context, in *CheckDuplicateMetadataRequest, opts ...grpc.CallOption) (*CheckDuplicateMetadataResponse, error)
+	MergeMetadata(ctx context.Context, in *MergeMetadataRequest, opts ...grpc.CallOption) (*MergeMetadataResponse, error)
+	DeleteMetadata(ctx context.Context, in *DeleteMetadataRequest, opts ...grpc.CallOption) (*DeleteMetadataResponse, error)
+	RestoreMetadata(ctx context.Context, in *RestoreMetadataRequest, opts ...grpc.CallOption) (*RestoreMetadataResponse, error)
+}
+
+type metadataServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMetadataServiceClient(cc grpc.ClientConnInterface) MetadataServiceClient {
+	return &metadataServiceClient{cc}
+}
+
+func (c *metadataServiceClient) GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_GetMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) GetManyMetadata(ctx context.Context, in *GetManyMetadataRequest, opts ...grpc.CallOption) (*GetManyMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetManyMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_GetManyMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) PutMetadata(ctx context.Context, in *PutMetadataRequest, opts ...grpc.CallOption) (*PutMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PutMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_PutMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) GetMetadataByExternalId(ctx context.Context, in *GetMetadataByExternalIdRequest, opts ...grpc.CallOption) (*GetMetadataByExternalIdResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMetadataByExternalIdResponse)
+	err := c.cc.Invoke(ctx, MetadataService_GetMetadataByExternalId_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) PutMetadataByExternalId(ctx context.Context, in *PutMetadataByExternalIdRequest, opts ...grpc.CallOption) (*PutMetadataByExternalIdResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PutMetadataByExternalIdResponse)
+	err := c.cc.Invoke(ctx, MetadataService_PutMetadataByExternalId_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) GetSimilarMetadata(ctx context.Context, in *GetSimilarMetadataRequest, opts ...grpc.CallOption) (*GetSimilarMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSimilarMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_GetSimilarMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) ListMetadata(ctx context.Context, in *ListMetadataRequest, opts ...grpc.CallOption) (*ListMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_ListMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) ExportMetadata(ctx context.Context, in *ExportMetadataRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportMetadataResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MetadataService_ServiceDesc.Streams[0], MetadataService_ExportMetadata_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportMetadataRequest, ExportMetadataResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MetadataService_ExportMetadataClient = grpc.ServerStreamingClient[ExportMetadataResponse]
+
+func (c *metadataServiceClient) SearchMetadata(ctx context.Context, in *SearchMetadataRequest, opts ...grpc.CallOption) (*SearchMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_SearchMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) CheckDuplicateMetadata(ctx context.Context, in *CheckDuplicateMetadataRequest, opts ...grpc.CallOption) (*CheckDuplicateMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckDuplicateMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_CheckDuplicateMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) MergeMetadata(ctx context.Context, in *MergeMetadataRequest, opts ...grpc.CallOption) (*MergeMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MergeMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_MergeMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) DeleteMetadata(ctx context.Context, in *DeleteMetadataRequest, opts ...grpc.CallOption) (*DeleteMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_DeleteMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) RestoreMetadata(ctx context.Context, in *RestoreMetadataRequest, opts ...grpc.CallOption) (*RestoreMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_RestoreMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MetadataServiceServer is the server API for MetadataService service.
+// All implementations must embed UnimplementedMetadataServiceServer
+// for forward compatibility.
+type MetadataServiceServer interface {
+	GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error)
+	GetManyMetadata(context.Context, *GetManyMetadataRequest) (*GetManyMetadataResponse, error)
+	PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error)
+	GetMetadataByExternalId(context.Context, *GetMetadataByExternalIdRequest) (*GetMetadataByExternalIdResponse, error)
+	PutMetadataByExternalId(context.Context, *PutMetadataByExternalIdRequest) (*PutMetadataByExternalIdResponse, error)
+	GetSimilarMetadata(context.Context, *GetSimilarMetadataRequest) (*GetSimilarMetadataResponse, error)
+	ListMetadata(context.Context, *ListMetadataRequest) (*ListMetadataResponse, error)
+	// ExportMetadata streams all metadata matching the filter in
+	// batches, for snapshots of the whole catalog.
+	ExportMetadata(*ExportMetadataRequest, grpc.ServerStreamingServer[ExportMetadataResponse]) error
+	SearchMetadata(context.Context, *SearchMetadataRequest) (*SearchMetadataResponse, error)
+	CheckDuplicateMetadata(context.Context, *CheckDuplicateMetadataRequest) (*CheckDuplicateMetadataResponse, error)
+	MergeMetadata(context.Context, *MergeMetadataRequest) (*MergeMetadataResponse, error)
+	DeleteMetadata(context.Context, *DeleteMetadataRequest) (*DeleteMetadataResponse, error)
+	RestoreMetadata(context.Context, *RestoreMetadataRequest) (*RestoreMetadataResponse, error)
+	mustEmbedUnimplementedMetadataServiceServer()
+}
+
+// UnimplementedMetadataServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMetadataServiceServer struct{}
+
+func (UnimplementedMetadataServiceServer) GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) GetManyMetadata(context.Context, *GetManyMetadataRequest) (*GetManyMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetManyMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) GetMetadataByExternalId(context.Context, *GetMetadataByExternalIdRequest) (*GetMetadataByExternalIdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetadataByExternalId not implemented")
+}
+func (UnimplementedMetadataServiceServer) PutMetadataByExternalId(context.Context, *PutMetadataByExternalIdRequest) (*PutMetadataByExternalIdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutMetadataByExternalId not implemented")
+}
+func (UnimplementedMetadataServiceServer) GetSimilarMetadata(context.Context, *GetSimilarMetadataRequest) (*GetSimilarMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSimilarMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) ListMetadata(context.Context, *ListMetadataRequest) (*ListMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) ExportMetadata(*ExportMetadataRequest, grpc.ServerStreamingServer[ExportMetadataResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) SearchMetadata(context.Context, *SearchMetadataRequest) (*SearchMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) CheckDuplicateMetadata(context.Context, *CheckDuplicateMetadataRequest) (*CheckDuplicateMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckDuplicateMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) MergeMetadata(context.Context, *MergeMetadataRequest) (*MergeMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) DeleteMetadata(context.Context, *DeleteMetadataRequest) (*DeleteMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) RestoreMetadata(context.Context, *RestoreMetadataRequest) (*RestoreMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) mustEmbedUnimplementedMetadataServiceServer() {}
+func (UnimplementedMetadataServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeMetadataServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MetadataServiceServer will
+// result in compilation errors.
+type UnsafeMetadataServiceServer interface {
+	mustEmbedUnimplementedMetadataServiceServer()
+}
+
+func RegisterMetadataServiceServer(s grpc.ServiceRegistrar, srv MetadataServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMetadataServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MetadataService_ServiceDesc, srv)
+}
+
+func _MetadataService_GetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).GetMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_GetMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).GetMetadata(ctx, req.(*GetMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_GetManyMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetManyMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).GetManyMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_GetManyMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).GetManyMetadata(ctx, req.(*GetManyMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_PutMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).PutMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_PutMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).PutMetadata(ctx, req.(*PutMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_GetMetadataByExternalId_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataByExternalIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).GetMetadataByExternalId(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_GetMetadataByExternalId_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).GetMetadataByExternalId(ctx, req.(*GetMetadataByExternalIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_PutMetadataByExternalId_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutMetadataByExternalIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).PutMetadataByExternalId(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_PutMetadataByExternalId_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).PutMetadataByExternalId(ctx, req.(*PutMetadataByExternalIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_GetSimilarMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSimilarMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).GetSimilarMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_GetSimilarMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).GetSimilarMetadata(ctx, req.(*GetSimilarMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_ListMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).ListMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_ListMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).ListMetadata(ctx, req.(*ListMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_ExportMetadata_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportMetadataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetadataServiceServer).ExportMetadata(m, &grpc.GenericServerStream[ExportMetadataRequest, ExportMetadataResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MetadataService_ExportMetadataServer = grpc.ServerStreamingServer[ExportMetadataResponse]
+
+func _MetadataService_SearchMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).SearchMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_SearchMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).SearchMetadata(ctx, req.(*SearchMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_CheckDuplicateMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckDuplicateMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).CheckDuplicateMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_CheckDuplicateMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).CheckDuplicateMetadata(ctx, req.(*CheckDuplicateMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_MergeMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).MergeMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_MergeMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).MergeMetadata(ctx, req.(*MergeMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_DeleteMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).DeleteMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_DeleteMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).DeleteMetadata(ctx, req.(*DeleteMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_RestoreMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).RestoreMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_RestoreMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).RestoreMetadata(ctx, req.(*RestoreMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MetadataService_ServiceDesc is the grpc.ServiceDesc for MetadataService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MetadataService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "MetadataService",
+	HandlerType: (*MetadataServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMetadata",
+			Handler:    _MetadataService_GetMetadata_Handler,
+		},
+		{
+			MethodName: "GetManyMetadata",
+			Handler:    _MetadataService_GetManyMetadata_Handler,
+		},
+		{
+			MethodName: "PutMetadata",
+			Handler:    _MetadataService_PutMetadata_Handler,
+		},
+		{
+			MethodName: "GetMetadataByExternalId",
+			Handler:    _MetadataService_GetMetadataByExternalId_Handler,
+		},
+		{
+			MethodName: "PutMetadataByExternalId",
+			Handler:    _MetadataService_PutMetadataByExternalId_Handler,
+		},
+		{
+			MethodName: "GetSimilarMetadata",
+			Handler:    _MetadataService_GetSimilarMetadata_Handler,
+		},
+		{
+			MethodName: "ListMetadata",
+			Handler:    _MetadataService_ListMetadata_Handler,
+		},
+		{
+			MethodName: "SearchMetadata",
+			Handler:    _MetadataService_SearchMetadata_Handler,
+		},
+		{
+			MethodName: "CheckDuplicateMetadata",
+			Handler:    _MetadataService_CheckDuplicateMetadata_Handler,
+		},
+		{
+			MethodName: "MergeMetadata",
+			Handler:    _MetadataService_MergeMetadata_Handler,
+		},
+		{
+			MethodName: "DeleteMetadata",
+			Handler:    _MetadataService_DeleteMetadata_Handler,
+		},
+		{
+			MethodName: "RestoreMetadata",
+			Handler:    _MetadataService_RestoreMetadata_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportMetadata",
+			Handler:       _MetadataService_ExportMetadata_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "metadata.proto",
+}
@@ -19,168 +19,112 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MetadataService_GetMetadata_FullMethodName = "/MetadataService/GetMetadata"
-	MetadataService_PutMetadata_FullMethodName = "/MetadataService/PutMetadata"
+	RatingService_GetAggregatedRating_FullMethodName  = "/RatingService/GetAggregatedRating"
+	RatingService_GetAggregatedRatings_FullMethodName = "/RatingService/GetAggregatedRatings"
+	RatingService_GetUserRatings_FullMethodName       = "/RatingService/GetUserRatings"
+	RatingService_GetTrending_FullMethodName          = "/RatingService/GetTrending"
+	RatingService_ListRecordRatings_FullMethodName    = "/RatingService/ListRecordRatings"
+	RatingService_ListUserRatings_FullMethodName      = "/RatingService/ListUserRatings"
+	RatingService_PutRating_FullMethodName            = "/RatingService/PutRating"
+	RatingService_MoveRatings_FullMethodName          = "/RatingService/MoveRatings"
 )
 
-// MetadataServiceClient is the client API for MetadataService service.
+// RatingServiceClient is the client API for RatingService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type MetadataServiceClient interface {
-	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error)
-	PutMetadata(ctx context.Context, in *PutMetadataRequest, opts ...grpc.CallOption) (*PutMetadataResponse, error)
+type RatingServiceClient interface {
+	GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error)
+	GetAggregatedRatings(ctx context.Context, in *GetAggregatedRatingsRequest, opts ...grpc.CallOption) (*GetAggregatedRatingsResponse, error)
+	GetUserRatings(ctx context.Context, in *GetUserRatingsRequest, opts ...grpc.CallOption) (*GetUserRatingsResponse, error)
+	GetTrending(ctx context.Context, in *GetTrendingRequest, opts ...grpc.CallOption) (*GetTrendingResponse, error)
+	ListRecordRatings(ctx context.Context, in *ListRecordRatingsRequest, opts ...grpc.CallOption) (*ListRecordRatingsResponse, error)
+	ListUserRatings(ctx context.Context, in *ListUserRatingsRequest, opts ...grpc.CallOption) (*ListUserRatingsResponse, error)
+	PutRating(ctx context.Context, in *PutRatingRequest, opts ...grpc.CallOption) (*PutRatingResponse, error)
+	MoveRatings(ctx context.Context, in *MoveRatingsRequest, opts ...grpc.CallOption) (*MoveRatingsResponse, error)
 }
 
-type metadataServiceClient struct {
+type ratingServiceClient struct {
 	cc grpc.ClientConnInterface
 }
 
-func NewMetadataServiceClient(cc grpc.ClientConnInterface) MetadataServiceClient {
-	return &metadataServiceClient{cc}
+func NewRatingServiceClient(cc grpc.ClientConnInterface) RatingServiceClient {
+	return &ratingServiceClient{cc}
 }
 
-func (c *metadataServiceClient) GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error) {
+func (c *ratingServiceClient) GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(GetMetadataResponse)
-	err := c.cc.Invoke(ctx, MetadataService_GetMetadata_FullMethodName, in, out, cOpts...)
+	out := new(GetAggregatedRatingResponse)
+	err := c.cc.Invoke(ctx, RatingService_GetAggregatedRating_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *metadataServiceClient) PutMetadata(ctx context.Context, in *PutMetadataRequest, opts ...grpc.CallOption) (*PutMetadataResponse, error) {
+func (c *ratingServiceClient) GetAggregatedRatings(ctx context.Context, in *GetAggregatedRatingsRequest, opts ...grpc.CallOption) (*GetAggregatedRatingsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(PutMetadataResponse)
-	err := c.cc.Invoke(ctx, MetadataService_PutMetadata_FullMethodName, in, out, cOpts...)
+	out := new(GetAggregatedRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_GetAggregatedRatings_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// MetadataServiceServer is the server API for MetadataService service.
-// All implementations must embed UnimplementedMetadataServiceServer
-// for forward compatibility.
-type MetadataServiceServer interface {
-	GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error)
-	PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error)
-	mustEmbedUnimplementedMetadataServiceServer()
-}
-
-// UnimplementedMetadataServiceServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedMetadataServiceServer struct{}
-
-func (UnimplementedMetadataServiceServer) GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetMetadata not implemented")
-}
-func (UnimplementedMetadataServiceServer) PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method PutMetadata not implemented")
-}
-func (UnimplementedMetadataServiceServer) mustEmbedUnimplementedMetadataServiceServer() {}
-func (UnimplementedMetadataServiceServer) testEmbeddedByValue()                         {}
-
-// UnsafeMetadataServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to MetadataServiceServer will
-// result in compilation errors.
-type UnsafeMetadataServiceServer interface {
-	mustEmbedUnimplementedMetadataServiceServer()
-}
-
-func RegisterMetadataServiceServer(s grpc.ServiceRegistrar, srv MetadataServiceServer) {
-	// If the following call pancis, it indicates UnimplementedMetadataServiceServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
+func (c *ratingServiceClient) GetUserRatings(ctx context.Context, in *GetUserRatingsRequest, opts ...grpc.CallOption) (*GetUserRatingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_GetUserRatings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
 	}
-	s.RegisterService(&MetadataService_ServiceDesc, srv)
+	return out, nil
 }
 
-func _MetadataService_GetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetMetadataRequest)
-	if err := dec(in); err != nil {
+func (c *ratingServiceClient) GetTrending(ctx context.Context, in *GetTrendingRequest, opts ...grpc.CallOption) (*GetTrendingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTrendingResponse)
+	err := c.cc.Invoke(ctx, RatingService_GetTrending_FullMethodName, in, out, cOpts...)
+	if err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
-		return srv.(MetadataServiceServer).GetMetadata(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MetadataService_GetMetadata_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MetadataServiceServer).GetMetadata(ctx, req.(*GetMetadataRequest))
-	}
-	return interceptor(ctx, in, info, handler)
+	return out, nil
 }
 
-func _MetadataService_PutMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PutMetadataRequest)
-	if err := dec(in); err != nil {
+func (c *ratingServiceClient) ListRecordRatings(ctx context.Context, in *ListRecordRatingsRequest, opts ...grpc.CallOption) (*ListRecordRatingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRecordRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListRecordRatings_FullMethodName, in, out, cOpts...)
+	if err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
-		return srv.(MetadataServiceServer).PutMetadata(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MetadataService_PutMetadata_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MetadataServiceServer).PutMetadata(ctx, req.(*PutMetadataRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-// MetadataService_ServiceDesc is the grpc.ServiceDesc for MetadataService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var MetadataService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "MetadataService",
-	HandlerType: (*MetadataServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "GetMetadata",
-			Handler:    _MetadataService_GetMetadata_Handler,
-		},
-		{
-			MethodName: "PutMetadata",
-			Handler:    _MetadataService_PutMetadata_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "movie.proto",
-}
-
-const (
-	RatingService_GetAggregatedRating_FullMethodName = "/RatingService/GetAggregatedRating"
-)
-
-// RatingServiceClient is the client API for RatingService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type RatingServiceClient interface {
-	GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error)
+	return out, nil
 }
 
-type ratingServiceClient struct {
-	cc grpc.ClientConnInterface
+func (c *ratingServiceClient) ListUserRatings(ctx context.Context, in *ListUserRatingsRequest, opts ...grpc.CallOption) (*ListUserRatingsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUserRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListUserRatings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func NewRatingServiceClient(cc grpc.ClientConnInterface) RatingServiceClient {
-	return &ratingServiceClient{cc}
+func (c *ratingServiceClient) PutRating(ctx context.Context, in *PutRatingRequest, opts ...grpc.CallOption) (*PutRatingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PutRatingResponse)
+	err := c.cc.Invoke(ctx, RatingService_PutRating_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (c *ratingServiceClient) GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error) {
+func (c *ratingServiceClient) MoveRatings(ctx context.Context, in *MoveRatingsRequest, opts ...grpc.CallOption) (*MoveRatingsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(GetAggregatedRatingResponse)
-	err := c.cc.Invoke(ctx, RatingService_GetAggregatedRating_FullMethodName, in, out, cOpts...)
+	out := new(MoveRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_MoveRatings_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +136,13 @@ func (c *ratingServiceClient) GetAggregatedRating(ctx context.Context, in *GetAg
 // for forward compatibility.
 type RatingServiceServer interface {
 	GetAggregatedRating(context.Context, *GetAggregatedRatingRequest) (*GetAggregatedRatingResponse, error)
+	GetAggregatedRatings(context.Context, *GetAggregatedRatingsRequest) (*GetAggregatedRatingsResponse, error)
+	GetUserRatings(context.Context, *GetUserRatingsRequest) (*GetUserRatingsResponse, error)
+	GetTrending(context.Context, *GetTrendingRequest) (*GetTrendingResponse, error)
+	ListRecordRatings(context.Context, *ListRecordRatingsRequest) (*ListRecordRatingsResponse, error)
+	ListUserRatings(context.Context, *ListUserRatingsRequest) (*ListUserRatingsResponse, error)
+	PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error)
+	MoveRatings(context.Context, *MoveRatingsRequest) (*MoveRatingsResponse, error)
 	mustEmbedUnimplementedRatingServiceServer()
 }
 
@@ -205,6 +156,27 @@ type UnimplementedRatingServiceServer struct{}
 func (UnimplementedRatingServiceServer) GetAggregatedRating(context.Context, *GetAggregatedRatingRequest) (*GetAggregatedRatingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAggregatedRating not implemented")
 }
+func (UnimplementedRatingServiceServer) GetAggregatedRatings(context.Context, *GetAggregatedRatingsRequest) (*GetAggregatedRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAggregatedRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) GetUserRatings(context.Context, *GetUserRatingsRequest) (*GetUserRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) GetTrending(context.Context, *GetTrendingRequest) (*GetTrendingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTrending not implemented")
+}
+func (UnimplementedRatingServiceServer) ListRecordRatings(context.Context, *ListRecordRatingsRequest) (*ListRecordRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRecordRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) ListUserRatings(context.Context, *ListUserRatingsRequest) (*ListUserRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutRating not implemented")
+}
+func (UnimplementedRatingServiceServer) MoveRatings(context.Context, *MoveRatingsRequest) (*MoveRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveRatings not implemented")
+}
 func (UnimplementedRatingServiceServer) mustEmbedUnimplementedRatingServiceServer() {}
 func (UnimplementedRatingServiceServer) testEmbeddedByValue()                       {}
 
@@ -244,6 +216,132 @@ func _RatingService_GetAggregatedRating_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RatingService_GetAggregatedRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAggregatedRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).GetAggregatedRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_GetAggregatedRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).GetAggregatedRatings(ctx, req.(*GetAggregatedRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_GetUserRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).GetUserRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_GetUserRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).GetUserRatings(ctx, req.(*GetUserRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_GetTrending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTrendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).GetTrending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_GetTrending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).GetTrending(ctx, req.(*GetTrendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListRecordRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRecordRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListRecordRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListRecordRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListRecordRatings(ctx, req.(*ListRecordRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListUserRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListUserRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListUserRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListUserRatings(ctx, req.(*ListUserRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_PutRating_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).PutRating(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_PutRating_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).PutRating(ctx, req.(*PutRatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_MoveRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).MoveRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_MoveRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).MoveRatings(ctx, req.(*MoveRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RatingService_ServiceDesc is the grpc.ServiceDesc for RatingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -255,13 +353,48 @@ var RatingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetAggregatedRating",
 			Handler:    _RatingService_GetAggregatedRating_Handler,
 		},
+		{
+			MethodName: "GetAggregatedRatings",
+			Handler:    _RatingService_GetAggregatedRatings_Handler,
+		},
+		{
+			MethodName: "GetUserRatings",
+			Handler:    _RatingService_GetUserRatings_Handler,
+		},
+		{
+			MethodName: "GetTrending",
+			Handler:    _RatingService_GetTrending_Handler,
+		},
+		{
+			MethodName: "ListRecordRatings",
+			Handler:    _RatingService_ListRecordRatings_Handler,
+		},
+		{
+			MethodName: "ListUserRatings",
+			Handler:    _RatingService_ListUserRatings_Handler,
+		},
+		{
+			MethodName: "PutRating",
+			Handler:    _RatingService_PutRating_Handler,
+		},
+		{
+			MethodName: "MoveRatings",
+			Handler:    _RatingService_MoveRatings_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
 }
 
 const (
-	MovieService_GetMovieDetails_FullMethodName = "/MovieService/GetMovieDetails"
+	MovieService_GetMovieDetails_FullMethodName         = "/MovieService/GetMovieDetails"
+	MovieService_GetManyMovieDetails_FullMethodName     = "/MovieService/GetManyMovieDetails"
+	MovieService_ListMovies_FullMethodName              = "/MovieService/ListMovies"
+	MovieService_GetTrendingMovies_FullMethodName       = "/MovieService/GetTrendingMovies"
+	MovieService_GetMovieRecommendations_FullMethodName = "/MovieService/GetMovieRecommendations"
+	MovieService_GetUserRecommendations_FullMethodName  = "/MovieService/GetUserRecommendations"
+	MovieService_SearchMovies_FullMethodName            = "/MovieService/SearchMovies"
+	MovieService_ExportMovieDetails_FullMethodName      = "/MovieService/ExportMovieDetails"
 )
 
 // MovieServiceClient is the client API for MovieService service.
@@ -269,6 +402,13 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MovieServiceClient interface {
 	GetMovieDetails(ctx context.Context, in *GetMovieDetailsRequest, opts ...grpc.CallOption) (*GetMovieDetailsResponse, error)
+	GetManyMovieDetails(ctx context.Context, in *GetManyMovieDetailsRequest, opts ...grpc.CallOption) (*GetManyMovieDetailsResponse, error)
+	ListMovies(ctx context.Context, in *ListMoviesRequest, opts ...grpc.CallOption) (*ListMoviesResponse, error)
+	GetTrendingMovies(ctx context.Context, in *GetTrendingMoviesRequest, opts ...grpc.CallOption) (*GetTrendingMoviesResponse, error)
+	GetMovieRecommendations(ctx context.Context, in *GetMovieRecommendationsRequest, opts ...grpc.CallOption) (*GetRecommendationsResponse, error)
+	GetUserRecommendations(ctx context.Context, in *GetUserRecommendationsRequest, opts ...grpc.CallOption) (*GetRecommendationsResponse, error)
+	SearchMovies(ctx context.Context, in *SearchMoviesRequest, opts ...grpc.CallOption) (*SearchMovieDetailsResponse, error)
+	ExportMovieDetails(ctx context.Context, in *ExportMovieDetailsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportMovieDetailsResponse], error)
 }
 
 type movieServiceClient struct {
@@ -289,11 +429,97 @@ func (c *movieServiceClient) GetMovieDetails(ctx context.Context, in *GetMovieDe
 	return out, nil
 }
 
+func (c *movieServiceClient) GetManyMovieDetails(ctx context.Context, in *GetManyMovieDetailsRequest, opts ...grpc.CallOption) (*GetManyMovieDetailsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetManyMovieDetailsResponse)
+	err := c.cc.Invoke(ctx, MovieService_GetManyMovieDetails_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) ListMovies(ctx context.Context, in *ListMoviesRequest, opts ...grpc.CallOption) (*ListMoviesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMoviesResponse)
+	err := c.cc.Invoke(ctx, MovieService_ListMovies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) GetTrendingMovies(ctx context.Context, in *GetTrendingMoviesRequest, opts ...grpc.CallOption) (*GetTrendingMoviesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTrendingMoviesResponse)
+	err := c.cc.Invoke(ctx, MovieService_GetTrendingMovies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) GetMovieRecommendations(ctx context.Context, in *GetMovieRecommendationsRequest, opts ...grpc.CallOption) (*GetRecommendationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRecommendationsResponse)
+	err := c.cc.Invoke(ctx, MovieService_GetMovieRecommendations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) GetUserRecommendations(ctx context.Context, in *GetUserRecommendationsRequest, opts ...grpc.CallOption) (*GetRecommendationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRecommendationsResponse)
+	err := c.cc.Invoke(ctx, MovieService_GetUserRecommendations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) SearchMovies(ctx context.Context, in *SearchMoviesRequest, opts ...grpc.CallOption) (*SearchMovieDetailsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchMovieDetailsResponse)
+	err := c.cc.Invoke(ctx, MovieService_SearchMovies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) ExportMovieDetails(ctx context.Context, in *ExportMovieDetailsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportMovieDetailsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MovieService_ServiceDesc.Streams[0], MovieService_ExportMovieDetails_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportMovieDetailsRequest, ExportMovieDetailsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MovieService_ExportMovieDetailsClient = grpc.ServerStreamingClient[ExportMovieDetailsResponse]
+
 // MovieServiceServer is the server API for MovieService service.
 // All implementations must embed UnimplementedMovieServiceServer
 // for forward compatibility.
 type MovieServiceServer interface {
 	GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error)
+	GetManyMovieDetails(context.Context, *GetManyMovieDetailsRequest) (*GetManyMovieDetailsResponse, error)
+	ListMovies(context.Context, *ListMoviesRequest) (*ListMoviesResponse, error)
+	GetTrendingMovies(context.Context, *GetTrendingMoviesRequest) (*GetTrendingMoviesResponse, error)
+	GetMovieRecommendations(context.Context, *GetMovieRecommendationsRequest) (*GetRecommendationsResponse, error)
+	GetUserRecommendations(context.Context, *GetUserRecommendationsRequest) (*GetRecommendationsResponse, error)
+	SearchMovies(context.Context, *SearchMoviesRequest) (*SearchMovieDetailsResponse, error)
+	ExportMovieDetails(*ExportMovieDetailsRequest, grpc.ServerStreamingServer[ExportMovieDetailsResponse]) error
 	mustEmbedUnimplementedMovieServiceServer()
 }
 
@@ -307,6 +533,27 @@ type UnimplementedMovieServiceServer struct{}
 func (UnimplementedMovieServiceServer) GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMovieDetails not implemented")
 }
+func (UnimplementedMovieServiceServer) GetManyMovieDetails(context.Context, *GetManyMovieDetailsRequest) (*GetManyMovieDetailsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetManyMovieDetails not implemented")
+}
+func (UnimplementedMovieServiceServer) ListMovies(context.Context, *ListMoviesRequest) (*ListMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMovies not implemented")
+}
+func (UnimplementedMovieServiceServer) GetTrendingMovies(context.Context, *GetTrendingMoviesRequest) (*GetTrendingMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTrendingMovies not implemented")
+}
+func (UnimplementedMovieServiceServer) GetMovieRecommendations(context.Context, *GetMovieRecommendationsRequest) (*GetRecommendationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMovieRecommendations not implemented")
+}
+func (UnimplementedMovieServiceServer) GetUserRecommendations(context.Context, *GetUserRecommendationsRequest) (*GetRecommendationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserRecommendations not implemented")
+}
+func (UnimplementedMovieServiceServer) SearchMovies(context.Context, *SearchMoviesRequest) (*SearchMovieDetailsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMovies not implemented")
+}
+func (UnimplementedMovieServiceServer) ExportMovieDetails(*ExportMovieDetailsRequest, grpc.ServerStreamingServer[ExportMovieDetailsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportMovieDetails not implemented")
+}
 func (UnimplementedMovieServiceServer) mustEmbedUnimplementedMovieServiceServer() {}
 func (UnimplementedMovieServiceServer) testEmbeddedByValue()                      {}
 
@@ -346,6 +593,125 @@ func _MovieService_GetMovieDetails_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MovieService_GetManyMovieDetails_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetManyMovieDetailsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).GetManyMovieDetails(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_GetManyMovieDetails_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).GetManyMovieDetails(ctx, req.(*GetManyMovieDetailsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_ListMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).ListMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_ListMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).ListMovies(ctx, req.(*ListMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_GetTrendingMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTrendingMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).GetTrendingMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_GetTrendingMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).GetTrendingMovies(ctx, req.(*GetTrendingMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_GetMovieRecommendations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMovieRecommendationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).GetMovieRecommendations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_GetMovieRecommendations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).GetMovieRecommendations(ctx, req.(*GetMovieRecommendationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_GetUserRecommendations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRecommendationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).GetUserRecommendations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_GetUserRecommendations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).GetUserRecommendations(ctx, req.(*GetUserRecommendationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_SearchMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).SearchMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_SearchMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).SearchMovies(ctx, req.(*SearchMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_ExportMovieDetails_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportMovieDetailsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MovieServiceServer).ExportMovieDetails(m, &grpc.GenericServerStream[ExportMovieDetailsRequest, ExportMovieDetailsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MovieService_ExportMovieDetailsServer = grpc.ServerStreamingServer[ExportMovieDetailsResponse]
+
 // MovieService_ServiceDesc is the grpc.ServiceDesc for MovieService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -357,7 +723,37 @@ var MovieService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMovieDetails",
 			Handler:    _MovieService_GetMovieDetails_Handler,
 		},
+		{
+			MethodName: "GetManyMovieDetails",
+			Handler:    _MovieService_GetManyMovieDetails_Handler,
+		},
+		{
+			MethodName: "ListMovies",
+			Handler:    _MovieService_ListMovies_Handler,
+		},
+		{
+			MethodName: "GetTrendingMovies",
+			Handler:    _MovieService_GetTrendingMovies_Handler,
+		},
+		{
+			MethodName: "GetMovieRecommendations",
+			Handler:    _MovieService_GetMovieRecommendations_Handler,
+		},
+		{
+			MethodName: "GetUserRecommendations",
+			Handler:    _MovieService_GetUserRecommendations_Handler,
+		},
+		{
+			MethodName: "SearchMovies",
+			Handler:    _MovieService_SearchMovies_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportMovieDetails",
+			Handler:       _MovieService_ExportMovieDetails_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
 }
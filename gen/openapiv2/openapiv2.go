@@ -0,0 +1,12 @@
+// Package openapiv2 embeds the OpenAPI v2 specs generated by
+// protoc-gen-openapiv2 from the google.api.http annotations of the
+// gRPC services.
+package openapiv2
+
+import "embed"
+
+// FS holds the specs, one per proto file, such as
+// movie.swagger.json.
+//
+//go:embed *.swagger.json
+var FS embed.FS
@@ -0,0 +1,235 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.12.4
+// source: watchlist.proto
+
+package gen
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WatchlistService_AddToWatchlist_FullMethodName      = "/WatchlistService/AddToWatchlist"
+	WatchlistService_RemoveFromWatchlist_FullMethodName = "/WatchlistService/RemoveFromWatchlist"
+	WatchlistService_ListWatchlist_FullMethodName       = "/WatchlistService/ListWatchlist"
+	WatchlistService_GetWatchlisted_FullMethodName      = "/WatchlistService/GetWatchlisted"
+)
+
+// WatchlistServiceClient is the client API for WatchlistService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WatchlistServiceClient interface {
+	AddToWatchlist(ctx context.Context, in *AddToWatchlistRequest, opts ...grpc.CallOption) (*AddToWatchlistResponse, error)
+	RemoveFromWatchlist(ctx context.Context, in *RemoveFromWatchlistRequest, opts ...grpc.CallOption) (*RemoveFromWatchlistResponse, error)
+	ListWatchlist(ctx context.Context, in *ListWatchlistRequest, opts ...grpc.CallOption) (*ListWatchlistResponse, error)
+	GetWatchlisted(ctx context.Context, in *GetWatchlistedRequest, opts ...grpc.CallOption) (*GetWatchlistedResponse, error)
+}
+
+type watchlistServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWatchlistServiceClient(cc grpc.ClientConnInterface) WatchlistServiceClient {
+	return &watchlistServiceClient{cc}
+}
+
+func (c *watchlistServiceClient) AddToWatchlist(ctx context.Context, in *AddToWatchlistRequest, opts ...grpc.CallOption) (*AddToWatchlistResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddToWatchlistResponse)
+	err := c.cc.Invoke(ctx, WatchlistService_AddToWatchlist_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchlistServiceClient) RemoveFromWatchlist(ctx context.Context, in *RemoveFromWatchlistRequest, opts ...grpc.CallOption) (*RemoveFromWatchlistResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveFromWatchlistResponse)
+	err := c.cc.Invoke(ctx, WatchlistService_RemoveFromWatchlist_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchlistServiceClient) ListWatchlist(ctx context.Context, in *ListWatchlistRequest, opts ...grpc.CallOption) (*ListWatchlistResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListWatchlistResponse)
+	err := c.cc.Invoke(ctx, WatchlistService_ListWatchlist_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchlistServiceClient) GetWatchlisted(ctx context.Context, in *GetWatchlistedRequest, opts ...grpc.CallOption) (*GetWatchlistedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetWatchlistedResponse)
+	err := c.cc.Invoke(ctx, WatchlistService_GetWatchlisted_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WatchlistServiceServer is the server API for WatchlistService service.
+// All implementations must embed UnimplementedWatchlistServiceServer
+// for forward compatibility.
+type WatchlistServiceServer interface {
+	AddToWatchlist(context.Context, *AddToWatchlistRequest) (*AddToWatchlistResponse, error)
+	RemoveFromWatchlist(context.Context, *RemoveFromWatchlistRequest) (*RemoveFromWatchlistResponse, error)
+	ListWatchlist(context.Context, *ListWatchlistRequest) (*ListWatchlistResponse, error)
+	GetWatchlisted(context.Context, *GetWatchlistedRequest) (*GetWatchlistedResponse, error)
+	mustEmbedUnimplementedWatchlistServiceServer()
+}
+
+// UnimplementedWatchlistServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWatchlistServiceServer struct{}
+
+func (UnimplementedWatchlistServiceServer) AddToWatchlist(context.Context, *AddToWatchlistRequest) (*AddToWatchlistResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddToWatchlist not implemented")
+}
+func (UnimplementedWatchlistServiceServer) RemoveFromWatchlist(context.Context, *RemoveFromWatchlistRequest) (*RemoveFromWatchlistResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFromWatchlist not implemented")
+}
+func (UnimplementedWatchlistServiceServer) ListWatchlist(context.Context, *ListWatchlistRequest) (*ListWatchlistResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWatchlist not implemented")
+}
+func (UnimplementedWatchlistServiceServer) GetWatchlisted(context.Context, *GetWatchlistedRequest) (*GetWatchlistedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWatchlisted not implemented")
+}
+func (UnimplementedWatchlistServiceServer) mustEmbedUnimplementedWatchlistServiceServer() {}
+func (UnimplementedWatchlistServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeWatchlistServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WatchlistServiceServer will
+// result in compilation errors.
+type UnsafeWatchlistServiceServer interface {
+	mustEmbedUnimplementedWatchlistServiceServer()
+}
+
+func RegisterWatchlistServiceServer(s grpc.ServiceRegistrar, srv WatchlistServiceServer) {
+	// If the following call pancis, it indicates UnimplementedWatchlistServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WatchlistService_ServiceDesc, srv)
+}
+
+func _WatchlistService_AddToWatchlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddToWatchlistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchlistServiceServer).AddToWatchlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchlistService_AddToWatchlist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchlistServiceServer).AddToWatchlist(ctx, req.(*AddToWatchlistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchlistService_RemoveFromWatchlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveFromWatchlistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchlistServiceServer).RemoveFromWatchlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchlistService_RemoveFromWatchlist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchlistServiceServer).RemoveFromWatchlist(ctx, req.(*RemoveFromWatchlistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchlistService_ListWatchlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWatchlistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchlistServiceServer).ListWatchlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchlistService_ListWatchlist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchlistServiceServer).ListWatchlist(ctx, req.(*ListWatchlistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WatchlistService_GetWatchlisted_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWatchlistedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchlistServiceServer).GetWatchlisted(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WatchlistService_GetWatchlisted_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchlistServiceServer).GetWatchlisted(ctx, req.(*GetWatchlistedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WatchlistService_ServiceDesc is the grpc.ServiceDesc for WatchlistService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WatchlistService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "WatchlistService",
+	HandlerType: (*WatchlistServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddToWatchlist",
+			Handler:    _WatchlistService_AddToWatchlist_Handler,
+		},
+		{
+			MethodName: "RemoveFromWatchlist",
+			Handler:    _WatchlistService_RemoveFromWatchlist_Handler,
+		},
+		{
+			MethodName: "ListWatchlist",
+			Handler:    _WatchlistService_ListWatchlist_Handler,
+		},
+		{
+			MethodName: "GetWatchlisted",
+			Handler:    _WatchlistService_GetWatchlisted_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "watchlist.proto",
+}
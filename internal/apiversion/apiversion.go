@@ -0,0 +1,117 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Header is the request header negotiating the API version of an
+// unversioned path, such as v2, and the response header of the
+// served version.
+const Header = "API-Version"
+
+// Router serves the handlers of several API versions side by side
+// under version path prefixes such as /v1. A version serves the
+// routes it does not change with the handlers of the versions
+// before it, so that a new version only registers the handlers of
+// the routes whose requests or responses change. Unversioned paths
+// are served by the version negotiated with the Header, or the
+// default version, so that clients predating versioning keep
+// working.
+type Router struct {
+	defaultVersion string
+	versions       []string
+	muxes          map[string]*http.ServeMux
+}
+
+// NewRouter creates a new router serving unversioned paths with
+// the default version unless negotiated otherwise.
+func NewRouter(defaultVersion string) *Router {
+	return &Router{defaultVersion: defaultVersion, muxes: map[string]*http.ServeMux{}}
+}
+
+// Version returns the mux of the handlers of a version, such as
+// v1, adding the version if new. Versions must be added from the
+// oldest to the newest.
+func (r *Router) Version(version string) *http.ServeMux {
+	mux, ok := r.muxes[version]
+	if !ok {
+		mux = http.NewServeMux()
+		r.muxes[version] = mux
+		r.versions = append(r.versions, version)
+	}
+	return mux
+}
+
+// ServeHTTP serves a request with the handler of its version.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	version, path, negotiated := r.route(req)
+	if negotiated {
+		w.Header().Add("Vary", Header)
+	}
+	i := slices.Index(r.versions, version)
+	if i < 0 {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	versioned := new(http.Request)
+	*versioned = *req
+	versioned.URL = new(url.URL)
+	*versioned.URL = *req.URL
+	versioned.URL.Path = path
+	versioned.URL.RawPath = ""
+	for ; i >= 0; i-- {
+		if h, pattern := r.muxes[r.versions[i]].Handler(versioned); pattern != "" {
+			w.Header().Set(Header, version)
+			h.ServeHTTP(w, versioned)
+			return
+		}
+	}
+	http.NotFound(w, req)
+}
+
+// Pattern returns the pattern of the route serving a request, such
+// as /movies/, without its version, or an empty string if no
+// version serves it.
+func (r *Router) Pattern(req *http.Request) string {
+	version, path, _ := r.route(req)
+	i := slices.Index(r.versions, version)
+	if i < 0 {
+		return ""
+	}
+	versioned := &http.Request{Method: req.Method, Host: req.Host, URL: &url.URL{Path: path}}
+	for ; i >= 0; i-- {
+		if _, pattern := r.muxes[r.versions[i]].Handler(versioned); pattern != "" {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// route returns the version of a request and its path without the
+// version prefix, and whether the version is negotiated rather
+// than in the path.
+func (r *Router) route(req *http.Request) (string, string, bool) {
+	prefix, rest, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if _, ok := r.muxes[prefix]; ok {
+		return prefix, "/" + rest, false
+	}
+	if v := req.Header.Get(Header); v != "" {
+		if !strings.HasPrefix(v, "v") {
+			v = "v" + v
+		}
+		return v, req.URL.Path, true
+	}
+	return r.defaultVersion, req.URL.Path, true
+}
+
+// Unversioned returns a path without its version prefix, if any.
+func Unversioned(path string) string {
+	prefix, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if len(prefix) < 2 || prefix[0] != 'v' || strings.Trim(prefix[1:], "0123456789") != "" {
+		return path
+	}
+	return "/" + rest
+}
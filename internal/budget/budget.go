@@ -0,0 +1,72 @@
+package budget
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header carries the remaining latency budget of a request in
+// milliseconds to downstream HTTP services. gRPC calls carry it
+// in their deadline instead.
+const Header = "X-Request-Budget"
+
+// WithBudget returns a context whose deadline is the given budget
+// from now, unless the context already has an earlier one. A
+// non-positive budget leaves the context as is.
+func WithBudget(ctx context.Context, budget time.Duration) (context.Context, context.CancelFunc) {
+	if budget <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, budget)
+}
+
+// Split returns a context for a downstream call that may spend
+// the given share of the remaining budget, and at most max. The
+// rest stays with the caller, so that a slow downstream cannot
+// leave no time for the calls after it or for the response.
+// Without a deadline, the call gets max.
+func Split(ctx context.Context, share float64, max time.Duration) (context.Context, context.CancelFunc) {
+	timeout := max
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(max, time.Duration(share*float64(time.Until(deadline))))
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// SetHeader sets the remaining budget of the context on an
+// outgoing request.
+func SetHeader(req *http.Request) {
+	if deadline, ok := req.Context().Deadline(); ok {
+		req.Header.Set(Header, strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
+}
+
+// Handler applies the budget of incoming requests set in the
+// Header, or the default budget if unset, to their contexts.
+func Handler(next http.Handler, defaultBudget time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		base := context.WithValue(req.Context(), unboundedKey{}, req.Context())
+		ctx, cancel := WithBudget(base, defaultBudget)
+		if ms, err := strconv.ParseInt(req.Header.Get(Header), 10, 64); err == nil {
+			cancel()
+			// A spent budget fails the downstream calls at once.
+			ctx, cancel = context.WithTimeout(base, time.Duration(ms)*time.Millisecond)
+		}
+		defer cancel()
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+type unboundedKey struct{}
+
+// Unbounded returns the context of a request served by Handler
+// before its budget was applied, for long running requests such as
+// streams. It is canceled only when the request is.
+func Unbounded(ctx context.Context) context.Context {
+	if v, ok := ctx.Value(unboundedKey{}).(context.Context); ok {
+		return v
+	}
+	return ctx
+}
@@ -0,0 +1,245 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Config defines which responses are compressed.
+type Config struct {
+	// MinSize is the size of the smallest compressed response,
+	// as compressing smaller ones saves less than it costs.
+	MinSize int
+	// ContentTypes are the media types of compressed responses,
+	// matched by prefix so that text/ matches all text types.
+	ContentTypes []string
+}
+
+// DefaultConfig returns the default config, compressing text and
+// JSON responses of at least 1 KiB.
+func DefaultConfig() Config {
+	return Config{
+		MinSize:      1024,
+		ContentTypes: []string{"application/json", "application/javascript", "application/xml", "image/svg+xml", "text/"},
+	}
+}
+
+// brotliLevel trades ratio for speed, as responses are compressed
+// on every request rather than once ahead of time.
+const brotliLevel = 4
+
+var (
+	gzipWriters   = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	brotliWriters = sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, brotliLevel) }}
+)
+
+// Handler compresses the responses of the next handler with
+// brotli or gzip, whichever the client accepts and prefers, if
+// their content types are compressible and they reach the
+// minimum size. Flushed responses, such as streams, are
+// compressed regardless of their size.
+func Handler(next http.Handler, config Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		encoding := negotiate(req.Header.Get("Accept-Encoding"))
+		if req.Method == http.MethodHead || req.Header.Get("Range") != "" {
+			encoding = ""
+		}
+		cw := &writer{ResponseWriter: w, config: config, encoding: encoding, status: http.StatusOK}
+		defer cw.close()
+		next.ServeHTTP(cw, req)
+	})
+}
+
+// negotiate returns the preferred encoding in an Accept-Encoding
+// header, empty if neither brotli nor gzip is acceptable. Brotli
+// wins ties as it compresses better.
+func negotiate(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "br" && name != "gzip" && name != "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if name == "*" {
+			name = "br"
+		}
+		if q > bestQ || q == bestQ && name == "br" {
+			best, bestQ = name, q
+		}
+	}
+	if bestQ == 0 {
+		return ""
+	}
+	return best
+}
+
+// writer buffers the start of a response until it knows whether
+// to compress it.
+type writer struct {
+	http.ResponseWriter
+	config   Config
+	encoding string
+	status   int
+	// wroteHeader is set once the status is passed to the client,
+	// from then on the response is written to enc if set.
+	wroteHeader bool
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+func (w *writer) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+	}
+	// Informational responses precede the final one.
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if w.wroteHeader {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.config.MinSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends the buffered response, compressing it if the
+// content type is compressible.
+func (w *writer) Flush() {
+	if !w.wroteHeader {
+		if err := w.start(true); err != nil {
+			return
+		}
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the next handler take over the connection, e.g.
+// for WebSockets, which are never compressed.
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		// The connection is no longer a response to finish.
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start writes the status and the buffer, compressing the rest
+// of the response if it may and should be.
+func (w *writer) start(compress bool) error {
+	w.wroteHeader = true
+	h := w.Header()
+	if w.compressible() {
+		h.Add("Vary", "Accept-Encoding")
+		if compress && w.encoding != "" {
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+			switch w.encoding {
+			case "br":
+				bw := brotliWriters.Get().(*brotli.Writer)
+				bw.Reset(w.ResponseWriter)
+				w.enc = bw
+			case "gzip":
+				gw := gzipWriters.Get().(*gzip.Writer)
+				gw.Reset(w.ResponseWriter)
+				w.enc = gw
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *writer) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || w.status < 200 || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		// Like net/http, which sniffs the type of the first write.
+		if len(w.buf) == 0 {
+			return false
+		}
+		ct = http.DetectContentType(w.buf)
+		h.Set("Content-Type", ct)
+	}
+	for _, t := range w.config.ContentTypes {
+		if strings.HasPrefix(ct, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// close sends a response smaller than the minimum size as is and
+// finishes a compressed one.
+func (w *writer) close() {
+	if !w.wroteHeader {
+		w.start(false)
+	}
+	if w.enc == nil {
+		return
+	}
+	w.enc.Close()
+	switch enc := w.enc.(type) {
+	case *brotli.Writer:
+		enc.Reset(nil)
+		brotliWriters.Put(enc)
+	case *gzip.Writer:
+		enc.Reset(nil)
+		gzipWriters.Put(enc)
+	}
+}
@@ -2,18 +2,51 @@ package grpcutil
 
 import (
 	"context"
+	"crypto/tls"
 	"math/rand"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"movieapp.com/pkg/discovery"
+	"movieapp.com/pkg/grpcmw"
 )
 
+// transportCredentials secure the connections of the gRPC clients,
+// in plaintext unless SetTLS is called.
+var transportCredentials = insecure.NewCredentials()
+
+// SetTLS secures the connections of the gRPC clients created
+// afterwards with the TLS config, such as the mutual TLS of
+// mtls.Credentials.Client.
+func SetTLS(cfg *tls.Config) {
+	transportCredentials = credentials.NewTLS(cfg)
+}
+
 // ServiceConnection attempts to select a random service instance and returns a gRPC connection to it.
 func ServiceConnection(ctx context.Context, serviceName string, registry discovery.Registry) (*grpc.ClientConn, error) {
 	addrs, err := registry.ServiceAddresses(ctx, serviceName)
 	if err != nil {
 		return nil, err
 	}
-	return grpc.Dial(addrs[rand.Intn(len(addrs))], grpc.WithTransportCredentials(insecure.NewCredentials()))
+	return grpc.Dial(addrs[rand.Intn(len(addrs))], grpcmw.DialOptions(transportCredentials, grpcmw.DefaultTimeout)...)
+}
+
+// NewInstanceClient creates a gRPC client connection to a single
+// service instance, such as one of the addresses of a service in
+// the registry, for calls that act on the state of each instance.
+func NewInstanceClient(addr string) (*grpc.ClientConn, error) {
+	return grpc.NewClient(addr, grpcmw.DialOptions(transportCredentials, grpcmw.DefaultTimeout)...)
+}
+
+// Retryable reports whether a gRPC call failed transiently, such
+// that repeating an idempotent call may succeed.
+func Retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,109 @@
+package grpcutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"movieapp.com/pkg/discovery"
+	"movieapp.com/pkg/grpcmw"
+)
+
+const (
+	resolverScheme = "discovery"
+	// resolveInterval bounds how long newly registered or
+	// deregistered instances go unnoticed by a client.
+	resolveInterval = 5 * time.Second
+)
+
+// NewClient creates a gRPC client connection to a service whose
+// instances are resolved through the registry. Unlike
+// ServiceConnection, the connection is meant to be reused for
+// all calls: it follows instances joining and leaving and
+// balances calls across them in round robin.
+func NewClient(serviceName string, registry discovery.Registry) (*grpc.ClientConn, error) {
+	opts := append(grpcmw.DialOptions(transportCredentials, grpcmw.DefaultTimeout),
+		grpc.WithResolvers(&discoveryBuilder{registry}),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin": {}}]}`))
+	return grpc.NewClient(resolverScheme+":///"+serviceName, opts...)
+}
+
+// discoveryBuilder builds resolvers of service names to the
+// addresses of the service instances in a registry.
+type discoveryBuilder struct {
+	registry discovery.Registry
+}
+
+func (b *discoveryBuilder) Scheme() string {
+	return resolverScheme
+}
+
+func (b *discoveryBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	service := target.Endpoint()
+	if service == "" {
+		return nil, fmt.Errorf("empty service name in target %q", target.URL.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &discoveryResolver{
+		registry: b.registry,
+		service:  service,
+		cc:       cc,
+		cancel:   cancel,
+		now:      make(chan struct{}, 1),
+	}
+	go r.watch(ctx)
+	return r, nil
+}
+
+// discoveryResolver polls the registry for the addresses of a
+// service, and again whenever gRPC asks to re-resolve, e.g. after
+// a connection to an instance failed.
+type discoveryResolver struct {
+	registry discovery.Registry
+	service  string
+	cc       resolver.ClientConn
+	cancel   context.CancelFunc
+	now      chan struct{}
+}
+
+func (r *discoveryResolver) watch(ctx context.Context) {
+	for {
+		r.resolve(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.now:
+		case <-time.After(resolveInterval):
+		}
+	}
+}
+
+func (r *discoveryResolver) resolve(ctx context.Context) {
+	addrs, err := r.registry.ServiceAddresses(ctx, r.service)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.cc.ReportError(err)
+		}
+		return
+	}
+	state := resolver.State{Addresses: make([]resolver.Address, 0, len(addrs))}
+	for _, addr := range addrs {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+	}
+	// A rejected state, e.g. without any addresses, is retried
+	// with the next poll.
+	_ = r.cc.UpdateState(state)
+}
+
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+func (r *discoveryResolver) Close() {
+	r.cancel()
+}
@@ -0,0 +1,44 @@
+package grpcutil
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"movieapp.com/internal/tracing"
+)
+
+// RegisterFunc registers the REST handlers of a gRPC service on a
+// mux, calling the service at the endpoint. The generated
+// Register*HandlerFromEndpoint functions are RegisterFuncs.
+type RegisterFunc func(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error
+
+// forwardedHeaders are the request headers passed to the gRPC
+// services as metadata besides the ones grpc-gateway passes.
+var forwardedHeaders = map[string]bool{
+	"x-user-id":    true,
+	"x-request-id": true,
+	"x-tenant-id":  true,
+}
+
+// RESTHandler returns a handler serving the REST mapping of the
+// google.api.http annotations of gRPC services, transcoding the
+// requests to calls of the gRPC server at the address. The same
+// gRPC handlers thus serve both APIs.
+func RESTHandler(ctx context.Context, addr string, register ...RegisterFunc) (http.Handler, error) {
+	mux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+		if k := strings.ToLower(key); forwardedHeaders[k] {
+			return k, true
+		}
+		return runtime.DefaultHeaderMatcher(key)
+	}))
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(transportCredentials), tracing.DialOption()}
+	for _, r := range register {
+		if err := r(ctx, mux, addr, opts); err != nil {
+			return nil, err
+		}
+	}
+	return mux, nil
+}
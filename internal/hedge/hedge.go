@@ -0,0 +1,142 @@
+package hedge
+
+import (
+	"context"
+	"expvar"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// window is the number of recent latencies the hedge delay is
+	// computed from.
+	window = 256
+	// minSamples is the number of latencies needed before the
+	// delay follows them instead of the initial delay.
+	minSamples = 20
+	percentile = 0.95
+)
+
+var stats = expvar.NewMap("hedged_calls")
+
+// Set holds the hedgers of the calls to a downstream service, one
+// for each call so that the delay of a cheap call does not follow
+// the latency of an expensive one.
+type Set struct {
+	name         string
+	initialDelay time.Duration
+
+	mu      sync.Mutex
+	hedgers map[string]*Hedger
+}
+
+// NewSet creates a new set of hedgers of a downstream service,
+// waiting the initial delay before hedging a call until enough of
+// its latencies are known.
+func NewSet(name string, initialDelay time.Duration) *Set {
+	return &Set{name: name, initialDelay: initialDelay, hedgers: map[string]*Hedger{}}
+}
+
+// For returns the hedger of a call. A nil set returns a nil
+// hedger, which does not hedge.
+func (s *Set) For(call string) *Hedger {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.hedgers[call]
+	if !ok {
+		h = &Hedger{name: s.name + "." + call, delay: s.initialDelay}
+		s.hedgers[call] = h
+	}
+	return h
+}
+
+// Hedger tracks the latency of a call and hedges the calls slower
+// than its 95th percentile.
+type Hedger struct {
+	name string
+
+	mu        sync.Mutex
+	latencies [window]time.Duration
+	n         int
+	delay     time.Duration
+}
+
+// Delay returns the time a call is waited for before hedging it.
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delay
+}
+
+func (h *Hedger) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latencies[h.n%window] = latency
+	h.n++
+	// Sorting on every call is not worth it, the percentile moves
+	// slowly.
+	if h.n >= minSamples && h.n%(minSamples/2) == 0 {
+		s := slices.Clone(h.latencies[:min(h.n, window)])
+		slices.Sort(s)
+		h.delay = s[int(percentile*float64(len(s)-1))]
+	}
+}
+
+type result[T any] struct {
+	v       T
+	err     error
+	attempt int
+}
+
+// Do calls fn and, if it has not returned after the delay of the
+// hedger, calls it again with the next attempt number, which fn
+// may use to pick another instance. The first success is returned
+// and the other call is canceled. If both fail, the last error is
+// returned. A failure within the delay is returned without
+// hedging, leaving retries to the caller. Only idempotent calls
+// may be hedged; a nil hedger calls fn once.
+func Do[T any](ctx context.Context, h *Hedger, fn func(ctx context.Context, attempt int) (T, error)) (T, error) {
+	if h == nil {
+		return fn(ctx, 0)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Buffered so that the call losing the race does not block.
+	results := make(chan result[T], 2)
+	call := func(attempt int) {
+		start := time.Now()
+		v, err := fn(ctx, attempt)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+		results <- result[T]{v, err, attempt}
+	}
+	go call(0)
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			stats.Add(h.name+".hedged", 1)
+			pending++
+			go call(1)
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if r.attempt == 1 {
+					stats.Add(h.name+".won", 1)
+				}
+				return r.v, nil
+			}
+			if pending == 0 {
+				timer.Stop()
+				return r.v, r.err
+			}
+		}
+	}
+}
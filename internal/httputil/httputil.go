@@ -0,0 +1,85 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ServiceTransport is the transport of the calls to other services,
+// in plaintext unless SetTLS is called.
+var ServiceTransport = http.DefaultTransport.(*http.Transport).Clone()
+
+// ServiceScheme is the URL scheme of the calls to other services.
+var ServiceScheme = "http"
+
+// SetTLS secures the calls to other services with the TLS config,
+// such as the mutual TLS of mtls.Credentials.Client, calling them
+// over HTTPS. It must be called before any call.
+func SetTLS(cfg *tls.Config) {
+	ServiceTransport.TLSClientConfig = cfg
+	ServiceScheme = "https"
+}
+
+// ETagMatches reports whether an If-None-Match header value
+// matches the given entity tag, using the weak comparison
+// required for If-None-Match.
+func ETagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusError is returned for unexpected response status codes.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("non-2xx response: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Retryable reports whether an HTTP call failed transiently, due
+// to a network error or an overloaded or unavailable server, such
+// that repeating an idempotent call may succeed.
+func Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// BufferBody reads the body of a response into memory, so that it
+// stays readable after the context of the request is canceled.
+func BufferBody(resp *http.Response) (*http.Response, error) {
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	return resp, nil
+}
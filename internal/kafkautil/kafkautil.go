@@ -0,0 +1,116 @@
+package kafkautil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// SASL mechanisms.
+const (
+	MechanismPlain       = "plain"
+	MechanismSCRAMSHA512 = "scram-sha-512"
+)
+
+// SASLConfig defines the SASL authentication of the Kafka clients
+// of a service, none if the username is empty. The password
+// usually references a secret, such as ${secret:kafka-password}.
+type SASLConfig struct {
+	Mechanism string `yaml:"mechanism"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+// Validate returns an error if the mechanism is not supported.
+func (c *SASLConfig) Validate() error {
+	if c.Username == "" {
+		return nil
+	}
+	switch c.Mechanism {
+	case MechanismPlain, MechanismSCRAMSHA512:
+		return nil
+	}
+	return fmt.Errorf("mechanism: invalid mechanism %q", c.Mechanism)
+}
+
+// Credentials authenticate the Kafka clients of a service with
+// SASL. Credentials may be rotated while in use, connections made
+// afterwards authenticating with the new ones. Nil credentials do
+// not authenticate.
+type Credentials struct {
+	mechanism string
+	current   atomic.Pointer[sasl.Mechanism]
+	transport *kafka.Transport
+}
+
+// NewCredentials creates the credentials of the config, nil if it
+// has no username.
+func NewCredentials(cfg SASLConfig) (*Credentials, error) {
+	if cfg.Username == "" {
+		return nil, nil
+	}
+	c := &Credentials{mechanism: cfg.Mechanism}
+	if err := c.Set(cfg.Username, cfg.Password); err != nil {
+		return nil, err
+	}
+	c.transport = &kafka.Transport{SASL: c}
+	return c, nil
+}
+
+// Set rotates the username and password, closing idle connections
+// so that they reconnect with them.
+func (c *Credentials) Set(username string, password string) error {
+	var m sasl.Mechanism
+	switch c.mechanism {
+	case MechanismPlain:
+		m = plain.Mechanism{Username: username, Password: password}
+	case MechanismSCRAMSHA512:
+		var err error
+		if m, err = scram.Mechanism(scram.SHA512, username, password); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unsupported SASL mechanism " + c.mechanism)
+	}
+	c.current.Store(&m)
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Name returns the name of the SASL mechanism.
+func (c *Credentials) Name() string {
+	return (*c.current.Load()).Name()
+}
+
+// Start starts a SASL authentication with the current username
+// and password.
+func (c *Credentials) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	return (*c.current.Load()).Start(ctx)
+}
+
+// Transport returns the transport of Kafka writers, nil for the
+// default one if the credentials are nil.
+func (c *Credentials) Transport() kafka.RoundTripper {
+	if c == nil {
+		return nil
+	}
+	return c.transport
+}
+
+// Dialer returns the dialer of Kafka readers, nil for the default
+// one if the credentials are nil.
+func (c *Credentials) Dialer() *kafka.Dialer {
+	if c == nil {
+		return nil
+	}
+	return &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true, SASLMechanism: c}
+}
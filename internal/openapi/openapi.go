@@ -0,0 +1,258 @@
+// Package openapi serves the OpenAPI v2 specs of the REST APIs of
+// the services, generated from the google.api.http annotations of
+// their gRPC services, the hand-maintained specs of their
+// hand-written HTTP APIs, and a Swagger UI browsing them.
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"movieapp.com/gen/openapiv2"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+)
+
+var logger = logging.New("openapi")
+
+// Path is the path specs are served at.
+const Path = "/openapi.json"
+
+// httpSpecs holds the hand-maintained specs of the hand-written HTTP
+// APIs, such as http/movie.json, which have no annotations to
+// generate them from. They must be updated with the routes.
+//
+//go:embed http/*.json
+var httpSpecs embed.FS
+
+// Spec defines an OpenAPI v2 spec. Operations and definitions are
+// kept as generated.
+type Spec struct {
+	Swagger  string   `json:"swagger"`
+	Info     Info     `json:"info"`
+	Tags     []Tag    `json:"tags,omitempty"`
+	Consumes []string `json:"consumes,omitempty"`
+	Produces []string `json:"produces,omitempty"`
+	// Paths maps the paths to their operations by method.
+	Paths       map[string]map[string]json.RawMessage `json:"paths"`
+	Definitions map[string]json.RawMessage            `json:"definitions,omitempty"`
+}
+
+// Info defines the title and version of a spec.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Tag defines a tag of operations, the gRPC service serving them in
+// generated specs.
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// refPattern matches the references to definitions.
+var refPattern = regexp.MustCompile(`"\$ref":\s*"#/definitions/([^"]+)"`)
+
+// Load loads the generated spec of a proto file, such as
+// movie.swagger.json, keeping only the operations of the services,
+// such as RatingService, and the definitions they use. All
+// operations are kept if no service is given.
+func Load(file string, services ...string) (*Spec, error) {
+	return load(openapiv2.FS, file, services...)
+}
+
+// LoadHTTP loads the hand-maintained spec of the HTTP API of a
+// service, such as movie.
+func LoadHTTP(service string) (*Spec, error) {
+	return load(httpSpecs, "http/"+service+".json")
+}
+
+func load(fsys fs.FS, file string, services ...string) (*Spec, error) {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	s := &Spec{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if len(services) == 0 {
+		return s, nil
+	}
+	for path, ops := range s.Paths {
+		for method, op := range ops {
+			var o struct {
+				Tags []string `json:"tags"`
+			}
+			if err := json.Unmarshal(op, &o); err != nil {
+				return nil, fmt.Errorf("%s: %s %s: %w", file, strings.ToUpper(method), path, err)
+			}
+			if !slices.ContainsFunc(o.Tags, func(tag string) bool { return slices.Contains(services, tag) }) {
+				delete(ops, method)
+			}
+		}
+		if len(ops) == 0 {
+			delete(s.Paths, path)
+		}
+	}
+	s.Tags = slices.DeleteFunc(s.Tags, func(t Tag) bool { return !slices.Contains(services, t.Name) })
+	s.prune()
+	return s, nil
+}
+
+// Combined merges the generated specs of all services and the
+// specs of their HTTP APIs into a spec with the title and version.
+// The HTTP APIs are described at their unversioned paths, which do
+// not clash with the /v1 paths of the REST APIs.
+func Combined(title string, version string) (*Spec, error) {
+	var specs []*Spec
+	for _, src := range []struct {
+		fsys    fs.FS
+		pattern string
+	}{{openapiv2.FS, "*.swagger.json"}, {httpSpecs, "http/*.json"}} {
+		files, err := fs.Glob(src.fsys, src.pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			s, err := load(src.fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			specs = append(specs, s)
+		}
+	}
+	return Merge(title, version, specs...)
+}
+
+// Merge merges specs into a spec with the title and version. The
+// specs may share identical definitions, such as rpcStatus, but not
+// operations.
+func Merge(title string, version string, specs ...*Spec) (*Spec, error) {
+	m := &Spec{
+		Swagger:     "2.0",
+		Info:        Info{Title: title, Version: version},
+		Consumes:    []string{"application/json"},
+		Produces:    []string{"application/json"},
+		Paths:       map[string]map[string]json.RawMessage{},
+		Definitions: map[string]json.RawMessage{},
+	}
+	for _, s := range specs {
+		m.Tags = append(m.Tags, s.Tags...)
+		for path, ops := range s.Paths {
+			if m.Paths[path] == nil {
+				m.Paths[path] = map[string]json.RawMessage{}
+			}
+			for method, op := range ops {
+				if _, ok := m.Paths[path][method]; ok {
+					return nil, fmt.Errorf("%s %s: defined by several specs", strings.ToUpper(method), path)
+				}
+				m.Paths[path][method] = op
+			}
+		}
+		for name, def := range s.Definitions {
+			if d, ok := m.Definitions[name]; ok && !equal(d, def) {
+				return nil, fmt.Errorf("definition %s: differs between specs", name)
+			}
+			m.Definitions[name] = def
+		}
+	}
+	return m, nil
+}
+
+// ServeHTTP serves the spec as JSON.
+func (s *Spec) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// Handler returns a handler serving the spec at Path and the other
+// requests with next.
+func Handler(next http.Handler, s *Spec) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == Path {
+			s.ServeHTTP(w, req)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>SwaggerUIBundle({url: {{.URL}}, dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`))
+
+// Docs returns a handler serving a Swagger UI page browsing the
+// spec at the URL, loading the UI from the unpkg CDN.
+func Docs(title string, specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			problem.Write(w, req, problem.MethodNotAllowed, "")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := docsPage.Execute(w, struct{ Title, URL string }{title, specURL}); err != nil {
+			logger.ErrorContext(req.Context(), "Docs page error", "error", err)
+		}
+	})
+}
+
+// prune removes the definitions the operations do not reference,
+// directly or through other definitions.
+func (s *Spec) prune() {
+	used := map[string]bool{}
+	var visit func(data []byte)
+	visit = func(data []byte) {
+		for _, m := range refPattern.FindAllSubmatch(data, -1) {
+			name := string(m[1])
+			if def, ok := s.Definitions[name]; ok && !used[name] {
+				used[name] = true
+				visit(def)
+			}
+		}
+	}
+	for _, ops := range s.Paths {
+		for _, op := range ops {
+			visit(op)
+		}
+	}
+	for name := range s.Definitions {
+		if !used[name] {
+			delete(s.Definitions, name)
+		}
+	}
+}
+
+// equal reports whether two JSON values are equal but for
+// whitespace.
+func equal(a, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	return json.Compact(&ca, a) == nil && json.Compact(&cb, b) == nil && bytes.Equal(ca.Bytes(), cb.Bytes())
+}
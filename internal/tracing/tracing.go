@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"movieapp.com/internal/httputil"
+	"movieapp.com/pkg/telemetry"
+)
+
+const instrumentationName = "movieapp.com/internal/tracing"
+
+// Client is an HTTP client creating a span for each request and
+// propagating its trace context to the called service. Its calls
+// are measured by telemetry.Transport, over
+// httputil.ServiceTransport.
+var Client = &http.Client{Transport: otelhttp.NewTransport(telemetry.Transport(httputil.ServiceTransport))}
+
+// Init sets up tracing of a service, exporting its spans over
+// OTLP/gRPC to the collector at the endpoint. Without an endpoint
+// no spans are recorded, but the trace context of incoming calls
+// is still propagated to outgoing ones so that traces stay
+// connected across the service. The returned function flushes
+// pending spans and must be called on exit.
+func Init(ctx context.Context, serviceName string, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Handler creates a span for each request to the next handler,
+// continuing the trace of the caller.
+func Handler(next http.Handler, operation string) http.Handler {
+	return otelhttp.NewHandler(next, operation)
+}
+
+// ServerOption creates a span for each call to a gRPC server.
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// DialOption creates a span for each call of a gRPC client,
+// propagating its trace context to the called service.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// Carrier returns the trace context of the context as a map, to
+// be stored with work handled later, such as an outbox event.
+func Carrier(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// FromCarrier returns a context continuing the trace context
+// stored by Carrier, the context as is if none was stored.
+func FromCarrier(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// kafkaCarrier adapts the headers of a Kafka message to the
+// propagators.
+type kafkaCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c kafkaCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c kafkaCarrier) Set(key string, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c kafkaCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// InjectKafka adds the trace context of the context to the
+// headers of a Kafka message, so that its consumers continue the
+// trace of the change that published it.
+func InjectKafka(ctx context.Context, headers []kafka.Header) []kafka.Header {
+	otel.GetTextMapPropagator().Inject(ctx, kafkaCarrier{&headers})
+	return headers
+}
+
+// StartKafkaConsumer starts the span of handling a Kafka message,
+// continuing the trace in its headers. The span must be ended once
+// the message is handled.
+func StartKafkaConsumer(ctx context.Context, msg kafka.Message) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, kafkaCarrier{&msg.Headers})
+	return otel.Tracer(instrumentationName).Start(ctx, msg.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+		))
+}
+
+// StartConsumer starts the span of handling a message of the topic
+// consumed from the messaging system, continuing the trace in its
+// headers as stored by Carrier. The span must be ended once the
+// message is handled.
+func StartConsumer(ctx context.Context, system attribute.KeyValue, topic string, headers map[string]string) (context.Context, trace.Span) {
+	ctx = FromCarrier(ctx, headers)
+	return otel.Tracer(instrumentationName).Start(ctx, topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(system, semconv.MessagingDestinationName(topic)))
+}
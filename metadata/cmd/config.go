@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/metadata/internal/dedup"
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/bus/sqs"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/debug"
+	"movieapp.com/pkg/httpmw"
+	"movieapp.com/pkg/lifecycle"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/mtls"
+	"movieapp.com/pkg/secrets"
+	"movieapp.com/pkg/tenant"
+)
+
+// Stores of the metadata.
+const (
+	// storeMemory keeps the metadata in memory, lost on restart.
+	storeMemory = "memory"
+	// storeMySQL keeps the metadata in the movies tables.
+	storeMySQL = "mysql"
+	// storeDynamoDB keeps the metadata in a single DynamoDB table.
+	storeDynamoDB = "dynamodb"
+)
+
+// serviceConfig defines the settings of the metadata service, loaded
+// by config.Load.
+type serviceConfig struct {
+	Host            string               `yaml:"host"`
+	DrainTimeout    time.Duration        `yaml:"drainTimeout"`
+	DrainDelay      time.Duration        `yaml:"drainDelay"`
+	ConfigReload    time.Duration        `yaml:"configReload"`
+	RegistryAddr    string               `yaml:"registryAddr"`
+	RegistryCheck   time.Duration        `yaml:"registryCheck"`
+	Port            int                  `yaml:"port"`
+	HTTPPort        int                  `yaml:"httpPort"`
+	RESTPort        int                  `yaml:"restPort"`
+	MetricsPort     int                  `yaml:"metricsPort"`
+	Store           string               `yaml:"store"`
+	MySQLDSN        string               `yaml:"mysqlDSN"`
+	DynamoDBTable   string               `yaml:"dynamodbTable"`
+	Duplicates      string               `yaml:"duplicates"`
+	Bus             string               `yaml:"bus"`
+	NATSURL         string               `yaml:"natsURL"`
+	SQS             sqs.Config           `yaml:"sqs"`
+	KafkaBrokers    config.List          `yaml:"kafkaBrokers"`
+	EventsTopic     string               `yaml:"eventsTopic"`
+	KafkaSASL       kafkautil.SASLConfig `yaml:"kafkaSASL"`
+	AdminToken      string               `yaml:"adminToken"`
+	RatingToken     string               `yaml:"ratingToken"`
+	AuditDSN        string               `yaml:"auditDSN"`
+	ArtworkBucket   string               `yaml:"artworkBucket"`
+	ArtworkEndpoint string               `yaml:"artworkEndpoint"`
+	CDNURL          string               `yaml:"cdnURL"`
+	SiteURL         string               `yaml:"siteURL"`
+	FeedURL         string               `yaml:"feedURL"`
+	RedisAddr       string               `yaml:"redisAddr"`
+	IdempotencyTTL  time.Duration        `yaml:"idempotencyTTL"`
+	OTLPEndpoint    string               `yaml:"otlpEndpoint"`
+	SentryDSN       string               `yaml:"sentryDSN"`
+	HTTPTimeouts    httpmw.TimeoutConfig `yaml:"httpTimeouts"`
+	LogLevel        string               `yaml:"logLevel"`
+	Tenants         config.List          `yaml:"tenants"`
+	TLS             mtls.Config          `yaml:"tls"`
+	Secrets         secrets.Config       `yaml:"secrets"`
+	Debug           debug.Config         `yaml:"debug"`
+}
+
+func defaultConfig() *serviceConfig {
+	httpTimeouts := httpmw.DefaultTimeoutConfig()
+	// Exports stream the whole catalog for as long as it takes.
+	httpTimeouts.Routes = map[string]time.Duration{"/v1/metadata:export": 0}
+	return &serviceConfig{
+		Host:           "localhost",
+		DrainTimeout:   lifecycle.DefaultDrainTimeout,
+		DrainDelay:     lifecycle.DefaultDrainDelay,
+		ConfigReload:   config.DefaultReload,
+		RegistryAddr:   "localhost:8500",
+		Port:           8081,
+		HTTPPort:       8091,
+		RESTPort:       8071,
+		MetricsPort:    8092,
+		Store:          storeMemory,
+		MySQLDSN:       "root:password@/movieexample?parseTime=true",
+		DynamoDBTable:  "metadata",
+		Duplicates:     string(dedup.ModeWarn),
+		Bus:            bus.BrokerKafka,
+		SQS:            sqs.DefaultConfig(),
+		KafkaBrokers:   config.List{"localhost:9092"},
+		EventsTopic:    "metadata",
+		KafkaSASL:      kafkautil.SASLConfig{Mechanism: kafkautil.MechanismSCRAMSHA512},
+		SiteURL:        "https://movieapp.com",
+		IdempotencyTTL: 24 * time.Hour,
+		LogLevel:       "info",
+		Secrets:        secrets.DefaultConfig(),
+		HTTPTimeouts:   httpTimeouts,
+	}
+}
+
+// Validate validates the config.
+func (c *serviceConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("host: empty"))
+	}
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drainTimeout: not positive"))
+	}
+	if c.DrainDelay < 0 || c.RegistryCheck < 0 || c.ConfigReload < 0 {
+		errs = append(errs, errors.New("drainDelay, registryCheck, configReload: negative"))
+	}
+	errs = append(errs,
+		config.ValidatePort("port", c.Port, false),
+		config.ValidatePort("httpPort", c.HTTPPort, false),
+		config.ValidatePort("restPort", c.RESTPort, true),
+		config.ValidatePort("metricsPort", c.MetricsPort, false),
+		config.ValidateAddr("registryAddr", c.RegistryAddr, false),
+		config.ValidateAddr("redisAddr", c.RedisAddr, true),
+		config.ValidateAddr("otlpEndpoint", c.OTLPEndpoint, true),
+	)
+	if c.IdempotencyTTL <= 0 {
+		errs = append(errs, errors.New("idempotencyTTL: not positive"))
+	}
+	switch c.Store {
+	case storeMemory:
+	case storeMySQL:
+		if c.MySQLDSN == "" {
+			errs = append(errs, errors.New("mysqlDSN: empty"))
+		}
+		if len(c.Tenants) > 1 && !strings.Contains(c.MySQLDSN, tenant.Placeholder) {
+			errs = append(errs, errors.New("mysqlDSN: no "+tenant.Placeholder+" for several tenants"))
+		}
+	case storeDynamoDB:
+		if c.DynamoDBTable == "" {
+			errs = append(errs, errors.New("dynamodbTable: empty"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("store: invalid store %q", c.Store))
+	}
+	switch dedup.Mode(c.Duplicates) {
+	case dedup.ModeOff, dedup.ModeWarn, dedup.ModeBlock:
+	default:
+		errs = append(errs, fmt.Errorf("duplicates: invalid mode %q", c.Duplicates))
+	}
+	if c.EventsTopic == "" {
+		errs = append(errs, errors.New("eventsTopic: empty"))
+	}
+	if _, err := logging.ParseLevels(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if err := bus.ValidateBroker(c.Bus); err != nil {
+		errs = append(errs, fmt.Errorf("bus: %w", err))
+	}
+	switch c.Bus {
+	case bus.BrokerKafka:
+		errs = append(errs, config.ValidateAddrs("kafkaBrokers", c.KafkaBrokers))
+		if err := c.KafkaSASL.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("kafkaSASL: %w", err))
+		}
+	case bus.BrokerNATS:
+		if c.NATSURL == "" {
+			errs = append(errs, errors.New("natsURL: empty"))
+		}
+	case bus.BrokerSQS:
+		if err := c.SQS.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sqs: %w", err))
+		}
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	}
+	if err := c.Debug.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("debug: %w", err))
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+	if err := c.HTTPTimeouts.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("httpTimeouts: %w", err))
+	}
+	for _, id := range c.Tenants {
+		if err := tenant.Validate(id); err != nil {
+			errs = append(errs, fmt.Errorf("tenants: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
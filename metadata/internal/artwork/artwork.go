@@ -0,0 +1,115 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/problem"
+)
+
+// MaxBytes defines the maximum size of uploaded artwork.
+const MaxBytes = 10 << 20
+
+// ErrTooLarge is returned when uploaded artwork exceeds MaxBytes.
+var ErrTooLarge = problem.Register(errors.New("artwork too large"), problem.TooLarge)
+
+// ErrUnsupportedType is returned when uploaded artwork is not a
+// JPEG, PNG or WebP image, or is not of its declared type.
+var ErrUnsupportedType = problem.Register(errors.New("unsupported artwork type"), problem.UnsupportedMediaType)
+
+// ErrInvalidKind is returned for unknown image kinds.
+var ErrInvalidKind = problem.Register(errors.New("invalid image kind"), problem.BadRequest)
+
+// contentTypes maps the accepted content types to the file
+// extensions of stored objects.
+var contentTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// Store defines an object storage artwork is uploaded to.
+type Store interface {
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+}
+
+type metadataController interface {
+	Get(ctx context.Context, id string) (*model.Metadata, error)
+	Put(ctx context.Context, m *model.Metadata, author string) error
+}
+
+// Uploader stores movie artwork in object storage and points the
+// metadata at its CDN URL.
+type Uploader struct {
+	store    Store
+	metadata metadataController
+	cdnURL   string
+}
+
+// New creates a new artwork uploader serving stored objects from
+// the given CDN base URL.
+func New(store Store, metadata metadataController, cdnURL string) *Uploader {
+	return &Uploader{store, metadata, strings.TrimSuffix(cdnURL, "/")}
+}
+
+// Upload stores artwork of the given kind and content type for a
+// movie and persists its CDN URL on the metadata. Objects are
+// named by their content hash, so replaced artwork gets a new URL
+// and cached copies never go stale.
+func (u *Uploader) Upload(ctx context.Context, movieID string, kind model.ImageKind, contentType string, data []byte, author string) (*model.Metadata, error) {
+	if kind != model.ImageKindPoster && kind != model.ImageKindBackdrop {
+		return nil, ErrInvalidKind
+	}
+	if len(data) > MaxBytes {
+		return nil, ErrTooLarge
+	}
+	ext, err := checkType(contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := u.metadata.Get(ctx, movieID)
+	if err != nil {
+		return nil, err
+	}
+	m := *cur
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("%ss/%s/%s.%s", kind, m.ID, hex.EncodeToString(sum[:]), ext)
+	if err := u.store.Put(ctx, key, contentType, data); err != nil {
+		return nil, err
+	}
+	url := u.cdnURL + "/" + key
+	switch kind {
+	case model.ImageKindPoster:
+		m.PosterURL = url
+	case model.ImageKindBackdrop:
+		m.BackdropURL = url
+	}
+	if err := u.metadata.Put(ctx, &m, author); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// checkType checks that the data is an image of the declared
+// content type and returns the file extension of the type.
+func checkType(contentType string, data []byte) (string, error) {
+	ext, ok := contentTypes[contentType]
+	if !ok || http.DetectContentType(data) != contentType {
+		return "", ErrUnsupportedType
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return "", ErrUnsupportedType
+	}
+	return ext, nil
+}
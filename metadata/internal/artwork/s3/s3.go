@@ -0,0 +1,47 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Store defines an S3-compatible artwork object storage.
+type Store struct {
+	client *awss3.Client
+	bucket string
+}
+
+// New creates a new S3 store for the given bucket using the
+// default AWS configuration. A non-empty endpoint selects an
+// S3-compatible storage with path-style addressing, e.g. MinIO.
+func New(ctx context.Context, bucket string, endpoint string) (*Store, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client := awss3.NewFromConfig(cfg, func(o *awss3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &Store{client, bucket}, nil
+}
+
+// Put stores an object. Objects are immutable, so they are
+// cached by clients and the CDN for a year.
+func (s *Store) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &awss3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String(contentType),
+		CacheControl:  aws.String("public, max-age=31536000, immutable"),
+	})
+	return err
+}
@@ -0,0 +1,28 @@
+package metadata
+
+import (
+	"time"
+
+	model "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/cache"
+	"movieapp.com/pkg/cache/memory"
+)
+
+const (
+	// readCacheTTL bounds how long other instances' writes may go
+	// unnoticed; writes through this controller evict at once.
+	readCacheTTL        = time.Second
+	maxReadCacheEntries = 10000
+	maxReadCacheBytes   = 64 << 20
+)
+
+// newReadCache creates an in-process read-through cache of
+// repository reads where concurrent misses of the same id share a
+// single read, so that a burst of requests for an uncached title
+// hits the repository once.
+func newReadCache() *cache.Cache[*model.Metadata] {
+	return cache.New(memory.NewStore("metadata-reads", maxReadCacheEntries, maxReadCacheBytes), cache.JSON[*model.Metadata](), cache.Config[*model.Metadata]{
+		Prefix: "metadata:",
+		TTL:    readCacheTTL,
+	})
+}
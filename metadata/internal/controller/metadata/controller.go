@@ -3,31 +3,116 @@ package metadata
 import (
 	"context"
 	"errors"
+	"sort"
+	"strconv"
+	"time"
 
+	"movieapp.com/metadata/internal/dedup"
 	"movieapp.com/metadata/internal/repository"
+	"movieapp.com/metadata/internal/similar"
 	model "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/cache"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/saga"
 )
 
+var logger = logging.New("controller/metadata")
+
 // ErrNotFound is returned when a requested record is not found.
-var ErrNotFound = errors.New("not found")
+var ErrNotFound = problem.Register(errors.New("not found"), problem.NotFound)
+
+// ErrDuplicateExternalID is returned when an external id is
+// already assigned to another movie.
+var ErrDuplicateExternalID = problem.Register(errors.New("external id already assigned to another movie"), problem.Conflict)
+
+// ErrTooManyIDs is returned when a batch read asks for more
+// than MaxBatchSize records.
+var ErrTooManyIDs = problem.Register(errors.New("too many ids"), problem.BadRequest)
+
+// MaxBatchSize defines the maximum number of records that can
+// be read in a single batch.
+const MaxBatchSize = 100
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	defaultSimilarLimit  = 10
+	maxSimilarLimit      = 50
+	maxSimilarCandidates = 500
+)
 
 type metadataRepository interface {
 	Get(ctx context.Context, id string) (*model.Metadata, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error)
+	GetByExternalID(ctx context.Context, source model.ExternalSource, externalID string) (*model.Metadata, error)
+	Put(ctx context.Context, id string, metadata *model.Metadata) error
+	GetVersion(ctx context.Context, id string, version int) (*model.Metadata, error)
+	History(ctx context.Context, id string) ([]*model.Metadata, error)
+	List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error)
+	Search(ctx context.Context, query string, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error)
+	Facets(ctx context.Context, query string, filter model.Filter) (*model.Facets, error)
+	GetPerson(ctx context.Context, id string) (*model.Person, error)
+	GetCredits(ctx context.Context, movieID string) ([]model.Credit, error)
+	GetFilmography(ctx context.Context, personID string) ([]model.Credit, error)
+}
+
+type ratingGateway interface {
+	MoveRatings(ctx context.Context, fromID string, toID string) error
 }
 
 // Controller defines a metadata service controller.
 type Controller struct {
-	repo metadataRepository
+	repo       metadataRepository
+	scorer     similar.Scorer
+	duplicates *dedup.Detector
+	ratings    ratingGateway
+	reads      *cache.Cache[*model.Metadata]
+	sagas      *saga.Coordinator
+	audit      *audit.Logger
 }
 
-// New creates a metadata service controller.
-func New(repo metadataRepository) *Controller {
-	return &Controller{repo}
+// New creates a metadata service controller using the given
+// scorer to rank similar movies, the duplicate detector to check
+// new metadata, the rating gateway to move ratings of merged
+// duplicates and the saga store to save the progress of merges.
+// Writes, deletions, restores, reverts and merges are recorded in
+// the audit log with the actor verified by the handlers, the
+// author given by the caller being only a detail. Concurrent reads
+// of the same metadata are coalesced into a single repository read.
+func New(repo metadataRepository, scorer similar.Scorer, duplicates *dedup.Detector, ratings ratingGateway, sagas saga.Store, auditLog *audit.Logger) *Controller {
+	c := &Controller{repo: repo, scorer: scorer, duplicates: duplicates, ratings: ratings, reads: newReadCache(), audit: auditLog}
+	c.sagas = saga.New(sagas, c.newMergeSaga())
+	return c
 }
 
-// Get returns movie metadata by id.
+// Get returns movie metadata by id. Deleted metadata is
+// reported as not found, while reads of merged duplicates
+// return the metadata they were merged into.
 func (c *Controller) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	res, err := c.repo.Get(ctx, id)
+	res, err := c.GetIncludingDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for hops := 0; res.MergedInto != "" && hops < maxMergeHops; hops++ {
+		if res, err = c.GetIncludingDeleted(ctx, res.MergedInto); err != nil {
+			return nil, err
+		}
+	}
+	if res.Deleted() {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// GetIncludingDeleted returns movie metadata by id even if it
+// is deleted.
+func (c *Controller) GetIncludingDeleted(ctx context.Context, id string) (*model.Metadata, error) {
+	res, err := c.reads.GetOrLoad(ctx, id, func(ctx context.Context) (*model.Metadata, error) {
+		return c.repo.Get(ctx, id)
+	})
 
 	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrNotFound
@@ -36,3 +121,385 @@ func (c *Controller) Get(ctx context.Context, id string) (*model.Metadata, error
 	return res, err
 
 }
+
+// GetMany returns movie metadata for the given ids in the same
+// order. Duplicate ids are returned once; missing and deleted
+// metadata is left out.
+func (c *Controller) GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	var unique []string
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > MaxBatchSize {
+		return nil, ErrTooManyIDs
+	}
+	ms, err := c.repo.GetMany(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*model.Metadata, 0, len(ms))
+	for _, m := range ms {
+		if !m.Deleted() {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// Put writes movie metadata as a new version authored by the
+// given user. Returns a *ValidationError if the metadata is
+// invalid and a *DuplicateError if it is new, looks like a
+// duplicate and duplicates are blocked.
+func (c *Controller) Put(ctx context.Context, m *model.Metadata, author string) error {
+	if err := c.write(ctx, m, author); err != nil {
+		return err
+	}
+	c.audit.Record(ctx, audit.Entry{Action: "metadata.put", Resource: m.ID, Details: map[string]string{"version": strconv.Itoa(m.Version), "author": author}})
+	return nil
+}
+
+// write writes movie metadata as Put does, without auditing it.
+func (c *Controller) write(ctx context.Context, m *model.Metadata, author string) error {
+	if err := validate(m); err != nil {
+		return err
+	}
+	// Writes keep the deleted state, which only changes with
+	// Delete and Restore.
+	cur, err := c.repo.Get(ctx, m.ID)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		m.DeletedAt = nil
+		m.MergedInto = ""
+		if err := c.checkNew(ctx, m); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else {
+		m.DeletedAt = cur.DeletedAt
+		m.MergedInto = cur.MergedInto
+	}
+	return c.put(ctx, m, author)
+}
+
+func (c *Controller) put(ctx context.Context, m *model.Metadata, author string) error {
+	m.UpdatedAt = time.Now().UTC()
+	m.UpdatedBy = author
+	err := c.repo.Put(ctx, m.ID, m)
+	// The in-process store of the reads cannot fail.
+	_ = c.reads.Delete(ctx, m.ID)
+	if err != nil && errors.Is(err, repository.ErrDuplicateExternalID) {
+		return ErrDuplicateExternalID
+	}
+	return err
+}
+
+// GetByExternalID returns movie metadata by its id in another
+// catalog. Deleted metadata is reported as not found.
+func (c *Controller) GetByExternalID(ctx context.Context, source model.ExternalSource, externalID string) (*model.Metadata, error) {
+	res, err := c.repo.GetByExternalID(ctx, source, externalID)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if res.Deleted() {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// PutByExternalID writes movie metadata identified by its id in
+// another catalog. If a movie, including a deleted one, already
+// has the external id, it is updated; otherwise the metadata is
+// written under its own id.
+func (c *Controller) PutByExternalID(ctx context.Context, source model.ExternalSource, externalID string, m *model.Metadata, author string) error {
+	cur, err := c.repo.GetByExternalID(ctx, source, externalID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	} else if err == nil {
+		m.ID = cur.ID
+	}
+	ids := make(map[model.ExternalSource]string, len(m.ExternalIDs)+1)
+	for k, v := range m.ExternalIDs {
+		ids[k] = v
+	}
+	ids[source] = externalID
+	m.ExternalIDs = ids
+	return c.Put(ctx, m, author)
+}
+
+// Delete removes movie metadata from the catalog. Deleted
+// metadata no longer shows up in reads, listings and searches
+// but is kept, as a new version, so that it can be restored.
+func (c *Controller) Delete(ctx context.Context, id string, author string) (*model.Metadata, error) {
+	return c.setDeleted(ctx, id, author, true)
+}
+
+// Restore brings deleted movie metadata back to the catalog.
+func (c *Controller) Restore(ctx context.Context, id string, author string) (*model.Metadata, error) {
+	return c.setDeleted(ctx, id, author, false)
+}
+
+func (c *Controller) setDeleted(ctx context.Context, id string, author string, deleted bool) (*model.Metadata, error) {
+	cur, err := c.GetIncludingDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cur.Deleted() == deleted {
+		return cur, nil
+	}
+	m := *cur
+	m.DeletedAt = nil
+	if deleted {
+		now := time.Now().UTC()
+		m.DeletedAt = &now
+	}
+	if err := c.put(ctx, &m, author); err != nil {
+		return nil, err
+	}
+	action := "metadata.restore"
+	if deleted {
+		action = "metadata.delete"
+	}
+	c.audit.Record(ctx, audit.Entry{Action: action, Resource: id, Details: map[string]string{"author": author}})
+	return &m, nil
+}
+
+// GetVersion returns the given version of movie metadata.
+func (c *Controller) GetVersion(ctx context.Context, id string, version int) (*model.Metadata, error) {
+	res, err := c.repo.GetVersion(ctx, id, version)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return res, err
+}
+
+// History returns all versions of movie metadata, latest first.
+func (c *Controller) History(ctx context.Context, id string) ([]*model.Metadata, error) {
+	res, err := c.repo.History(ctx, id)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return res, err
+}
+
+// Revert restores movie metadata to the given version. The
+// restored content is written as a new version so the change
+// can itself be reverted.
+func (c *Controller) Revert(ctx context.Context, id string, version int, author string) (*model.Metadata, error) {
+	old, err := c.GetVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	m := *old
+	if err := c.write(ctx, &m, author); err != nil {
+		return nil, err
+	}
+	c.audit.Record(ctx, audit.Entry{Action: "metadata.revert", Resource: id, Details: map[string]string{"version": strconv.Itoa(version), "author": author}})
+	return &m, nil
+}
+
+// GetLocalized returns movie metadata by id with its text
+// resolved for the first available of the preferred locales.
+func (c *Controller) GetLocalized(ctx context.Context, id string, locales ...string) (*model.Metadata, error) {
+	m, err := c.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return m.Localize(locales...), nil
+}
+
+// List returns a page of movie metadata matching the filter
+// and the token of the next page, empty if it is the last one.
+func (c *Controller) List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	return c.repo.List(ctx, filter, normalizePageSize(pageSize), pageToken)
+}
+
+// Export passes all movie metadata matching the filter to fn in
+// batches, ordered like List, until fn returns an error.
+func (c *Controller) Export(ctx context.Context, filter model.Filter, fn func([]*model.Metadata) error) error {
+	for token := ""; ; {
+		page, next, err := c.repo.List(ctx, filter, maxPageSize, token)
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// Search returns a page of movie metadata matching the text
+// query and the filter, and the token of the next page.
+func (c *Controller) Search(ctx context.Context, query string, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	return c.repo.Search(ctx, query, filter, normalizePageSize(pageSize), pageToken)
+}
+
+// Facets returns the number of all movie metadata matching the
+// text query and the filter by genre, decade, language and
+// certification.
+func (c *Controller) Facets(ctx context.Context, query string, filter model.Filter) (*model.Facets, error) {
+	return c.repo.Facets(ctx, query, filter)
+}
+
+// Genres returns the known genre taxonomy.
+func (c *Controller) Genres(_ context.Context) []model.Genre {
+	return model.Genres()
+}
+
+// Person returns a person by id.
+func (c *Controller) Person(ctx context.Context, id string) (*model.Person, error) {
+	res, err := c.repo.GetPerson(ctx, id)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return res, err
+}
+
+// Credits returns the cast and crew of a movie in billing order.
+func (c *Controller) Credits(ctx context.Context, movieID string) ([]model.Credit, error) {
+	if _, err := c.Get(ctx, movieID); err != nil {
+		return nil, err
+	}
+	return c.repo.GetCredits(ctx, movieID)
+}
+
+// Filmography returns the credits of a person across movies.
+func (c *Controller) Filmography(ctx context.Context, personID string) ([]model.Credit, error) {
+	if _, err := c.Person(ctx, personID); err != nil {
+		return nil, err
+	}
+	return c.repo.GetFilmography(ctx, personID)
+}
+
+// Similar returns up to limit movies most similar to the given
+// one, most similar first. Candidates are the movies sharing a
+// genre, a tag or a credited person with it.
+func (c *Controller) Similar(ctx context.Context, id string, limit int) ([]model.SimilarMovie, error) {
+	if limit <= 0 {
+		limit = defaultSimilarLimit
+	} else if limit > maxSimilarLimit {
+		limit = maxSimilarLimit
+	}
+	m, err := c.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	target, err := c.similarItem(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := c.similarCandidates(ctx, m, target.People)
+	if err != nil {
+		return nil, err
+	}
+	var res []model.SimilarMovie
+	for _, candidate := range candidates {
+		item, err := c.similarItem(ctx, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if score := c.scorer.Score(target, item); score > 0 {
+			res = append(res, model.SimilarMovie{Metadata: candidate, Score: score})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].Metadata.ID < res[j].Metadata.ID
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (c *Controller) similarItem(ctx context.Context, m *model.Metadata) (similar.Item, error) {
+	credits, err := c.repo.GetCredits(ctx, m.ID)
+	if err != nil {
+		return similar.Item{}, err
+	}
+	item := similar.Item{Metadata: m}
+	for _, credit := range credits {
+		item.People = append(item.People, credit.PersonID)
+	}
+	return item, nil
+}
+
+func (c *Controller) similarCandidates(ctx context.Context, m *model.Metadata, people []string) ([]*model.Metadata, error) {
+	seen := map[string]bool{m.ID: true}
+	var res []*model.Metadata
+	add := func(candidate *model.Metadata) bool {
+		if !seen[candidate.ID] {
+			seen[candidate.ID] = true
+			res = append(res, candidate)
+		}
+		return len(res) < maxSimilarCandidates
+	}
+	var filters []model.Filter
+	for _, g := range m.Genres {
+		filters = append(filters, model.Filter{Genres: []model.Genre{g}})
+	}
+	for _, t := range m.Tags {
+		filters = append(filters, model.Filter{Tags: []string{t}})
+	}
+	for _, f := range filters {
+		for token := ""; ; {
+			page, next, err := c.repo.List(ctx, f, maxPageSize, token)
+			if err != nil {
+				return nil, err
+			}
+			for _, candidate := range page {
+				if !add(candidate) {
+					return res, nil
+				}
+			}
+			if next == "" {
+				break
+			}
+			token = next
+		}
+	}
+	for _, p := range people {
+		credits, err := c.repo.GetFilmography(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, credit := range credits {
+			if seen[credit.MovieID] {
+				continue
+			}
+			candidate, err := c.Get(ctx, credit.MovieID)
+			if err != nil && errors.Is(err, ErrNotFound) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			if !add(candidate) {
+				return res, nil
+			}
+		}
+	}
+	return res, nil
+}
+
+func normalizePageSize(pageSize int) int {
+	if pageSize <= 0 {
+		return defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		return maxPageSize
+	}
+	return pageSize
+}
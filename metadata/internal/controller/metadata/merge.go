@@ -0,0 +1,265 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"movieapp.com/metadata/internal/dedup"
+	model "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/saga"
+)
+
+// ErrInvalidMerge is returned when metadata cannot be merged,
+// e.g. into itself.
+var ErrInvalidMerge = problem.Register(errors.New("invalid merge"), problem.BadRequest)
+
+const (
+	maxDuplicateCandidates = 1000
+	maxMergeHops           = 5
+)
+
+// DuplicateError is returned when new metadata looks like a
+// duplicate of existing metadata and duplicates are blocked.
+type DuplicateError struct {
+	Matches []model.DuplicateMatch
+}
+
+func (e *DuplicateError) Error() string {
+	ids := make([]string, 0, len(e.Matches))
+	for _, m := range e.Matches {
+		ids = append(ids, m.Metadata.ID)
+	}
+	return "likely duplicate of " + strings.Join(ids, ", ")
+}
+
+// CheckDuplicates returns existing metadata likely describing
+// the same movie as the given metadata, most similar first.
+func (c *Controller) CheckDuplicates(ctx context.Context, m *model.Metadata) ([]model.DuplicateMatch, error) {
+	candidates, err := c.duplicateCandidates(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return c.duplicates.Matches(m, candidates), nil
+}
+
+// checkNew checks new metadata for duplicates according to the
+// detector mode.
+func (c *Controller) checkNew(ctx context.Context, m *model.Metadata) error {
+	if c.duplicates.Mode == dedup.ModeOff {
+		return nil
+	}
+	matches, err := c.CheckDuplicates(ctx, m)
+	if err != nil || len(matches) == 0 {
+		return err
+	}
+	dupErr := &DuplicateError{Matches: matches}
+	if c.duplicates.Mode == dedup.ModeBlock {
+		return dupErr
+	}
+	logger.WarnContext(ctx, "Likely duplicate metadata", "id", m.ID, "error", dupErr)
+	return nil
+}
+
+// duplicateCandidates returns the metadata released around the
+// same year or, without a release date, sharing a title word.
+func (c *Controller) duplicateCandidates(ctx context.Context, m *model.Metadata) ([]*model.Metadata, error) {
+	var filter model.Filter
+	var query string
+	if year := m.ReleaseYear(); year > 0 {
+		filter.YearFrom = year - c.duplicates.YearTolerance
+		filter.YearTo = year + c.duplicates.YearTolerance
+	} else {
+		for _, w := range strings.Fields(dedup.Normalize(m.Title)) {
+			if len(w) > len(query) {
+				query = w
+			}
+		}
+		if query == "" {
+			return nil, nil
+		}
+	}
+	var res []*model.Metadata
+	for token := ""; len(res) < maxDuplicateCandidates; {
+		var page []*model.Metadata
+		var err error
+		if query != "" {
+			page, token, err = c.repo.Search(ctx, query, filter, maxPageSize, token)
+		} else {
+			page, token, err = c.repo.List(ctx, filter, maxPageSize, token)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, page...)
+		if token == "" {
+			break
+		}
+	}
+	return res, nil
+}
+
+// Merge merges the source metadata, a duplicate, into the target.
+// Ratings of the source are moved to the target, empty fields of
+// the target are filled from the source, and the source is
+// deleted with reads of it redirected to the target. Returns the
+// updated target.
+//
+// Merges run as sagas: if the metadata writes fail, the ones done
+// are undone, and moving the ratings, which comes last, is retried
+// in the background until it succeeds.
+func (c *Controller) Merge(ctx context.Context, targetID string, sourceID string, author string) (*model.Metadata, error) {
+	target, err := c.Get(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := c.GetIncludingDeleted(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source.MergedInto == target.ID {
+		return target, nil
+	}
+	if source.ID == target.ID || source.MergedInto != "" {
+		return nil, fmt.Errorf("%w: %s into %s", ErrInvalidMerge, sourceID, targetID)
+	}
+	data := &mergeData{Source: source, Target: target, Merged: mergeMetadata(target, source), Author: author}
+	// Merges of the same versions are the same execution, while
+	// a merge undone can be tried again.
+	id := fmt.Sprintf("merge:%s@%d:%s@%d", source.ID, source.Version, target.ID, target.Version)
+	if _, err := c.sagas.Start(ctx, mergeSaga, id, data); err != nil {
+		return nil, err
+	}
+	c.audit.Record(ctx, audit.Entry{Action: "metadata.merge", Resource: target.ID, Details: map[string]string{"source": source.ID, "author": author}})
+	return c.Get(ctx, target.ID)
+}
+
+// mergeSaga is the name of the saga of merges.
+const mergeSaga = "merge"
+
+// mergeData defines the data of a merge saga: the metadata before
+// the merge, to restore it if compensating, and the merged
+// metadata.
+type mergeData struct {
+	Source *model.Metadata `json:"source"`
+	Target *model.Metadata `json:"target"`
+	Merged *model.Metadata `json:"merged"`
+	Author string          `json:"author"`
+}
+
+// newMergeSaga returns the steps of a merge. The source gives up
+// its external ids first, as they are unique and move to the
+// target, and gets them back last when compensating.
+func (c *Controller) newMergeSaga() *saga.Saga {
+	step := func(fn func(ctx context.Context, d *mergeData) error) func(context.Context, json.RawMessage) error {
+		return func(ctx context.Context, data json.RawMessage) error {
+			var d mergeData
+			if err := json.Unmarshal(data, &d); err != nil {
+				return err
+			}
+			return fn(ctx, &d)
+		}
+	}
+	return &saga.Saga{Name: mergeSaga, Steps: []saga.Step{
+		{
+			Name: "redirect-source",
+			Do: step(func(ctx context.Context, d *mergeData) error {
+				redirect := *d.Source
+				now := time.Now().UTC()
+				redirect.DeletedAt = &now
+				redirect.MergedInto = d.Target.ID
+				redirect.ExternalIDs = nil
+				return c.put(ctx, &redirect, d.Author)
+			}),
+			Compensate: step(func(ctx context.Context, d *mergeData) error {
+				return c.put(ctx, d.Source, d.Author)
+			}),
+		},
+		{
+			Name: "merge-target",
+			Do: step(func(ctx context.Context, d *mergeData) error {
+				return c.put(ctx, d.Merged, d.Author)
+			}),
+			Compensate: step(func(ctx context.Context, d *mergeData) error {
+				return c.put(ctx, d.Target, d.Author)
+			}),
+		},
+		{
+			// Moving ratings again moves none, and is not undone.
+			Name: "move-ratings",
+			Do: step(func(ctx context.Context, d *mergeData) error {
+				return c.ratings.MoveRatings(ctx, d.Source.ID, d.Target.ID)
+			}),
+			Retry: true,
+		},
+	}}
+}
+
+// RunSagas resumes the interrupted merges at the interval until the
+// context is canceled.
+func (c *Controller) RunSagas(ctx context.Context, interval time.Duration) {
+	c.sagas.Run(ctx, interval)
+}
+
+// mergeMetadata returns a copy of the target with its empty
+// fields filled from the source and the genres, tags, external
+// ids and localizations of both.
+func mergeMetadata(target *model.Metadata, source *model.Metadata) *model.Metadata {
+	m := *target
+	for _, f := range []struct{ dst, src *string }{
+		{&m.Description, &source.Description},
+		{&m.Director, &source.Director},
+		{&m.PosterURL, &source.PosterURL},
+		{&m.BackdropURL, &source.BackdropURL},
+		{&m.Tagline, &source.Tagline},
+		{&m.ReleaseDate, &source.ReleaseDate},
+		{&m.Certification, &source.Certification},
+		{&m.OriginalLanguage, &source.OriginalLanguage},
+	} {
+		if *f.dst == "" {
+			*f.dst = *f.src
+		}
+	}
+	if m.RuntimeMinutes == 0 {
+		m.RuntimeMinutes = source.RuntimeMinutes
+	}
+	m.Genres = slices.Clone(m.Genres)
+	for _, g := range source.Genres {
+		if !slices.Contains(m.Genres, g) {
+			m.Genres = append(m.Genres, g)
+		}
+	}
+	m.Tags = slices.Clone(m.Tags)
+	for _, t := range source.Tags {
+		if !slices.Contains(m.Tags, t) {
+			m.Tags = append(m.Tags, t)
+		}
+	}
+	if len(source.ExternalIDs) > 0 {
+		ids := map[model.ExternalSource]string{}
+		for k, v := range source.ExternalIDs {
+			ids[k] = v
+		}
+		for k, v := range target.ExternalIDs {
+			ids[k] = v
+		}
+		m.ExternalIDs = ids
+	}
+	if len(source.Localizations) > 0 {
+		ls := map[string]model.Localization{}
+		for k, v := range source.Localizations {
+			ls[k] = v
+		}
+		for k, v := range target.Localizations {
+			ls[k] = v
+		}
+		m.Localizations = ls
+	}
+	return &m
+}
@@ -0,0 +1,151 @@
+package metadata
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+	model "movieapp.com/metadata/pkg/model"
+)
+
+const (
+	maxIDLength            = 64
+	maxTitleLength         = 200
+	maxDescriptionLength   = 5000
+	maxDirectorLength      = 200
+	maxTaglineLength       = 300
+	maxTagLength           = 50
+	maxTags                = 50
+	maxCertificationLength = 16
+	maxRuntimeMinutes      = 24 * 60
+	minReleaseYear         = 1870
+)
+
+// FieldError defines a validation error of a single field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when metadata fails validation. It
+// lists every invalid field rather than only the first one.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var msgs []string
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "invalid metadata: " + strings.Join(msgs, "; ")
+}
+
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) text(field, value string, required bool, maxLength int) {
+	if strings.TrimSpace(value) == "" {
+		if required {
+			v.add(field, "is required")
+		}
+		return
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		v.add(field, "must be at most %d characters long", maxLength)
+	}
+}
+
+func (v *validator) url(field, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(field, "must be an absolute http or https URL")
+	}
+}
+
+// validate checks movie metadata before it is written.
+func validate(m *model.Metadata) error {
+	v := &validator{}
+	v.text("id", m.ID, true, maxIDLength)
+	if strings.ContainsAny(m.ID, " \t\r\n/") {
+		v.add("id", "must not contain whitespace or slashes")
+	}
+	v.text("title", m.Title, true, maxTitleLength)
+	v.text("description", m.Description, false, maxDescriptionLength)
+	v.text("director", m.Director, false, maxDirectorLength)
+	v.text("tagline", m.Tagline, false, maxTaglineLength)
+	genres := model.Genres()
+	for i, g := range m.Genres {
+		field := fmt.Sprintf("genres[%d]", i)
+		if !slices.Contains(genres, g) {
+			v.add(field, "unknown genre %q", g)
+		} else if slices.Contains(m.Genres[:i], g) {
+			v.add(field, "duplicate genre %q", g)
+		}
+	}
+	if len(m.Tags) > maxTags {
+		v.add("tags", "must have at most %d tags", maxTags)
+	}
+	for i, t := range m.Tags {
+		v.text(fmt.Sprintf("tags[%d]", i), t, true, maxTagLength)
+	}
+	if m.ReleaseDate != "" {
+		if t, err := time.Parse(model.ReleaseDateLayout, m.ReleaseDate); err != nil {
+			v.add("releaseDate", "must be a YYYY-MM-DD date")
+		} else if maxYear := time.Now().Year() + 10; t.Year() < minReleaseYear || t.Year() > maxYear {
+			v.add("releaseDate", "year must be between %d and %d", minReleaseYear, maxYear)
+		}
+	}
+	if m.RuntimeMinutes < 0 || m.RuntimeMinutes > maxRuntimeMinutes {
+		v.add("runtimeMinutes", "must be between 0 and %d", maxRuntimeMinutes)
+	}
+	v.text("certification", m.Certification, false, maxCertificationLength)
+	if m.OriginalLanguage != "" {
+		if _, err := language.Parse(m.OriginalLanguage); err != nil {
+			v.add("originalLanguage", "invalid language tag")
+		}
+	}
+	sources := make([]model.ExternalSource, 0, len(m.ExternalIDs))
+	for source := range m.ExternalIDs {
+		sources = append(sources, source)
+	}
+	slices.Sort(sources)
+	for _, source := range sources {
+		if id := m.ExternalIDs[source]; !model.ValidExternalID(source, id) {
+			v.add("externalIds["+string(source)+"]", "invalid external id %q", id)
+		}
+	}
+	v.url("posterUrl", m.PosterURL)
+	v.url("backdropUrl", m.BackdropURL)
+	locales := make([]string, 0, len(m.Localizations))
+	for locale := range m.Localizations {
+		locales = append(locales, locale)
+	}
+	slices.Sort(locales)
+	for _, locale := range locales {
+		l := m.Localizations[locale]
+		field := "localizations[" + locale + "]"
+		if _, err := language.Parse(locale); err != nil {
+			v.add(field, "invalid locale")
+		}
+		v.text(field+".title", l.Title, false, maxTitleLength)
+		v.text(field+".description", l.Description, false, maxDescriptionLength)
+		v.text(field+".tagline", l.Tagline, false, maxTaglineLength)
+	}
+	if len(v.errs) > 0 {
+		return &ValidationError{Fields: v.errs}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package dedup
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"movieapp.com/metadata/pkg/model"
+)
+
+// Mode defines how writes of likely duplicates are handled.
+type Mode string
+
+// Existing modes.
+const (
+	// ModeOff disables duplicate detection on writes.
+	ModeOff = Mode("off")
+	// ModeWarn logs likely duplicates but lets writes through.
+	ModeWarn = Mode("warn")
+	// ModeBlock rejects writes creating likely duplicates.
+	ModeBlock = Mode("block")
+)
+
+// Detector finds existing metadata likely describing the same
+// movie, matching titles fuzzily and release years loosely.
+type Detector struct {
+	Mode Mode
+	// Threshold is the minimum title similarity, between 0 and
+	// 1, of a likely duplicate.
+	Threshold float64
+	// YearTolerance is the maximum difference of release years
+	// of a likely duplicate.
+	YearTolerance int
+}
+
+// NewDetector creates a duplicate detector with the given mode.
+func NewDetector(mode Mode) *Detector {
+	return &Detector{Mode: mode, Threshold: 0.85, YearTolerance: 1}
+}
+
+// Matches returns the candidates likely being duplicates of the
+// metadata, most similar first.
+func (d *Detector) Matches(m *model.Metadata, candidates []*model.Metadata) []model.DuplicateMatch {
+	title := Normalize(m.Title)
+	year := m.ReleaseYear()
+	var res []model.DuplicateMatch
+	for _, c := range candidates {
+		if c.ID == m.ID {
+			continue
+		}
+		if cy := c.ReleaseYear(); year > 0 && cy > 0 && abs(year-cy) > d.YearTolerance {
+			continue
+		}
+		if score := Similarity(title, Normalize(c.Title)); score >= d.Threshold {
+			res = append(res, model.DuplicateMatch{Metadata: c, Score: score})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].Metadata.ID < res[j].Metadata.ID
+	})
+	return res
+}
+
+var articles = []string{"the", "a", "an"}
+
+// Normalize returns the title lowercased, without punctuation,
+// articles and repeated whitespace, so that e.g. "The Matrix",
+// "Matrix, The" and "matrix" compare equal.
+func Normalize(title string) string {
+	title = strings.ToLower(strings.TrimSpace(title))
+	for _, a := range articles {
+		if strings.HasSuffix(title, ", "+a) {
+			title = strings.TrimSuffix(title, ", "+a)
+			break
+		}
+	}
+	var b strings.Builder
+	for _, r := range title {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '&':
+			b.WriteString(" and ")
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	words := strings.Fields(b.String())
+	if len(words) > 1 && slices.Contains(articles, words[0]) {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// Similarity returns the similarity of two strings between 0
+// and 1, based on their Levenshtein distance.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := max(len(ra), len(rb))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(n)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
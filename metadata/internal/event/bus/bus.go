@@ -0,0 +1,54 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+
+	"movieapp.com/internal/tracing"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/bus"
+)
+
+// Publisher defines a metadata change event publisher.
+type Publisher struct {
+	bus   bus.Publisher
+	topic string
+}
+
+// NewPublisher creates a publisher of events to the topic of the
+// bus. Events are keyed by movie id, so the events of a movie are
+// consumed in order.
+func NewPublisher(b bus.Publisher, topic string) *Publisher {
+	return &Publisher{b, topic}
+}
+
+// Publish publishes the events, each continuing the trace of the
+// change it records.
+func (p *Publisher) Publish(ctx context.Context, events []*model.Event) error {
+	msgs := make([]bus.Message, 0, len(events))
+	for _, e := range events {
+		msg, err := Encode(ctx, e)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+	return p.bus.Publish(ctx, p.topic, msgs...)
+}
+
+// Encode returns the message of an event, keyed by movie id and
+// carrying the trace context of the change it records, that of the
+// context if none.
+func Encode(ctx context.Context, e *model.Event) (bus.Message, error) {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return bus.Message{}, err
+	}
+	headers := tracing.Carrier(tracing.FromCarrier(ctx, e.TraceContext))
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["type"] = string(e.Type)
+	headers["id"] = e.ID
+	return bus.Message{Key: e.MovieID, Value: value, Headers: headers}, nil
+}
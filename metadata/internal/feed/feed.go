@@ -0,0 +1,145 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/tenant"
+)
+
+var logger = logging.New("feed")
+
+// ErrNotReady is returned before the first feed is generated.
+var ErrNotReady = problem.Register(errors.New("feed not generated yet"), problem.Unavailable)
+
+// ErrNotFound is returned for pages past the end of the feed.
+var ErrNotFound = problem.Register(errors.New("feed page not found"), problem.NotFound)
+
+// PageSize defines the number of entries of a feed page, the
+// maximum number of URLs of a sitemap.
+const PageSize = 50000
+
+// listBatchSize is the page size of catalog reads.
+const listBatchSize = 100
+
+type metadataLister interface {
+	List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error)
+}
+
+// Entry defines a public title of the catalog.
+type Entry struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// Snapshot defines a generated feed of all public titles split
+// into pages.
+type Snapshot struct {
+	GeneratedAt time.Time
+	Pages       [][]Entry
+}
+
+// LastModified returns the latest modification time of the
+// entries of a page.
+func LastModified(page []Entry) time.Time {
+	var res time.Time
+	for _, e := range page {
+		if e.LastModified.After(res) {
+			res = e.LastModified
+		}
+	}
+	return res
+}
+
+// Generator periodically generates the feed of the catalog of each
+// tenant in the background, so that crawlers and partners fetching
+// it do not page through the whole catalog on every request.
+type Generator struct {
+	metadata metadataLister
+	tenants  *tenant.Set
+	interval time.Duration
+
+	mu        sync.RWMutex
+	snapshots map[string]*Snapshot
+}
+
+// NewGenerator creates a feed generator regenerating the feeds of
+// the tenants of the set at the given interval.
+func NewGenerator(metadata metadataLister, tenants *tenant.Set, interval time.Duration) *Generator {
+	return &Generator{metadata: metadata, tenants: tenants, interval: interval, snapshots: map[string]*Snapshot{}}
+}
+
+// Run generates the feeds until the context is canceled.
+func (g *Generator) Run(ctx context.Context) {
+	for {
+		if err := g.tenants.Each(ctx, g.generate); err != nil {
+			logger.ErrorContext(ctx, "Feed generation error", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(g.interval):
+		}
+	}
+}
+
+// Snapshot returns the last generated feed of the tenant of the
+// context.
+func (g *Generator) Snapshot(ctx context.Context) (*Snapshot, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	s, ok := g.snapshots[tenant.FromContext(ctx)]
+	if !ok {
+		return nil, ErrNotReady
+	}
+	return s, nil
+}
+
+// Page returns a page of the last generated feed of the tenant of
+// the context, numbered from 1.
+func (g *Generator) Page(ctx context.Context, n int) ([]Entry, *Snapshot, error) {
+	s, err := g.Snapshot(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n < 1 || n > len(s.Pages) {
+		return nil, nil, ErrNotFound
+	}
+	return s.Pages[n-1], s, nil
+}
+
+func (g *Generator) generate(ctx context.Context) error {
+	var pages [][]Entry
+	var page []Entry
+	// Deleted and merged metadata is excluded by the filter.
+	for token := ""; ; {
+		ms, next, err := g.metadata.List(ctx, model.Filter{}, listBatchSize, token)
+		if err != nil {
+			return err
+		}
+		for _, m := range ms {
+			page = append(page, Entry{ID: m.ID, Title: m.Title, LastModified: m.UpdatedAt})
+			if len(page) == PageSize {
+				pages = append(pages, page)
+				page = nil
+			}
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.snapshots[tenant.FromContext(ctx)] = &Snapshot{GeneratedAt: time.Now().UTC(), Pages: pages}
+	return nil
+}
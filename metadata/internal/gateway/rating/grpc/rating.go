@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/rating/pkg/model"
+)
+
+// Gateway defines an gRPC gateway for a rating service.
+type Gateway struct {
+	client gen.RatingServiceClient
+	token  string
+}
+
+// New creates a new gRPC gateway for a rating service calling it
+// through the connection, whose calls bear the token unless empty,
+// as ratings are moved on behalf of the metadata service rather than
+// of the caller.
+func New(conn grpc.ClientConnInterface, token string) *Gateway {
+	return &Gateway{gen.NewRatingServiceClient(conn), token}
+}
+
+// MoveRatings reassigns all ratings of a movie to another movie.
+func (g *Gateway) MoveRatings(ctx context.Context, fromID string, toID string) error {
+	if g.token != "" {
+		ctx = auth.WithToken(ctx, g.token)
+	}
+	_, err := g.client.MoveRatings(ctx, &gen.MoveRatingsRequest{
+		RecordType:   string(model.RecordTypeMovie),
+		FromRecordId: fromID,
+		ToRecordId:   toID,
+	})
+	return err
+}
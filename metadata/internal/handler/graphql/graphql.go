@@ -0,0 +1,347 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graphql-go/graphql"
+	"movieapp.com/metadata/internal/controller/metadata"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("handler/graphql")
+
+// Handler defines a GraphQL API handler of the metadata catalog.
+// Movies, people and credits referenced by the results are loaded
+// in batches per request instead of one by one.
+type Handler struct {
+	ctrl   *metadata.Controller
+	schema graphql.Schema
+}
+
+// New creates a new GraphQL API handler.
+func New(ctrl *metadata.Controller) (*Handler, error) {
+	h := &Handler{ctrl: ctrl}
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP handles GET and POST /graphql requests with the query
+// in the query parameter or in the JSON body.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var r request
+	switch req.Method {
+	case http.MethodGet:
+		r.Query = req.FormValue("query")
+		r.OperationName = req.FormValue("operationName")
+		if v := req.FormValue("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &r.Variables); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Query == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	res := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  r.Query,
+		OperationName:  r.OperationName,
+		VariableValues: r.Variables,
+		Context:        h.withLoaders(req.Context()),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+type loadersKey struct{}
+
+// loaders batch and deduplicate the reads of a single request.
+type loaders struct {
+	movies  *dataloader.Loader[string, *model.Metadata]
+	people  *dataloader.Loader[string, *model.Person]
+	credits *dataloader.Loader[string, []model.Credit]
+	// filmography loads credits by person id.
+	filmography *dataloader.Loader[string, []model.Credit]
+}
+
+func (h *Handler) withLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		movies: dataloader.NewBatchedLoader(h.loadMovies,
+			dataloader.WithBatchCapacity[string, *model.Metadata](metadata.MaxBatchSize)),
+		people:      dataloader.NewBatchedLoader(h.loadPeople),
+		credits:     dataloader.NewBatchedLoader(h.loadCredits),
+		filmography: dataloader.NewBatchedLoader(h.loadFilmography),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loadMovies reads movies in a single batch. Missing movies
+// resolve to null.
+func (h *Handler) loadMovies(ctx context.Context, ids []string) []*dataloader.Result[*model.Metadata] {
+	res := make([]*dataloader.Result[*model.Metadata], len(ids))
+	ms, err := h.ctrl.GetMany(ctx, ids)
+	byID := make(map[string]*model.Metadata, len(ms))
+	for _, m := range ms {
+		byID[m.ID] = m
+	}
+	for i, id := range ids {
+		res[i] = &dataloader.Result[*model.Metadata]{Data: byID[id], Error: err}
+	}
+	return res
+}
+
+// loadPeople reads the people of a batch. There is no batch read
+// of people, but repeated ids are read once.
+func (h *Handler) loadPeople(ctx context.Context, ids []string) []*dataloader.Result[*model.Person] {
+	res := make([]*dataloader.Result[*model.Person], len(ids))
+	for i, id := range ids {
+		p, err := h.ctrl.Person(ctx, id)
+		if err != nil && errors.Is(err, metadata.ErrNotFound) {
+			p, err = nil, nil
+		}
+		res[i] = &dataloader.Result[*model.Person]{Data: p, Error: err}
+	}
+	return res
+}
+
+func (h *Handler) loadCredits(ctx context.Context, movieIDs []string) []*dataloader.Result[[]model.Credit] {
+	res := make([]*dataloader.Result[[]model.Credit], len(movieIDs))
+	for i, id := range movieIDs {
+		credits, err := h.ctrl.Credits(ctx, id)
+		if err != nil && errors.Is(err, metadata.ErrNotFound) {
+			credits, err = nil, nil
+		}
+		res[i] = &dataloader.Result[[]model.Credit]{Data: credits, Error: err}
+	}
+	return res
+}
+
+func (h *Handler) loadFilmography(ctx context.Context, personIDs []string) []*dataloader.Result[[]model.Credit] {
+	res := make([]*dataloader.Result[[]model.Credit], len(personIDs))
+	for i, id := range personIDs {
+		credits, err := h.ctrl.Filmography(ctx, id)
+		if err != nil && errors.Is(err, metadata.ErrNotFound) {
+			credits, err = nil, nil
+		}
+		res[i] = &dataloader.Result[[]model.Credit]{Data: credits, Error: err}
+	}
+	return res
+}
+
+// loadMovie resolves to a movie loaded in a batch. The returned
+// thunk defers the read until all fields of the level are queued.
+func loadMovie(ctx context.Context, id string) (any, error) {
+	thunk := loadersFrom(ctx).movies.Load(ctx, id)
+	return func() (any, error) {
+		m, err := thunk()
+		if err != nil || m == nil {
+			return nil, err
+		}
+		return m, nil
+	}, nil
+}
+
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	movieType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Movie",
+		Fields: graphql.Fields{
+			"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"title":            &graphql.Field{Type: graphql.String},
+			"description":      &graphql.Field{Type: graphql.String},
+			"director":         &graphql.Field{Type: graphql.String},
+			"tags":             &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"posterUrl":        &graphql.Field{Type: graphql.String},
+			"backdropUrl":      &graphql.Field{Type: graphql.String},
+			"tagline":          &graphql.Field{Type: graphql.String},
+			"releaseDate":      &graphql.Field{Type: graphql.String},
+			"runtimeMinutes":   &graphql.Field{Type: graphql.Int},
+			"certification":    &graphql.Field{Type: graphql.String},
+			"originalLanguage": &graphql.Field{Type: graphql.String},
+			"version":          &graphql.Field{Type: graphql.Int},
+			"updatedAt":        &graphql.Field{Type: graphql.DateTime},
+			"genres": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					var res []string
+					for _, g := range p.Source.(*model.Metadata).Genres {
+						res = append(res, string(g))
+					}
+					return res, nil
+				},
+			},
+		},
+	})
+	personType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Person",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	creditType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Credit",
+		Fields: graphql.Fields{
+			"role":      &graphql.Field{Type: graphql.String},
+			"character": &graphql.Field{Type: graphql.String},
+			"order":     &graphql.Field{Type: graphql.Int},
+			"movie": &graphql.Field{
+				Type: movieType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return loadMovie(p.Context, p.Source.(model.Credit).MovieID)
+				},
+			},
+			"person": &graphql.Field{
+				Type: personType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					thunk := loadersFrom(p.Context).people.Load(p.Context, p.Source.(model.Credit).PersonID)
+					return func() (any, error) {
+						person, err := thunk()
+						if err != nil || person == nil {
+							return nil, err
+						}
+						return person, nil
+					}, nil
+				},
+			},
+		},
+	})
+	movieType.AddFieldConfig("credits", &graphql.Field{
+		Type: graphql.NewList(creditType),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			thunk := loadersFrom(p.Context).credits.Load(p.Context, p.Source.(*model.Metadata).ID)
+			return func() (any, error) {
+				return thunk()
+			}, nil
+		},
+	})
+	movieType.AddFieldConfig("similar", &graphql.Field{
+		Type: graphql.NewList(movieType),
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			limit, _ := p.Args["limit"].(int)
+			res, err := h.ctrl.Similar(p.Context, p.Source.(*model.Metadata).ID, limit)
+			if err != nil {
+				return nil, err
+			}
+			ms := make([]*model.Metadata, 0, len(res))
+			for _, s := range res {
+				ms = append(ms, s.Metadata)
+			}
+			return ms, nil
+		},
+	})
+	personType.AddFieldConfig("filmography", &graphql.Field{
+		Type: graphql.NewList(creditType),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			thunk := loadersFrom(p.Context).filmography.Load(p.Context, p.Source.(*model.Person).ID)
+			return func() (any, error) {
+				return thunk()
+			}, nil
+		},
+	})
+	pageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MoviePage",
+		Fields: graphql.Fields{
+			"movies":        &graphql.Field{Type: graphql.NewList(movieType)},
+			"nextPageToken": &graphql.Field{Type: graphql.String},
+		},
+	})
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"movie": &graphql.Field{
+				Type: movieType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return loadMovie(p.Context, p.Args["id"].(string))
+				},
+			},
+			"movies": &graphql.Field{
+				Type: graphql.NewList(movieType),
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					ids, _ := p.Args["ids"].([]any)
+					if len(ids) > metadata.MaxBatchSize {
+						return nil, metadata.ErrTooManyIDs
+					}
+					res := make([]any, 0, len(ids))
+					for _, id := range ids {
+						m, err := loadMovie(p.Context, id.(string))
+						if err != nil {
+							return nil, err
+						}
+						res = append(res, m)
+					}
+					return res, nil
+				},
+			},
+			"person": &graphql.Field{
+				Type: personType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					person, err := h.ctrl.Person(p.Context, p.Args["id"].(string))
+					if err != nil && errors.Is(err, metadata.ErrNotFound) {
+						return nil, nil
+					}
+					return person, err
+				},
+			},
+			"search": &graphql.Field{
+				Type: pageType,
+				Args: graphql.FieldConfigArgument{
+					"query":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"pageSize":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"pageToken": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					pageSize, _ := p.Args["pageSize"].(int)
+					pageToken, _ := p.Args["pageToken"].(string)
+					res, next, err := h.ctrl.Search(p.Context, p.Args["query"].(string), model.Filter{}, pageSize, pageToken)
+					if err != nil {
+						return nil, err
+					}
+					return map[string]any{"movies": res, "nextPageToken": next}, nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
@@ -2,24 +2,47 @@ package grpc
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
+	"strings"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"movieapp.com/gen"
 	"movieapp.com/metadata/internal/controller/metadata"
 	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/problem"
 )
 
 // Handler defines a movie metadata gRPC handler.
 type Handler struct {
 	gen.UnimplementedMetadataServiceServer
-	ctrl *metadata.Controller
+	ctrl       *metadata.Controller
+	adminToken string
 }
 
-// New creates a new movie metadata gRPC handler.
-func New(ctrl *metadata.Controller) *Handler {
-	return &Handler{ctrl: ctrl}
+// New creates a new movie metadata gRPC handler. Writing, deleting,
+// restoring and merging metadata, and reading deleted metadata, are
+// admin calls carrying the admin token as a bearer token in their
+// authorization metadata, all rejected if it is empty.
+func New(ctrl *metadata.Controller, adminToken string) *Handler {
+	return &Handler{ctrl: ctrl, adminToken: adminToken}
+}
+
+// requireAdmin returns an Unauthenticated error unless the call
+// carries the admin token.
+func (h *Handler) requireAdmin(ctx context.Context) error {
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	if vals := md.Get("authorization"); len(vals) > 0 && h.adminToken != "" {
+		token, ok := strings.CutPrefix(vals[0], "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid admin token")
 }
 
 // GetMetadata returns movie metadata.
@@ -27,11 +50,311 @@ func (h *Handler) GetMetadata(ctx context.Context, req *gen.GetMetadataRequest)
 	if req == nil || req.MovieId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	m, err := h.ctrl.Get(ctx, req.MovieId)
-	if err != nil && errors.Is(err, metadata.ErrNotFound) {
-		return nil, status.Errorf(codes.NotFound, err.Error())
-	} else if err != nil {
-		return nil, status.Errorf(codes.Internal, err.Error())
+	var m *model.Metadata
+	var err error
+	if req.IncludeDeleted {
+		if err := h.requireAdmin(ctx); err != nil {
+			return nil, err
+		}
+		m, err = h.ctrl.GetIncludingDeleted(ctx, req.MovieId)
+	} else {
+		m, err = h.ctrl.Get(ctx, req.MovieId)
+	}
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	if req.Locale != "" {
+		m = m.Localize(req.Locale)
+	}
+	etag := m.ETag()
+	if req.IfNoneMatch != "" && req.IfNoneMatch == etag {
+		return &gen.GetMetadataResponse{Etag: etag, NotModified: true}, nil
+	}
+	return &gen.GetMetadataResponse{Metadata: model.MetadataToProto(m), Etag: etag}, nil
+}
+
+// GetManyMetadata returns metadata of several movies at once.
+func (h *Handler) GetManyMetadata(ctx context.Context, req *gen.GetManyMetadataRequest) (*gen.GetManyMetadataResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	res, err := h.ctrl.GetMany(ctx, req.MovieIds)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	if req.Locale != "" {
+		for i, m := range res {
+			res[i] = m.Localize(req.Locale)
+		}
+	}
+	return &gen.GetManyMetadataResponse{Metadata: metadataToProto(res)}, nil
+}
+
+// PutMetadata writes movie metadata as a new version, for admin
+// calls.
+func (h *Handler) PutMetadata(ctx context.Context, req *gen.PutMetadataRequest) (*gen.PutMetadataResponse, error) {
+	if req == nil || req.Metadata == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or metadata")
+	}
+	if err := h.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	ctx = audit.WithActor(ctx, audit.Admin)
+	m := model.MetadataFromProto(req.Metadata)
+	if err := h.ctrl.Put(ctx, m, req.Author); err != nil {
+		return nil, putStatus(err)
+	}
+	return &gen.PutMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// GetMetadataByExternalId returns movie metadata by its id in
+// another catalog.
+func (h *Handler) GetMetadataByExternalId(ctx context.Context, req *gen.GetMetadataByExternalIdRequest) (*gen.GetMetadataByExternalIdResponse, error) {
+	if req == nil || req.Source == "" || req.ExternalId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty source or external id")
+	}
+	m, err := h.ctrl.GetByExternalID(ctx, model.ExternalSource(req.Source), req.ExternalId)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.GetMetadataByExternalIdResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// PutMetadataByExternalId upserts movie metadata by its id in
+// another catalog, for admin calls.
+func (h *Handler) PutMetadataByExternalId(ctx context.Context, req *gen.PutMetadataByExternalIdRequest) (*gen.PutMetadataByExternalIdResponse, error) {
+	if req == nil || req.Metadata == nil || req.Source == "" || req.ExternalId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or metadata or empty source or external id")
+	}
+	if err := h.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	ctx = audit.WithActor(ctx, audit.Admin)
+	m := model.MetadataFromProto(req.Metadata)
+	if err := h.ctrl.PutByExternalID(ctx, model.ExternalSource(req.Source), req.ExternalId, m, req.Author); err != nil {
+		return nil, putStatus(err)
+	}
+	return &gen.PutMetadataByExternalIdResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// GetSimilarMetadata returns the movies most similar to a movie.
+func (h *Handler) GetSimilarMetadata(ctx context.Context, req *gen.GetSimilarMetadataRequest) (*gen.GetSimilarMetadataResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	res, err := h.ctrl.Similar(ctx, req.MovieId, int(req.Limit))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	resp := &gen.GetSimilarMetadataResponse{}
+	for _, s := range res {
+		resp.Similar = append(resp.Similar, &gen.SimilarMetadata{Metadata: model.MetadataToProto(s.Metadata), Score: s.Score})
+	}
+	return resp, nil
+}
+
+// ListMetadata returns a page of movie metadata matching a filter.
+func (h *Handler) ListMetadata(ctx context.Context, req *gen.ListMetadataRequest) (*gen.ListMetadataResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	filter := filterFromProto(req)
+	if filter.IncludeDeleted {
+		if err := h.requireAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+	res, next, err := h.ctrl.List(ctx, filter, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.ListMetadataResponse{Metadata: metadataToProto(res), NextPageToken: next}, nil
+}
+
+// ExportMetadata streams all movie metadata matching a filter.
+func (h *Handler) ExportMetadata(req *gen.ExportMetadataRequest, stream gen.MetadataService_ExportMetadataServer) error {
+	if req == nil {
+		return status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	filter := filterFromProto(req)
+	if filter.IncludeDeleted {
+		if err := h.requireAdmin(stream.Context()); err != nil {
+			return err
+		}
+	}
+	if req.UpdatedSince != nil {
+		filter.UpdatedSince = req.UpdatedSince.AsTime()
+	}
+	err := h.ctrl.Export(stream.Context(), filter, func(ms []*model.Metadata) error {
+		return stream.Send(&gen.ExportMetadataResponse{Metadata: metadataToProto(ms)})
+	})
+	if err != nil {
+		return problem.Status(err)
+	}
+	return nil
+}
+
+// SearchMetadata returns a page of movie metadata matching a text
+// query and a filter.
+func (h *Handler) SearchMetadata(ctx context.Context, req *gen.SearchMetadataRequest) (*gen.SearchMetadataResponse, error) {
+	if req == nil || req.Query == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty query")
+	}
+	filter := filterFromProto(req)
+	if filter.IncludeDeleted {
+		if err := h.requireAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+	res, next, err := h.ctrl.Search(ctx, req.Query, filter, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	resp := &gen.SearchMetadataResponse{Metadata: metadataToProto(res), NextPageToken: next}
+	if req.IncludeFacets {
+		facets, err := h.ctrl.Facets(ctx, req.Query, filter)
+		if err != nil {
+			return nil, problem.Status(err)
+		}
+		resp.Facets = model.FacetsToProto(facets)
+	}
+	return resp, nil
+}
+
+// DeleteMetadata removes movie metadata from the catalog, for
+// admin calls.
+func (h *Handler) DeleteMetadata(ctx context.Context, req *gen.DeleteMetadataRequest) (*gen.DeleteMetadataResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	if err := h.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	ctx = audit.WithActor(ctx, audit.Admin)
+	m, err := h.ctrl.Delete(ctx, req.MovieId, req.Author)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.DeleteMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// RestoreMetadata brings deleted movie metadata back to the
+// catalog, for admin calls.
+func (h *Handler) RestoreMetadata(ctx context.Context, req *gen.RestoreMetadataRequest) (*gen.RestoreMetadataResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	if err := h.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	ctx = audit.WithActor(ctx, audit.Admin)
+	m, err := h.ctrl.Restore(ctx, req.MovieId, req.Author)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.RestoreMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+type filterRequest interface {
+	GetGenres() []string
+	GetTags() []string
+	GetYearFrom() int32
+	GetYearTo() int32
+	GetCertifications() []string
+	GetOriginalLanguage() string
+	GetMinRuntime() int32
+	GetMaxRuntime() int32
+	GetIncludeDeleted() bool
+}
+
+func filterFromProto(req filterRequest) model.Filter {
+	return model.Filter{
+		Genres:           genresFromProto(req.GetGenres()),
+		Tags:             req.GetTags(),
+		YearFrom:         int(req.GetYearFrom()),
+		YearTo:           int(req.GetYearTo()),
+		Certifications:   req.GetCertifications(),
+		OriginalLanguage: req.GetOriginalLanguage(),
+		MinRuntime:       int(req.GetMinRuntime()),
+		MaxRuntime:       int(req.GetMaxRuntime()),
+		IncludeDeleted:   req.GetIncludeDeleted(),
+	}
+}
+
+// CheckDuplicateMetadata returns existing metadata likely
+// describing the same movie as the given metadata.
+func (h *Handler) CheckDuplicateMetadata(ctx context.Context, req *gen.CheckDuplicateMetadataRequest) (*gen.CheckDuplicateMetadataResponse, error) {
+	if req == nil || req.Metadata == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or metadata")
+	}
+	matches, err := h.ctrl.CheckDuplicates(ctx, model.MetadataFromProto(req.Metadata))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	resp := &gen.CheckDuplicateMetadataResponse{}
+	for _, m := range matches {
+		resp.Duplicates = append(resp.Duplicates, &gen.DuplicateMetadata{Metadata: model.MetadataToProto(m.Metadata), Score: m.Score})
+	}
+	return resp, nil
+}
+
+// MergeMetadata merges a duplicate into another movie, for admin
+// calls.
+func (h *Handler) MergeMetadata(ctx context.Context, req *gen.MergeMetadataRequest) (*gen.MergeMetadataResponse, error) {
+	if req == nil || req.TargetId == "" || req.SourceId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty target or source id")
+	}
+	if err := h.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	ctx = audit.WithActor(ctx, audit.Admin)
+	m, err := h.ctrl.Merge(ctx, req.TargetId, req.SourceId, req.Author)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.MergeMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+func genresFromProto(genres []string) []model.Genre {
+	var res []model.Genre
+	for _, g := range genres {
+		res = append(res, model.Genre(g))
+	}
+	return res
+}
+
+func metadataToProto(ms []*model.Metadata) []*gen.Metadata {
+	var res []*gen.Metadata
+	for _, m := range ms {
+		res = append(res, model.MetadataToProto(m))
+	}
+	return res
+}
+
+func putStatus(err error) error {
+	var validationErr *metadata.ValidationError
+	var duplicateErr *metadata.DuplicateError
+	switch {
+	case errors.As(err, &validationErr):
+		return validationStatus(validationErr)
+	case errors.As(err, &duplicateErr):
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	default:
+		return problem.Status(err)
+	}
+}
+
+func validationStatus(err *metadata.ValidationError) error {
+	br := &errdetails.BadRequest{}
+	for _, f := range err.Fields {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Message,
+		})
+	}
+	st, detailsErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(br)
+	if detailsErr != nil {
+		return status.Errorf(codes.InvalidArgument, err.Error())
 	}
-	return &gen.GetMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+	return st.Err()
 }
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"movieapp.com/metadata/internal/reindex"
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+)
+
+// AdminHandler defines a metadata admin HTTP handler. Requests
+// must carry the admin token as a bearer token.
+type AdminHandler struct {
+	reindexer *reindex.Reindexer
+	audit     *audit.Logger
+	token     string
+}
+
+// NewAdmin creates a new metadata admin HTTP handler recording the
+// reindexes started in the audit log. With an empty token all
+// requests are rejected.
+func NewAdmin(reindexer *reindex.Reindexer, auditLog *audit.Logger, token string) *AdminHandler {
+	return &AdminHandler{reindexer, auditLog, token}
+}
+
+// Reindex handles /admin/reindex requests. POST starts a reindex
+// of all metadata, or of the metadata updated since the since
+// parameter (RFC 3339), with up to concurrency parallel index
+// writes. GET returns the progress of the job with the given id.
+func (h *AdminHandler) Reindex(w http.ResponseWriter, req *http.Request) {
+	if !h.authorized(req) {
+		problem.Write(w, req, problem.Unauthenticated, "invalid admin token")
+		return
+	}
+	switch req.Method {
+	case http.MethodPost:
+		h.startReindex(w, req)
+	case http.MethodGet:
+		h.getReindex(w, req)
+	default:
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+	}
+}
+
+func (h *AdminHandler) startReindex(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		Since       time.Time `form:"since"`
+		Concurrency int       `form:"concurrency" validate:"min=0"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	job, err := h.reindexer.Start(params.Since, params.Concurrency)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	h.audit.Record(req.Context(), audit.Entry{Actor: audit.Admin, Action: "metadata.reindex", Resource: job.ID})
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+func (h *AdminHandler) getReindex(w http.ResponseWriter, req *http.Request) {
+	var params idParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	job, err := h.reindexer.Job(params.ID)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// Audit handles GET /admin/audit requests, returning the entries of
+// the audit log matching the filter in the query parameters as
+// audit.Logger.Handler.
+func (h *AdminHandler) Audit(w http.ResponseWriter, req *http.Request) {
+	if !h.authorized(req) {
+		problem.Write(w, req, problem.Unauthenticated, "invalid admin token")
+		return
+	}
+	h.audit.Handler().ServeHTTP(w, req)
+}
+
+func (h *AdminHandler) authorized(req *http.Request) bool {
+	return adminAuthorized(req, h.token)
+}
+
+// adminAuthorized reports whether the request carries the admin
+// token as a bearer token, never if the token is empty.
+func adminAuthorized(req *http.Request, adminToken string) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
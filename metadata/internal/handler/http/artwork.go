@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"movieapp.com/metadata/internal/artwork"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+)
+
+// ArtworkHandler defines a movie artwork upload HTTP handler.
+// Requests must carry the admin token as a bearer token.
+type ArtworkHandler struct {
+	uploader   *artwork.Uploader
+	adminToken string
+}
+
+// NewArtwork creates a new movie artwork upload HTTP handler. With
+// an empty admin token all requests are rejected.
+func NewArtwork(uploader *artwork.Uploader, adminToken string) *ArtworkHandler {
+	return &ArtworkHandler{uploader, adminToken}
+}
+
+// UploadArtwork handles POST /metadata/artwork admin requests with
+// the movie id, the image kind (poster or backdrop) and the image as
+// the body, returning the metadata with the new artwork URL.
+func (h *ArtworkHandler) UploadArtwork(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	if !adminAuthorized(req, h.adminToken) {
+		problem.Write(w, req, problem.Unauthenticated, "invalid admin token")
+		return
+	}
+	var params struct {
+		ID     string          `form:"id" validate:"required"`
+		Kind   model.ImageKind `form:"kind"`
+		Author string          `form:"author"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	contentType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		problem.Write(w, req, problem.UnsupportedMediaType, "invalid Content-Type")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(req.Body, artwork.MaxBytes+1))
+	if err != nil {
+		problem.Write(w, req, problem.BadRequest, "unreadable body")
+		return
+	}
+	m, err := h.uploader.Upload(audit.WithActor(req.Context(), audit.Admin), params.ID, params.Kind, contentType, data, params.Author)
+	if err != nil {
+		writePutError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
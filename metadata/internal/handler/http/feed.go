@@ -0,0 +1,157 @@
+package http
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"movieapp.com/metadata/internal/feed"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+)
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// FeedHandler defines a catalog feed HTTP handler serving the
+// sitemap for search engines and the JSON feed for partners.
+type FeedHandler struct {
+	generator *feed.Generator
+	siteURL   string
+	baseURL   string
+}
+
+// NewFeed creates a new catalog feed HTTP handler. Sitemap URLs
+// point at the movie pages under siteURL, and sitemap index
+// entries at the sitemap pages served under baseURL.
+func NewFeed(generator *feed.Generator, siteURL string, baseURL string) *FeedHandler {
+	return &FeedHandler{generator, strings.TrimSuffix(siteURL, "/"), strings.TrimSuffix(baseURL, "/")}
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap handles GET /sitemap.xml requests. Without a page
+// parameter it returns the sitemap index, otherwise the given
+// sitemap page.
+func (h *FeedHandler) Sitemap(w http.ResponseWriter, req *http.Request) {
+	if req.FormValue("page") == "" {
+		s, err := h.generator.Snapshot(req.Context())
+		if err != nil {
+			writeFeedError(w, req, err)
+			return
+		}
+		index := sitemapIndex{XMLNS: sitemapNamespace}
+		for i, page := range s.Pages {
+			index.Sitemaps = append(index.Sitemaps, sitemapEntry{
+				Loc:     fmt.Sprintf("%s/sitemap.xml?page=%d", h.baseURL, i+1),
+				LastMod: formatLastMod(feed.LastModified(page)),
+			})
+		}
+		writeXML(w, req, index)
+		return
+	}
+	page, _, _, ok := h.page(w, req)
+	if !ok {
+		return
+	}
+	set := urlSet{XMLNS: sitemapNamespace}
+	for _, e := range page {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     h.siteURL + "/movies/" + url.PathEscape(e.ID),
+			LastMod: formatLastMod(e.LastModified),
+		})
+	}
+	writeXML(w, req, set)
+}
+
+// Feed handles GET /feed.json requests with an optional page,
+// the first one by default.
+func (h *FeedHandler) Feed(w http.ResponseWriter, req *http.Request) {
+	page, n, s, ok := h.page(w, req)
+	if !ok {
+		return
+	}
+	resp := struct {
+		Items       []feed.Entry `json:"items"`
+		Page        int          `json:"page"`
+		Pages       int          `json:"pages"`
+		GeneratedAt time.Time    `json:"generatedAt"`
+	}{page, n, len(s.Pages), s.GeneratedAt}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Last-Modified", s.GeneratedAt.Format(http.TimeFormat))
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// page returns the requested feed page and its number, writing
+// the error response if it is not available.
+func (h *FeedHandler) page(w http.ResponseWriter, req *http.Request) ([]feed.Entry, int, *feed.Snapshot, bool) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return nil, 0, nil, false
+	}
+	params := struct {
+		Page int `form:"page" validate:"min=1"`
+	}{Page: 1}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return nil, 0, nil, false
+	}
+	n := params.Page
+	page, s, err := h.generator.Page(req.Context(), n)
+	if err != nil {
+		writeFeedError(w, req, err)
+		return nil, 0, nil, false
+	}
+	return page, n, s, true
+}
+
+func writeFeedError(w http.ResponseWriter, req *http.Request, err error) {
+	if errors.Is(err, feed.ErrNotReady) {
+		w.Header().Set("Retry-After", "60")
+	}
+	problem.Error(w, req, err)
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func writeXML(w http.ResponseWriter, req *http.Request, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		logger.ErrorContext(req.Context(), "Response write error", "error", err)
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
@@ -1,44 +1,558 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
 
+	"movieapp.com/internal/httputil"
 	"movieapp.com/metadata/internal/controller/metadata"
-	"movieapp.com/metadata/internal/repository"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
 )
 
+var logger = logging.New("handler/http")
+
 // Handler defines a movie metadata HTTP handler.
 type Handler struct {
-	ctrl *metadata.Controller
+	ctrl       *metadata.Controller
+	adminToken string
+}
+
+// New creates a new movie metadata HTTP handler. Writing, deleting,
+// restoring, reverting and merging metadata, and reading deleted
+// metadata or past versions, are admin requests carrying the admin
+// token as a bearer token, all rejected if it is empty.
+func New(ctrl *metadata.Controller, adminToken string) *Handler {
+	return &Handler{ctrl, adminToken}
 }
 
-// New creates a new movie metadata HTTP handler.
-func New(ctrl *metadata.Controller) *Handler {
-	return &Handler{ctrl}
+// admin reports whether the request carries the admin token,
+// rejecting it with 401 otherwise.
+func (h *Handler) admin(w http.ResponseWriter, req *http.Request) bool {
+	if !adminAuthorized(req, h.adminToken) {
+		problem.Write(w, req, problem.Unauthenticated, "invalid admin token")
+		return false
+	}
+	return true
 }
 
-// GetMetadata handles GET /metadata requests.
+// GetMetadata handles GET /metadata requests. The text is
+// localized for the locale query parameter or, if absent, the
+// Accept-Language header; without either the metadata is
+// returned with all its localizations. Requests carrying the
+// current ETag in If-None-Match get a 304 without a body.
+// Deleted metadata is only returned to admin requests with
+// includeDeleted=true and requests for merged duplicates are
+// redirected.
 func (h *Handler) GetMetadata(w http.ResponseWriter, req *http.Request) {
-	id := req.FormValue("id")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	var params struct {
+		ID             string `form:"id" validate:"required"`
+		IncludeDeleted bool   `form:"includeDeleted"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if params.IncludeDeleted && !h.admin(w, req) {
 		return
 	}
+	id := params.ID
 	ctx := req.Context()
-	m, err := h.ctrl.Get(ctx, id)
-	if err != nil && errors.Is(err, repository.ErrNotFound) {
-		w.WriteHeader(http.StatusNotFound)
+	w.Header().Set("Vary", "Accept-Language")
+	var m *model.Metadata
+	var err error
+	if params.IncludeDeleted {
+		m, err = h.ctrl.GetIncludingDeleted(ctx, id)
+	} else {
+		m, err = h.ctrl.Get(ctx, id)
+	}
+	if locales := requestLocales(req); err == nil && len(locales) > 0 {
+		m = m.Localize(locales...)
+	}
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if m.ID != id {
+		// The requested metadata was merged into another one.
+		values := req.URL.Query()
+		values.Set("id", m.ID)
+		http.Redirect(w, req, req.URL.Path+"?"+values.Encode(), http.StatusMovedPermanently)
+		return
+	}
+	if m.Locale != "" {
+		w.Header().Set("Content-Language", m.Locale)
+	}
+	etag := m.ETag()
+	w.Header().Set("ETag", etag)
+	if httputil.ETagMatches(req.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logger.ErrorContext(ctx, "Response encode error", "error", err)
+	}
+
+}
+
+// GetManyMetadata handles GET /metadata/batch requests with
+// repeated id parameters. The text is localized like in
+// GetMetadata.
+func (h *Handler) GetManyMetadata(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		IDs []string `form:"id"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.GetMany(req.Context(), params.IDs)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	w.Header().Set("Vary", "Accept-Language")
+	if locales := requestLocales(req); len(locales) > 0 {
+		for i, m := range res {
+			res[i] = m.Localize(locales...)
+		}
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// PutMetadata handles PUT /metadata admin requests with the
+// metadata encoded as JSON in the body and the author of the change.
+func (h *Handler) PutMetadata(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	if !h.admin(w, req) {
 		return
-	} else if err != nil {
-		log.Printf("Repository get error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
+	}
+	var params struct {
+		Author string `form:"author"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	var m model.Metadata
+	if err := request.DecodeJSON(req, &m); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := h.ctrl.Put(audit.WithActor(req.Context(), audit.Admin), &m, params.Author); err != nil {
+		writePutError(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(&m); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetMetadataByExternalID handles GET /metadata/external requests
+// with the external source and id, e.g. source=imdb&id=tt0111161.
+func (h *Handler) GetMetadataByExternalID(w http.ResponseWriter, req *http.Request) {
+	var params externalIDParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	m, err := h.ctrl.GetByExternalID(req.Context(), params.Source, params.ID)
+	if err != nil {
+		problem.Error(w, req, err)
 		return
 	}
 	if err := json.NewEncoder(w).Encode(m); err != nil {
-		log.Printf("Response encode error: %v\n", err)
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
 	}
+}
 
+// PutMetadataByExternalID handles PUT /metadata/external admin
+// requests.
+func (h *Handler) PutMetadataByExternalID(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	if !h.admin(w, req) {
+		return
+	}
+	var params struct {
+		externalIDParams
+		Author string `form:"author"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	var m model.Metadata
+	if err := request.DecodeJSON(req, &m); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := h.ctrl.PutByExternalID(audit.WithActor(req.Context(), audit.Admin), params.Source, params.ID, &m, params.Author); err != nil {
+		writePutError(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(&m); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// CheckDuplicates handles POST /metadata/duplicates requests with
+// the metadata encoded as JSON in the body, returning existing
+// metadata likely describing the same movie.
+func (h *Handler) CheckDuplicates(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	var m model.Metadata
+	if err := request.DecodeJSON(req, &m); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	matches, err := h.ctrl.CheckDuplicates(req.Context(), &m)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	writeDuplicates(w, req, matches)
+}
+
+// MergeMetadata handles POST /metadata/merge admin requests
+// merging the source duplicate into the target.
+func (h *Handler) MergeMetadata(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	if !h.admin(w, req) {
+		return
+	}
+	var params struct {
+		TargetID string `form:"target" validate:"required"`
+		SourceID string `form:"source" validate:"required"`
+		Author   string `form:"author"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	m, err := h.ctrl.Merge(audit.WithActor(req.Context(), audit.Admin), params.TargetID, params.SourceID, params.Author)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// externalIDParams defines the parameters identifying metadata by
+// an external id.
+type externalIDParams struct {
+	Source model.ExternalSource `form:"source" validate:"required"`
+	ID     string               `form:"id" validate:"required"`
+}
+
+func writeDuplicates(w http.ResponseWriter, req *http.Request, matches []model.DuplicateMatch) {
+	w.Header().Set("Content-Type", "application/json")
+	resp := struct {
+		Duplicates []model.DuplicateMatch `json:"duplicates"`
+	}{matches}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+func writePutError(w http.ResponseWriter, req *http.Request, err error) {
+	var validationErr *metadata.ValidationError
+	var duplicateErr *metadata.DuplicateError
+	switch {
+	case errors.As(err, &validationErr):
+		problem.New(req, problem.Invalid, err.Error()).With("errors", validationErr.Fields).Write(w, req)
+	case errors.As(err, &duplicateErr):
+		problem.New(req, problem.Conflict, err.Error()).With("duplicates", duplicateErr.Matches).Write(w, req)
+	default:
+		problem.Error(w, req, err)
+	}
+}
+
+// DeleteMetadata handles DELETE /metadata admin requests.
+func (h *Handler) DeleteMetadata(w http.ResponseWriter, req *http.Request) {
+	h.setDeleted(w, req, http.MethodDelete, h.ctrl.Delete)
+}
+
+// RestoreMetadata handles POST /metadata/restore admin requests.
+func (h *Handler) RestoreMetadata(w http.ResponseWriter, req *http.Request) {
+	h.setDeleted(w, req, http.MethodPost, h.ctrl.Restore)
+}
+
+func (h *Handler) setDeleted(w http.ResponseWriter, req *http.Request, method string,
+	fn func(ctx context.Context, id string, author string) (*model.Metadata, error)) {
+	if req.Method != method {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	if !h.admin(w, req) {
+		return
+	}
+	var params struct {
+		ID     string `form:"id" validate:"required"`
+		Author string `form:"author"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	m, err := fn(audit.WithActor(req.Context(), audit.Admin), params.ID, params.Author)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetMetadataVersion handles GET /metadata/version admin requests,
+// as versions may be of deleted or merged metadata.
+func (h *Handler) GetMetadataVersion(w http.ResponseWriter, req *http.Request) {
+	if !h.admin(w, req) {
+		return
+	}
+	var params versionParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	m, err := h.ctrl.GetVersion(req.Context(), params.ID, params.Version)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// versionParams defines the parameters identifying a version of
+// metadata.
+type versionParams struct {
+	ID      string `form:"id" validate:"required"`
+	Version int    `form:"version" validate:"required,min=1"`
+}
+
+// GetMetadataHistory handles GET /metadata/history admin requests.
+func (h *Handler) GetMetadataHistory(w http.ResponseWriter, req *http.Request) {
+	if !h.admin(w, req) {
+		return
+	}
+	var params idParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	history, err := h.ctrl.History(req.Context(), params.ID)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// RevertMetadata handles POST /metadata/revert admin requests
+// restoring the given version of movie metadata.
+func (h *Handler) RevertMetadata(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	if !h.admin(w, req) {
+		return
+	}
+	var params struct {
+		versionParams
+		Author string `form:"author"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	m, err := h.ctrl.Revert(audit.WithActor(req.Context(), audit.Admin), params.ID, params.Version, params.Author)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// ListMetadata handles GET /metadata/list requests. Results
+// can be narrowed down with repeated genre, tag and certification
+// parameters, language, yearFrom, yearTo, minRuntime and
+// maxRuntime.
+// Deleted metadata is only listed for admin requests with
+// includeDeleted=true.
+func (h *Handler) ListMetadata(w http.ResponseWriter, req *http.Request) {
+	var params listParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if params.IncludeDeleted && !h.admin(w, req) {
+		return
+	}
+	res, next, err := h.ctrl.List(req.Context(), params.Filter, params.PageSize, params.PageToken)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(&model.Page{Metadata: res, NextPageToken: next}); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// listParams defines the parameters of metadata listings.
+type listParams struct {
+	model.Filter
+	PageSize  int    `form:"pageSize" validate:"min=0"`
+	PageToken string `form:"pageToken"`
+}
+
+// SearchMetadata handles GET /metadata/search requests. With
+// facets=true the hits are also counted by facet. Deleted metadata
+// is only found like in ListMetadata.
+func (h *Handler) SearchMetadata(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		listParams
+		Query  string `form:"q" validate:"required"`
+		Facets bool   `form:"facets"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if params.IncludeDeleted && !h.admin(w, req) {
+		return
+	}
+	res, next, err := h.ctrl.Search(req.Context(), params.Query, params.Filter, params.PageSize, params.PageToken)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	page := &model.Page{Metadata: res, NextPageToken: next}
+	if params.Facets {
+		if page.Facets, err = h.ctrl.Facets(req.Context(), params.Query, params.Filter); err != nil {
+			problem.Error(w, req, err)
+			return
+		}
+	}
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetGenres handles GET /genres requests.
+func (h *Handler) GetGenres(w http.ResponseWriter, req *http.Request) {
+	if err := json.NewEncoder(w).Encode(h.ctrl.Genres(req.Context())); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// idParams defines the parameter identifying metadata or a
+// person.
+type idParams struct {
+	ID string `form:"id" validate:"required"`
+}
+
+// GetCredits handles GET /metadata/credits requests.
+func (h *Handler) GetCredits(w http.ResponseWriter, req *http.Request) {
+	var params idParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	credits, err := h.ctrl.Credits(req.Context(), params.ID)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(credits); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetSimilar handles GET /metadata/similar requests.
+func (h *Handler) GetSimilar(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		ID    string `form:"id" validate:"required"`
+		Limit int    `form:"limit" validate:"min=0"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.Similar(req.Context(), params.ID, params.Limit)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetPerson handles GET /person requests.
+func (h *Handler) GetPerson(w http.ResponseWriter, req *http.Request) {
+	var params idParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	p, err := h.ctrl.Person(req.Context(), params.ID)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetFilmography handles GET /person/filmography requests.
+func (h *Handler) GetFilmography(w http.ResponseWriter, req *http.Request) {
+	var params idParams
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	credits, err := h.ctrl.Filmography(req.Context(), params.ID)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(credits); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+func requestLocales(req *http.Request) []string {
+	if l := req.FormValue("locale"); l != "" {
+		return []string{l}
+	}
+	return model.ParseAcceptLanguage(req.Header.Get("Accept-Language"))
 }
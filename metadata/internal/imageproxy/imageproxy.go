@@ -0,0 +1,224 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/singleflight"
+	"movieapp.com/internal/httputil"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/cache/memory"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+)
+
+var logger = logging.New("imageproxy")
+
+// Widths defines the image widths clients can request. Keeping
+// the set small keeps the cache hit rate high.
+var Widths = []int{92, 154, 185, 342, 500, 780, 1280}
+
+const (
+	defaultWidth   = 342
+	maxOriginBytes = 20 << 20
+	// maxOriginPixels bounds the size of decoded origin images, whose
+	// small files may declare huge dimensions.
+	maxOriginPixels = 40 << 20
+	maxCacheItems   = 512
+	maxCacheBytes   = 256 << 20
+	cacheMaxAge     = 7 * 24 * time.Hour
+)
+
+var errOrigin = errors.New("failed to fetch origin image")
+
+type metadataGetter interface {
+	Get(ctx context.Context, id string) (*model.Metadata, error)
+}
+
+type cacheKey struct {
+	url   string
+	width int
+}
+
+type cachedImage struct {
+	data        []byte
+	contentType string
+	etag        string
+}
+
+// Proxy serves movie artwork resized to the requested width so
+// that clients do not hotlink full resolution origin images.
+type Proxy struct {
+	metadata metadataGetter
+	client   *http.Client
+	cache    *memory.LRU[cacheKey, *cachedImage]
+	// loads coalesces concurrent fetches of the same image.
+	loads singleflight.Group
+}
+
+// New creates a new image proxy.
+func New(metadata metadataGetter) *Proxy {
+	return &Proxy{
+		metadata: metadata,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		cache: memory.New(memory.Config[cacheKey, *cachedImage]{
+			Name:       "images",
+			MaxEntries: maxCacheItems,
+			MaxBytes:   maxCacheBytes,
+			Size:       func(_ cacheKey, img *cachedImage) int64 { return int64(len(img.data)) },
+		}),
+	}
+}
+
+// ServeHTTP handles GET /images requests with the movie id,
+// the image kind (poster or backdrop) and the desired width.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	params := struct {
+		ID    string          `form:"id" validate:"required"`
+		Kind  model.ImageKind `form:"kind"`
+		Width int             `form:"width"`
+	}{Kind: model.ImageKindPoster, Width: defaultWidth}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if !slices.Contains(Widths, params.Width) {
+		problem.Error(w, req, request.Invalid("width", fmt.Sprintf("must be one of %v", Widths)))
+		return
+	}
+	kind, width := params.Kind, params.Width
+	m, err := p.metadata.Get(req.Context(), params.ID)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	url := m.ImageURL(kind)
+	if url == "" {
+		problem.Write(w, req, problem.NotFound, "no "+string(kind)+" image")
+		return
+	}
+	img, err := p.image(req.Context(), url, width)
+	if err != nil && errors.Is(err, errOrigin) {
+		logger.ErrorContext(req.Context(), "Image proxy error", "error", err)
+		problem.Write(w, req, problem.BadGateway, "image origin failed")
+		return
+	} else if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cacheMaxAge.Seconds())))
+	w.Header().Set("ETag", img.etag)
+	if httputil.ETagMatches(req.Header.Get("If-None-Match"), img.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", img.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(img.data)))
+	if req.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(img.data); err != nil {
+		logger.ErrorContext(req.Context(), "Response write error", "error", err)
+	}
+}
+
+func (p *Proxy) image(ctx context.Context, url string, width int) (*cachedImage, error) {
+	key := cacheKey{url, width}
+	if img, ok := p.cache.Get(key); ok {
+		return img, nil
+	}
+	// The fetch is shared by the callers of the image, so it does not
+	// fail because the one starting it goes away; the client bounds
+	// it with its timeout.
+	ch := p.loads.DoChan(strconv.Itoa(width)+" "+url, func() (any, error) {
+		img, err := p.fetchResized(context.WithoutCancel(ctx), url, width)
+		if err != nil {
+			return nil, err
+		}
+		p.cache.Add(key, img, 0)
+		return img, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*cachedImage), nil
+	}
+}
+
+func (p *Proxy) fetchResized(ctx context.Context, url string, width int) (*cachedImage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errOrigin, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%w: non-2xx response: %v", errOrigin, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxOriginBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errOrigin, err)
+	}
+	// The dimensions are checked before decoding, which allocates
+	// the whole image.
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errOrigin, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxOriginPixels {
+		return nil, fmt.Errorf("%w: image of %dx%d pixels is too large", errOrigin, cfg.Width, cfg.Height)
+	}
+	src, format, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errOrigin, err)
+	}
+	dst := src
+	if b := src.Bounds(); b.Dx() > width {
+		height := b.Dy() * width / b.Dx()
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, b, draw.Src, nil)
+		dst = scaled
+	}
+	var buf bytes.Buffer
+	contentType := "image/jpeg"
+	if format == "png" {
+		contentType = "image/png"
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return &cachedImage{
+		data:        buf.Bytes(),
+		contentType: contentType,
+		etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+
+	eventbus "movieapp.com/metadata/internal/event/bus"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/outbox"
+)
+
+type eventRepository interface {
+	PendingEvents(ctx context.Context, limit int) ([]*model.Event, error)
+	MarkEventsPublished(ctx context.Context, ids []string) error
+}
+
+// Source delivers the change events recorded in the outbox of a
+// repository keeping them as events, such as the memory and DynamoDB
+// repositories, through an outbox.Relay. Events are written together
+// with the metadata, so none is lost if the broker is down.
+type Source struct {
+	repo eventRepository
+	// mu serializes deliveries, as the repositories do not lock
+	// pending events.
+	mu sync.Mutex
+}
+
+// NewSource creates an outbox source of the events of the
+// repository.
+func NewSource(repo eventRepository) *Source {
+	return &Source{repo: repo}
+}
+
+// Deliver passes the oldest pending events as messages to deliver,
+// marking them published if it succeeds.
+func (s *Source) Deliver(ctx context.Context, limit int, deliver func(ctx context.Context, msgs []outbox.Message) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events, err := s.repo.PendingEvents(ctx, limit)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	msgs := make([]outbox.Message, 0, len(events))
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		m, err := Message(ctx, e)
+		if err != nil {
+			return 0, err
+		}
+		msgs = append(msgs, m)
+		ids = append(ids, e.ID)
+	}
+	if err := deliver(ctx, msgs); err != nil {
+		return 0, err
+	}
+	return len(events), s.repo.MarkEventsPublished(ctx, ids)
+}
+
+// Message returns the outbox message of a change event.
+func Message(ctx context.Context, e *model.Event) (outbox.Message, error) {
+	m, err := eventbus.Encode(ctx, e)
+	if err != nil {
+		return outbox.Message{}, err
+	}
+	return outbox.Message{ID: e.ID, Key: m.Key, Value: m.Value, Headers: m.Headers}, nil
+}
@@ -0,0 +1,208 @@
+package reindex
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/problem"
+)
+
+// ErrNotFound is returned when a reindex job is not found.
+var ErrNotFound = problem.Register(errors.New("reindex job not found"), problem.NotFound)
+
+// ErrRunning is returned when a reindex is requested while
+// another one is still running.
+var ErrRunning = problem.Register(errors.New("reindex already running"), problem.Conflict)
+
+const (
+	batchSize          = 100
+	defaultConcurrency = 4
+	maxConcurrency     = 32
+)
+
+// Indexer defines a search index to write metadata to. Deleted
+// metadata is passed as well so it can be removed from the index.
+type Indexer interface {
+	Index(ctx context.Context, run string, ms []*model.Metadata) error
+}
+
+type metadataLister interface {
+	List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error)
+}
+
+// State defines the state of a reindex job.
+type State string
+
+// Existing states.
+const (
+	StateRunning   = State("running")
+	StateSucceeded = State("succeeded")
+	StateFailed    = State("failed")
+)
+
+// Job defines the progress of a reindex job.
+type Job struct {
+	ID    string `json:"id"`
+	State State  `json:"state"`
+	// Since is the time the metadata was updated since, zero
+	// for a full reindex.
+	Since       time.Time  `json:"since"`
+	Concurrency int        `json:"concurrency"`
+	Indexed     int        `json:"indexed"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Reindexer streams metadata into a search index, for recovering
+// from index corruption or mapping changes. Only one job runs at
+// a time.
+type Reindexer struct {
+	metadata metadataLister
+	indexer  Indexer
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	running bool
+}
+
+// New creates a new reindexer.
+func New(metadata metadataLister, indexer Indexer) *Reindexer {
+	return &Reindexer{metadata: metadata, indexer: indexer, jobs: map[string]*Job{}}
+}
+
+// Start starts reindexing the metadata updated since the given
+// time, or all metadata if it is zero, with the given number of
+// concurrent index writes. Returns the started job.
+func (r *Reindexer) Start(since time.Time, concurrency int) (*Job, error) {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	} else if concurrency > maxConcurrency {
+		concurrency = maxConcurrency
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return nil, ErrRunning
+	}
+	job := &Job{
+		ID:          newJobID(),
+		State:       StateRunning,
+		Since:       since,
+		Concurrency: concurrency,
+		StartedAt:   time.Now().UTC(),
+	}
+	r.jobs[job.ID] = job
+	r.running = true
+	go r.run(job)
+	c := *job
+	return &c, nil
+}
+
+// Job returns the progress of a reindex job.
+func (r *Reindexer) Job(id string) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *job
+	return &c, nil
+}
+
+func (r *Reindexer) run(job *Job) {
+	// The job outlives the request starting it.
+	ctx := context.Background()
+	batches := make(chan []*model.Metadata)
+	var wg sync.WaitGroup
+	for i := 0; i < job.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				err := r.indexer.Index(ctx, job.ID, batch)
+				r.mu.Lock()
+				if err != nil {
+					job.Failed += len(batch)
+					if job.Error == "" {
+						job.Error = err.Error()
+					}
+				} else {
+					job.Indexed += len(batch)
+				}
+				r.mu.Unlock()
+			}
+		}()
+	}
+	listErr := r.list(ctx, job.Since, batches)
+	close(batches)
+	wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	job.State = StateSucceeded
+	if listErr != nil {
+		job.Error = listErr.Error()
+	}
+	if job.Error != "" {
+		job.State = StateFailed
+	}
+	r.running = false
+}
+
+func (r *Reindexer) list(ctx context.Context, since time.Time, batches chan<- []*model.Metadata) error {
+	filter := model.Filter{UpdatedSince: since, IncludeDeleted: true}
+	for token := ""; ; {
+		page, next, err := r.metadata.List(ctx, filter, batchSize, token)
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			batches <- page
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// EventIndexer feeds a search index through the change event
+// stream, publishing reindex events its indexer consumes.
+type EventIndexer struct {
+	publisher eventPublisher
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, events []*model.Event) error
+}
+
+// NewEventIndexer creates an indexer publishing reindex events.
+func NewEventIndexer(publisher eventPublisher) *EventIndexer {
+	return &EventIndexer{publisher}
+}
+
+// Index publishes a reindex event for each of the metadata.
+func (i *EventIndexer) Index(ctx context.Context, run string, ms []*model.Metadata) error {
+	events := make([]*model.Event, 0, len(ms))
+	for _, m := range ms {
+		events = append(events, model.NewReindexEvent(m, run))
+	}
+	return i.publisher.Publish(ctx, events)
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,757 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/metadata/internal/repository"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/tenant"
+)
+
+// ErrConcurrentUpdate is returned when movie metadata is changed
+// by another writer between reading and writing it.
+var ErrConcurrentUpdate = errors.New("metadata changed concurrently")
+
+// Index names of the table, see schema/dynamodb.json.
+const (
+	// externalIndex maps external ids and people to the movies
+	// referencing them.
+	externalIndex = "GSI1"
+	// titleIndex orders movies by title, partitioned by the first
+	// letter of the lowercased title.
+	titleIndex = "GSI2"
+)
+
+const (
+	metaSK         = "META"
+	versionPrefix  = "VERSION#"
+	externalPrefix = "EXTERNAL#"
+	creditPrefix   = "CREDIT#"
+	personSK       = "PERSON"
+	outboxPK       = "OUTBOX"
+	eventPrefix    = "EVENT#"
+	// Limits of the DynamoDB batch APIs.
+	maxBatchGet   = 100
+	maxBatchWrite = 25
+)
+
+// Repository defines a DynamoDB-based movie metadata repository
+// keeping all items in a single table.
+//
+// Movies are partitioned by id, holding the current metadata, its
+// versions, external ids and credits. People and the outbox of
+// change events have partitions of their own. Partition keys are
+// prefixed by the tenant, so that the tenants sharing the table never
+// read each other's items.
+type Repository struct {
+	client *dynamodb.Client
+	table  string
+}
+
+// New creates a new DynamoDB-based repository using the given
+// table and the default AWS configuration.
+func New(ctx context.Context, table string) (*Repository, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{dynamodb.NewFromConfig(cfg), table}, nil
+}
+
+func movieKey(ctx context.Context, id string) string {
+	return tenant.Key(ctx, "MOVIE#"+id)
+}
+
+func personKey(ctx context.Context, id string) string {
+	return tenant.Key(ctx, "PERSON#"+id)
+}
+
+func externalKey(ctx context.Context, source model.ExternalSource, externalID string) string {
+	return tenant.Key(ctx, externalPrefix+string(source)+"#"+externalID)
+}
+
+func outboxKey(ctx context.Context) string {
+	return tenant.Key(ctx, outboxPK)
+}
+
+func versionSK(version int) string {
+	return fmt.Sprintf("%s%010d", versionPrefix, version)
+}
+
+func str(v string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: v}
+}
+
+func key(pk, sk string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"PK": str(pk), "SK": str(sk)}
+}
+
+// titleKeys returns the keys of the title index, so that titles
+// can be looked up by prefix.
+func titleKeys(ctx context.Context, m *model.Metadata) (string, string) {
+	title := strings.ToLower(strings.TrimSpace(m.Title))
+	r, _ := utf8.DecodeRuneInString(title)
+	return tenant.Key(ctx, "TITLE#"+string(r)), title + "#" + m.ID
+}
+
+func marshal(v any) (map[string]types.AttributeValue, error) {
+	return attributevalue.MarshalMapWithOptions(v, func(o *attributevalue.EncoderOptions) {
+		o.TagKey = "json"
+	})
+}
+
+func unmarshal(item map[string]types.AttributeValue, v any) error {
+	return attributevalue.UnmarshalMapWithOptions(item, v, func(o *attributevalue.DecoderOptions) {
+		o.TagKey = "json"
+	})
+}
+
+func unmarshalMetadata(item map[string]types.AttributeValue) (*model.Metadata, error) {
+	m := &model.Metadata{}
+	if err := unmarshal(item, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func metadataItem(pk, sk string, m *model.Metadata) (map[string]types.AttributeValue, error) {
+	item, err := marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	item["PK"], item["SK"] = str(pk), str(sk)
+	return item, nil
+}
+
+// Get retrieves movie metadata for by movie id.
+func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
+	return r.getMetadata(ctx, movieKey(ctx, id), metaSK)
+}
+
+func (r *Repository) getMetadata(ctx context.Context, pk, sk string) (*model.Metadata, error) {
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.table),
+		Key:            key(pk, sk),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, repository.ErrNotFound
+	}
+	return unmarshalMetadata(out.Item)
+}
+
+// GetMany retrieves movie metadata for the given movie ids in
+// the same order, skipping the ones not found.
+func (r *Repository) GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, key(movieKey(ctx, id), metaSK))
+	}
+	items, err := r.batchGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.Metadata, len(items))
+	for _, item := range items {
+		m, err := unmarshalMetadata(item)
+		if err != nil {
+			return nil, err
+		}
+		byID[m.ID] = m
+	}
+	var res []*model.Metadata
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// Put adds movie metadata for a given movie id as its next
+// version, keeping the previous versions in the history, and
+// records the change event in the outbox, all in one transaction.
+//
+// The uniqueness of external ids is checked on the eventually
+// consistent external index before writing.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) error {
+	prev, err := r.Get(ctx, id)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	for source, externalID := range metadata.ExternalIDs {
+		owner, err := r.externalOwner(ctx, source, externalID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if err == nil && owner != id {
+			return repository.ErrDuplicateExternalID
+		}
+	}
+	m := *metadata
+	m.Version = 1
+	if prev != nil {
+		m.Version = prev.Version + 1
+	}
+	current, err := metadataItem(movieKey(ctx, id), metaSK, &m)
+	if err != nil {
+		return err
+	}
+	titlePK, titleSK := titleKeys(ctx, &m)
+	current["GSI2PK"], current["GSI2SK"] = str(titlePK), str(titleSK)
+	version, err := metadataItem(movieKey(ctx, id), versionSK(m.Version), &m)
+	if err != nil {
+		return err
+	}
+	event := model.NewEvent(prev, &m)
+	event.TraceContext = tracing.Carrier(ctx)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	outbox := key(outboxKey(ctx), fmt.Sprintf("%s%020d#%s", eventPrefix, time.Now().UnixNano(), event.ID))
+	outbox["eventId"], outbox["payload"] = str(event.ID), str(string(payload))
+
+	put := &types.Put{TableName: aws.String(r.table), Item: current}
+	if prev == nil {
+		put.ConditionExpression = aws.String("attribute_not_exists(PK)")
+	} else {
+		put.ConditionExpression = aws.String("#version = :version")
+		put.ExpressionAttributeNames = map[string]string{"#version": "version"}
+		put.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: fmt.Sprint(prev.Version)},
+		}
+	}
+	writes := []types.TransactWriteItem{
+		{Put: put},
+		{Put: &types.Put{TableName: aws.String(r.table), Item: version}},
+		{Put: &types.Put{TableName: aws.String(r.table), Item: outbox}},
+	}
+	if prev != nil {
+		for source := range prev.ExternalIDs {
+			if _, ok := m.ExternalIDs[source]; !ok {
+				writes = append(writes, types.TransactWriteItem{Delete: &types.Delete{
+					TableName: aws.String(r.table),
+					Key:       key(movieKey(ctx, id), externalPrefix+string(source)),
+				}})
+			}
+		}
+	}
+	for source, externalID := range m.ExternalIDs {
+		item := key(movieKey(ctx, id), externalPrefix+string(source))
+		item["GSI1PK"], item["GSI1SK"] = str(externalKey(ctx, source, externalID)), str(movieKey(ctx, id))
+		item["movieId"] = str(id)
+		writes = append(writes, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(r.table), Item: item}})
+	}
+	if _, err := r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: writes}); err != nil {
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			return ErrConcurrentUpdate
+		}
+		return err
+	}
+	metadata.Version = m.Version
+	return nil
+}
+
+// PendingEvents returns up to limit change events not yet
+// published, oldest first.
+func (r *Repository) PendingEvents(ctx context.Context, limit int) ([]*model.Event, error) {
+	out, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": str(outboxKey(ctx)),
+		},
+		ConsistentRead: aws.Bool(true),
+		Limit:          aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var res []*model.Event
+	for _, item := range out.Items {
+		var stored struct {
+			Payload string `json:"payload"`
+		}
+		if err := unmarshal(item, &stored); err != nil {
+			return nil, err
+		}
+		var e *model.Event
+		if err := json.Unmarshal([]byte(stored.Payload), &e); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// MarkEventsPublished removes published change events from the
+// outbox.
+func (r *Repository) MarkEventsPublished(ctx context.Context, ids []string) error {
+	pending := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		pending[id] = true
+	}
+	var deletes []types.WriteRequest
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": str(outboxKey(ctx)),
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+	// Published events are the oldest ones, so the scan of the
+	// outbox ends early.
+	for len(pending) > 0 {
+		out, err := r.client.Query(ctx, input)
+		if err != nil {
+			return err
+		}
+		for _, item := range out.Items {
+			var stored struct {
+				EventID string `json:"eventId"`
+			}
+			if err := unmarshal(item, &stored); err != nil {
+				return err
+			}
+			if pending[stored.EventID] {
+				delete(pending, stored.EventID)
+				deletes = append(deletes, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+					Key: map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]},
+				}})
+			}
+		}
+		if out.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+	return r.batchWrite(ctx, deletes)
+}
+
+// GetByExternalID retrieves movie metadata by its id in
+// another catalog.
+func (r *Repository) GetByExternalID(ctx context.Context, source model.ExternalSource, externalID string) (*model.Metadata, error) {
+	id, err := r.externalOwner(ctx, source, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *Repository) externalOwner(ctx context.Context, source model.ExternalSource, externalID string) (string, error) {
+	out, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		IndexName:              aws.String(externalIndex),
+		KeyConditionExpression: aws.String("GSI1PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": str(externalKey(ctx, source, externalID)),
+		},
+		Limit: aws.Int32(1),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(out.Items) == 0 {
+		return "", repository.ErrNotFound
+	}
+	var stored struct {
+		MovieID string `json:"movieId"`
+	}
+	if err := unmarshal(out.Items[0], &stored); err != nil {
+		return "", err
+	}
+	return stored.MovieID, nil
+}
+
+// GetVersion retrieves the given version of movie metadata.
+func (r *Repository) GetVersion(ctx context.Context, id string, version int) (*model.Metadata, error) {
+	if version < 1 {
+		return nil, repository.ErrNotFound
+	}
+	return r.getMetadata(ctx, movieKey(ctx, id), versionSK(version))
+}
+
+// History retrieves all versions of movie metadata, latest first.
+func (r *Repository) History(ctx context.Context, id string) ([]*model.Metadata, error) {
+	items, err := r.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": str(movieKey(ctx, id)),
+			":sk": str(versionPrefix),
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	res := make([]*model.Metadata, 0, len(items))
+	for _, item := range items {
+		m, err := unmarshalMetadata(item)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// List returns a page of movie metadata matching the filter and
+// the token of the next page. Listing scans the table, so movies
+// are not ordered.
+func (r *Repository) List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	return r.find(ctx, filter, pageSize, pageToken, []string{"PK", "SK"},
+		func(ctx context.Context, start map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+			out, err := r.client.Scan(ctx, &dynamodb.ScanInput{
+				TableName:        aws.String(r.table),
+				FilterExpression: aws.String("SK = :sk AND begins_with(PK, :tenant)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":sk":     str(metaSK),
+					":tenant": str(tenant.Key(ctx, "")),
+				},
+				ExclusiveStartKey: start,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			return out.Items, out.LastEvaluatedKey, nil
+		})
+}
+
+// Search returns a page of movie metadata whose title starts with
+// the query and which matches the filter, ordered by title.
+// Unlike other repositories, descriptions are not searched.
+func (r *Repository) Search(ctx context.Context, query string, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	prefix := strings.ToLower(strings.TrimSpace(query))
+	if prefix == "" {
+		return r.List(ctx, filter, pageSize, pageToken)
+	}
+	pk, _ := titleKeys(ctx, &model.Metadata{Title: prefix})
+	return r.find(ctx, filter, pageSize, pageToken, []string{"PK", "SK", "GSI2PK", "GSI2SK"},
+		func(ctx context.Context, start map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+			out, err := r.client.Query(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(r.table),
+				IndexName:              aws.String(titleIndex),
+				KeyConditionExpression: aws.String("GSI2PK = :pk AND begins_with(GSI2SK, :prefix)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":pk":     str(pk),
+					":prefix": str(prefix),
+				},
+				ExclusiveStartKey: start,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			return out.Items, out.LastEvaluatedKey, nil
+		})
+}
+
+// Facets counts all movie metadata matching the query and the
+// filter by facet, reading all of it.
+func (r *Repository) Facets(ctx context.Context, query string, filter model.Filter) (*model.Facets, error) {
+	res := model.NewFacets()
+	for token := ""; ; {
+		page, next, err := r.Search(ctx, query, filter, maxBatchGet, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page {
+			res.Add(m)
+		}
+		if next == "" {
+			return res, nil
+		}
+		token = next
+	}
+}
+
+type pageFunc func(ctx context.Context, start map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error)
+
+// find reads pages until pageSize items match the filter. The
+// page token encodes the keys of the last returned item, named
+// by keyNames, to continue reading after it.
+func (r *Repository) find(ctx context.Context, filter model.Filter, pageSize int, pageToken string, keyNames []string, page pageFunc) ([]*model.Metadata, string, error) {
+	start, err := decodeToken(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	var res []*model.Metadata
+	var last map[string]types.AttributeValue
+	for {
+		items, next, err := page(ctx, start)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, item := range items {
+			m, err := unmarshalMetadata(item)
+			if err != nil {
+				return nil, "", err
+			}
+			if !filter.Matches(m) {
+				continue
+			}
+			if len(res) == pageSize {
+				return res, encodeToken(last, keyNames), nil
+			}
+			res = append(res, m)
+			last = item
+		}
+		if next == nil {
+			return res, "", nil
+		}
+		start = next
+	}
+}
+
+func encodeToken(item map[string]types.AttributeValue, keyNames []string) string {
+	keys := make(map[string]string, len(keyNames))
+	for _, name := range keyNames {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			keys[name] = v.Value
+		}
+	}
+	b, _ := json.Marshal(keys)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeToken(token string) (map[string]types.AttributeValue, error) {
+	if token == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token: %w", err)
+	}
+	var keys map[string]string
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("invalid page token: %w", err)
+	}
+	res := make(map[string]types.AttributeValue, len(keys))
+	for name, v := range keys {
+		res[name] = str(v)
+	}
+	return res, nil
+}
+
+// GetPerson retrieves a person by id.
+func (r *Repository) GetPerson(ctx context.Context, id string) (*model.Person, error) {
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.table),
+		Key:       key(personKey(ctx, id), personSK),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, repository.ErrNotFound
+	}
+	p := &model.Person{}
+	if err := unmarshal(out.Item, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PutPerson adds a person.
+func (r *Repository) PutPerson(ctx context.Context, person *model.Person) error {
+	item, err := marshal(person)
+	if err != nil {
+		return err
+	}
+	item["PK"], item["SK"] = str(personKey(ctx, person.ID)), str(personSK)
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(r.table), Item: item})
+	return err
+}
+
+// PutCredits replaces the credits of a given movie. The credits
+// are written in batches, so readers may see a partial update.
+func (r *Repository) PutCredits(ctx context.Context, movieID string, credits []model.Credit) error {
+	existing, err := r.creditItems(ctx, movieID)
+	if err != nil {
+		return err
+	}
+	var writes []types.WriteRequest
+	for _, item := range existing {
+		writes = append(writes, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+			Key: map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]},
+		}})
+	}
+	if err := r.batchWrite(ctx, writes); err != nil {
+		return err
+	}
+	writes = nil
+	for i, c := range credits {
+		c.MovieID, c.PersonName, c.MovieTitle = movieID, "", ""
+		item, err := marshal(c)
+		if err != nil {
+			return err
+		}
+		// The index keeps credits with equal billing order apart.
+		item["PK"] = str(movieKey(ctx, movieID))
+		item["SK"] = str(fmt.Sprintf("%s%06d#%06d", creditPrefix, c.Order, i))
+		item["GSI1PK"], item["GSI1SK"] = str(personKey(ctx, c.PersonID)), str(movieKey(ctx, movieID))
+		writes = append(writes, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+	}
+	return r.batchWrite(ctx, writes)
+}
+
+func (r *Repository) creditItems(ctx context.Context, movieID string) ([]map[string]types.AttributeValue, error) {
+	return r.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": str(movieKey(ctx, movieID)),
+			":sk": str(creditPrefix),
+		},
+	})
+}
+
+// GetCredits retrieves the credits of a given movie in billing order.
+func (r *Repository) GetCredits(ctx context.Context, movieID string) ([]model.Credit, error) {
+	items, err := r.creditItems(ctx, movieID)
+	if err != nil {
+		return nil, err
+	}
+	return r.displayCredits(ctx, items)
+}
+
+// GetFilmography retrieves the credits of a given person.
+func (r *Repository) GetFilmography(ctx context.Context, personID string) ([]model.Credit, error) {
+	items, err := r.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		IndexName:              aws.String(externalIndex),
+		KeyConditionExpression: aws.String("GSI1PK = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": str(personKey(ctx, personID)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.displayCredits(ctx, items)
+}
+
+// displayCredits decodes credits, adding the names of the people
+// and the titles of the movies.
+func (r *Repository) displayCredits(ctx context.Context, items []map[string]types.AttributeValue) ([]model.Credit, error) {
+	var res []model.Credit
+	var keys []map[string]types.AttributeValue
+	for _, item := range items {
+		var c model.Credit
+		if err := unmarshal(item, &c); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+		keys = append(keys, key(personKey(ctx, c.PersonID), personSK), key(movieKey(ctx, c.MovieID), metaSK))
+	}
+	related, err := r.batchGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	titles := map[string]string{}
+	for _, item := range related {
+		var v struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Title string `json:"title"`
+		}
+		if err := unmarshal(item, &v); err != nil {
+			return nil, err
+		}
+		if sk, ok := item["SK"].(*types.AttributeValueMemberS); ok && sk.Value == personSK {
+			names[v.ID] = v.Name
+		} else {
+			titles[v.ID] = v.Title
+		}
+	}
+	for i := range res {
+		res[i].PersonName = names[res[i].PersonID]
+		res[i].MovieTitle = titles[res[i].MovieID]
+	}
+	return res, nil
+}
+
+func (r *Repository) queryAll(ctx context.Context, input *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
+	var res []map[string]types.AttributeValue
+	for {
+		out, err := r.client.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, out.Items...)
+		if out.LastEvaluatedKey == nil {
+			return res, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// batchGet retrieves the items with the given keys in batches,
+// retrying unprocessed keys. Repeated keys are read once.
+func (r *Repository) batchGet(ctx context.Context, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	seen := map[string]bool{}
+	var unique []map[string]types.AttributeValue
+	for _, k := range keys {
+		pk, sk := k["PK"].(*types.AttributeValueMemberS), k["SK"].(*types.AttributeValueMemberS)
+		if id := pk.Value + "\x00" + sk.Value; !seen[id] {
+			seen[id] = true
+			unique = append(unique, k)
+		}
+	}
+	var res []map[string]types.AttributeValue
+	for len(unique) > 0 {
+		n := min(len(unique), maxBatchGet)
+		pending := map[string]types.KeysAndAttributes{r.table: {Keys: unique[:n]}}
+		unique = unique[n:]
+		for len(pending) > 0 {
+			out, err := r.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, out.Responses[r.table]...)
+			pending = out.UnprocessedKeys
+		}
+	}
+	return res, nil
+}
+
+// batchWrite applies the write requests in batches, retrying
+// unprocessed requests.
+func (r *Repository) batchWrite(ctx context.Context, writes []types.WriteRequest) error {
+	for len(writes) > 0 {
+		n := min(len(writes), maxBatchWrite)
+		pending := map[string][]types.WriteRequest{r.table: writes[:n]}
+		writes = writes[n:]
+		for len(pending) > 0 {
+			out, err := r.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return err
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+	return nil
+}
@@ -4,3 +4,7 @@ import "errors"
 
 // ErrNotFound is returned when a requested record is not found.
 var ErrNotFound = errors.New("not found")
+
+// ErrDuplicateExternalID is returned when an external id is
+// already assigned to another record.
+var ErrDuplicateExternalID = errors.New("duplicate external id")
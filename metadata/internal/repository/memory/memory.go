@@ -2,38 +2,310 @@ package memory
 
 import (
 	"context"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 
+	"movieapp.com/internal/tracing"
 	"movieapp.com/metadata/internal/repository"
 	model "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/tenant"
 )
 
-// Repository defines a memory movie metadata repository.
+// Repository defines a memory movie metadata repository, keeping
+// the data of each tenant apart.
 type Repository struct {
 	sync.RWMutex
-	data map[string]*model.Metadata
+	tenants map[string]*store
+}
+
+// store holds the data of a tenant.
+type store struct {
+	data     map[string]*model.Metadata
+	versions map[string][]*model.Metadata
+	people   map[string]*model.Person
+	credits  map[string][]model.Credit
+	outbox   []*model.Event
 }
 
 // New creates a new memory repository.
 func New() *Repository {
-	return &Repository{data: map[string]*model.Metadata{}}
+	return &Repository{tenants: map[string]*store{}}
+}
+
+// of returns the data of the tenant of the context, created if
+// missing, which requires the write lock.
+func (r *Repository) of(ctx context.Context, create bool) *store {
+	id := tenant.FromContext(ctx)
+	s, ok := r.tenants[id]
+	if !ok {
+		s = &store{
+			data:     map[string]*model.Metadata{},
+			versions: map[string][]*model.Metadata{},
+			people:   map[string]*model.Person{},
+			credits:  map[string][]model.Credit{},
+		}
+		if create {
+			r.tenants[id] = s
+		}
+	}
+	return s
 }
 
 // Get retrieves movie metadata for by movie id.
-func (r *Repository) Get(_ context.Context, id string) (*model.Metadata, error) {
+func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	r.RLock()
 	defer r.RUnlock()
-	m, ok := r.data[id]
+	s := r.of(ctx, false)
+	m, ok := s.data[id]
 	if !ok {
 		return nil, repository.ErrNotFound
 	}
 	return m, nil
 }
 
-// Put adds movie metadata for a given movie id.
-func (r *Repository) Put(_ context.Context, id string, metadata *model.Metadata) error {
+// GetMany retrieves movie metadata for the given movie ids in
+// the same order, skipping the ones not found.
+func (r *Repository) GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	var res []*model.Metadata
+	for _, id := range ids {
+		if m, ok := s.data[id]; ok {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// Put adds movie metadata for a given movie id as its next
+// version, keeping the previous versions in the history, and
+// records the change event in the outbox.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) error {
+	r.Lock()
+	defer r.Unlock()
+	s := r.of(ctx, true)
+	for source, externalID := range metadata.ExternalIDs {
+		if other := s.findByExternalID(source, externalID); other != nil && other.ID != id {
+			return repository.ErrDuplicateExternalID
+		}
+	}
+	m := *metadata
+	m.Version = len(s.versions[id]) + 1
+	metadata.Version = m.Version
+	event := model.NewEvent(s.data[id], &m)
+	event.TraceContext = tracing.Carrier(ctx)
+	s.outbox = append(s.outbox, event)
+	s.data[id] = &m
+	s.versions[id] = append(s.versions[id], &m)
+	return nil
+}
+
+// PendingEvents returns up to limit change events not yet
+// published, oldest first.
+func (r *Repository) PendingEvents(ctx context.Context, limit int) ([]*model.Event, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	return slices.Clone(s.outbox[:min(limit, len(s.outbox))]), nil
+}
+
+// MarkEventsPublished removes published change events from the
+// outbox.
+func (r *Repository) MarkEventsPublished(ctx context.Context, ids []string) error {
+	r.Lock()
+	defer r.Unlock()
+	s := r.of(ctx, true)
+	s.outbox = slices.DeleteFunc(s.outbox, func(e *model.Event) bool {
+		return slices.Contains(ids, e.ID)
+	})
+	return nil
+}
+
+// GetByExternalID retrieves movie metadata by its id in
+// another catalog.
+func (r *Repository) GetByExternalID(ctx context.Context, source model.ExternalSource, externalID string) (*model.Metadata, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	m := s.findByExternalID(source, externalID)
+	if m == nil {
+		return nil, repository.ErrNotFound
+	}
+	return m, nil
+}
+
+func (s *store) findByExternalID(source model.ExternalSource, externalID string) *model.Metadata {
+	for _, m := range s.data {
+		if m.ExternalIDs[source] == externalID {
+			return m
+		}
+	}
+	return nil
+}
+
+// GetVersion retrieves the given version of movie metadata.
+func (r *Repository) GetVersion(ctx context.Context, id string, version int) (*model.Metadata, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	versions := s.versions[id]
+	if version < 1 || version > len(versions) {
+		return nil, repository.ErrNotFound
+	}
+	return versions[version-1], nil
+}
+
+// History retrieves all versions of movie metadata, latest first.
+func (r *Repository) History(ctx context.Context, id string) ([]*model.Metadata, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	versions := s.versions[id]
+	if len(versions) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	res := slices.Clone(versions)
+	slices.Reverse(res)
+	return res, nil
+}
+
+// List returns a page of movie metadata matching the filter,
+// ordered by movie id, and the token of the next page.
+func (r *Repository) List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.of(ctx, false).find(filter, pageSize, pageToken, func(*model.Metadata) bool { return true })
+}
+
+// Search returns a page of movie metadata whose title or
+// description contains the query and which matches the filter.
+func (r *Repository) Search(ctx context.Context, query string, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.of(ctx, false).find(filter, pageSize, pageToken, matchQuery(query))
+}
+
+// Facets counts all movie metadata matching the query and the
+// filter by facet.
+func (r *Repository) Facets(ctx context.Context, query string, filter model.Filter) (*model.Facets, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	match := matchQuery(query)
+	res := model.NewFacets()
+	for _, m := range s.data {
+		if filter.Matches(m) && match(m) {
+			res.Add(m)
+		}
+	}
+	return res, nil
+}
+
+func matchQuery(query string) func(*model.Metadata) bool {
+	query = strings.ToLower(query)
+	return func(m *model.Metadata) bool {
+		return strings.Contains(strings.ToLower(m.Title), query) ||
+			strings.Contains(strings.ToLower(m.Description), query)
+	}
+}
+
+func (s *store) find(filter model.Filter, pageSize int, pageToken string, match func(*model.Metadata) bool) ([]*model.Metadata, string, error) {
+	ids := make([]string, 0, len(s.data))
+	for id := range s.data {
+		if id > pageToken {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	var res []*model.Metadata
+	var last string
+	for _, id := range ids {
+		m := s.data[id]
+		if !filter.Matches(m) || !match(m) {
+			continue
+		}
+		if len(res) == pageSize {
+			return res, last, nil
+		}
+		res = append(res, m)
+		last = id
+	}
+	return res, "", nil
+}
+
+// GetPerson retrieves a person by id.
+func (r *Repository) GetPerson(ctx context.Context, id string) (*model.Person, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	p, ok := s.people[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return p, nil
+}
+
+// PutPerson adds a person.
+func (r *Repository) PutPerson(ctx context.Context, person *model.Person) error {
+	r.Lock()
+	defer r.Unlock()
+	s := r.of(ctx, true)
+	s.people[person.ID] = person
+	return nil
+}
+
+// PutCredits replaces the credits of a given movie.
+func (r *Repository) PutCredits(ctx context.Context, movieID string, credits []model.Credit) error {
 	r.Lock()
 	defer r.Unlock()
-	r.data[id] = metadata
+	s := r.of(ctx, true)
+	stored := slices.Clone(credits)
+	for i := range stored {
+		stored[i].MovieID = movieID
+	}
+	s.credits[movieID] = stored
 	return nil
 }
+
+// GetCredits retrieves the credits of a given movie in billing order.
+func (r *Repository) GetCredits(ctx context.Context, movieID string) ([]model.Credit, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	var res []model.Credit
+	for _, c := range s.credits[movieID] {
+		res = append(res, s.displayCredit(c))
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Order < res[j].Order })
+	return res, nil
+}
+
+// GetFilmography retrieves the credits of a given person.
+func (r *Repository) GetFilmography(ctx context.Context, personID string) ([]model.Credit, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	var res []model.Credit
+	for _, credits := range s.credits {
+		for _, c := range credits {
+			if c.PersonID == personID {
+				res = append(res, s.displayCredit(c))
+			}
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].MovieID < res[j].MovieID })
+	return res, nil
+}
+
+func (s *store) displayCredit(c model.Credit) model.Credit {
+	if p, ok := s.people[c.PersonID]; ok {
+		c.PersonName = p.Name
+	}
+	if m, ok := s.data[c.MovieID]; ok {
+		c.MovieTitle = m.Title
+	}
+	return c
+}
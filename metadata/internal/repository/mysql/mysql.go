@@ -3,45 +3,603 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"movieapp.com/internal/tracing"
+	eventbus "movieapp.com/metadata/internal/event/bus"
 	"movieapp.com/metadata/internal/repository"
 	"movieapp.com/metadata/pkg/model"
-) // Repository defines a MySQL-based movie matadata repository.
+	"movieapp.com/pkg/outbox"
+	"movieapp.com/pkg/tenant"
+)
+
+// Repository defines a MySQL-based movie matadata repository.
 type Repository struct {
-	db *sql.DB
+	db     *tenant.DB
+	outbox *outbox.SQL
 }
 
-// New creates a new MySQL-based repository.
-func New() (*Repository, error) {
-	db, err := sql.Open("mysql", "root:password@/movieexample")
+// New creates a new MySQL-based repository of the database with
+// the data source name, which must set parseTime=true, per tenant
+// of the set as with tenant.OpenDB.
+func New(dsn string, tenants *tenant.Set) (*Repository, error) {
+	db, err := tenant.OpenDB("mysql", dsn, tenants)
 	if err != nil {
 		return nil, err
 	}
-	return &Repository{db}, nil
+	return &Repository{db, outbox.NewSQL(db, "metadata_outbox")}, nil
+}
+
+// PingContext checks that the databases are reachable.
+func (r *Repository) PingContext(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the connection pools of the repository.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// errDupEntry is the MySQL error number of unique key violations.
+const errDupEntry = 1062
+
+// movieColumns lists the movies table columns read by scanMetadata.
+const movieColumns = "id, title, description, director, poster_url, backdrop_url, tagline, release_date, runtime_minutes, certification, original_language, version, updated_at, updated_by, deleted_at, merged_into"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMetadata(s scanner) (*model.Metadata, error) {
+	m := &model.Metadata{}
+	var releaseDate, deletedAt sql.NullTime
+	if err := s.Scan(&m.ID, &m.Title, &m.Description, &m.Director, &m.PosterURL, &m.BackdropURL,
+		&m.Tagline, &releaseDate, &m.RuntimeMinutes, &m.Certification, &m.OriginalLanguage,
+		&m.Version, &m.UpdatedAt, &m.UpdatedBy, &deletedAt, &m.MergedInto); err != nil {
+		return nil, err
+	}
+	if releaseDate.Valid {
+		m.ReleaseDate = releaseDate.Time.Format(model.ReleaseDateLayout)
+	}
+	if deletedAt.Valid {
+		m.DeletedAt = &deletedAt.Time
+	}
+	return m, nil
 }
 
 // Get retrieves movie metadata for by movie id.
 func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	var title, description, director string
-	row := r.db.QueryRowContext(ctx, "SELECT title, description, director FROM movies WHERE id = ?", id)
-	if err := row.Scan(&title, &description, &director); err != nil {
+	m, err := scanMetadata(r.db.QueryRowContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE id = ?", id))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, repository.ErrNotFound
 		}
 		return nil, err
 	}
-	return &model.Metadata{
-		ID:          id,
-		Title:       title,
-		Description: description,
-		Director:    director,
-	}, nil
+	if err := r.loadDetails(ctx, []*model.Metadata{m}); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-// Put adds movie metadata for a given movie id.
+// GetMany retrieves movie metadata for the given movie ids in
+// the same order, skipping the ones not found.
+func (r *Repository) GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE id IN "+placeholders(len(ids)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byID := map[string]*model.Metadata{}
+	for rows.Next() {
+		m, err := scanMetadata(rows)
+		if err != nil {
+			return nil, err
+		}
+		byID[m.ID] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var res []*model.Metadata
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			res = append(res, m)
+		}
+	}
+	if err := r.loadDetails(ctx, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Put adds movie metadata for a given movie id as its next
+// version, keeping the previous versions in the history, and
+// records the change event in the outbox in the same transaction.
 func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO movies (id, title, description, director) VALUES (?, ?, ?, ?)",
-		id, metadata.Title, metadata.Description, metadata.Director)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var version int
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM movie_versions WHERE movie_id = ? FOR UPDATE", id).Scan(&version); err != nil {
+		return err
+	}
+	var previous *model.Metadata
+	if version > 0 {
+		previous, err = scanMetadata(tx.QueryRowContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE id = ?", id))
+		if err != nil {
+			return err
+		}
+	}
+	m := *metadata
+	m.ID = id
+	m.Version = version + 1
+	snapshot, err := json.Marshal(&m)
+	if err != nil {
+		return err
+	}
+	event := model.NewEvent(previous, &m)
+	event.TraceContext = tracing.Carrier(ctx)
+	msg, err := eventbus.Encode(ctx, event)
+	if err != nil {
+		return err
+	}
+	if err := r.outbox.Enqueue(ctx, tx, outbox.Message{ID: event.ID, Key: msg.Key, Value: msg.Value, Headers: msg.Headers}); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO movie_versions (movie_id, version, updated_at, updated_by, data) VALUES (?, ?, ?, ?, ?)",
+		id, m.Version, m.UpdatedAt, m.UpdatedBy, snapshot); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO movies ("+movieColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE title = VALUES(title), description = VALUES(description), director = VALUES(director), "+
+		"poster_url = VALUES(poster_url), backdrop_url = VALUES(backdrop_url), tagline = VALUES(tagline), "+
+		"release_date = VALUES(release_date), runtime_minutes = VALUES(runtime_minutes), "+
+		"certification = VALUES(certification), original_language = VALUES(original_language), "+
+		"version = VALUES(version), updated_at = VALUES(updated_at), updated_by = VALUES(updated_by), deleted_at = VALUES(deleted_at), merged_into = VALUES(merged_into)",
+		id, m.Title, m.Description, m.Director, m.PosterURL, m.BackdropURL, m.Tagline,
+		nullString(m.ReleaseDate), m.RuntimeMinutes, m.Certification, m.OriginalLanguage,
+		m.Version, m.UpdatedAt, m.UpdatedBy, m.DeletedAt, m.MergedInto); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_genres WHERE movie_id = ?", id); err != nil {
+		return err
+	}
+	for _, g := range m.Genres {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO movie_genres (movie_id, genre) VALUES (?, ?)", id, g); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_tags WHERE movie_id = ?", id); err != nil {
+		return err
+	}
+	for _, t := range m.Tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO movie_tags (movie_id, tag) VALUES (?, ?)", id, t); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_external_ids WHERE movie_id = ?", id); err != nil {
+		return err
+	}
+	for source, externalID := range m.ExternalIDs {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO movie_external_ids (movie_id, source, external_id) VALUES (?, ?, ?)",
+			id, source, externalID); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+				return repository.ErrDuplicateExternalID
+			}
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_localizations WHERE movie_id = ?", id); err != nil {
+		return err
+	}
+	for locale, l := range m.Localizations {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO movie_localizations (movie_id, locale, title, description, tagline) VALUES (?, ?, ?, ?, ?)",
+			id, locale, l.Title, l.Description, l.Tagline); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	metadata.Version = m.Version
+	return nil
+}
+
+// Outbox returns the outbox of the change events, kept per tenant
+// and delivered by an outbox.Relay of an outbox.PerTenant source.
+func (r *Repository) Outbox() *outbox.SQL {
+	return r.outbox
+}
+
+// GetByExternalID retrieves movie metadata by its id in
+// another catalog.
+func (r *Repository) GetByExternalID(ctx context.Context, source model.ExternalSource, externalID string) (*model.Metadata, error) {
+	var id string
+	row := r.db.QueryRowContext(ctx, "SELECT movie_id FROM movie_external_ids WHERE source = ? AND external_id = ?", source, externalID)
+	if err := row.Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+// GetVersion retrieves the given version of movie metadata.
+func (r *Repository) GetVersion(ctx context.Context, id string, version int) (*model.Metadata, error) {
+	var data []byte
+	row := r.db.QueryRowContext(ctx, "SELECT data FROM movie_versions WHERE movie_id = ? AND version = ?", id, version)
+	if err := row.Scan(&data); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	var m *model.Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// History retrieves all versions of movie metadata, latest first.
+func (r *Repository) History(ctx context.Context, id string) ([]*model.Metadata, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT data FROM movie_versions WHERE movie_id = ? ORDER BY version DESC", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*model.Metadata
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var m *model.Metadata
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return res, nil
+}
+
+// List returns a page of movie metadata matching the filter,
+// ordered by movie id, and the token of the next page.
+func (r *Repository) List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	return r.find(ctx, "", nil, filter, pageSize, pageToken)
+}
+
+// Search returns a page of movie metadata whose title or
+// description contains the query and which matches the filter.
+func (r *Repository) Search(ctx context.Context, query string, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	pattern := "%" + query + "%"
+	return r.find(ctx, "(title LIKE ? OR description LIKE ?)", []any{pattern, pattern}, filter, pageSize, pageToken)
+}
+
+// Facets counts all movie metadata matching the query and the
+// filter by facet.
+func (r *Repository) Facets(ctx context.Context, query string, filter model.Filter) (*model.Facets, error) {
+	pattern := "%" + query + "%"
+	where, params := conditions("(title LIKE ? OR description LIKE ?)", []any{pattern, pattern}, filter)
+	movies := "SELECT id FROM movies WHERE " + strings.Join(where, " AND ")
+	res := model.NewFacets()
+	queries := []struct {
+		query string
+		add   func(value string, n int)
+	}{
+		{"SELECT genre, COUNT(*) FROM movie_genres WHERE movie_id IN (" + movies + ") GROUP BY genre",
+			func(v string, n int) { res.Genres[model.Genre(v)] = n }},
+		{"SELECT CAST(FLOOR(YEAR(release_date) / 10) * 10 AS CHAR), COUNT(*) FROM movies WHERE release_date IS NOT NULL AND id IN (" + movies + ") GROUP BY 1",
+			func(v string, n int) {
+				if d, err := strconv.Atoi(v); err == nil {
+					res.Decades[d] = n
+				}
+			}},
+		{"SELECT original_language, COUNT(*) FROM movies WHERE original_language <> '' AND id IN (" + movies + ") GROUP BY 1",
+			func(v string, n int) { res.Languages[v] = n }},
+		{"SELECT certification, COUNT(*) FROM movies WHERE certification <> '' AND id IN (" + movies + ") GROUP BY 1",
+			func(v string, n int) { res.Certifications[v] = n }},
+	}
+	for _, q := range queries {
+		if err := r.count(ctx, q.query, params, q.add); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (r *Repository) count(ctx context.Context, query string, params []any, add func(value string, n int)) error {
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value string
+		var n int
+		if err := rows.Scan(&value, &n); err != nil {
+			return err
+		}
+		add(value, n)
+	}
+	return rows.Err()
+}
+
+func (r *Repository) find(ctx context.Context, cond string, args []any, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	where, params := conditions(cond, args, filter)
+	where = append([]string{"id > ?"}, where...)
+	params = append([]any{pageToken}, params...)
+	// Fetch one extra row to find out whether there is a next page.
+	params = append(params, pageSize+1)
+	rows, err := r.db.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE "+
+		strings.Join(where, " AND ")+" ORDER BY id LIMIT ?", params...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	var res []*model.Metadata
+	for rows.Next() {
+		m, err := scanMetadata(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		res = append(res, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	var next string
+	if len(res) > pageSize {
+		res = res[:pageSize]
+		next = res[len(res)-1].ID
+	}
+	if err := r.loadDetails(ctx, res); err != nil {
+		return nil, "", err
+	}
+	return res, next, nil
+}
+
+// conditions returns the conditions of the movies table, and
+// their parameters, matching the additional condition and the
+// filter.
+func conditions(cond string, args []any, filter model.Filter) ([]string, []any) {
+	var where []string
+	var params []any
+	if !filter.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+	if cond != "" {
+		where = append(where, cond)
+		params = append(params, args...)
+	}
+	for _, g := range filter.Genres {
+		where = append(where, "id IN (SELECT movie_id FROM movie_genres WHERE genre = ?)")
+		params = append(params, g)
+	}
+	for _, t := range filter.Tags {
+		where = append(where, "id IN (SELECT movie_id FROM movie_tags WHERE tag = ?)")
+		params = append(params, t)
+	}
+	if filter.YearFrom > 0 {
+		where = append(where, "YEAR(release_date) >= ?")
+		params = append(params, filter.YearFrom)
+	}
+	if filter.YearTo > 0 {
+		where = append(where, "YEAR(release_date) <= ?")
+		params = append(params, filter.YearTo)
+	}
+	if len(filter.Certifications) > 0 {
+		where = append(where, "certification IN "+placeholders(len(filter.Certifications)))
+		for _, c := range filter.Certifications {
+			params = append(params, c)
+		}
+	}
+	if filter.OriginalLanguage != "" {
+		where = append(where, "original_language = ?")
+		params = append(params, filter.OriginalLanguage)
+	}
+	if filter.MinRuntime > 0 {
+		where = append(where, "runtime_minutes >= ?")
+		params = append(params, filter.MinRuntime)
+	}
+	if !filter.UpdatedSince.IsZero() {
+		where = append(where, "updated_at >= ?")
+		params = append(params, filter.UpdatedSince)
+	}
+	if filter.MaxRuntime > 0 {
+		where = append(where, "runtime_minutes BETWEEN 1 AND ?")
+		params = append(params, filter.MaxRuntime)
+	}
+	return where, params
+}
+
+// loadDetails populates genres, tags, localizations and external
+// ids of the given metadata.
+func (r *Repository) loadDetails(ctx context.Context, ms []*model.Metadata) error {
+	if len(ms) == 0 {
+		return nil
+	}
+	byID := map[string]*model.Metadata{}
+	ids := make([]any, 0, len(ms))
+	for _, m := range ms {
+		byID[m.ID] = m
+		ids = append(ids, m.ID)
+	}
+	in := placeholders(len(ids))
+	if err := r.loadTaxonomy(ctx, byID, in, ids); err != nil {
+		return err
+	}
+	if err := r.loadLocalizations(ctx, byID, in, ids); err != nil {
+		return err
+	}
+	return r.loadExternalIDs(ctx, byID, in, ids)
+}
+
+func (r *Repository) loadExternalIDs(ctx context.Context, byID map[string]*model.Metadata, in string, ids []any) error {
+	rows, err := r.db.QueryContext(ctx, "SELECT movie_id, source, external_id FROM movie_external_ids WHERE movie_id IN "+in, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, source, externalID string
+		if err := rows.Scan(&id, &source, &externalID); err != nil {
+			return err
+		}
+		m := byID[id]
+		if m.ExternalIDs == nil {
+			m.ExternalIDs = map[model.ExternalSource]string{}
+		}
+		m.ExternalIDs[model.ExternalSource(source)] = externalID
+	}
+	return rows.Err()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func placeholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?,", n), ",") + ")"
+}
+
+func (r *Repository) loadTaxonomy(ctx context.Context, byID map[string]*model.Metadata, in string, ids []any) error {
+	rows, err := r.db.QueryContext(ctx, "SELECT movie_id, genre FROM movie_genres WHERE movie_id IN "+in, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, genre string
+		if err := rows.Scan(&id, &genre); err != nil {
+			return err
+		}
+		byID[id].Genres = append(byID[id].Genres, model.Genre(genre))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows, err = r.db.QueryContext(ctx, "SELECT movie_id, tag FROM movie_tags WHERE movie_id IN "+in, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, tag)
+	}
+	return rows.Err()
+}
+
+func (r *Repository) loadLocalizations(ctx context.Context, byID map[string]*model.Metadata, in string, ids []any) error {
+	rows, err := r.db.QueryContext(ctx, "SELECT movie_id, locale, title, description, tagline FROM movie_localizations WHERE movie_id IN "+in, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, locale string
+		var l model.Localization
+		if err := rows.Scan(&id, &locale, &l.Title, &l.Description, &l.Tagline); err != nil {
+			return err
+		}
+		m := byID[id]
+		if m.Localizations == nil {
+			m.Localizations = map[string]model.Localization{}
+		}
+		m.Localizations[locale] = l
+	}
+	return rows.Err()
+}
+
+// GetPerson retrieves a person by id.
+func (r *Repository) GetPerson(ctx context.Context, id string) (*model.Person, error) {
+	p := &model.Person{ID: id}
+	row := r.db.QueryRowContext(ctx, "SELECT name FROM people WHERE id = ?", id)
+	if err := row.Scan(&p.Name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// PutPerson adds a person.
+func (r *Repository) PutPerson(ctx context.Context, person *model.Person) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO people (id, name) VALUES (?, ?)", person.ID, person.Name)
 	return err
 }
+
+// PutCredits replaces the credits of a given movie.
+func (r *Repository) PutCredits(ctx context.Context, movieID string, credits []model.Credit) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM credits WHERE movie_id = ?", movieID); err != nil {
+		return err
+	}
+	for _, c := range credits {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO credits (movie_id, person_id, role, character_name, billing_order) VALUES (?, ?, ?, ?, ?)",
+			movieID, c.PersonID, c.Role, c.Character, c.Order); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCredits retrieves the credits of a given movie in billing order.
+func (r *Repository) GetCredits(ctx context.Context, movieID string) ([]model.Credit, error) {
+	return r.queryCredits(ctx, "c.movie_id = ? ORDER BY c.billing_order", movieID)
+}
+
+// GetFilmography retrieves the credits of a given person.
+func (r *Repository) GetFilmography(ctx context.Context, personID string) ([]model.Credit, error) {
+	return r.queryCredits(ctx, "c.person_id = ? ORDER BY c.movie_id", personID)
+}
+
+func (r *Repository) queryCredits(ctx context.Context, cond string, arg string) ([]model.Credit, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT c.movie_id, c.person_id, c.role, c.character_name, c.billing_order, "+
+		"COALESCE(p.name, ''), COALESCE(m.title, '') FROM credits c "+
+		"LEFT JOIN people p ON p.id = c.person_id LEFT JOIN movies m ON m.id = c.movie_id WHERE "+cond, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []model.Credit
+	for rows.Next() {
+		var c model.Credit
+		if err := rows.Scan(&c.MovieID, &c.PersonID, &c.Role, &c.Character, &c.Order, &c.PersonName, &c.MovieTitle); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"movieapp.com/metadata/pkg/model"
+)
+
+// Repository defines a movie metadata repository, implemented by
+// the memory, MySQL and DynamoDB repositories.
+type Repository interface {
+	Get(ctx context.Context, id string) (*model.Metadata, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error)
+	GetByExternalID(ctx context.Context, source model.ExternalSource, externalID string) (*model.Metadata, error)
+	Put(ctx context.Context, id string, metadata *model.Metadata) error
+	GetVersion(ctx context.Context, id string, version int) (*model.Metadata, error)
+	History(ctx context.Context, id string) ([]*model.Metadata, error)
+	List(ctx context.Context, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error)
+	Search(ctx context.Context, query string, filter model.Filter, pageSize int, pageToken string) ([]*model.Metadata, string, error)
+	Facets(ctx context.Context, query string, filter model.Filter) (*model.Facets, error)
+	GetPerson(ctx context.Context, id string) (*model.Person, error)
+	GetCredits(ctx context.Context, movieID string) ([]model.Credit, error)
+	GetFilmography(ctx context.Context, personID string) ([]model.Credit, error)
+}
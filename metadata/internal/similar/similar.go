@@ -0,0 +1,64 @@
+package similar
+
+import (
+	"movieapp.com/metadata/pkg/model"
+)
+
+// Item defines a movie compared for similarity along with the
+// ids of the people credited in it.
+type Item struct {
+	Metadata *model.Metadata
+	People   []string
+}
+
+// Scorer defines a similarity scorer. Scores are non-negative,
+// higher means more similar and zero means unrelated.
+type Scorer interface {
+	Score(a, b Item) float64
+}
+
+// WeightedScorer scores movies by the weighted Jaccard index of
+// their genres, tags and credited people.
+type WeightedScorer struct {
+	GenreWeight  float64
+	TagWeight    float64
+	PeopleWeight float64
+}
+
+// NewWeightedScorer creates a weighted scorer favouring shared
+// genres, then cast and crew, then tags.
+func NewWeightedScorer() *WeightedScorer {
+	return &WeightedScorer{GenreWeight: 0.5, TagWeight: 0.2, PeopleWeight: 0.3}
+}
+
+// Score returns the similarity of two movies.
+func (s *WeightedScorer) Score(a, b Item) float64 {
+	return s.GenreWeight*jaccard(a.Metadata.Genres, b.Metadata.Genres) +
+		s.TagWeight*jaccard(a.Metadata.Tags, b.Metadata.Tags) +
+		s.PeopleWeight*jaccard(a.People, b.People)
+}
+
+func jaccard[T comparable](a, b []T) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[T]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	var shared int
+	union := len(set)
+	seen := make(map[T]bool, len(b))
+	for _, v := range b {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		if set[v] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	return float64(shared) / float64(union)
+}
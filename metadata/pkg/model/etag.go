@@ -0,0 +1,18 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ETag returns an entity tag identifying the content of the
+// metadata, suitable for conditional requests.
+func (m *Metadata) ETag() string {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// EventType defines the type of a metadata change event.
+type EventType string
+
+// Existing event types.
+const (
+	EventTypeCreated = EventType("MetadataCreated")
+	EventTypeUpdated = EventType("MetadataUpdated")
+	EventTypeDeleted = EventType("MetadataDeleted")
+	// EventTypeReindexed events carry unchanged metadata to
+	// rebuild downstream indexes.
+	EventTypeReindexed = EventType("MetadataReindexed")
+)
+
+// Event defines a metadata change event published to downstream
+// systems after each write.
+type Event struct {
+	// ID is unique per event and stable across redeliveries, so
+	// consumers can use it to deduplicate.
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	MovieID   string    `json:"movieId"`
+	Version   int       `json:"version"`
+	Metadata  *Metadata `json:"metadata"`
+	Timestamp time.Time `json:"timestamp"`
+	// TraceContext is the trace context of the write, propagated
+	// to the consumers of the event.
+	TraceContext map[string]string `json:"traceContext,omitempty"`
+}
+
+// NewEvent creates the change event of a write of the current
+// metadata over the previous one, nil if there was none.
+func NewEvent(previous *Metadata, current *Metadata) *Event {
+	t := EventTypeUpdated
+	switch {
+	case previous == nil:
+		t = EventTypeCreated
+	case current.Deleted() && !previous.Deleted():
+		t = EventTypeDeleted
+	}
+	return &Event{
+		ID:        current.ID + "@" + strconv.Itoa(current.Version),
+		Type:      t,
+		MovieID:   current.ID,
+		Version:   current.Version,
+		Metadata:  current,
+		Timestamp: current.UpdatedAt,
+	}
+}
+
+// NewReindexEvent creates the event carrying the metadata to
+// downstream indexes in the given reindex run.
+func NewReindexEvent(m *Metadata, run string) *Event {
+	return &Event{
+		ID:        m.ID + "@" + strconv.Itoa(m.Version) + "/" + run,
+		Type:      EventTypeReindexed,
+		MovieID:   m.ID,
+		Version:   m.Version,
+		Metadata:  m,
+		Timestamp: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import "regexp"
+
+// ExternalSource defines a catalog assigning external movie ids.
+type ExternalSource string
+
+// Known external sources.
+const (
+	ExternalSourceIMDb = ExternalSource("imdb")
+	ExternalSourceTMDB = ExternalSource("tmdb")
+)
+
+var externalIDPatterns = map[ExternalSource]*regexp.Regexp{
+	ExternalSourceIMDb: regexp.MustCompile(`^tt[0-9]{7,}$`),
+	ExternalSourceTMDB: regexp.MustCompile(`^[1-9][0-9]*$`),
+}
+
+// ExternalSources returns the known external sources.
+func ExternalSources() []ExternalSource {
+	return []ExternalSource{ExternalSourceIMDb, ExternalSourceTMDB}
+}
+
+// ValidExternalID checks whether the id is well formed for the
+// source. Ids of unknown sources are never valid.
+func ValidExternalID(source ExternalSource, id string) bool {
+	p, ok := externalIDPatterns[source]
+	return ok && p.MatchString(id)
+}
@@ -0,0 +1,39 @@
+package model
+
+// Facets defines the number of search hits by genre, release
+// decade, original language and certification, for rendering
+// filters next to the hits.
+type Facets struct {
+	Genres map[Genre]int `json:"genres"`
+	// Decades are keyed by their first year, e.g. 1990.
+	Decades        map[int]int    `json:"decades"`
+	Languages      map[string]int `json:"languages"`
+	Certifications map[string]int `json:"certifications"`
+}
+
+// NewFacets creates empty facets.
+func NewFacets() *Facets {
+	return &Facets{
+		Genres:         map[Genre]int{},
+		Decades:        map[int]int{},
+		Languages:      map[string]int{},
+		Certifications: map[string]int{},
+	}
+}
+
+// Add counts the metadata in the facets. Unset values are not
+// counted.
+func (f *Facets) Add(m *Metadata) {
+	for _, g := range m.Genres {
+		f.Genres[g]++
+	}
+	if year := m.ReleaseYear(); year > 0 {
+		f.Decades[year/10*10]++
+	}
+	if m.OriginalLanguage != "" {
+		f.Languages[m.OriginalLanguage]++
+	}
+	if m.Certification != "" {
+		f.Certifications[m.Certification]++
+	}
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Filter defines the criteria used to narrow down metadata
+// listings and searches. Empty criteria match everything. Form
+// tags name the criteria in HTTP requests.
+type Filter struct {
+	// Genres the metadata must all be classified with.
+	Genres []Genre `form:"genre"`
+	// Tags the metadata must all be tagged with.
+	Tags []string `form:"tag"`
+	// YearFrom and YearTo bound the release year, inclusive.
+	// Metadata without a release date does not match bounds.
+	YearFrom int `form:"yearFrom" validate:"min=0"`
+	YearTo   int `form:"yearTo" validate:"min=0"`
+	// Certifications the metadata must have one of.
+	Certifications []string `form:"certification"`
+	// OriginalLanguage the metadata must be originally in.
+	OriginalLanguage string `form:"language"`
+	// MinRuntime and MaxRuntime bound the runtime in minutes,
+	// inclusive.
+	MinRuntime int `form:"minRuntime" validate:"min=0"`
+	MaxRuntime int `form:"maxRuntime" validate:"min=0"`
+	// UpdatedSince makes only metadata updated at or after the
+	// time match.
+	UpdatedSince time.Time
+	// IncludeDeleted makes deleted metadata match as well.
+	IncludeDeleted bool `form:"includeDeleted"`
+}
+
+// Matches checks whether the metadata satisfies the filter.
+func (f Filter) Matches(m *Metadata) bool {
+	if m.Deleted() && !f.IncludeDeleted {
+		return false
+	}
+	for _, g := range f.Genres {
+		if !slices.Contains(m.Genres, g) {
+			return false
+		}
+	}
+	for _, t := range f.Tags {
+		if !slices.Contains(m.Tags, t) {
+			return false
+		}
+	}
+	if f.YearFrom > 0 || f.YearTo > 0 {
+		year := m.ReleaseYear()
+		if year == 0 || (f.YearFrom > 0 && year < f.YearFrom) || (f.YearTo > 0 && year > f.YearTo) {
+			return false
+		}
+	}
+	if len(f.Certifications) > 0 && !slices.Contains(f.Certifications, m.Certification) {
+		return false
+	}
+	if f.OriginalLanguage != "" && !strings.EqualFold(f.OriginalLanguage, m.OriginalLanguage) {
+		return false
+	}
+	if f.MinRuntime > 0 && m.RuntimeMinutes < f.MinRuntime {
+		return false
+	}
+	if f.MaxRuntime > 0 && (m.RuntimeMinutes == 0 || m.RuntimeMinutes > f.MaxRuntime) {
+		return false
+	}
+	if !f.UpdatedSince.IsZero() && m.UpdatedAt.Before(f.UpdatedSince) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package model
+
+// Genre defines a movie genre from the known taxonomy.
+type Genre string
+
+// Known genres.
+const (
+	GenreAction      = Genre("action")
+	GenreAdventure   = Genre("adventure")
+	GenreAnimation   = Genre("animation")
+	GenreComedy      = Genre("comedy")
+	GenreCrime       = Genre("crime")
+	GenreDocumentary = Genre("documentary")
+	GenreDrama       = Genre("drama")
+	GenreFamily      = Genre("family")
+	GenreFantasy     = Genre("fantasy")
+	GenreHistory     = Genre("history")
+	GenreHorror      = Genre("horror")
+	GenreMusic       = Genre("music")
+	GenreMystery     = Genre("mystery")
+	GenreRomance     = Genre("romance")
+	GenreSciFi       = Genre("science-fiction")
+	GenreThriller    = Genre("thriller")
+	GenreWar         = Genre("war")
+	GenreWestern     = Genre("western")
+)
+
+// Genres returns the known genre taxonomy.
+func Genres() []Genre {
+	return []Genre{
+		GenreAction, GenreAdventure, GenreAnimation, GenreComedy,
+		GenreCrime, GenreDocumentary, GenreDrama, GenreFamily,
+		GenreFantasy, GenreHistory, GenreHorror, GenreMusic,
+		GenreMystery, GenreRomance, GenreSciFi, GenreThriller,
+		GenreWar, GenreWestern,
+	}
+}
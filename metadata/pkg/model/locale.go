@@ -0,0 +1,99 @@
+package model
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale defines the locale of the base metadata text.
+const DefaultLocale = "en"
+
+// Localization defines locale-specific metadata text. Empty
+// fields fall back to the base metadata.
+type Localization struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Tagline     string `json:"tagline,omitempty"`
+}
+
+// LocaleFallbacks returns the locales to try for a requested
+// locale, from the most to the least specific, e.g. pt-BR, pt.
+func LocaleFallbacks(locale string) []string {
+	var res []string
+	for locale != "" {
+		res = append(res, locale)
+		i := strings.LastIndexAny(locale, "-_")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return res
+}
+
+// ParseAcceptLanguage returns the locales listed in an
+// Accept-Language header value ordered by preference.
+func ParseAcceptLanguage(header string) []string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+	var res []string
+	for _, t := range tags {
+		if t != language.Und {
+			res = append(res, t.String())
+		}
+	}
+	return res
+}
+
+// Localize returns a copy of the metadata with its text resolved
+// for the first of the preferred locales that has a localization,
+// falling back to the base text in DefaultLocale. The returned
+// copy has Locale set and no Localizations.
+func (m *Metadata) Localize(preferred ...string) *Metadata {
+	res := *m
+	res.Localizations = nil
+	res.Locale = DefaultLocale
+	for _, p := range preferred {
+		for _, locale := range LocaleFallbacks(p) {
+			if strings.EqualFold(locale, DefaultLocale) {
+				return &res
+			}
+			k, ok := m.localization(locale)
+			if !ok {
+				continue
+			}
+			l := m.Localizations[k]
+			res.Locale = k
+			if l.Title != "" {
+				res.Title = l.Title
+			}
+			if l.Description != "" {
+				res.Description = l.Description
+			}
+			if l.Tagline != "" {
+				res.Tagline = l.Tagline
+			}
+			return &res
+		}
+	}
+	return &res
+}
+
+// localization returns the key of the localization of a locale,
+// matched case-insensitively. Of keys differing only in case, an
+// exact match wins, then the first in sorted order.
+func (m *Metadata) localization(locale string) (string, bool) {
+	if _, ok := m.Localizations[locale]; ok {
+		return locale, true
+	}
+	var res string
+	for k := range m.Localizations {
+		if strings.EqualFold(k, locale) && (res == "" || k < res) {
+			res = k
+		}
+	}
+	return res, res != ""
+}
@@ -1,6 +1,11 @@
 package model
 
 import (
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"movieapp.com/gen"
 )
 
@@ -8,10 +13,27 @@ import (
 // generated proto counterpart.
 func MetadataToProto(m *Metadata) *gen.Metadata {
 	return &gen.Metadata{
-		Id:          m.ID,
-		Title:       m.Title,
-		Description: m.Description,
-		Director:    m.Director,
+		Id:               m.ID,
+		Title:            m.Title,
+		Description:      m.Description,
+		Director:         m.Director,
+		Genres:           genresToProto(m.Genres),
+		Tags:             m.Tags,
+		PosterUrl:        m.PosterURL,
+		BackdropUrl:      m.BackdropURL,
+		Tagline:          m.Tagline,
+		ReleaseDate:      m.ReleaseDate,
+		RuntimeMinutes:   int32(m.RuntimeMinutes),
+		Certification:    m.Certification,
+		OriginalLanguage: m.OriginalLanguage,
+		ExternalIds:      externalIDsToProto(m.ExternalIDs),
+		Localizations:    localizationsToProto(m.Localizations),
+		Locale:           m.Locale,
+		Version:          int64(m.Version),
+		UpdatedAt:        timeToProto(m.UpdatedAt),
+		UpdatedBy:        m.UpdatedBy,
+		DeletedAt:        optionalTimeToProto(m.DeletedAt),
+		MergedInto:       m.MergedInto,
 	}
 }
 
@@ -19,9 +41,148 @@ func MetadataToProto(m *Metadata) *gen.Metadata {
 // into a Metadata struct.
 func MetadataFromProto(m *gen.Metadata) *Metadata {
 	return &Metadata{
-		ID:          m.Id,
-		Title:       m.Title,
-		Description: m.Description,
-		Director:    m.Director,
+		ID:               m.Id,
+		Title:            m.Title,
+		Description:      m.Description,
+		Director:         m.Director,
+		Genres:           genresFromProto(m.Genres),
+		Tags:             m.Tags,
+		PosterURL:        m.PosterUrl,
+		BackdropURL:      m.BackdropUrl,
+		Tagline:          m.Tagline,
+		ReleaseDate:      m.ReleaseDate,
+		RuntimeMinutes:   int(m.RuntimeMinutes),
+		Certification:    m.Certification,
+		OriginalLanguage: m.OriginalLanguage,
+		ExternalIDs:      externalIDsFromProto(m.ExternalIds),
+		Localizations:    localizationsFromProto(m.Localizations),
+		Locale:           m.Locale,
+		Version:          int(m.Version),
+		UpdatedAt:        timeFromProto(m.UpdatedAt),
+		UpdatedBy:        m.UpdatedBy,
+		DeletedAt:        optionalTimeFromProto(m.DeletedAt),
+		MergedInto:       m.MergedInto,
+	}
+}
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func optionalTimeToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func optionalTimeFromProto(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	res := t.AsTime()
+	return &res
+}
+
+func genresToProto(genres []Genre) []string {
+	if genres == nil {
+		return nil
+	}
+	res := make([]string, 0, len(genres))
+	for _, g := range genres {
+		res = append(res, string(g))
+	}
+	return res
+}
+
+func genresFromProto(genres []string) []Genre {
+	if genres == nil {
+		return nil
+	}
+	res := make([]Genre, 0, len(genres))
+	for _, g := range genres {
+		res = append(res, Genre(g))
+	}
+	return res
+}
+
+func localizationsToProto(ls map[string]Localization) map[string]*gen.Localization {
+	if ls == nil {
+		return nil
+	}
+	res := make(map[string]*gen.Localization, len(ls))
+	for k, l := range ls {
+		res[k] = &gen.Localization{Title: l.Title, Description: l.Description, Tagline: l.Tagline}
+	}
+	return res
+}
+
+func localizationsFromProto(ls map[string]*gen.Localization) map[string]Localization {
+	if ls == nil {
+		return nil
+	}
+	res := make(map[string]Localization, len(ls))
+	for k, l := range ls {
+		res[k] = Localization{Title: l.Title, Description: l.Description, Tagline: l.Tagline}
+	}
+	return res
+}
+
+func externalIDsToProto(ids map[ExternalSource]string) map[string]string {
+	if ids == nil {
+		return nil
+	}
+	res := make(map[string]string, len(ids))
+	for k, v := range ids {
+		res[string(k)] = v
+	}
+	return res
+}
+
+func externalIDsFromProto(ids map[string]string) map[ExternalSource]string {
+	if ids == nil {
+		return nil
+	}
+	res := make(map[ExternalSource]string, len(ids))
+	for k, v := range ids {
+		res[ExternalSource(k)] = v
+	}
+	return res
+}
+
+// FacetsToProto converts facets into a generated proto
+// counterpart.
+func FacetsToProto(f *Facets) *gen.Facets {
+	res := &gen.Facets{}
+	for g, n := range f.Genres {
+		res.Genres = append(res.Genres, &gen.FacetCount{Value: string(g), Count: int32(n)})
+	}
+	for d, n := range f.Decades {
+		res.Decades = append(res.Decades, &gen.FacetCount{Value: strconv.Itoa(d), Count: int32(n)})
+	}
+	for l, n := range f.Languages {
+		res.Languages = append(res.Languages, &gen.FacetCount{Value: l, Count: int32(n)})
+	}
+	for c, n := range f.Certifications {
+		res.Certifications = append(res.Certifications, &gen.FacetCount{Value: c, Count: int32(n)})
+	}
+	for _, counts := range [][]*gen.FacetCount{res.Genres, res.Decades, res.Languages, res.Certifications} {
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Value < counts[j].Value
+		})
 	}
+	return res
 }
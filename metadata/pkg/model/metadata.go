@@ -1,9 +1,105 @@
 package model
 
+import "time"
+
 // Metadata defines the movie metadata
 type Metadata struct {
-	ID          string `json: "id"`
-	Title       string `json: "title"`
-	Description string `json: "description"`
-	Director    string `json: "director"`
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Director    string   `json:"director"`
+	Genres      []Genre  `json:"genres,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	PosterURL   string   `json:"posterUrl,omitempty"`
+	BackdropURL string   `json:"backdropUrl,omitempty"`
+	Tagline     string   `json:"tagline,omitempty"`
+	// ReleaseDate is the date of the original release in the
+	// YYYY-MM-DD format.
+	ReleaseDate    string `json:"releaseDate,omitempty"`
+	RuntimeMinutes int    `json:"runtimeMinutes,omitempty"`
+	// Certification is the age rating, e.g. PG-13.
+	Certification string `json:"certification,omitempty"`
+	// OriginalLanguage is the BCP 47 tag of the original
+	// language, e.g. ja.
+	OriginalLanguage string `json:"originalLanguage,omitempty"`
+	// ExternalIDs holds the ids of the movie in other catalogs,
+	// each unique across all metadata.
+	ExternalIDs map[ExternalSource]string `json:"externalIds,omitempty"`
+	// Localizations holds the localized text keyed by locale.
+	Localizations map[string]Localization `json:"localizations,omitempty"`
+	// Locale is the locale the text is resolved for, set only
+	// on localized metadata.
+	Locale string `json:"locale,omitempty"`
+	// Version is incremented on every change of the metadata.
+	Version   int       `json:"version,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	// DeletedAt is set when the metadata is removed from the
+	// catalog. Deleted metadata is kept and can be restored.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// MergedInto is the id of the metadata this duplicate was
+	// merged into. Reads of merged metadata are redirected.
+	MergedInto string `json:"mergedInto,omitempty"`
+}
+
+// Deleted checks whether the metadata is removed from the catalog.
+func (m *Metadata) Deleted() bool {
+	return m.DeletedAt != nil
+}
+
+// ReleaseDateLayout defines the format of metadata release dates.
+const ReleaseDateLayout = "2006-01-02"
+
+// ReleaseYear returns the year of the release date, or zero if
+// the release date is not set or invalid.
+func (m *Metadata) ReleaseYear() int {
+	t, err := time.Parse(ReleaseDateLayout, m.ReleaseDate)
+	if err != nil {
+		return 0
+	}
+	return t.Year()
+}
+
+// ImageKind defines a kind of movie artwork.
+type ImageKind string
+
+// Existing image kinds.
+const (
+	ImageKindPoster   = ImageKind("poster")
+	ImageKindBackdrop = ImageKind("backdrop")
+)
+
+// ImageURL returns the origin URL of the artwork of the given
+// kind or an empty string if the movie has none.
+func (m *Metadata) ImageURL(kind ImageKind) string {
+	switch kind {
+	case ImageKindPoster:
+		return m.PosterURL
+	case ImageKindBackdrop:
+		return m.BackdropURL
+	}
+	return ""
+}
+
+// Page defines a page of movie metadata along with the
+// token to fetch the next one, empty on the last page.
+type Page struct {
+	Metadata      []*Metadata `json:"metadata"`
+	NextPageToken string      `json:"nextPageToken,omitempty"`
+	// Facets count all search hits, set only if requested.
+	Facets *Facets `json:"facets,omitempty"`
+}
+
+// DuplicateMatch defines existing metadata likely describing the
+// same movie as another one, along with the title similarity.
+type DuplicateMatch struct {
+	Metadata *Metadata `json:"metadata"`
+	Score    float64   `json:"score"`
+}
+
+// SimilarMovie defines a movie similar to another one along
+// with its similarity score.
+type SimilarMovie struct {
+	Metadata *Metadata `json:"metadata"`
+	Score    float64   `json:"score"`
 }
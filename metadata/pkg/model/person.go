@@ -0,0 +1,34 @@
+package model
+
+// Person defines a person involved in making movies, such as
+// an actor or a director.
+type Person struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Role defines the role of a person in a movie.
+type Role string
+
+// Existing roles.
+const (
+	RoleActor    = Role("actor")
+	RoleDirector = Role("director")
+	RoleWriter   = Role("writer")
+	RoleProducer = Role("producer")
+	RoleComposer = Role("composer")
+)
+
+// Credit defines the participation of a person in a movie.
+type Credit struct {
+	MovieID  string `json:"movieId"`
+	PersonID string `json:"personId"`
+	Role     Role   `json:"role"`
+	// Character is the name of the character played by an actor.
+	Character string `json:"character,omitempty"`
+	// Order is the billing order of the credit within a movie.
+	Order int `json:"order"`
+	// PersonName and MovieTitle are populated on reads for display.
+	PersonName string `json:"personName,omitempty"`
+	MovieTitle string `json:"movieTitle,omitempty"`
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/movie/internal/controller/movie"
+	"movieapp.com/movie/internal/realtime"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/debug"
+	"movieapp.com/pkg/flags"
+	"movieapp.com/pkg/httpmw"
+	"movieapp.com/pkg/lifecycle"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/mtls"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/pkg/secrets"
+	"movieapp.com/pkg/tenant"
+)
+
+// serviceConfig defines the settings of the movie service, loaded
+// by config.Load.
+type serviceConfig struct {
+	Host                 string                    `yaml:"host"`
+	DrainTimeout         time.Duration             `yaml:"drainTimeout"`
+	DrainDelay           time.Duration             `yaml:"drainDelay"`
+	ConfigReload         time.Duration             `yaml:"configReload"`
+	RegistryAddr         string                    `yaml:"registryAddr"`
+	RegistryCheck        time.Duration             `yaml:"registryCheck"`
+	Port                 int                       `yaml:"port"`
+	HTTPPort             int                       `yaml:"httpPort"`
+	RESTPort             int                       `yaml:"restPort"`
+	MetricsPort          int                       `yaml:"metricsPort"`
+	KafkaBrokers         config.List               `yaml:"kafkaBrokers"`
+	EventsTopic          string                    `yaml:"eventsTopic"`
+	RatingEventsTopic    string                    `yaml:"ratingEventsTopic"`
+	KafkaSASL            kafkautil.SASLConfig      `yaml:"kafkaSASL"`
+	DetailsCacheTTL      time.Duration             `yaml:"detailsCacheTTL"`
+	RequestBudget        time.Duration             `yaml:"requestBudget"`
+	HedgeDelay           time.Duration             `yaml:"hedgeDelay"`
+	RedisAddr            string                    `yaml:"redisAddr"`
+	RateLimitConfig      string                    `yaml:"rateLimitConfig"`
+	AdminToken           string                    `yaml:"adminToken"`
+	AuditDSN             string                    `yaml:"auditDSN"`
+	CachePolicyConfig    string                    `yaml:"cachePolicyConfig"`
+	ExperimentsConfig    string                    `yaml:"experimentsConfig"`
+	SimilarTitles        int                       `yaml:"similarTitles"`
+	SimilarExperiment    string                    `yaml:"similarExperiment"`
+	SimilarFlag          string                    `yaml:"similarFlag"`
+	Watchlist            bool                      `yaml:"watchlist"`
+	Realtime             realtime.Config           `yaml:"realtime"`
+	CatalogEvents        realtime.FeedConfig       `yaml:"catalogEvents"`
+	ExportBucket         string                    `yaml:"exportBucket"`
+	ExportEndpoint       string                    `yaml:"exportEndpoint"`
+	ExportLinkTTL        time.Duration             `yaml:"exportLinkTTL"`
+	ModelRecommendations bool                      `yaml:"modelRecommendations"`
+	RatingDegradation    string                    `yaml:"ratingDegradation"`
+	OTLPEndpoint         string                    `yaml:"otlpEndpoint"`
+	SentryDSN            string                    `yaml:"sentryDSN"`
+	HTTPTimeouts         httpmw.TimeoutConfig      `yaml:"httpTimeouts"`
+	LogLevel             string                    `yaml:"logLevel"`
+	Tenants              config.List               `yaml:"tenants"`
+	TLS                  mtls.Config               `yaml:"tls"`
+	Auth                 auth.Config               `yaml:"auth"`
+	CORS                 httpmw.CORSConfig         `yaml:"cors"`
+	Breaker              resilience.BreakerConfig  `yaml:"breaker"`
+	Bulkhead             resilience.BulkheadConfig `yaml:"bulkhead"`
+	Faults               resilience.FaultConfig    `yaml:"faults"`
+	Flags                flags.Config              `yaml:"flags"`
+	Secrets              secrets.Config            `yaml:"secrets"`
+	Debug                debug.Config              `yaml:"debug"`
+}
+
+func defaultConfig() *serviceConfig {
+	httpTimeouts := httpmw.DefaultTimeoutConfig()
+	// Exports stream the whole catalog for as long as it takes, and
+	// catalog events for as long as clients listen.
+	httpTimeouts.Routes = map[string]time.Duration{"/movies/export": 0, "/v1/movies:export": 0, "/movies/events": 0}
+	return &serviceConfig{
+		Host:              "localhost",
+		DrainTimeout:      lifecycle.DefaultDrainTimeout,
+		DrainDelay:        lifecycle.DefaultDrainDelay,
+		ConfigReload:      config.DefaultReload,
+		RegistryAddr:      "localhost:8500",
+		Port:              8083,
+		HTTPPort:          8084,
+		RESTPort:          8073,
+		MetricsPort:       8093,
+		KafkaBrokers:      config.List{"localhost:9092"},
+		EventsTopic:       "metadata",
+		RatingEventsTopic: "ratings",
+		KafkaSASL:         kafkautil.SASLConfig{Mechanism: kafkautil.MechanismSCRAMSHA512},
+		DetailsCacheTTL:   30 * time.Second,
+		RequestBudget:     3 * time.Second,
+		ExportLinkTTL:     24 * time.Hour,
+		Realtime:          realtime.DefaultConfig(),
+		CatalogEvents:     realtime.DefaultFeedConfig(),
+		RatingDegradation: string(movie.DefaultDegradationPolicy().Rating),
+		LogLevel:          "info",
+		Breaker:           resilience.DefaultBreakerConfig(),
+		Bulkhead:          resilience.DefaultBulkheadConfig(),
+		CORS:              httpmw.DefaultCORSConfig(),
+		Flags:             flags.Config{Refresh: flags.DefaultRefresh},
+		Secrets:           secrets.DefaultConfig(),
+		HTTPTimeouts:      httpTimeouts,
+	}
+}
+
+// Validate validates the config.
+func (c *serviceConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("host: empty"))
+	}
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drainTimeout: not positive"))
+	}
+	if c.DrainDelay < 0 || c.RegistryCheck < 0 || c.ConfigReload < 0 {
+		errs = append(errs, errors.New("drainDelay, registryCheck, configReload: negative"))
+	}
+	errs = append(errs,
+		config.ValidatePort("port", c.Port, false),
+		config.ValidatePort("httpPort", c.HTTPPort, false),
+		config.ValidatePort("restPort", c.RESTPort, true),
+		config.ValidatePort("metricsPort", c.MetricsPort, false),
+		config.ValidateAddr("registryAddr", c.RegistryAddr, false),
+		config.ValidateAddrs("kafkaBrokers", c.KafkaBrokers),
+		config.ValidateAddr("redisAddr", c.RedisAddr, true),
+		config.ValidateAddr("otlpEndpoint", c.OTLPEndpoint, true),
+	)
+	if c.EventsTopic == "" || c.RatingEventsTopic == "" {
+		errs = append(errs, errors.New("eventsTopic, ratingEventsTopic: empty"))
+	}
+	if c.DetailsCacheTTL <= 0 || c.RequestBudget <= 0 {
+		errs = append(errs, errors.New("detailsCacheTTL, requestBudget: not positive"))
+	}
+	// Presigned S3 links are valid for up to a week.
+	if c.ExportLinkTTL <= 0 || c.ExportLinkTTL > 7*24*time.Hour {
+		errs = append(errs, errors.New("exportLinkTTL: not between 0 and 168h"))
+	}
+	if c.HedgeDelay < 0 || c.SimilarTitles < 0 {
+		errs = append(errs, errors.New("hedgeDelay, similarTitles: negative"))
+	}
+	if c.Breaker.FailureThreshold <= 0 || c.Breaker.OpenTimeout <= 0 || c.Breaker.HalfOpenProbes <= 0 {
+		errs = append(errs, errors.New("breaker: not positive"))
+	}
+	if c.Bulkhead.Limit < 0 || c.Bulkhead.MaxWait < 0 {
+		errs = append(errs, errors.New("bulkhead: negative"))
+	}
+	if err := c.Realtime.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("realtime: %w", err))
+	}
+	if err := c.CatalogEvents.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("catalogEvents: %w", err))
+	}
+	if err := c.Faults.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("faults: %w", err))
+	}
+	if _, err := movie.ParseDegradation(c.RatingDegradation); err != nil {
+		errs = append(errs, fmt.Errorf("ratingDegradation: %w", err))
+	}
+	if _, err := logging.ParseLevels(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if err := c.KafkaSASL.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("kafkaSASL: %w", err))
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	}
+	if err := c.CORS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("cors: %w", err))
+	}
+	if err := c.Auth.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth: %w", err))
+	}
+	if err := c.Flags.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("flags: %w", err))
+	}
+	if err := c.Debug.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("debug: %w", err))
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+	if err := c.HTTPTimeouts.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("httpTimeouts: %w", err))
+	}
+	for _, id := range c.Tenants {
+		if err := tenant.Validate(id); err != nil {
+			errs = append(errs, fmt.Errorf("tenants: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
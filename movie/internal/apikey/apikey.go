@@ -0,0 +1,259 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"movieapp.com/pkg/problem"
+)
+
+// Header is the header of the API key of a partner.
+const Header = "X-API-Key"
+
+// keyPrefix starts the API keys, so that leaked keys are easy to
+// find, such as by secret scanners.
+const keyPrefix = "mk"
+
+var (
+	// ErrNotFound is returned when a key does not exist.
+	ErrNotFound = problem.Register(errors.New("api key not found"), problem.NotFound)
+	// ErrInvalid is returned for malformed, unknown, revoked and
+	// expired keys.
+	ErrInvalid = problem.Register(errors.New("invalid api key"), problem.Unauthenticated)
+)
+
+// Key defines an API key issued to a partner. Only the hash of its
+// secret is stored, the key itself is returned once when issued.
+type Key struct {
+	ID      string `json:"id"`
+	Partner string `json:"partner"`
+	Hash    []byte `json:"hash"`
+	// Quota is the number of requests the key may send per
+	// calendar month (UTC), unlimited if zero.
+	Quota     int64      `json:"quota"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Active reports whether the key authenticates requests at a time.
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Repository stores API keys and counts their usage.
+type Repository interface {
+	Put(ctx context.Context, key *Key) error
+	// Get returns a key or ErrNotFound.
+	Get(ctx context.Context, id string) (*Key, error)
+	List(ctx context.Context, partner string) ([]*Key, error)
+	// IncrUsage increments the count of the requests of a key in a
+	// period and returns the count.
+	IncrUsage(ctx context.Context, id string, period string) (int64, error)
+	Usage(ctx context.Context, id string, period string) (int64, error)
+}
+
+// Period returns the billing period of a time, its UTC month such
+// as 2024-05.
+func Period(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// Manager issues, rotates and authenticates API keys.
+type Manager struct {
+	repo Repository
+}
+
+// New creates a new API key manager.
+func New(repo Repository) *Manager {
+	return &Manager{repo}
+}
+
+// Issue issues a new key to a partner with a monthly quota, and
+// returns it with its secret form, to hand to the partner.
+func (m *Manager) Issue(ctx context.Context, partner string, quota int64) (string, *Key, error) {
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	key := &Key{ID: id, Partner: partner, Hash: hash(secret), Quota: quota, CreatedAt: time.Now().UTC()}
+	if err := m.repo.Put(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return keyPrefix + "_" + id + "_" + secret, key, nil
+}
+
+// Rotate issues a replacement of a key, with the same partner and
+// quota, and expires the key after the grace period, during which
+// the partner switches to the new one.
+func (m *Manager) Rotate(ctx context.Context, id string, grace time.Duration) (string, *Key, error) {
+	old, err := m.repo.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !old.Active(time.Now()) {
+		return "", nil, ErrInvalid
+	}
+	raw, key, err := m.Issue(ctx, old.Partner, old.Quota)
+	if err != nil {
+		return "", nil, err
+	}
+	expiresAt := time.Now().UTC().Add(grace)
+	if old.ExpiresAt == nil || expiresAt.Before(*old.ExpiresAt) {
+		old.ExpiresAt = &expiresAt
+	}
+	if err := m.repo.Put(ctx, old); err != nil {
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// Revoke revokes a key immediately.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	key, err := m.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+	}
+	return m.repo.Put(ctx, key)
+}
+
+// List returns the keys of a partner.
+func (m *Manager) List(ctx context.Context, partner string) ([]*Key, error) {
+	return m.repo.List(ctx, partner)
+}
+
+// Usage returns the count of the requests of a key in a period.
+func (m *Manager) Usage(ctx context.Context, id string, period string) (int64, error) {
+	return m.repo.Usage(ctx, id, period)
+}
+
+// Authenticate returns the active key of its secret form, or
+// ErrInvalid.
+func (m *Manager) Authenticate(ctx context.Context, raw string) (*Key, error) {
+	prefix, rest, ok := strings.Cut(raw, "_")
+	if !ok || prefix != keyPrefix {
+		return nil, ErrInvalid
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalid
+	}
+	key, err := m.repo.Get(ctx, id)
+	if err != nil && errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalid
+	} else if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(hash(secret), key.Hash) != 1 || !key.Active(time.Now()) {
+		return nil, ErrInvalid
+	}
+	return key, nil
+}
+
+// hash hashes the secret of a key. A fast hash suffices, as
+// secrets are random rather than chosen by people.
+func hash(secret string) []byte {
+	h := sha256.Sum256([]byte(secret))
+	return h[:]
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a context of a request authenticated by a key.
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key of the request of the context, nil
+// if it is not sent by a partner.
+func FromContext(ctx context.Context) *Key {
+	key, _ := ctx.Value(contextKey{}).(*Key)
+	return key
+}
+
+// MemoryRepository stores keys in process, for a single instance.
+type MemoryRepository struct {
+	mu    sync.Mutex
+	keys  map[string]Key
+	usage map[string]int64
+}
+
+// NewMemoryRepository creates a new in-process repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{keys: map[string]Key{}, usage: map[string]int64{}}
+}
+
+// Put stores a key.
+func (r *MemoryRepository) Put(_ context.Context, key *Key) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.ID] = *key
+	return nil
+}
+
+// Get returns a key or ErrNotFound.
+func (r *MemoryRepository) Get(_ context.Context, id string) (*Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+// List returns the keys of a partner.
+func (r *MemoryRepository) List(_ context.Context, partner string) ([]*Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []*Key
+	for _, key := range r.keys {
+		if key.Partner == partner {
+			key := key
+			res = append(res, &key)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+// IncrUsage increments the count of the requests of a key in a
+// period.
+func (r *MemoryRepository) IncrUsage(_ context.Context, id string, period string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usage[id+":"+period]++
+	return r.usage[id+":"+period], nil
+}
+
+// Usage returns the count of the requests of a key in a period.
+func (r *MemoryRepository) Usage(_ context.Context, id string, period string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage[id+":"+period], nil
+}
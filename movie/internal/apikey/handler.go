@@ -0,0 +1,62 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/ratelimit"
+)
+
+var logger = logging.New("apikey")
+
+// Handler authenticates the partners sending an API key to the
+// next handler and counts their requests against the monthly quota
+// of the key, rejecting invalid keys with 401 and requests over the
+// quota with 429. Requests without a key are served anonymously.
+// Repository failures are logged and serve the requests
+// anonymously, as they are no fault of the partners.
+func (m *Manager) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		raw := req.Header.Get(Header)
+		if raw == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		ctx := req.Context()
+		key, err := m.Authenticate(ctx, raw)
+		if err != nil && errors.Is(err, ErrInvalid) {
+			problem.Write(w, req, problem.Unauthenticated, err.Error())
+			return
+		} else if err != nil {
+			logger.ErrorContext(ctx, "API key authentication error", "error", err)
+			next.ServeHTTP(w, req)
+			return
+		}
+		n, err := m.repo.IncrUsage(ctx, key.ID, Period(time.Now()))
+		if err != nil {
+			logger.ErrorContext(ctx, "API key usage error", "key_id", key.ID, "partner", key.Partner, "error", err)
+		} else if key.Quota > 0 {
+			w.Header().Set("X-Quota-Limit", strconv.FormatInt(key.Quota, 10))
+			w.Header().Set("X-Quota-Remaining", strconv.FormatInt(max(key.Quota-n, 0), 10))
+			if n > key.Quota {
+				problem.Write(w, req, problem.TooManyRequests, "monthly quota exceeded")
+				return
+			}
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(ctx, key)))
+	})
+}
+
+// Client identifies the client of a request by the id of the API
+// key of its partner, as authenticated by Handler, or by its IP
+// address, such as to rate limit partners by key.
+func Client(req *http.Request) string {
+	if key := FromContext(req.Context()); key != nil {
+		return "key:" + key.ID
+	}
+	return ratelimit.ClientIP(req)
+}
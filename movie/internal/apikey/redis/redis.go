@@ -0,0 +1,103 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"movieapp.com/movie/internal/apikey"
+)
+
+// usageTTL bounds how long the usage counts of past periods are
+// kept for billing.
+const usageTTL = 400 * 24 * time.Hour
+
+// Repository defines a Redis API key repository shared by all
+// instances of a service.
+type Repository struct {
+	client *redis.Client
+}
+
+// New creates a Redis API key repository at the given address.
+func New(addr string) *Repository {
+	return &Repository{redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+// Put stores a key.
+func (r *Repository) Put(ctx context.Context, key *apikey.Key) error {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, "apikey:"+key.ID, b, 0)
+	pipe.SAdd(ctx, "apikey:partner:"+key.Partner, key.ID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get returns a key or apikey.ErrNotFound.
+func (r *Repository) Get(ctx context.Context, id string) (*apikey.Key, error) {
+	b, err := r.client.Get(ctx, "apikey:"+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, apikey.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var key apikey.Key
+	if err := json.Unmarshal(b, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List returns the keys of a partner.
+func (r *Repository) List(ctx context.Context, partner string) ([]*apikey.Key, error) {
+	ids, err := r.client.SMembers(ctx, "apikey:partner:"+partner).Result()
+	if err != nil {
+		return nil, err
+	}
+	var res []*apikey.Key
+	for _, id := range ids {
+		key, err := r.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, key)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+// IncrUsage increments the count of the requests of a key in a
+// period.
+func (r *Repository) IncrUsage(ctx context.Context, id string, period string) (int64, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, usageKey(id, period))
+	pipe.ExpireNX(ctx, usageKey(id, period), usageTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// Usage returns the count of the requests of a key in a period.
+func (r *Repository) Usage(ctx context.Context, id string, period string) (int64, error) {
+	n, err := r.client.Get(ctx, usageKey(id, period)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Close closes the client.
+func (r *Repository) Close() error {
+	return r.client.Close()
+}
+
+func usageKey(id string, period string) string {
+	return "apikey:usage:" + id + ":" + period
+}
@@ -0,0 +1,208 @@
+package cachepolicy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"movieapp.com/internal/apiversion"
+	"movieapp.com/movie/internal/apikey"
+)
+
+// UserIDHeader is the header of the id of the authenticated user,
+// whose responses are personalized, like those of requests with a
+// bearer token or the API key of a partner, whose usage is counted.
+const UserIDHeader = "X-User-ID"
+
+// Policy defines how long the responses of a route may be cached,
+// in seconds. MaxAge applies to browsers and SurrogateMaxAge to a
+// CDN in front of the service, which strips Surrogate-Control
+// before passing responses on.
+type Policy struct {
+	MaxAge               int  `json:"maxAge"`
+	SurrogateMaxAge      int  `json:"surrogateMaxAge"`
+	StaleWhileRevalidate int  `json:"staleWhileRevalidate"`
+	Private              bool `json:"private"`
+	NoStore              bool `json:"noStore"`
+}
+
+func (p Policy) validate() error {
+	if p.MaxAge < 0 || p.SurrogateMaxAge < 0 || p.StaleWhileRevalidate < 0 {
+		return errors.New("negative age")
+	}
+	return nil
+}
+
+func (p Policy) cacheControl() string {
+	scope := "public"
+	if p.Private {
+		scope = "private"
+	}
+	if p.NoStore {
+		return strings.TrimPrefix(scope+", no-store", "public, ")
+	}
+	v := scope + ", max-age=" + strconv.Itoa(p.MaxAge)
+	if p.StaleWhileRevalidate > 0 {
+		v += ", stale-while-revalidate=" + strconv.Itoa(p.StaleWhileRevalidate)
+	}
+	return v
+}
+
+func (p Policy) surrogateControl() string {
+	if p.NoStore || p.Private || p.SurrogateMaxAge == 0 {
+		return ""
+	}
+	return "max-age=" + strconv.Itoa(p.SurrogateMaxAge)
+}
+
+// Config defines the cache policies of the routes of a service.
+// Routes are unversioned paths matched like http.ServeMux
+// patterns: a path ending in a slash matches all paths below it,
+// and the longest match wins. Other routes get the default policy,
+// and requests of authenticated users the personalized one, as
+// their responses depend on the user.
+type Config struct {
+	Default      Policy            `json:"default"`
+	Personalized Policy            `json:"personalized"`
+	Routes       map[string]Policy `json:"routes"`
+}
+
+// DefaultConfig returns the cache policies of the movie service:
+// details and lists change with metadata edits and ratings, so
+// they are cached for a minute by browsers and longer by a CDN,
+// trending movies move faster, and exports and the recommendations
+// of users are never stored.
+func DefaultConfig() *Config {
+	return &Config{
+		Default:      Policy{NoStore: true},
+		Personalized: Policy{Private: true, NoStore: true},
+		Routes: map[string]Policy{
+			"/movie":           {MaxAge: 60, SurrogateMaxAge: 300, StaleWhileRevalidate: 60},
+			"/movies":          {MaxAge: 60, SurrogateMaxAge: 300, StaleWhileRevalidate: 60},
+			"/movies/list":     {MaxAge: 60, SurrogateMaxAge: 300, StaleWhileRevalidate: 60},
+			"/movies/":         {MaxAge: 300, SurrogateMaxAge: 900, StaleWhileRevalidate: 300},
+			"/movies/trending": {MaxAge: 30, SurrogateMaxAge: 60, StaleWhileRevalidate: 30},
+			"/movies/export":   {NoStore: true},
+			"/users/":          {Private: true, NoStore: true},
+		},
+	}
+}
+
+// LoadConfig reads a JSON cache policy config file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	for route, p := range c.Routes {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+	}
+	if err := c.Default.validate(); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	if err := c.Personalized.validate(); err != nil {
+		return nil, fmt.Errorf("personalized: %w", err)
+	}
+	return &c, nil
+}
+
+// policy returns the policy of a request.
+func (c *Config) policy(req *http.Request) Policy {
+	if req.Header.Get(UserIDHeader) != "" || req.Header.Get("Authorization") != "" || req.Header.Get(apikey.Header) != "" {
+		return c.Personalized
+	}
+	path := apiversion.Unversioned(req.URL.Path)
+	route, policy := "", c.Default
+	for pattern, p := range c.Routes {
+		matches := pattern == path || strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern)
+		if matches && len(pattern) > len(route) {
+			route, policy = pattern, p
+		}
+	}
+	return policy
+}
+
+// Handler sets the Cache-Control and Surrogate-Control headers of
+// the responses of GET and HEAD requests to the next handler by
+// their route, unless the handler set Cache-Control itself. Error
+// responses are never stored, and responses to other methods are
+// left as they are.
+func Handler(next http.Handler, config *Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			next.ServeHTTP(w, req)
+			return
+		}
+		next.ServeHTTP(&writer{ResponseWriter: w, policy: config.policy(req)}, req)
+	})
+}
+
+// writer sets the cache headers of a response before its header
+// is written.
+type writer struct {
+	http.ResponseWriter
+	policy      Policy
+	wroteHeader bool
+}
+
+func (w *writer) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		h := w.Header()
+		if h.Get("Cache-Control") == "" {
+			p := w.policy
+			if code >= http.StatusBadRequest {
+				p = Policy{NoStore: true}
+			}
+			h.Set("Cache-Control", p.cacheControl())
+			if v := p.surrogateControl(); v != "" {
+				h.Set("Surrogate-Control", v)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered response to the client.
+func (w *writer) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the next handler take over the connection.
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
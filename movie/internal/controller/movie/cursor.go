@@ -0,0 +1,43 @@
+package movie
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"movieapp.com/pkg/problem"
+)
+
+// ErrInvalidCursor is returned when a cursor was not issued by
+// List.
+var ErrInvalidCursor = problem.Register(errors.New("invalid cursor"), problem.BadRequest)
+
+// cursor is the position of a page of List. The page token of
+// the metadata service is wrapped so that clients do not depend
+// on its format, which changes with the metadata repository.
+type cursor struct {
+	MetadataToken string `json:"m"`
+}
+
+func encodeCursor(metadataToken string) string {
+	if metadataToken == "" {
+		return ""
+	}
+	b, _ := json.Marshal(cursor{metadataToken})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.MetadataToken == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,144 @@
+package movie
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/internal/budget"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/internal/httputil"
+	"movieapp.com/movie/internal/experiment"
+	"movieapp.com/movie/pkg/model"
+	"movieapp.com/pkg/flags"
+	"movieapp.com/pkg/resilience"
+)
+
+// Request defines the caller of an enrichment.
+type Request struct {
+	// UserID is the authenticated user, empty if anonymous.
+	UserID string
+}
+
+// Enrichment sets the data fetched by an enricher on the details
+// of the movie at index i of the enriched ids.
+type Enrichment func(i int, details *model.MovieDetails)
+
+// Enricher fetches the data of a downstream for movie details.
+// Enrichers of a request run concurrently and their enrichments
+// are applied once all have returned, so an enricher does not see
+// the data of the others.
+type Enricher interface {
+	// Enrich fetches the data of several movies. A nil enrichment
+	// leaves the details as they are.
+	Enrich(ctx context.Context, req Request, ids []string) (Enrichment, error)
+}
+
+// Stage defines an enricher in the pipeline of the controller.
+type Stage struct {
+	// Name is listed in the degraded downstreams of details the
+	// enricher failed for.
+	Name     string
+	Enricher Enricher
+	// Timeout is the longest the enricher is waited for, and Share
+	// the share of the remaining budget of the request it may
+	// spend.
+	Timeout time.Duration
+	Share   float64
+	// Fallback handles a failure of the enricher.
+	Fallback Degradation
+	// PerUser stages enrich details for the requesting user only,
+	// they are skipped by anonymous requests and never cached.
+	PerUser bool
+	// Experiment and Variant restrict the stage to the users
+	// assigned to the variant of the experiment, for testing an
+	// enrichment before serving it to all users. The stage is run
+	// per user, as its data differs between users.
+	Experiment string
+	Variant    string
+	// Flag restricts the stage to the users the boolean feature
+	// flag is on for, for rolling out an enrichment gradually.
+	// Like experiment stages, flagged stages are run per user.
+	Flag string
+}
+
+// enrichment defines the result of the stages of a request.
+type enrichment struct {
+	fills   []Enrichment
+	omitted []model.Omission
+}
+
+// apply sets the enriched data on the details of the movie at
+// index i.
+func (e *enrichment) apply(i int, details *model.MovieDetails) {
+	for _, fill := range e.fills {
+		fill(i, details)
+	}
+	if len(e.omitted) > 0 {
+		// The lists may be shared with concurrent callers.
+		details.Degraded = slices.Clip(details.Degraded)
+		for _, o := range e.omitted {
+			details.Degraded = append(details.Degraded, o.Component)
+		}
+		details.Omitted = append(slices.Clip(details.Omitted), e.omitted...)
+		details.Partial = true
+	}
+}
+
+// enrich runs stages concurrently for the movies, each within its
+// own timeout and share of the budget, skipping flagged stages
+// that are off by the feature flags.
+func enrich(ctx context.Context, features *flags.Set, stages []Stage, req Request, ids []string) (*enrichment, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	fills := make([]Enrichment, len(stages))
+	failed := make([]error, len(stages))
+	for i, s := range stages {
+		i, s := i, s
+		if s.Experiment != "" && experiment.VariantOf(ctx, s.Experiment, req.UserID) != s.Variant {
+			continue
+		}
+		if s.Flag != "" && !features.Bool(s.Flag, req.UserID, false) {
+			continue
+		}
+		g.Go(func() error {
+			ctx, cancel := budget.Split(ctx, s.Share, s.Timeout)
+			defer cancel()
+			fill, err := s.Enricher.Enrich(ctx, req, ids)
+			if err != nil && s.Fallback == DegradationOmit {
+				logger.WarnContext(ctx, "Enrichment degraded", "stage", s.Name, "movies", len(ids), "error", err)
+				failed[i] = err
+				return nil
+			}
+			fills[i] = fill
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	res := &enrichment{}
+	for i, s := range stages {
+		if failed[i] != nil {
+			res.omitted = append(res.omitted, omission(s.Name, failed[i]))
+		} else if fills[i] != nil {
+			res.fills = append(res.fills, fills[i])
+		}
+	}
+	return res, nil
+}
+
+// omission returns the omission of the data of a stage that
+// failed with err.
+func omission(name string, err error) model.Omission {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded:
+		return model.Omission{Component: name, Reason: model.OmissionTimeout, Retryable: true}
+	case errors.Is(err, resilience.ErrBreakerOpen) || errors.Is(err, resilience.ErrBulkheadFull) || grpcutil.Retryable(err) || httputil.Retryable(err):
+		return model.Omission{Component: name, Reason: model.OmissionUnavailable, Retryable: true}
+	}
+	return model.Omission{Component: name, Reason: model.OmissionError}
+}
@@ -0,0 +1,167 @@
+package movie
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sync/errgroup"
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/movie/pkg/model"
+	ratingmodel "movieapp.com/rating/pkg/model"
+)
+
+// similarConcurrency bounds the similar movie calls of a batch,
+// which are made per movie.
+const similarConcurrency = 8
+
+// metadataEnricher sets the movie metadata. Details of movies
+// without metadata are left out of the responses.
+type metadataEnricher struct {
+	gateway metadataGateway
+}
+
+func (e metadataEnricher) Enrich(ctx context.Context, _ Request, ids []string) (Enrichment, error) {
+	// A single movie is read by Get, which revalidates its cached
+	// metadata.
+	if len(ids) == 1 {
+		m, err := e.gateway.Get(ctx, ids[0])
+		if err != nil && errors.Is(err, gateway.ErrNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return func(_ int, d *model.MovieDetails) { d.Metadata = *m }, nil
+	}
+	metadata, err := e.gateway.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*metadatamodel.Metadata, len(metadata))
+	for _, m := range metadata {
+		byID[m.ID] = m
+	}
+	return func(i int, d *model.MovieDetails) {
+		if m, ok := byID[ids[i]]; ok {
+			d.Metadata = *m
+		}
+	}, nil
+}
+
+// ratingEnricher sets the aggregated ratings of the movies.
+type ratingEnricher struct {
+	gateway ratingGateway
+}
+
+func (e ratingEnricher) Enrich(ctx context.Context, _ Request, ids []string) (Enrichment, error) {
+	ratings, err := e.gateway.GetAggregatedRatings(ctx, recordIDs(ids), ratingmodel.RecordTypeMovie)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int, d *model.MovieDetails) {
+		if v, ok := ratings[ratingmodel.RecordID(ids[i])]; ok {
+			d.Rating = &v
+		}
+	}, nil
+}
+
+// userRatingEnricher sets the ratings the requesting user gave to
+// the movies.
+type userRatingEnricher struct {
+	gateway ratingGateway
+}
+
+func (e userRatingEnricher) Enrich(ctx context.Context, req Request, ids []string) (Enrichment, error) {
+	ratings, err := e.gateway.GetUserRatings(ctx, ratingmodel.UserID(req.UserID), recordIDs(ids), ratingmodel.RecordTypeMovie)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int, d *model.MovieDetails) {
+		if v, ok := ratings[ratingmodel.RecordID(ids[i])]; ok {
+			v := int(v)
+			d.UserRating = &v
+		}
+	}, nil
+}
+
+// watchlistEnricher marks the movies in the watchlist of the
+// requesting user.
+type watchlistEnricher struct {
+	gateway watchlistGateway
+}
+
+// WatchlistStage returns a stage marking the movies in the
+// watchlist of the requesting user on movie details, which are
+// served unmarked if the watchlist service fails.
+func WatchlistStage(watchlistGateway watchlistGateway) Stage {
+	return Stage{
+		Name:     "watchlist",
+		Enricher: watchlistEnricher{watchlistGateway},
+		Timeout:  ratingTimeout,
+		Share:    ratingShare,
+		Fallback: DegradationOmit,
+		PerUser:  true,
+	}
+}
+
+func (e watchlistEnricher) Enrich(ctx context.Context, req Request, ids []string) (Enrichment, error) {
+	watchlisted, err := e.gateway.GetWatchlisted(ctx, req.UserID, ids)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int, d *model.MovieDetails) {
+		_, d.InWatchlist = watchlisted[ids[i]]
+	}, nil
+}
+
+// similarEnricher sets the titles most similar to the movies.
+type similarEnricher struct {
+	gateway metadataGateway
+	limit   int
+}
+
+// SimilarStage returns a stage setting up to limit similar titles
+// on movie details, which are served without them if the metadata
+// service fails.
+func SimilarStage(metadataGateway metadataGateway, limit int) Stage {
+	return Stage{
+		Name:     "similar",
+		Enricher: similarEnricher{metadataGateway, limit},
+		Timeout:  ratingTimeout,
+		Share:    ratingShare,
+		Fallback: DegradationOmit,
+	}
+}
+
+func (e similarEnricher) Enrich(ctx context.Context, _ Request, ids []string) (Enrichment, error) {
+	similar := make([][]model.SimilarTitle, len(ids))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(similarConcurrency)
+	for i, id := range ids {
+		i, id := i, id
+		g.Go(func() error {
+			res, err := e.gateway.GetSimilar(ctx, id, e.limit)
+			if err != nil && errors.Is(err, gateway.ErrNotFound) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			for _, s := range res {
+				similar[i] = append(similar[i], model.SimilarTitle{ID: s.Metadata.ID, Title: s.Metadata.Title, Score: s.Score})
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return func(i int, d *model.MovieDetails) { d.Similar = similar[i] }, nil
+}
+
+func recordIDs(ids []string) []ratingmodel.RecordID {
+	res := make([]ratingmodel.RecordID, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, ratingmodel.RecordID(id))
+	}
+	return res
+}
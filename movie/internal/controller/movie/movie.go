@@ -3,55 +3,519 @@ package movie
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"golang.org/x/sync/errgroup"
+	"movieapp.com/internal/budget"
 	metadatamodel "movieapp.com/metadata/pkg/model"
 	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/movie/internal/recommendation"
 	"movieapp.com/movie/pkg/model"
+	"movieapp.com/pkg/cache"
+	"movieapp.com/pkg/flags"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
 	ratingmodel "movieapp.com/rating/pkg/model"
+	searchmodel "movieapp.com/search/pkg/model"
 )
 
+var logger = logging.New("controller/movie")
+
 // ErrNotFound is returned when the movie metadata is not
 // found.
-var ErrNotFound = errors.New("movie metadata not found")
+var ErrNotFound = problem.Register(errors.New("movie metadata not found"), problem.NotFound)
+
+// ErrTooManyIDs is returned when details are requested for more
+// than MaxBatchSize movies.
+var ErrTooManyIDs = problem.Register(errors.New("too many ids"), problem.BadRequest)
+
+// MaxBatchSize defines the maximum number of movies whose details
+// can be requested at once.
+const MaxBatchSize = 100
+
+// DefaultPageSize defines the number of movies listed per page
+// unless requested otherwise.
+const DefaultPageSize = 20
+
+// DefaultRecommendationLimit defines the number of recommended
+// movies returned unless requested otherwise.
+const DefaultRecommendationLimit = 20
 
 type ratingGateway interface {
 	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
+	GetAggregatedRatings(ctx context.Context, recordIDs []ratingmodel.RecordID, recordType ratingmodel.RecordType) (map[ratingmodel.RecordID]float64, error)
+	GetUserRatings(ctx context.Context, userID ratingmodel.UserID, recordIDs []ratingmodel.RecordID, recordType ratingmodel.RecordType) (map[ratingmodel.RecordID]ratingmodel.RatingValue, error)
+	GetTrending(ctx context.Context, recordType ratingmodel.RecordType, window time.Duration, limit int) ([]ratingmodel.TrendingRecord, error)
 	// PutRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType, rating *ratingmodel.Rating) error
 }
 type metadataGateway interface {
 	Get(ctx context.Context, id string) (*metadatamodel.Metadata, error)
+	GetMany(ctx context.Context, ids []string) ([]*metadatamodel.Metadata, error)
+	GetSimilar(ctx context.Context, id string, limit int) ([]metadatamodel.SimilarMovie, error)
+	List(ctx context.Context, pageSize int, pageToken string) ([]*metadatamodel.Metadata, string, error)
+}
+type searchGateway interface {
+	Search(ctx context.Context, q searchmodel.Query, pageSize int, cursor string) ([]searchmodel.Hit, string, int, error)
+}
+type watchlistGateway interface {
+	GetWatchlisted(ctx context.Context, userID string, movieIDs []string) (map[string]time.Time, error)
+}
+
+// Timeouts of the downstream calls of Get, the rating is not
+// worth waiting for as long as the metadata. Within a request
+// budget, each call also gets at most its share of the remaining
+// budget.
+const (
+	metadataTimeout = 2 * time.Second
+	metadataShare   = 0.8
+	ratingTimeout   = time.Second
+	ratingShare     = 0.5
+)
+
+// Degradation defines how a failure of a downstream is handled.
+type Degradation string
+
+// Existing degradations.
+const (
+	// DegradationFail fails the whole request.
+	DegradationFail = Degradation("fail")
+	// DegradationOmit leaves the data of the downstream out and
+	// marks the response as degraded.
+	DegradationOmit = Degradation("omit")
+)
+
+// ParseDegradation parses a degradation by its name.
+func ParseDegradation(s string) (Degradation, error) {
+	switch d := Degradation(s); d {
+	case DegradationFail, DegradationOmit:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown degradation %q", s)
+}
+
+// DegradationPolicy defines the degradation of each optional
+// downstream. Movie details are never served without metadata.
+type DegradationPolicy struct {
+	Rating Degradation
+}
+
+// DefaultDegradationPolicy returns metadata without a rating if
+// the rating service fails.
+func DefaultDegradationPolicy() DegradationPolicy {
+	return DegradationPolicy{Rating: DegradationOmit}
 }
 
 // Controller defines a movie service controller.
 type Controller struct {
 	ratingGateway   ratingGateway
 	metadataGateway metadataGateway
+	searchGateway   searchGateway
+	stages          []Stage
+	cache           *cache.Cache[*model.MovieDetails]
+	recommender     recommendation.Strategy
+	features        *flags.Set
+}
+
+// NewDetailsCache creates a cache of movie details for the ttl in
+// the store. Degraded details are not cached.
+func NewDetailsCache(store cache.Store, ttl time.Duration) *cache.Cache[*model.MovieDetails] {
+	return cache.New(store, cache.JSON[*model.MovieDetails](), cache.Config[*model.MovieDetails]{
+		Prefix:    "movie:details:",
+		TTL:       ttl,
+		Jitter:    0.1,
+		Cacheable: func(d *model.MovieDetails) bool { return len(d.Degraded) == 0 },
+	})
 }
 
-// New creates a new movie service controller.
-func New(ratingGateway ratingGateway, metadataGateway metadataGateway) *Controller {
-	return &Controller{ratingGateway, metadataGateway}
+// Stage names of the enrichers of every controller.
+const (
+	metadataStage   = "metadata"
+	ratingStage     = "rating"
+	userRatingStage = "userRating"
+)
+
+// New creates a new movie service controller degrading on
+// downstream failures by the given policy, caching the movie
+// details in the given cache, recommending movies by the given
+// strategy and searching movies with the search gateway. The
+// details are enriched with the metadata, the
+// aggregated rating and the user rating; other enrichers are
+// added by Register, and flagged ones switched by the given
+// feature flags.
+func New(ratingGateway ratingGateway, metadataGateway metadataGateway, searchGateway searchGateway, degradation DegradationPolicy, cache *cache.Cache[*model.MovieDetails], recommender recommendation.Strategy, features *flags.Set) *Controller {
+	return &Controller{
+		ratingGateway:   ratingGateway,
+		metadataGateway: metadataGateway,
+		searchGateway:   searchGateway,
+		stages: []Stage{
+			{Name: metadataStage, Enricher: metadataEnricher{metadataGateway}, Timeout: metadataTimeout, Share: metadataShare, Fallback: DegradationFail},
+			{Name: ratingStage, Enricher: ratingEnricher{ratingGateway}, Timeout: ratingTimeout, Share: ratingShare, Fallback: degradation.Rating},
+			{Name: userRatingStage, Enricher: userRatingEnricher{ratingGateway}, Timeout: ratingTimeout, Share: ratingShare, Fallback: degradation.Rating, PerUser: true},
+		},
+		cache:       cache,
+		recommender: recommender,
+		features:    features,
+	}
+}
+
+// Register adds an enricher to the details of all movies. It
+// must be called before the controller serves requests.
+func (c *Controller) Register(stage Stage) {
+	if stage.Experiment != "" || stage.Flag != "" {
+		stage.PerUser = true
+	}
+	c.stages = append(c.stages, stage)
 }
 
 // Get returns the movie details including the aggregated
-// rating and movie metadata.
-// Get returns the movie details including the aggregated rating and movie metadata.
+// rating and movie metadata. The enrichers are run concurrently,
+// each with its own timeout. A failure of the metadata fails the
+// request, while a failure of another enricher, including an open
+// circuit, is handled by its fallback.
+// Cached details are returned without calling either; degraded
+// details are not cached. Concurrent requests of the same uncached
+// movie share a single fetch.
 func (c *Controller) Get(ctx context.Context, id string) (*model.MovieDetails, error) {
-	metadata, err := c.metadataGateway.Get(ctx, id)
+	return c.cache.GetOrLoad(ctx, id, func(ctx context.Context) (*model.MovieDetails, error) {
+		res, err := c.fetch(ctx, []string{id})
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 0 {
+			return nil, ErrNotFound
+		}
+		return res[0], nil
+	})
+}
+
+// GetForUser returns the movie details like Get, along with the
+// data of the per-user enrichers such as the rating the given user
+// gave to the movie. The per-user data is fetched in parallel with
+// the details and, unlike them, never cached.
+func (c *Controller) GetForUser(ctx context.Context, id string, userID string) (*model.MovieDetails, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	var details *model.MovieDetails
+	g.Go(func() error {
+		var err error
+		details, err = c.Get(ctx, id)
+		return err
+	})
+	var user *enrichment
+	g.Go(func() error {
+		var err error
+		user, err = enrich(ctx, c.features, c.stagesFor(true, ""), Request{UserID: userID}, []string{id})
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	user.apply(0, details)
+	return details, nil
+}
+
+// GetMany returns the details of several movies in the order of
+// the given ids. Duplicate ids are returned once and missing
+// movies are left out. Uncached movies are enriched together,
+// with a single metadata and a single rating call, degrading like
+// Get.
+func (c *Controller) GetMany(ctx context.Context, ids []string) ([]*model.MovieDetails, error) {
+	var unique []string
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > MaxBatchSize {
+		return nil, ErrTooManyIDs
+	}
+	found, err := c.cache.GetOrLoadMany(ctx, unique, func(ctx context.Context, ids []string) (map[string]*model.MovieDetails, error) {
+		fetched, err := c.fetch(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		res := make(map[string]*model.MovieDetails, len(fetched))
+		for _, details := range fetched {
+			res[details.Metadata.ID] = details
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*model.MovieDetails, 0, len(found))
+	for _, id := range unique {
+		if details, ok := found[id]; ok {
+			res = append(res, details)
+		}
+	}
+	return res, nil
+}
+
+// List returns a page of the details of the movies of the
+// catalog, in the order of the metadata list, and the cursor of
+// the next page, empty if it is the last one. The page size
+// defaults to DefaultPageSize and is capped at MaxBatchSize. The
+// ratings of a page are fetched in a single call, and the details
+// are not cached, like in Export.
+func (c *Controller) List(ctx context.Context, pageSize int, cursor string) ([]*model.MovieDetails, string, error) {
+	pos, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	res, next, err := c.page(ctx, min(pageSize, MaxBatchSize), pos.MetadataToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return res, encodeCursor(next), nil
+}
+
+// Export passes the details of all movies of the catalog to fn
+// in batches, in the order of the metadata list, until fn returns
+// an error. Each page of metadata is rated in a single call. The
+// details are not cached, as an export would evict the movies
+// being read.
+func (c *Controller) Export(ctx context.Context, fn func([]*model.MovieDetails) error) error {
+	for token := ""; ; {
+		res, next, err := c.page(ctx, MaxBatchSize, token)
+		if err != nil {
+			return err
+		}
+		if len(res) > 0 {
+			if err := fn(res); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// page returns the enriched details of a page of the metadata
+// list and the metadata page token of the next one. The listed
+// metadata is not fetched again.
+func (c *Controller) page(ctx context.Context, pageSize int, token string) ([]*model.MovieDetails, string, error) {
+	listCtx, cancel := budget.Split(ctx, metadataShare, metadataTimeout)
+	page, next, err := c.metadataGateway.List(listCtx, pageSize, token)
+	cancel()
+	if err != nil || len(page) == 0 {
+		return nil, next, err
+	}
+	ids := make([]string, 0, len(page))
+	for _, m := range page {
+		ids = append(ids, m.ID)
+	}
+	e, err := enrich(ctx, c.features, c.stagesFor(false, metadataStage), Request{}, ids)
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]*model.MovieDetails, 0, len(page))
+	for i, m := range page {
+		details := &model.MovieDetails{Metadata: *m}
+		e.apply(i, details)
+		res = append(res, details)
+	}
+	return res, next, nil
+}
+
+// Similar returns up to limit movies most similar to a movie.
+func (c *Controller) Similar(ctx context.Context, id string, limit int) ([]metadatamodel.SimilarMovie, error) {
+	res, err := c.metadataGateway.GetSimilar(ctx, id, limit)
 	if err != nil && errors.Is(err, gateway.ErrNotFound) {
 		return nil, ErrNotFound
-	} else if err != nil {
+	}
+	return res, err
+}
+
+// UserRatings returns the ratings a user gave to several movies
+// by movie id. Movies the user has not rated are left out.
+func (c *Controller) UserRatings(ctx context.Context, userID string, ids []string) (map[string]int, error) {
+	if len(ids) > MaxBatchSize {
+		return nil, ErrTooManyIDs
+	}
+	recordIDs := make([]ratingmodel.RecordID, 0, len(ids))
+	for _, id := range ids {
+		recordIDs = append(recordIDs, ratingmodel.RecordID(id))
+	}
+	ratings, err := c.ratingGateway.GetUserRatings(ctx, ratingmodel.UserID(userID), recordIDs, ratingmodel.RecordTypeMovie)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]int, len(ratings))
+	for id, v := range ratings {
+		res[string(id)] = int(v)
+	}
+	return res, nil
+}
+
+// Trending returns up to limit movies trending by the volume of
+// their ratings within the window, by descending score. The rating
+// service defaults and caps the window and limit. Movies without
+// metadata are left out.
+func (c *Controller) Trending(ctx context.Context, window time.Duration, limit int) ([]*model.TrendingMovie, error) {
+	trending, err := c.ratingGateway.GetTrending(ctx, ratingmodel.RecordTypeMovie, window, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(trending) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(trending))
+	for _, t := range trending {
+		ids = append(ids, string(t.RecordID))
+	}
+	details, err := c.GetMany(ctx, ids)
+	if err != nil {
 		return nil, err
 	}
-	details := &model.MovieDetails{Metadata: *metadata}
-	rating, err := c.ratingGateway.GetAggregatedRating(ctx, ratingmodel.RecordID(id),
-		ratingmodel.RecordTypeMovie)
-	if err != nil && !errors.Is(err, gateway.ErrNotFound) {
-		// Just proceed in this case, it's ok not to have ratings yet.
+	byID := make(map[string]*model.MovieDetails, len(details))
+	for _, d := range details {
+		byID[d.Metadata.ID] = d
+	}
+	res := make([]*model.TrendingMovie, 0, len(trending))
+	for _, t := range trending {
+		if d, ok := byID[string(t.RecordID)]; ok {
+			res = append(res, &model.TrendingMovie{MovieDetails: *d, Count: t.Count, Score: t.Score})
+		}
+	}
+	return res, nil
+}
+
+// Search returns the details of a page of the movies matching a
+// query by descending score, the cursor of the next page, empty on
+// the last page, and the number of matching movies. Matching
+// movies whose metadata is gone are left out of the page.
+func (c *Controller) Search(ctx context.Context, q searchmodel.Query, pageSize int, cursor string) ([]*model.SearchedMovie, string, int, error) {
+	hits, next, total, err := c.searchGateway.Search(ctx, q, pageSize, cursor)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if len(hits) == 0 {
+		return nil, next, total, nil
+	}
+	ids := make([]string, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.MovieID)
+	}
+	details, err := c.GetMany(ctx, ids)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	byID := make(map[string]*model.MovieDetails, len(details))
+	for _, d := range details {
+		byID[d.Metadata.ID] = d
+	}
+	res := make([]*model.SearchedMovie, 0, len(hits))
+	for _, h := range hits {
+		if d, ok := byID[h.MovieID]; ok {
+			res = append(res, &model.SearchedMovie{MovieDetails: *d, Score: h.Score})
+		}
+	}
+	return res, next, total, nil
+}
+
+// MovieRecommendations returns up to limit movies recommended
+// after the given one, by descending score. The limit defaults
+// when not positive and is capped at MaxBatchSize.
+func (c *Controller) MovieRecommendations(ctx context.Context, id string, limit int) ([]*model.RecommendedMovie, error) {
+	limit = recommendationLimit(limit)
+	recs, err := c.recommender.ForMovie(ctx, id, limit)
+	if err != nil && errors.Is(err, gateway.ErrNotFound) {
+		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, err
-	} else {
-		details.Rating = &rating
 	}
-	return details, nil
+	return c.recommended(ctx, recs)
+}
+
+// UserRecommendations returns up to limit movies recommended to a
+// user, by descending score. The limit defaults when not positive
+// and is capped at MaxBatchSize.
+func (c *Controller) UserRecommendations(ctx context.Context, userID string, limit int) ([]*model.RecommendedMovie, error) {
+	limit = recommendationLimit(limit)
+	recs, err := c.recommender.ForUser(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.recommended(ctx, recs)
+}
+
+func recommendationLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultRecommendationLimit
+	}
+	return min(limit, MaxBatchSize)
+}
+
+// recommended returns the details of recommended movies, leaving
+// out movies without metadata.
+func (c *Controller) recommended(ctx context.Context, recs []recommendation.Recommendation) ([]*model.RecommendedMovie, error) {
+	if len(recs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(recs))
+	for _, r := range recs {
+		ids = append(ids, r.MovieID)
+	}
+	details, err := c.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.MovieDetails, len(details))
+	for _, d := range details {
+		byID[d.Metadata.ID] = d
+	}
+	res := make([]*model.RecommendedMovie, 0, len(recs))
+	for _, r := range recs {
+		if d, ok := byID[r.MovieID]; ok {
+			res = append(res, &model.RecommendedMovie{MovieDetails: *d, Score: r.Score})
+		}
+	}
+	return res, nil
+}
+
+// Invalidate drops the cached details of a movie after its
+// metadata or ratings changed.
+func (c *Controller) Invalidate(ctx context.Context, id string) {
+	if err := c.cache.Delete(ctx, id); err != nil {
+		logger.ErrorContext(ctx, "Details cache delete error", "error", err)
+	}
+}
+
+// fetch returns the details of the movies by the shared
+// enrichers, in the order of the ids. Movies without metadata are
+// left out.
+func (c *Controller) fetch(ctx context.Context, ids []string) ([]*model.MovieDetails, error) {
+	e, err := enrich(ctx, c.features, c.stagesFor(false, ""), Request{}, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*model.MovieDetails, 0, len(ids))
+	for i := range ids {
+		details := &model.MovieDetails{}
+		e.apply(i, details)
+		if details.Metadata.ID != "" {
+			res = append(res, details)
+		}
+	}
+	return res, nil
+}
+
+// stagesFor returns the per-user or the shared stages, leaving
+// out the skipped one.
+func (c *Controller) stagesFor(perUser bool, skip string) []Stage {
+	var res []Stage
+	for _, s := range c.stages {
+		if s.PerUser == perUser && s.Name != skip {
+			res = append(res, s)
+		}
+	}
+	return res
 }
@@ -0,0 +1,126 @@
+package movie
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/cache/memory"
+	ratingmodel "movieapp.com/rating/pkg/model"
+)
+
+// fakeMetadata serves the metadata of Get by calling get, other
+// methods are not expected to be called.
+type fakeMetadata struct {
+	metadataGateway
+	get func(ctx context.Context, id string) (*metadatamodel.Metadata, error)
+}
+
+func (f *fakeMetadata) Get(ctx context.Context, id string) (*metadatamodel.Metadata, error) {
+	return f.get(ctx, id)
+}
+
+// fakeRating serves the aggregated ratings of Get by calling get,
+// other methods are not expected to be called.
+type fakeRating struct {
+	ratingGateway
+	get func(ctx context.Context, recordIDs []ratingmodel.RecordID) (map[ratingmodel.RecordID]float64, error)
+}
+
+func (f *fakeRating) GetAggregatedRatings(ctx context.Context, recordIDs []ratingmodel.RecordID, _ ratingmodel.RecordType) (map[ratingmodel.RecordID]float64, error) {
+	return f.get(ctx, recordIDs)
+}
+
+func newTestController(metadata *fakeMetadata, rating *fakeRating) *Controller {
+	return New(rating, metadata, nil, DefaultDegradationPolicy(), NewDetailsCache(memory.NewStore("", 0, 0), time.Minute), nil, nil)
+}
+
+// TestGetConcurrent checks that the metadata and the rating are
+// fetched concurrently: each call waits for the other to start,
+// which would time out if they were made one after the other.
+func TestGetConcurrent(t *testing.T) {
+	metadataStarted, ratingStarted := make(chan struct{}), make(chan struct{})
+	c := newTestController(&fakeMetadata{get: func(ctx context.Context, id string) (*metadatamodel.Metadata, error) {
+		close(metadataStarted)
+		select {
+		case <-ratingStarted:
+			return &metadatamodel.Metadata{ID: id}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}}, &fakeRating{get: func(ctx context.Context, recordIDs []ratingmodel.RecordID) (map[ratingmodel.RecordID]float64, error) {
+		close(ratingStarted)
+		select {
+		case <-metadataStarted:
+			return map[ratingmodel.RecordID]float64{recordIDs[0]: 4.5}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}})
+	details, err := c.Get(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if details.Metadata.ID != "1" || details.Rating == nil || *details.Rating != 4.5 {
+		t.Errorf("Get = %+v, want metadata 1 rated 4.5", details)
+	}
+	if len(details.Degraded) > 0 {
+		t.Errorf("Get degraded %v, want none", details.Degraded)
+	}
+}
+
+// TestGetTimeouts checks that each call gets its own timeout.
+func TestGetTimeouts(t *testing.T) {
+	var metadataWait, ratingWait time.Duration
+	c := newTestController(&fakeMetadata{get: func(ctx context.Context, id string) (*metadatamodel.Metadata, error) {
+		deadline, _ := ctx.Deadline()
+		metadataWait = time.Until(deadline)
+		return &metadatamodel.Metadata{ID: id}, nil
+	}}, &fakeRating{get: func(ctx context.Context, _ []ratingmodel.RecordID) (map[ratingmodel.RecordID]float64, error) {
+		deadline, _ := ctx.Deadline()
+		ratingWait = time.Until(deadline)
+		return nil, nil
+	}})
+	if _, err := c.Get(context.Background(), "1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	for _, tt := range []struct {
+		name     string
+		got      time.Duration
+		expected time.Duration
+	}{
+		{"metadata", metadataWait, 2 * time.Second},
+		{"rating", ratingWait, time.Second},
+	} {
+		if tt.got <= tt.expected-100*time.Millisecond || tt.got > tt.expected {
+			t.Errorf("%s timeout = %v, want %v", tt.name, tt.got, tt.expected)
+		}
+	}
+}
+
+// TestGetMetadataFailureCancelsRating checks that a failing
+// metadata call fails Get without waiting for the rating, whose
+// call is canceled.
+func TestGetMetadataFailureCancelsRating(t *testing.T) {
+	errMetadata := errors.New("metadata unavailable")
+	ratingErr := make(chan error, 1)
+	c := newTestController(&fakeMetadata{get: func(context.Context, string) (*metadatamodel.Metadata, error) {
+		return nil, errMetadata
+	}}, &fakeRating{get: func(ctx context.Context, _ []ratingmodel.RecordID) (map[ratingmodel.RecordID]float64, error) {
+		<-ctx.Done()
+		ratingErr <- ctx.Err()
+		return nil, ctx.Err()
+	}})
+	start := time.Now()
+	if _, err := c.Get(context.Background(), "1"); !errors.Is(err, errMetadata) {
+		t.Fatalf("Get error = %v, want %v", err, errMetadata)
+	}
+	if elapsed := time.Since(start); elapsed >= ratingTimeout {
+		t.Errorf("Get took %v, want less than the rating timeout", elapsed)
+	}
+	if err := <-ratingErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("rating call error = %v, want %v", err, context.Canceled)
+	}
+}
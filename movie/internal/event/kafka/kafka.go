@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/tenant"
+	ratingmodel "movieapp.com/rating/pkg/model"
+)
+
+var logger = logging.New("event/kafka")
+
+// Consumer defines a Kafka metadata change event consumer.
+type Consumer struct {
+	reader *kafka.Reader
+}
+
+// NewConsumer creates a Kafka consumer reading the events of the
+// topic published from now on. Consumers with distinct group ids
+// each receive all events. Connections authenticate with the
+// credentials unless nil.
+func NewConsumer(brokers []string, topic string, groupID string, creds *kafkautil.Credentials) *Consumer {
+	return &Consumer{kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		GroupID:     groupID,
+		StartOffset: kafka.LastOffset,
+		Dialer:      creds.Dialer(),
+	})}
+}
+
+// Run passes events to the handler until the context is canceled.
+// Events the handler fails on are logged and skipped.
+func (c *Consumer) Run(ctx context.Context, handle func(context.Context, *model.Event) error) {
+	run(ctx, c.reader, func(ctx context.Context, value []byte) (string, error) {
+		var e *model.Event
+		if err := json.Unmarshal(value, &e); err != nil {
+			return "", err
+		}
+		return e.ID, handle(ctx, e)
+	})
+}
+
+// Close closes the consumer.
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+// RatingConsumer defines a Kafka rating change event consumer.
+type RatingConsumer struct {
+	reader *kafka.Reader
+}
+
+// NewRatingConsumer creates a Kafka consumer reading the rating
+// events of the topic published from now on. Connections authenticate
+// with the credentials unless nil.
+func NewRatingConsumer(brokers []string, topic string, groupID string, creds *kafkautil.Credentials) *RatingConsumer {
+	return &RatingConsumer{kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		GroupID:     groupID,
+		StartOffset: kafka.LastOffset,
+		Dialer:      creds.Dialer(),
+	})}
+}
+
+// Run passes events to the handler until the context is canceled.
+// Events the handler fails on are logged and skipped.
+func (c *RatingConsumer) Run(ctx context.Context, handle func(context.Context, *ratingmodel.RatingEvent) error) {
+	run(ctx, c.reader, func(ctx context.Context, value []byte) (string, error) {
+		var e *ratingmodel.RatingEvent
+		if err := json.Unmarshal(value, &e); err != nil {
+			return "", err
+		}
+		return string(e.RecordID), handle(ctx, e)
+	})
+}
+
+// Close closes the consumer.
+func (c *RatingConsumer) Close() error {
+	return c.reader.Close()
+}
+
+// run fetches and commits messages until the context is canceled,
+// passing their values to handle, which returns the id of the
+// decoded event for logging.
+func run(ctx context.Context, reader *kafka.Reader, handle func(context.Context, []byte) (string, error)) {
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.ErrorContext(ctx, "Event fetch error", "error", err)
+			}
+			return
+		}
+		spanCtx, span := tracing.StartKafkaConsumer(ctx, msg)
+		for _, h := range msg.Headers {
+			if h.Key == bus.TenantHeader {
+				spanCtx = tenant.NewContext(spanCtx, string(h.Value))
+			}
+		}
+		if id, err := handle(spanCtx, msg.Value); err != nil && id == "" {
+			logger.ErrorContext(spanCtx, "Event decode error", "error", err)
+			span.SetStatus(codes.Error, err.Error())
+		} else if err != nil {
+			logger.ErrorContext(spanCtx, "Event handling error", "id", id, "error", err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "Event commit error", "error", err)
+		}
+	}
+}
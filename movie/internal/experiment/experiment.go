@@ -0,0 +1,187 @@
+package experiment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+
+	"google.golang.org/grpc"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("experiment")
+
+// exposures counts the users exposed to each variant by
+// experiment, published at /debug/vars.
+var exposures = expvar.NewMap("experiment_exposures")
+
+// Variant defines an arm of an experiment and the share of users
+// allocated to it, relative to the weights of the other variants.
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// Experiment defines an experiment and the allocation of users to
+// its variants. Users are bucketed by a hash of their id salted
+// with the experiment name, so that a user stays in a variant
+// while the allocation is unchanged and the buckets of different
+// experiments are independent.
+type Experiment struct {
+	Name     string    `json:"name"`
+	Variants []Variant `json:"variants"`
+}
+
+// Config defines the running experiments of a service.
+type Config struct {
+	Experiments []Experiment `json:"experiments"`
+}
+
+// LoadConfig reads a JSON experiment config file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, e := range c.Experiments {
+		if seen[e.Name] {
+			return nil, fmt.Errorf("experiment %s: duplicate", e.Name)
+		}
+		seen[e.Name] = true
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("experiment %s: %w", e.Name, err)
+		}
+	}
+	return &c, nil
+}
+
+func (e Experiment) validate() error {
+	if e.Name == "" {
+		return errors.New("empty name")
+	}
+	total := 0
+	for _, v := range e.Variants {
+		if v.Name == "" {
+			return errors.New("empty variant name")
+		}
+		if v.Weight < 0 {
+			return fmt.Errorf("variant %s: negative weight %d", v.Name, v.Weight)
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return errors.New("no weighted variants")
+	}
+	return nil
+}
+
+// Exposure defines the assignment of a user to a variant.
+type Exposure struct {
+	Experiment string `json:"experiment"`
+	Variant    string `json:"variant"`
+	UserID     string `json:"userId"`
+}
+
+// Logger logs the exposures of users to experiments for their
+// analysis.
+type Logger interface {
+	LogExposure(ctx context.Context, exposure Exposure)
+}
+
+// StdLogger logs exposures with the service logger.
+type StdLogger struct{}
+
+// LogExposure logs an exposure as a record with its fields.
+func (StdLogger) LogExposure(ctx context.Context, exposure Exposure) {
+	logger.InfoContext(ctx, "Experiment exposure", "experiment", exposure.Experiment, "variant", exposure.Variant, "user_id", exposure.UserID)
+}
+
+// Evaluator assigns users to the variants of experiments.
+type Evaluator struct {
+	experiments map[string]Experiment
+	logger      Logger
+}
+
+// New creates a new evaluator of the configured experiments,
+// logging exposures with the given logger.
+func New(config *Config, logger Logger) *Evaluator {
+	experiments := make(map[string]Experiment, len(config.Experiments))
+	for _, e := range config.Experiments {
+		experiments[e.Name] = e
+	}
+	return &Evaluator{experiments, logger}
+}
+
+// Variant returns the variant of an experiment a user is assigned
+// to and logs the exposure. Anonymous users and experiments that
+// are not running get no variant, which callers treat as the
+// control.
+func (e *Evaluator) Variant(ctx context.Context, experiment string, userID string) string {
+	if e == nil || userID == "" {
+		return ""
+	}
+	exp, ok := e.experiments[experiment]
+	if !ok {
+		return ""
+	}
+	total := 0
+	for _, v := range exp.Variants {
+		total += v.Weight
+	}
+	h := fnv.New64a()
+	h.Write([]byte(exp.Name + "\x00" + userID))
+	bucket := int(h.Sum64() % uint64(total))
+	var variant string
+	for _, v := range exp.Variants {
+		if bucket < v.Weight {
+			variant = v.Name
+			break
+		}
+		bucket -= v.Weight
+	}
+	exposures.Add(exp.Name+"."+variant, 1)
+	e.logger.LogExposure(ctx, Exposure{exp.Name, variant, userID})
+	return variant
+}
+
+type evaluatorKey struct{}
+
+// NewContext returns a context carrying an evaluator, queried by
+// VariantOf.
+func NewContext(ctx context.Context, e *Evaluator) context.Context {
+	return context.WithValue(ctx, evaluatorKey{}, e)
+}
+
+// VariantOf returns the variant of an experiment a user is
+// assigned to by the evaluator of the context, none if the context
+// has no evaluator.
+func VariantOf(ctx context.Context, experiment string, userID string) string {
+	e, _ := ctx.Value(evaluatorKey{}).(*Evaluator)
+	return e.Variant(ctx, experiment, userID)
+}
+
+// Handler makes the evaluator available to the handlers of
+// incoming requests.
+func Handler(next http.Handler, e *Evaluator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), e)))
+	})
+}
+
+// UnaryServerInterceptor makes the evaluator available to the
+// handlers of unary gRPC calls.
+func UnaryServerInterceptor(e *Evaluator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(NewContext(ctx, e), req)
+	}
+}
@@ -0,0 +1,152 @@
+// Package export collects the data the services keep about a user,
+// for the data access requests of the GDPR, into an archive in
+// object storage downloaded through a signed link.
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/tenant"
+	ratingmodel "movieapp.com/rating/pkg/model"
+	usermodel "movieapp.com/user/pkg/model"
+	watchlistmodel "movieapp.com/watchlist/pkg/model"
+)
+
+type userGateway interface {
+	GetUser(ctx context.Context, userID string) (*usermodel.User, error)
+}
+
+type ratingGateway interface {
+	ListUserRatings(ctx context.Context, userID ratingmodel.UserID, recordType ratingmodel.RecordType) ([]ratingmodel.Rating, error)
+}
+
+type watchlistGateway interface {
+	ListWatchlist(ctx context.Context, userID string) ([]*watchlistmodel.Item, error)
+}
+
+// Store defines the object storage of the archives.
+type Store interface {
+	// Put stores an archive.
+	Put(ctx context.Context, key string, data []byte) error
+	// URL returns a link to download an archive, valid for the ttl.
+	URL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Link defines the download link of an archive.
+type Link struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// manifest describes an archive.
+type manifest struct {
+	UserID     string    `json:"userId"`
+	Tenant     string    `json:"tenant"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Files      []string  `json:"files"`
+}
+
+// Exporter exports the data of users from the user, rating and
+// watchlist services.
+type Exporter struct {
+	users      userGateway
+	ratings    ratingGateway
+	watchlists watchlistGateway
+	store      Store
+	linkTTL    time.Duration
+}
+
+// New creates an exporter storing archives in the store, linked for
+// linkTTL.
+func New(users userGateway, ratings ratingGateway, watchlists watchlistGateway, store Store, linkTTL time.Duration) *Exporter {
+	return &Exporter{users, ratings, watchlists, store, linkTTL}
+}
+
+// Export collects the account, ratings and watchlist of a user into
+// a zip archive of JSON files and returns its download link. Only
+// the user may export their data when identities are enforced.
+func (e *Exporter) Export(ctx context.Context, userID string) (*Link, error) {
+	if err := auth.RequireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	var user *usermodel.User
+	var ratings []ratingmodel.Rating
+	var watchlist []*watchlistmodel.Item
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		user, err = e.users.GetUser(gctx, userID)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		ratings, err = e.ratings.ListUserRatings(gctx, ratingmodel.UserID(userID), ratingmodel.RecordTypeMovie)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		watchlist, err = e.watchlists.ListWatchlist(gctx, userID)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	files := []file{{"user.json", user}, {"ratings.json", ratings}, {"watchlist.json", watchlist}}
+	data, err := archive(manifest{UserID: userID, Tenant: tenant.FromContext(ctx), ExportedAt: now}, files)
+	if err != nil {
+		return nil, err
+	}
+	key := tenant.Key(ctx, "exports/"+userID+"/"+now.Format("20060102T150405Z")+".zip")
+	if err := e.store.Put(ctx, key, data); err != nil {
+		return nil, err
+	}
+	url, err := e.store.URL(ctx, key, e.linkTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &Link{URL: url, ExpiresAt: now.Add(e.linkTTL)}, nil
+}
+
+// file defines a file of an archive, encoded as JSON.
+type file struct {
+	name  string
+	value any
+}
+
+// archive returns a zip archive of the files, listed in its
+// manifest.json.
+func archive(m manifest, files []file) ([]byte, error) {
+	for _, f := range files {
+		m.Files = append(m.Files, f.name)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name string, v any) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: m.ExportedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	if err := write("manifest.json", m); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := write(f.name, f.value); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Store defines an S3-compatible object storage of export archives.
+// The bucket should expire the archives with a lifecycle rule, as
+// they hold personal data.
+type Store struct {
+	client  *awss3.Client
+	presign *awss3.PresignClient
+	bucket  string
+}
+
+// New creates a new S3 store for the given bucket using the
+// default AWS configuration. A non-empty endpoint selects an
+// S3-compatible storage with path-style addressing, e.g. MinIO.
+func New(ctx context.Context, bucket string, endpoint string) (*Store, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client := awss3.NewFromConfig(cfg, func(o *awss3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &Store{client, awss3.NewPresignClient(client), bucket}, nil
+}
+
+// Put stores an archive, encrypted at rest and never cached.
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &awss3.PutObjectInput{
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(data),
+		ContentLength:        aws.Int64(int64(len(data))),
+		ContentType:          aws.String("application/zip"),
+		ContentDisposition:   aws.String("attachment"),
+		CacheControl:         aws.String("private, no-store"),
+		ServerSideEncryption: types.ServerSideEncryptionAes256,
+	})
+	return err
+}
+
+// URL returns a presigned link to download an archive for the ttl.
+func (s *Store) URL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, err := s.presign.PresignGetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, awss3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
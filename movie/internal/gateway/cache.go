@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/cache/memory"
+	"movieapp.com/pkg/tenant"
+)
+
+// MetadataCache keeps recently fetched movie metadata with its
+// entity tag. Entries younger than the TTL are served as is;
+// older ones are revalidated with conditional requests instead
+// of being transferred again. Metadata change events keep the
+// entries up to date in between.
+type MetadataCache struct {
+	ttl time.Duration
+	// mu makes the updates of Apply atomic.
+	mu      sync.Mutex
+	entries *memory.LRU[string, metadataEntry]
+}
+
+type metadataEntry struct {
+	metadata  *model.Metadata
+	etag      string
+	fetchedAt time.Time
+}
+
+// NewMetadataCache creates a metadata cache holding up to size
+// entries served without revalidation for the ttl.
+func NewMetadataCache(size int, ttl time.Duration) *MetadataCache {
+	return &MetadataCache{ttl: ttl, entries: memory.New(memory.Config[string, metadataEntry]{Name: "movie-metadata", MaxEntries: size})}
+}
+
+// Get returns the cached metadata of a movie of the tenant of the
+// context, its entity tag and whether it is still fresh.
+func (c *MetadataCache) Get(ctx context.Context, id string) (m *model.Metadata, etag string, fresh bool) {
+	e, ok := c.entries.Get(tenant.Key(ctx, id))
+	return e.metadata, e.etag, ok && time.Since(e.fetchedAt) < c.ttl
+}
+
+// Put stores metadata with its entity tag, evicting the least
+// recently used entry when the cache is full.
+func (c *MetadataCache) Put(ctx context.Context, id string, m *model.Metadata, etag string) {
+	if etag == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(tenant.Key(ctx, id), m, etag)
+}
+
+func (c *MetadataCache) put(key string, m *model.Metadata, etag string) {
+	c.entries.Add(key, metadataEntry{m, etag, time.Now()}, 0)
+}
+
+// Apply updates the cache with a metadata change event. Cached
+// metadata is refreshed with the changed one and dropped when
+// deleted; events older than the cached version are ignored.
+func (c *MetadataCache) Apply(ctx context.Context, e *model.Event) {
+	key := tenant.Key(ctx, e.MovieID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cur, ok := c.entries.Peek(key)
+	if !ok || e.Metadata == nil || e.Version <= cur.metadata.Version {
+		return
+	}
+	if e.Type == model.EventTypeDeleted || e.Metadata.Deleted() {
+		c.entries.Remove(key)
+		return
+	}
+	c.put(key, e.Metadata, e.Metadata.ETag())
+}
@@ -3,33 +3,124 @@ package grpc
 import (
 	"context"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"movieapp.com/gen"
 	"movieapp.com/internal/grpcutil"
+	"movieapp.com/internal/hedge"
 	"movieapp.com/metadata/pkg/model"
-	"movieapp.com/pkg/discovery"
+	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/pkg/resilience"
 )
 
 // Gateway defines a movie metadata gRPC gateway.
 type Gateway struct {
-	registry discovery.Registry
+	client gen.MetadataServiceClient
+	cache  *gateway.MetadataCache
+	retry  resilience.Retry
+	hedge  *hedge.Set
 }
 
-// New creates a new gRPC gateway for a movie metadata service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// New creates a new gRPC gateway for a movie metadata service
+// calling it through the connection and caching metadata in the
+// given cache. Transient failures of reads are retried, and slow
+// reads are hedged with the given hedgers unless nil; the
+// connection balances a hedge to another instance.
+func New(conn grpc.ClientConnInterface, cache *gateway.MetadataCache, hedges *hedge.Set) *Gateway {
+	return &Gateway{gen.NewMetadataServiceClient(conn), cache, resilience.DefaultRetry(grpcutil.Retryable), hedges}
 }
 
-// Get returns movie metadata by a movie id.
+// Get returns movie metadata by a movie id. Fresh cached metadata
+// is returned as is, while stale cached metadata is revalidated
+// by its entity tag and reused if it has not changed.
 func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	conn, err := grpcutil.ServiceConnection(ctx, "metadata", g.registry)
-	if err != nil {
+	cached, etag, fresh := g.cache.Get(ctx, id)
+	if fresh {
+		return cached, nil
+	}
+	var resp *gen.GetMetadataResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("GetMetadata"), func(ctx context.Context, _ int) (*gen.GetMetadataResponse, error) {
+			return g.client.GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: id, IfNoneMatch: etag})
+		})
+		return err
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, gateway.ErrNotFound
+	} else if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
-	client := gen.NewMetadataServiceClient(conn)
-	resp, err := client.GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: id})
+	if resp.NotModified && cached != nil {
+		g.cache.Put(ctx, id, cached, etag)
+		return cached, nil
+	}
+	m := model.MetadataFromProto(resp.Metadata)
+	g.cache.Put(ctx, id, m, resp.Etag)
+	return m, nil
+}
+
+// GetMany returns metadata of several movies in a single call,
+// in the order of the given ids. Missing movies are left out.
+func (g *Gateway) GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	var resp *gen.GetManyMetadataResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("GetManyMetadata"), func(ctx context.Context, _ int) (*gen.GetManyMetadataResponse, error) {
+			return g.client.GetManyMetadata(ctx, &gen.GetManyMetadataRequest{MovieIds: ids})
+		})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return model.MetadataFromProto(resp.Metadata), nil
+	res := make([]*model.Metadata, 0, len(resp.Metadata))
+	for _, m := range resp.Metadata {
+		res = append(res, model.MetadataFromProto(m))
+	}
+	return res, nil
+}
+
+// GetSimilar returns up to limit movies most similar to a movie.
+func (g *Gateway) GetSimilar(ctx context.Context, id string, limit int) ([]model.SimilarMovie, error) {
+	var resp *gen.GetSimilarMetadataResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("GetSimilarMetadata"), func(ctx context.Context, _ int) (*gen.GetSimilarMetadataResponse, error) {
+			return g.client.GetSimilarMetadata(ctx, &gen.GetSimilarMetadataRequest{MovieId: id, Limit: int32(limit)})
+		})
+		return err
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, gateway.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	res := make([]model.SimilarMovie, 0, len(resp.Similar))
+	for _, s := range resp.Similar {
+		res = append(res, model.SimilarMovie{Metadata: model.MetadataFromProto(s.Metadata), Score: s.Score})
+	}
+	return res, nil
+}
+
+// List returns a page of the movie metadata of the catalog and
+// the token of the next page, empty if it is the last one.
+func (g *Gateway) List(ctx context.Context, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	var resp *gen.ListMetadataResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("ListMetadata"), func(ctx context.Context, _ int) (*gen.ListMetadataResponse, error) {
+			return g.client.ListMetadata(ctx, &gen.ListMetadataRequest{PageSize: int32(pageSize), PageToken: pageToken})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]*model.Metadata, 0, len(resp.Metadata))
+	for _, m := range resp.Metadata {
+		res = append(res, model.MetadataFromProto(m))
+	}
+	return res, resp.NextPageToken, nil
 }
@@ -3,42 +3,96 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"golang.org/x/exp/rand"
+	"movieapp.com/internal/budget"
+	"movieapp.com/internal/hedge"
+	"movieapp.com/internal/httputil"
+	"movieapp.com/internal/tracing"
 	"movieapp.com/metadata/pkg/model"
 	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/pkg/auth"
 	"movieapp.com/pkg/discovery"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/pkg/tenant"
 )
 
+var logger = logging.New("gateway/metadata/http")
+
 // Gateway defines a movie metadata HTTP gateway.
 type Gateway struct {
 	registry discovery.Registry
+	cache    *gateway.MetadataCache
+	retry    resilience.Retry
+	hedge    *hedge.Set
 }
 
 // New creates a new HTTP gateway for a movie metadata service
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// caching metadata in the given cache. Transient failures of
+// reads are retried, and slow reads are hedged with the given
+// hedgers unless nil.
+func New(registry discovery.Registry, cache *gateway.MetadataCache, hedges *hedge.Set) *Gateway {
+	return &Gateway{registry, cache, resilience.DefaultRetry(httputil.Retryable), hedges}
 }
 
+// Get returns movie metadata by a movie id. Fresh cached metadata
+// is returned as is, while stale cached metadata is revalidated
+// with If-None-Match and reused on 304.
 func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	addrs, err := g.registry.ServiceAddresses(ctx, "metadata")
+	cached, etag, fresh := g.cache.Get(ctx, id)
+	if fresh {
+		return cached, nil
+	}
+	header := http.Header{}
+	if cached != nil {
+		header.Set("If-None-Match", etag)
+	}
+	resp, err := g.get(ctx, "/v1/metadata", url.Values{"id": {id}}, header)
 	if err != nil {
 		return nil, err
 	}
-	url := "http://" + addrs[rand.Intn(len(addrs))] + "/metadata"
-	log.Printf("Calling metadata service. Request: GET " + url)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		g.cache.Put(ctx, id, cached, etag)
+		return cached, nil
+	} else if resp.StatusCode == http.StatusNotFound {
+		return nil, gateway.ErrNotFound
+	} else if resp.StatusCode/100 != 2 {
+		return nil, &httputil.StatusError{StatusCode: resp.StatusCode}
+	}
+	var v *model.Metadata
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, err
+	}
+	g.cache.Put(ctx, id, v, resp.Header.Get("ETag"))
+	return v, nil
+}
+
+// GetMany returns metadata of several movies in a single call,
+// in the order of the given ids. Missing movies are left out.
+func (g *Gateway) GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	resp, err := g.get(ctx, "/v1/metadata/batch", url.Values{"id": ids}, nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-	values := req.URL.Query()
-	values.Add("id", id)
-	req.URL.RawQuery = values.Encode()
-	resp, err := http.DefaultClient.Do(req)
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, &httputil.StatusError{StatusCode: resp.StatusCode}
+	}
+	var v []*model.Metadata
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetSimilar returns up to limit movies most similar to a movie.
+func (g *Gateway) GetSimilar(ctx context.Context, id string, limit int) ([]model.SimilarMovie, error) {
+	resp, err := g.get(ctx, "/v1/metadata/similar", url.Values{"id": {id}, "limit": {strconv.Itoa(limit)}}, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -46,12 +100,71 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	if resp.StatusCode == http.StatusNotFound {
 		return nil, gateway.ErrNotFound
 	} else if resp.StatusCode/100 != 2 {
-		return nil, fmt.Errorf("non-2xx response: %v", resp)
+		return nil, &httputil.StatusError{StatusCode: resp.StatusCode}
 	}
-	var v *model.Metadata
+	var v []model.SimilarMovie
 	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
 		return nil, err
 	}
-
 	return v, nil
 }
+
+// List returns a page of the movie metadata of the catalog and
+// the token of the next page, empty if it is the last one.
+func (g *Gateway) List(ctx context.Context, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	resp, err := g.get(ctx, "/v1/metadata/list", url.Values{"pageSize": {strconv.Itoa(pageSize)}, "pageToken": {pageToken}}, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, "", &httputil.StatusError{StatusCode: resp.StatusCode}
+	}
+	var v model.Page
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, "", err
+	}
+	return v.Metadata, v.NextPageToken, nil
+}
+
+// get sends a GET request to a random metadata service instance,
+// hedging it with another instance if slow and retrying transient
+// failures with another random instance.
+func (g *Gateway) get(ctx context.Context, path string, values url.Values, header http.Header) (*http.Response, error) {
+	var resp *http.Response
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		addrs, err := g.registry.ServiceAddresses(ctx, "metadata")
+		if err != nil {
+			return err
+		}
+		// A hedge goes to the instance after the random first one.
+		first := rand.Intn(len(addrs))
+		resp, err = hedge.Do(ctx, g.hedge.For(path), func(ctx context.Context, attempt int) (*http.Response, error) {
+			url := httputil.ServiceScheme + "://" + addrs[(first+attempt)%len(addrs)] + path
+			logger.DebugContext(ctx, "Calling metadata service", "method", http.MethodGet, "url", url)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.URL.RawQuery = values.Encode()
+			for k, v := range header {
+				req.Header[k] = v
+			}
+			budget.SetHeader(req)
+			logging.SetHeader(req)
+			tenant.SetHeader(req)
+			auth.SetHeader(req)
+			resp, err := tracing.Client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			if statusErr := (&httputil.StatusError{StatusCode: resp.StatusCode}); httputil.Retryable(statusErr) {
+				resp.Body.Close()
+				return nil, statusErr
+			}
+			return httputil.BufferBody(resp)
+		})
+		return err
+	})
+	return resp, err
+}
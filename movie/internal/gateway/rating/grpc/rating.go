@@ -2,34 +2,154 @@ package grpc
 
 import (
 	"context"
+	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"movieapp.com/gen"
 	"movieapp.com/internal/grpcutil"
-	"movieapp.com/pkg/discovery"
+	"movieapp.com/internal/hedge"
+	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/pkg/resilience"
 	"movieapp.com/rating/pkg/model"
 )
 
 // Gateway defines an gRPC gateway for a rating service.
 type Gateway struct {
-	registry discovery.Registry
+	client gen.RatingServiceClient
+	retry  resilience.Retry
+	hedge  *hedge.Set
 }
 
-// New creates a new gRPC gateway for a rating service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// New creates a new gRPC gateway for a rating service calling it
+// through the connection. Transient failures of reads are retried,
+// and slow reads are hedged with the given hedgers unless nil;
+// the connection balances a hedge to another instance.
+func New(conn grpc.ClientConnInterface, hedges *hedge.Set) *Gateway {
+	return &Gateway{gen.NewRatingServiceClient(conn), resilience.DefaultRetry(grpcutil.Retryable), hedges}
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
 func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
-	conn, err := grpcutil.ServiceConnection(ctx, "rating", g.registry)
-	if err != nil {
+	var resp *gen.GetAggregatedRatingResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("GetAggregatedRating"), func(ctx context.Context, _ int) (*gen.GetAggregatedRatingResponse, error) {
+			return g.client.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
+		})
+		return err
+	})
+	if status.Code(err) == codes.NotFound {
+		return 0, gateway.ErrNotFound
+	} else if err != nil {
 		return 0, err
 	}
-	defer conn.Close()
-	client := gen.NewRatingServiceClient(conn)
-	resp, err := client.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
+	return resp.RatingValue, nil
+}
+
+// GetAggregatedRatings returns the aggregated ratings of several
+// records by record id in a single call. Records without ratings
+// are left out.
+func (g *Gateway) GetAggregatedRatings(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]float64, error) {
+	ids := make([]string, 0, len(recordIDs))
+	for _, id := range recordIDs {
+		ids = append(ids, string(id))
+	}
+	var resp *gen.GetAggregatedRatingsResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("GetAggregatedRatings"), func(ctx context.Context, _ int) (*gen.GetAggregatedRatingsResponse, error) {
+			return g.client.GetAggregatedRatings(ctx, &gen.GetAggregatedRatingsRequest{RecordIds: ids, RecordType: string(recordType)})
+		})
+		return err
+	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return resp.RatingValue, nil
+	res := make(map[model.RecordID]float64, len(resp.RatingValues))
+	for id, v := range resp.RatingValues {
+		res[model.RecordID(id)] = v
+	}
+	return res, nil
+}
+
+// GetUserRatings returns the ratings a user gave to several
+// records by record id in a single call. Records the user has not
+// rated are left out.
+func (g *Gateway) GetUserRatings(ctx context.Context, userID model.UserID, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]model.RatingValue, error) {
+	ids := make([]string, 0, len(recordIDs))
+	for _, id := range recordIDs {
+		ids = append(ids, string(id))
+	}
+	var resp *gen.GetUserRatingsResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("GetUserRatings"), func(ctx context.Context, _ int) (*gen.GetUserRatingsResponse, error) {
+			return g.client.GetUserRatings(ctx, &gen.GetUserRatingsRequest{UserId: string(userID), RecordIds: ids, RecordType: string(recordType)})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[model.RecordID]model.RatingValue, len(resp.RatingValues))
+	for id, v := range resp.RatingValues {
+		res[model.RecordID(id)] = model.RatingValue(v)
+	}
+	return res, nil
+}
+
+// GetTrending returns up to limit records trending by their
+// ratings within the window.
+func (g *Gateway) GetTrending(ctx context.Context, recordType model.RecordType, window time.Duration, limit int) ([]model.TrendingRecord, error) {
+	var resp *gen.GetTrendingResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("GetTrending"), func(ctx context.Context, _ int) (*gen.GetTrendingResponse, error) {
+			return g.client.GetTrending(ctx, &gen.GetTrendingRequest{RecordType: string(recordType), WindowHours: int32(window.Hours()), Limit: int32(limit)})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.TrendingRecord, 0, len(resp.Records))
+	for _, t := range resp.Records {
+		res = append(res, model.TrendingRecord{RecordID: model.RecordID(t.RecordId), RecordType: recordType, Count: int(t.Count), Score: t.Score})
+	}
+	return res, nil
+}
+
+// ListRecordRatings returns the individual ratings of a record.
+func (g *Gateway) ListRecordRatings(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	var resp *gen.ListRecordRatingsResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("ListRecordRatings"), func(ctx context.Context, _ int) (*gen.ListRecordRatingsResponse, error) {
+			return g.client.ListRecordRatings(ctx, &gen.ListRecordRatingsRequest{RecordId: string(recordID), RecordType: string(recordType)})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.RatingsFromProto(resp.Ratings), nil
+}
+
+// ListUserRatings returns all ratings a user gave to records of a
+// type.
+func (g *Gateway) ListUserRatings(ctx context.Context, userID model.UserID, recordType model.RecordType) ([]model.Rating, error) {
+	var resp *gen.ListUserRatingsResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = hedge.Do(ctx, g.hedge.For("ListUserRatings"), func(ctx context.Context, _ int) (*gen.ListUserRatingsResponse, error) {
+			return g.client.ListUserRatings(ctx, &gen.ListUserRatingsRequest{UserId: string(userID), RecordType: string(recordType)})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.RatingsFromProto(resp.Ratings), nil
 }
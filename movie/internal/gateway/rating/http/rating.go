@@ -4,68 +4,100 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/exp/rand"
+	"movieapp.com/internal/budget"
+	"movieapp.com/internal/hedge"
+	"movieapp.com/internal/httputil"
+	"movieapp.com/internal/tracing"
 	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/pkg/auth"
 	"movieapp.com/pkg/discovery"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/pkg/tenant"
 	"movieapp.com/rating/pkg/model"
 )
 
+var logger = logging.New("gateway/rating/http")
+
 // Gateway defines an HTTP gateway for a rating service.
 type Gateway struct {
 	registry discovery.Registry
+	retry    resilience.Retry
+	hedge    *hedge.Set
 }
 
-// New creates a new HTTP gateway for a rating service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// New creates a new HTTP gateway for a rating service. Transient
+// failures of reads are retried, and slow reads are hedged with
+// the given hedgers unless nil.
+func New(registry discovery.Registry, hedges *hedge.Set) *Gateway {
+	return &Gateway{registry, resilience.DefaultRetry(httputil.Retryable), hedges}
 }
 
 // GetAggregatedRating returns the aggregated rating for a
 // record or ErrNotFound if there are no ratings for it.
 func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.RecordID,
 	recordType model.RecordType) (float64, error) {
-	addrs, err := g.registry.ServiceAddresses(ctx, "rating")
-	if err != nil {
-		return 0, err
-	}
-	url := "http://" + addrs[rand.Intn(len(addrs))] + "/rating"
-	log.Printf("Calling rating service. Request: GET " + url)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return 0, err
-	}
-	req = req.WithContext(ctx)
-	values := req.URL.Query()
-	values.Add("id", string(recordID))
-	values.Add("type", fmt.Sprintf("%v", recordType))
-	req.URL.RawQuery = values.Encode()
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusNotFound {
-		return 0, gateway.ErrNotFound
-	} else if resp.StatusCode/100 != 2 {
-		return 0, fmt.Errorf("non-2xx response: %v", resp)
-	}
 	var v float64
-	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
-		return 0, err
-	}
-	return v, nil
+	err := g.get(ctx, "/rating", url.Values{"id": {string(recordID)}, "type": {string(recordType)}}, &v)
+	return v, err
 }
 
+// GetAggregatedRatings returns the aggregated ratings of several
+// records by record id in a single call. Records without ratings
+// are left out.
+func (g *Gateway) GetAggregatedRatings(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]float64, error) {
+	var res map[model.RecordID]float64
+	err := g.get(ctx, "/ratings", url.Values{"ids": {joinIDs(recordIDs)}, "type": {string(recordType)}}, &res)
+	return res, err
+}
+
+// GetUserRatings returns the ratings a user gave to several
+// records by record id in a single call. Records the user has not
+// rated are left out.
+func (g *Gateway) GetUserRatings(ctx context.Context, userID model.UserID, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]model.RatingValue, error) {
+	var res map[model.RecordID]model.RatingValue
+	err := g.get(ctx, "/ratings/user", url.Values{"userId": {string(userID)}, "ids": {joinIDs(recordIDs)}, "type": {string(recordType)}}, &res)
+	return res, err
+}
+
+// GetTrending returns up to limit records trending by their
+// ratings within the window.
+func (g *Gateway) GetTrending(ctx context.Context, recordType model.RecordType, window time.Duration, limit int) ([]model.TrendingRecord, error) {
+	var res []model.TrendingRecord
+	err := g.get(ctx, "/ratings/trending", url.Values{"type": {string(recordType)}, "window": {window.String()}, "limit": {strconv.Itoa(limit)}}, &res)
+	return res, err
+}
+
+// ListRecordRatings returns the individual ratings of a record.
+func (g *Gateway) ListRecordRatings(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	var res []model.Rating
+	err := g.get(ctx, "/ratings/record", url.Values{"id": {string(recordID)}, "type": {string(recordType)}}, &res)
+	return res, err
+}
+
+// ListUserRatings returns all ratings a user gave to records of a
+// type.
+func (g *Gateway) ListUserRatings(ctx context.Context, userID model.UserID, recordType model.RecordType) ([]model.Rating, error) {
+	var res []model.Rating
+	err := g.get(ctx, "/ratings/history", url.Values{"userId": {string(userID)}, "type": {string(recordType)}}, &res)
+	return res, err
+}
+
+// PutRating writes a rating. Unlike reads, writes are not retried.
 func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
 	addrs, err := g.registry.ServiceAddresses(ctx, "rating")
 	if err != nil {
 		return err
 	}
-	url := "http://" + addrs[rand.Intn(len(addrs))] + "/rating"
-	log.Printf("Calling rating service. Request: PUT " + url)
+	url := httputil.ServiceScheme + "://" + addrs[rand.Intn(len(addrs))] + "/rating"
+	logger.DebugContext(ctx, "Calling rating service", "method", http.MethodPut, "url", url)
 	req, err := http.NewRequest(http.MethodPut, url, nil)
 	if err != nil {
 		return err
@@ -77,7 +109,11 @@ func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, record
 	values.Add("userId", string(rating.UserID))
 	values.Add("value", fmt.Sprintf("%v", rating.Value))
 	req.URL.RawQuery = values.Encode()
-	resp, err := http.DefaultClient.Do(req)
+	budget.SetHeader(req)
+	logging.SetHeader(req)
+	tenant.SetHeader(req)
+	auth.SetHeader(req)
+	resp, err := tracing.Client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -87,3 +123,60 @@ func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, record
 	}
 	return nil
 }
+
+// get sends a GET request to a random rating service instance,
+// hedging it with another instance if slow and retrying transient
+// failures with another random instance, and
+// decodes the JSON response into v. Returns ErrNotFound on 404.
+func (g *Gateway) get(ctx context.Context, path string, values url.Values, v any) error {
+	var resp *http.Response
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		addrs, err := g.registry.ServiceAddresses(ctx, "rating")
+		if err != nil {
+			return err
+		}
+		// A hedge goes to the instance after the random first one.
+		first := rand.Intn(len(addrs))
+		resp, err = hedge.Do(ctx, g.hedge.For(path), func(ctx context.Context, attempt int) (*http.Response, error) {
+			url := httputil.ServiceScheme + "://" + addrs[(first+attempt)%len(addrs)] + path
+			logger.DebugContext(ctx, "Calling rating service", "method", http.MethodGet, "url", url)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.URL.RawQuery = values.Encode()
+			budget.SetHeader(req)
+			logging.SetHeader(req)
+			tenant.SetHeader(req)
+			auth.SetHeader(req)
+			resp, err := tracing.Client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			if statusErr := (&httputil.StatusError{StatusCode: resp.StatusCode}); httputil.Retryable(statusErr) {
+				resp.Body.Close()
+				return nil, statusErr
+			}
+			return httputil.BufferBody(resp)
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return gateway.ErrNotFound
+	} else if resp.StatusCode/100 != 2 {
+		return &httputil.StatusError{StatusCode: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func joinIDs(recordIDs []model.RecordID) string {
+	ids := make([]string, 0, len(recordIDs))
+	for _, id := range recordIDs {
+		ids = append(ids, string(id))
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,61 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/recommendation/pkg/model"
+)
+
+// Gateway defines a gRPC gateway for a recommendation service.
+type Gateway struct {
+	client gen.RecommendationServiceClient
+	retry  resilience.Retry
+}
+
+// New creates a new gRPC gateway for a recommendation service
+// calling it through the connection. Transient failures are
+// retried.
+func New(conn grpc.ClientConnInterface) *Gateway {
+	return &Gateway{gen.NewRecommendationServiceClient(conn), resilience.DefaultRetry(grpcutil.Retryable)}
+}
+
+// RecommendForMovie returns up to limit movies recommended after a
+// movie by the model, or gateway.ErrNotFound if the model has none.
+func (g *Gateway) RecommendForMovie(ctx context.Context, movieID string, limit int) ([]model.Recommendation, error) {
+	var resp *gen.RecommendResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.RecommendForMovie(ctx, &gen.RecommendForMovieRequest{MovieId: movieID, Limit: int32(limit)})
+		return err
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, gateway.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return model.RecommendationsFromProto(resp.Recommendations), nil
+}
+
+// RecommendForUser returns up to limit movies recommended to a user
+// by the model, or gateway.ErrNotFound if the model has none.
+func (g *Gateway) RecommendForUser(ctx context.Context, userID string, limit int) ([]model.Recommendation, error) {
+	var resp *gen.RecommendResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.RecommendForUser(ctx, &gen.RecommendForUserRequest{UserId: userID, Limit: int32(limit)})
+		return err
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, gateway.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return model.RecommendationsFromProto(resp.Recommendations), nil
+}
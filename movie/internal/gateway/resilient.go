@@ -0,0 +1,234 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/resilience"
+	ratingmodel "movieapp.com/rating/pkg/model"
+	recommendationmodel "movieapp.com/recommendation/pkg/model"
+	searchmodel "movieapp.com/search/pkg/model"
+)
+
+// MetadataGateway defines a movie metadata gateway.
+type MetadataGateway interface {
+	Get(ctx context.Context, id string) (*model.Metadata, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error)
+	GetSimilar(ctx context.Context, id string, limit int) ([]model.SimilarMovie, error)
+	List(ctx context.Context, pageSize int, pageToken string) ([]*model.Metadata, string, error)
+}
+
+// RatingGateway defines a rating gateway.
+type RatingGateway interface {
+	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
+	GetAggregatedRatings(ctx context.Context, recordIDs []ratingmodel.RecordID, recordType ratingmodel.RecordType) (map[ratingmodel.RecordID]float64, error)
+	GetUserRatings(ctx context.Context, userID ratingmodel.UserID, recordIDs []ratingmodel.RecordID, recordType ratingmodel.RecordType) (map[ratingmodel.RecordID]ratingmodel.RatingValue, error)
+	GetTrending(ctx context.Context, recordType ratingmodel.RecordType, window time.Duration, limit int) ([]ratingmodel.TrendingRecord, error)
+	ListRecordRatings(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) ([]ratingmodel.Rating, error)
+	ListUserRatings(ctx context.Context, userID ratingmodel.UserID, recordType ratingmodel.RecordType) ([]ratingmodel.Rating, error)
+}
+
+// WatchlistGateway defines a watchlist gateway.
+type WatchlistGateway interface {
+	GetWatchlisted(ctx context.Context, userID string, movieIDs []string) (map[string]time.Time, error)
+}
+
+// SearchGateway defines a search gateway.
+type SearchGateway interface {
+	Search(ctx context.Context, q searchmodel.Query, pageSize int, cursor string) ([]searchmodel.Hit, string, int, error)
+}
+
+// RecommendationGateway defines a recommendation gateway.
+type RecommendationGateway interface {
+	RecommendForMovie(ctx context.Context, movieID string, limit int) ([]recommendationmodel.Recommendation, error)
+	RecommendForUser(ctx context.Context, userID string, limit int) ([]recommendationmodel.Recommendation, error)
+}
+
+// NewBreaker creates a circuit breaker of a downstream service.
+// Missing records and canceled calls do not count as failures.
+func NewBreaker(service string, config resilience.BreakerConfig) *resilience.Breaker {
+	config.IsFailure = func(err error) bool {
+		return !errors.Is(err, ErrNotFound) && !errors.Is(err, context.Canceled)
+	}
+	return resilience.NewBreaker(service, config)
+}
+
+// ResilientMetadata wraps a metadata gateway with a resilience
+// policy, such as a bulkhead and a circuit breaker.
+type ResilientMetadata struct {
+	gateway MetadataGateway
+	policy  resilience.Policy
+}
+
+// NewResilientMetadata creates a metadata gateway calling the
+// given one through the policy.
+func NewResilientMetadata(gateway MetadataGateway, policy resilience.Policy) *ResilientMetadata {
+	return &ResilientMetadata{gateway, policy}
+}
+
+// Get returns movie metadata by a movie id.
+func (g *ResilientMetadata) Get(ctx context.Context, id string) (*model.Metadata, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (*model.Metadata, error) {
+		return g.gateway.Get(ctx, id)
+	})
+}
+
+// GetMany returns metadata of several movies in a single call.
+func (g *ResilientMetadata) GetMany(ctx context.Context, ids []string) ([]*model.Metadata, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) ([]*model.Metadata, error) {
+		return g.gateway.GetMany(ctx, ids)
+	})
+}
+
+// GetSimilar returns the movies most similar to a movie.
+func (g *ResilientMetadata) GetSimilar(ctx context.Context, id string, limit int) ([]model.SimilarMovie, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) ([]model.SimilarMovie, error) {
+		return g.gateway.GetSimilar(ctx, id, limit)
+	})
+}
+
+// List returns a page of the movie metadata of the catalog.
+func (g *ResilientMetadata) List(ctx context.Context, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	var res []*model.Metadata
+	var next string
+	err := g.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		res, next, err = g.gateway.List(ctx, pageSize, pageToken)
+		return err
+	})
+	return res, next, err
+}
+
+// ResilientRating wraps a rating gateway with a resilience policy,
+// such as a bulkhead and a circuit breaker.
+type ResilientRating struct {
+	gateway RatingGateway
+	policy  resilience.Policy
+}
+
+// NewResilientRating creates a rating gateway calling the given one
+// through the policy.
+func NewResilientRating(gateway RatingGateway, policy resilience.Policy) *ResilientRating {
+	return &ResilientRating{gateway, policy}
+}
+
+// GetAggregatedRating returns the aggregated rating for a record.
+func (g *ResilientRating) GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (float64, error) {
+		return g.gateway.GetAggregatedRating(ctx, recordID, recordType)
+	})
+}
+
+// GetAggregatedRatings returns the aggregated ratings of several
+// records in a single call.
+func (g *ResilientRating) GetAggregatedRatings(ctx context.Context, recordIDs []ratingmodel.RecordID, recordType ratingmodel.RecordType) (map[ratingmodel.RecordID]float64, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (map[ratingmodel.RecordID]float64, error) {
+		return g.gateway.GetAggregatedRatings(ctx, recordIDs, recordType)
+	})
+}
+
+// GetUserRatings returns the ratings a user gave to several
+// records in a single call.
+func (g *ResilientRating) GetUserRatings(ctx context.Context, userID ratingmodel.UserID, recordIDs []ratingmodel.RecordID, recordType ratingmodel.RecordType) (map[ratingmodel.RecordID]ratingmodel.RatingValue, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (map[ratingmodel.RecordID]ratingmodel.RatingValue, error) {
+		return g.gateway.GetUserRatings(ctx, userID, recordIDs, recordType)
+	})
+}
+
+// GetTrending returns the records trending by recent ratings.
+func (g *ResilientRating) GetTrending(ctx context.Context, recordType ratingmodel.RecordType, window time.Duration, limit int) ([]ratingmodel.TrendingRecord, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) ([]ratingmodel.TrendingRecord, error) {
+		return g.gateway.GetTrending(ctx, recordType, window, limit)
+	})
+}
+
+// ListRecordRatings returns the individual ratings of a record.
+func (g *ResilientRating) ListRecordRatings(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) ([]ratingmodel.Rating, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) ([]ratingmodel.Rating, error) {
+		return g.gateway.ListRecordRatings(ctx, recordID, recordType)
+	})
+}
+
+// ListUserRatings returns all ratings a user gave to records of a
+// type.
+func (g *ResilientRating) ListUserRatings(ctx context.Context, userID ratingmodel.UserID, recordType ratingmodel.RecordType) ([]ratingmodel.Rating, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) ([]ratingmodel.Rating, error) {
+		return g.gateway.ListUserRatings(ctx, userID, recordType)
+	})
+}
+
+// ResilientWatchlist wraps a watchlist gateway with a resilience
+// policy, such as a bulkhead and a circuit breaker.
+type ResilientWatchlist struct {
+	gateway WatchlistGateway
+	policy  resilience.Policy
+}
+
+// NewResilientWatchlist creates a watchlist gateway calling the
+// given one through the policy.
+func NewResilientWatchlist(gateway WatchlistGateway, policy resilience.Policy) *ResilientWatchlist {
+	return &ResilientWatchlist{gateway, policy}
+}
+
+// GetWatchlisted returns the times several movies were added to
+// the watchlist of a user in a single call.
+func (g *ResilientWatchlist) GetWatchlisted(ctx context.Context, userID string, movieIDs []string) (map[string]time.Time, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) (map[string]time.Time, error) {
+		return g.gateway.GetWatchlisted(ctx, userID, movieIDs)
+	})
+}
+
+// ResilientSearch wraps a search gateway with a resilience policy,
+// such as a bulkhead and a circuit breaker.
+type ResilientSearch struct {
+	gateway SearchGateway
+	policy  resilience.Policy
+}
+
+// NewResilientSearch creates a search gateway calling the given one
+// through the policy.
+func NewResilientSearch(gateway SearchGateway, policy resilience.Policy) *ResilientSearch {
+	return &ResilientSearch{gateway, policy}
+}
+
+// Search returns a page of the movies matching a query.
+func (g *ResilientSearch) Search(ctx context.Context, q searchmodel.Query, pageSize int, cursor string) ([]searchmodel.Hit, string, int, error) {
+	var hits []searchmodel.Hit
+	var next string
+	var total int
+	err := g.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		hits, next, total, err = g.gateway.Search(ctx, q, pageSize, cursor)
+		return err
+	})
+	return hits, next, total, err
+}
+
+// ResilientRecommendation wraps a recommendation gateway with a
+// resilience policy, such as a bulkhead and a circuit breaker.
+type ResilientRecommendation struct {
+	gateway RecommendationGateway
+	policy  resilience.Policy
+}
+
+// NewResilientRecommendation creates a recommendation gateway
+// calling the given one through the policy.
+func NewResilientRecommendation(gateway RecommendationGateway, policy resilience.Policy) *ResilientRecommendation {
+	return &ResilientRecommendation{gateway, policy}
+}
+
+// RecommendForMovie returns the movies recommended after a movie.
+func (g *ResilientRecommendation) RecommendForMovie(ctx context.Context, movieID string, limit int) ([]recommendationmodel.Recommendation, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) ([]recommendationmodel.Recommendation, error) {
+		return g.gateway.RecommendForMovie(ctx, movieID, limit)
+	})
+}
+
+// RecommendForUser returns the movies recommended to a user.
+func (g *ResilientRecommendation) RecommendForUser(ctx context.Context, userID string, limit int) ([]recommendationmodel.Recommendation, error) {
+	return resilience.Call(ctx, g.policy, func(ctx context.Context) ([]recommendationmodel.Recommendation, error) {
+		return g.gateway.RecommendForUser(ctx, userID, limit)
+	})
+}
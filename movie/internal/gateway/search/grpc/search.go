@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/search/pkg/model"
+)
+
+// Gateway defines a gRPC gateway for a search service.
+type Gateway struct {
+	client gen.SearchServiceClient
+	retry  resilience.Retry
+}
+
+// New creates a new gRPC gateway for a search service calling it
+// through the connection. Transient failures are retried.
+func New(conn grpc.ClientConnInterface) *Gateway {
+	return &Gateway{gen.NewSearchServiceClient(conn), resilience.DefaultRetry(grpcutil.Retryable)}
+}
+
+// Search returns a page of the movies matching a query by
+// descending score, the cursor of the next page and the number of
+// matching movies.
+func (g *Gateway) Search(ctx context.Context, q model.Query, pageSize int, cursor string) ([]model.Hit, string, int, error) {
+	var resp *gen.SearchMoviesResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.SearchMovies(ctx, &gen.SearchMoviesRequest{
+			Query:    q.Text,
+			Genres:   q.Genres,
+			YearFrom: int32(q.YearFrom),
+			YearTo:   int32(q.YearTo),
+			PageSize: int32(pageSize),
+			Cursor:   cursor,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return model.HitsFromProto(resp.Hits), resp.NextCursor, int(resp.Total), nil
+}
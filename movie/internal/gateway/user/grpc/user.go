@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/user/pkg/model"
+)
+
+// Gateway defines a gRPC gateway for a user service.
+type Gateway struct {
+	client gen.UserServiceClient
+	retry  resilience.Retry
+}
+
+// New creates a new gRPC gateway for a user service calling it
+// through the connection. Transient failures are retried.
+func New(conn grpc.ClientConnInterface) *Gateway {
+	return &Gateway{gen.NewUserServiceClient(conn), resilience.DefaultRetry(grpcutil.Retryable)}
+}
+
+// GetUser returns the account of a user.
+func (g *Gateway) GetUser(ctx context.Context, userID string) (*model.User, error) {
+	var resp *gen.GetUserResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.GetUser(ctx, &gen.GetUserRequest{UserId: userID})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.UserFromProto(resp.User), nil
+}
@@ -0,0 +1,67 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/watchlist/pkg/model"
+)
+
+// listPageSize is the largest page of watchlist items.
+const listPageSize = 100
+
+// Gateway defines a gRPC gateway for a watchlist service.
+type Gateway struct {
+	client gen.WatchlistServiceClient
+	retry  resilience.Retry
+}
+
+// New creates a new gRPC gateway for a watchlist service calling
+// it through the connection. Transient failures are retried.
+func New(conn grpc.ClientConnInterface) *Gateway {
+	return &Gateway{gen.NewWatchlistServiceClient(conn), resilience.DefaultRetry(grpcutil.Retryable)}
+}
+
+// GetWatchlisted returns the times several movies were added to
+// the watchlist of a user by movie id in a single call. Movies not
+// in the watchlist are left out.
+func (g *Gateway) GetWatchlisted(ctx context.Context, userID string, movieIDs []string) (map[string]time.Time, error) {
+	var resp *gen.GetWatchlistedResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.GetWatchlisted(ctx, &gen.GetWatchlistedRequest{UserId: userID, MovieIds: movieIDs})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.AddedAtFromProto(resp.AddedAt), nil
+}
+
+// ListWatchlist returns all the items of the watchlist of a user,
+// the most recently added first, paging through them.
+func (g *Gateway) ListWatchlist(ctx context.Context, userID string) ([]*model.Item, error) {
+	var res []*model.Item
+	for cursor := ""; ; {
+		var resp *gen.ListWatchlistResponse
+		err := g.retry.Do(ctx, func(ctx context.Context) error {
+			var err error
+			resp, err = g.client.ListWatchlist(ctx, &gen.ListWatchlistRequest{UserId: userID, PageSize: listPageSize, Cursor: cursor})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range resp.Items {
+			res = append(res, model.ItemFromProto(i))
+		}
+		if resp.NextCursor == "" {
+			return res, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
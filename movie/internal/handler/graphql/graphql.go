@@ -0,0 +1,333 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graphql-go/graphql"
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/movie/internal/controller/movie"
+	"movieapp.com/movie/pkg/model"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("handler/graphql")
+
+// Handler defines a GraphQL API handler aggregating movie
+// metadata, ratings and similar movies for clients. Movie details
+// and user ratings referenced by the results are loaded in
+// batches per request instead of one by one.
+type Handler struct {
+	ctrl   *movie.Controller
+	schema graphql.Schema
+}
+
+// New creates a new GraphQL API handler.
+func New(ctrl *movie.Controller) (*Handler, error) {
+	h := &Handler{ctrl: ctrl}
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP handles GET and POST /graphql requests with the query
+// in the query parameter or in the JSON body.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var r request
+	switch req.Method {
+	case http.MethodGet:
+		r.Query = req.FormValue("query")
+		r.OperationName = req.FormValue("operationName")
+		if v := req.FormValue("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &r.Variables); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Query == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	res := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  r.Query,
+		OperationName:  r.OperationName,
+		VariableValues: r.Variables,
+		Context:        h.withLoaders(req.Context()),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+type loadersKey struct{}
+
+// userRatingKey identifies the rating of a movie by a user.
+type userRatingKey struct {
+	userID  string
+	movieID string
+}
+
+// similarKey identifies the similar movies of a movie.
+type similarKey struct {
+	movieID string
+	limit   int
+}
+
+// loaders batch and deduplicate the downstream reads of a single
+// request.
+type loaders struct {
+	movies      *dataloader.Loader[string, *model.MovieDetails]
+	userRatings *dataloader.Loader[userRatingKey, *int]
+	similar     *dataloader.Loader[similarKey, []metadatamodel.SimilarMovie]
+}
+
+func (h *Handler) withLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		movies: dataloader.NewBatchedLoader(h.loadMovies,
+			dataloader.WithBatchCapacity[string, *model.MovieDetails](movie.MaxBatchSize)),
+		userRatings: dataloader.NewBatchedLoader(h.loadUserRatings,
+			dataloader.WithBatchCapacity[userRatingKey, *int](movie.MaxBatchSize)),
+		similar: dataloader.NewBatchedLoader(h.loadSimilar),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loadMovies reads the details of movies in a single batch.
+// Missing movies resolve to null.
+func (h *Handler) loadMovies(ctx context.Context, ids []string) []*dataloader.Result[*model.MovieDetails] {
+	res := make([]*dataloader.Result[*model.MovieDetails], len(ids))
+	details, err := h.ctrl.GetMany(ctx, ids)
+	byID := make(map[string]*model.MovieDetails, len(details))
+	for _, d := range details {
+		byID[d.Metadata.ID] = d
+	}
+	for i, id := range ids {
+		res[i] = &dataloader.Result[*model.MovieDetails]{Data: byID[id], Error: err}
+	}
+	return res
+}
+
+// loadUserRatings reads the ratings of a batch with a single call
+// per user. Movies the user has not rated resolve to null.
+func (h *Handler) loadUserRatings(ctx context.Context, keys []userRatingKey) []*dataloader.Result[*int] {
+	res := make([]*dataloader.Result[*int], len(keys))
+	byUser := map[string][]string{}
+	for _, k := range keys {
+		byUser[k.userID] = append(byUser[k.userID], k.movieID)
+	}
+	ratings := map[string]map[string]int{}
+	errs := map[string]error{}
+	for userID, ids := range byUser {
+		ratings[userID], errs[userID] = h.ctrl.UserRatings(ctx, userID, ids)
+	}
+	for i, k := range keys {
+		r := &dataloader.Result[*int]{Error: errs[k.userID]}
+		if v, ok := ratings[k.userID][k.movieID]; ok {
+			r.Data = &v
+		}
+		res[i] = r
+	}
+	return res
+}
+
+// loadSimilar reads the similar movies of a batch. There is no
+// batch read of similar movies, but repeated ids are read once.
+func (h *Handler) loadSimilar(ctx context.Context, keys []similarKey) []*dataloader.Result[[]metadatamodel.SimilarMovie] {
+	res := make([]*dataloader.Result[[]metadatamodel.SimilarMovie], len(keys))
+	for i, k := range keys {
+		similar, err := h.ctrl.Similar(ctx, k.movieID, k.limit)
+		if err != nil && errors.Is(err, movie.ErrNotFound) {
+			similar, err = nil, nil
+		}
+		res[i] = &dataloader.Result[[]metadatamodel.SimilarMovie]{Data: similar, Error: err}
+	}
+	return res
+}
+
+// loadMovie resolves to the details of a movie loaded in a batch.
+// The returned thunk defers the read until all fields of the
+// level are queued.
+func loadMovie(ctx context.Context, id string) (any, error) {
+	thunk := loadersFrom(ctx).movies.Load(ctx, id)
+	return func() (any, error) {
+		d, err := thunk()
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return d, nil
+	}, nil
+}
+
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	metadataType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Metadata",
+		Fields: graphql.Fields{
+			"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"title":            &graphql.Field{Type: graphql.String},
+			"description":      &graphql.Field{Type: graphql.String},
+			"director":         &graphql.Field{Type: graphql.String},
+			"tags":             &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"posterUrl":        &graphql.Field{Type: graphql.String},
+			"backdropUrl":      &graphql.Field{Type: graphql.String},
+			"tagline":          &graphql.Field{Type: graphql.String},
+			"releaseDate":      &graphql.Field{Type: graphql.String},
+			"runtimeMinutes":   &graphql.Field{Type: graphql.Int},
+			"certification":    &graphql.Field{Type: graphql.String},
+			"originalLanguage": &graphql.Field{Type: graphql.String},
+			"genres": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					var res []string
+					for _, g := range p.Source.(*metadatamodel.Metadata).Genres {
+						res = append(res, string(g))
+					}
+					return res, nil
+				},
+			},
+		},
+	})
+	omissionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Omission",
+		Fields: graphql.Fields{
+			"component": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"reason":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"retryable": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	movieType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Movie",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(*model.MovieDetails).Metadata.ID, nil
+				},
+			},
+			"metadata": &graphql.Field{
+				Type: metadataType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return &p.Source.(*model.MovieDetails).Metadata, nil
+				},
+			},
+			"rating": &graphql.Field{
+				Type: graphql.Float,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if r := p.Source.(*model.MovieDetails).Rating; r != nil {
+						return *r, nil
+					}
+					return nil, nil
+				},
+			},
+			"degraded": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"partial":  &graphql.Field{Type: graphql.Boolean},
+			"omitted":  &graphql.Field{Type: graphql.NewList(omissionType)},
+			"userRating": &graphql.Field{
+				Type: graphql.Int,
+				Args: graphql.FieldConfigArgument{
+					"userId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					key := userRatingKey{p.Args["userId"].(string), p.Source.(*model.MovieDetails).Metadata.ID}
+					thunk := loadersFrom(p.Context).userRatings.Load(p.Context, key)
+					return func() (any, error) {
+						v, err := thunk()
+						if err != nil || v == nil {
+							return nil, err
+						}
+						return *v, nil
+					}, nil
+				},
+			},
+		},
+	})
+	movieType.AddFieldConfig("similar", &graphql.Field{
+		Type: graphql.NewList(movieType),
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			limit, _ := p.Args["limit"].(int)
+			ctx := p.Context
+			thunk := loadersFrom(ctx).similar.Load(ctx, similarKey{p.Source.(*model.MovieDetails).Metadata.ID, limit})
+			return func() (any, error) {
+				similar, err := thunk()
+				if err != nil {
+					return nil, err
+				}
+				// The details of all similar movies of the level
+				// are loaded in a single batch.
+				res := make([]any, 0, len(similar))
+				for _, s := range similar {
+					m, err := loadMovie(ctx, s.Metadata.ID)
+					if err != nil {
+						return nil, err
+					}
+					res = append(res, m)
+				}
+				return res, nil
+			}, nil
+		},
+	})
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"movie": &graphql.Field{
+				Type: movieType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return loadMovie(p.Context, p.Args["id"].(string))
+				},
+			},
+			"movies": &graphql.Field{
+				Type: graphql.NewList(movieType),
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					ids, _ := p.Args["ids"].([]any)
+					if len(ids) > movie.MaxBatchSize {
+						return nil, movie.ErrTooManyIDs
+					}
+					res := make([]any, 0, len(ids))
+					for _, id := range ids {
+						m, err := loadMovie(p.Context, id.(string))
+						if err != nil {
+							return nil, err
+						}
+						res = append(res, m)
+					}
+					return res, nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
@@ -2,13 +2,22 @@ package grpc
 
 import (
 	"context"
-	"errors"
+	"slices"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"movieapp.com/gen"
 	"movieapp.com/metadata/pkg/model"
 	"movieapp.com/movie/internal/controller/movie"
+	moviemodel "movieapp.com/movie/pkg/model"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
+	searchmodel "movieapp.com/search/pkg/model"
 )
 
 // Handler defines a movie gRPC handler.
@@ -22,21 +31,246 @@ func New(ctrl *movie.Controller) *Handler {
 	return &Handler{ctrl: ctrl}
 }
 
-// GetMovieDetails returns moviie details by id.
+// GetMovieDetails returns moviie details by id. Details requested
+// by an authenticated user include the user's own rating.
 func (h *Handler) GetMovieDetails(ctx context.Context, req *gen.GetMovieDetailsRequest) (*gen.GetMovieDetailsResponse, error) {
 	if req == nil || req.MovieId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	m, err := h.ctrl.Get(ctx, req.MovieId)
-	if err != nil && errors.Is(err, movie.ErrNotFound) {
-		return nil, status.Errorf(codes.NotFound, err.Error())
-	} else if err != nil {
-		return nil, status.Errorf(codes.Internal, err.Error())
-	}
-	return &gen.GetMovieDetailsResponse{
-		MovieDetails: &gen.MovieDetails{
-			Metadata: model.MetadataToProto(&m.Metadata),
-			Rating:   *m.Rating,
-		},
-	}, nil
+	if !validFieldMask(req.FieldMask) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid field mask")
+	}
+	var m *moviemodel.MovieDetails
+	var err error
+	if userID := userID(ctx); userID != "" {
+		m, err = h.ctrl.GetForUser(ctx, req.MovieId, userID)
+	} else {
+		m, err = h.ctrl.Get(ctx, req.MovieId)
+	}
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	m.Localize(locales(req.Locale)...)
+	details := movieDetailsToProto(m)
+	pruneDetails(details, req.FieldMask)
+	return &gen.GetMovieDetailsResponse{MovieDetails: details}, nil
+}
+
+// GetManyMovieDetails returns details of several movies at once.
+func (h *Handler) GetManyMovieDetails(ctx context.Context, req *gen.GetManyMovieDetailsRequest) (*gen.GetManyMovieDetailsResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	if !validFieldMask(req.FieldMask) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid field mask")
+	}
+	res, err := h.ctrl.GetMany(ctx, req.MovieIds)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	details := make([]*gen.MovieDetails, 0, len(res))
+	for _, m := range res {
+		m.Localize(locales(req.Locale)...)
+		d := movieDetailsToProto(m)
+		pruneDetails(d, req.FieldMask)
+		details = append(details, d)
+	}
+	return &gen.GetManyMovieDetailsResponse{MovieDetails: details}, nil
+}
+
+// ListMovies returns a page of the details of the movies of the
+// catalog.
+func (h *Handler) ListMovies(ctx context.Context, req *gen.ListMoviesRequest) (*gen.ListMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	res, next, err := h.ctrl.List(ctx, int(req.PageSize), req.Cursor)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	details := make([]*gen.MovieDetails, 0, len(res))
+	for _, m := range res {
+		m.Localize(locales(req.Locale)...)
+		details = append(details, movieDetailsToProto(m))
+	}
+	return &gen.ListMoviesResponse{MovieDetails: details, NextCursor: next}, nil
+}
+
+// ExportMovieDetails streams the details of all movies of the
+// catalog.
+func (h *Handler) ExportMovieDetails(req *gen.ExportMovieDetailsRequest, stream gen.MovieService_ExportMovieDetailsServer) error {
+	if req == nil {
+		return status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	err := h.ctrl.Export(stream.Context(), func(res []*moviemodel.MovieDetails) error {
+		details := make([]*gen.MovieDetails, 0, len(res))
+		for _, m := range res {
+			details = append(details, movieDetailsToProto(m))
+		}
+		return stream.Send(&gen.ExportMovieDetailsResponse{MovieDetails: details})
+	})
+	if err != nil {
+		return problem.Status(err)
+	}
+	return nil
+}
+
+// GetTrendingMovies returns the movies trending by recent ratings.
+func (h *Handler) GetTrendingMovies(ctx context.Context, req *gen.GetTrendingMoviesRequest) (*gen.GetTrendingMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	res, err := h.ctrl.Trending(ctx, time.Duration(req.WindowHours)*time.Hour, int(req.Limit))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	resp := &gen.GetTrendingMoviesResponse{}
+	for _, t := range res {
+		resp.Movies = append(resp.Movies, &gen.TrendingMovie{
+			MovieDetails: movieDetailsToProto(&t.MovieDetails),
+			Count:        int64(t.Count),
+			Score:        t.Score,
+		})
+	}
+	return resp, nil
+}
+
+// SearchMovies returns the details of a page of the movies
+// matching a query.
+func (h *Handler) SearchMovies(ctx context.Context, req *gen.SearchMoviesRequest) (*gen.SearchMovieDetailsResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	q := searchmodel.Query{Text: req.Query, Genres: req.Genres, YearFrom: int(req.YearFrom), YearTo: int(req.YearTo)}
+	res, next, total, err := h.ctrl.Search(ctx, q, int(req.PageSize), req.Cursor)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	resp := &gen.SearchMovieDetailsResponse{NextCursor: next, Total: int32(total)}
+	for _, m := range res {
+		resp.Movies = append(resp.Movies, &gen.SearchedMovie{MovieDetails: movieDetailsToProto(&m.MovieDetails), Score: m.Score})
+	}
+	return resp, nil
+}
+
+// GetMovieRecommendations returns the movies recommended after a
+// movie.
+func (h *Handler) GetMovieRecommendations(ctx context.Context, req *gen.GetMovieRecommendationsRequest) (*gen.GetRecommendationsResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	res, err := h.ctrl.MovieRecommendations(ctx, req.MovieId, int(req.Limit))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return recommendationsToProto(res), nil
+}
+
+// GetUserRecommendations returns the movies recommended to a user.
+func (h *Handler) GetUserRecommendations(ctx context.Context, req *gen.GetUserRecommendationsRequest) (*gen.GetRecommendationsResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	res, err := h.ctrl.UserRecommendations(ctx, req.UserId, int(req.Limit))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return recommendationsToProto(res), nil
+}
+
+func recommendationsToProto(res []*moviemodel.RecommendedMovie) *gen.GetRecommendationsResponse {
+	resp := &gen.GetRecommendationsResponse{}
+	for _, r := range res {
+		resp.Movies = append(resp.Movies, &gen.RecommendedMovie{MovieDetails: movieDetailsToProto(&r.MovieDetails), Score: r.Score})
+	}
+	return resp
+}
+
+func movieDetailsToProto(m *moviemodel.MovieDetails) *gen.MovieDetails {
+	details := &gen.MovieDetails{
+		Rating:      m.Rating,
+		Metadata:    model.MetadataToProto(&m.Metadata),
+		Degraded:    m.Degraded,
+		Partial:     m.Partial,
+		InWatchlist: m.InWatchlist,
+	}
+	if m.UserRating != nil {
+		v := int32(*m.UserRating)
+		details.UserRating = &v
+	}
+	for _, o := range m.Omitted {
+		details.Omitted = append(details.Omitted, &gen.Omission{Component: o.Component, Reason: o.Reason, Retryable: o.Retryable})
+	}
+	for _, s := range m.Similar {
+		details.Similar = append(details.Similar, &gen.SimilarTitle{MovieId: s.ID, Title: s.Title, Score: s.Score})
+	}
+	return details
+}
+
+// locales returns the preferred locales of a request, none if
+// its locale is unset.
+func locales(locale string) []string {
+	if locale == "" {
+		return nil
+	}
+	return []string{locale}
+}
+
+func validFieldMask(mask *fieldmaskpb.FieldMask) bool {
+	return mask == nil || mask.IsValid(&gen.MovieDetails{})
+}
+
+// pruneDetails clears the fields of movie details not in a field
+// mask, keeping the metadata id so that clients can tell the
+// movies apart and the degraded downstreams and partial status so
+// that they can tell a missing rating from an unselected one. An
+// empty mask keeps all fields.
+func pruneDetails(d *gen.MovieDetails, mask *fieldmaskpb.FieldMask) {
+	if len(mask.GetPaths()) == 0 {
+		return
+	}
+	prune(d.ProtoReflect(), append(slices.Clip(mask.Paths), "metadata.id", "degraded", "partial", "omitted"))
+}
+
+// prune clears the fields of a message not in the paths, pruning
+// the fields of nested messages named by longer paths in turn.
+func prune(m protoreflect.Message, paths []string) {
+	whole := map[protoreflect.Name]bool{}
+	nested := map[protoreflect.Name][]string{}
+	for _, p := range paths {
+		name, rest, ok := strings.Cut(p, ".")
+		if ok {
+			nested[protoreflect.Name(name)] = append(nested[protoreflect.Name(name)], rest)
+		} else {
+			whole[protoreflect.Name(name)] = true
+		}
+	}
+	var cleared []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case whole[fd.Name()]:
+		case len(nested[fd.Name()]) > 0 && fd.Message() != nil && fd.Cardinality() != protoreflect.Repeated:
+			prune(v.Message(), nested[fd.Name()])
+		default:
+			cleared = append(cleared, fd)
+		}
+		return true
+	})
+	for _, fd := range cleared {
+		m.Clear(fd)
+	}
+}
+
+// userIDKey is the metadata key of the id of the authenticated
+// caller, set by the authenticating proxy in front of the service.
+const userIDKey = "x-user-id"
+
+func userID(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, userIDKey); len(v) > 0 {
+		return v[0]
+	}
+	return ""
 }
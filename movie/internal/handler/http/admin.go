@@ -0,0 +1,206 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"movieapp.com/movie/internal/apikey"
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+)
+
+// defaultRotationGrace is how long a rotated API key keeps working
+// unless the rotation sets the grace period.
+const defaultRotationGrace = 7 * 24 * time.Hour
+
+// AdminHandler defines a movie admin HTTP handler. Requests must
+// carry the admin token as a bearer token.
+type AdminHandler struct {
+	keys  *apikey.Manager
+	audit *audit.Logger
+	token string
+}
+
+// NewAdmin creates a new movie admin HTTP handler recording the
+// changes of API keys in the audit log. With an empty token all
+// requests are rejected.
+func NewAdmin(keys *apikey.Manager, auditLog *audit.Logger, token string) *AdminHandler {
+	return &AdminHandler{keys, auditLog, token}
+}
+
+// apiKey defines an API key in admin responses, with its usage in
+// the requested period and, once issued, its secret form.
+type apiKey struct {
+	ID        string     `json:"id"`
+	Partner   string     `json:"partner"`
+	Quota     int64      `json:"quota"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	Period    string     `json:"period,omitempty"`
+	Usage     int64      `json:"usage"`
+	Key       string     `json:"key,omitempty"`
+}
+
+func newAPIKey(k *apikey.Key) *apiKey {
+	return &apiKey{ID: k.ID, Partner: k.Partner, Quota: k.Quota, CreatedAt: k.CreatedAt, ExpiresAt: k.ExpiresAt, RevokedAt: k.RevokedAt}
+}
+
+// APIKeys handles /admin/apikeys requests. POST issues a key to the
+// partner parameter with the optional monthly quota parameter,
+// returning its secret form once. GET lists the keys of the partner
+// with their usage in the period parameter (such as 2024-05), the
+// current month by default. DELETE revokes the key with the given
+// id.
+func (h *AdminHandler) APIKeys(w http.ResponseWriter, req *http.Request) {
+	if !h.authorized(req) {
+		problem.Write(w, req, problem.Unauthenticated, "invalid admin token")
+		return
+	}
+	switch req.Method {
+	case http.MethodPost:
+		h.issueAPIKey(w, req)
+	case http.MethodGet:
+		h.listAPIKeys(w, req)
+	case http.MethodDelete:
+		h.revokeAPIKey(w, req)
+	default:
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+	}
+}
+
+func (h *AdminHandler) issueAPIKey(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		Partner string `form:"partner" validate:"required"`
+		Quota   int64  `form:"quota" validate:"min=0"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	raw, key, err := h.keys.Issue(req.Context(), params.Partner, params.Quota)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	h.audit.Record(req.Context(), audit.Entry{Actor: audit.Admin, Action: "apikey.issue", Resource: key.ID, Details: map[string]string{"partner": key.Partner}})
+	res := newAPIKey(key)
+	res.Key = raw
+	writeAdmin(w, req, http.StatusCreated, res)
+}
+
+func (h *AdminHandler) listAPIKeys(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		Partner string `form:"partner" validate:"required"`
+		Period  string `form:"period"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	period := params.Period
+	if period == "" {
+		period = apikey.Period(time.Now())
+	} else if _, err := time.Parse("2006-01", period); err != nil {
+		problem.Error(w, req, request.Invalid("period", "must be a month such as 2024-01"))
+		return
+	}
+	keys, err := h.keys.List(req.Context(), params.Partner)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res := make([]*apiKey, 0, len(keys))
+	for _, k := range keys {
+		r := newAPIKey(k)
+		r.Period = period
+		if r.Usage, err = h.keys.Usage(req.Context(), k.ID, period); err != nil {
+			problem.Error(w, req, err)
+			return
+		}
+		res = append(res, r)
+	}
+	writeAdmin(w, req, http.StatusOK, res)
+}
+
+func (h *AdminHandler) revokeAPIKey(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		ID string `form:"id" validate:"required"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := h.keys.Revoke(req.Context(), params.ID); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	h.audit.Record(req.Context(), audit.Entry{Actor: audit.Admin, Action: "apikey.revoke", Resource: params.ID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateAPIKey handles POST /admin/apikeys/rotate requests,
+// issuing a replacement of the key with the given id and expiring
+// the key after the grace parameter, such as 24h, a week by
+// default.
+func (h *AdminHandler) RotateAPIKey(w http.ResponseWriter, req *http.Request) {
+	if !h.authorized(req) {
+		problem.Write(w, req, problem.Unauthenticated, "invalid admin token")
+		return
+	}
+	if req.Method != http.MethodPost {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	params := struct {
+		ID    string        `form:"id" validate:"required"`
+		Grace time.Duration `form:"grace" validate:"min=0s"`
+	}{Grace: defaultRotationGrace}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	raw, key, err := h.keys.Rotate(req.Context(), params.ID, params.Grace)
+	if err != nil && errors.Is(err, apikey.ErrInvalid) {
+		// Revoked and expired keys are not rotated.
+		problem.Write(w, req, problem.Conflict, err.Error())
+		return
+	} else if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	h.audit.Record(req.Context(), audit.Entry{Actor: audit.Admin, Action: "apikey.rotate", Resource: params.ID, Details: map[string]string{"replacement": key.ID, "grace": params.Grace.String()}})
+	res := newAPIKey(key)
+	res.Key = raw
+	writeAdmin(w, req, http.StatusCreated, res)
+}
+
+// Audit handles GET /admin/audit requests, returning the entries of
+// the audit log matching the filter in the query parameters as
+// audit.Logger.Handler.
+func (h *AdminHandler) Audit(w http.ResponseWriter, req *http.Request) {
+	if !h.authorized(req) {
+		problem.Write(w, req, problem.Unauthenticated, "invalid admin token")
+		return
+	}
+	h.audit.Handler().ServeHTTP(w, req)
+}
+
+func writeAdmin(w http.ResponseWriter, req *http.Request, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+func (h *AdminHandler) authorized(req *http.Request) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && h.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"movieapp.com/movie/internal/export"
+	"movieapp.com/pkg/problem"
+)
+
+// ExportHandler defines a user data export HTTP handler.
+type ExportHandler struct {
+	exporter *export.Exporter
+}
+
+// NewExport creates a new user data export HTTP handler.
+func NewExport(exporter *export.Exporter) *ExportHandler {
+	return &ExportHandler{exporter}
+}
+
+// Export handles POST /exports requests, exporting the data of the
+// authenticated user and returning the signed link to download it
+// with its expiration time.
+func (h *ExportHandler) Export(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	userID := userID(req)
+	if userID == "" {
+		problem.Write(w, req, problem.Unauthenticated, "")
+		return
+	}
+	link, err := h.exporter.Export(req.Context(), userID)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(link); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
@@ -2,13 +2,27 @@ package http
 
 import (
 	"encoding/json"
-	"errors"
-	"log"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
 
+	"movieapp.com/internal/budget"
+	"movieapp.com/internal/httputil"
+	metadatamodel "movieapp.com/metadata/pkg/model"
 	"movieapp.com/movie/internal/controller/movie"
+	"movieapp.com/movie/pkg/model"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
 )
 
+var logger = logging.New("handler/http")
+
 // Handler defines a movie handler.
 type Handler struct {
 	ctrl *movie.Controller
@@ -19,19 +33,372 @@ func New(ctrl *movie.Controller) *Handler {
 	return &Handler{ctrl}
 }
 
-// GetMovieDetails handles GET /movie requests.
+// userIDHeader is the header of the id of the authenticated
+// caller, set by the authenticating proxy in front of the service.
+const userIDHeader = "X-User-ID"
+
+// userID returns the id of the authenticated caller, empty if
+// anonymous. The header of the proxy is trusted only if the bearer
+// tokens of requests are not verified.
+func userID(req *http.Request) string {
+	if p := auth.FromContext(req.Context()); p != nil {
+		return p.Subject
+	}
+	if auth.Enforced(req.Context()) {
+		return ""
+	}
+	return req.Header.Get(userIDHeader)
+}
+
+// GetMovieDetails handles GET /movie requests with optional comma
+// separated fields to return, such as title,rating. The metadata
+// text is localized for the locale parameter or, if absent, the
+// Accept-Language header. Details requested by an authenticated
+// user include the user's own rating. Requests carrying the
+// current ETag in If-None-Match get a 304 without a body.
 func (h *Handler) GetMovieDetails(w http.ResponseWriter, req *http.Request) {
-	id := req.FormValue("id")
-	details, err := h.ctrl.Get(req.Context(), id)
-	if err != nil && errors.Is(err, movie.ErrNotFound) {
-		w.WriteHeader(http.StatusNotFound)
+	var params struct {
+		ID     string   `form:"id"`
+		Fields []string `form:"fields,comma"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	fields, err := parseFields(params.Fields)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	// The user's rating makes the details differ between users.
+	w.Header().Add("Vary", userIDHeader)
+	w.Header().Add("Vary", "Authorization")
+	w.Header().Add("Vary", "Accept-Language")
+	var details *model.MovieDetails
+	if userID := userID(req); userID != "" {
+		details, err = h.ctrl.GetForUser(req.Context(), params.ID, userID)
+	} else {
+		details, err = h.ctrl.Get(req.Context(), params.ID)
+	}
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	details.Localize(requestLocales(req)...)
+	if details.Metadata.Locale != "" {
+		w.Header().Set("Content-Language", details.Metadata.Locale)
+	}
+	etag := details.ETag()
+	w.Header().Set("ETag", etag)
+	if httputil.ETagMatches(req.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(fields.apply(details)); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetManyMovieDetails handles GET /movies requests with comma
+// separated movie ids and optional fields and locale as in
+// GetMovieDetails.
+func (h *Handler) GetManyMovieDetails(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		IDs    []string `form:"ids,comma" validate:"required"`
+		Fields []string `form:"fields,comma"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	fields, err := parseFields(params.Fields)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.GetMany(req.Context(), params.IDs)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	locales := requestLocales(req)
+	selected := make([]any, 0, len(res))
+	for _, d := range res {
+		d.Localize(locales...)
+		selected = append(selected, fields.apply(d))
+	}
+	if err := json.NewEncoder(w).Encode(selected); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// ListMovies handles GET /movies/list requests with an optional
+// page size and cursor, and optional fields and locale as in
+// GetMovieDetails. The cursor of the next page is returned in the
+// response and is empty on the last page.
+func (h *Handler) ListMovies(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		PageSize int      `form:"page_size" validate:"min=0"`
+		Cursor   string   `form:"cursor"`
+		Fields   []string `form:"fields,comma"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	fields, err := parseFields(params.Fields)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, next, err := h.ctrl.List(req.Context(), params.PageSize, params.Cursor)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	page := struct {
+		Movies     []any  `json:"movies"`
+		NextCursor string `json:"nextCursor,omitempty"`
+	}{make([]any, 0, len(res)), next}
+	locales := requestLocales(req)
+	for _, d := range res {
+		d.Localize(locales...)
+		page.Movies = append(page.Movies, fields.apply(d))
+	}
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// ExportMovieDetails handles GET /movies/export requests, streaming
+// the details of all movies of the catalog as a chunked JSON array
+// for partner exports. The request is not bounded by the budget
+// of the service, and an array left open tells the client that
+// the export failed midway.
+func (h *Handler) ExportMovieDetails(w http.ResponseWriter, req *http.Request) {
+	ctx := budget.Unbounded(req.Context())
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	first := true
+	err := h.ctrl.Export(ctx, func(res []*model.MovieDetails) error {
+		for _, d := range res {
+			sep := ","
+			if first {
+				sep, first = "[", false
+			}
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+			if err := enc.Encode(d); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil && first {
+		problem.Error(w, req, err)
 		return
 	} else if err != nil {
-		log.Printf("Repository get error: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		logger.ErrorContext(ctx, "Export error", "error", err)
+		return
+	}
+	if first {
+		io.WriteString(w, "[")
+	}
+	io.WriteString(w, "]\n")
+}
+
+// GetTrendingMovies handles GET /movies/trending requests with an
+// optional window duration such as 24h, an optional limit and an
+// optional locale as in GetMovieDetails.
+func (h *Handler) GetTrendingMovies(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		Window time.Duration `form:"window" validate:"min=0s"`
+		Limit  int           `form:"limit" validate:"min=0"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.Trending(req.Context(), params.Window, params.Limit)
+	if err != nil {
+		problem.Error(w, req, err)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(details); err != nil {
-		log.Printf("Response encode error: %v\n", err)
+	locales := requestLocales(req)
+	for _, m := range res {
+		m.Localize(locales...)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetMovieRecommendations handles GET /movies/{id}/recommendations
+// requests with an optional limit and locale as in
+// GetMovieDetails.
+func (h *Handler) GetMovieRecommendations(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(req.URL.Path, "/movies/", "/recommendations")
+	if !ok {
+		problem.Write(w, req, problem.NotFound, "")
+		return
+	}
+	var params struct {
+		Limit int `form:"limit" validate:"min=0"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.MovieRecommendations(req.Context(), id, params.Limit)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	locales := requestLocales(req)
+	for _, m := range res {
+		m.Localize(locales...)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetUserRecommendations handles GET /users/{id}/recommendations
+// requests with an optional limit and locale as in
+// GetMovieDetails.
+func (h *Handler) GetUserRecommendations(w http.ResponseWriter, req *http.Request) {
+	userID, ok := pathID(req.URL.Path, "/users/", "/recommendations")
+	if !ok {
+		problem.Write(w, req, problem.NotFound, "")
+		return
+	}
+	var params struct {
+		Limit int `form:"limit" validate:"min=0"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.UserRecommendations(req.Context(), userID, params.Limit)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	locales := requestLocales(req)
+	for _, m := range res {
+		m.Localize(locales...)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// pathID returns the id in a path of the form prefix{id}suffix.
+func pathID(path string, prefix string, suffix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return "", false
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	if id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
+
+// requestLocales returns the preferred locales of a request, the
+// locale parameter or else the Accept-Language header.
+func requestLocales(req *http.Request) []string {
+	if l := req.FormValue("locale"); l != "" {
+		return []string{l}
+	}
+	return metadatamodel.ParseAcceptLanguage(req.Header.Get("Accept-Language"))
+}
+
+// fieldSet selects the fields of movie details in a response by
+// their JSON names. Metadata fields are selected by their own
+// names, such as title, or prefixed, such as metadata.title. The
+// metadata id is kept so that clients can tell the movies apart,
+// and the degraded downstreams and partial status so that they can
+// tell a missing rating from an unselected one.
+type fieldSet struct {
+	details  map[string]bool
+	metadata map[string]bool
+}
+
+var (
+	detailsFields  = jsonNames(reflect.TypeOf(model.MovieDetails{}))
+	metadataFields = jsonNames(reflect.TypeOf(metadatamodel.Metadata{}))
+)
+
+// parseFields parses the fields to select, returning nil to select
+// all fields if there are none.
+func parseFields(names []string) (*fieldSet, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	fs := &fieldSet{details: map[string]bool{"degraded": true, "partial": true, "omitted": true}, metadata: map[string]bool{"id": true}}
+	for _, f := range names {
+		name, prefixed := strings.CutPrefix(strings.TrimSpace(f), "metadata.")
+		switch {
+		case !prefixed && detailsFields[name]:
+			fs.details[name] = true
+		case metadataFields[name]:
+			fs.metadata[name] = true
+		default:
+			return nil, request.Invalid("fields", fmt.Sprintf("unknown field %q", f))
+		}
+	}
+	return fs, nil
+}
+
+// apply returns the selected fields of movie details for encoding.
+func (fs *fieldSet) apply(d *model.MovieDetails) any {
+	if fs == nil {
+		return d
+	}
+	var v, m map[string]json.RawMessage
+	if err := remarshal(d, &v); err != nil {
+		logger.Error("Field selection error", "error", err)
+		return d
+	}
+	maps.DeleteFunc(v, func(k string, _ json.RawMessage) bool {
+		return !fs.details[k] && k != "metadata"
+	})
+	if fs.details["metadata"] {
+		return v
+	}
+	if err := remarshal(v["metadata"], &m); err != nil {
+		logger.Error("Field selection error", "error", err)
+		return d
+	}
+	maps.DeleteFunc(m, func(k string, _ json.RawMessage) bool {
+		return !fs.metadata[k]
+	})
+	v["metadata"], _ = json.Marshal(m)
+	return v
+}
+
+func remarshal(from any, to any) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+// jsonNames returns the JSON names of the fields of a struct type.
+func jsonNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
 	}
+	return names
 }
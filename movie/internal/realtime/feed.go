@@ -0,0 +1,303 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"movieapp.com/internal/budget"
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/tenant"
+	ratingmodel "movieapp.com/rating/pkg/model"
+)
+
+// Catalog event types.
+const (
+	TypeMetadataCreated = "metadata.created"
+	TypeMetadataUpdated = "metadata.updated"
+	TypeMetadataDeleted = "metadata.deleted"
+	TypeRatingAggregate = "rating.aggregate"
+	// TypeReset tells clients resuming from an event no longer kept
+	// that they missed events and must refetch what they show.
+	TypeReset = "reset"
+)
+
+// ratingTimeout bounds the calls fetching the aggregated ratings of
+// the rated movies.
+const ratingTimeout = 2 * time.Second
+
+// retry is the reconnection delay suggested to clients.
+const retry = 3 * time.Second
+
+type ratingGateway interface {
+	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
+}
+
+// Event defines a catalog update streamed to clients.
+type Event struct {
+	id      uint64
+	Type    string `json:"type"`
+	MovieID string `json:"movieId"`
+	// Metadata is set on metadata events.
+	Metadata *metadatamodel.Metadata `json:"metadata,omitempty"`
+	// Rating is the aggregated rating of the movie, set on rating
+	// events.
+	Rating    *float64  `json:"rating,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FeedConfig defines the settings of the stream of catalog updates.
+type FeedConfig struct {
+	// Enabled serves the stream.
+	Enabled bool `yaml:"enabled"`
+	// History is the number of recent events kept per tenant for
+	// clients resuming the stream.
+	History int `yaml:"history"`
+	// Heartbeat is the interval of the comments keeping idle streams
+	// open through proxies.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+}
+
+// DefaultFeedConfig returns the default config, with the stream
+// disabled.
+func DefaultFeedConfig() FeedConfig {
+	return FeedConfig{History: 1000, Heartbeat: 30 * time.Second}
+}
+
+// Validate validates the config.
+func (c *FeedConfig) Validate() error {
+	if c.History <= 0 || c.Heartbeat <= 0 {
+		return errors.New("history, heartbeat: not positive")
+	}
+	return nil
+}
+
+// Feed streams the metadata and aggregated rating changes of the
+// catalog to web clients and partners as server-sent events, for
+// those that cannot use WebSockets. The recent events of each
+// tenant are kept so that clients reconnecting with the
+// Last-Event-ID header resume where they left off. Event ids are
+// only known to the instance that sent them, so clients resuming
+// on another instance are sent a reset event instead. A nil feed
+// publishes nothing.
+type Feed struct {
+	cfg     FeedConfig
+	ratings ratingGateway
+	// epoch tells the ids of the events of this instance apart
+	// from those of other instances and previous runs.
+	epoch   string
+	mu      sync.Mutex
+	tenants map[string]*history
+	done    chan struct{}
+	closed  bool
+}
+
+// history defines the recent events of a tenant.
+type history struct {
+	// events is a ring of the last events, the event with id n at
+	// index n%len(events).
+	events []*Event
+	// next is the id of the next event, from 1.
+	next uint64
+	// notify is closed and replaced on each event, waking up the
+	// streams.
+	notify chan struct{}
+}
+
+// NewFeed creates a feed fetching the aggregated ratings of the
+// rated movies from the gateway.
+func NewFeed(cfg FeedConfig, ratings ratingGateway) *Feed {
+	return &Feed{
+		cfg:     cfg,
+		ratings: ratings,
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		tenants: map[string]*history{},
+		done:    make(chan struct{}),
+	}
+}
+
+// PublishMetadataEvent publishes the metadata changes.
+func (f *Feed) PublishMetadataEvent(ctx context.Context, e *metadatamodel.Event) {
+	if f == nil {
+		return
+	}
+	var t string
+	switch e.Type {
+	case metadatamodel.EventTypeCreated:
+		t = TypeMetadataCreated
+	case metadatamodel.EventTypeUpdated:
+		t = TypeMetadataUpdated
+	case metadatamodel.EventTypeDeleted:
+		t = TypeMetadataDeleted
+	default:
+		return
+	}
+	f.append(ctx, &Event{Type: t, MovieID: e.MovieID, Metadata: e.Metadata, Timestamp: e.Timestamp})
+}
+
+// PublishRatingEvent publishes the aggregated rating of the movie
+// of a rating change.
+func (f *Feed) PublishRatingEvent(ctx context.Context, e *ratingmodel.RatingEvent) {
+	if f == nil || e.RecordType != ratingmodel.RecordTypeMovie {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, ratingTimeout)
+	defer cancel()
+	rating, err := f.ratings.GetAggregatedRating(ctx, e.RecordID, e.RecordType)
+	if err != nil && !errors.Is(err, gateway.ErrNotFound) {
+		logger.ErrorContext(ctx, "Aggregated rating error", "movieId", e.RecordID, "error", err)
+		return
+	}
+	// Movies whose ratings all moved away are rated 0.
+	f.append(ctx, &Event{Type: TypeRatingAggregate, MovieID: string(e.RecordID), Rating: &rating, Timestamp: e.Timestamp})
+}
+
+// append appends an event to the history of the tenant of the
+// context and wakes up its streams.
+func (f *Feed) append(ctx context.Context, e *Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := f.history(tenant.FromContext(ctx))
+	e.id = h.next
+	h.events[e.id%uint64(len(h.events))] = e
+	h.next++
+	close(h.notify)
+	h.notify = make(chan struct{})
+}
+
+// history returns the history of a tenant. The caller holds the
+// mutex.
+func (f *Feed) history(id string) *history {
+	h, ok := f.tenants[id]
+	if !ok {
+		h = &history{events: make([]*Event, f.cfg.History), next: 1, notify: make(chan struct{})}
+		f.tenants[id] = h
+	}
+	return h
+}
+
+// read returns the events of a tenant after an id, whether events
+// after it are no longer kept, and the channel closed on the next
+// event.
+func (f *Feed) read(id string, after uint64) ([]*Event, bool, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := f.history(id)
+	oldest := uint64(1)
+	if h.next > uint64(len(h.events)) {
+		oldest = h.next - uint64(len(h.events))
+	}
+	after = min(after, h.next-1)
+	reset := after+1 < oldest
+	if reset {
+		after = oldest - 1
+	}
+	var events []*Event
+	for n := after + 1; n < h.next; n++ {
+		events = append(events, h.events[n%uint64(len(h.events))])
+	}
+	return events, reset, h.notify
+}
+
+// last returns the id of the last event of a tenant, 0 if none.
+func (f *Feed) last(id string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.history(id).next - 1
+}
+
+// ServeHTTP streams the events of the tenant of a GET request
+// published from now on, or after the event of its Last-Event-ID
+// header.
+func (f *Feed) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+		return
+	}
+	rc := http.NewResponseController(w)
+	// Streams are not bounded by the budget of the service.
+	ctx := budget.Unbounded(req.Context())
+	id := tenant.FromContext(ctx)
+	after, resumed := f.parseID(req.Header.Get("Last-Event-ID"))
+	reset := !resumed && req.Header.Get("Last-Event-ID") != ""
+	if !resumed {
+		after = f.last(id)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds())
+	if reset {
+		f.write(w, &Event{id: after, Type: TypeReset, Timestamp: time.Now().UTC()})
+	}
+	heartbeat := time.NewTicker(f.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		events, missed, notify := f.read(id, after)
+		if missed {
+			// The events before the kept ones were missed.
+			f.write(w, &Event{id: events[0].id - 1, Type: TypeReset, Timestamp: time.Now().UTC()})
+		}
+		for _, e := range events {
+			if err := f.write(w, e); err != nil {
+				return
+			}
+			after = e.id
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		select {
+		case <-notify:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// write writes an event in the event stream format.
+func (f *Feed) write(w http.ResponseWriter, e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s-%d\nevent: %s\ndata: %s\n\n", f.epoch, e.id, e.Type, data)
+	return err
+}
+
+// parseID returns the event id of a Last-Event-ID header, and
+// whether it was sent by this instance.
+func (f *Feed) parseID(header string) (uint64, bool) {
+	epoch, n, ok := strings.Cut(header, "-")
+	if !ok || epoch != f.epoch {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(n, 10, 64)
+	return id, err == nil
+}
+
+// Close ends the streams, so that clients reconnect to other
+// instances before the servers drain.
+func (f *Feed) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		close(f.done)
+	}
+	return nil
+}
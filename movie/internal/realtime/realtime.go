@@ -0,0 +1,355 @@
+// Package realtime pushes live updates, such as rating changes and
+// newly added titles, to the clients subscribed to them over
+// WebSockets, and streams the changes of the catalog as server-sent
+// events.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/tenant"
+	ratingmodel "movieapp.com/rating/pkg/model"
+)
+
+var logger = logging.New("realtime")
+
+// hubCounts counts the open connections, the messages sent and the
+// connections closed for falling behind, published at /debug/vars.
+var hubCounts = expvar.NewMap("realtime")
+
+// TitlesTopic is the topic of the titles added to the catalog.
+const TitlesTopic = "titles"
+
+// ratingsPrefix prefixes the topics of the rating changes of
+// movies.
+const ratingsPrefix = "ratings:"
+
+// RatingsTopic returns the topic of the rating changes of a movie.
+func RatingsTopic(movieID string) string {
+	return ratingsPrefix + movieID
+}
+
+// maxRequestBytes bounds the messages clients send.
+const maxRequestBytes = 4 << 10
+
+// Message types.
+const (
+	TypeTitleAdded    = "title.added"
+	TypeRatingChanged = "rating.changed"
+	TypeSubscribed    = "subscribed"
+	TypeUnsubscribed  = "unsubscribed"
+	TypeHeartbeat     = "heartbeat"
+	TypeError         = "error"
+)
+
+// Message defines a message sent to clients.
+type Message struct {
+	Type    string `json:"type"`
+	Topic   string `json:"topic,omitempty"`
+	MovieID string `json:"movieId,omitempty"`
+	Title   string `json:"title,omitempty"`
+	// Rating is the rating put by a user, unset when ratings moved
+	// between movies.
+	Rating    ratingmodel.RatingValue `json:"rating,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+	Timestamp time.Time               `json:"timestamp"`
+}
+
+// request defines a message clients send to manage their
+// subscriptions, such as {"action":"subscribe","topic":"titles"}.
+type request struct {
+	Action string `json:"action"`
+	Topic  string `json:"topic"`
+}
+
+// Config defines the settings of the WebSocket endpoint.
+type Config struct {
+	// Enabled serves the endpoint.
+	Enabled bool `yaml:"enabled"`
+	// Buffer is the number of messages queued per connection.
+	// Connections falling further behind are closed, as clients
+	// missing updates must refetch what they show.
+	Buffer int `yaml:"buffer"`
+	// MaxSubscriptions is the number of topics a connection may
+	// subscribe to.
+	MaxSubscriptions int `yaml:"maxSubscriptions"`
+	// WriteTimeout is the time to send a message to a client.
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// Heartbeat is the interval of the heartbeat messages keeping
+	// idle connections open through proxies.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+}
+
+// DefaultConfig returns the default config, with the endpoint
+// disabled.
+func DefaultConfig() Config {
+	return Config{
+		Buffer:           64,
+		MaxSubscriptions: 100,
+		WriteTimeout:     10 * time.Second,
+		Heartbeat:        30 * time.Second,
+	}
+}
+
+// Validate validates the config.
+func (c *Config) Validate() error {
+	if c.Buffer <= 0 || c.MaxSubscriptions <= 0 || c.WriteTimeout <= 0 || c.Heartbeat <= 0 {
+		return errors.New("buffer, maxSubscriptions, writeTimeout, heartbeat: not positive")
+	}
+	return nil
+}
+
+// Hub tracks the connections of the clients and the topics they
+// subscribed to, and sends them the messages published to their
+// topics. Topics are scoped to the tenant of the connections. A nil
+// hub publishes nothing.
+type Hub struct {
+	cfg Config
+	mu  sync.RWMutex
+	// topics maps the tenant scoped topics to their connections.
+	topics map[string]map[*conn]struct{}
+	conns  map[*conn]struct{}
+	closed bool
+}
+
+// conn defines the connection of a client.
+type conn struct {
+	ws   *websocket.Conn
+	send chan *Message
+	// topics are the tenant scoped topics of the connection,
+	// guarded by the mutex of the hub.
+	topics    map[string]struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewHub creates a hub.
+func NewHub(cfg Config) *Hub {
+	return &Hub{cfg: cfg, topics: map[string]map[*conn]struct{}{}, conns: map[*conn]struct{}{}}
+}
+
+// Handler returns the WebSocket handler of the hub. Browsers are
+// only allowed to connect from the origin of the API or the
+// origins allowOrigin allows, so that other sites cannot open
+// connections on behalf of their visitors.
+func (h *Hub) Handler(allowOrigin func(origin string) bool) http.Handler {
+	return websocket.Server{
+		Handshake: func(_ *websocket.Config, req *http.Request) error {
+			origin := req.Header.Get("Origin")
+			if origin == "" || allowOrigin(origin) {
+				return nil
+			}
+			if u, err := url.Parse(origin); err == nil && u.Host == req.Host {
+				return nil
+			}
+			return errors.New("origin not allowed")
+		},
+		Handler: h.serve,
+	}
+}
+
+// serve reads the subscription requests of a connection until it
+// closes.
+func (h *Hub) serve(ws *websocket.Conn) {
+	// The connection outlives the deadlines of the request, its
+	// context only scopes the topics to its tenant.
+	ctx := ws.Request().Context()
+	_ = ws.SetDeadline(time.Time{})
+	ws.MaxPayloadBytes = maxRequestBytes
+	c := &conn{ws: ws, send: make(chan *Message, h.cfg.Buffer), topics: map[string]struct{}{}, done: make(chan struct{})}
+	if !h.add(c) {
+		ws.Close()
+		return
+	}
+	defer h.remove(c)
+	go h.write(c)
+	for {
+		var r request
+		err := websocket.JSON.Receive(ws, &r)
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+			h.push(c, &Message{Type: TypeError, Error: "invalid request"})
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.DebugContext(ctx, "WebSocket receive error", "error", err)
+			}
+			c.close()
+			return
+		}
+		h.handle(ctx, c, r)
+	}
+}
+
+// handle handles a subscription request.
+func (h *Hub) handle(ctx context.Context, c *conn, r request) {
+	if r.Topic != TitlesTopic && (!strings.HasPrefix(r.Topic, ratingsPrefix) || len(r.Topic) == len(ratingsPrefix)) {
+		h.push(c, &Message{Type: TypeError, Topic: r.Topic, Error: "unknown topic"})
+		return
+	}
+	key := tenant.Key(ctx, r.Topic)
+	switch r.Action {
+	case "subscribe":
+		h.mu.Lock()
+		if _, ok := c.topics[key]; !ok && len(c.topics) >= h.cfg.MaxSubscriptions {
+			h.mu.Unlock()
+			h.push(c, &Message{Type: TypeError, Topic: r.Topic, Error: "too many subscriptions"})
+			return
+		}
+		c.topics[key] = struct{}{}
+		if h.topics[key] == nil {
+			h.topics[key] = map[*conn]struct{}{}
+		}
+		h.topics[key][c] = struct{}{}
+		h.mu.Unlock()
+		h.push(c, &Message{Type: TypeSubscribed, Topic: r.Topic})
+	case "unsubscribe":
+		h.mu.Lock()
+		h.unsubscribe(c, key)
+		h.mu.Unlock()
+		h.push(c, &Message{Type: TypeUnsubscribed, Topic: r.Topic})
+	default:
+		h.push(c, &Message{Type: TypeError, Topic: r.Topic, Error: "unknown action"})
+	}
+}
+
+// write sends the queued messages of a connection, and heartbeats
+// while idle, until it closes.
+func (h *Hub) write(c *conn) {
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var m *Message
+		select {
+		case <-c.done:
+			return
+		case m = <-c.send:
+		case t := <-heartbeat.C:
+			m = &Message{Type: TypeHeartbeat, Timestamp: t.UTC()}
+		}
+		_ = c.ws.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout))
+		if err := websocket.JSON.Send(c.ws, m); err != nil {
+			c.close()
+			return
+		}
+		hubCounts.Add("sent", 1)
+	}
+}
+
+// Publish sends a message to the connections subscribed to its
+// topic in the tenant of the context.
+func (h *Hub) Publish(ctx context.Context, m *Message) {
+	if h == nil {
+		return
+	}
+	// Stamped before it is shared by the connections.
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now().UTC()
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.topics[tenant.Key(ctx, m.Topic)] {
+		h.push(c, m)
+	}
+}
+
+// push queues a message of a connection, closing it if its queue
+// is full. Messages without a timestamp are stamped now.
+func (h *Hub) push(c *conn, m *Message) {
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now().UTC()
+	}
+	select {
+	case c.send <- m:
+	default:
+		hubCounts.Add("slow", 1)
+		c.close()
+	}
+}
+
+// PublishMetadataEvent publishes the titles added to the catalog.
+func (h *Hub) PublishMetadataEvent(ctx context.Context, e *metadatamodel.Event) {
+	if e.Type != metadatamodel.EventTypeCreated || e.Metadata == nil {
+		return
+	}
+	h.Publish(ctx, &Message{Type: TypeTitleAdded, Topic: TitlesTopic, MovieID: e.MovieID, Title: e.Metadata.Title, Timestamp: e.Timestamp})
+}
+
+// PublishRatingEvent publishes the rating changes of movies, without
+// the users who rated.
+func (h *Hub) PublishRatingEvent(ctx context.Context, e *ratingmodel.RatingEvent) {
+	if e.RecordType != ratingmodel.RecordTypeMovie {
+		return
+	}
+	m := &Message{Type: TypeRatingChanged, Topic: RatingsTopic(string(e.RecordID)), MovieID: string(e.RecordID), Timestamp: e.Timestamp}
+	if e.Type == ratingmodel.RatingEventTypePut {
+		m.Rating = e.Value
+	}
+	h.Publish(ctx, m)
+}
+
+// Close closes the connections, so that clients reconnect to other
+// instances before the servers drain.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for c := range h.conns {
+		c.close()
+	}
+	return nil
+}
+
+func (h *Hub) add(c *conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	hubCounts.Add("connections", 1)
+	return true
+}
+
+func (h *Hub) remove(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key := range c.topics {
+		h.unsubscribe(c, key)
+	}
+	delete(h.conns, c)
+	hubCounts.Add("connections", -1)
+}
+
+// unsubscribe removes a topic of a connection. The caller holds the
+// mutex.
+func (h *Hub) unsubscribe(c *conn, key string) {
+	delete(c.topics, key)
+	delete(h.topics[key], c)
+	if len(h.topics[key]) == 0 {
+		delete(h.topics, key)
+	}
+}
+
+// close closes the connection, ending its reads and writes.
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
@@ -0,0 +1,203 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/movie/internal/gateway"
+	"movieapp.com/pkg/logging"
+	ratingmodel "movieapp.com/rating/pkg/model"
+)
+
+var logger = logging.New("recommendation")
+
+// Tuning of the heuristic strategy.
+const (
+	// likedRating is the lowest rating counted as liking a movie.
+	likedRating = 4
+	// maxFans bounds the users whose ratings are read to find
+	// movies co-rated with a movie.
+	maxFans = 20
+	// maxSeeds bounds the liked movies of a user whose
+	// recommendations are combined.
+	maxSeeds = 5
+	// Weights of the similarity of genres and tags and of the
+	// co-ratings, each normalized to at most 1.
+	similarWeight  = 0.5
+	coRatingWeight = 0.5
+	// fanConcurrency bounds the concurrent reads of fan ratings.
+	fanConcurrency = 4
+	trendingWindow = 7 * 24 * time.Hour
+)
+
+type metadataGateway interface {
+	GetSimilar(ctx context.Context, id string, limit int) ([]metadatamodel.SimilarMovie, error)
+}
+
+type ratingGateway interface {
+	ListRecordRatings(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) ([]ratingmodel.Rating, error)
+	ListUserRatings(ctx context.Context, userID ratingmodel.UserID, recordType ratingmodel.RecordType) ([]ratingmodel.Rating, error)
+	GetTrending(ctx context.Context, recordType ratingmodel.RecordType, window time.Duration, limit int) ([]ratingmodel.TrendingRecord, error)
+}
+
+// Heuristic recommends movies sharing genres and tags with a movie,
+// as scored by the metadata service, and movies liked by the users
+// who liked it. Users are recommended what their liked movies
+// recommend, or what is trending if they have not liked any.
+type Heuristic struct {
+	metadata metadataGateway
+	rating   ratingGateway
+}
+
+// NewHeuristic creates a heuristic recommendation strategy.
+func NewHeuristic(metadata metadataGateway, rating ratingGateway) *Heuristic {
+	return &Heuristic{metadata, rating}
+}
+
+// ForMovie returns up to limit movies similar to or co-rated with
+// the given one, or gateway.ErrNotFound if it does not exist. If
+// the ratings cannot be read, the movies are recommended by
+// similarity only.
+func (h *Heuristic) ForMovie(ctx context.Context, movieID string, limit int) ([]Recommendation, error) {
+	scores, err := h.movieScores(ctx, movieID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return top(scores, limit), nil
+}
+
+func (h *Heuristic) movieScores(ctx context.Context, movieID string, limit int) (map[string]float64, error) {
+	scores := map[string]float64{}
+	similar, err := h.metadata.GetSimilar(ctx, movieID, limit)
+	if err != nil {
+		return nil, err
+	}
+	var maxScore float64
+	for _, s := range similar {
+		maxScore = max(maxScore, s.Score)
+	}
+	for _, s := range similar {
+		if maxScore > 0 {
+			scores[s.Metadata.ID] += similarWeight * s.Score / maxScore
+		}
+	}
+	coRated, err := h.coRated(ctx, movieID)
+	if err != nil {
+		logger.ErrorContext(ctx, "Co-rating error", "movie_id", movieID, "error", err)
+	}
+	var maxCount int
+	for _, n := range coRated {
+		maxCount = max(maxCount, n)
+	}
+	for id, n := range coRated {
+		scores[id] += coRatingWeight * float64(n) / float64(maxCount)
+	}
+	delete(scores, movieID)
+	return scores, nil
+}
+
+// coRated counts the other movies liked by the users who liked
+// the given one.
+func (h *Heuristic) coRated(ctx context.Context, movieID string) (map[string]int, error) {
+	ratings, err := h.rating.ListRecordRatings(ctx, ratingmodel.RecordID(movieID), ratingmodel.RecordTypeMovie)
+	if err != nil {
+		return nil, err
+	}
+	var fans []ratingmodel.UserID
+	seen := map[ratingmodel.UserID]bool{}
+	for _, r := range ratings {
+		if r.Value >= likedRating && !seen[r.UserID] && len(fans) < maxFans {
+			seen[r.UserID] = true
+			fans = append(fans, r.UserID)
+		}
+	}
+	var mu sync.Mutex
+	counts := map[string]int{}
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(fanConcurrency)
+	for _, fan := range fans {
+		fan := fan
+		g.Go(func() error {
+			liked, err := h.rating.ListUserRatings(ctx, fan, ratingmodel.RecordTypeMovie)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range liked {
+				if r.Value >= likedRating {
+					counts[string(r.RecordID)]++
+				}
+			}
+			return nil
+		})
+	}
+	return counts, g.Wait()
+}
+
+// ForUser returns up to limit movies combining the recommendations
+// for the movies the user liked, or trending movies if the user
+// has not liked any.
+func (h *Heuristic) ForUser(ctx context.Context, userID string, limit int) ([]Recommendation, error) {
+	ratings, err := h.rating.ListUserRatings(ctx, ratingmodel.UserID(userID), ratingmodel.RecordTypeMovie)
+	if err != nil {
+		return nil, err
+	}
+	rated := map[string]bool{}
+	var seeds []string
+	for _, r := range ratings {
+		rated[string(r.RecordID)] = true
+		if r.Value >= likedRating && len(seeds) < maxSeeds {
+			seeds = append(seeds, string(r.RecordID))
+		}
+	}
+	scores := map[string]float64{}
+	if len(seeds) == 0 {
+		trending, err := h.rating.GetTrending(ctx, ratingmodel.RecordTypeMovie, trendingWindow, limit+len(rated))
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range trending {
+			scores[string(t.RecordID)] = t.Score
+		}
+	}
+	for _, seed := range seeds {
+		seedScores, err := h.movieScores(ctx, seed, limit)
+		if err != nil && errors.Is(err, gateway.ErrNotFound) {
+			// The movie was removed after the user rated it.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for id, s := range seedScores {
+			scores[id] += s
+		}
+	}
+	for id := range rated {
+		delete(scores, id)
+	}
+	return top(scores, limit), nil
+}
+
+// top returns the limit highest scored movies.
+func top(scores map[string]float64, limit int) []Recommendation {
+	res := make([]Recommendation, 0, len(scores))
+	for id, s := range scores {
+		res = append(res, Recommendation{id, s})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].MovieID < res[j].MovieID
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
@@ -0,0 +1,75 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"expvar"
+
+	"movieapp.com/movie/internal/gateway"
+	recommendationmodel "movieapp.com/recommendation/pkg/model"
+)
+
+var fallbacks = expvar.NewMap("recommendation_fallbacks")
+
+type recommendationGateway interface {
+	RecommendForMovie(ctx context.Context, movieID string, limit int) ([]recommendationmodel.Recommendation, error)
+	RecommendForUser(ctx context.Context, userID string, limit int) ([]recommendationmodel.Recommendation, error)
+}
+
+// Model recommends movies by the model precomputed offline and
+// served by the recommendation service. The movies and users the
+// model does not know yet, and all of them while the service is
+// unavailable, are recommended by a fallback strategy such as the
+// heuristic one.
+type Model struct {
+	gateway  recommendationGateway
+	fallback Strategy
+}
+
+// NewModel creates a model recommendation strategy.
+func NewModel(gateway recommendationGateway, fallback Strategy) *Model {
+	return &Model{gateway, fallback}
+}
+
+// ForMovie returns up to limit movies recommended after the given
+// one by the model, or by the fallback strategy.
+func (m *Model) ForMovie(ctx context.Context, movieID string, limit int) ([]Recommendation, error) {
+	recs, err := m.gateway.RecommendForMovie(ctx, movieID, limit)
+	if err != nil && ctx.Err() == nil {
+		m.logFallback(ctx, "movie", err)
+		return m.fallback.ForMovie(ctx, movieID, limit)
+	} else if err != nil {
+		return nil, err
+	}
+	return fromModel(recs), nil
+}
+
+// ForUser returns up to limit movies recommended to the user by the
+// model, or by the fallback strategy.
+func (m *Model) ForUser(ctx context.Context, userID string, limit int) ([]Recommendation, error) {
+	recs, err := m.gateway.RecommendForUser(ctx, userID, limit)
+	if err != nil && ctx.Err() == nil {
+		m.logFallback(ctx, "user", err)
+		return m.fallback.ForUser(ctx, userID, limit)
+	} else if err != nil {
+		return nil, err
+	}
+	return fromModel(recs), nil
+}
+
+// logFallback counts a fallback, logging it unless the model does
+// not know the movie or user.
+func (m *Model) logFallback(ctx context.Context, kind string, err error) {
+	fallbacks.Add(kind, 1)
+	if !errors.Is(err, gateway.ErrNotFound) {
+		logger.WarnContext(ctx, "Model recommendation error, falling back", "kind", kind, "error", err)
+	}
+}
+
+func fromModel(recs []recommendationmodel.Recommendation) []Recommendation {
+	res := make([]Recommendation, 0, len(recs))
+	for _, r := range recs {
+		res = append(res, Recommendation{r.MovieID, r.Score})
+	}
+	return res
+}
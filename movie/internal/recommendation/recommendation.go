@@ -0,0 +1,23 @@
+package recommendation
+
+import "context"
+
+// Recommendation defines a movie recommended with a score, higher
+// scores recommending more strongly. Scores are only comparable
+// within the results of a single call.
+type Recommendation struct {
+	MovieID string  `json:"movieId"`
+	Score   float64 `json:"score"`
+}
+
+// Strategy defines a way of recommending movies. Implementations
+// may build on the other movie services, like the heuristic one,
+// or call an external model.
+type Strategy interface {
+	// ForMovie returns up to limit movies to watch after the
+	// given one, excluding it, by descending score.
+	ForMovie(ctx context.Context, movieID string, limit int) ([]Recommendation, error)
+	// ForUser returns up to limit movies the user has not rated
+	// yet, by descending score.
+	ForUser(ctx context.Context, userID string, limit int) ([]Recommendation, error)
+}
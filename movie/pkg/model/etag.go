@@ -0,0 +1,30 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// ETag returns a weak entity tag over the metadata version and
+// locale, the ratings, the watchlist and the similar titles of the
+// movie details, suitable for conditional requests. It is weak
+// since it does not cover every byte of the response, only the
+// versions it is built from.
+func (d *MovieDetails) ETag() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%v\x00", d.Metadata.ID, d.Metadata.Version, d.Metadata.UpdatedAt.Format(time.RFC3339Nano), d.Metadata.Locale, d.Degraded)
+	if d.Rating != nil {
+		fmt.Fprintf(h, "%g", *d.Rating)
+	}
+	h.Write([]byte{0})
+	if d.UserRating != nil {
+		fmt.Fprintf(h, "%d", *d.UserRating)
+	}
+	fmt.Fprintf(h, "\x00%t", d.InWatchlist)
+	for _, s := range d.Similar {
+		fmt.Fprintf(h, "\x00%s\x00%s\x00%g", s.ID, s.Title, s.Score)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
@@ -4,6 +4,89 @@ import "movieapp.com/metadata/pkg/model"
 
 // MovieDetails includes movie metadata its aggregated rating.
 type MovieDetails struct {
-	Rating   *float64       `json:"rating:omitEmpty"`
+	Rating   *float64       `json:"rating,omitempty"`
 	Metadata model.Metadata `json:"metadata"`
+	// Degraded lists the downstreams whose data is missing
+	// because they failed.
+	Degraded []string `json:"degraded,omitempty"`
+	// Partial is set when data is missing, and Omitted tells which
+	// data and why, so that clients can retry for it alone.
+	Partial bool       `json:"partial,omitempty"`
+	Omitted []Omission `json:"omitted,omitempty"`
+	// UserRating is the rating of the requesting user, set only
+	// on details requested by an authenticated user who rated
+	// the movie.
+	UserRating *int `json:"userRating,omitempty"`
+	// InWatchlist is set on details requested by an authenticated
+	// user who has the movie in their watchlist, when the watchlist
+	// enricher is registered.
+	InWatchlist bool `json:"inWatchlist,omitempty"`
+	// Similar lists the titles most similar to the movie, set only
+	// when the similar titles enricher is registered.
+	Similar []SimilarTitle `json:"similar,omitempty"`
+}
+
+// Localize resolves the metadata text of the details for the
+// first of the preferred locales that has a localization, falling
+// back like the metadata service. Without preferred locales the
+// details keep all localizations.
+func (d *MovieDetails) Localize(preferred ...string) {
+	if len(preferred) > 0 {
+		d.Metadata = *d.Metadata.Localize(preferred...)
+	}
+}
+
+// Omission reasons.
+const (
+	// OmissionTimeout is a downstream that did not respond in
+	// time.
+	OmissionTimeout = "timeout"
+	// OmissionUnavailable is a downstream that is overloaded, down
+	// or behind an open circuit breaker.
+	OmissionUnavailable = "unavailable"
+	// OmissionError is any other failure of a downstream.
+	OmissionError = "error"
+)
+
+// Omission defines data missing from movie details because a
+// downstream failed.
+type Omission struct {
+	// Component names the missing data, as listed in Degraded.
+	Component string `json:"component"`
+	Reason    string `json:"reason"`
+	// Retryable reports whether a retry may soon return the data.
+	Retryable bool `json:"retryable"`
+}
+
+// SimilarTitle defines a movie similar to another.
+type SimilarTitle struct {
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
+// TrendingMovie defines the details of a movie trending by its
+// recent ratings.
+type TrendingMovie struct {
+	MovieDetails
+	// Count is the number of ratings within the trending window.
+	Count int `json:"count"`
+	// Score weighs the ratings by their age.
+	Score float64 `json:"score"`
+}
+
+// RecommendedMovie defines the details of a recommended movie.
+type RecommendedMovie struct {
+	MovieDetails
+	// Score ranks the recommendations of a single request, higher
+	// scores recommending more strongly.
+	Score float64 `json:"score"`
+}
+
+// SearchedMovie defines the details of a movie matching a search.
+type SearchedMovie struct {
+	MovieDetails
+	// Score ranks the movies of a single search, higher scores
+	// matching better.
+	Score float64 `json:"score"`
 }
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"movieapp.com/pkg/discovery"
+)
+
+// services are the services listed by default.
+var services = []string{"movie", "metadata", "rating", "user", "watchlist", "notification", "search", "recommendation", "analytics"}
+
+func newInstancesCommand(e *env) *cobra.Command {
+	return &cobra.Command{
+		Use:     "instances [SERVICE...]",
+		Short:   "List the healthy instances registered by the services, all of them if none given",
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = services
+			}
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SERVICE\tADDRESS")
+			for _, service := range args {
+				addrs, err := e.registry.ServiceAddresses(ctx, service)
+				if errors.Is(err, discovery.ErrNotFound) {
+					fmt.Fprintf(w, "%s\t<none>\n", service)
+					continue
+				} else if err != nil {
+					return fmt.Errorf("%s: %w", service, err)
+				}
+				for _, addr := range addrs {
+					fmt.Fprintf(w, "%s\t%s\n", service, addr)
+				}
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+)
+
+func newMetadataCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metadata",
+		Short: "Read and write movie metadata",
+	}
+	var locale string
+	var deleted bool
+	get := &cobra.Command{
+		Use:     "get MOVIE_ID",
+		Short:   "Print the metadata of a movie as JSON",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			if deleted {
+				// Reads of deleted metadata are admin calls.
+				ctx = auth.WithToken(ctx, e.profile.AdminToken)
+			}
+			conn, err := e.client("metadata")
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			resp, err := gen.NewMetadataServiceClient(conn).GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: args[0], Locale: locale, IncludeDeleted: deleted})
+			if err != nil {
+				return err
+			}
+			return e.printProto(resp.Metadata)
+		},
+	}
+	get.Flags().StringVar(&locale, "locale", "", "preferred locale, such as pt-BR, the default one if empty")
+	get.Flags().BoolVar(&deleted, "include-deleted", false, "print the metadata even if deleted, with the admin token of the profile")
+	var author string
+	put := &cobra.Command{
+		Use:     "put FILE",
+		Short:   "Write the metadata of a movie from a JSON file, - for standard input, with the admin token of the profile",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readFile(args[0])
+			if err != nil {
+				return err
+			}
+			var m gen.Metadata
+			if err := protojson.Unmarshal(data, &m); err != nil {
+				return err
+			}
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			// Writes of metadata are admin calls.
+			ctx = auth.WithToken(ctx, e.profile.AdminToken)
+			conn, err := e.client("metadata")
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			resp, err := gen.NewMetadataServiceClient(conn).PutMetadata(ctx, &gen.PutMetadataRequest{Metadata: &m, Author: author})
+			if err != nil {
+				return err
+			}
+			return e.printProto(resp.Metadata)
+		},
+	}
+	put.Flags().StringVar(&author, "author", os.Getenv("USER"), "author of the change recorded in the metadata history")
+	cmd.AddCommand(get, put)
+	return cmd
+}
+
+// readFile reads a file, or standard input for -.
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
@@ -0,0 +1,118 @@
+package command
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"movieapp.com/moviectl/internal/profile"
+)
+
+func newProfileCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage the profiles of the environments",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			f, _, err := e.loadProfiles()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "CURRENT\tNAME\tREGISTRY\tMETADATA URL")
+			for _, name := range f.Names() {
+				p := f.Profiles[name]
+				var current string
+				if name == f.Current {
+					current = "*"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", current, name, p.RegistryAddr, p.MetadataURL)
+			}
+			return w.Flush()
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use NAME",
+		Short: "Select the current profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			f, path, err := e.loadProfiles()
+			if err != nil {
+				return err
+			}
+			if _, err := f.Get(args[0]); err != nil {
+				return err
+			}
+			f.Current = args[0]
+			return f.Save(path)
+		},
+	})
+	var p profile.Profile
+	set := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or change a profile, leaving the unset flags as they are",
+		Long:  "Create or change a profile. Its registry is set by the global --registry-addr flag.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, path, err := e.loadProfiles()
+			if err != nil {
+				return err
+			}
+			cur, ok := f.Profiles[args[0]]
+			if !ok {
+				cur = profile.Local()
+				f.Profiles[args[0]] = cur
+			}
+			flags := cmd.Flags()
+			if flags.Changed("registry-addr") {
+				cur.RegistryAddr = e.registryAddr
+			}
+			if flags.Changed("metadata-url") {
+				cur.MetadataURL = p.MetadataURL
+			}
+			if flags.Changed("token") {
+				cur.Token = p.Token
+			}
+			if flags.Changed("admin-token") {
+				cur.AdminToken = p.AdminToken
+			}
+			if flags.Changed("tls-cert") {
+				cur.TLS.CertFile = p.TLS.CertFile
+			}
+			if flags.Changed("tls-key") {
+				cur.TLS.KeyFile = p.TLS.KeyFile
+			}
+			if flags.Changed("tls-ca") {
+				cur.TLS.CAFile = p.TLS.CAFile
+			}
+			if err := cur.TLS.Validate(); err != nil {
+				return fmt.Errorf("tls: %w", err)
+			}
+			return f.Save(path)
+		},
+	}
+	flags := set.Flags()
+	flags.StringVar(&p.MetadataURL, "metadata-url", "", "base URL of the HTTP API of the metadata service")
+	flags.StringVar(&p.Token, "token", "", "bearer token of the calls, such as ${MOVIECTL_TOKEN} to read it from the environment")
+	flags.StringVar(&p.AdminToken, "admin-token", "", "bearer token of the metadata admin endpoints, such as ${MOVIECTL_ADMIN_TOKEN}")
+	flags.StringVar(&p.TLS.CertFile, "tls-cert", "", "PEM client certificate file for mutual TLS")
+	flags.StringVar(&p.TLS.KeyFile, "tls-key", "", "PEM private key file of the client certificate")
+	flags.StringVar(&p.TLS.CAFile, "tls-ca", "", "PEM CA bundle verifying the certificates of the services")
+	cmd.AddCommand(set)
+	return cmd
+}
+
+// loadProfiles reads the profiles file and returns it with its
+// path.
+func (e *env) loadProfiles() (*profile.File, string, error) {
+	path, err := e.path()
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := profile.Load(path)
+	return f, path, err
+}
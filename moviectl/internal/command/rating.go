@@ -0,0 +1,81 @@
+package command
+
+import (
+	"github.com/spf13/cobra"
+	"movieapp.com/gen"
+	"movieapp.com/rating/pkg/model"
+)
+
+func newRatingCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rating",
+		Short: "Query and write ratings",
+	}
+	var recordType string
+	cmd.PersistentFlags().StringVar(&recordType, "type", string(model.RecordTypeMovie), "type of the rated records")
+	cmd.AddCommand(&cobra.Command{
+		Use:     "get RECORD_ID",
+		Short:   "Print the aggregated rating of a record",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			conn, err := e.client("rating")
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			resp, err := gen.NewRatingServiceClient(conn).GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: args[0], RecordType: recordType})
+			if err != nil {
+				return err
+			}
+			return e.printProto(resp)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:     "list RECORD_ID",
+		Short:   "Print the ratings of a record",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			conn, err := e.client("rating")
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			resp, err := gen.NewRatingServiceClient(conn).ListRecordRatings(ctx, &gen.ListRecordRatingsRequest{RecordId: args[0], RecordType: recordType})
+			if err != nil {
+				return err
+			}
+			return e.printProto(resp)
+		},
+	})
+	var userID string
+	var value int32
+	put := &cobra.Command{
+		Use:     "put RECORD_ID",
+		Short:   "Write the rating of a record by a user",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			conn, err := e.client("rating")
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			_, err = gen.NewRatingServiceClient(conn).PutRating(ctx, &gen.PutRatingRequest{UserId: userID, RecordId: args[0], RecordType: recordType, RatingValue: value})
+			return err
+		},
+	}
+	put.Flags().StringVar(&userID, "user", "", "id of the rating user")
+	put.Flags().Int32Var(&value, "value", 0, "rating value")
+	put.MarkFlagRequired("user")
+	put.MarkFlagRequired("value")
+	cmd.AddCommand(put)
+	return cmd
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+)
+
+func newRecomputeCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recompute",
+		Short: "Recompute the state kept by each instance of a service",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:     "search-ratings",
+		Short:   "Refresh the ratings of the movies indexed by each search instance",
+		Args:    cobra.NoArgs,
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return e.eachInstance(cmd, "search", func(ctx context.Context, conn grpc.ClientConnInterface) (string, error) {
+				resp, err := gen.NewSearchServiceClient(conn).RefreshSearchRatings(ctx, &gen.RefreshSearchRatingsRequest{})
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d movies refreshed", resp.Refreshed), nil
+			})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:     "recommendations",
+		Short:   "Load the latest recommendation model into each recommendation instance",
+		Args:    cobra.NoArgs,
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return e.eachInstance(cmd, "recommendation", func(ctx context.Context, conn grpc.ClientConnInterface) (string, error) {
+				resp, err := gen.NewRecommendationServiceClient(conn).ReloadModel(ctx, &gen.ReloadModelRequest{})
+				if err != nil {
+					return "", err
+				}
+				return "model " + resp.ModelVersion, nil
+			})
+		},
+	})
+	return cmd
+}
+
+// eachInstance calls each healthy instance of a service in turn,
+// printing the result of each call. All instances are called even
+// if some fail.
+func (e *env) eachInstance(cmd *cobra.Command, service string, call func(ctx context.Context, conn grpc.ClientConnInterface) (string, error)) error {
+	ctx, cancel := e.context(cmd)
+	defer cancel()
+	addrs, err := e.registry.ServiceAddresses(ctx, service)
+	if err != nil {
+		return fmt.Errorf("%s: %w", service, err)
+	}
+	var errs []error
+	for _, addr := range addrs {
+		res, err := e.callInstance(ctx, addr, call)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			fmt.Fprintf(e.out, "%s\t%v\n", addr, err)
+			continue
+		}
+		fmt.Fprintf(e.out, "%s\t%s\n", addr, res)
+	}
+	return errors.Join(errs...)
+}
+
+func (e *env) callInstance(ctx context.Context, addr string, call func(ctx context.Context, conn grpc.ClientConnInterface) (string, error)) (string, error) {
+	conn, err := grpcutil.NewInstanceClient(addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return call(ctx, conn)
+}
@@ -0,0 +1,157 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"movieapp.com/internal/httputil"
+)
+
+// pollInterval is the interval the progress of a waited for
+// reindex is checked at.
+const pollInterval = 2 * time.Second
+
+// reindexJob defines the progress of a reindex job, as returned by
+// the metadata admin API.
+type reindexJob struct {
+	ID          string     `json:"id"`
+	State       string     `json:"state"`
+	Since       time.Time  `json:"since"`
+	Concurrency int        `json:"concurrency"`
+	Indexed     int        `json:"indexed"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+func newReindexCommand(e *env) *cobra.Command {
+	var sinceFlag string
+	var concurrency int
+	var wait bool
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Reindex the metadata into search, all of it or that updated since a time",
+		Long: "Start a reindex job of the metadata service, publishing the metadata to the search index. " +
+			"Admin requests carry the admin token of the profile.",
+		Args:    cobra.NoArgs,
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			form := url.Values{}
+			if sinceFlag != "" {
+				since, err := time.Parse(time.RFC3339, sinceFlag)
+				if err != nil {
+					return fmt.Errorf("since: %w", err)
+				}
+				form.Set("since", since.Format(time.RFC3339))
+			}
+			if concurrency > 0 {
+				form.Set("concurrency", strconv.Itoa(concurrency))
+			}
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			var job reindexJob
+			if err := e.admin(ctx, http.MethodPost, form, &job); err != nil {
+				return err
+			}
+			if wait {
+				// Jobs take longer than the timeout of each call.
+				return e.waitReindex(cmd.Context(), job.ID)
+			}
+			return e.printJSON(job)
+		},
+	}
+	cmd.Flags().StringVar(&sinceFlag, "since", "", "RFC 3339 time of the oldest updates reindexed, all metadata if empty")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 0, "number of parallel index writes, the default of the service if 0")
+	cmd.Flags().BoolVar(&wait, "wait", false, "wait for the job to finish, printing its progress")
+	cmd.AddCommand(&cobra.Command{
+		Use:     "status JOB_ID",
+		Short:   "Print the progress of a reindex job",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: e.connect,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := e.context(cmd)
+			defer cancel()
+			var job reindexJob
+			if err := e.admin(ctx, http.MethodGet, url.Values{"id": {args[0]}}, &job); err != nil {
+				return err
+			}
+			return e.printJSON(job)
+		},
+	})
+	return cmd
+}
+
+// waitReindex polls a reindex job until it finishes, failing if
+// the job failed.
+func (e *env) waitReindex(ctx context.Context, id string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		var job reindexJob
+		callCtx, cancel := context.WithTimeout(ctx, e.timeout)
+		err := e.admin(callCtx, http.MethodGet, url.Values{"id": {id}}, &job)
+		cancel()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.out, "%s: %s, %d indexed, %d failed\n", job.ID, job.State, job.Indexed, job.Failed)
+		switch job.State {
+		case "running":
+		case "failed":
+			return fmt.Errorf("reindex failed: %s", job.Error)
+		default:
+			return nil
+		}
+	}
+}
+
+// admin calls the reindex endpoint of the metadata admin API,
+// decoding the JSON response into v.
+func (e *env) admin(ctx context.Context, method string, form url.Values, v any) error {
+	if e.profile.MetadataURL == "" {
+		return errors.New("metadata URL of the profile not set")
+	}
+	if e.profile.AdminToken == "" {
+		return errors.New("admin token of the profile not set")
+	}
+	u := strings.TrimSuffix(e.profile.MetadataURL, "/") + "/admin/reindex"
+	var body io.Reader
+	if method == http.MethodGet {
+		u += "?" + form.Encode()
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Authorization", "Bearer "+e.profile.AdminToken)
+	resp, err := (&http.Client{Transport: httputil.ServiceTransport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, u, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/internal/httputil"
+	"movieapp.com/moviectl/internal/profile"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/discovery/consul"
+	"movieapp.com/pkg/mtls"
+)
+
+// env defines the settings of a command line, resolved from the
+// profile and the global flags before a command runs.
+type env struct {
+	configPath   string
+	profileName  string
+	registryAddr string
+	timeout      time.Duration
+
+	profile  *profile.Profile
+	registry *consul.Registry
+	out      io.Writer
+}
+
+// NewRoot creates the moviectl command.
+func NewRoot() *cobra.Command {
+	e := &env{out: os.Stdout}
+	root := &cobra.Command{
+		Use:           "moviectl",
+		Short:         "Administer the movieapp services",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&e.configPath, "config", "", "profiles file, ~/.config/moviectl/config.yaml if empty")
+	flags.StringVarP(&e.profileName, "profile", "p", "", "profile of the environment, the current one if empty")
+	flags.StringVar(&e.registryAddr, "registry-addr", "", "address of the Consul service registry, overriding the profile")
+	flags.DurationVar(&e.timeout, "timeout", 30*time.Second, "timeout of each command")
+	root.AddCommand(
+		newProfileCommand(e),
+		newInstancesCommand(e),
+		newMetadataCommand(e),
+		newRatingCommand(e),
+		newReindexCommand(e),
+		newRecomputeCommand(e),
+	)
+	return root
+}
+
+// Execute runs the moviectl command line, printing errors.
+func Execute() error {
+	err := NewRoot().Execute()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	return err
+}
+
+func (e *env) path() (string, error) {
+	if e.configPath != "" {
+		return e.configPath, nil
+	}
+	return profile.DefaultPath()
+}
+
+// connect resolves the profile and connects to the registry, set
+// up as the PreRunE of the commands calling services.
+func (e *env) connect(*cobra.Command, []string) error {
+	path, err := e.path()
+	if err != nil {
+		return err
+	}
+	f, err := profile.Load(path)
+	if err != nil {
+		return err
+	}
+	p, err := f.Get(e.profileName)
+	if err != nil {
+		return err
+	}
+	e.profile = p.Expand()
+	if e.registryAddr != "" {
+		e.profile.RegistryAddr = e.registryAddr
+	}
+	if e.profile.TLS.Enabled() {
+		creds, err := mtls.Load(e.profile.TLS)
+		if err != nil {
+			return fmt.Errorf("load certificates: %w", err)
+		}
+		grpcutil.SetTLS(creds.Client())
+		httputil.SetTLS(creds.Client())
+	}
+	e.registry, err = consul.NewRegistry(e.profile.RegistryAddr)
+	return err
+}
+
+// context returns the context of a command, timing out and passing
+// the token of the profile to the called services.
+func (e *env) context(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(cmd.Context(), e.timeout)
+	if e.profile != nil && e.profile.Token != "" {
+		ctx = auth.NewContext(ctx, nil, e.profile.Token)
+	}
+	return ctx, cancel
+}
+
+// client connects to a service through the registry.
+func (e *env) client(service string) (*grpc.ClientConn, error) {
+	return grpcutil.NewClient(service, e.registry)
+}
+
+// printProto writes a message as indented JSON.
+func (e *env) printProto(m proto.Message) error {
+	data, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(e.out, string(data))
+	return err
+}
+
+// printJSON writes a value as indented JSON.
+func (e *env) printJSON(v any) error {
+	enc := json.NewEncoder(e.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,119 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"movieapp.com/pkg/mtls"
+)
+
+// DefaultName is the name of the profile used when none is
+// selected.
+const DefaultName = "local"
+
+// Profile defines how to reach the services of an environment.
+type Profile struct {
+	RegistryAddr string `yaml:"registryAddr"`
+	// MetadataURL is the base URL of the HTTP API of the metadata
+	// service, serving the admin endpoints.
+	MetadataURL string `yaml:"metadataURL"`
+	// Token is the bearer token of the calls to the services and
+	// AdminToken the one of the metadata admin endpoints. Both may
+	// reference environment variables, such as ${MOVIECTL_TOKEN},
+	// to keep them out of the file.
+	Token      string      `yaml:"token,omitempty"`
+	AdminToken string      `yaml:"adminToken,omitempty"`
+	TLS        mtls.Config `yaml:"tls,omitempty"`
+}
+
+// Local returns the profile of the services run locally with their
+// default settings.
+func Local() *Profile {
+	return &Profile{RegistryAddr: "localhost:8500", MetadataURL: "http://localhost:8091"}
+}
+
+// Expand returns the profile with the environment variables its
+// tokens reference expanded.
+func (p *Profile) Expand() *Profile {
+	res := *p
+	res.Token = os.ExpandEnv(p.Token)
+	res.AdminToken = os.ExpandEnv(p.AdminToken)
+	return &res
+}
+
+// File defines the profiles of the environments and the one in
+// use.
+type File struct {
+	Current  string              `yaml:"current"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// DefaultPath returns the path of the profiles file in the user
+// config directory, e.g. ~/.config/moviectl/config.yaml.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "moviectl", "config.yaml"), nil
+}
+
+// Load reads the profiles file at the path. A missing file yields
+// the local profile only.
+func Load(path string) (*File, error) {
+	f := &File{Current: DefaultName, Profiles: map[string]*Profile{DefaultName: Local()}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+	f.Profiles = nil
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]*Profile{}
+	}
+	return f, nil
+}
+
+// Save writes the profiles file to the path, readable by the user
+// only since it may hold tokens.
+func (f *File) Save(path string) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Get returns the profile with the name, or the current one if the
+// name is empty.
+func (f *File) Get(name string) (*Profile, error) {
+	if name == "" {
+		name = f.Current
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	return p, nil
+}
+
+// Names returns the names of the profiles in order.
+func (f *File) Names() []string {
+	res := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
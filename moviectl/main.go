@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"movieapp.com/moviectl/internal/command"
+)
+
+func main() {
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/notification/internal/channel"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/bus/sqs"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/debug"
+	"movieapp.com/pkg/httpmw"
+	"movieapp.com/pkg/lifecycle"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/mtls"
+	"movieapp.com/pkg/secrets"
+	"movieapp.com/pkg/tenant"
+)
+
+// serviceConfig defines the settings of the notification service,
+// loaded by config.Load.
+type serviceConfig struct {
+	Host                 string               `yaml:"host"`
+	Port                 int                  `yaml:"port"`
+	RESTPort             int                  `yaml:"restPort"`
+	MetricsPort          int                  `yaml:"metricsPort"`
+	DrainTimeout         time.Duration        `yaml:"drainTimeout"`
+	DrainDelay           time.Duration        `yaml:"drainDelay"`
+	ConfigReload         time.Duration        `yaml:"configReload"`
+	RegistryAddr         string               `yaml:"registryAddr"`
+	RegistryCheck        time.Duration        `yaml:"registryCheck"`
+	MySQLDSN             string               `yaml:"mysqlDSN"`
+	Bus                  string               `yaml:"bus"`
+	NATSURL              string               `yaml:"natsURL"`
+	SQS                  sqs.Config           `yaml:"sqs"`
+	KafkaBrokers         config.List          `yaml:"kafkaBrokers"`
+	RatingEventsTopic    string               `yaml:"ratingEventsTopic"`
+	MetadataEventsTopic  string               `yaml:"metadataEventsTopic"`
+	WatchlistEventsTopic string               `yaml:"watchlistEventsTopic"`
+	KafkaSASL            kafkautil.SASLConfig `yaml:"kafkaSASL"`
+	TemplatesDir         string               `yaml:"templatesDir"`
+	Email                channel.EmailConfig  `yaml:"email"`
+	Push                 channel.PushConfig   `yaml:"push"`
+	WebhookSecret        string               `yaml:"webhookSecret"`
+	OTLPEndpoint         string               `yaml:"otlpEndpoint"`
+	SentryDSN            string               `yaml:"sentryDSN"`
+	HTTPTimeouts         httpmw.TimeoutConfig `yaml:"httpTimeouts"`
+	LogLevel             string               `yaml:"logLevel"`
+	Tenants              config.List          `yaml:"tenants"`
+	TLS                  mtls.Config          `yaml:"tls"`
+	Auth                 auth.Config          `yaml:"auth"`
+	Secrets              secrets.Config       `yaml:"secrets"`
+	Debug                debug.Config         `yaml:"debug"`
+}
+
+func defaultConfig() *serviceConfig {
+	return &serviceConfig{
+		Host:                 "localhost",
+		Port:                 8087,
+		RESTPort:             8077,
+		MetricsPort:          8098,
+		DrainTimeout:         lifecycle.DefaultDrainTimeout,
+		DrainDelay:           lifecycle.DefaultDrainDelay,
+		ConfigReload:         config.DefaultReload,
+		RegistryAddr:         "localhost:8500",
+		MySQLDSN:             "root:password@/movieexample",
+		Bus:                  bus.BrokerKafka,
+		SQS:                  sqs.DefaultConfig(),
+		KafkaBrokers:         config.List{"localhost:9092"},
+		RatingEventsTopic:    "ratings",
+		MetadataEventsTopic:  "metadata",
+		WatchlistEventsTopic: "watchlist",
+		KafkaSASL:            kafkautil.SASLConfig{Mechanism: kafkautil.MechanismSCRAMSHA512},
+		LogLevel:             "info",
+		Secrets:              secrets.DefaultConfig(),
+		HTTPTimeouts:         httpmw.DefaultTimeoutConfig(),
+	}
+}
+
+// Validate validates the config.
+func (c *serviceConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("host: empty"))
+	}
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drainTimeout: not positive"))
+	}
+	if c.DrainDelay < 0 || c.RegistryCheck < 0 || c.ConfigReload < 0 {
+		errs = append(errs, errors.New("drainDelay, registryCheck, configReload: negative"))
+	}
+	errs = append(errs,
+		config.ValidatePort("port", c.Port, false),
+		config.ValidatePort("restPort", c.RESTPort, true),
+		config.ValidatePort("metricsPort", c.MetricsPort, false),
+		config.ValidateAddr("registryAddr", c.RegistryAddr, false),
+		config.ValidateAddr("otlpEndpoint", c.OTLPEndpoint, true),
+	)
+	if c.MySQLDSN == "" {
+		errs = append(errs, errors.New("mysqlDSN: empty"))
+	}
+	if len(c.Tenants) > 1 && !strings.Contains(c.MySQLDSN, tenant.Placeholder) {
+		errs = append(errs, errors.New("mysqlDSN: no "+tenant.Placeholder+" for several tenants"))
+	}
+	if c.RatingEventsTopic == "" || c.MetadataEventsTopic == "" || c.WatchlistEventsTopic == "" {
+		errs = append(errs, errors.New("ratingEventsTopic, metadataEventsTopic, watchlistEventsTopic: empty"))
+	}
+	if err := c.Email.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("email: %w", err))
+	}
+	if err := c.Push.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("push: %w", err))
+	}
+	if _, err := logging.ParseLevels(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if err := bus.ValidateBroker(c.Bus); err != nil {
+		errs = append(errs, fmt.Errorf("bus: %w", err))
+	}
+	switch c.Bus {
+	case bus.BrokerKafka:
+		errs = append(errs, config.ValidateAddrs("kafkaBrokers", c.KafkaBrokers))
+		if err := c.KafkaSASL.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("kafkaSASL: %w", err))
+		}
+	case bus.BrokerNATS:
+		if c.NATSURL == "" {
+			errs = append(errs, errors.New("natsURL: empty"))
+		}
+	case bus.BrokerSQS:
+		if err := c.SQS.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sqs: %w", err))
+		}
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	}
+	if err := c.Auth.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth: %w", err))
+	}
+	if err := c.Debug.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("debug: %w", err))
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+	if err := c.HTTPTimeouts.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("httpTimeouts: %w", err))
+	}
+	for _, id := range c.Tenants {
+		if err := tenant.Validate(id); err != nil {
+			errs = append(errs, fmt.Errorf("tenants: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"movieapp.com/internal/httputil"
+	"movieapp.com/notification/pkg/model"
+)
+
+// ErrNoAddress is returned when a user has not set the address of
+// a channel, such as their email address.
+var ErrNoAddress = errors.New("no address")
+
+// Sender delivers notifications through a channel.
+type Sender interface {
+	// Send delivers the message of a notification to the user of
+	// the preferences.
+	Send(ctx context.Context, p *model.Preferences, n *model.Notification, msg *model.Message) error
+}
+
+// sendTimeout bounds the calls of the HTTP channels.
+const sendTimeout = 10 * time.Second
+
+var client = &http.Client{Timeout: sendTimeout}
+
+// post posts the value as JSON to the URL with the headers.
+func post(ctx context.Context, url string, v any, header http.Header) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return &httputil.StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
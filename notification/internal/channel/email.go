@@ -0,0 +1,82 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"movieapp.com/notification/pkg/model"
+)
+
+// EmailConfig defines the SMTP server emails are sent through.
+type EmailConfig struct {
+	// Addr is the host:port of the SMTP server, emails are not
+	// sent if empty.
+	Addr string `yaml:"addr"`
+	From string `yaml:"from"`
+	// Username and Password authenticate with PLAIN auth unless
+	// the username is empty.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Enabled reports whether emails are sent.
+func (c EmailConfig) Enabled() bool {
+	return c.Addr != ""
+}
+
+// Validate validates the config.
+func (c EmailConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr: %w", err)
+	}
+	if c.From == "" {
+		return errors.New("from: empty")
+	}
+	return nil
+}
+
+// Email sends notifications by email.
+type Email struct {
+	config EmailConfig
+}
+
+// NewEmail creates an email sender.
+func NewEmail(config EmailConfig) *Email {
+	return &Email{config}
+}
+
+// Send emails the message to the email address of the user.
+func (e *Email) Send(ctx context.Context, p *model.Preferences, _ *model.Notification, msg *model.Message) error {
+	if p.Email == "" {
+		return ErrNoAddress
+	}
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		host, _, _ := net.SplitHostPort(e.config.Addr)
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, host)
+	}
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		e.config.From, p.Email, headerValue(msg.Subject), msg.Body)
+	// SendMail takes no context, so a canceled send is abandoned.
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(e.config.Addr, auth, e.config.From, []string{p.Email}, []byte(body)) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// headerValue strips line breaks off a header value, so that a
+// rendered subject cannot add headers.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
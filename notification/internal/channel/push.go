@@ -0,0 +1,70 @@
+package channel
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"movieapp.com/internal/httputil"
+	"movieapp.com/notification/pkg/model"
+	"movieapp.com/pkg/resilience"
+)
+
+// PushConfig defines the push gateway push notifications are sent
+// through.
+type PushConfig struct {
+	// URL is the endpoint of the push gateway, push notifications
+	// are not sent if empty.
+	URL string `yaml:"url"`
+	// Key is the server key authenticating with the gateway.
+	Key string `yaml:"key"`
+}
+
+// Enabled reports whether push notifications are sent.
+func (c PushConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// Validate validates the config.
+func (c PushConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	_, err := url.ParseRequestURI(c.URL)
+	return err
+}
+
+// Push sends push notifications through a push gateway, which
+// delivers them to the devices of the tokens.
+type Push struct {
+	config PushConfig
+	retry  resilience.Retry
+}
+
+// NewPush creates a push notification sender. Transient failures
+// of the gateway are retried.
+func NewPush(config PushConfig) *Push {
+	return &Push{config, resilience.DefaultRetry(httputil.Retryable)}
+}
+
+type pushRequest struct {
+	To    string              `json:"to"`
+	Title string              `json:"title"`
+	Body  string              `json:"body"`
+	Data  *model.Notification `json:"data"`
+}
+
+// Send pushes the message to the device of the push token of the
+// user.
+func (p *Push) Send(ctx context.Context, prefs *model.Preferences, n *model.Notification, msg *model.Message) error {
+	if prefs.PushToken == "" {
+		return ErrNoAddress
+	}
+	header := http.Header{}
+	if p.config.Key != "" {
+		header.Set("Authorization", "Bearer "+p.config.Key)
+	}
+	return p.retry.Do(ctx, func(ctx context.Context) error {
+		return post(ctx, p.config.URL, pushRequest{prefs.PushToken, msg.Subject, msg.Body, n}, header)
+	})
+}
@@ -0,0 +1,59 @@
+package channel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"movieapp.com/internal/httputil"
+	"movieapp.com/notification/pkg/model"
+	"movieapp.com/pkg/resilience"
+)
+
+// SignatureHeader is the header of the webhook requests carrying
+// the hex HMAC-SHA256 of the body keyed with the webhook secret,
+// prefixed with sha256=.
+const SignatureHeader = "X-Movieapp-Signature"
+
+// Webhook sends notifications to the webhooks of the users.
+type Webhook struct {
+	secret []byte
+	retry  resilience.Retry
+}
+
+// NewWebhook creates a webhook sender signing the requests with
+// the secret unless empty. Transient failures of the webhooks are
+// retried.
+func NewWebhook(secret string) *Webhook {
+	return &Webhook{[]byte(secret), resilience.DefaultRetry(httputil.Retryable)}
+}
+
+type webhookRequest struct {
+	Notification *model.Notification `json:"notification"`
+	Subject      string              `json:"subject"`
+	Body         string              `json:"body"`
+}
+
+// Send posts the notification and its message to the webhook URL
+// of the user.
+func (w *Webhook) Send(ctx context.Context, p *model.Preferences, n *model.Notification, msg *model.Message) error {
+	if p.WebhookURL == "" {
+		return ErrNoAddress
+	}
+	b, err := json.Marshal(webhookRequest{n, msg.Subject, msg.Body})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(b)
+		header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return w.retry.Do(ctx, func(ctx context.Context) error {
+		return post(ctx, p.WebhookURL, json.RawMessage(b), header)
+	})
+}
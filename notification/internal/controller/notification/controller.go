@@ -0,0 +1,215 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/notification/internal/channel"
+	"movieapp.com/notification/internal/repository"
+	"movieapp.com/notification/pkg/model"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/request"
+	ratingmodel "movieapp.com/rating/pkg/model"
+	watchlistmodel "movieapp.com/watchlist/pkg/model"
+)
+
+var logger = logging.New("controller")
+
+// deliveries counts the sent and failed notifications by channel,
+// published at /debug/vars.
+var deliveries = expvar.NewMap("notification_deliveries")
+
+// notifyConcurrency bounds the users notified of an event at once.
+const notifyConcurrency = 8
+
+type notificationRepository interface {
+	GetPreferences(ctx context.Context, userID string) (*model.Preferences, error)
+	PutPreferences(ctx context.Context, preferences *model.Preferences) error
+	AddWatcher(ctx context.Context, movieID string, userID string) error
+	RemoveWatcher(ctx context.Context, movieID string, userID string) error
+	Watchers(ctx context.Context, movieID string) ([]string, error)
+}
+
+type renderer interface {
+	Render(n *model.Notification) (*model.Message, error)
+}
+
+// Controller defines a notification service controller.
+type Controller struct {
+	repo     notificationRepository
+	renderer renderer
+	senders  map[model.Channel]channel.Sender
+}
+
+// New creates a notification service controller rendering
+// notifications with the renderer and delivering them with the
+// senders of their channels. Channels without a sender are
+// skipped.
+func New(repo notificationRepository, renderer renderer, senders map[model.Channel]channel.Sender) *Controller {
+	return &Controller{repo, renderer, senders}
+}
+
+// Preferences returns the notification preferences of a user, the
+// defaults if the user has not set any.
+func (c *Controller) Preferences(ctx context.Context, userID string) (*model.Preferences, error) {
+	p, err := c.repo.GetPreferences(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &model.Preferences{UserID: userID}, nil
+	}
+	return p, err
+}
+
+// PutPreferences replaces the notification preferences of a user.
+func (c *Controller) PutPreferences(ctx context.Context, p *model.Preferences) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+	return c.repo.PutPreferences(ctx, p)
+}
+
+// HandleWatchlistEvent keeps track of the users watching each
+// movie.
+func (c *Controller) HandleWatchlistEvent(ctx context.Context, e *watchlistmodel.Event) error {
+	switch e.Type {
+	case watchlistmodel.EventTypeAdded:
+		return c.repo.AddWatcher(ctx, e.MovieID, e.UserID)
+	case watchlistmodel.EventTypeRemoved:
+		return c.repo.RemoveWatcher(ctx, e.MovieID, e.UserID)
+	}
+	return nil
+}
+
+// HandleRatingEvent notifies the users watching a movie of its new
+// ratings, except the user who rated it.
+func (c *Controller) HandleRatingEvent(ctx context.Context, e *ratingmodel.RatingEvent) error {
+	if e.Type != ratingmodel.RatingEventTypePut || e.RecordType != ratingmodel.RecordTypeMovie {
+		return nil
+	}
+	return c.notifyWatchers(ctx, string(e.RecordID), string(e.UserID), func(userID string) *model.Notification {
+		return &model.Notification{
+			ID:      fmt.Sprintf("%s:%s:%s:%d", model.KindWatchlistRating, e.RecordID, e.UserID, e.Timestamp.UnixNano()),
+			Kind:    model.KindWatchlistRating,
+			UserID:  userID,
+			MovieID: string(e.RecordID),
+			Rating:  int(e.Value),
+		}
+	})
+}
+
+// HandleMetadataEvent notifies the users watching a movie of the
+// updates of its metadata.
+func (c *Controller) HandleMetadataEvent(ctx context.Context, e *metadatamodel.Event) error {
+	if e.Type != metadatamodel.EventTypeUpdated {
+		return nil
+	}
+	var title string
+	if e.Metadata != nil {
+		title = e.Metadata.Title
+	}
+	return c.notifyWatchers(ctx, e.MovieID, "", func(userID string) *model.Notification {
+		return &model.Notification{
+			ID:      fmt.Sprintf("%s:%s", model.KindWatchlistUpdated, e.ID),
+			Kind:    model.KindWatchlistUpdated,
+			UserID:  userID,
+			MovieID: e.MovieID,
+			Title:   title,
+		}
+	})
+}
+
+// notifyWatchers notifies the users watching a movie but the
+// excluded one. A failure to notify a user is logged and does not
+// stop the others from being notified.
+func (c *Controller) notifyWatchers(ctx context.Context, movieID string, exclude string, notification func(userID string) *model.Notification) error {
+	watchers, err := c.repo.Watchers(ctx, movieID)
+	if err != nil {
+		return err
+	}
+	var g errgroup.Group
+	g.SetLimit(notifyConcurrency)
+	for _, userID := range watchers {
+		if userID == exclude {
+			continue
+		}
+		n := notification(userID)
+		g.Go(func() error {
+			if err := c.notify(ctx, n); err != nil {
+				logger.ErrorContext(ctx, "Notification error", "id", n.ID, "user", n.UserID, "error", err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// notify delivers a notification through the channels the user
+// chose for its kind. Users without preferences have no address
+// to be notified at.
+func (c *Controller) notify(ctx context.Context, n *model.Notification) error {
+	p, err := c.repo.GetPreferences(ctx, n.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	channels := p.ChannelsOf(n.Kind)
+	if len(channels) == 0 {
+		return nil
+	}
+	msg, err := c.renderer.Render(n)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, ch := range channels {
+		sender, ok := c.senders[ch]
+		if !ok {
+			continue
+		}
+		if err := sender.Send(ctx, p, n, msg); errors.Is(err, channel.ErrNoAddress) {
+			continue
+		} else if err != nil {
+			deliveries.Add(string(ch)+"_failed", 1)
+			errs = append(errs, fmt.Errorf("%s: %w", ch, err))
+			continue
+		}
+		deliveries.Add(string(ch)+"_sent", 1)
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks preferences before they are written.
+func validate(p *model.Preferences) error {
+	var fields []request.FieldError
+	if p.Email != "" {
+		if addr, err := mail.ParseAddress(p.Email); err != nil || addr.Address != p.Email {
+			fields = append(fields, request.FieldError{Field: "email", Message: "must be an email address"})
+		}
+	}
+	if p.WebhookURL != "" {
+		if u, err := url.Parse(p.WebhookURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			fields = append(fields, request.FieldError{Field: "webhookUrl", Message: "must be an absolute https URL"})
+		}
+	}
+	for kind, channels := range p.Subscriptions {
+		if !slices.Contains(model.Kinds, kind) {
+			fields = append(fields, request.FieldError{Field: "subscriptions", Message: fmt.Sprintf("unknown kind %q", kind)})
+		}
+		for _, ch := range channels {
+			if !slices.Contains(model.Channels, ch) {
+				fields = append(fields, request.FieldError{Field: "subscriptions", Message: fmt.Sprintf("unknown channel %q", ch)})
+			}
+		}
+	}
+	if len(fields) > 0 {
+		return &request.Error{Fields: fields}
+	}
+	return nil
+}
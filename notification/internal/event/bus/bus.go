@@ -0,0 +1,46 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("event/bus")
+
+// Consumer defines a consumer of the JSON events of type T.
+type Consumer[T any] struct {
+	bus   bus.Subscriber
+	topic string
+	group string
+	id    func(*T) string
+}
+
+// NewConsumer creates a consumer of the events of the topic of the
+// bus published from now on, identified for logging by the id
+// function. Consumers sharing a group split the events between them.
+func NewConsumer[T any](b bus.Subscriber, topic string, group string, id func(*T) string) *Consumer[T] {
+	return &Consumer[T]{b, topic, group, id}
+}
+
+// Run passes events to the handler until the context is canceled.
+// Events the handler fails on are logged and skipped.
+func (c *Consumer[T]) Run(ctx context.Context, handle func(context.Context, *T) error) {
+	err := c.bus.Subscribe(ctx, c.topic, c.group, func(ctx context.Context, msg *bus.Message) error {
+		var e *T
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			logger.ErrorContext(ctx, "Event decode error", "topic", c.topic, "error", err)
+			return err
+		}
+		if err := handle(ctx, e); err != nil {
+			logger.ErrorContext(ctx, "Event handling error", "id", c.id(e), "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "Event subscription error", "topic", c.topic, "error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/gen"
+	"movieapp.com/notification/internal/controller/notification"
+	"movieapp.com/notification/pkg/model"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
+)
+
+// Handler defines a gRPC notification API handler.
+type Handler struct {
+	gen.UnimplementedNotificationServiceServer
+	ctrl *notification.Controller
+}
+
+// New creates a new notification gRPC handler.
+func New(ctrl *notification.Controller) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// GetNotificationPreferences returns the notification preferences
+// of a user, only to the user.
+func (h *Handler) GetNotificationPreferences(ctx context.Context, req *gen.GetNotificationPreferencesRequest) (*gen.GetNotificationPreferencesResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	p, err := h.ctrl.Preferences(ctx, req.UserId)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.GetNotificationPreferencesResponse{Preferences: model.PreferencesToProto(p)}, nil
+}
+
+// PutNotificationPreferences replaces the notification preferences
+// of a user, only by the user.
+func (h *Handler) PutNotificationPreferences(ctx context.Context, req *gen.PutNotificationPreferencesRequest) (*gen.PutNotificationPreferencesResponse, error) {
+	if req == nil || req.Preferences == nil || req.Preferences.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or preferences or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.Preferences.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	p := model.PreferencesFromProto(req.Preferences)
+	if err := h.ctrl.PutPreferences(ctx, p); err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.PutNotificationPreferencesResponse{Preferences: model.PreferencesToProto(p)}, nil
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"movieapp.com/notification/pkg/model"
+)
+
+//go:embed templates/*.tmpl
+var defaults embed.FS
+
+// Renderer renders notifications into messages by the template of
+// their kind. A template defines the subject and the body
+// templates, executed on the notification.
+type Renderer struct {
+	templates map[model.Kind]*template.Template
+}
+
+// New creates a renderer of the default templates, replaced by the
+// templates named after their kind, such as watchlistRating.tmpl,
+// found in the directory unless empty.
+func New(dir string) (*Renderer, error) {
+	r := &Renderer{templates: map[model.Kind]*template.Template{}}
+	if err := r.load(defaults, "templates"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := r.load(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// load parses the templates of the directory of the file system.
+func (r *Renderer) load(fsys fs.FS, dir string) error {
+	matches, err := fs.Glob(fsys, path.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	for _, name := range matches {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		kind := model.Kind(strings.TrimSuffix(path.Base(name), ".tmpl"))
+		t, err := template.New(string(kind)).Option("missingkey=error").Parse(string(b))
+		if err != nil {
+			return fmt.Errorf("template %s: %w", kind, err)
+		}
+		if t.Lookup("subject") == nil || t.Lookup("body") == nil {
+			return fmt.Errorf("template %s: subject or body not defined", kind)
+		}
+		r.templates[kind] = t
+	}
+	return nil
+}
+
+// Render renders a notification by the template of its kind.
+func (r *Renderer) Render(n *model.Notification) (*model.Message, error) {
+	t, ok := r.templates[n.Kind]
+	if !ok {
+		return nil, fmt.Errorf("no template of %s notifications", n.Kind)
+	}
+	var subject, body strings.Builder
+	if err := errors.Join(t.ExecuteTemplate(&subject, "subject", n), t.ExecuteTemplate(&body, "body", n)); err != nil {
+		return nil, err
+	}
+	return &model.Message{Subject: strings.TrimSpace(subject.String()), Body: strings.TrimSpace(body.String())}, nil
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"movieapp.com/notification/internal/repository"
+	"movieapp.com/notification/pkg/model"
+	"movieapp.com/pkg/tenant"
+)
+
+// Repository defines a memory notification repository.
+type Repository struct {
+	sync.RWMutex
+	// preferences and watchers are keyed by user and movie with
+	// tenant.Key, so that the data of each tenant is kept apart.
+	preferences map[string]model.Preferences
+	watchers    map[string]map[string]struct{}
+}
+
+// New creates a new memory repository.
+func New() *Repository {
+	return &Repository{preferences: map[string]model.Preferences{}, watchers: map[string]map[string]struct{}{}}
+}
+
+// GetPreferences retrieves the notification preferences of a user.
+func (r *Repository) GetPreferences(ctx context.Context, userID string) (*model.Preferences, error) {
+	r.RLock()
+	defer r.RUnlock()
+	p, ok := r.preferences[tenant.Key(ctx, userID)]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &p, nil
+}
+
+// PutPreferences replaces the notification preferences of a user.
+func (r *Repository) PutPreferences(ctx context.Context, preferences *model.Preferences) error {
+	r.Lock()
+	defer r.Unlock()
+	r.preferences[tenant.Key(ctx, preferences.UserID)] = *preferences
+	return nil
+}
+
+// AddWatcher records that a user has a movie on their watchlist.
+func (r *Repository) AddWatcher(ctx context.Context, movieID string, userID string) error {
+	r.Lock()
+	defer r.Unlock()
+	key := tenant.Key(ctx, movieID)
+	if _, ok := r.watchers[key]; !ok {
+		r.watchers[key] = map[string]struct{}{}
+	}
+	r.watchers[key][userID] = struct{}{}
+	return nil
+}
+
+// RemoveWatcher records that a user no longer has a movie on their
+// watchlist.
+func (r *Repository) RemoveWatcher(ctx context.Context, movieID string, userID string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.watchers[tenant.Key(ctx, movieID)], userID)
+	return nil
+}
+
+// Watchers retrieves the users having a movie on their watchlist.
+func (r *Repository) Watchers(ctx context.Context, movieID string) ([]string, error) {
+	r.RLock()
+	defer r.RUnlock()
+	watchers := r.watchers[tenant.Key(ctx, movieID)]
+	res := make([]string, 0, len(watchers))
+	for id := range watchers {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res, nil
+}
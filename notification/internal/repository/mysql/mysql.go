@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	_ "github.com/go-sql-driver/mysql"
+	"movieapp.com/notification/internal/repository"
+	"movieapp.com/notification/pkg/model"
+	"movieapp.com/pkg/tenant"
+)
+
+// Repository defines a MySQL-based notification repository.
+type Repository struct {
+	db *tenant.DB
+}
+
+// New creates a new MySQL-based repository of the database with
+// the data source name per tenant of the set, as with
+// tenant.OpenDB.
+func New(dsn string, tenants *tenant.Set) (*Repository, error) {
+	db, err := tenant.OpenDB("mysql", dsn, tenants)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{db}, nil
+}
+
+// PingContext checks that the databases are reachable.
+func (r *Repository) PingContext(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the connection pools of the repository.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// GetPreferences retrieves the notification preferences of a user.
+func (r *Repository) GetPreferences(ctx context.Context, userID string) (*model.Preferences, error) {
+	var b []byte
+	err := r.db.QueryRowContext(ctx, "SELECT preferences FROM notification_preferences WHERE user_id = ?", userID).Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var p *model.Preferences
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PutPreferences replaces the notification preferences of a user.
+func (r *Repository) PutPreferences(ctx context.Context, preferences *model.Preferences) error {
+	b, err := json.Marshal(preferences)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO notification_preferences (user_id, preferences) VALUES (?, ?) ON DUPLICATE KEY UPDATE preferences = VALUES(preferences)",
+		preferences.UserID, b)
+	return err
+}
+
+// AddWatcher records that a user has a movie on their watchlist.
+func (r *Repository) AddWatcher(ctx context.Context, movieID string, userID string) error {
+	_, err := r.db.ExecContext(ctx, "INSERT IGNORE INTO notification_watchers (movie_id, user_id) VALUES (?, ?)", movieID, userID)
+	return err
+}
+
+// RemoveWatcher records that a user no longer has a movie on their
+// watchlist.
+func (r *Repository) RemoveWatcher(ctx context.Context, movieID string, userID string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM notification_watchers WHERE movie_id = ? AND user_id = ?", movieID, userID)
+	return err
+}
+
+// Watchers retrieves the users having a movie on their watchlist.
+func (r *Repository) Watchers(ctx context.Context, movieID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id FROM notification_watchers WHERE movie_id = ? ORDER BY user_id", movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, rows.Err()
+}
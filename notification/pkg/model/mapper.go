@@ -0,0 +1,52 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+
+	"movieapp.com/gen"
+)
+
+// PreferencesToProto converts a Preferences struct into a generated
+// proto counterpart, listing the subscriptions by kind.
+func PreferencesToProto(p *Preferences) *gen.NotificationPreferences {
+	res := &gen.NotificationPreferences{
+		UserId:     p.UserID,
+		Email:      p.Email,
+		PushToken:  p.PushToken,
+		WebhookUrl: p.WebhookURL,
+	}
+	for kind, channels := range p.Subscriptions {
+		s := &gen.NotificationSubscription{Kind: string(kind), Channels: []string{}}
+		for _, c := range channels {
+			s.Channels = append(s.Channels, string(c))
+		}
+		res.Subscriptions = append(res.Subscriptions, s)
+	}
+	slices.SortFunc(res.Subscriptions, func(a, b *gen.NotificationSubscription) int {
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	return res
+}
+
+// PreferencesFromProto converts a generated proto counterpart into
+// a Preferences struct.
+func PreferencesFromProto(p *gen.NotificationPreferences) *Preferences {
+	res := &Preferences{
+		UserID:     p.UserId,
+		Email:      p.Email,
+		PushToken:  p.PushToken,
+		WebhookURL: p.WebhookUrl,
+	}
+	if len(p.Subscriptions) > 0 {
+		res.Subscriptions = make(map[Kind][]Channel, len(p.Subscriptions))
+	}
+	for _, s := range p.Subscriptions {
+		channels := []Channel{}
+		for _, c := range s.Channels {
+			channels = append(channels, Channel(c))
+		}
+		res.Subscriptions[Kind(s.Kind)] = channels
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package model
+
+// Kind defines a kind of notification.
+type Kind string
+
+// Existing notification kinds.
+const (
+	// KindWatchlistRating notifies that a movie on the watchlist
+	// of the user got a new rating.
+	KindWatchlistRating = Kind("watchlistRating")
+	// KindWatchlistUpdated notifies that the metadata of a movie
+	// on the watchlist of the user changed.
+	KindWatchlistUpdated = Kind("watchlistUpdated")
+)
+
+// Kinds lists the existing notification kinds.
+var Kinds = []Kind{KindWatchlistRating, KindWatchlistUpdated}
+
+// Channel defines a way notifications are delivered.
+type Channel string
+
+// Existing notification channels.
+const (
+	ChannelEmail   = Channel("email")
+	ChannelPush    = Channel("push")
+	ChannelWebhook = Channel("webhook")
+)
+
+// Channels lists the existing notification channels.
+var Channels = []Channel{ChannelEmail, ChannelPush, ChannelWebhook}
+
+// DefaultChannels defines the channels of the kinds a user has not
+// chosen channels for.
+var DefaultChannels = []Channel{ChannelEmail}
+
+// Preferences defines where and about what a user is notified.
+type Preferences struct {
+	UserID     string `json:"userId"`
+	Email      string `json:"email,omitempty"`
+	PushToken  string `json:"pushToken,omitempty"`
+	WebhookURL string `json:"webhookUrl,omitempty"`
+	// Subscriptions lists the channels of each kind, DefaultChannels
+	// for the kinds left out.
+	Subscriptions map[Kind][]Channel `json:"subscriptions,omitempty"`
+}
+
+// ChannelsOf returns the channels the user is notified of a kind
+// through.
+func (p *Preferences) ChannelsOf(kind Kind) []Channel {
+	if channels, ok := p.Subscriptions[kind]; ok {
+		return channels
+	}
+	return DefaultChannels
+}
+
+// Notification defines a notification of a user about a movie.
+type Notification struct {
+	// ID is unique per notification and stable across redeliveries
+	// of the event it is about, so webhooks can deduplicate.
+	ID      string `json:"id"`
+	Kind    Kind   `json:"kind"`
+	UserID  string `json:"userId"`
+	MovieID string `json:"movieId"`
+	// Title is the title of the movie, empty if unknown.
+	Title string `json:"title,omitempty"`
+	// Rating is the value of the rating of KindWatchlistRating.
+	Rating int `json:"rating,omitempty"`
+}
+
+// Message defines a notification rendered for delivery.
+type Message struct {
+	Subject string
+	Body    string
+}
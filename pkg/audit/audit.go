@@ -0,0 +1,173 @@
+// Package audit records who did what among the admin and write
+// operations of the services, such as metadata edits and API key
+// revocations, in an append-only store queried by the admin APIs
+// for compliance reviews.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"time"
+
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+	"movieapp.com/pkg/tenant"
+)
+
+var logger = logging.New("audit")
+
+// recordCounts counts the entries recorded and those the store
+// failed to append, published at /debug/vars.
+var recordCounts = expvar.NewMap("audit")
+
+// Admin is the actor of the operations requested with the admin
+// token of a service.
+const Admin = "admin"
+
+// Limits of the entries returned by a query.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Entry defines an audited operation.
+type Entry struct {
+	// ID orders the entries of a store, assigned when appended.
+	ID      int64     `json:"id"`
+	Time    time.Time `json:"time"`
+	Service string    `json:"service"`
+	Tenant  string    `json:"tenant"`
+	// Actor is the user who made the operation, Admin for the
+	// requests carrying the admin token.
+	Actor string `json:"actor"`
+	// Action names the operation, such as metadata.delete.
+	Action string `json:"action"`
+	// Resource identifies what the operation changed, such as the
+	// id of a movie.
+	Resource  string            `json:"resource"`
+	RequestID string            `json:"requestId,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// Filter selects the entries returned by a query, newest first.
+// Empty fields match all entries.
+type Filter struct {
+	Service  string    `form:"service"`
+	Actor    string    `form:"actor"`
+	Action   string    `form:"action"`
+	Resource string    `form:"resource"`
+	Since    time.Time `form:"since"`
+	Until    time.Time `form:"until"`
+	// Before pages through the entries: only those with a smaller
+	// id match, 0 for the newest.
+	Before int64 `form:"before" validate:"min=0"`
+	Limit  int   `form:"limit" validate:"min=0,max=1000"`
+}
+
+// Store defines an append-only store of entries.
+type Store interface {
+	// Append appends an entry, setting its id.
+	Append(ctx context.Context, e *Entry) error
+	// Query returns up to filter.Limit entries matching the filter,
+	// DefaultLimit if 0, newest first.
+	Query(ctx context.Context, filter Filter) ([]*Entry, error)
+}
+
+// Logger records the entries of the operations of a service. A nil
+// logger records nothing.
+type Logger struct {
+	service string
+	store   Store
+}
+
+// New creates a logger of the operations of the service appending
+// entries to the store.
+func New(service string, store Store) *Logger {
+	return &Logger{service, store}
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose operations are recorded with the
+// actor, verified by the caller, such as Admin once the admin token
+// of a request is checked.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Record appends an entry of an operation with the time, tenant and
+// request id of the context and, if the entry has no actor, the
+// actor of the context or else its principal. Failures are logged
+// rather than returned, as the operation already happened.
+func (l *Logger) Record(ctx context.Context, e Entry) {
+	if l == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	e.Service = l.service
+	e.Tenant = tenant.FromContext(ctx)
+	e.RequestID = logging.RequestID(ctx)
+	if e.Actor == "" {
+		if actor, ok := ctx.Value(actorKey{}).(string); ok {
+			e.Actor = actor
+		} else if p := auth.FromContext(ctx); p != nil {
+			e.Actor = p.Subject
+		}
+	}
+	if err := l.store.Append(ctx, &e); err != nil {
+		recordCounts.Add("failed", 1)
+		logger.ErrorContext(ctx, "Audit append error", "action", e.Action, "resource", e.Resource, "actor", e.Actor, "error", err)
+		return
+	}
+	recordCounts.Add("recorded", 1)
+}
+
+// Handler serves the entries of the store matching the filter in
+// the query parameters of GET requests, such as
+// ?actor=alice&since=2024-05-01T00:00:00Z, as JSON. Callers check
+// that the requests are authorized.
+func (l *Logger) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			problem.Write(w, req, problem.MethodNotAllowed, "")
+			return
+		}
+		var filter Filter
+		if err := request.Decode(req, &filter); err != nil {
+			problem.Error(w, req, err)
+			return
+		}
+		entries, err := l.store.Query(req.Context(), filter)
+		if err != nil {
+			problem.Error(w, req, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+		}
+	})
+}
+
+// match reports whether an entry matches the filter.
+func (f *Filter) match(e *Entry) bool {
+	return (f.Service == "" || e.Service == f.Service) &&
+		(f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.Resource == "" || e.Resource == f.Resource) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until)) &&
+		(f.Before == 0 || e.ID < f.Before)
+}
+
+func (f *Filter) limit() int {
+	if f.Limit == 0 {
+		return DefaultLimit
+	}
+	return min(f.Limit, MaxLimit)
+}
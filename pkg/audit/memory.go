@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory defines an audit store in process, for tests and
+// deployments without an audit database. Entries are lost on
+// restart.
+type Memory struct {
+	mu      sync.RWMutex
+	entries []*Entry
+}
+
+// NewMemory creates an in-process audit store.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Append appends an entry.
+func (m *Memory) Append(_ context.Context, e *Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.ID = int64(len(m.entries)) + 1
+	c := *e
+	m.entries = append(m.entries, &c)
+	return nil
+}
+
+// Query returns the entries matching the filter, newest first.
+func (m *Memory) Query(_ context.Context, filter Filter) ([]*Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	res := []*Entry{}
+	for i := len(m.entries) - 1; i >= 0 && len(res) < filter.limit(); i-- {
+		if filter.match(m.entries[i]) {
+			c := *m.entries[i]
+			res = append(res, &c)
+		}
+	}
+	return res, nil
+}
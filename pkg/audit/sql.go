@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// DefaultTable is the table of the audit log shared by the
+// services.
+const DefaultTable = "audit_log"
+
+// Schema returns the MySQL statement creating an audit table.
+// Services should only be granted INSERT and SELECT on it, so that
+// entries cannot be changed or deleted.
+func Schema(table string) string {
+	return "CREATE TABLE IF NOT EXISTS " + table + " (id BIGINT AUTO_INCREMENT PRIMARY KEY, recorded_at DATETIME(6), service VARCHAR(64), tenant VARCHAR(32), actor VARCHAR(255), action VARCHAR(64), resource VARCHAR(255), request_id VARCHAR(64), details JSON, INDEX (recorded_at), INDEX (actor, id), INDEX (resource, id))"
+}
+
+// SQL defines an audit store in a MySQL table created by Schema.
+type SQL struct {
+	db    *sql.DB
+	table string
+}
+
+// OpenSQL opens an audit store in the table of the database with
+// the data source name, which must set parseTime=true.
+func OpenSQL(dsn string, table string) (*SQL, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &SQL{db, table}, nil
+}
+
+// Append inserts an entry.
+func (s *SQL) Append(ctx context.Context, e *Entry) error {
+	details, err := json.Marshal(e.Details)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, "INSERT INTO "+s.table+" (recorded_at, service, tenant, actor, action, resource, request_id, details) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		e.Time, e.Service, e.Tenant, e.Actor, e.Action, e.Resource, e.RequestID, details)
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
+
+// Query returns the entries matching the filter, newest first.
+func (s *SQL) Query(ctx context.Context, filter Filter) ([]*Entry, error) {
+	var conds []string
+	var args []any
+	for _, c := range []struct {
+		column string
+		value  string
+	}{{"service", filter.Service}, {"actor", filter.Actor}, {"action", filter.Action}, {"resource", filter.Resource}} {
+		if c.value != "" {
+			conds = append(conds, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !filter.Since.IsZero() {
+		conds = append(conds, "recorded_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, "recorded_at < ?")
+		args = append(args, filter.Until)
+	}
+	if filter.Before != 0 {
+		conds = append(conds, "id < ?")
+		args = append(args, filter.Before)
+	}
+	query := "SELECT id, recorded_at, service, tenant, actor, action, resource, request_id, details FROM " + s.table
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY id DESC LIMIT ?", append(args, filter.limit())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*Entry{}
+	for rows.Next() {
+		e := &Entry{}
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.Time, &e.Service, &e.Tenant, &e.Actor, &e.Action, &e.Resource, &e.RequestID, &details); err != nil {
+			return nil, err
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// PingContext checks that the database is reachable.
+func (s *SQL) PingContext(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the connection pool of the store.
+func (s *SQL) Close() error {
+	return s.db.Close()
+}
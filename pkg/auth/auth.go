@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+)
+
+var logger = logging.New("auth")
+
+// leeway tolerates the clock skew between the issuer and the
+// service when validating the times of a token.
+const leeway = 30 * time.Second
+
+var (
+	// ErrUnauthenticated is returned when a call requiring an
+	// authenticated caller has no valid token.
+	ErrUnauthenticated = problem.Register(errors.New("unauthenticated"), problem.Unauthenticated)
+	// ErrForbidden is returned when the caller lacks a scope or
+	// calls on behalf of another user.
+	ErrForbidden = problem.Register(errors.New("forbidden"), problem.Forbidden)
+)
+
+// Config defines the issuer of the tokens accepted by a service.
+type Config struct {
+	// Issuer is the iss claim of the tokens, an OpenID Connect
+	// issuer whose JWKS endpoint is discovered unless set by
+	// JWKSURL.
+	Issuer  string `yaml:"issuer"`
+	JWKSURL string `yaml:"jwksURL"`
+	// Audience is the aud claim the tokens must include, not
+	// checked if empty.
+	Audience string `yaml:"audience"`
+}
+
+// Enabled reports whether tokens are verified.
+func (c *Config) Enabled() bool {
+	return c.Issuer != "" || c.JWKSURL != ""
+}
+
+// Validate returns an error if the config is enabled without an
+// issuer.
+func (c *Config) Validate() error {
+	if c.Enabled() && c.Issuer == "" {
+		return errors.New("issuer: empty")
+	}
+	return nil
+}
+
+// Principal defines the authenticated caller of a request.
+type Principal struct {
+	// Subject is the id of the user.
+	Subject string
+	Scopes  []string
+}
+
+// HasScope reports whether the principal was granted a scope.
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	// Scope is space separated, as in OAuth 2.0, and Scp a list,
+	// as issued by some providers.
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+}
+
+// Verifier verifies bearer tokens signed by the keys of an issuer.
+type Verifier struct {
+	cfg  Config
+	keys *keySet
+}
+
+// NewVerifier creates a verifier of the tokens of the config. The
+// keys of the issuer are fetched on first use.
+func NewVerifier(cfg Config) *Verifier {
+	return &Verifier{cfg, &keySet{issuer: cfg.Issuer, url: cfg.JWKSURL}}
+}
+
+// Verify returns the principal of a valid token, or an error
+// wrapping ErrUnauthenticated.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(v.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	}, opts...)
+	if err != nil {
+		return nil, errors.Join(ErrUnauthenticated, err)
+	}
+	if c.Subject == "" {
+		return nil, errors.Join(ErrUnauthenticated, errors.New("no subject"))
+	}
+	return &Principal{Subject: c.Subject, Scopes: append(strings.Fields(c.Scope), c.Scp...)}, nil
+}
+
+type contextKey struct{}
+
+// credentials are the verified caller of a request, kept with its
+// token to forward to called services.
+type credentials struct {
+	principal *Principal
+	token     string
+}
+
+// NewContext returns a context of a request verified by a
+// verifier, with its principal and the token it was verified from,
+// or none if anonymous.
+func NewContext(ctx context.Context, p *Principal, token string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &credentials{p, token})
+}
+
+// WithToken returns a context whose calls to other services bear
+// the token, such as a token of the service itself for the calls it
+// makes on its own behalf.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &credentials{FromContext(ctx), token})
+}
+
+// FromContext returns the principal of the context, nil if the
+// request is anonymous or was not verified.
+func FromContext(ctx context.Context) *Principal {
+	c, _ := ctx.Value(contextKey{}).(*credentials)
+	if c == nil {
+		return nil
+	}
+	return c.principal
+}
+
+// Enforced reports whether the request of the context was verified,
+// making identities enforced. Services without a verifier trust
+// the user ids of their requests, as set by the authenticating
+// proxy in front of them.
+func Enforced(ctx context.Context) bool {
+	return ctx.Value(contextKey{}) != nil
+}
+
+func token(ctx context.Context) string {
+	c, _ := ctx.Value(contextKey{}).(*credentials)
+	if c == nil {
+		return ""
+	}
+	return c.token
+}
+
+// RequireScope returns ErrUnauthenticated or ErrForbidden unless
+// the caller was granted the scope. It allows any call that is not
+// enforced.
+func RequireScope(ctx context.Context, scope string) error {
+	if !Enforced(ctx) {
+		return nil
+	}
+	p := FromContext(ctx)
+	if p == nil {
+		return ErrUnauthenticated
+	}
+	if !p.HasScope(scope) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// RequireUser returns ErrUnauthenticated or ErrForbidden unless
+// the caller is the user. It allows any call that is not enforced.
+func RequireUser(ctx context.Context, userID string) error {
+	if !Enforced(ctx) {
+		return nil
+	}
+	p := FromContext(ctx)
+	if p == nil {
+		return ErrUnauthenticated
+	}
+	if p.Subject != userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Status converts an error of RequireScope or RequireUser to a
+// gRPC status.
+func Status(err error) error {
+	if errors.Is(err, ErrUnauthenticated) {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return status.Error(codes.PermissionDenied, err.Error())
+}
+
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// Handler verifies the bearer tokens of the requests to the next
+// handler, failing those with an invalid token with 401, and
+// passes their principal in the context. Requests without a token
+// are anonymous. A nil verifier leaves requests unverified.
+func Handler(next http.Handler, v *Verifier) http.Handler {
+	if v == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var p *Principal
+		token, ok := bearerToken(req.Header.Get("Authorization"))
+		if ok {
+			var err error
+			if p, err = v.Verify(req.Context(), token); err != nil {
+				logger.InfoContext(req.Context(), "Invalid token", "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				problem.Write(w, req, problem.Unauthenticated, "invalid token")
+				return
+			}
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), p, token)))
+	})
+}
+
+// SetHeader sets the bearer token of the context on an outgoing
+// request, so that the called service authorizes the same caller.
+func SetHeader(req *http.Request) {
+	if t := token(req.Context()); t != "" {
+		req.Header.Set("Authorization", "Bearer "+t)
+	}
+}
+
+// UnaryServerInterceptor verifies the bearer tokens in the
+// authorization metadata of incoming gRPC calls like Handler,
+// failing those with an invalid token with Unauthenticated. A nil
+// verifier leaves calls unverified.
+func UnaryServerInterceptor(v *Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := verifyIncoming(ctx, v, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor verifies the bearer tokens of incoming
+// gRPC streams like UnaryServerInterceptor.
+func StreamServerInterceptor(v *Verifier) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := verifyIncoming(ss.Context(), v, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ss, ctx})
+	}
+}
+
+// verifyIncoming returns the context of an incoming gRPC call
+// carrying its verified principal, if any.
+func verifyIncoming(ctx context.Context, v *Verifier, method string) (context.Context, error) {
+	if v == nil {
+		return ctx, nil
+	}
+	var p *Principal
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get("authorization"); len(vals) > 0 {
+			var valid bool
+			if token, valid = bearerToken(vals[0]); !valid {
+				return nil, status.Error(codes.Unauthenticated, "invalid authorization")
+			}
+			var err error
+			if p, err = v.Verify(ctx, token); err != nil {
+				logger.InfoContext(ctx, "Invalid token", "method", method, "error", err)
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+		}
+	}
+	return NewContext(ctx, p, token), nil
+}
+
+// serverStream overrides the context of a gRPC server stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// DialOptions pass the bearer token of the context of the calls and
+// streams of a gRPC client to the called service.
+func DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+		}),
+	}
+}
+
+func outgoingContext(ctx context.Context) context.Context {
+	if t := token(ctx); t != "" {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+t)
+	}
+	return ctx
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keysRefreshInterval bounds how long the keys of the issuer are
+	// cached, so that revoked keys stop being accepted.
+	keysRefreshInterval = time.Hour
+	// minKeysRefreshInterval bounds how often the keys are fetched
+	// again for a token signed by an unknown key, such as one the
+	// issuer rotated in.
+	minKeysRefreshInterval = time.Minute
+)
+
+// ErrUnknownKey is returned for tokens signed by a key missing from
+// the JWKS of the issuer.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+// keySet caches the public keys of a JWKS endpoint by key id. The
+// endpoint is discovered from the issuer if not configured.
+type keySet struct {
+	issuer string
+	url    string
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// key returns the key of an id, fetching the keys if they are stale
+// or, at most every minKeysRefreshInterval, if the id is unknown.
+func (s *keySet) key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	age := time.Since(s.fetchedAt)
+	if k, ok := s.keys[id]; ok && age < keysRefreshInterval {
+		return k, nil
+	}
+	if s.keys == nil || age >= minKeysRefreshInterval {
+		keys, err := s.fetch(ctx)
+		if err != nil && s.keys == nil {
+			return nil, err
+		} else if err == nil {
+			s.keys, s.fetchedAt = keys, time.Now()
+		}
+	}
+	if k, ok := s.keys[id]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+}
+
+func (s *keySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if s.url == "" {
+		url, err := discoverKeys(ctx, s.issuer)
+		if err != nil {
+			return nil, err
+		}
+		s.url = url
+	}
+	return fetchKeys(ctx, s.url)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys fetches the signature keys of a JWKS endpoint, skipping
+// those of unsupported types.
+func fetchKeys(ctx context.Context, url string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, url, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// discoverKeys returns the JWKS endpoint of an OpenID Connect
+// issuer, from its discovery document.
+func discoverKeys(ctx context.Context, issuer string) (string, error) {
+	var doc struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+		return "", err
+	}
+	if doc.JWKSURI == "" {
+		return "", errors.New("no jwks_uri in OpenID configuration")
+	}
+	return doc.JWKSURI, nil
+}
+
+func getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
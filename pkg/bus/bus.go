@@ -0,0 +1,119 @@
+// Package bus defines the publishing and consuming of the events of
+// the services over a message broker, so that they do not depend on
+// the client library of one. Kafka, NATS JetStream and AWS SNS and
+// SQS backends are in the kafka, nats and sqs packages, and an
+// in-process one for tests is provided by Memory.
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/tenant"
+)
+
+// Brokers of the backends.
+const (
+	BrokerKafka = "kafka"
+	BrokerNATS  = "nats"
+	BrokerSQS   = "sqs"
+)
+
+// ValidateBroker returns an error if the broker has no backend.
+func ValidateBroker(broker string) error {
+	switch broker {
+	case BrokerKafka, BrokerNATS, BrokerSQS:
+		return nil
+	case "":
+		return errors.New("empty")
+	}
+	return fmt.Errorf("invalid broker %q", broker)
+}
+
+// Message defines a message of a topic.
+type Message struct {
+	// Key orders the messages of a topic: messages with the same key
+	// are consumed in the order they were published. Brokers without
+	// partitions order all the messages of a topic.
+	Key string
+	// Value is the payload of the message.
+	Value []byte
+	// Headers carry the metadata of the message, such as its type
+	// and trace context.
+	Headers map[string]string
+}
+
+// Handler handles a message consumed from a topic.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Publisher defines a publisher of messages.
+type Publisher interface {
+	// Publish publishes the messages to the topic, returning once
+	// the broker stored them all. Messages without a trace context
+	// or tenant in their headers carry those of the context.
+	Publish(ctx context.Context, topic string, msgs ...Message) error
+}
+
+// Subscriber defines a consumer of messages.
+type Subscriber interface {
+	// Subscribe passes the messages of the topic published from the
+	// first subscription of the group on to the handler, until the
+	// context is canceled or the subscription fails. Subscribers
+	// sharing a group split the messages between them. Messages are
+	// acknowledged once handled, even if the handler fails, so that
+	// they do not hold back those behind them; handlers retry what
+	// they can themselves.
+	Subscribe(ctx context.Context, topic string, group string, handle Handler) error
+}
+
+// Bus defines a connection to a broker publishing and consuming
+// messages.
+type Bus interface {
+	Publisher
+	Subscriber
+	// Close flushes pending messages and closes the connection.
+	Close() error
+}
+
+// TenantHeader carries the tenant of a message, that of the context
+// it was published in.
+const TenantHeader = "tenant"
+
+// Headers returns the headers of a message being published with the
+// trace context and the tenant of the context, unless they carry
+// them, for the backends.
+func Headers(ctx context.Context, headers map[string]string) map[string]string {
+	carrier := tracing.Carrier(ctx)
+	for k := range carrier {
+		if _, ok := headers[k]; ok {
+			carrier = nil
+			break
+		}
+	}
+	if id := tenant.FromContext(ctx); id != tenant.Default {
+		if _, ok := headers[TenantHeader]; !ok {
+			if carrier == nil {
+				carrier = make(map[string]string, len(headers)+1)
+			}
+			carrier[TenantHeader] = id
+		}
+	}
+	if carrier == nil {
+		return headers
+	}
+	for k, v := range headers {
+		carrier[k] = v
+	}
+	return carrier
+}
+
+// NewContext returns the context of handling a message, carrying its
+// tenant, for the backends.
+func NewContext(ctx context.Context, msg *Message) context.Context {
+	if id := msg.Headers[TenantHeader]; id != "" {
+		return tenant.NewContext(ctx, id)
+	}
+	return ctx
+}
@@ -0,0 +1,94 @@
+// Package kafka provides a Kafka backend of the bus.
+package kafka
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("bus/kafka")
+
+// Bus publishes and consumes messages over Kafka. Messages are keyed
+// by their key, so the messages with the same key land on the same
+// partition and are consumed in order.
+type Bus struct {
+	brokers []string
+	creds   *kafkautil.Credentials
+	writer  *kafka.Writer
+}
+
+// New creates a bus over the brokers. Connections authenticate with
+// the credentials unless nil.
+func New(brokers []string, creds *kafkautil.Credentials) *Bus {
+	return &Bus{brokers, creds, &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Transport:    creds.Transport(),
+	}}
+}
+
+// Publish writes the messages to the topic.
+func (b *Bus) Publish(ctx context.Context, topic string, msgs ...bus.Message) error {
+	kmsgs := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		headers := bus.Headers(ctx, msg.Headers)
+		kmsg := kafka.Message{
+			Topic:   topic,
+			Key:     []byte(msg.Key),
+			Value:   msg.Value,
+			Headers: make([]kafka.Header, 0, len(headers)),
+		}
+		for k, v := range headers {
+			kmsg.Headers = append(kmsg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		kmsgs = append(kmsgs, kmsg)
+	}
+	return b.writer.WriteMessages(ctx, kmsgs...)
+}
+
+// Subscribe reads the messages of the topic in the consumer group,
+// committing their offsets once handled.
+func (b *Bus) Subscribe(ctx context.Context, topic string, group string, handle bus.Handler) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     b.brokers,
+		Topic:       topic,
+		GroupID:     group,
+		StartOffset: kafka.LastOffset,
+		Dialer:      b.creds.Dialer(),
+	})
+	defer reader.Close()
+	for {
+		kmsg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		msg := &bus.Message{Key: string(kmsg.Key), Value: kmsg.Value, Headers: make(map[string]string, len(kmsg.Headers))}
+		for _, h := range kmsg.Headers {
+			msg.Headers[h.Key] = string(h.Value)
+		}
+		spanCtx, span := tracing.StartConsumer(ctx, semconv.MessagingSystemKafka, topic, msg.Headers)
+		if err := handle(bus.NewContext(spanCtx, msg), msg); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err := reader.CommitMessages(ctx, kmsg); err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "Message commit error", "topic", topic, "error", err)
+		}
+	}
+}
+
+// Close flushes pending writes and closes the bus.
+func (b *Bus) Close() error {
+	return b.writer.Close()
+}
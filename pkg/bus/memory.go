@@ -0,0 +1,75 @@
+package bus
+
+import (
+	"context"
+	"sync"
+
+	"movieapp.com/internal/tracing"
+)
+
+// memoryBuffer is the number of messages of a group buffered before
+// publishing blocks.
+const memoryBuffer = 64
+
+// Memory passes messages between publishers and subscribers in
+// process, for tests. Messages published to a topic before a group
+// subscribed to it are dropped.
+type Memory struct {
+	mu     sync.Mutex
+	groups map[string]map[string]chan Message
+}
+
+// NewMemory creates an in-process bus.
+func NewMemory() *Memory {
+	return &Memory{groups: map[string]map[string]chan Message{}}
+}
+
+// Publish passes the messages to each group subscribed to the topic,
+// blocking while one has memoryBuffer messages pending.
+func (m *Memory) Publish(ctx context.Context, topic string, msgs ...Message) error {
+	m.mu.Lock()
+	groups := make([]chan Message, 0, len(m.groups[topic]))
+	for _, ch := range m.groups[topic] {
+		groups = append(groups, ch)
+	}
+	m.mu.Unlock()
+	for _, msg := range msgs {
+		msg.Headers = Headers(ctx, msg.Headers)
+		for _, ch := range groups {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ch <- msg:
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe passes the messages of the topic to the handler until the
+// context is canceled.
+func (m *Memory) Subscribe(ctx context.Context, topic string, group string, handle Handler) error {
+	m.mu.Lock()
+	if m.groups[topic] == nil {
+		m.groups[topic] = map[string]chan Message{}
+	}
+	ch, ok := m.groups[topic][group]
+	if !ok {
+		ch = make(chan Message, memoryBuffer)
+		m.groups[topic][group] = ch
+	}
+	m.mu.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-ch:
+			_ = handle(NewContext(tracing.FromCarrier(ctx, msg.Headers), &msg), &msg)
+		}
+	}
+}
+
+// Close does nothing, as there is nothing to flush.
+func (m *Memory) Close() error {
+	return nil
+}
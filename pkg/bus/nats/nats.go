@@ -0,0 +1,176 @@
+// Package nats provides a NATS JetStream backend of the bus.
+package nats
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("bus/nats")
+
+// retention is the time messages are kept in the streams, as the
+// default retention of Kafka topics.
+const retention = 7 * 24 * time.Hour
+
+// keyHeader carries the key of a message, as JetStream messages have
+// none.
+const keyHeader = "Bus-Key"
+
+var messagingSystem = semconv.MessagingSystemKey.String("nats")
+
+// Bus publishes and consumes messages over NATS JetStream. Each topic
+// is the subject of a stream of the same name, created on first use,
+// so all the messages of a topic are ordered and consumed in order,
+// unless several subscribers share a group.
+type Bus struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+
+	mu      sync.Mutex
+	streams map[string]bool
+}
+
+// New connects to the NATS servers at the comma separated URLs.
+func New(url string) (*Bus, error) {
+	conn, err := nats.Connect(url, nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Bus{conn: conn, js: js, streams: map[string]bool{}}, nil
+}
+
+// Publish stores the messages in the stream of the topic.
+func (b *Bus) Publish(ctx context.Context, topic string, msgs ...bus.Message) error {
+	if err := b.ensureStream(ctx, topic); err != nil {
+		return err
+	}
+	futures := make([]jetstream.PubAckFuture, 0, len(msgs))
+	for _, msg := range msgs {
+		nmsg := &nats.Msg{Subject: topic, Data: msg.Value, Header: nats.Header{}}
+		for k, v := range bus.Headers(ctx, msg.Headers) {
+			nmsg.Header[k] = []string{v}
+		}
+		if msg.Key != "" {
+			nmsg.Header[keyHeader] = []string{msg.Key}
+		}
+		f, err := b.js.PublishMsgAsync(nmsg)
+		if err != nil {
+			return err
+		}
+		futures = append(futures, f)
+	}
+	for _, f := range futures {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.Ok():
+		case err := <-f.Err():
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe consumes the messages of the topic with a durable
+// consumer named after the group, acknowledging them once handled.
+func (b *Bus) Subscribe(ctx context.Context, topic string, group string, handle bus.Handler) error {
+	if err := b.ensureStream(ctx, topic); err != nil {
+		return err
+	}
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, streamName(topic), jetstream.ConsumerConfig{
+		Durable:       streamName(group),
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return err
+	}
+	iter, err := consumer.Messages()
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, iter.Stop)
+	defer stop()
+	for {
+		nmsg, err := iter.Next()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return nil
+			}
+			return err
+		}
+		msg := &bus.Message{Value: nmsg.Data(), Headers: make(map[string]string, len(nmsg.Headers()))}
+		for k, v := range nmsg.Headers() {
+			if len(v) == 0 {
+				continue
+			}
+			if k == keyHeader {
+				msg.Key = v[0]
+			} else {
+				msg.Headers[k] = v[0]
+			}
+		}
+		spanCtx, span := tracing.StartConsumer(ctx, messagingSystem, topic, msg.Headers)
+		if err := handle(bus.NewContext(spanCtx, msg), msg); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err := nmsg.Ack(); err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "Message ack error", "topic", topic, "error", err)
+		}
+	}
+}
+
+// Check returns an error unless connected, for the readiness checks.
+func (b *Bus) Check(context.Context) error {
+	if status := b.conn.Status(); status != nats.CONNECTED {
+		return errors.New("not connected: " + status.String())
+	}
+	return nil
+}
+
+// Close flushes pending messages and closes the connection.
+func (b *Bus) Close() error {
+	return b.conn.Drain()
+}
+
+// ensureStream creates the stream of a topic unless it was by this
+// bus.
+func (b *Bus) ensureStream(ctx context.Context, topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.streams[topic] {
+		return nil
+	}
+	if _, err := b.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     streamName(topic),
+		Subjects: []string{topic},
+		MaxAge:   retention,
+	}); err != nil {
+		return err
+	}
+	b.streams[topic] = true
+	return nil
+}
+
+// streamName returns the name of a stream or consumer, which may not
+// contain the separators and wildcards of subjects.
+func streamName(name string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(name)
+}
@@ -0,0 +1,365 @@
+// Package sqs provides an AWS SNS and SQS backend of the bus, for
+// deployments without Kafka.
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("bus/sqs")
+
+// Limits of SNS and SQS.
+const (
+	maxBatch         = 10
+	maxWait          = 20 * time.Second
+	maxVisibility    = 12 * time.Hour
+	maxNameLength    = 80
+	receiveRetryWait = time.Second
+)
+
+// keyAttribute carries the key of a message, as SNS messages have
+// none.
+const keyAttribute = "Bus-Key"
+
+// Config defines the SNS topics and SQS queues of a bus.
+type Config struct {
+	// Endpoint selects an SNS and SQS compatible endpoint, such as
+	// LocalStack, AWS if empty.
+	Endpoint string `yaml:"endpoint"`
+	// FIFO uses FIFO topics and queues, so that messages with the
+	// same key are consumed in order and deduplicated by content for
+	// 5 minutes. Standard ones may deliver messages out of order.
+	FIFO bool `yaml:"fifo"`
+	// VisibilityTimeout is the time a received message is hidden
+	// from the other subscribers of its group, extended while it is
+	// handled, after which it is received again if the subscriber
+	// went away.
+	VisibilityTimeout time.Duration `yaml:"visibilityTimeout"`
+}
+
+// DefaultConfig returns the default config, of standard topics and
+// queues.
+func DefaultConfig() Config {
+	return Config{VisibilityTimeout: 30 * time.Second}
+}
+
+// Validate returns an error if the visibility timeout is not a whole
+// number of seconds up to 12 hours.
+func (c *Config) Validate() error {
+	if c.VisibilityTimeout < time.Second || c.VisibilityTimeout > maxVisibility || c.VisibilityTimeout%time.Second != 0 {
+		return errors.New("visibilityTimeout: not whole seconds from 1s to 12h")
+	}
+	return nil
+}
+
+// Bus publishes messages to SNS topics named after their topics and
+// consumes them from SQS queues subscribed to the topics, one per
+// group, named after the group and the topic. Topics, queues and
+// subscriptions are created on first use. Values must be UTF-8 text,
+// such as JSON.
+type Bus struct {
+	cfg Config
+	sns *sns.Client
+	sqs *awssqs.Client
+
+	mu     sync.Mutex
+	topics map[string]string
+}
+
+// New creates a bus using the default AWS configuration.
+func New(ctx context.Context, cfg Config) (*Bus, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Bus{
+		cfg: cfg,
+		sns: sns.NewFromConfig(awsCfg, func(o *sns.Options) {
+			if cfg.Endpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.Endpoint)
+			}
+		}),
+		sqs: awssqs.NewFromConfig(awsCfg, func(o *awssqs.Options) {
+			if cfg.Endpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.Endpoint)
+			}
+		}),
+		topics: map[string]string{},
+	}, nil
+}
+
+// Publish publishes the messages to the SNS topic of the topic, in
+// batches of up to 10. Messages of FIFO topics are grouped by key,
+// or all together if none.
+func (b *Bus) Publish(ctx context.Context, topic string, msgs ...bus.Message) error {
+	arn, err := b.topicARN(ctx, topic)
+	if err != nil {
+		return err
+	}
+	for len(msgs) > 0 {
+		batch := msgs[:min(len(msgs), maxBatch)]
+		msgs = msgs[len(batch):]
+		entries := make([]snstypes.PublishBatchRequestEntry, 0, len(batch))
+		for i, msg := range batch {
+			if !utf8.Valid(msg.Value) {
+				return errors.New("message value not UTF-8 text")
+			}
+			attrs := map[string]snstypes.MessageAttributeValue{}
+			for k, v := range bus.Headers(ctx, msg.Headers) {
+				if v != "" {
+					attrs[k] = snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}
+				}
+			}
+			if msg.Key != "" {
+				attrs[keyAttribute] = snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(msg.Key)}
+			}
+			entry := snstypes.PublishBatchRequestEntry{
+				Id:                aws.String(strconv.Itoa(i)),
+				Message:           aws.String(string(msg.Value)),
+				MessageAttributes: attrs,
+			}
+			if b.cfg.FIFO {
+				group := msg.Key
+				if group == "" {
+					group = topic
+				}
+				entry.MessageGroupId = aws.String(group)
+			}
+			entries = append(entries, entry)
+		}
+		out, err := b.sns.PublishBatch(ctx, &sns.PublishBatchInput{TopicArn: aws.String(arn), PublishBatchRequestEntries: entries})
+		if err != nil {
+			return err
+		}
+		if len(out.Failed) > 0 {
+			f := out.Failed[0]
+			return fmt.Errorf("%d of %d messages not published: %s: %s", len(out.Failed), len(entries), aws.ToString(f.Code), aws.ToString(f.Message))
+		}
+	}
+	return nil
+}
+
+// Subscribe long polls the SQS queue of the group for the messages of
+// the topic, deleting them once handled. The visibility of received
+// messages is extended while they wait and are handled, so that
+// slow handlers do not get them received again by others.
+func (b *Bus) Subscribe(ctx context.Context, topic string, group string, handle bus.Handler) error {
+	queueURL, err := b.queue(ctx, topic, group)
+	if err != nil {
+		return err
+	}
+	for {
+		out, err := b.sqs.ReceiveMessage(ctx, &awssqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(queueURL),
+			MaxNumberOfMessages:   maxBatch,
+			MessageAttributeNames: []string{"All"},
+			VisibilityTimeout:     int32(b.cfg.VisibilityTimeout / time.Second),
+			WaitTimeSeconds:       int32(maxWait / time.Second),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.ErrorContext(ctx, "Message receive error", "topic", topic, "error", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(receiveRetryWait):
+			}
+			continue
+		}
+		if len(out.Messages) > 0 {
+			b.handleBatch(ctx, topic, queueURL, out.Messages, handle)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// handleBatch handles received messages in order, extending the
+// visibility of those not deleted yet every half visibility timeout.
+// Messages left when the context is canceled become visible again
+// once their timeout expires.
+func (b *Bus) handleBatch(ctx context.Context, topic string, queueURL string, msgs []sqstypes.Message, handle bus.Handler) {
+	var mu sync.Mutex
+	pending := make(map[string]string, len(msgs))
+	for _, m := range msgs {
+		pending[aws.ToString(m.MessageId)] = aws.ToString(m.ReceiptHandle)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(b.cfg.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			mu.Lock()
+			entries := make([]sqstypes.ChangeMessageVisibilityBatchRequestEntry, 0, len(pending))
+			for id, receipt := range pending {
+				entries = append(entries, sqstypes.ChangeMessageVisibilityBatchRequestEntry{
+					Id:                aws.String(id),
+					ReceiptHandle:     aws.String(receipt),
+					VisibilityTimeout: int32(b.cfg.VisibilityTimeout / time.Second),
+				})
+			}
+			mu.Unlock()
+			if len(entries) == 0 {
+				continue
+			}
+			if _, err := b.sqs.ChangeMessageVisibilityBatch(ctx, &awssqs.ChangeMessageVisibilityBatchInput{QueueUrl: aws.String(queueURL), Entries: entries}); err != nil && ctx.Err() == nil {
+				logger.ErrorContext(ctx, "Message visibility error", "topic", topic, "error", err)
+			}
+		}
+	}()
+	for _, m := range msgs {
+		if ctx.Err() != nil {
+			return
+		}
+		msg := &bus.Message{Value: []byte(aws.ToString(m.Body)), Headers: make(map[string]string, len(m.MessageAttributes))}
+		for k, v := range m.MessageAttributes {
+			if k == keyAttribute {
+				msg.Key = aws.ToString(v.StringValue)
+			} else {
+				msg.Headers[k] = aws.ToString(v.StringValue)
+			}
+		}
+		spanCtx, span := tracing.StartConsumer(ctx, semconv.MessagingSystemAWSSqs, topic, msg.Headers)
+		if err := handle(bus.NewContext(spanCtx, msg), msg); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		mu.Lock()
+		delete(pending, aws.ToString(m.MessageId))
+		mu.Unlock()
+		if _, err := b.sqs.DeleteMessage(ctx, &awssqs.DeleteMessageInput{QueueUrl: aws.String(queueURL), ReceiptHandle: m.ReceiptHandle}); err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "Message delete error", "topic", topic, "error", err)
+		}
+	}
+}
+
+// Close does nothing, as messages are published synchronously.
+func (b *Bus) Close() error {
+	return nil
+}
+
+// topicARN returns the ARN of the SNS topic of a topic, creating it
+// unless it was by this bus.
+func (b *Bus) topicARN(ctx context.Context, topic string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if arn, ok := b.topics[topic]; ok {
+		return arn, nil
+	}
+	in := &sns.CreateTopicInput{Name: aws.String(b.name(topic))}
+	if b.cfg.FIFO {
+		in.Attributes = map[string]string{"FifoTopic": "true", "ContentBasedDeduplication": "true"}
+	}
+	out, err := b.sns.CreateTopic(ctx, in)
+	if err != nil {
+		return "", err
+	}
+	arn := aws.ToString(out.TopicArn)
+	b.topics[topic] = arn
+	return arn, nil
+}
+
+// queue creates the SQS queue of a group subscribed to the SNS topic
+// of a topic unless it exists, returning its URL.
+func (b *Bus) queue(ctx context.Context, topic string, group string) (string, error) {
+	topicARN, err := b.topicARN(ctx, topic)
+	if err != nil {
+		return "", err
+	}
+	attrs := map[string]string{
+		string(sqstypes.QueueAttributeNameVisibilityTimeout): strconv.Itoa(int(b.cfg.VisibilityTimeout / time.Second)),
+	}
+	if b.cfg.FIFO {
+		attrs[string(sqstypes.QueueAttributeNameFifoQueue)] = "true"
+		attrs[string(sqstypes.QueueAttributeNameContentBasedDeduplication)] = "true"
+	}
+	created, err := b.sqs.CreateQueue(ctx, &awssqs.CreateQueueInput{QueueName: aws.String(b.name(group + "-" + topic)), Attributes: attrs})
+	if err != nil {
+		return "", err
+	}
+	queueURL := aws.ToString(created.QueueUrl)
+	got, err := b.sqs.GetQueueAttributes(ctx, &awssqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		return "", err
+	}
+	queueARN := got.Attributes[string(sqstypes.QueueAttributeNameQueueArn)]
+	// The topic may only deliver to the queue if allowed to.
+	policy, err := json.Marshal(map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "sns.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueARN,
+			"Condition": map[string]any{"ArnEquals": map[string]string{"aws:SourceArn": topicARN}},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := b.sqs.SetQueueAttributes(ctx, &awssqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: map[string]string{string(sqstypes.QueueAttributeNamePolicy): string(policy)},
+	}); err != nil {
+		return "", err
+	}
+	// Raw delivery passes messages and their attributes as published
+	// instead of wrapped in an SNS notification.
+	if _, err := b.sns.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn:   aws.String(topicARN),
+		Protocol:   aws.String("sqs"),
+		Endpoint:   aws.String(queueARN),
+		Attributes: map[string]string{"RawMessageDelivery": "true"},
+	}); err != nil {
+		return "", err
+	}
+	return queueURL, nil
+}
+
+// name returns the name of a topic or queue, made of up to 80
+// letters, digits, hyphens and underscores, with the suffix .fifo if
+// FIFO.
+func (b *Bus) name(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if b.cfg.FIFO {
+		return name[:min(len(name), maxNameLength-len(".fifo"))] + ".fifo"
+	}
+	return name[:min(len(name), maxNameLength)]
+}
@@ -0,0 +1,305 @@
+// Package cache provides typed caches of values encoded into a
+// store of bytes, either in process as with memory.Store or shared
+// by all instances of a service in Redis, with read-through loads
+// coalesced across concurrent callers.
+package cache
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/tenant"
+)
+
+var logger = logging.New("cache")
+
+// loadCounts counts the loads of missed values by cache prefix and
+// whether they loaded the value or joined a concurrent load,
+// published at /debug/vars.
+var loadCounts = expvar.NewMap("cache_loads")
+
+// ErrMiss is returned when a key is not cached.
+var ErrMiss = errors.New("cache miss")
+
+// Store defines the storage of the encoded values of a cache.
+type Store interface {
+	// Get returns the value of a key or ErrMiss if not set.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set sets the value of a key expiring after the ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete deletes a key.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config defines a cache of values of type V.
+type Config[V any] struct {
+	// Prefix prefixes the keys of the values in the store, so that
+	// caches can share a store, such as movie:details:.
+	Prefix string
+	// TTL is the time values are cached for.
+	TTL time.Duration
+	// Jitter spreads the expiration of values cached at once by
+	// shortening their TTL by up to this fraction of it, so that
+	// they are not all loaded again at once.
+	Jitter float64
+	// Cacheable reports whether a loaded value is cached, nil to
+	// cache all values.
+	Cacheable func(V) bool
+}
+
+// Cache defines a cache of values of type V encoded by a codec.
+// Values are decoded on every read, so that callers never share
+// them, and kept apart per tenant of the context. Failures of the
+// store are logged and treated as misses.
+type Cache[V any] struct {
+	store Store
+	codec Codec[V]
+	cfg   Config[V]
+	loads singleflight.Group
+	// ttlNanos is the TTL of the config, changed by SetTTL.
+	ttlNanos atomic.Int64
+
+	mu sync.Mutex
+	// loading holds the keys being loaded, whose generation changes
+	// on deletion so that values loaded before a change are not
+	// cached. Keys are dropped once no load of them is in flight.
+	loading map[string]*inflight
+	// gens numbers the generations, so that a key loaded again once
+	// dropped never gets a generation it had before.
+	gens uint64
+}
+
+// inflight defines the loads in flight of a key.
+type inflight struct {
+	gen   uint64
+	loads int
+}
+
+// New creates a cache of values stored in the store.
+func New[V any](store Store, codec Codec[V], cfg Config[V]) *Cache[V] {
+	c := &Cache[V]{store: store, codec: codec, cfg: cfg, loading: map[string]*inflight{}}
+	c.ttlNanos.Store(int64(cfg.TTL))
+	return c
+}
+
+// SetTTL changes the time values cached from now on are cached for,
+// such as when the config of a service is reloaded.
+func (c *Cache[V]) SetTTL(ttl time.Duration) {
+	c.ttlNanos.Store(int64(ttl))
+}
+
+// Get returns the cached value of a key or ErrMiss.
+func (c *Cache[V]) Get(ctx context.Context, key string) (V, error) {
+	var zero V
+	b, err := c.store.Get(ctx, c.storeKey(ctx, key))
+	if err != nil {
+		return zero, err
+	}
+	v, err := c.codec.Decode(b)
+	if err != nil {
+		return zero, err
+	}
+	return v, nil
+}
+
+// Set caches the value of a key.
+func (c *Cache[V]) Set(ctx context.Context, key string, v V) error {
+	b, err := c.codec.Encode(v)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, c.storeKey(ctx, key), b, c.ttl())
+}
+
+// Delete drops the cached value of a key after it changed. Values
+// being loaded then are not cached.
+func (c *Cache[V]) Delete(ctx context.Context, key string) error {
+	scoped := tenant.Key(ctx, key)
+	c.mu.Lock()
+	if f, ok := c.loading[scoped]; ok {
+		c.gens++
+		f.gen = c.gens
+	}
+	c.mu.Unlock()
+	// Later loads start anew instead of joining one that may
+	// return the previous value.
+	c.loads.Forget(scoped)
+	return c.store.Delete(ctx, c.storeKey(ctx, key))
+}
+
+// GetOrLoad returns the cached value of a key, or loads and caches
+// it on a miss. Concurrent misses of the same key share a single
+// load, which does not fail because the caller starting it goes
+// away, but keeps to its deadline. Each caller gets its own copy of
+// the loaded value.
+func (c *Cache[V]) GetOrLoad(ctx context.Context, key string, load func(context.Context) (V, error)) (V, error) {
+	var zero V
+	if v, ok := c.lookup(ctx, key); ok {
+		return v, nil
+	}
+	leader := false
+	ch := c.loads.DoChan(tenant.Key(ctx, key), func() (any, error) {
+		leader = true
+		gen := c.begin(ctx, key)
+		defer c.end(ctx, key)
+		deadline, hasDeadline := ctx.Deadline()
+		ctx := context.WithoutCancel(ctx)
+		if hasDeadline {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b, err := c.codec.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		c.fill(ctx, key, v, b, gen)
+		return b, nil
+	})
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if leader {
+			loadCounts.Add(c.cfg.Prefix+"loaded", 1)
+		} else {
+			loadCounts.Add(c.cfg.Prefix+"coalesced", 1)
+		}
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return c.codec.Decode(res.Val.([]byte))
+	}
+}
+
+// GetOrLoadMany returns the values of several keys by key, loading
+// the uncached ones together. Keys loaded without a value are left
+// out. Batch loads are not shared between callers.
+func (c *Cache[V]) GetOrLoadMany(ctx context.Context, keys []string, load func(ctx context.Context, keys []string) (map[string]V, error)) (map[string]V, error) {
+	res := make(map[string]V, len(keys))
+	var missing []string
+	for _, key := range keys {
+		if v, ok := c.lookup(ctx, key); ok {
+			res[key] = v
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+	gens := make(map[string]uint64, len(missing))
+	for _, key := range missing {
+		gens[key] = c.begin(ctx, key)
+		defer c.end(ctx, key)
+	}
+	loaded, err := load(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for key, v := range loaded {
+		res[key] = v
+		gen, ok := gens[key]
+		if !ok {
+			continue
+		}
+		b, err := c.codec.Encode(v)
+		if err != nil {
+			logger.ErrorContext(ctx, "Cache encode error", "prefix", c.cfg.Prefix, "error", err)
+			continue
+		}
+		c.fill(ctx, key, v, b, gen)
+	}
+	return res, nil
+}
+
+// lookup returns the cached value of a key and whether it is
+// cached, logging failures of the store.
+func (c *Cache[V]) lookup(ctx context.Context, key string) (V, bool) {
+	v, err := c.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, ErrMiss) {
+			logger.ErrorContext(ctx, "Cache get error", "prefix", c.cfg.Prefix, "error", err)
+		}
+		return v, false
+	}
+	return v, true
+}
+
+// fill caches a value loaded at the generation of its key, encoded
+// as b, unless the key was deleted since or the value is not
+// cacheable.
+func (c *Cache[V]) fill(ctx context.Context, key string, v V, b []byte, gen uint64) {
+	if c.cfg.Cacheable != nil && !c.cfg.Cacheable(v) {
+		return
+	}
+	if c.generation(ctx, key) != gen {
+		return
+	}
+	if err := c.store.Set(ctx, c.storeKey(ctx, key), b, c.ttl()); err != nil {
+		logger.ErrorContext(ctx, "Cache set error", "prefix", c.cfg.Prefix, "error", err)
+	}
+}
+
+// begin registers a load of a key, returning the generation of
+// the key, which must be released with end.
+func (c *Cache[V]) begin(ctx context.Context, key string) uint64 {
+	scoped := tenant.Key(ctx, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.loading[scoped]
+	if !ok {
+		c.gens++
+		f = &inflight{gen: c.gens}
+		c.loading[scoped] = f
+	}
+	f.loads++
+	return f.gen
+}
+
+// end releases a load of a key, dropping the key once no load of it
+// is in flight.
+func (c *Cache[V]) end(ctx context.Context, key string) {
+	scoped := tenant.Key(ctx, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := c.loading[scoped]
+	f.loads--
+	if f.loads == 0 {
+		delete(c.loading, scoped)
+	}
+}
+
+// generation returns the generation of a key being loaded.
+func (c *Cache[V]) generation(ctx context.Context, key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loading[tenant.Key(ctx, key)].gen
+}
+
+// storeKey returns the key of a value in the store, prefixed by the
+// cache prefix and scoped to the tenant of the context.
+func (c *Cache[V]) storeKey(ctx context.Context, key string) string {
+	return tenant.Key(ctx, c.cfg.Prefix+key)
+}
+
+// ttl returns the TTL of a value being cached, shortened by up to
+// the jitter.
+func (c *Cache[V]) ttl() time.Duration {
+	ttl := time.Duration(c.ttlNanos.Load())
+	if c.cfg.Jitter <= 0 {
+		return ttl
+	}
+	return ttl - time.Duration(rand.Float64()*c.cfg.Jitter*float64(ttl))
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec defines the encoding of the values of a cache.
+type Codec[V any] interface {
+	Encode(V) ([]byte, error)
+	Decode([]byte) (V, error)
+}
+
+// JSON returns a codec encoding values as JSON.
+func JSON[V any]() Codec[V] {
+	return jsonCodec[V]{}
+}
+
+type jsonCodec[V any] struct{}
+
+func (jsonCodec[V]) Encode(v V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec[V]) Decode(b []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// Proto returns a codec encoding protobuf messages of type *T in
+// their binary format.
+func Proto[T any, M interface {
+	*T
+	proto.Message
+}]() Codec[M] {
+	return protoCodec[T, M]{}
+}
+
+type protoCodec[T any, M interface {
+	*T
+	proto.Message
+}] struct{}
+
+func (protoCodec[T, M]) Encode(m M) ([]byte, error) {
+	return proto.Marshal(m)
+}
+
+func (protoCodec[T, M]) Decode(b []byte) (M, error) {
+	m := M(new(T))
+	if err := proto.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,273 @@
+// Package memory provides in-process least recently used caches
+// bounded by their number of entries and their size in bytes, for
+// the in-process tiers of the caches of the services.
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"movieapp.com/pkg/cache"
+	"movieapp.com/pkg/telemetry"
+)
+
+// caches collects the entries, bytes, hits, misses and evictions of
+// every named cache as Prometheus metrics labeled by its name.
+var caches = &collector{stats: map[string]func() stats{}}
+
+func init() {
+	telemetry.Register(caches)
+}
+
+// Config defines the bounds of a cache of values of type V by keys
+// of type K. Caches without bounds grow until their values expire.
+type Config[K comparable, V any] struct {
+	// Name labels the metrics of the cache, such as
+	// metadata-reads, none if empty.
+	Name string
+	// MaxEntries bounds the number of entries, 0 for no bound.
+	MaxEntries int
+	// MaxBytes bounds the total size of the entries as returned by
+	// Size, 0 for no bound.
+	MaxBytes int64
+	// Size returns the size of an entry in bytes, required by
+	// MaxBytes.
+	Size func(K, V) int64
+	// OnEvict is called with the entries evicted to make room for
+	// others or expired, but not with those removed or replaced.
+	// It is called with the lock of the cache held and must not call
+	// the cache.
+	OnEvict func(K, V)
+}
+
+// LRU caches values in process, evicting the least recently used
+// entries once a bound is reached. It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	cfg Config[K, V]
+
+	mu        sync.Mutex
+	order     *list.List
+	entries   map[K]*list.Element
+	bytes     int64
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	size      int64
+	expiresAt time.Time
+}
+
+// New creates a cache bounded by the config, labeling its metrics
+// with the name of the config. A cache replaces the metrics of the
+// one created before it under the same name.
+func New[K comparable, V any](cfg Config[K, V]) *LRU[K, V] {
+	l := &LRU[K, V]{cfg: cfg, order: list.New(), entries: map[K]*list.Element{}}
+	if cfg.Name != "" {
+		caches.add(cfg.Name, l.stats)
+	}
+	return l
+}
+
+// Get returns the value of a key and whether it is cached and not
+// expired, marking it as the most recently used.
+func (l *LRU[K, V]) Get(key K) (V, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.entries[key]
+	if ok {
+		e := el.Value.(*entry[K, V])
+		if e.expiresAt.IsZero() || time.Now().Before(e.expiresAt) {
+			l.order.MoveToFront(el)
+			l.hits++
+			return e.value, true
+		}
+		l.evict(el)
+	}
+	l.misses++
+	var zero V
+	return zero, false
+}
+
+// Peek returns the value of a key and whether it is cached and not
+// expired like Get, without marking it as used or counting it in
+// the stats.
+func (l *LRU[K, V]) Peek(key K) (V, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		if e.expiresAt.IsZero() || time.Now().Before(e.expiresAt) {
+			return e.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Add caches the value of a key expiring after the ttl, or never
+// if 0, evicting the least recently used entries once over a
+// bound. Values larger than MaxBytes are not cached.
+func (l *LRU[K, V]) Add(key K, value V, ttl time.Duration) {
+	var size int64
+	if l.cfg.Size != nil {
+		size = l.cfg.Size(key, value)
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[key]; ok {
+		l.remove(el)
+	}
+	if l.cfg.MaxBytes > 0 && size > l.cfg.MaxBytes {
+		return
+	}
+	l.entries[key] = l.order.PushFront(&entry[K, V]{key, value, size, expiresAt})
+	l.bytes += size
+	for l.over() {
+		l.evict(l.order.Back())
+	}
+}
+
+// Remove removes the value of a key.
+func (l *LRU[K, V]) Remove(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[key]; ok {
+		l.remove(el)
+	}
+}
+
+// Len returns the number of cached entries, including expired ones
+// not evicted yet.
+func (l *LRU[K, V]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+func (l *LRU[K, V]) over() bool {
+	return (l.cfg.MaxEntries > 0 && len(l.entries) > l.cfg.MaxEntries) ||
+		(l.cfg.MaxBytes > 0 && l.bytes > l.cfg.MaxBytes)
+}
+
+func (l *LRU[K, V]) evict(el *list.Element) {
+	e := l.remove(el)
+	l.evictions++
+	if l.cfg.OnEvict != nil {
+		l.cfg.OnEvict(e.key, e.value)
+	}
+}
+
+func (l *LRU[K, V]) remove(el *list.Element) *entry[K, V] {
+	e := l.order.Remove(el).(*entry[K, V])
+	delete(l.entries, e.key)
+	l.bytes -= e.size
+	return e
+}
+
+func (l *LRU[K, V]) stats() stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return stats{len(l.entries), l.bytes, l.hits, l.misses, l.evictions}
+}
+
+// stats defines the stats of a cache.
+type stats struct {
+	entries   int
+	bytes     int64
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+var (
+	entriesDesc   = prometheus.NewDesc("memory_cache_entries", "Entries of the in-process caches by cache, including expired ones not evicted yet.", []string{"cache"}, nil)
+	bytesDesc     = prometheus.NewDesc("memory_cache_bytes", "Size of the entries of the in-process caches by cache.", []string{"cache"}, nil)
+	hitsDesc      = prometheus.NewDesc("memory_cache_hits_total", "Lookups of the in-process caches that found an entry, by cache.", []string{"cache"}, nil)
+	missesDesc    = prometheus.NewDesc("memory_cache_misses_total", "Lookups of the in-process caches that found no entry, by cache.", []string{"cache"}, nil)
+	evictionsDesc = prometheus.NewDesc("memory_cache_evictions_total", "Entries evicted from the in-process caches for room or expiry, by cache.", []string{"cache"}, nil)
+)
+
+// collector collects the stats of the named caches when scraped, so
+// that lookups only count them. Hit rates are derived from the hits
+// and misses.
+type collector struct {
+	mu    sync.Mutex
+	stats map[string]func() stats
+}
+
+func (c *collector) add(name string, fn func() stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats[name] = fn
+}
+
+// Describe sends the descriptors of the metrics.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{entriesDesc, bytesDesc, hitsDesc, missesDesc, evictionsDesc} {
+		ch <- d
+	}
+}
+
+// Collect sends the metrics of every cache.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, fn := range c.stats {
+		s := fn()
+		ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(s.entries), name)
+		ch <- prometheus.MustNewConstMetric(bytesDesc, prometheus.GaugeValue, float64(s.bytes), name)
+		ch <- prometheus.MustNewConstMetric(hitsDesc, prometheus.CounterValue, float64(s.hits), name)
+		ch <- prometheus.MustNewConstMetric(missesDesc, prometheus.CounterValue, float64(s.misses), name)
+		ch <- prometheus.MustNewConstMetric(evictionsDesc, prometheus.CounterValue, float64(s.evictions), name)
+	}
+}
+
+// Store stores the encoded values of a cache.Cache in an LRU, so
+// that each instance caches the values it loaded.
+type Store struct {
+	lru *LRU[string, []byte]
+}
+
+// NewStore creates a store of up to maxEntries values and maxBytes
+// of keys and values, either 0 for no bound, labeling its metrics
+// with the name.
+func NewStore(name string, maxEntries int, maxBytes int64) *Store {
+	return &Store{New(Config[string, []byte]{
+		Name:       name,
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+		Size:       func(key string, value []byte) int64 { return int64(len(key) + len(value)) },
+	})}
+}
+
+// Get returns the value of a key or cache.ErrMiss if not set.
+func (s *Store) Get(_ context.Context, key string) ([]byte, error) {
+	b, ok := s.lru.Get(key)
+	if !ok {
+		return nil, cache.ErrMiss
+	}
+	return b, nil
+}
+
+// Set sets the value of a key expiring after the ttl.
+func (s *Store) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.lru.Add(key, value, ttl)
+	return nil
+}
+
+// Delete deletes a key.
+func (s *Store) Delete(_ context.Context, key string) error {
+	s.lru.Remove(key)
+	return nil
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"movieapp.com/pkg/cache"
+)
+
+// Store defines a Redis store of cached values shared by all
+// instances of a service.
+type Store struct {
+	client *redis.Client
+}
+
+// New creates a Redis store at the given address.
+func New(addr string) *Store {
+	return &Store{redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+// Get returns the value of a key or cache.ErrMiss if not set.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, cache.ErrMiss
+	}
+	return b, err
+}
+
+// Set sets the value of a key expiring after the ttl.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete deletes a key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
+
+// Close closes the client.
+func (s *Store) Close() error {
+	return s.client.Close()
+}
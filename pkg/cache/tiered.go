@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Tiered stores values in process in front of a remote store
+// shared by all instances, so that instances share the values one
+// of them loaded without a round trip for each read.
+type Tiered struct {
+	local    Store
+	localTTL atomic.Int64
+	remote   Store
+}
+
+// NewTiered creates a store of values in the local store in front
+// of the remote one. Values read from the remote store are kept in
+// the local one for localTTL.
+func NewTiered(local Store, remote Store, localTTL time.Duration) *Tiered {
+	t := &Tiered{local: local, remote: remote}
+	t.SetLocalTTL(localTTL)
+	return t
+}
+
+// SetLocalTTL changes the time values read from now on are kept in
+// the local store for.
+func (t *Tiered) SetLocalTTL(ttl time.Duration) {
+	t.localTTL.Store(int64(ttl))
+}
+
+// Get returns the value of a key from the local store, or else from
+// the remote one, or ErrMiss if not set.
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, error) {
+	if b, err := t.local.Get(ctx, key); err == nil {
+		return b, nil
+	}
+	b, err := t.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.local.Set(ctx, key, b, time.Duration(t.localTTL.Load())); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Set sets the value of a key in both stores.
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := t.local.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return t.remote.Set(ctx, key, value, ttl)
+}
+
+// Delete deletes a key from both stores.
+func (t *Tiered) Delete(ctx context.Context, key string) error {
+	if err := t.local.Delete(ctx, key); err != nil {
+		return err
+	}
+	return t.remote.Delete(ctx, key)
+}
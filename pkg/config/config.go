@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config defines the config of a service, which validates itself
+// once loaded.
+type Config interface {
+	Validate() error
+}
+
+// Load loads the config of a service. The config holds the
+// defaults, and its fields are bound to the flags of the flag set,
+// which must be parsed. Settings are then read from, in increasing
+// precedence:
+//
+//   - the YAML file at the path, if not empty, whose keys are the
+//     yaml tags of the config
+//   - environment variables named by the prefix and the flag name,
+//     such as MOVIE_KAFKA_BROKERS for the kafka-brokers flag
+//   - the flags set on the command line
+func Load(path string, envPrefix string, cfg Config, flags *flag.FlagSet) error {
+	explicit := map[string]string{}
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = f.Value.String() })
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		name := EnvName(envPrefix, f.Name)
+		if v, ok := os.LookupEnv(name); ok && err == nil {
+			if setErr := f.Value.Set(v); setErr != nil {
+				err = fmt.Errorf("%s: %w", name, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for name, v := range explicit {
+		if err := flags.Set(name, v); err != nil {
+			return fmt.Errorf("flag %s: %w", name, err)
+		}
+	}
+	return cfg.Validate()
+}
+
+// EnvName returns the name of the environment variable of a flag.
+func EnvName(prefix string, flagName string) string {
+	return strings.ToUpper(prefix + "_" + strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// List is a list of strings set from a YAML sequence or comma
+// separated in a flag or environment variable.
+type List []string
+
+// String returns the comma separated list.
+func (l *List) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set sets the list from comma separated values.
+func (l *List) Set(v string) error {
+	*l = nil
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+// ValidatePort returns an error if a port is out of range. A zero
+// port is valid if optional, usually to not serve an API.
+func ValidatePort(name string, port int, optional bool) error {
+	if port == 0 && optional {
+		return nil
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("%s: invalid port %d", name, port)
+	}
+	return nil
+}
+
+// ValidateAddr returns an error if an address is not of the form
+// host:port. An empty address is valid if optional.
+func ValidateAddr(name string, addr string, optional bool) error {
+	if addr == "" && optional {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// ValidateAddrs returns an error if a list of addresses is empty
+// or one is not of the form host:port.
+func ValidateAddrs(name string, addrs []string) error {
+	if len(addrs) == 0 {
+		return fmt.Errorf("%s: no addresses", name)
+	}
+	for _, addr := range addrs {
+		if err := ValidateAddr(name, addr, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expand replaces the string values of a loaded config, including
+// those of lists and nested structs, by their expansion, such as
+// the resolution of the references to secrets of
+// secrets.Resolver.Expand, and validates the config again.
+func Expand(cfg Config, expand func(string) (string, error)) error {
+	if err := expandValue(reflect.ValueOf(cfg).Elem(), expand); err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func expandValue(v reflect.Value, expand func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := expand(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		// The elements are copied, as the slice may be shared
+		// with the config the values were expanded from.
+		expanded := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(expanded, v)
+		for i := 0; i < expanded.Len(); i++ {
+			if err := expandValue(expanded.Index(i), expand); err != nil {
+				return err
+			}
+		}
+		v.Set(expanded)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := expandValue(v.Field(i), expand); err != nil {
+				return fmt.Errorf("%s: %w", yamlName(v.Type().Field(i)), err)
+			}
+		}
+	}
+	return nil
+}
+
+func yamlName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// Redacted is the value of the redacted settings.
+const Redacted = "REDACTED"
+
+// secretNames are the parts of the names of the settings holding
+// secrets, such as credentials or DSNs embedding passwords.
+var secretNames = []string{"password", "token", "secret", "dsn"}
+
+// Redact returns a copy of a config, such as the one kept before
+// Expand, whose settings named like secrets, such as kafkaSASL
+// password or auditDSN, are replaced by Redacted if set, so that it
+// can be shown.
+func Redact(cfg Config) Config {
+	v := reflect.New(reflect.TypeOf(cfg).Elem())
+	v.Elem().Set(reflect.ValueOf(cfg).Elem())
+	redactValue(v.Elem(), false)
+	return v.Interface().(Config)
+}
+
+func redactValue(v reflect.Value, secret bool) {
+	switch v.Kind() {
+	case reflect.String:
+		if secret && v.String() != "" {
+			v.SetString(Redacted)
+		}
+	case reflect.Slice:
+		if !secret || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		// The slice is shared with the config it was copied from.
+		redacted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < redacted.Len(); i++ {
+			redacted.Index(i).SetString(Redacted)
+		}
+		v.Set(redacted)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			name := strings.ToLower(yamlName(v.Type().Field(i)))
+			redactValue(v.Field(i), slices.ContainsFunc(secretNames, func(s string) bool { return strings.Contains(name, s) }))
+		}
+	}
+}
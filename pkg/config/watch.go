@@ -0,0 +1,228 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("config")
+
+// DefaultReload is the default interval config files are checked for
+// changes at.
+const DefaultReload = 30 * time.Second
+
+// Watcher reloads the config of a service from its file on SIGHUP
+// and when the file changes, and passes it to the components
+// subscribed to the settings that changed, such as the log level or
+// cache TTLs. Settings no component subscribed to keep their value
+// until the service restarts. Settings set by environment variables
+// or flags keep their value, as these take precedence over the
+// file.
+type Watcher[C Config] struct {
+	path      string
+	envPrefix string
+	// bound is the config the flags are bound to, whose settings set
+	// by environment variables or flags are kept on reload.
+	bound    C
+	flags    *flag.FlagSet
+	defaults func() C
+	expand   func(string) (string, error)
+
+	mu      sync.Mutex
+	current C
+	modTime time.Time
+	subs    []subscription[C]
+}
+
+type subscription[C Config] struct {
+	name     string
+	settings []string
+	fn       func(cfg C) error
+}
+
+// NewWatcher creates a watcher of the config loaded by Load from the
+// path, the environment variables of the prefix and the flags,
+// which must still be bound to it. Reloaded configs start from
+// defaults and their values are expanded by expand, as the loaded
+// config was by Expand.
+func NewWatcher[C Config](path string, envPrefix string, cfg C, flags *flag.FlagSet, defaults func() C, expand func(string) (string, error)) *Watcher[C] {
+	w := &Watcher[C]{path: path, envPrefix: envPrefix, bound: cfg, flags: flags, defaults: defaults, expand: expand, current: cfg}
+	if info, err := os.Stat(path); err == nil {
+		w.modTime = info.ModTime()
+	}
+	return w
+}
+
+// Subscribe registers a component applying settings, by their yaml
+// names such as logLevel or breaker, when they change. The name of
+// the component labels its errors.
+func (w *Watcher[C]) Subscribe(name string, fn func(cfg C) error, settings ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, subscription[C]{name, settings, fn})
+}
+
+// Current returns the config last loaded.
+func (w *Watcher[C]) Current() C {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Reload loads the config again and passes it to the components
+// subscribed to the settings that changed, or to all of them if
+// forced, so that they read again the files settings refer to. An
+// invalid config is not applied. The errors of the components are
+// joined, the others applying the config nonetheless.
+func (w *Watcher[C]) Reload(ctx context.Context, force bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	next, err := w.load()
+	if err != nil {
+		return err
+	}
+	changed := diff(w.current, next)
+	applied := map[string]bool{}
+	var errs []error
+	for _, s := range w.subs {
+		if !force && !slices.ContainsFunc(s.settings, func(name string) bool { return slices.Contains(changed, name) }) {
+			continue
+		}
+		for _, name := range s.settings {
+			applied[name] = true
+		}
+		if err := s.fn(next); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	ignored := slices.DeleteFunc(changed, func(name string) bool { return applied[name] })
+	if len(ignored) > 0 {
+		logger.WarnContext(ctx, "Config settings changed that only apply on restart", "settings", ignored)
+	}
+	w.current = next
+	return errors.Join(errs...)
+}
+
+// load loads the config from the defaults, the file and the
+// settings of the bound config set by environment variables or
+// flags, then expands and validates it.
+func (w *Watcher[C]) load() (C, error) {
+	next := w.defaults()
+	b, err := os.ReadFile(w.path)
+	if err != nil {
+		return next, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(next); err != nil && !errors.Is(err, io.EOF) {
+		return next, fmt.Errorf("%s: %w", w.path, err)
+	}
+	set := map[string]bool{}
+	w.flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	fields := boundFields(reflect.ValueOf(w.bound).Elem(), nil, map[boundField][]int{})
+	bound, dst := reflect.ValueOf(w.bound).Elem(), reflect.ValueOf(next).Elem()
+	w.flags.VisitAll(func(f *flag.Flag) {
+		if _, ok := os.LookupEnv(EnvName(w.envPrefix, f.Name)); !ok && !set[f.Name] {
+			return
+		}
+		// Flags are bound to the fields of the config by pointer.
+		p := reflect.ValueOf(f.Value)
+		if p.Kind() != reflect.Pointer {
+			return
+		}
+		if index, ok := fields[boundField{p.Pointer(), p.Elem().Kind()}]; ok {
+			dst.FieldByIndex(index).Set(bound.FieldByIndex(index))
+		}
+	})
+	if err := expandValue(dst, w.expand); err != nil {
+		return next, err
+	}
+	return next, next.Validate()
+}
+
+// Run reloads the config on SIGHUP, and when its file changes as
+// checked at the interval if positive, until the context is
+// canceled.
+func (w *Watcher[C]) Run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		force := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			force = true
+		case <-tick:
+			info, err := os.Stat(w.path)
+			if err != nil || info.ModTime().Equal(w.modTime) {
+				continue
+			}
+			w.modTime = info.ModTime()
+		}
+		if err := w.Reload(ctx, force); err != nil {
+			logger.ErrorContext(ctx, "Config reload error", "path", w.path, "error", err)
+			continue
+		}
+		logger.InfoContext(ctx, "Config reloaded", "path", w.path)
+	}
+}
+
+// boundField identifies the field a flag is bound to by its address
+// and kind, as a struct shares its address with its first field.
+type boundField struct {
+	addr uintptr
+	kind reflect.Kind
+}
+
+// boundFields maps the fields of a struct, including those of
+// nested structs, to their index.
+func boundFields(v reflect.Value, index []int, fields map[boundField][]int) map[boundField][]int {
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		f := v.Field(i)
+		fi := append(index[:len(index):len(index)], i)
+		if f.Kind() == reflect.Struct {
+			boundFields(f, fi, fields)
+			continue
+		}
+		fields[boundField{f.UnsafeAddr(), f.Kind()}] = fi
+	}
+	return fields
+}
+
+// diff returns the yaml names of the settings of two configs that
+// differ.
+func diff(a, b Config) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	var changed []string
+	for i := 0; i < va.NumField(); i++ {
+		if va.Type().Field(i).IsExported() && !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, yamlName(va.Type().Field(i)))
+		}
+	}
+	return changed
+}
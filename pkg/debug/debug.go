@@ -0,0 +1,153 @@
+// Package debug serves the internal debug endpoints of a service on
+// a separate port: the pprof profiles, the expvar metrics, the
+// garbage collector stats and the current config, so that the
+// performance of production instances can be investigated without
+// rebuilding them.
+package debug
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimedebug "runtime/debug"
+	"runtime/metrics"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/httpmw"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+)
+
+var logger = logging.New("debug")
+
+// Config defines the settings of the debug endpoints.
+type Config struct {
+	// Port is the port of the endpoints, not served if 0. It should
+	// not be reachable from outside the cluster.
+	Port int `yaml:"port"`
+	// Token is the bearer token of the requests, not checked if
+	// empty.
+	Token string `yaml:"token"`
+}
+
+// Enabled reports whether the endpoints are served.
+func (c *Config) Enabled() bool {
+	return c.Port != 0
+}
+
+// Validate validates the config.
+func (c *Config) Validate() error {
+	return config.ValidatePort("port", c.Port, true)
+}
+
+// GCStats defines the garbage collector and memory stats of a
+// service.
+type GCStats struct {
+	Goroutines    int             `json:"goroutines"`
+	NumGC         int64           `json:"numGC"`
+	LastGC        time.Time       `json:"lastGC"`
+	PauseTotal    time.Duration   `json:"pauseTotalNs"`
+	RecentPauses  []time.Duration `json:"recentPausesNs"`
+	HeapAlloc     uint64          `json:"heapAlloc"`
+	HeapInuse     uint64          `json:"heapInuse"`
+	HeapObjects   uint64          `json:"heapObjects"`
+	Sys           uint64          `json:"sys"`
+	NextGC        uint64          `json:"nextGC"`
+	GCCPUFraction float64         `json:"gcCPUFraction"`
+	// GOGC and MemoryLimit are the tuning of the collector, from the
+	// GOGC and GOMEMLIMIT environment variables.
+	GOGC        int   `json:"gogc"`
+	MemoryLimit int64 `json:"memoryLimit"`
+}
+
+// recentPauses is the number of recent pauses of GCStats, most
+// recent first.
+const recentPauses = 16
+
+// Handler returns the handler of the debug endpoints:
+//
+//   - /debug/pprof/, the pprof profiles, such as
+//     /debug/pprof/profile?seconds=30 for the CPU and
+//     /debug/pprof/heap for the heap
+//   - /debug/vars, the expvar metrics
+//   - /debug/gc, the GCStats
+//   - /debug/config, the config as YAML, redacted by config.Redact
+//
+// The config should be the one kept before config.Expand, so that
+// the references to secrets are shown rather than their values.
+// Requests must bear the token, if any.
+func Handler(cfg Config, current config.Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/gc", serveGC)
+	redacted, err := yaml.Marshal(config.Redact(current))
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, req *http.Request) {
+		if err != nil {
+			logger.ErrorContext(req.Context(), "Config encode error", "error", err)
+			problem.Write(w, req, problem.Internal, "")
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(redacted)
+	})
+	if cfg.Token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			problem.Write(w, req, problem.Unauthenticated, "")
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+func serveGC(w http.ResponseWriter, req *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	var gc runtimedebug.GCStats
+	runtimedebug.ReadGCStats(&gc)
+	tuning := []metrics.Sample{{Name: "/gc/gogc:percent"}, {Name: "/gc/gomemlimit:bytes"}}
+	metrics.Read(tuning)
+	stats := GCStats{
+		Goroutines:    runtime.NumGoroutine(),
+		NumGC:         gc.NumGC,
+		LastGC:        gc.LastGC,
+		PauseTotal:    gc.PauseTotal,
+		RecentPauses:  gc.Pause[:min(len(gc.Pause), recentPauses)],
+		HeapAlloc:     mem.HeapAlloc,
+		HeapInuse:     mem.HeapInuse,
+		HeapObjects:   mem.HeapObjects,
+		Sys:           mem.Sys,
+		NextGC:        mem.NextGC,
+		GCCPUFraction: mem.GCCPUFraction,
+		GOGC:          int(tuning[0].Value.Uint64()),
+		MemoryLimit:   int64(tuning[1].Value.Uint64()),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// Timeouts returns the timeouts of the debug server, which does not
+// bound the writing of responses, as profiles and traces are
+// recorded for as long as requested.
+func Timeouts(t httpmw.TimeoutConfig) httpmw.TimeoutConfig {
+	t.Write = 0
+	return t
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	consul "github.com/hashicorp/consul/api"
 	"movieapp.com/pkg/discovery"
@@ -14,6 +15,11 @@ import (
 // Registry defines a Consul-based service regisry.
 type Registry struct {
 	client *consul.Client
+	tags   []string
+	// grpcCheck is the interval of the gRPC health checks of the
+	// instances, 0 for none.
+	grpcCheck    time.Duration
+	grpcCheckTLS bool
 }
 
 // NewRegistry creates a new Consul-based service
@@ -28,6 +34,24 @@ func NewRegistry(addr string) (*Registry, error) {
 	return &Registry{client: client}, nil
 }
 
+// SetTenants tags the service records created afterwards with the
+// tenants served by the instance, as tenant:<id>, so that operators
+// can tell which instances serve which tenants.
+func (r *Registry) SetTenants(ids []string) {
+	r.tags = make([]string, 0, len(ids))
+	for _, id := range ids {
+		r.tags = append(r.tags, "tenant:"+id)
+	}
+}
+
+// SetGRPCCheck has Consul check the gRPC health of the instances
+// registered afterwards every interval, over TLS if useTLS, besides
+// their heartbeats, so that instances that hang or stop while
+// reported healthy are found before their heartbeats expire.
+func (r *Registry) SetGRPCCheck(interval time.Duration, useTLS bool) {
+	r.grpcCheck, r.grpcCheckTLS = interval, useTLS
+}
+
 // Register creates a service record in the registry.
 func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
 	parts := strings.Split(hostPort, ":")
@@ -38,12 +62,23 @@ func (r *Registry) Register(ctx context.Context, instanceID string, serviceName
 	if err != nil {
 		return err
 	}
+	checks := consul.AgentServiceChecks{{CheckID: instanceID, TTL: "5s"}}
+	if r.grpcCheck > 0 {
+		checks = append(checks, &consul.AgentServiceCheck{
+			CheckID:    instanceID + ":grpc",
+			GRPC:       hostPort,
+			GRPCUseTLS: r.grpcCheckTLS,
+			Interval:   r.grpcCheck.String(),
+			Timeout:    min(r.grpcCheck, 5*time.Second).String(),
+		})
+	}
 	return r.client.Agent().ServiceRegister(&consul.AgentServiceRegistration{
 		Address: parts[0],
 		ID:      instanceID,
 		Name:    serviceName,
 		Port:    port,
-		Check:   &consul.AgentServiceCheck{CheckID: instanceID, TTL: "5s"},
+		Tags:    r.tags,
+		Checks:  checks,
 	})
 }
 
@@ -0,0 +1,177 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sync/atomic"
+	"time"
+
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("flags")
+
+// Flag defines the value of a feature flag and the users it is
+// rolled out to.
+type Flag struct {
+	// Value is the value of the flag, a boolean, a number or a
+	// string. Durations are strings such as 30s.
+	Value any `json:"value"`
+	// Rollout is the percentage of users the flag is rolled out
+	// to, all if nil. Users are bucketed by a hash of their id
+	// salted with the flag name, so that a user stays in the
+	// rollout while it grows and the buckets of different flags
+	// are independent.
+	Rollout *float64 `json:"rollout,omitempty"`
+}
+
+func (f Flag) validate() error {
+	switch f.Value.(type) {
+	case bool, float64, string:
+	default:
+		return fmt.Errorf("value of unsupported type %T", f.Value)
+	}
+	if f.Rollout != nil && (*f.Rollout < 0 || *f.Rollout > 100) {
+		return fmt.Errorf("rollout %v not within 0 to 100", *f.Rollout)
+	}
+	return nil
+}
+
+// Provider provides flags from a source.
+type Provider interface {
+	// Flags returns the flags by name.
+	Flags(ctx context.Context) (map[string]Flag, error)
+}
+
+// Set defines the flags of a service, refreshed from its
+// providers. A nil set has no flags.
+type Set struct {
+	providers atomic.Pointer[[]Provider]
+	flags     atomic.Pointer[map[string]Flag]
+}
+
+// New creates a set of the flags of the providers, flags of later
+// providers overriding those of earlier ones. The set has no flags
+// until refreshed.
+func New(providers ...Provider) *Set {
+	s := &Set{}
+	s.providers.Store(&providers)
+	s.flags.Store(&map[string]Flag{})
+	return s
+}
+
+// SetProviders replaces the providers of the flags, such as when the
+// config of a service is reloaded, from the next refresh.
+func (s *Set) SetProviders(providers ...Provider) {
+	s.providers.Store(&providers)
+}
+
+// Refresh reads the flags of the providers. The flags are left as
+// they are if a provider fails.
+func (s *Set) Refresh(ctx context.Context) error {
+	flags := map[string]Flag{}
+	for _, p := range *s.providers.Load() {
+		res, err := p.Flags(ctx)
+		if err != nil {
+			return err
+		}
+		maps.Copy(flags, res)
+	}
+	s.flags.Store(&flags)
+	return nil
+}
+
+// Run refreshes the flags at the given interval until the context
+// is canceled.
+func (s *Set) Run(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if err := s.Refresh(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			logger.ErrorContext(ctx, "Flag refresh error", "error", err)
+		}
+	}
+}
+
+// lookup returns the value of a flag for a user, false if the
+// flag is not set or not rolled out to the user. Flags rolled out
+// to some users only are not rolled out to anonymous users.
+func (s *Set) lookup(name string, userID string) (any, bool) {
+	if s == nil {
+		return nil, false
+	}
+	f, ok := (*s.flags.Load())[name]
+	if !ok || f.Rollout == nil || *f.Rollout >= 100 {
+		return f.Value, ok
+	}
+	if userID == "" {
+		return nil, false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name + "\x00" + userID))
+	return f.Value, float64(h.Sum64()%10000) < *f.Rollout*100
+}
+
+// Bool returns the boolean value of a flag for a user, or def if
+// the flag is not set, not rolled out to the user or not a
+// boolean. The user id is empty for lookups not made for a user.
+func (s *Set) Bool(name string, userID string, def bool) bool {
+	if v, ok := s.lookup(name, userID); ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return def
+}
+
+// String returns the string value of a flag for a user, or def as
+// in Bool.
+func (s *Set) String(name string, userID string, def string) string {
+	if v, ok := s.lookup(name, userID); ok {
+		if str, ok := v.(string); ok {
+			return str
+		}
+	}
+	return def
+}
+
+// Float returns the number value of a flag for a user, or def as
+// in Bool.
+func (s *Set) Float(name string, userID string, def float64) float64 {
+	if v, ok := s.lookup(name, userID); ok {
+		if x, ok := v.(float64); ok {
+			return x
+		}
+	}
+	return def
+}
+
+// Int returns the integer value of a flag for a user, or def as
+// in Bool. Numbers are truncated to integers.
+func (s *Set) Int(name string, userID string, def int) int {
+	if v, ok := s.lookup(name, userID); ok {
+		if x, ok := v.(float64); ok {
+			return int(x)
+		}
+	}
+	return def
+}
+
+// Duration returns the duration value of a flag for a user, such
+// as 30s, or def as in Bool.
+func (s *Set) Duration(name string, userID string, def time.Duration) time.Duration {
+	if v, ok := s.lookup(name, userID); ok {
+		if str, ok := v.(string); ok {
+			if d, err := time.ParseDuration(str); err == nil {
+				return d
+			}
+		}
+	}
+	return def
+}
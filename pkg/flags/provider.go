@@ -0,0 +1,139 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config defines the sources of the flags of a service. Flags of
+// the URL override those of the file, and flags of environment
+// variables override both.
+type Config struct {
+	// File is a JSON flags file, as served by URL.
+	File string `yaml:"file"`
+	// URL serves the flags as a JSON object of the form
+	// {"flags": {"name": {"value": true, "rollout": 10}}}.
+	URL string `yaml:"url"`
+	// Refresh is the interval the flags are read again at.
+	Refresh time.Duration `yaml:"refresh"`
+}
+
+// DefaultRefresh is the default interval flags are refreshed at.
+const DefaultRefresh = 30 * time.Second
+
+// Validate returns an error if the refresh interval is not
+// positive.
+func (c *Config) Validate() error {
+	if c.Refresh <= 0 {
+		return errors.New("refresh: not positive")
+	}
+	return nil
+}
+
+// Providers returns the providers of the config, reading flags
+// from environment variables with the given prefix as in Env.
+func (c *Config) Providers(envPrefix string) []Provider {
+	var res []Provider
+	if c.File != "" {
+		res = append(res, File(c.File))
+	}
+	if c.URL != "" {
+		res = append(res, Remote(c.URL))
+	}
+	return append(res, Env(envPrefix))
+}
+
+// document defines the JSON encoding of flags.
+type document struct {
+	Flags map[string]Flag `json:"flags"`
+}
+
+func decode(r io.Reader) (map[string]Flag, error) {
+	var doc document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	for name, f := range doc.Flags {
+		if err := f.validate(); err != nil {
+			return nil, fmt.Errorf("flag %s: %w", name, err)
+		}
+	}
+	return doc.Flags, nil
+}
+
+// File provides the flags of a JSON file.
+type File string
+
+// Flags reads the flags of the file.
+func (f File) Flags(context.Context) (map[string]Flag, error) {
+	file, err := os.Open(string(f))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	res, err := decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f, err)
+	}
+	return res, nil
+}
+
+// Remote provides the flags served as JSON at a URL, such as by a
+// flag service.
+type Remote string
+
+// Flags fetches the flags of the URL.
+func (r Remote) Flags(ctx context.Context) (map[string]Flag, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, string(r), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d", r, resp.StatusCode)
+	}
+	res, err := decode(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r, err)
+	}
+	return res, nil
+}
+
+// Env provides the flags of the environment variables with a
+// prefix, rolled out to all users. The flag of a variable is
+// named by the rest of its name in lower case with dashes for
+// underscores, so that MOVIE_FEATURE_SIMILAR_TITLES sets the
+// similar-titles flag for the MOVIE_FEATURE prefix. Values are
+// parsed as JSON booleans or numbers, or else taken as strings.
+type Env string
+
+// Flags reads the flags of the environment variables.
+func (e Env) Flags(context.Context) (map[string]Flag, error) {
+	res := map[string]Flag{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(k, string(e)+"_")
+		if !ok || name == "" {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal([]byte(v), &value); err != nil || (Flag{Value: value}).validate() != nil {
+			value = v
+		}
+		res[strings.ReplaceAll(strings.ToLower(name), "_", "-")] = Flag{Value: value}
+	}
+	return res, nil
+}
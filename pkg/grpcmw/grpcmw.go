@@ -0,0 +1,92 @@
+// Package grpcmw assembles the interceptors of the gRPC servers and
+// clients of the services, so that every service traces, scopes to
+// their tenant, measures, logs, authenticates and bounds its calls
+// the same way and in the same order.
+package grpcmw
+
+import (
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/telemetry"
+	"movieapp.com/pkg/tenant"
+)
+
+// DefaultTimeout is the default deadline of the unary calls served
+// or made without one.
+const DefaultTimeout = 30 * time.Second
+
+// ServerConfig defines the interceptors of a gRPC server.
+type ServerConfig struct {
+	// Verifier verifies the bearer tokens of the calls, nil to
+	// trust the user ids of the requests.
+	Verifier *auth.Verifier
+	// Timeout is the deadline of the unary calls arriving without
+	// one, DefaultTimeout if zero. Streams are not bounded.
+	Timeout time.Duration
+	// Timeouts overrides Timeout by full method name, 0 for calls
+	// not to be bounded, such as long running admin calls.
+	Timeouts map[string]time.Duration
+	// TLS serves the calls over TLS unless nil.
+	TLS *tls.Config
+	// Tenants are the tenants served, nil for the default one only.
+	Tenants *tenant.Set
+	// Unary and Stream are the interceptors of the service, such as
+	// rate limits, run after the shared ones.
+	Unary  []grpc.UnaryServerInterceptor
+	Stream []grpc.StreamServerInterceptor
+}
+
+// ServerOptions returns the options of a gRPC server with the
+// shared interceptors, in order: tracing, tenants, metrics, panic
+// recovery, request ids, authentication, and the default deadline,
+// followed by those of the service.
+func ServerOptions(cfg ServerConfig) []grpc.ServerOption {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	opts := append([]grpc.ServerOption{
+		tracing.ServerOption(),
+		grpc.ChainUnaryInterceptor(tenant.UnaryServerInterceptor(cfg.Tenants)),
+		grpc.ChainStreamInterceptor(tenant.StreamServerInterceptor(cfg.Tenants)),
+	}, telemetry.ServerOptions()...)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{
+			UnaryServerRecovery(),
+			logging.UnaryServerInterceptor(),
+			auth.UnaryServerInterceptor(cfg.Verifier),
+			UnaryServerTimeout(timeout, cfg.Timeouts),
+		}, cfg.Unary...)...),
+		grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{
+			StreamServerRecovery(),
+			logging.StreamServerInterceptor(),
+			auth.StreamServerInterceptor(cfg.Verifier),
+		}, cfg.Stream...)...),
+	)
+	if cfg.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.TLS)))
+	}
+	return opts
+}
+
+// DialOptions returns the options of a gRPC client connection over
+// the transport credentials with the shared interceptors: tracing,
+// metrics, request id, tenant and bearer token propagation, and a
+// deadline of timeout for the unary calls made without one.
+func DialOptions(creds credentials.TransportCredentials, timeout time.Duration) []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		tracing.DialOption(),
+		telemetry.DialOption(),
+		logging.DialOption(),
+	}
+	opts = append(opts, tenant.DialOptions()...)
+	opts = append(opts, auth.DialOptions()...)
+	return append(opts, grpc.WithChainUnaryInterceptor(UnaryClientTimeout(timeout)))
+}
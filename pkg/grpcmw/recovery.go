@@ -0,0 +1,42 @@
+package grpcmw
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/pkg/panics"
+)
+
+// UnaryServerRecovery fails calls whose handler panics with
+// Internal instead of crashing the server, handling the panic with
+// panics.Recovered.
+func UnaryServerRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recovered(ctx, info.FullMethod, v)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRecovery fails streams whose handler panics with
+// Internal like UnaryServerRecovery.
+func StreamServerRecovery() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recovered(ss.Context(), info.FullMethod, v)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, method string, v any) error {
+	panics.Recovered(ctx, method, v)
+	return status.Error(codes.Internal, "internal error")
+}
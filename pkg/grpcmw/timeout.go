@@ -0,0 +1,40 @@
+package grpcmw
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryServerTimeout applies a deadline of timeout to the incoming
+// calls without one, or the timeout of their method if overridden,
+// so that a client not setting deadlines cannot keep handlers and
+// their downstream calls running indefinitely.
+func UnaryServerTimeout(timeout time.Duration, overrides map[string]time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		d := timeout
+		if o, ok := overrides[info.FullMethod]; ok {
+			d = o
+		}
+		if _, ok := ctx.Deadline(); !ok && d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientTimeout applies a deadline of timeout to the calls
+// made without one, unless the timeout is 0.
+func UnaryClientTimeout(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// RegisterGRPC serves the gRPC health checking protocol on the
+// server, for the gRPC checks of the registry and the gRPC probes of
+// Kubernetes. The empty service and the services of the server are
+// serving while the service is ready, and each check is a service
+// serving while its dependency is, such as mysql. Statuses are
+// updated by Heartbeat.
+func (h *Health) RegisterGRPC(srv *grpc.Server) {
+	hs := grpchealth.NewServer()
+	// Not serving until the first heartbeat checked the
+	// dependencies.
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.grpcHealth, h.srv = hs, srv
+}
+
+// setGRPCStatuses sets the statuses of the gRPC health service, if
+// registered, by the failed required and optional checks.
+func (h *Health) setGRPCStatuses(failed map[string]error, degraded map[string]error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.grpcHealth == nil {
+		return
+	}
+	status := func(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+		if ok {
+			return healthpb.HealthCheckResponse_SERVING
+		}
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	ready := status(len(failed) == 0)
+	h.grpcHealth.SetServingStatus("", ready)
+	for name := range h.srv.GetServiceInfo() {
+		if name != healthpb.Health_ServiceDesc.ServiceName {
+			h.grpcHealth.SetServingStatus(name, ready)
+		}
+	}
+	for name := range h.checks {
+		_, f := failed[name]
+		_, d := degraded[name]
+		h.grpcHealth.SetServingStatus(name, status(!f && !d))
+	}
+}
@@ -0,0 +1,235 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	"movieapp.com/pkg/discovery"
+)
+
+// checkTimeout bounds each check, so that a hanging dependency
+// fails readiness rather than the probe.
+const checkTimeout = 2 * time.Second
+
+// ErrShuttingDown is reported by the readiness of a service once it
+// is shutting down.
+var ErrShuttingDown = errors.New("shutting down")
+
+// Checker checks a dependency a service needs to serve requests.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls the function.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type check struct {
+	Checker
+	optional bool
+}
+
+// Health reports the liveness and readiness of a service. A
+// running service is live, and ready once all its required checks
+// pass, until it shuts down.
+type Health struct {
+	mu           sync.RWMutex
+	checks       map[string]check
+	shuttingDown atomic.Bool
+	// grpcHealth serves the statuses of the gRPC server srv, if
+	// registered.
+	grpcHealth *grpchealth.Server
+	srv        *grpc.Server
+}
+
+// New creates a new health reporter without checks.
+func New() *Health {
+	return &Health{checks: map[string]check{}}
+}
+
+// Register registers a check of a dependency the service cannot
+// serve without.
+func (h *Health) Register(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check{c, false}
+}
+
+// RegisterOptional registers a check of a dependency the service
+// degrades without, such as one whose work is retried later. Its
+// failures are reported but leave the service ready.
+func (h *Health) RegisterOptional(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check{c, true}
+}
+
+// Shutdown marks the service as not ready when it starts shutting
+// down, so that it stops receiving traffic before it drains.
+func (h *Health) Shutdown(context.Context) error {
+	h.shuttingDown.Store(true)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.grpcHealth != nil {
+		h.grpcHealth.Shutdown()
+	}
+	return nil
+}
+
+// Ready runs the checks concurrently and returns the errors of the
+// failed required ones by name, none if the service is ready, and
+// of the failed optional ones.
+func (h *Health) Ready(ctx context.Context) (failed map[string]error, degraded map[string]error) {
+	failed, degraded = map[string]error{}, map[string]error{}
+	if h.shuttingDown.Load() {
+		failed["service"] = ErrShuttingDown
+		return failed, degraded
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, c := range h.checks {
+		name, c := name, c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			if err := c.Check(ctx); err != nil {
+				mu.Lock()
+				if c.optional {
+					degraded[name] = err
+				} else {
+					failed[name] = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failed, degraded
+}
+
+// LivenessHandler serves /healthz, which succeeds as long as the
+// service serves requests.
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeStatus(w, nil, nil)
+	})
+}
+
+// ReadinessHandler serves /readyz, which fails with the errors of
+// the failed checks while the service is not ready, and lists the
+// failed optional checks.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		failed, degraded := h.Ready(req.Context())
+		writeStatus(w, failed, degraded)
+	})
+}
+
+func writeStatus(w http.ResponseWriter, failed map[string]error, degraded map[string]error) {
+	res := struct {
+		Status   string            `json:"status"`
+		Failed   map[string]string `json:"failed,omitempty"`
+		Degraded map[string]string `json:"degraded,omitempty"`
+	}{Status: "ok", Failed: errorStrings(failed), Degraded: errorStrings(degraded)}
+	code := http.StatusOK
+	if len(failed) > 0 {
+		res.Status, code = "unavailable", http.StatusServiceUnavailable
+	} else if len(degraded) > 0 {
+		res.Status = "degraded"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
+}
+
+func errorStrings(errs map[string]error) map[string]string {
+	if len(errs) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(errs))
+	for name, err := range errs {
+		res[name] = err.Error()
+	}
+	return res
+}
+
+// Heartbeat reports the instance as healthy to the registry every
+// interval while it is ready, until the context is canceled, and
+// updates the statuses of the gRPC health service if registered.
+// The registry stops returning the address of an unready instance
+// once its last report expires.
+func (h *Health) Heartbeat(ctx context.Context, registry discovery.Registry, instanceID string, serviceName string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		failed, degraded := h.Ready(ctx)
+		h.setGRPCStatuses(failed, degraded)
+		if len(failed) > 0 {
+			for name, err := range failed {
+				slog.WarnContext(ctx, "Not ready", "check", name, "error", err)
+			}
+		} else if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
+			slog.ErrorContext(ctx, "Failed to report healthy state", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pinger is a connection pool that can be pinged, such as a
+// database.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping checks that a connection pool reaches its server.
+func Ping(p Pinger) Checker {
+	return CheckerFunc(p.PingContext)
+}
+
+// Dial checks that at least one of the addresses, such as those of
+// Kafka brokers, accepts TCP connections.
+func Dial(addrs []string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		var d net.Dialer
+		var errs []error
+		for _, addr := range addrs {
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				return conn.Close()
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// Service checks that the registry has instances of a downstream
+// service.
+func Service(registry discovery.Registry, serviceName string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		_, err := registry.ServiceAddresses(ctx, serviceName)
+		return err
+	})
+}
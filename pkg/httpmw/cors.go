@@ -0,0 +1,103 @@
+package httpmw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/tenant"
+)
+
+// corsMethods are the methods allowed to cross-origin requests.
+const corsMethods = "GET, HEAD, POST, PUT, PATCH, DELETE"
+
+// CORSConfig defines the cross-origin requests allowed to browser
+// clients. Tokens are sent in the Authorization header rather than
+// cookies, so requests with credentials are not allowed.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed, such as
+	// https://movieapp.com, or * for any. Cross-origin requests are
+	// not allowed if empty.
+	AllowedOrigins config.List `yaml:"allowedOrigins"`
+	// AllowedHeaders are the request headers allowed besides the
+	// CORS-safelisted ones.
+	AllowedHeaders config.List `yaml:"allowedHeaders"`
+	// MaxAge is the time browsers may cache preflight responses.
+	MaxAge time.Duration `yaml:"maxAge"`
+}
+
+// DefaultCORSConfig returns the default config, allowing no origin
+// and the request headers of the APIs.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedHeaders: config.List{"Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-None-Match", logging.RequestIDHeader, tenant.Header},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+// Validate validates the config.
+func (c *CORSConfig) Validate() error {
+	var errs []error
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			continue
+		}
+		if u, err := url.Parse(o); err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("allowedOrigins: invalid origin %q", o))
+		}
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, errors.New("maxAge: negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// AllowsOrigin reports whether the config allows requests from the
+// origin, such as https://movieapp.com.
+func (c *CORSConfig) AllowsOrigin(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || strings.TrimSuffix(o, "/") == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS sets the CORS headers of the responses to requests from the
+// allowed origins, and answers their preflight requests.
+func CORS(cfg CORSConfig) Middleware {
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	return func(next http.Handler) http.Handler {
+		if len(cfg.AllowedOrigins) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			origin := req.Header.Get("Origin")
+			if origin == "" || !cfg.AllowsOrigin(origin) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			h.Set("Access-Control-Allow-Origin", origin)
+			if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", corsMethods)
+				if headers != "" {
+					h.Set("Access-Control-Allow-Headers", headers)
+				}
+				h.Set("Access-Control-Max-Age", maxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			h.Set("Access-Control-Expose-Headers", logging.RequestIDHeader)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
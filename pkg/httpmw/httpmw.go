@@ -0,0 +1,117 @@
+// Package httpmw assembles the middlewares of the HTTP servers of
+// the services, so that every API compresses, traces, scopes to
+// their tenant, measures, logs, recovers and bounds its requests the
+// same way and in the same order.
+package httpmw
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"movieapp.com/internal/compress"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/telemetry"
+	"movieapp.com/pkg/tenant"
+)
+
+var logger = logging.New("httpmw")
+
+// Middleware defines a wrapper of a handler.
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares, the first being the outermost.
+func Chain(ms ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(ms) - 1; i >= 0; i-- {
+			next = ms[i](next)
+		}
+		return next
+	}
+}
+
+// Config defines the middlewares of an HTTP API.
+type Config struct {
+	// Name is the operation of the spans of the requests, such as
+	// movie-http.
+	Name string
+	// Route returns the route pattern of a request labeling its
+	// logs and metrics, such as apiversion.Router.Pattern, or nil to
+	// label requests by path in logs and as other in metrics.
+	Route func(*http.Request) string
+	// Timeouts bounds the handling of the requests by route, as
+	// passed to NewServer for the timeouts of the connections.
+	Timeouts TimeoutConfig
+	// CORS allows cross-origin requests of browser clients.
+	CORS CORSConfig
+	// Tenants are the tenants served, nil for the default one only.
+	Tenants *tenant.Set
+}
+
+// Handler wraps the handler of an HTTP API with the shared
+// middlewares, in order: compression, tracing, tenants, metrics,
+// request ids, access logs, panic recovery, CORS and the deadline.
+func Handler(next http.Handler, cfg Config) http.Handler {
+	return Chain(
+		Compress(compress.DefaultConfig()),
+		Trace(cfg.Name),
+		Tenant(cfg.Tenants),
+		Metrics(cfg.Route),
+		RequestID(cfg.Route),
+		AccessLog(cfg.Route),
+		Recover(cfg.Route),
+		CORS(cfg.CORS),
+		Timeout(cfg.Timeouts, cfg.Route),
+	)(next)
+}
+
+// NewServer creates an HTTP server listening on the address,
+// serving over TLS unless tlsConfig is nil, with the connection
+// timeouts of the config.
+func NewServer(addr string, handler http.Handler, tlsConfig *tls.Config, timeouts TimeoutConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+	}
+}
+
+// Compress compresses the responses as compress.Handler.
+func Compress(cfg compress.Config) Middleware {
+	return func(next http.Handler) http.Handler {
+		return compress.Handler(next, cfg)
+	}
+}
+
+// Trace creates a span for each request as tracing.Handler.
+func Trace(operation string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return tracing.Handler(next, operation)
+	}
+}
+
+// Tenant scopes the requests to the tenants of the set as
+// tenant.Handler.
+func Tenant(s *tenant.Set) Middleware {
+	return func(next http.Handler) http.Handler {
+		return tenant.Handler(next, s)
+	}
+}
+
+// Metrics measures the requests as telemetry.HTTPHandler.
+func Metrics(route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return telemetry.HTTPHandler(next, route)
+	}
+}
+
+// RequestID assigns each request an id as logging.Handler.
+func RequestID(route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return logging.Handler(next, route)
+	}
+}
@@ -0,0 +1,76 @@
+package httpmw
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// AccessLog logs each request with its status and duration: server
+// errors as warnings, and other requests at debug level, enabled by
+// a log level such as httpmw=debug. The route is that of RequestID.
+func AccessLog(route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+			next.ServeHTTP(sw, req)
+			level := slog.LevelDebug
+			if sw.code >= http.StatusInternalServerError {
+				level = slog.LevelWarn
+			}
+			r := req.URL.Path
+			if route != nil {
+				r = route(req)
+			}
+			logger.Log(req.Context(), level, "HTTP request served", "method", req.Method, "route", r, "status", sw.code, "duration", time.Since(start))
+		})
+	}
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered response to the client.
+func (w *statusWriter) Flush() {
+	w.wroteHeader = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the next handler take over the connection.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+	w.wroteHeader = true
+	w.code = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,42 @@
+package httpmw
+
+import (
+	"net/http"
+
+	"movieapp.com/pkg/panics"
+	"movieapp.com/pkg/problem"
+)
+
+// Recover responds to requests whose handler panics with an
+// internal error problem instead of crashing the server, handling
+// the panic with panics.Recovered. Handlers aborted with
+// http.ErrAbortHandler are left to the server. Panics are counted
+// by the route of the request as returned by route, or as other.
+func Recover(route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				r := "other"
+				if route != nil {
+					if p := route(req); p != "" {
+						r = p
+					}
+				}
+				panics.Recovered(req.Context(), req.Method+" "+r, v)
+				// A response already started cannot be replaced.
+				if !sw.wroteHeader {
+					problem.Write(sw, req, problem.Internal, "")
+				}
+			}()
+			next.ServeHTTP(sw, req)
+		})
+	}
+}
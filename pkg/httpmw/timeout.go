@@ -0,0 +1,107 @@
+package httpmw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// writeGrace is the time left to write the response of a route
+// whose timeout is overridden after its deadline.
+const writeGrace = 5 * time.Second
+
+// TimeoutConfig defines the timeouts of an HTTP server and of the
+// handling of its requests, so that slow clients and runaway
+// handlers cannot hold connections and goroutines indefinitely.
+type TimeoutConfig struct {
+	// ReadHeader is the time to read the headers of a request.
+	ReadHeader time.Duration `yaml:"readHeader"`
+	// Read is the time to read a whole request.
+	Read time.Duration `yaml:"read"`
+	// Write is the time from the end of reading the headers of a
+	// request to writing its whole response.
+	Write time.Duration `yaml:"write"`
+	// Idle is the time a kept alive connection waits for the next
+	// request.
+	Idle time.Duration `yaml:"idle"`
+	// Handler is the deadline of the context of the handling of
+	// each request, failing the downstream calls of handlers still
+	// running then.
+	Handler time.Duration `yaml:"handler"`
+	// Routes overrides Handler and Write by the route of the
+	// requests, their pattern or their path if the API has no
+	// routes, 0 for requests not to be bounded, such as streams.
+	Routes map[string]time.Duration `yaml:"routes"`
+}
+
+// DefaultTimeoutConfig returns the default timeouts.
+func DefaultTimeoutConfig() TimeoutConfig {
+	return TimeoutConfig{
+		ReadHeader: 5 * time.Second,
+		Read:       30 * time.Second,
+		Write:      45 * time.Second,
+		Idle:       2 * time.Minute,
+		Handler:    30 * time.Second,
+	}
+}
+
+// Validate validates the config.
+func (c *TimeoutConfig) Validate() error {
+	var errs []error
+	if c.ReadHeader < 0 || c.Read < 0 || c.Write < 0 || c.Idle < 0 || c.Handler < 0 {
+		errs = append(errs, errors.New("negative timeout"))
+	}
+	if c.Write > 0 && c.Handler > 0 && c.Write <= c.Handler {
+		errs = append(errs, errors.New("write: not above handler, leaving no time to respond once the handler deadline passed"))
+	}
+	for route, d := range c.Routes {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("routes: negative timeout of %s", route))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Timeout bounds the handling of each request with a context
+// deadline of the Handler timeout of the config, or of the timeout
+// of its route as returned by route, or its path if nil. The write
+// timeout of the server is moved along for overridden routes.
+// Unlike http.TimeoutHandler, responses are not buffered, so that
+// handlers can stream them.
+func Timeout(cfg TimeoutConfig, route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if cfg.Handler <= 0 && len(cfg.Routes) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			d := cfg.Handler
+			if len(cfg.Routes) > 0 {
+				r := req.URL.Path
+				if route != nil {
+					r = route(req)
+				}
+				if o, ok := cfg.Routes[r]; ok {
+					d = o
+					// Writers not supporting deadlines are left
+					// with the write timeout of the server.
+					var deadline time.Time
+					if d > 0 {
+						deadline = time.Now().Add(d + writeGrace)
+					}
+					if cfg.Write > 0 {
+						_ = http.NewResponseController(w).SetWriteDeadline(deadline)
+					}
+				}
+			}
+			if d <= 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+			ctx, cancel := context.WithTimeout(req.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/tenant"
+)
+
+var logger = logging.New("idempotency")
+
+// Header is the header of the key clients send with unsafe
+// requests to have them handled at most once.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from a store.
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// maxKeyLength bounds the length of keys, long enough for
+	// UUIDs and the like.
+	maxKeyLength = 255
+	// maxBodySize bounds the size of the stored responses. Larger
+	// responses are not stored and their requests may be retried.
+	maxBodySize = 1 << 20
+	// maxRequestSize bounds the size of the request bodies, which
+	// are buffered to fingerprint them before the handlers apply
+	// their own limits, such as the 10 MB of artwork uploads.
+	maxRequestSize = 16 << 20
+	// pendingTTL bounds the time a request is handled before a
+	// retry with the same key is handled again, in case the
+	// instance handling it went away.
+	pendingTTL = time.Minute
+)
+
+// Response defines a stored response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Record defines the state of a key: the fingerprint of the
+// request it was first sent with, and the response to the request
+// once it was handled.
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Response    *Response `json:"response,omitempty"`
+}
+
+// Store defines the records of keys, expiring after a time to
+// live.
+type Store interface {
+	// Reserve stores a record for a key unless it has one,
+	// returning the existing record if so and nil otherwise.
+	Reserve(ctx context.Context, key string, rec *Record, ttl time.Duration) (*Record, error)
+	// Save replaces the record of a key.
+	Save(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+	// Delete deletes the record of a key.
+	Delete(ctx context.Context, key string) error
+}
+
+// Handler handles the unsafe requests carrying an Idempotency-Key
+// header at most once per key, tenant and client, identified by
+// scope, storing their responses for ttl and replaying them to
+// retries. Retries with a request differing from the first one are
+// rejected with 422 and retries while the first one is handled
+// with 409. Their bodies larger than 16 MB are rejected with 413. Server errors are not stored, so that a retry may
+// succeed. Store failures are logged and let the requests through,
+// as they are no fault of the clients.
+func Handler(next http.Handler, store Store, ttl time.Duration, scope func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(Header)
+		if key == "" || safe(req.Method) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if len(key) > maxKeyLength {
+			problem.Write(w, req, problem.BadRequest, "Idempotency-Key longer than 255 characters")
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestSize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem.Write(w, req, problem.TooLarge, "body larger than 16 MB")
+			return
+		} else if err != nil {
+			problem.Write(w, req, problem.BadRequest, "unreadable body")
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		ctx := req.Context()
+		key = tenant.Key(ctx, scope(req)+":"+key)
+		fingerprint := fingerprint(req, body)
+		rec, err := store.Reserve(ctx, key, &Record{Fingerprint: fingerprint}, pendingTTL)
+		if err != nil {
+			logger.ErrorContext(ctx, "Idempotency store error", "error", err)
+			next.ServeHTTP(w, req)
+			return
+		}
+		switch {
+		case rec != nil && rec.Fingerprint != fingerprint:
+			problem.Write(w, req, problem.Invalid, "Idempotency-Key reused with a different request")
+			return
+		case rec != nil && rec.Response == nil:
+			problem.Write(w, req, problem.Conflict, "request with the same Idempotency-Key in progress")
+			return
+		case rec != nil:
+			replay(w, rec.Response)
+			return
+		}
+		rw := &recorder{ResponseWriter: w}
+		saved := false
+		defer func() {
+			// The key is released if the request failed, or
+			// panicked, for a retry to be handled.
+			if saved {
+				return
+			}
+			if err := store.Delete(context.WithoutCancel(ctx), key); err != nil {
+				logger.ErrorContext(ctx, "Idempotency store error", "error", err)
+			}
+		}()
+		next.ServeHTTP(rw, req)
+		if rw.status >= http.StatusInternalServerError || rw.overflow {
+			return
+		}
+		res := &Response{Status: rw.status, Header: w.Header().Clone(), Body: rw.body.Bytes()}
+		if err := store.Save(context.WithoutCancel(ctx), key, &Record{fingerprint, res}, ttl); err != nil {
+			logger.ErrorContext(ctx, "Idempotency store error", "error", err)
+			return
+		}
+		saved = true
+	})
+}
+
+func safe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// fingerprint returns a hash of the method, URL and body of a
+// request.
+func fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, res *Response) {
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+// Caller identifies the client of a request by a hash of its
+// Authorization header or else by its IP address.
+func Caller(req *http.Request) string {
+	if v := req.Header.Get("Authorization"); v != "" {
+		sum := sha256.Sum256([]byte(v))
+		return "auth:" + hex.EncodeToString(sum[:])
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// recorder records the status code and body of a response.
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(b) > maxBodySize {
+		w.overflow = true
+	} else if !w.overflow {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *recorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps the records of keys in process, so that retries
+// are only recognized by the instance that handled the request.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	ops     int
+}
+
+type memoryEntry struct {
+	rec       Record
+	expiresAt time.Time
+}
+
+// NewMemory creates a new in-process store.
+func NewMemory() *Memory {
+	return &Memory{entries: map[string]*memoryEntry{}}
+}
+
+// Reserve stores a record for a key unless it has one, returning
+// the existing record if so and nil otherwise.
+func (m *Memory) Reserve(_ context.Context, key string, rec *Record, ttl time.Duration) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if e, ok := m.entries[key]; ok && now.Before(e.expiresAt) {
+		existing := e.rec
+		return &existing, nil
+	}
+	m.set(key, rec, now, ttl)
+	return nil, nil
+}
+
+// Save replaces the record of a key.
+func (m *Memory) Save(_ context.Context, key string, rec *Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, rec, time.Now(), ttl)
+	return nil
+}
+
+// Delete deletes the record of a key.
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// set sets the record of a key, removing expired entries now and
+// then to bound the memory.
+func (m *Memory) set(key string, rec *Record, now time.Time, ttl time.Duration) {
+	if m.ops++; m.ops%1024 == 0 {
+		for k, e := range m.entries {
+			if now.After(e.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	m.entries[key] = &memoryEntry{*rec, now.Add(ttl)}
+}
@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"movieapp.com/pkg/idempotency"
+)
+
+// Store defines a Redis store of idempotency keys shared by all
+// instances of a service.
+type Store struct {
+	client *redis.Client
+}
+
+// New creates a Redis store at the given address.
+func New(addr string) *Store {
+	return &Store{redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+// Reserve stores a record for a key unless it has one, returning
+// the existing record if so and nil otherwise.
+func (s *Store) Reserve(ctx context.Context, key string, rec *idempotency.Record, ttl time.Duration) (*idempotency.Record, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := s.client.SetNX(ctx, redisKey(key), b, ttl).Result()
+		if err != nil || ok {
+			return nil, err
+		}
+		existing, err := s.client.Get(ctx, redisKey(key)).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// The record expired in between, try again.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		var res idempotency.Record
+		if err := json.Unmarshal(existing, &res); err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+}
+
+// Save replaces the record of a key.
+func (s *Store) Save(ctx context.Context, key string, rec *idempotency.Record, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisKey(key), b, ttl).Err()
+}
+
+// Delete deletes the record of a key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKey(key)).Err()
+}
+
+// Close closes the client.
+func (s *Store) Close() error {
+	return s.client.Close()
+}
+
+func redisKey(key string) string {
+	return "idempotency:" + key
+}
@@ -0,0 +1,180 @@
+// Package jobs runs background jobs of the services on schedules,
+// such as recomputes, snapshots and purges. Jobs of a service run
+// on one of its instances at a time unless local, by locking each
+// scheduled run in a store shared by the instances.
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/telemetry"
+)
+
+var logger = logging.New("jobs")
+
+// The metrics of the runs follow the RED method of the requests,
+// runs of other instances being counted as skipped.
+var (
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "job_runs_total",
+		Help: "Runs of the jobs by job and result: success, failure or skipped.",
+	}, []string{"job", "result"})
+	jobRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "job_retries_total",
+		Help: "Retries of failed runs of the jobs by job.",
+	}, []string{"job"})
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_run_duration_seconds",
+		Help:    "Duration of the runs of the jobs, including their retries, by job.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 3, 10),
+	}, []string{"job"})
+	jobLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "job_last_success_timestamp_seconds",
+		Help: "Start time of the last successful run of the jobs by job.",
+	}, []string{"job"})
+)
+
+func init() {
+	telemetry.Register(jobRuns, jobRetries, jobDuration, jobLastSuccess)
+}
+
+// Defaults of the jobs.
+const (
+	DefaultTimeout      = 10 * time.Minute
+	DefaultRetryBackoff = time.Second
+)
+
+// Locker defines a store of locks shared by the instances of a
+// service.
+type Locker interface {
+	// Lock acquires the named lock for the ttl unless it is held,
+	// reporting whether it was acquired. Locks are not released
+	// but expire.
+	Lock(ctx context.Context, name string, ttl time.Duration) (bool, error)
+}
+
+// Job defines a background job.
+type Job struct {
+	// Name identifies the job in its locks, logs and metrics, such as
+	// purge-rating-counts.
+	Name string
+	// Schedule defines when the job runs.
+	Schedule Schedule
+	// Run runs the job.
+	Run func(ctx context.Context) error
+	// Timeout bounds each run including its retries, DefaultTimeout
+	// if zero.
+	Timeout time.Duration
+	// Retries is the number of times a failed run is retried, after
+	// RetryBackoff doubling on each retry, DefaultRetryBackoff if
+	// zero.
+	Retries      int
+	RetryBackoff time.Duration
+	// Local runs the job on every instance, for jobs maintaining
+	// the state of an instance such as an in-process index.
+	Local bool
+}
+
+// Scheduler runs jobs on their schedules.
+type Scheduler struct {
+	locker Locker
+	jobs   []Job
+}
+
+// New creates a scheduler locking the runs of the jobs that are not
+// local in the locker.
+func New(locker Locker) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// Add adds a job to be run by Run.
+func (s *Scheduler) Add(j Job) {
+	if j.Timeout <= 0 {
+		j.Timeout = DefaultTimeout
+	}
+	if j.RetryBackoff <= 0 {
+		j.RetryBackoff = DefaultRetryBackoff
+	}
+	s.jobs = append(s.jobs, j)
+}
+
+// Run runs the jobs on their schedules until the context is
+// canceled, then waits for the running ones to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		j := j
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j Job) {
+	for {
+		next := j.Schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.WarnContext(ctx, "Job never scheduled", "job", j.Name)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(ctx, j, next)
+	}
+}
+
+// run runs a job scheduled at the given time unless another
+// instance runs it. The lock of a run is kept until the next one is
+// due, so that instances whose clocks are a little off do not run
+// it again.
+func (s *Scheduler) run(ctx context.Context, j Job, scheduled time.Time) {
+	if !j.Local {
+		ttl := max(j.Schedule.Next(scheduled).Sub(scheduled), time.Minute)
+		ok, err := s.locker.Lock(ctx, "job:"+j.Name+":"+scheduled.UTC().Format(time.RFC3339), ttl)
+		if err != nil {
+			logger.ErrorContext(ctx, "Job lock error", "job", j.Name, "error", err)
+			return
+		}
+		if !ok {
+			jobRuns.WithLabelValues(j.Name, "skipped").Inc()
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, j.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := j.Run(ctx)
+	backoff := j.RetryBackoff
+	for retry := 0; err != nil && retry < j.Retries && ctx.Err() == nil; retry++ {
+		logger.WarnContext(ctx, "Job run error, retrying", "job", j.Name, "retry", retry+1, "error", err)
+		jobRetries.WithLabelValues(j.Name).Inc()
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+			err = j.Run(ctx)
+		}
+		backoff *= 2
+	}
+	d := time.Since(start)
+	jobDuration.WithLabelValues(j.Name).Observe(d.Seconds())
+	if err != nil {
+		jobRuns.WithLabelValues(j.Name, "failure").Inc()
+		logger.ErrorContext(ctx, "Job run error", "job", j.Name, "duration", d, "error", err)
+		return
+	}
+	jobRuns.WithLabelValues(j.Name, "success").Inc()
+	jobLastSuccess.WithLabelValues(j.Name).Set(float64(start.Unix()))
+	logger.DebugContext(ctx, "Job run", "job", j.Name, "duration", d)
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps the locks of the jobs in process, so that each
+// instance runs all jobs.
+type Memory struct {
+	mu    sync.Mutex
+	locks map[string]time.Time
+}
+
+// NewMemory creates an in-process locker.
+func NewMemory() *Memory {
+	return &Memory{locks: map[string]time.Time{}}
+}
+
+// Lock acquires the named lock for the ttl unless it is held.
+func (m *Memory) Lock(_ context.Context, name string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for k, expiresAt := range m.locks {
+		if !now.Before(expiresAt) {
+			delete(m.locks, k)
+		}
+	}
+	if _, ok := m.locks[name]; ok {
+		return false, nil
+	}
+	m.locks[name] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,29 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker defines a Redis store of the locks of the jobs shared by
+// all instances of a service, so that one of them runs each job.
+type Locker struct {
+	client *redis.Client
+}
+
+// New creates a Redis locker at the given address.
+func New(addr string) *Locker {
+	return &Locker{redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+// Lock acquires the named lock for the ttl unless it is held.
+func (l *Locker) Lock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	return l.client.SetNX(ctx, name, 1, ttl).Result()
+}
+
+// Close closes the client.
+func (l *Locker) Close() error {
+	return l.client.Close()
+}
@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule defines when a job runs.
+type Schedule interface {
+	// Next returns the first time the job runs after t, or the zero
+	// time if never.
+	Next(t time.Time) time.Time
+}
+
+// Every returns a schedule running at the interval, aligned on
+// multiples of it since the Unix epoch, so that all instances of a
+// service run a job at the same times.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	return t.Truncate(d).Add(d)
+}
+
+// ParseSchedule parses a schedule in the cron format of five fields,
+// minute, hour, day of month, month and day of week, each either *,
+// a value, a range a-b or a list of them, optionally stepped by /n,
+// such as */15 * * * * or 0 3 * * 1-5. Days of week run from 0 for
+// Sunday to 6, 7 also being Sunday. The descriptors @hourly, @daily,
+// @weekly, @monthly and @yearly, and @every followed by a duration,
+// such as @every 5m, are also accepted. Cron schedules are in the
+// local time zone.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, err
+		}
+		if interval < time.Second {
+			return nil, errors.New("interval below a second")
+		}
+		return Every(interval), nil
+	}
+	if s, ok := descriptors[spec]; ok {
+		spec = s
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q: not five fields", spec)
+	}
+	var c cron
+	var err error
+	for i, dst := range []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow} {
+		if *dst, err = parseField(fields[i], bounds[i]); err != nil {
+			return nil, fmt.Errorf("%q: %w", spec, err)
+		}
+	}
+	// Sunday is either 0 or 7.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return &c, nil
+}
+
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+var bounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+func parseField(field string, bound [2]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepExpr, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepExpr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("%s: invalid step", part)
+			}
+		}
+		lo, hi := bound[0], bound[1]
+		if expr != "*" {
+			from, to, isRange := strings.Cut(expr, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("%s: invalid value", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("%s: invalid value", part)
+				}
+			} else if stepped {
+				hi = bound[1]
+			}
+		}
+		if lo < bound[0] || hi > bound[1] || lo > hi {
+			return 0, fmt.Errorf("%s: out of range %d-%d", part, bound[0], bound[1])
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// cron defines a cron schedule by the bits of the values of its
+// fields.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set when the day fields are *, as days
+	// match either field when both are restricted.
+	domAny, dowAny bool
+}
+
+// maxSearch bounds the search of the next time of schedules that
+// never run, such as on February 30.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+func (c *cron) Next(t time.Time) time.Time {
+	end := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(end) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,226 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultDrainTimeout bounds how long in-flight requests may take
+// to complete on shutdown.
+const DefaultDrainTimeout = 15 * time.Second
+
+// DefaultDrainDelay is a little longer than clients take to notice
+// that an instance left service discovery, as they resolve the
+// instances of services every 5 seconds.
+const DefaultDrainDelay = 6 * time.Second
+
+// server is a server run until shutdown.
+type server struct {
+	name     string
+	serve    func() error
+	shutdown func(context.Context) error
+	// grpc tells the gRPC servers apart, drained after the HTTP
+	// servers as REST gateways call them.
+	grpc bool
+}
+
+type hook struct {
+	name string
+	f    func(context.Context) error
+}
+
+// Runner runs the servers of a service until it receives SIGTERM or
+// SIGINT, then shuts it down gracefully:
+//
+//  1. the hooks registered by BeforeDrain run, such as the
+//     deregistration from service discovery, so that clients stop
+//     sending new requests
+//  2. the servers keep serving for the drain delay, until clients
+//     no longer resolve the instance
+//  3. the HTTP servers stop accepting connections and drain their
+//     in-flight requests, then the gRPC servers send GOAWAY to
+//     their clients, which move to other instances, and drain
+//     theirs, all within the drain timeout
+//  4. the hooks registered by AfterDrain run in reverse order of
+//     registration, such as flushing Kafka producers and closing
+//     database pools, which the drained requests no longer use
+type Runner struct {
+	drainTimeout time.Duration
+	drainDelay   time.Duration
+	servers      []server
+	before       []hook
+	after        []hook
+}
+
+// New creates a new runner draining requests for at most the
+// timeout on shutdown.
+func New(drainTimeout time.Duration) *Runner {
+	return &Runner{drainTimeout: drainTimeout}
+}
+
+// SetDrainDelay sets how long the servers keep serving on shutdown
+// once the hooks registered by BeforeDrain ran, none by default.
+func (r *Runner) SetDrainDelay(d time.Duration) {
+	r.drainDelay = d
+}
+
+// Context returns a context canceled on SIGTERM or SIGINT, for the
+// background work of a service, and a function releasing it.
+func Context() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+}
+
+// HTTP runs an HTTP server serving at its address, over TLS if it
+// has a TLS config, which provides its certificate.
+func (r *Runner) HTTP(name string, srv *http.Server) {
+	r.servers = append(r.servers, server{
+		name: name,
+		serve: func() error {
+			listen := srv.ListenAndServe
+			if srv.TLSConfig != nil {
+				listen = func() error { return srv.ListenAndServeTLS("", "") }
+			}
+			if err := listen(); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		shutdown: srv.Shutdown,
+	})
+}
+
+// GRPC runs a gRPC server serving on the listener. On shutdown it
+// stops gracefully: it sends GOAWAY to its clients, so that they
+// send new calls to other instances without failing them, and
+// waits for the calls in flight, which are canceled at the drain
+// timeout.
+func (r *Runner) GRPC(name string, srv *grpc.Server, lis net.Listener) {
+	r.servers = append(r.servers, server{
+		name:  name,
+		grpc:  true,
+		serve: func() error { return srv.Serve(lis) },
+		shutdown: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				slog.Warn("Canceling the gRPC calls in flight", "server", name)
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// BeforeDrain registers a hook run on shutdown before the servers
+// are drained.
+func (r *Runner) BeforeDrain(name string, f func(context.Context) error) {
+	r.before = append(r.before, hook{name, f})
+}
+
+// AfterDrain registers a hook run on shutdown once the servers are
+// drained, after the hooks registered later.
+func (r *Runner) AfterDrain(name string, f func(context.Context) error) {
+	r.after = append(r.after, hook{name, f})
+}
+
+// Close adapts a closer, such as a Kafka producer or a database
+// pool, to a hook.
+func Close(c io.Closer) func(context.Context) error {
+	return func(context.Context) error { return c.Close() }
+}
+
+// Run serves until the context is canceled, usually by a signal
+// with Context, or a server fails, then shuts down. It returns the
+// error of the failed server, if any, and of the shutdown.
+func (r *Runner) Run(ctx context.Context) error {
+	errs := make(chan error, len(r.servers))
+	for _, s := range r.servers {
+		s := s
+		go func() {
+			if err := s.serve(); err != nil {
+				errs <- fmt.Errorf("%s: %w", s.name, err)
+			}
+		}()
+	}
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	case err = <-errs:
+		slog.Error("Server error, shutting down", "error", err)
+	}
+	return errors.Join(err, r.shutdown())
+}
+
+func (r *Runner) shutdown() error {
+	var errs []error
+	// Hooks get a context of their own, as the one of Run is
+	// canceled by now.
+	ctx := context.Background()
+	for _, h := range r.before {
+		if err := h.f(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	if r.drainDelay > 0 {
+		slog.Info("Serving until clients stop resolving the instance", "delay", r.drainDelay)
+		time.Sleep(r.drainDelay)
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, r.drainTimeout)
+	defer cancel()
+	errs = append(errs, r.drain(drainCtx, false)...)
+	errs = append(errs, r.drain(drainCtx, true)...)
+	for i := len(r.after) - 1; i >= 0; i-- {
+		h := r.after[i]
+		if err := h.f(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// drain shuts down the gRPC servers, or the others, concurrently and
+// returns their errors.
+func (r *Runner) drain(ctx context.Context, grpcServers bool) []error {
+	var servers []server
+	for _, s := range r.servers {
+		if s.grpc == grpcServers {
+			servers = append(servers, s)
+		}
+	}
+	drained := make(chan error, len(servers))
+	for _, s := range servers {
+		s := s
+		go func() {
+			if err := s.shutdown(ctx); err != nil {
+				drained <- fmt.Errorf("%s: %w", s.name, err)
+				return
+			}
+			drained <- nil
+		}()
+	}
+	var errs []error
+	for range servers {
+		if err := <-drained; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
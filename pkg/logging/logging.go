@@ -0,0 +1,260 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader carries the id of a request to downstream HTTP
+// services, and is returned to clients so that they can report it.
+// gRPC calls carry it in the x-request-id metadata.
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDMetadata = "x-request-id"
+
+// Levels defines the minimum level of the records logged by each
+// package, by the name given to New, and by the other packages.
+type Levels struct {
+	Default  slog.Level
+	Packages map[string]slog.Level
+}
+
+// ParseLevels parses levels such as "info,cache=debug,event/kafka=warn":
+// a level without a package name sets the default.
+func ParseLevels(s string) (Levels, error) {
+	levels := Levels{Default: slog.LevelInfo, Packages: map[string]slog.Level{}}
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		pkg, value, ok := strings.Cut(field, "=")
+		if !ok {
+			pkg, value = "", field
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return Levels{}, fmt.Errorf("package %q: %w", pkg, err)
+		}
+		if pkg == "" {
+			levels.Default = level
+		} else {
+			levels.Packages[pkg] = level
+		}
+	}
+	return levels, nil
+}
+
+func (l Levels) level(pkg string) slog.Level {
+	if level, ok := l.Packages[pkg]; ok {
+		return level
+	}
+	return l.Default
+}
+
+type config struct {
+	levels Levels
+	base   slog.Handler
+}
+
+// current is the config set by Init. Loggers look it up on each
+// record, so that the loggers of packages created before Init log
+// as configured.
+var current atomic.Pointer[config]
+
+func init() {
+	current.Store(&config{
+		levels: Levels{Default: slog.LevelInfo},
+		base:   slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	})
+}
+
+// Init sets up the logging of a service: records are written as
+// JSON lines to standard error, labeled with the service name and
+// filtered by the levels. The standard logger and slog default
+// logger write through it too.
+func Init(serviceName string, levels Levels) {
+	base := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}).
+		WithAttrs([]slog.Attr{slog.String("service", serviceName)})
+	current.Store(&config{levels: levels, base: base})
+	slog.SetDefault(New("main"))
+}
+
+// SetLevels changes the levels of the records logged from now on,
+// such as when the config of a service is reloaded.
+func SetLevels(levels Levels) {
+	for {
+		cfg := current.Load()
+		if current.CompareAndSwap(cfg, &config{levels: levels, base: cfg.base}) {
+			return
+		}
+	}
+}
+
+// New returns the logger of a package. Records logged with a
+// context carry the request id and route set by Handler or
+// UnaryServerInterceptor and the trace and span ids of the
+// context.
+func New(pkg string) *slog.Logger {
+	return slog.New(&handler{pkg: pkg})
+}
+
+// handler applies the attributes and groups of a logger to the
+// base handler of the current config.
+type handler struct {
+	pkg string
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= current.Load().levels.level(h.pkg)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	base := current.Load().base.WithAttrs(contextAttrs(ctx, h.pkg))
+	for _, op := range h.ops {
+		base = op(base)
+	}
+	return base.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(base slog.Handler) slog.Handler { return base.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(base slog.Handler) slog.Handler { return base.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := append(h.ops[:len(h.ops):len(h.ops)], op)
+	return &handler{h.pkg, ops}
+}
+
+func contextAttrs(ctx context.Context, pkg string) []slog.Attr {
+	attrs := []slog.Attr{slog.String("package", pkg)}
+	if ctx == nil {
+		return attrs
+	}
+	if f, ok := ctx.Value(fieldsKey{}).(fields); ok {
+		attrs = append(attrs, slog.String("request_id", f.requestID), slog.String("route", f.route))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return attrs
+}
+
+type fieldsKey struct{}
+
+type fields struct {
+	requestID string
+	route     string
+}
+
+// NewContext returns a context carrying the id and route of a
+// request, logged with the records of the request.
+func NewContext(ctx context.Context, requestID string, route string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields{requestID, route})
+}
+
+// RequestID returns the id of the request of the context, if any.
+func RequestID(ctx context.Context) string {
+	f, _ := ctx.Value(fieldsKey{}).(fields)
+	return f.requestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Handler assigns incoming requests the id in their RequestIDHeader,
+// or a new one, and sets it on their responses. Their route is the
+// pattern returned by route, such as apiversion.Router.Pattern, or
+// their path if route is nil.
+func Handler(next http.Handler, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		r := req.URL.Path
+		if route != nil {
+			r = route(req)
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), id, r)))
+	})
+}
+
+// SetHeader sets the request id of the context on an outgoing
+// request.
+func SetHeader(req *http.Request) {
+	if id := RequestID(req.Context()); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+}
+
+// UnaryServerInterceptor assigns incoming gRPC calls the request id
+// in their metadata, or a new one. Their route is the full method.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(incomingContext(ctx, info.FullMethod), req)
+	}
+}
+
+// StreamServerInterceptor assigns incoming gRPC streams a request
+// id like UnaryServerInterceptor.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ss, incomingContext(ss.Context(), info.FullMethod)})
+	}
+}
+
+// incomingContext returns the context of an incoming gRPC call
+// carrying its request id and method.
+func incomingContext(ctx context.Context, method string) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestIDMetadata); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	return NewContext(ctx, id, method)
+}
+
+// serverStream overrides the context of a gRPC server stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// DialOption passes the request id of the context of the calls of
+// a gRPC client to the called service.
+func DialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if id := RequestID(ctx); id != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, requestIDMetadata, id)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
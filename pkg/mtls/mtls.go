@@ -0,0 +1,218 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"movieapp.com/pkg/config"
+)
+
+// ErrPeerNotAllowed is returned when the certificate of a peer is
+// valid but identifies a service that is not allowed.
+var ErrPeerNotAllowed = errors.New("peer identity not allowed")
+
+// Config defines the certificates of a service for mutual TLS. The
+// certificate identifies the service to its peers, either by its
+// DNS names or, SPIFFE-style, by a spiffe:// URI SAN such as
+// spiffe://movieapp.com/movie, and the CA bundle verifies the
+// certificates of the peers.
+type Config struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	CAFile   string `yaml:"caFile"`
+	// PeerIDs are the SPIFFE IDs of the peers accepted, in addition
+	// to the service itself. Any peer with a certificate signed by
+	// the CA is accepted if empty.
+	PeerIDs config.List `yaml:"peerIds"`
+}
+
+// Enabled reports whether certificates are configured.
+func (c *Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+// Validate returns an error if the config is enabled but
+// incomplete or a peer ID is not a SPIFFE ID.
+func (c *Config) Validate() error {
+	if !c.Enabled() {
+		if len(c.PeerIDs) > 0 {
+			return errors.New("peerIds: set without certificates")
+		}
+		return nil
+	}
+	var errs []error
+	if c.CertFile == "" {
+		errs = append(errs, errors.New("certFile: empty"))
+	}
+	if c.KeyFile == "" {
+		errs = append(errs, errors.New("keyFile: empty"))
+	}
+	if c.CAFile == "" {
+		errs = append(errs, errors.New("caFile: empty"))
+	}
+	for _, id := range c.PeerIDs {
+		if u, err := url.Parse(id); err != nil || u.Scheme != "spiffe" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("peerIds: invalid SPIFFE ID %q", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Credentials holds the loaded certificates of a service. The key
+// pair is reloaded when its files change, so that short-lived
+// certificates, such as SPIFFE SVIDs rotated by an agent, are
+// renewed without a restart.
+type Credentials struct {
+	cfg   Config
+	roots *x509.CertPool
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	selfID  string
+}
+
+// Load loads the certificates of the config.
+func Load(cfg Config) (*Credentials, error) {
+	pem, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates", cfg.CAFile)
+	}
+	c := &Credentials{cfg: cfg, roots: roots}
+	if _, err := c.certificate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// certificate returns the key pair, reloading it if the
+// certificate file was modified since it was loaded. The loaded one
+// is kept if reloading fails, such as while the files are being
+// replaced.
+func (c *Credentials) certificate() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, err := os.Stat(c.cfg.CertFile)
+	if err != nil || info.ModTime().Equal(c.modTime) {
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+	if err != nil {
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf = leaf
+	c.cert, c.modTime, c.selfID = &cert, info.ModTime(), spiffeID(leaf)
+	return c.cert, nil
+}
+
+// Server returns the TLS config of a server, requiring clients to
+// present a certificate signed by the CA of an allowed peer.
+func (c *Credentials) Server() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  c.roots,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.certificate()
+		},
+		VerifyPeerCertificate: func(_ [][]byte, chains [][]*x509.Certificate) error {
+			return c.verifyPeer(chains[0][0])
+		},
+	}
+}
+
+// Client returns the TLS config of a client, presenting the
+// certificate of the service and verifying that the server has a
+// certificate signed by the CA of an allowed peer. Servers are not
+// verified by host name, as SPIFFE certificates identify services
+// by URI rather than by the addresses they are dialed at, unless
+// no peer IDs are configured.
+func (c *Credentials) Client() *tls.Config {
+	if len(c.cfg.PeerIDs) == 0 {
+		return &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    c.roots,
+			GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return c.certificate()
+			},
+		}
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The chain is verified by VerifyPeerCertificate instead.
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.certificate()
+		},
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			certs := make([]*x509.Certificate, len(raw))
+			for i, b := range raw {
+				cert, err := x509.ParseCertificate(b)
+				if err != nil {
+					return err
+				}
+				certs[i] = cert
+			}
+			if len(certs) == 0 {
+				return errors.New("no server certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range certs[1:] {
+				intermediates.AddCert(cert)
+			}
+			if _, err := certs[0].Verify(x509.VerifyOptions{Roots: c.roots, Intermediates: intermediates}); err != nil {
+				return err
+			}
+			return c.verifyPeer(certs[0])
+		},
+	}
+}
+
+// verifyPeer returns ErrPeerNotAllowed unless the verified
+// certificate of a peer identifies an allowed peer or the service
+// itself, like its REST gateway calling its gRPC API.
+func (c *Credentials) verifyPeer(cert *x509.Certificate) error {
+	if len(c.cfg.PeerIDs) == 0 {
+		return nil
+	}
+	id := spiffeID(cert)
+	c.mu.Lock()
+	self := c.selfID
+	c.mu.Unlock()
+	if id != "" && (id == self || slices.Contains(c.cfg.PeerIDs, id)) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrPeerNotAllowed, id)
+}
+
+// spiffeID returns the SPIFFE ID of a certificate, empty if it has
+// none.
+func spiffeID(cert *x509.Certificate) string {
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			return u.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory defines an outbox in process, for tests and in-memory
+// repositories. Messages are removed once published.
+type Memory struct {
+	// delivering serializes deliveries, so that concurrent ones do
+	// not pass the same messages.
+	delivering sync.Mutex
+
+	mu      sync.Mutex
+	pending []Message
+}
+
+// NewMemory creates an in-process outbox.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Enqueue records the messages. Callers record them under the lock
+// of the change they announce, so that they are enqueued in order.
+func (o *Memory) Enqueue(_ context.Context, msgs ...Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending = append(o.pending, msgs...)
+}
+
+// Deliver passes the oldest pending messages to deliver, removing
+// them if it succeeds.
+func (o *Memory) Deliver(ctx context.Context, limit int, deliver func(ctx context.Context, msgs []Message) error) (int, error) {
+	o.delivering.Lock()
+	defer o.delivering.Unlock()
+	o.mu.Lock()
+	msgs := append([]Message(nil), o.pending[:min(limit, len(o.pending))]...)
+	o.mu.Unlock()
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	if err := deliver(ctx, msgs); err != nil {
+		return 0, err
+	}
+	o.mu.Lock()
+	o.pending = o.pending[len(msgs):]
+	o.mu.Unlock()
+	return len(msgs), nil
+}
@@ -0,0 +1,172 @@
+// Package outbox implements transactional outboxes: messages are
+// recorded in the same transaction as the change they announce, so
+// that none is lost if the broker is down, and a relay publishes
+// them in order once it is back.
+package outbox
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"movieapp.com/pkg/bus"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/tenant"
+)
+
+var logger = logging.New("outbox")
+
+// relayStats publishes the deliveries of every relay, keyed by
+// topic, at /debug/vars.
+var relayStats = expvar.NewMap("outbox")
+
+// Defaults of the relays.
+const (
+	DefaultBatchSize = 100
+	maxBackoff       = time.Minute
+)
+
+// IDHeader is the header carrying the id of a message, by which
+// consumers deduplicate the messages delivered again after the relay
+// failed to mark them published.
+const IDHeader = "id"
+
+// Message defines a message recorded in an outbox.
+type Message struct {
+	// ID identifies the message, unique in its outbox.
+	ID      string
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// Source defines an outbox delivered by a relay.
+type Source interface {
+	// Deliver passes up to limit pending messages, oldest first, to
+	// deliver and marks them published if it succeeds, returning
+	// their number. Messages passed to a concurrent Deliver are not
+	// passed again until it returns.
+	Deliver(ctx context.Context, limit int, deliver func(ctx context.Context, msgs []Message) error) (int, error)
+}
+
+// PerTenant returns a source delivering the messages of the outbox
+// of each tenant of the set, such as an SQL outbox in a tenant.DB,
+// with a context of the tenant. Tenants take turns starting the
+// batches, so that a busy one does not hold back the others.
+func PerTenant(source Source, s *tenant.Set) Source {
+	return &perTenant{source: source, ids: s.IDs()}
+}
+
+type perTenant struct {
+	source Source
+	ids    []string
+	next   atomic.Uint64
+}
+
+func (p *perTenant) Deliver(ctx context.Context, limit int, deliver func(ctx context.Context, msgs []Message) error) (int, error) {
+	start := p.next.Add(1)
+	total := 0
+	for i := range p.ids {
+		id := p.ids[(start+uint64(i))%uint64(len(p.ids))]
+		n, err := p.source.Deliver(tenant.NewContext(ctx, id), limit-total, deliver)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("tenant %s: %w", id, err)
+		}
+		if total == limit {
+			break
+		}
+	}
+	return total, nil
+}
+
+// Relay publishes the messages of an outbox to a topic, at least
+// once and in order. Messages are marked published once the broker
+// stored them, so they are only published again if marking fails.
+type Relay struct {
+	source    Source
+	publisher bus.Publisher
+	topic     string
+	interval  time.Duration
+	batchSize int
+
+	mu        sync.Mutex
+	delivered int64
+	failures  int64
+	last      time.Time
+}
+
+// NewRelay creates a relay polling the source for pending messages
+// at the interval, and publishing them to the topic in batches of up
+// to DefaultBatchSize.
+func NewRelay(source Source, publisher bus.Publisher, topic string, interval time.Duration) *Relay {
+	r := &Relay{source: source, publisher: publisher, topic: topic, interval: interval, batchSize: DefaultBatchSize}
+	relayStats.Set(topic, expvar.Func(r.stats))
+	return r
+}
+
+// Run delivers messages until the context is canceled. Full batches
+// are followed by the next at once, and failures are retried after
+// a backoff doubling up to a minute.
+func (r *Relay) Run(ctx context.Context) {
+	backoff := r.interval
+	for {
+		n, err := r.source.Deliver(ctx, r.batchSize, r.publish)
+		wait := r.interval
+		if err != nil {
+			logger.ErrorContext(ctx, "Outbox delivery error", "topic", r.topic, "error", err)
+			wait = backoff
+			backoff = min(2*backoff, maxBackoff)
+		} else {
+			backoff = r.interval
+		}
+		r.record(n, err)
+		if n == r.batchSize && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, msgs []Message) error {
+	bmsgs := make([]bus.Message, 0, len(msgs))
+	for _, m := range msgs {
+		headers := make(map[string]string, len(m.Headers)+1)
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+		headers[IDHeader] = m.ID
+		bmsgs = append(bmsgs, bus.Message{Key: m.Key, Value: m.Value, Headers: headers})
+	}
+	return r.publisher.Publish(ctx, r.topic, bmsgs...)
+}
+
+func (r *Relay) record(n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.failures++
+		return
+	}
+	r.delivered += int64(n)
+	if n > 0 {
+		r.last = time.Now()
+	}
+}
+
+func (r *Relay) stats() any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return map[string]any{
+		"delivered":     r.delivered,
+		"failures":      r.failures,
+		"lastDelivered": r.last,
+	}
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"movieapp.com/pkg/bus"
+)
+
+// Schema returns the MySQL statement creating an outbox table.
+func Schema(table string) string {
+	return "CREATE TABLE IF NOT EXISTS " + table + " (seq BIGINT AUTO_INCREMENT PRIMARY KEY, message_id VARCHAR(255) UNIQUE, message_key VARCHAR(255), payload LONGBLOB, headers JSON, created_at DATETIME(6), published_at DATETIME(6) NULL, INDEX (published_at, seq))"
+}
+
+// DB defines the database of an SQL outbox, such as *sql.DB, or
+// *tenant.DB for an outbox per tenant.
+type DB interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// SQL defines an outbox in a MySQL table created by Schema.
+type SQL struct {
+	db    DB
+	table string
+}
+
+// NewSQL creates an outbox in the table of the database.
+func NewSQL(db DB, table string) *SQL {
+	return &SQL{db, table}
+}
+
+// Enqueue records the messages in the transaction of the change
+// they announce, with the trace context of the context unless they
+// carry one.
+func (o *SQL) Enqueue(ctx context.Context, tx *sql.Tx, msgs ...Message) error {
+	now := time.Now().UTC()
+	for _, m := range msgs {
+		if m.ID == "" {
+			return errors.New("message without id")
+		}
+		headers, err := json.Marshal(bus.Headers(ctx, m.Headers))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO "+o.table+" (message_id, message_key, payload, headers, created_at) VALUES (?, ?, ?, ?, ?)",
+			m.ID, m.Key, m.Value, headers, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver passes the oldest pending messages to deliver, locking
+// them until they are marked published so that the relays of other
+// instances wait for them instead of publishing them again.
+func (o *SQL) Deliver(ctx context.Context, limit int, deliver func(ctx context.Context, msgs []Message) error) (int, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, "SELECT seq, message_id, message_key, payload, headers FROM "+o.table+" WHERE published_at IS NULL ORDER BY seq LIMIT ? FOR UPDATE", limit)
+	if err != nil {
+		return 0, err
+	}
+	var msgs []Message
+	var seqs []any
+	for rows.Next() {
+		var seq int64
+		var m Message
+		var headers []byte
+		if err := rows.Scan(&seq, &m.ID, &m.Key, &m.Value, &headers); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &m.Headers); err != nil {
+				rows.Close()
+				return 0, err
+			}
+		}
+		msgs = append(msgs, m)
+		seqs = append(seqs, seq)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	if err := deliver(ctx, msgs); err != nil {
+		return 0, err
+	}
+	args := append([]any{time.Now().UTC()}, seqs...)
+	if _, err := tx.ExecContext(ctx, "UPDATE "+o.table+" SET published_at = ? WHERE seq IN (?"+strings.Repeat(", ?", len(seqs)-1)+")", args...); err != nil {
+		return 0, err
+	}
+	return len(msgs), tx.Commit()
+}
+
+// Purge deletes the messages published before the given time,
+// returning their number.
+func (o *SQL) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res, err := o.db.ExecContext(ctx, "DELETE FROM "+o.table+" WHERE published_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
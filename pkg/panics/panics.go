@@ -0,0 +1,103 @@
+// Package panics handles the panics recovered while serving
+// requests: it logs them with their stack and the context of the
+// request, and reports them to an error tracker such as Sentry, so
+// that a bad request fails alone instead of crashing the instance
+// unnoticed.
+package panics
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("panics")
+
+// recovered publishes the number of panics recovered by operation
+// at /debug/vars.
+var recovered = expvar.NewMap("recovered_panics")
+
+// Panic defines a recovered panic and the request it happened in.
+type Panic struct {
+	// Operation is the route or the gRPC method of the request.
+	Operation string
+	Value     any
+	Stack     []byte
+	RequestID string
+	TraceID   string
+	// UserID is the subject of the caller, empty if anonymous.
+	UserID string
+	Time   time.Time
+}
+
+// Message returns the panic value as a string.
+func (p *Panic) Message() string {
+	if err, ok := p.Value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(p.Value)
+}
+
+// Reporter defines a reporter of panics to an error tracker.
+// Reports must not block for long, as the failed request waits.
+type Reporter interface {
+	Report(ctx context.Context, p *Panic)
+}
+
+// ReporterFunc adapts a function to a Reporter.
+type ReporterFunc func(ctx context.Context, p *Panic)
+
+// Report calls f.
+func (f ReporterFunc) Report(ctx context.Context, p *Panic) {
+	f(ctx, p)
+}
+
+var reporter atomic.Pointer[Reporter]
+
+// SetReporter reports the panics recovered afterwards to the
+// reporter, besides logging them.
+func SetReporter(r Reporter) {
+	reporter.Store(&r)
+}
+
+// Recovered handles a panic recovered from the handler of a request
+// with the context: it logs and counts the panic and reports it.
+// It must be called from the deferred function that recovered, for
+// the stack to be that of the panic.
+func Recovered(ctx context.Context, operation string, v any) {
+	p := &Panic{
+		Operation: operation,
+		Value:     v,
+		Stack:     debug.Stack(),
+		RequestID: logging.RequestID(ctx),
+		Time:      time.Now().UTC(),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		p.TraceID = sc.TraceID().String()
+	}
+	if principal := auth.FromContext(ctx); principal != nil {
+		p.UserID = principal.Subject
+	}
+	recovered.Add(operation, 1)
+	logger.ErrorContext(ctx, "Panic serving request", "operation", operation, "panic", p.Message(), "stack", string(p.Stack))
+	if r := reporter.Load(); r != nil {
+		report(ctx, *r, p)
+	}
+}
+
+// report reports a panic, recovering from panics of the reporter.
+func report(ctx context.Context, r Reporter, p *Panic) {
+	defer func() {
+		if v := recover(); v != nil {
+			logger.ErrorContext(ctx, "Panic reporter panicked", "panic", v)
+		}
+	}()
+	r.Report(ctx, p)
+}
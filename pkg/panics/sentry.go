@@ -0,0 +1,133 @@
+package panics
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// sentryTimeout bounds the time a report is sent in.
+const sentryTimeout = 5 * time.Second
+
+// Sentry reports panics to a Sentry project as events of its store
+// API. Reports are sent in the background, so that failed requests
+// do not wait for them.
+type Sentry struct {
+	storeURL   string
+	auth       string
+	service    string
+	serverName string
+	client     *http.Client
+}
+
+// NewSentry creates a reporter of the panics of the named service
+// to the Sentry project of the DSN, such as
+// https://<key>@o1.ingest.sentry.io/2.
+func NewSentry(dsn string, serviceName string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("no public key in the Sentry DSN")
+	}
+	// The project id is the last segment of the path.
+	i := strings.LastIndex(u.Path, "/")
+	path, project := u.Path[:max(i, 0)], u.Path[i+1:]
+	if project == "" {
+		return nil, errors.New("no project in the Sentry DSN")
+	}
+	hostname, _ := os.Hostname()
+	return &Sentry{
+		storeURL:   fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path, project),
+		auth:       fmt.Sprintf("Sentry sentry_version=7, sentry_client=movieapp/1.0, sentry_key=%s", u.User.Username()),
+		service:    serviceName,
+		serverName: hostname,
+		client:     &http.Client{Timeout: sentryTimeout},
+	}, nil
+}
+
+// sentryEvent defines an event of the Sentry store API.
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Transaction string            `json:"transaction"`
+	Exception   sentryExceptions  `json:"exception"`
+	Tags        map[string]string `json:"tags"`
+	User        *sentryUser       `json:"user,omitempty"`
+	Extra       map[string]string `json:"extra"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryUser struct {
+	ID string `json:"id"`
+}
+
+// Report sends the panic as an event in the background.
+func (s *Sentry) Report(ctx context.Context, p *Panic) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	e := &sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   p.Time.Format(time.RFC3339),
+		Platform:    "go",
+		Level:       "fatal",
+		Logger:      "panics",
+		ServerName:  s.serverName,
+		Transaction: p.Operation,
+		Exception:   sentryExceptions{[]sentryException{{Type: "panic", Value: p.Message()}}},
+		Tags:        map[string]string{"service": s.service, "request_id": p.RequestID, "trace_id": p.TraceID},
+		Extra:       map[string]string{"stack": string(p.Stack)},
+	}
+	if p.UserID != "" {
+		e.User = &sentryUser{p.UserID}
+	}
+	go func() {
+		if err := s.send(context.WithoutCancel(ctx), e); err != nil {
+			logger.WarnContext(ctx, "Sentry report error", "error", err)
+		}
+	}()
+}
+
+func (s *Sentry) send(ctx context.Context, e *sentryEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("problem")
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// Kind defines a class of errors, with the problem type, title
+// and HTTP status of their problem details and their gRPC code.
+type Kind struct {
+	Type   string
+	Title  string
+	Status int
+	Code   codes.Code
+}
+
+// Existing kinds. Types are URI references relative to the API,
+// documenting the problems.
+var (
+	BadRequest           = Kind{"/problems/bad-request", "Bad request", http.StatusBadRequest, codes.InvalidArgument}
+	Unauthenticated      = Kind{"/problems/unauthenticated", "Unauthenticated", http.StatusUnauthorized, codes.Unauthenticated}
+	Forbidden            = Kind{"/problems/forbidden", "Forbidden", http.StatusForbidden, codes.PermissionDenied}
+	NotFound             = Kind{"/problems/not-found", "Not found", http.StatusNotFound, codes.NotFound}
+	MethodNotAllowed     = Kind{"/problems/method-not-allowed", "Method not allowed", http.StatusMethodNotAllowed, codes.Unimplemented}
+	Conflict             = Kind{"/problems/conflict", "Conflict", http.StatusConflict, codes.AlreadyExists}
+	TooLarge             = Kind{"/problems/too-large", "Request too large", http.StatusRequestEntityTooLarge, codes.InvalidArgument}
+	UnsupportedMediaType = Kind{"/problems/unsupported-media-type", "Unsupported media type", http.StatusUnsupportedMediaType, codes.InvalidArgument}
+	Invalid              = Kind{"/problems/invalid", "Validation failed", http.StatusUnprocessableEntity, codes.InvalidArgument}
+	TooManyRequests      = Kind{"/problems/too-many-requests", "Too many requests", http.StatusTooManyRequests, codes.ResourceExhausted}
+	BadGateway           = Kind{"/problems/bad-gateway", "Bad gateway", http.StatusBadGateway, codes.Unavailable}
+	Internal             = Kind{"/problems/internal", "Internal error", http.StatusInternalServerError, codes.Internal}
+	Unavailable          = Kind{"/problems/unavailable", "Dependency unavailable", http.StatusServiceUnavailable, codes.Unavailable}
+	Timeout              = Kind{"/problems/timeout", "Timed out", http.StatusGatewayTimeout, codes.DeadlineExceeded}
+)
+
+// Problem defines the RFC 7807 problem details of a failed
+// request. Extensions are encoded as additional members.
+type Problem struct {
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	TraceID    string         `json:"traceId,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+// New creates the problem details of a kind for a request, with
+// the id of its trace if sampled.
+func New(req *http.Request, kind Kind, detail string) *Problem {
+	p := &Problem{Type: kind.Type, Title: kind.Title, Status: kind.Status, Detail: detail}
+	if sc := trace.SpanContextFromContext(req.Context()); sc.IsValid() {
+		p.TraceID = sc.TraceID().String()
+	}
+	return p
+}
+
+// With sets an extension member of the problem.
+func (p *Problem) With(name string, v any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]any{}
+	}
+	p.Extensions[name] = v
+	return p
+}
+
+// MarshalJSON encodes the problem with its extensions.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	b, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return b, err
+	}
+	var members map[string]any
+	if err := json.Unmarshal(b, &members); err != nil {
+		return nil, err
+	}
+	ext := maps.Clone(p.Extensions)
+	maps.Copy(ext, members)
+	return json.Marshal(ext)
+}
+
+// Write writes the problem as the response.
+func (p *Problem) Write(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logger.ErrorContext(req.Context(), "Problem encode error", "error", err)
+	}
+}
+
+// Write writes the problem details of a kind as the response.
+func Write(w http.ResponseWriter, req *http.Request, kind Kind, detail string) {
+	New(req, kind, detail).Write(w, req)
+}
+
+type registered struct {
+	err  error
+	kind Kind
+}
+
+var kinds = []registered{{context.DeadlineExceeded, Timeout}}
+
+// Register registers the kind of a domain error, and of the
+// errors wrapping it, and returns the error. It is meant to be
+// called where the error is declared.
+func Register(err error, kind Kind) error {
+	kinds = append(kinds, registered{err, kind})
+	return err
+}
+
+// KindOf returns the kind of an error, Internal if not registered.
+func KindOf(err error) Kind {
+	for _, r := range kinds {
+		if errors.Is(err, r.err) {
+			return r.kind
+		}
+	}
+	return Internal
+}
+
+// Extended is implemented by errors adding extension members to
+// their problem details, such as the invalid fields of a request.
+type Extended interface {
+	ProblemExtensions() map[string]any
+}
+
+// Error writes the problem details of an error as the response.
+// Errors of registered kinds are detailed by their message and
+// extended if Extended, while other errors are logged and not
+// detailed, as their messages may reveal the internals of the
+// service.
+func Error(w http.ResponseWriter, req *http.Request, err error) {
+	kind := KindOf(err)
+	if kind == Internal {
+		logger.ErrorContext(req.Context(), "Request error", "error", err)
+		Write(w, req, kind, "")
+		return
+	}
+	p := New(req, kind, err.Error())
+	var ext Extended
+	if errors.As(err, &ext) {
+		for name, v := range ext.ProblemExtensions() {
+			p.With(name, v)
+		}
+	}
+	p.Write(w, req)
+}
+
+// Status converts an error to a gRPC status of the code of its
+// kind. gRPC status errors are returned as they are.
+func Status(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(KindOf(err).Code, err.Error())
+}
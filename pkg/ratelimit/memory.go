@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Memory keeps the state of limiters in process, limiting the
+// clients of each instance separately.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	allows  int
+}
+
+type memoryEntry struct {
+	// tokens are left in a token bucket at time at. count is the
+	// count of the fixed window starting at at, and prevCount of the
+	// window before it.
+	tokens    float64
+	count     int
+	prevCount int
+	at        time.Time
+	expiresAt time.Time
+}
+
+// NewMemory creates a new in-process backend.
+func NewMemory() *Memory {
+	return &Memory{entries: map[string]*memoryEntry{}}
+}
+
+// Limiter returns a limiter of a rule.
+func (m *Memory) Limiter(name string, rule Rule) Limiter {
+	return &memoryLimiter{m, name, rule}
+}
+
+// entry returns the entry of a key, removing expired entries now
+// and then to bound the memory.
+func (m *Memory) entry(key string, now time.Time, ttl time.Duration) (*memoryEntry, bool) {
+	if m.allows++; m.allows%1024 == 0 {
+		for k, e := range m.entries {
+			if now.After(e.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	e, ok := m.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		e = &memoryEntry{}
+		m.entries[key] = e
+		ok = false
+	}
+	e.expiresAt = now.Add(ttl)
+	return e, ok
+}
+
+type memoryLimiter struct {
+	m    *Memory
+	name string
+	rule Rule
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string) (Decision, error) {
+	l.m.mu.Lock()
+	defer l.m.mu.Unlock()
+	now := time.Now()
+	window := time.Duration(l.rule.Window)
+	if l.rule.Algorithm == TokenBucket {
+		e, ok := l.m.entry(l.name+":"+key, now, window)
+		if !ok {
+			e.tokens, e.at = float64(l.rule.BucketSize()), now
+		}
+		return takeToken(l.rule, e, now), nil
+	}
+	e, ok := l.m.entry(l.name+":"+key, now, 2*window)
+	start := now.Truncate(window)
+	if !ok {
+		e.at = start
+	}
+	if !e.at.Equal(start) {
+		e.prevCount = 0
+		if e.at.Add(window).Equal(start) {
+			e.prevCount = e.count
+		}
+		e.count, e.at = 0, start
+	}
+	d := SlidingWindowDecision(l.rule, e.prevCount, e.count, now.Sub(start))
+	if d.Allowed {
+		e.count++
+	}
+	return d, nil
+}
+
+// takeToken refills the bucket of an entry and takes a token.
+func takeToken(rule Rule, e *memoryEntry, now time.Time) Decision {
+	e.tokens = math.Min(float64(rule.BucketSize()), e.tokens+now.Sub(e.at).Seconds()*rule.rate())
+	e.at = now
+	allowed := e.tokens >= 1
+	if allowed {
+		e.tokens--
+	}
+	return TokenBucketDecision(rule, allowed, e.tokens)
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"movieapp.com/internal/apiversion"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+)
+
+var logger = logging.New("ratelimit")
+
+// Routes limits the requests clients send to the routes of an HTTP
+// API by the rules of a config.
+type Routes struct {
+	backend Backend
+	rules   atomic.Pointer[routeRules]
+}
+
+// routeRules defines the rules of a config and their limiters.
+type routeRules struct {
+	config   *Config
+	limiters map[string]Limiter
+}
+
+// NewRoutes creates the limiters of the routes of a config from the
+// backend.
+func NewRoutes(config *Config, backend Backend) *Routes {
+	r := &Routes{backend: backend}
+	r.SetConfig(config)
+	return r
+}
+
+// SetConfig replaces the rules of the routes, such as when the
+// config of a service is reloaded. The counts of the in process
+// limiters start over.
+func (r *Routes) SetConfig(config *Config) {
+	limiters := map[string]Limiter{"": New(r.backend, "default", config.Default)}
+	for route, rule := range config.Routes {
+		limiters[route] = New(r.backend, route, rule)
+	}
+	r.rules.Store(&routeRules{config, limiters})
+}
+
+// Handler limits the requests to the next handler of the clients
+// identified by key, rejecting those over the limit with 429 and a
+// Retry-After header of the seconds until a request may be
+// allowed. Backend failures are logged and let the requests
+// through, as they are no fault of the clients.
+func (r *Routes) Handler(next http.Handler, key func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rules := r.rules.Load()
+		limiter := rules.limiters[rules.config.route(apiversion.Unversioned(req.URL.Path))]
+		d, err := limiter.Allow(req.Context(), key(req))
+		if err != nil {
+			logger.ErrorContext(req.Context(), "Rate limiter error", "error", err)
+			next.ServeHTTP(w, req)
+			return
+		}
+		if d.Limit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+		}
+		if !d.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(d.RetryAfter)))
+			problem.Write(w, req, problem.TooManyRequests, fmt.Sprintf("rate limit of %d exceeded", d.Limit))
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
+
+// ClientIP identifies the client of a request by its IP address.
+func ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// PeerIP identifies the client of a gRPC call by the IP address of
+// its peer.
+func PeerIP(ctx context.Context, _ any) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+// UnaryServerInterceptor limits the gRPC calls of the methods with
+// a limiter, by full method name, of the clients identified by
+// key, failing those over the limit with ResourceExhausted and
+// retry-after trailer metadata. Limiter failures are logged and let
+// the calls through.
+func UnaryServerInterceptor(limiters map[string]Limiter, key func(ctx context.Context, req any) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		limiter, ok := limiters[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		d, err := limiter.Allow(ctx, key(ctx, req))
+		if err != nil {
+			logger.ErrorContext(ctx, "Rate limiter error", "error", err)
+			return handler(ctx, req)
+		}
+		if !d.Allowed {
+			grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfterSeconds(d.RetryAfter))))
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit of %d exceeded", d.Limit)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,221 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Algorithm defines how a limiter spreads the requests it allows
+// over time.
+type Algorithm string
+
+const (
+	// SlidingWindow allows up to the limit of requests within any
+	// window, estimated from the counts of the current and the
+	// previous fixed windows. It is the default.
+	SlidingWindow Algorithm = "sliding-window"
+	// TokenBucket refills a bucket of burst tokens at the limit per
+	// window, each request taking a token, so that clients may
+	// burst after being idle.
+	TokenBucket Algorithm = "token-bucket"
+)
+
+// Rule defines the number of requests a client may send within a
+// window. A zero limit does not limit.
+type Rule struct {
+	Algorithm Algorithm `json:"algorithm" yaml:"algorithm"`
+	Limit     int       `json:"limit" yaml:"limit"`
+	Window    Duration  `json:"window" yaml:"window"`
+	// Burst is the size of the bucket of a token bucket, the limit
+	// if zero.
+	Burst int `json:"burst" yaml:"burst"`
+}
+
+// Validate returns an error if the rule is invalid.
+func (r Rule) Validate() error {
+	if r.Limit < 0 {
+		return fmt.Errorf("negative limit %d", r.Limit)
+	}
+	if r.Limit > 0 && r.Window <= 0 {
+		return fmt.Errorf("non-positive window %v", time.Duration(r.Window))
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("negative burst %d", r.Burst)
+	}
+	switch r.Algorithm {
+	case "", SlidingWindow, TokenBucket:
+	default:
+		return fmt.Errorf("unknown algorithm %q", r.Algorithm)
+	}
+	return nil
+}
+
+// BucketSize returns the size of the bucket of a token bucket rule.
+func (r Rule) BucketSize() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// rate returns the tokens a token bucket refills per second.
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / time.Duration(r.Window).Seconds()
+}
+
+// Duration defines a duration in the format of
+// time.ParseDuration, such as 1m, in JSON and YAML.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// UnmarshalYAML parses a duration string.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.parse(node.Value)
+}
+
+func (d *Duration) parse(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Decision defines whether a request is allowed, and the state of
+// the limit of its client.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the wait until a denied request may be allowed.
+	RetryAfter time.Duration
+}
+
+// TokenBucketDecision returns the decision of a request to a
+// token bucket of a rule, left with tokens once the request took
+// one if allowed. Backends refill and take the tokens themselves.
+func TokenBucketDecision(rule Rule, allowed bool, tokens float64) Decision {
+	d := Decision{Allowed: allowed, Limit: rule.BucketSize(), Remaining: int(tokens)}
+	if !allowed {
+		d.RetryAfter = time.Duration((1 - tokens) / rule.rate() * float64(time.Second))
+	}
+	return d
+}
+
+// SlidingWindowDecision decides whether a request is allowed by
+// the counts of the previous and the current fixed windows before
+// the request, elapsed into the current one. The previous count is
+// weighted by the share of the previous window still within the
+// sliding window. Backends count the allowed requests themselves.
+func SlidingWindowDecision(rule Rule, prevCount int, count int, elapsed time.Duration) Decision {
+	window := time.Duration(rule.Window)
+	estimate := float64(prevCount)*PrevWeight(rule, elapsed) + float64(count)
+	d := Decision{Limit: rule.Limit, Allowed: estimate+1 <= float64(rule.Limit)}
+	if d.Allowed {
+		estimate++
+	} else if count >= rule.Limit || prevCount == 0 {
+		d.RetryAfter = window - elapsed
+	} else {
+		// The weight of the previous window drops until a request
+		// fits.
+		fits := 1 - (float64(rule.Limit)-1-float64(count))/float64(prevCount)
+		d.RetryAfter = time.Duration(fits*float64(window)) - elapsed
+	}
+	d.Remaining = max(rule.Limit-int(math.Ceil(estimate)), 0)
+	return d
+}
+
+// PrevWeight returns the weight of the count of the previous fixed
+// window of a sliding window rule, elapsed into the current one.
+func PrevWeight(rule Rule, elapsed time.Duration) float64 {
+	return 1 - float64(elapsed)/float64(rule.Window)
+}
+
+// Limiter limits the requests of clients identified by keys.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Decision, error)
+}
+
+// Backend creates the limiters of rules, keeping their state in
+// process or shared between instances.
+type Backend interface {
+	// Limiter returns a limiter of a rule, whose keys are
+	// namespaced by name.
+	Limiter(name string, rule Rule) Limiter
+}
+
+// unlimited allows all requests.
+type unlimited struct{}
+
+func (unlimited) Allow(context.Context, string) (Decision, error) {
+	return Decision{Allowed: true}, nil
+}
+
+// New returns a limiter of a rule from the backend, allowing all
+// requests if the rule does not limit.
+func New(backend Backend, name string, rule Rule) Limiter {
+	if rule.Limit == 0 {
+		return unlimited{}
+	}
+	return backend.Limiter(name, rule)
+}
+
+// Config defines the rate limits of the routes of an HTTP API.
+// Routes are unversioned paths matched like http.ServeMux
+// patterns: a path ending in a slash matches all paths below it,
+// and the longest match wins. Requests to other routes are limited
+// by the default rule.
+type Config struct {
+	Default Rule            `json:"default"`
+	Routes  map[string]Rule `json:"routes"`
+}
+
+// LoadConfig reads a JSON rate limit config file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	for route, r := range c.Routes {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+	}
+	if err := c.Default.Validate(); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	return &c, nil
+}
+
+// route returns the route a path matches, empty for the default
+// rule.
+func (c *Config) route(path string) string {
+	route := ""
+	for pattern := range c.Routes {
+		matches := pattern == path || strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern)
+		if matches && len(pattern) > len(route) {
+			route = pattern
+		}
+	}
+	return route
+}
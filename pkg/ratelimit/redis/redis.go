@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"movieapp.com/pkg/ratelimit"
+)
+
+// tokenBucket refills the bucket at KEYS[1] by ARGV[1] tokens per
+// millisecond up to ARGV[2] tokens since it was last taken from,
+// at ARGV[3] milliseconds, takes a token if there is one and
+// returns whether it did and the tokens left. Tokens are returned
+// as a string, as Lua numbers are converted to integers.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1]) or burst
+local at = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(now - at, 0) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindow counts a request in the current fixed window at
+// KEYS[1] if the count of the previous one at KEYS[2], weighted by
+// ARGV[1], and the current count leave room for it within the
+// limit of ARGV[2], and returns the counts before the request.
+var slidingWindow = redis.NewScript(`
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+if prev * tonumber(ARGV[1]) + count + 1 <= tonumber(ARGV[2]) then
+	redis.call('INCR', KEYS[1])
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return {prev, count}
+`)
+
+// Backend defines a Redis rate limiter backend shared by all
+// instances of a service. Windows and buckets are timed by the
+// clocks of the instances, which are assumed to be in sync.
+type Backend struct {
+	client *redis.Client
+}
+
+// New creates a Redis rate limiter backend at the given address.
+func New(addr string) *Backend {
+	return &Backend{redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+// Limiter returns a limiter of a rule.
+func (b *Backend) Limiter(name string, rule ratelimit.Rule) ratelimit.Limiter {
+	return &limiter{b.client, "ratelimit:" + name + ":", rule}
+}
+
+// Close closes the client.
+func (b *Backend) Close() error {
+	return b.client.Close()
+}
+
+type limiter struct {
+	client *redis.Client
+	prefix string
+	rule   ratelimit.Rule
+}
+
+func (l *limiter) Allow(ctx context.Context, key string) (ratelimit.Decision, error) {
+	now := time.Now()
+	window := time.Duration(l.rule.Window)
+	if l.rule.Algorithm == ratelimit.TokenBucket {
+		rate := float64(l.rule.Limit) / float64(window.Milliseconds())
+		res, err := tokenBucket.Run(ctx, l.client, []string{l.prefix + key}, rate, l.rule.BucketSize(), now.UnixMilli(), window.Milliseconds()).Slice()
+		if err != nil {
+			return ratelimit.Decision{}, err
+		}
+		tokens, err := strconv.ParseFloat(res[1].(string), 64)
+		if err != nil {
+			return ratelimit.Decision{}, fmt.Errorf("token bucket %s: %w", key, err)
+		}
+		return ratelimit.TokenBucketDecision(l.rule, res[0].(int64) == 1, tokens), nil
+	}
+	start := now.Truncate(window)
+	elapsed := now.Sub(start)
+	keys := []string{
+		fmt.Sprintf("%s%s:%d", l.prefix, key, start.UnixMilli()),
+		fmt.Sprintf("%s%s:%d", l.prefix, key, start.Add(-window).UnixMilli()),
+	}
+	res, err := slidingWindow.Run(ctx, l.client, keys, ratelimit.PrevWeight(l.rule, elapsed), l.rule.Limit, 2*window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return ratelimit.Decision{}, err
+	}
+	return ratelimit.SlidingWindowDecision(l.rule, int(res[0]), int(res[1]), elapsed), nil
+}
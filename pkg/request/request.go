@@ -0,0 +1,304 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"movieapp.com/pkg/problem"
+)
+
+var (
+	// ErrInvalid is wrapped by the errors of requests with invalid
+	// fields.
+	ErrInvalid = problem.Register(errors.New("invalid request"), problem.Invalid)
+	// ErrMalformed is wrapped by the errors of request bodies that
+	// cannot be decoded.
+	ErrMalformed = problem.Register(errors.New("malformed request body"), problem.BadRequest)
+)
+
+// FieldError defines why a field of a request is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error defines the invalid fields of a request.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return ErrInvalid
+}
+
+// ProblemExtensions adds the invalid fields to the problem details
+// of the error.
+func (e *Error) ProblemExtensions() map[string]any {
+	return map[string]any{"errors": e.Fields}
+}
+
+// Invalid returns the error of a request with an invalid field,
+// for the checks that struct tags cannot express.
+func Invalid(field string, msg string) error {
+	return &Error{Fields: []FieldError{{field, msg}}}
+}
+
+func (e *Error) add(field string, msg string) {
+	e.Fields = append(e.Fields, FieldError{field, msg})
+}
+
+func (e *Error) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// Decode decodes the query and form values of a request into the
+// struct v points to and validates it. Fields are decoded from
+// the values named by their form tag, such as `form:"limit"`, and
+// slices from repeated values or, with the comma option, from
+// comma separated ones. Strings, booleans, integers, floats,
+// durations such as 24h, and RFC 3339 times are supported, as are
+// types defined on them. Values are validated by the validate tag
+// as in Validate, and values that do not parse are invalid too.
+// The fields of embedded structs are decoded as if they were
+// fields of v.
+func Decode(req *http.Request, v any) error {
+	if err := req.ParseForm(); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	verr := &Error{}
+	decode(req.Form, reflect.ValueOf(v).Elem(), verr)
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return Validate(v)
+}
+
+func decode(form url.Values, rv reflect.Value, verr *Error) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("form"), ",")
+		if name == "" && t.Field(i).Anonymous && t.Field(i).Type.Kind() == reflect.Struct {
+			decode(form, rv.Field(i), verr)
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		values := form[name]
+		if len(values) == 0 || len(values) == 1 && values[0] == "" {
+			continue
+		}
+		f := rv.Field(i)
+		if f.Kind() == reflect.Slice {
+			if opts == "comma" {
+				values = strings.Split(strings.Join(values, ","), ",")
+			}
+			s := reflect.MakeSlice(f.Type(), len(values), len(values))
+			for j, value := range values {
+				if msg := parse(s.Index(j), value); msg != "" {
+					verr.add(name, msg)
+					break
+				}
+			}
+			f.Set(s)
+			continue
+		}
+		if msg := parse(f, values[0]); msg != "" {
+			verr.add(name, msg)
+		}
+	}
+}
+
+// parse parses a form value into a field, returning why it is
+// invalid.
+func parse(f reflect.Value, s string) string {
+	switch {
+	case f.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return "must be a duration such as 24h"
+		}
+		f.SetInt(int64(d))
+	case f.Type() == timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return "must be an RFC 3339 time"
+		}
+		f.Set(reflect.ValueOf(t))
+	case f.Kind() == reflect.String:
+		f.SetString(s)
+	case f.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return "must be true or false"
+		}
+		f.SetBool(b)
+	case f.CanInt():
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return "must be an integer"
+		}
+		f.SetInt(n)
+	case f.CanFloat():
+		x, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return "must be a number"
+		}
+		f.SetFloat(x)
+	default:
+		panic("request: unsupported field type " + f.Type().String())
+	}
+	return ""
+}
+
+// DecodeJSON decodes the JSON body of a request into v and, if v
+// points to a struct, validates it as in Validate.
+func DecodeJSON(req *http.Request, v any) error {
+	if err := json.NewDecoder(req.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if reflect.ValueOf(v).Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return Validate(v)
+}
+
+// Validate validates the fields of the struct v points to by their
+// comma separated validate tag rules:
+//
+//   - required: the field is not zero
+//   - min=n, max=n: the number or duration, such as 24h, is at
+//     least or at most n, or the string or slice is at least or at
+//     most n long
+//   - oneof=a|b: the string is one of the values
+//
+// Rules other than required are not checked on zero fields.
+// Fields are named by their form or JSON tag, and the fields of
+// embedded structs are validated as well.
+func Validate(v any) error {
+	verr := &Error{}
+	validate(reflect.ValueOf(v).Elem(), verr)
+	return verr.err()
+}
+
+func validate(rv reflect.Value, verr *Error) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Anonymous && t.Field(i).Type.Kind() == reflect.Struct {
+			validate(rv.Field(i), verr)
+			continue
+		}
+		rules := t.Field(i).Tag.Get("validate")
+		if rules == "" {
+			continue
+		}
+		name := fieldName(t.Field(i))
+		f := rv.Field(i)
+		for _, rule := range strings.Split(rules, ",") {
+			rule, arg, _ := strings.Cut(rule, "=")
+			if rule == "required" {
+				if f.IsZero() {
+					verr.add(name, "is required")
+					break
+				}
+				continue
+			}
+			if f.IsZero() {
+				break
+			}
+			if msg := check(f, rule, arg); msg != "" {
+				verr.add(name, msg)
+				break
+			}
+		}
+	}
+}
+
+// check checks a rule other than required on a field, returning
+// why the field breaks it.
+func check(f reflect.Value, rule string, arg string) string {
+	switch rule {
+	case "min", "max":
+		var n, limit float64
+		switch {
+		case f.Type() == durationType:
+			d, err := time.ParseDuration(arg)
+			if err != nil {
+				panic("request: invalid duration rule " + rule + "=" + arg)
+			}
+			n, limit = float64(f.Int()), float64(d)
+		case f.Kind() == reflect.String || f.Kind() == reflect.Slice:
+			n, limit = float64(f.Len()), mustParseFloat(rule, arg)
+		case f.CanInt():
+			n, limit = float64(f.Int()), mustParseFloat(rule, arg)
+		case f.CanFloat():
+			n, limit = f.Float(), mustParseFloat(rule, arg)
+		default:
+			panic("request: rule " + rule + " on unsupported type " + f.Type().String())
+		}
+		if rule == "min" && n < limit {
+			return bound(f, "at least", arg)
+		} else if rule == "max" && n > limit {
+			return bound(f, "at most", arg)
+		}
+	case "oneof":
+		if !slices.Contains(strings.Split(arg, "|"), f.String()) {
+			return "must be one of " + strings.ReplaceAll(arg, "|", ", ")
+		}
+	default:
+		panic("request: unknown rule " + rule)
+	}
+	return ""
+}
+
+func bound(f reflect.Value, limit string, arg string) string {
+	switch f.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", limit, arg)
+	case reflect.Slice:
+		return fmt.Sprintf("must have %s %s values", limit, arg)
+	}
+	return fmt.Sprintf("must be %s %s", limit, arg)
+}
+
+func mustParseFloat(rule string, arg string) float64 {
+	x, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic("request: invalid rule " + rule + "=" + arg)
+	}
+	return x
+}
+
+// fieldName returns the name of a field in requests.
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
@@ -0,0 +1,216 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	"movieapp.com/pkg/problem"
+)
+
+// ErrBreakerOpen is returned instead of calling a downstream
+// whose breaker is open.
+var ErrBreakerOpen = problem.Register(errors.New("circuit breaker open"), problem.Unavailable)
+
+// BreakerState defines the state of a circuit breaker.
+type BreakerState int
+
+// Existing states.
+const (
+	// StateClosed lets all calls through.
+	StateClosed BreakerState = iota
+	// StateOpen rejects all calls until the open timeout passes.
+	StateOpen
+	// StateHalfOpen lets a limited number of probe calls through
+	// to find out whether the downstream has recovered.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig defines the thresholds of a circuit breaker.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures
+	// opening the breaker.
+	FailureThreshold int `yaml:"failureThreshold"`
+	// OpenTimeout is how long the breaker stays open before
+	// probing the downstream.
+	OpenTimeout time.Duration `yaml:"openTimeout"`
+	// HalfOpenProbes is the number of concurrent probes, all of
+	// which must succeed to close the breaker again.
+	HalfOpenProbes int `yaml:"halfOpenProbes"`
+	// IsFailure reports whether an error counts as a failure of
+	// the downstream. By default all errors but cancellations by
+	// the caller do.
+	IsFailure func(err error) bool `yaml:"-"`
+}
+
+// DefaultBreakerConfig returns the default circuit breaker
+// thresholds.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{FailureThreshold: 5, OpenTimeout: 10 * time.Second, HalfOpenProbes: 1}
+}
+
+// breakerStats publishes the breaker state of every downstream, keyed
+// by name, at /debug/vars.
+var breakerStats = expvar.NewMap("circuit_breakers")
+
+// Breaker defines a circuit breaker of a single downstream. It
+// fails calls fast while the downstream keeps failing instead of
+// letting every caller wait for its timeout.
+type Breaker struct {
+	config BreakerConfig
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+	rejected  int64
+}
+
+// NewBreaker creates a new circuit breaker of the named downstream
+// and publishes its state.
+func NewBreaker(name string, config BreakerConfig) *Breaker {
+	config = config.withDefaults()
+	if config.IsFailure == nil {
+		config.IsFailure = func(err error) bool { return !errors.Is(err, context.Canceled) }
+	}
+	b := &Breaker{config: config}
+	breakerStats.Set(name, expvar.Func(b.stats))
+	return b
+}
+
+// withDefaults returns the config with the default thresholds in
+// place of those not set.
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = DefaultBreakerConfig().FailureThreshold
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = DefaultBreakerConfig().OpenTimeout
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = DefaultBreakerConfig().HalfOpenProbes
+	}
+	return c
+}
+
+// SetThresholds changes the thresholds of the breaker, such as when
+// the config of a service is reloaded, keeping its state and
+// IsFailure.
+func (b *Breaker) SetThresholds(config BreakerConfig) {
+	config = config.withDefaults()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config.FailureThreshold = config.FailureThreshold
+	b.config.OpenTimeout = config.OpenTimeout
+	b.config.HalfOpenProbes = config.HalfOpenProbes
+}
+
+// Do calls fn unless the breaker is open and records its outcome.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	b.record(probe, err == nil || !b.config.IsFailure(err))
+	return err
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// advance moves an open breaker to half-open once the open
+// timeout has passed.
+func (b *Breaker) advance() {
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.config.OpenTimeout {
+		b.state = StateHalfOpen
+		b.probes = 0
+		b.successes = 0
+	}
+}
+
+func (b *Breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	switch b.state {
+	case StateOpen:
+		b.rejected++
+		return false, ErrBreakerOpen
+	case StateHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			b.rejected++
+			return false, ErrBreakerOpen
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (b *Breaker) record(probe bool, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		// The breaker may have opened again due to another probe.
+		if b.state != StateHalfOpen {
+			return
+		}
+		if !ok {
+			b.open()
+			return
+		}
+		if b.successes++; b.successes >= b.config.HalfOpenProbes {
+			b.state = StateClosed
+			b.failures = 0
+		}
+		return
+	}
+	if b.state != StateClosed {
+		return
+	}
+	if ok {
+		b.failures = 0
+		return
+	}
+	if b.failures++; b.failures >= b.config.FailureThreshold {
+		b.open()
+	}
+}
+
+func (b *Breaker) open() {
+	b.state = StateOpen
+	b.openedAt = time.Now()
+}
+
+func (b *Breaker) stats() any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return map[string]any{
+		"state":               b.state.String(),
+		"consecutiveFailures": b.failures,
+		"rejected":            b.rejected,
+	}
+}
@@ -0,0 +1,97 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync/atomic"
+	"time"
+
+	"movieapp.com/pkg/problem"
+)
+
+// ErrBulkheadFull is returned instead of calling a downstream
+// whose bulkhead has no free slot.
+var ErrBulkheadFull = problem.Register(errors.New("bulkhead full"), problem.Unavailable)
+
+// BulkheadConfig defines the limits of a bulkhead.
+type BulkheadConfig struct {
+	// Limit is the number of concurrent calls let through, not
+	// limited if 0.
+	Limit int `yaml:"limit"`
+	// MaxWait is how long a call waits for a slot before failing.
+	MaxWait time.Duration `yaml:"maxWait"`
+}
+
+// DefaultBulkheadConfig returns the default bulkhead limits.
+func DefaultBulkheadConfig() BulkheadConfig {
+	return BulkheadConfig{Limit: 100, MaxWait: 100 * time.Millisecond}
+}
+
+// bulkheadStats publishes the concurrency of every bulkhead, keyed
+// by name, at /debug/vars.
+var bulkheadStats = expvar.NewMap("bulkheads")
+
+// Bulkhead limits the concurrent calls to a downstream, so that a
+// slow downstream ties up a bounded share of the callers instead
+// of all of them.
+type Bulkhead struct {
+	slots    chan struct{}
+	maxWait  time.Duration
+	rejected atomic.Int64
+}
+
+// NewBulkhead creates a bulkhead of the named downstream and
+// publishes its concurrency. A config without a limit returns a
+// nil bulkhead, which does not limit calls.
+func NewBulkhead(name string, config BulkheadConfig) *Bulkhead {
+	if config.Limit <= 0 {
+		return nil
+	}
+	b := &Bulkhead{slots: make(chan struct{}, config.Limit), maxWait: config.MaxWait}
+	bulkheadStats.Set(name, expvar.Func(b.stats))
+	return b
+}
+
+// Do calls fn once a slot is free, or returns ErrBulkheadFull if
+// none frees up within the max wait. A nil bulkhead calls fn.
+func (b *Bulkhead) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b == nil {
+		return fn(ctx)
+	}
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+	}
+	defer func() { <-b.slots }()
+	return fn(ctx)
+}
+
+func (b *Bulkhead) wait(ctx context.Context) error {
+	if b.maxWait <= 0 {
+		b.rejected.Add(1)
+		return ErrBulkheadFull
+	}
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		b.rejected.Add(1)
+		return ErrBulkheadFull
+	}
+}
+
+func (b *Bulkhead) stats() any {
+	return map[string]any{
+		"active":   len(b.slots),
+		"limit":    cap(b.slots),
+		"rejected": b.rejected.Load(),
+	}
+}
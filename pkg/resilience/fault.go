@@ -0,0 +1,112 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"movieapp.com/pkg/problem"
+)
+
+// Errors returned by calls a fault is injected into.
+var (
+	// ErrInjectedFault is returned instead of making the call.
+	ErrInjectedFault = problem.Register(errors.New("injected fault"), problem.Unavailable)
+	// ErrInjectedDrop is returned after making the call, as if the
+	// connection dropped before the response came back.
+	ErrInjectedDrop = problem.Register(errors.New("injected connection drop"), problem.Unavailable)
+)
+
+// FaultConfig defines the faults injected into calls, to verify
+// that breakers, timeouts and fallbacks work. Faults are not
+// injected unless a rate is set.
+type FaultConfig struct {
+	// LatencyRate is the fraction of calls delayed.
+	LatencyRate float64 `yaml:"latencyRate"`
+	// Latency is the longest delay, delays being uniformly
+	// distributed up to it.
+	Latency time.Duration `yaml:"latency"`
+	// ErrorRate is the fraction of calls failing without being
+	// made.
+	ErrorRate float64 `yaml:"errorRate"`
+	// DropRate is the fraction of calls failing after being made,
+	// as when the connection drops, so that writes are applied
+	// although they fail.
+	DropRate float64 `yaml:"dropRate"`
+}
+
+// Enabled reports whether the config injects faults.
+func (c *FaultConfig) Enabled() bool {
+	return c.LatencyRate > 0 || c.ErrorRate > 0 || c.DropRate > 0
+}
+
+// Validate validates the config.
+func (c *FaultConfig) Validate() error {
+	for _, rate := range []float64{c.LatencyRate, c.ErrorRate, c.DropRate} {
+		if rate < 0 || rate > 1 {
+			return errors.New("rates must be between 0 and 1")
+		}
+	}
+	if c.Latency < 0 || (c.LatencyRate > 0 && c.Latency == 0) {
+		return errors.New("latency must be positive if delaying calls")
+	}
+	return nil
+}
+
+// faultStats publishes the faults injected into the calls to every
+// downstream, keyed by name, at /debug/vars.
+var faultStats = expvar.NewMap("injected_faults")
+
+// Fault injects latency, errors and dropped connections into calls
+// with the probabilities of its config.
+type Fault struct {
+	config                   FaultConfig
+	delayed, failed, dropped atomic.Int64
+}
+
+// NewFault creates a fault injector of the named downstream and
+// publishes the faults it injects. A config not injecting faults
+// returns a nil injector, which calls fn as is.
+func NewFault(name string, config FaultConfig) *Fault {
+	if !config.Enabled() {
+		return nil
+	}
+	f := &Fault{config: config}
+	faultStats.Set(name, expvar.Func(f.stats))
+	return f
+}
+
+// Do calls fn unless a fault is injected, delaying it or failing
+// before or after it as drawn. A nil injector calls fn.
+func (f *Fault) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if f == nil {
+		return fn(ctx)
+	}
+	if rand.Float64() < f.config.LatencyRate {
+		f.delayed.Add(1)
+		t := time.NewTimer(time.Duration(rand.Int63n(int64(f.config.Latency) + 1)))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if rand.Float64() < f.config.ErrorRate {
+		f.failed.Add(1)
+		return ErrInjectedFault
+	}
+	err := fn(ctx)
+	if err == nil && rand.Float64() < f.config.DropRate {
+		f.dropped.Add(1)
+		return ErrInjectedDrop
+	}
+	return err
+}
+
+func (f *Fault) stats() any {
+	return map[string]int64{"delayed": f.delayed.Load(), "failed": f.failed.Load(), "dropped": f.dropped.Load()}
+}
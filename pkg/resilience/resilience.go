@@ -0,0 +1,73 @@
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+// Policy defines how a call is made.
+type Policy interface {
+	// Do makes the call, calling fn once, several times or not at
+	// all, and returns its error or the error of the policy.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// PolicyFunc is a function used as a policy.
+type PolicyFunc func(ctx context.Context, fn func(ctx context.Context) error) error
+
+// Do calls f.
+func (f PolicyFunc) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return f(ctx, fn)
+}
+
+// Chain composes policies, the first one making the call through
+// the second one and so on. Usually a bulkhead comes first, so
+// that a call holds its slot while retried, then the circuit
+// breaker, then the retries, then the timeout of each attempt.
+// Nil policies are skipped.
+func Chain(policies ...Policy) Policy {
+	return PolicyFunc(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return chain(ctx, policies, fn)
+	})
+}
+
+func chain(ctx context.Context, policies []Policy, fn func(ctx context.Context) error) error {
+	for len(policies) > 0 && policies[0] == nil {
+		policies = policies[1:]
+	}
+	if len(policies) == 0 {
+		return fn(ctx)
+	}
+	return policies[0].Do(ctx, func(ctx context.Context) error {
+		return chain(ctx, policies[1:], fn)
+	})
+}
+
+// Timeout returns a policy canceling calls that take longer than
+// the timeout, none if not positive.
+func Timeout(timeout time.Duration) Policy {
+	return PolicyFunc(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		if timeout <= 0 {
+			return fn(ctx)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+// Call makes a call returning a value through a policy, the zero
+// value if the call fails.
+func Call[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var res T
+	err := p.Do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = fn(ctx)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return res, nil
+}
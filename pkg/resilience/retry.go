@@ -0,0 +1,59 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Retry defines how failed idempotent calls are retried. Only
+// calls safe to repeat, such as reads, must be retried.
+type Retry struct {
+	// MaxAttempts is the maximum number of calls, including the
+	// first one.
+	MaxAttempts int
+	// InitialBackoff is the upper bound of the first wait, which
+	// doubles with every attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Retryable reports whether an error is transient.
+	Retryable func(err error) bool
+}
+
+// DefaultRetry returns the default policy retrying the errors the
+// given function reports as transient.
+func DefaultRetry(retryable func(err error) bool) Retry {
+	return Retry{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Retryable:      retryable,
+	}
+}
+
+// Do calls fn until it succeeds, fails with an error that is not
+// retryable or the attempts are used up, waiting a random time up
+// to the backoff between the calls. No call is attempted if the
+// wait would pass the context deadline; the last error is
+// returned instead.
+func (p Retry) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || p.Retryable == nil || !p.Retryable(err) {
+			return err
+		}
+		// Full jitter keeps clients failing together from retrying
+		// together.
+		wait := time.Duration(rand.Int63n(int64(backoff) + 1))
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff = min(2*backoff, p.MaxBackoff)
+	}
+}
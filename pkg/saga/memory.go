@@ -0,0 +1,51 @@
+package saga
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps executions in process, so that they are lost, and
+// not resumed, if the process exits.
+type Memory struct {
+	mu         sync.Mutex
+	executions map[string]Execution
+}
+
+// NewMemory creates a new in-process store.
+func NewMemory() *Memory {
+	return &Memory{executions: map[string]Execution{}}
+}
+
+// Save creates or replaces an execution.
+func (m *Memory) Save(_ context.Context, e *Execution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.executions[e.ID] = *e
+	return nil
+}
+
+// Get returns an execution by id, or ErrNotFound.
+func (m *Memory) Get(_ context.Context, id string) (*Execution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.executions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+// Unfinished returns the executions not finished yet.
+func (m *Memory) Unfinished(_ context.Context) ([]*Execution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*Execution
+	for _, e := range m.executions {
+		if !e.State.Finished() {
+			e := e
+			res = append(res, &e)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"movieapp.com/pkg/saga"
+)
+
+// retention is the time finished executions are kept for.
+const retention = 7 * 24 * time.Hour
+
+// unfinishedKey is the key of the set of the ids of the unfinished
+// executions.
+const unfinishedKey = "saga:unfinished"
+
+// Store defines a Redis store of saga executions shared by all
+// instances of a service, so that any of them resumes the
+// executions of another one that exited.
+type Store struct {
+	client *redis.Client
+}
+
+// New creates a Redis store at the given address.
+func New(addr string) *Store {
+	return &Store{redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+// Save creates or replaces an execution. Finished executions
+// expire after a retention period.
+func (s *Store) Save(ctx context.Context, e *saga.Execution) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		if e.State.Finished() {
+			p.Set(ctx, redisKey(e.ID), b, retention)
+			p.SRem(ctx, unfinishedKey, e.ID)
+		} else {
+			p.Set(ctx, redisKey(e.ID), b, 0)
+			p.SAdd(ctx, unfinishedKey, e.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+// Get returns an execution by id, or saga.ErrNotFound.
+func (s *Store) Get(ctx context.Context, id string) (*saga.Execution, error) {
+	b, err := s.client.Get(ctx, redisKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, saga.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var res saga.Execution
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Unfinished returns the executions not finished yet.
+func (s *Store) Unfinished(ctx context.Context) ([]*saga.Execution, error) {
+	ids, err := s.client.SMembers(ctx, unfinishedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	var res []*saga.Execution
+	for _, id := range ids {
+		e, err := s.Get(ctx, id)
+		if errors.Is(err, saga.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// Close closes the client.
+func (s *Store) Close() error {
+	return s.client.Close()
+}
+
+func redisKey(id string) string {
+	return "saga:execution:" + id
+}
@@ -0,0 +1,317 @@
+// Package saga coordinates writes spanning several services as
+// sagas: sequences of local steps, each undone by a compensating
+// step if a later one fails. The progress of every execution is
+// saved after each step, so that executions interrupted by a crash
+// or a failing downstream are resumed instead of leaving the
+// services inconsistent.
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/tenant"
+)
+
+var logger = logging.New("saga")
+
+// ErrNotFound is returned by stores for unknown executions.
+var ErrNotFound = errors.New("saga execution not found")
+
+// ErrCompensated is returned when a saga failed and its completed
+// steps were undone, wrapped with the error of the failed step,
+// whose kind the error keeps.
+var ErrCompensated = errors.New("saga compensated")
+
+const (
+	// DefaultMaxAttempts is the default number of attempts of a
+	// retried step or a compensation before the execution fails
+	// for good.
+	DefaultMaxAttempts = 10
+	// staleAfter is the time after its last update an unfinished
+	// execution is resumed by Run, so that executions in progress
+	// on an instance are left alone.
+	staleAfter = time.Minute
+)
+
+// State defines the state of a saga execution.
+type State string
+
+// Existing states.
+const (
+	// StateRunning executions run their steps in order.
+	StateRunning = State("running")
+	// StateCompensating executions undo their completed steps in
+	// reverse order.
+	StateCompensating = State("compensating")
+	// StateCompleted executions ran all their steps.
+	StateCompleted = State("completed")
+	// StateCompensated executions undid all their completed
+	// steps.
+	StateCompensated = State("compensated")
+	// StateFailed executions gave up retrying a step or a
+	// compensation and need an operator.
+	StateFailed = State("failed")
+)
+
+// Finished reports whether executions in the state are done.
+func (s State) Finished() bool {
+	return s == StateCompleted || s == StateCompensated || s == StateFailed
+}
+
+// Step defines a step of a saga. Steps get the data the execution
+// was started with. As executions are resumed after a crash, a step
+// may run again after it succeeded and must be idempotent.
+type Step struct {
+	Name string
+	Do   func(ctx context.Context, data json.RawMessage) error
+	// Compensate undoes the step, nil if there is nothing to undo.
+	Compensate func(ctx context.Context, data json.RawMessage) error
+	// Retry retries failures of the step until it succeeds instead
+	// of compensating the saga, for the steps after which the saga
+	// can no longer be undone.
+	Retry bool
+}
+
+// Saga defines a named sequence of steps.
+type Saga struct {
+	Name  string
+	Steps []Step
+}
+
+// Execution defines the progress of an execution of a saga.
+type Execution struct {
+	ID   string          `json:"id"`
+	Saga string          `json:"saga"`
+	Data json.RawMessage `json:"data"`
+	// State is the state of the execution.
+	State State `json:"state"`
+	// Step is the index of the next step to run, or to compensate
+	// while compensating.
+	Step int `json:"step"`
+	// Attempts is the number of failed attempts of the step.
+	Attempts int `json:"attempts,omitempty"`
+	// Error is the last error of a step.
+	Error string `json:"error,omitempty"`
+	// Tenant is the tenant the execution was started for, in the
+	// context of its steps.
+	Tenant    string    `json:"tenant,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Store defines the saved executions.
+type Store interface {
+	// Save creates or replaces an execution.
+	Save(ctx context.Context, e *Execution) error
+	// Get returns an execution by id, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Execution, error)
+	// Unfinished returns the executions not finished yet.
+	Unfinished(ctx context.Context) ([]*Execution, error)
+}
+
+// Coordinator runs the executions of sagas and resumes the
+// interrupted ones.
+type Coordinator struct {
+	store       Store
+	sagas       map[string]*Saga
+	maxAttempts int
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// New creates a coordinator of the given sagas saving executions
+// to the store.
+func New(store Store, sagas ...*Saga) *Coordinator {
+	c := &Coordinator{store: store, sagas: map[string]*Saga{}, maxAttempts: DefaultMaxAttempts, running: map[string]bool{}}
+	for _, s := range sagas {
+		c.sagas[s.Name] = s
+	}
+	return c
+}
+
+// Start runs an execution of the named saga with the data encoded
+// as JSON. Ids identify the executions of the tenant of the context,
+// so that starting an execution again returns the existing one,
+// resuming it if unfinished.
+// Returns ErrCompensated wrapping the error of the failed step if
+// the saga was undone. An execution left running by a failing
+// retried step is returned without error and resumed by Run.
+func (c *Coordinator) Start(ctx context.Context, saga string, id string, data any) (*Execution, error) {
+	if _, ok := c.sagas[saga]; !ok {
+		return nil, fmt.Errorf("unknown saga %q", saga)
+	}
+	id = tenant.Key(ctx, id)
+	e, err := c.store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		e = &Execution{ID: id, Saga: saga, Data: b, State: StateRunning, Tenant: tenant.FromContext(ctx), StartedAt: now, UpdatedAt: now}
+		if err := c.store.Save(ctx, e); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if e.Saga != saga {
+		return nil, fmt.Errorf("execution %s is of saga %s", id, e.Saga)
+	}
+	return e, c.resume(ctx, e)
+}
+
+// Run resumes the unfinished executions not updated recently at
+// the interval until the context is canceled.
+func (c *Coordinator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		es, err := c.store.Unfinished(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Saga store error", "error", err)
+			continue
+		}
+		for _, e := range es {
+			if time.Since(e.UpdatedAt) < staleAfter {
+				continue
+			}
+			if err := c.resume(tenant.NewContext(ctx, e.tenant()), e); err != nil {
+				logger.WarnContext(ctx, "Saga resumption error", "saga", e.Saga, "id", e.ID, "error", err)
+			}
+		}
+	}
+}
+
+// tenant returns the tenant of the execution, the default tenant for
+// those started before executions recorded theirs.
+func (e *Execution) tenant() string {
+	if e.Tenant == "" {
+		return tenant.Default
+	}
+	return e.Tenant
+}
+
+// resume continues an execution from its saved progress, unless
+// it is finished or already running in process.
+func (c *Coordinator) resume(ctx context.Context, e *Execution) error {
+	if e.State.Finished() {
+		if e.State == StateCompensated {
+			return fmt.Errorf("%w: %s", ErrCompensated, e.Error)
+		}
+		return nil
+	}
+	c.mu.Lock()
+	if c.running[e.ID] {
+		c.mu.Unlock()
+		return nil
+	}
+	c.running[e.ID] = true
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.running, e.ID)
+		c.mu.Unlock()
+	}()
+	s, ok := c.sagas[e.Saga]
+	if !ok {
+		return fmt.Errorf("unknown saga %q", e.Saga)
+	}
+	// Progress is saved even if the caller gives up meanwhile.
+	ctx = context.WithoutCancel(ctx)
+	stepErr := c.forward(ctx, s, e)
+	if e.State == StateCompensating {
+		if err := c.backward(ctx, s, e); err != nil {
+			return err
+		}
+	}
+	if e.State == StateCompensated {
+		return fmt.Errorf("%w: %w", ErrCompensated, stepErr)
+	}
+	return nil
+}
+
+// forward runs the steps of a running execution, switching it to
+// compensating if a step not retried fails.
+func (c *Coordinator) forward(ctx context.Context, s *Saga, e *Execution) error {
+	for e.State == StateRunning && e.Step < len(s.Steps) {
+		step := s.Steps[e.Step]
+		err := step.Do(ctx, e.Data)
+		switch {
+		case err == nil:
+			e.Step++
+			e.Attempts, e.Error = 0, ""
+		case step.Retry:
+			e.Attempts++
+			e.Error = err.Error()
+			if e.Attempts >= c.maxAttempts {
+				e.State = StateFailed
+				logger.ErrorContext(ctx, "Saga step failed for good", "saga", s.Name, "id", e.ID, "step", step.Name, "error", err)
+			}
+			return errors.Join(err, c.save(ctx, e))
+		default:
+			logger.WarnContext(ctx, "Saga step failed, compensating", "saga", s.Name, "id", e.ID, "step", step.Name, "error", err)
+			e.State = StateCompensating
+			e.Step--
+			e.Attempts, e.Error = 0, err.Error()
+			return errors.Join(err, c.save(ctx, e))
+		}
+		if e.Step == len(s.Steps) {
+			e.State = StateCompleted
+		}
+		if err := c.save(ctx, e); err != nil {
+			return err
+		}
+	}
+	if e.State == StateCompensating {
+		return errors.New(e.Error)
+	}
+	return nil
+}
+
+// backward compensates the completed steps of an execution in
+// reverse order. Failing compensations are retried up to the max
+// attempts, at the next resumption.
+func (c *Coordinator) backward(ctx context.Context, s *Saga, e *Execution) error {
+	for e.State == StateCompensating {
+		if e.Step < 0 {
+			e.State = StateCompensated
+			return c.save(ctx, e)
+		}
+		step := s.Steps[e.Step]
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, e.Data); err != nil {
+				e.Attempts++
+				if e.Attempts >= c.maxAttempts {
+					e.State = StateFailed
+					e.Error = fmt.Sprintf("compensate %s: %v", step.Name, err)
+					logger.ErrorContext(ctx, "Saga compensation failed for good", "saga", s.Name, "id", e.ID, "step", step.Name, "error", err)
+				}
+				return errors.Join(err, c.save(ctx, e))
+			}
+		}
+		e.Step--
+		e.Attempts = 0
+		if err := c.save(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Coordinator) save(ctx context.Context, e *Execution) error {
+	e.UpdatedAt = time.Now().UTC()
+	return c.store.Save(ctx, e)
+}
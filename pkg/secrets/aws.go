@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// AWS provides the secrets of AWS Secrets Manager.
+type AWS struct {
+	client *secretsmanager.Client
+	prefix string
+}
+
+// NewAWS creates a provider of the AWS Secrets Manager secrets
+// named by a prefix and the secret name, configured from the
+// environment.
+func NewAWS(ctx context.Context, prefix string) (*AWS, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &AWS{secretsmanager.NewFromConfig(cfg), prefix}, nil
+}
+
+// Secret returns the current string value of a secret.
+func (a *AWS) Secret(ctx context.Context, name string) (string, error) {
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(a.prefix + name),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.SecretString), nil
+}
@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"movieapp.com/pkg/config"
+)
+
+// Sources of secrets.
+const (
+	SourceEnv   = "env"
+	SourceFile  = "file"
+	SourceVault = "vault"
+	SourceAWS   = "aws"
+)
+
+// Config defines the source of the secrets of a service.
+type Config struct {
+	// Source is env, file, vault or aws.
+	Source string `yaml:"source"`
+	// Dir is the directory of the secret files of the file source,
+	// such as mounted Kubernetes secrets.
+	Dir string `yaml:"dir"`
+	// VaultAddr is the address of the Vault server of the vault
+	// source, authenticated with the token of the VAULT_TOKEN
+	// environment variable, and VaultPath the path of the KV
+	// version 2 secret whose keys are the secrets, such as
+	// secret/data/rating.
+	VaultAddr string `yaml:"vaultAddr"`
+	VaultPath string `yaml:"vaultPath"`
+	// AWSPrefix prefixes the names of the secrets of the aws
+	// source in AWS Secrets Manager, such as movieapp/rating/.
+	AWSPrefix string `yaml:"awsPrefix"`
+	// Refresh is the interval secrets are checked for rotation at.
+	Refresh time.Duration `yaml:"refresh"`
+}
+
+// DefaultConfig returns the config of secrets read from
+// environment variables.
+func DefaultConfig() Config {
+	return Config{Source: SourceEnv, Refresh: 5 * time.Minute}
+}
+
+// Validate returns an error if the settings of the source are
+// missing.
+func (c *Config) Validate() error {
+	var errs []error
+	switch c.Source {
+	case SourceEnv, SourceAWS:
+	case SourceFile:
+		if c.Dir == "" {
+			errs = append(errs, errors.New("dir: empty"))
+		}
+	case SourceVault:
+		if c.VaultAddr == "" {
+			errs = append(errs, errors.New("vaultAddr: empty"))
+		}
+		if c.VaultPath == "" {
+			errs = append(errs, errors.New("vaultPath: empty"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("source: invalid source %q", c.Source))
+	}
+	if c.Refresh <= 0 {
+		errs = append(errs, errors.New("refresh: not positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// Provider returns the provider of the source, reading secrets
+// from environment variables with the given prefix as in Env.
+func (c *Config) Provider(ctx context.Context, envPrefix string) (Provider, error) {
+	switch c.Source {
+	case SourceFile:
+		return Dir(c.Dir), nil
+	case SourceVault:
+		return NewVault(c.VaultAddr, os.Getenv("VAULT_TOKEN"), c.VaultPath), nil
+	case SourceAWS:
+		return NewAWS(ctx, c.AWSPrefix)
+	}
+	return Env(envPrefix), nil
+}
+
+// Env provides the secrets of environment variables named by a
+// prefix and the secret name, such as RATING_SECRET_MYSQL_PASSWORD
+// for the mysql-password secret and the RATING_SECRET prefix.
+type Env string
+
+// Secret returns the value of the environment variable of a
+// secret.
+func (e Env) Secret(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(config.EnvName(string(e), name))
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Dir provides the secrets of the files of a directory named
+// after the secrets, with trailing newlines trimmed.
+type Dir string
+
+// Secret reads the file of a secret.
+func (d Dir) Secret(_ context.Context, name string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("secrets")
+
+// ErrNotFound is returned when a provider has no secret of a
+// name.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider provides secrets by name, such as mysql-password.
+type Provider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// reference matches the references to secrets in values, such as
+// ${secret:mysql-password}.
+var reference = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_./-]+)\}`)
+
+// Resolver resolves the references to secrets in values, such as
+// the data source name root:${secret:mysql-password}@/movieapp,
+// and watches values for the rotation of their secrets.
+type Resolver struct {
+	provider Provider
+
+	mu      sync.Mutex
+	watches []*watch
+}
+
+type watch struct {
+	templates []string
+	values    []string
+	fn        func(values []string) error
+}
+
+// NewResolver creates a resolver of the secrets of the provider.
+func NewResolver(provider Provider) *Resolver {
+	return &Resolver{provider: provider}
+}
+
+// Expand replaces the references to secrets in a value by the
+// secrets.
+func (r *Resolver) Expand(ctx context.Context, s string) (string, error) {
+	var errs []error
+	res := reference.ReplaceAllStringFunc(s, func(ref string) string {
+		name := reference.FindStringSubmatch(ref)[1]
+		v, err := r.provider.Secret(ctx, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("secret %s: %w", name, err))
+		}
+		return v
+	})
+	return res, errors.Join(errs...)
+}
+
+// Watch calls fn with the expansions of the templates, values
+// referencing secrets, whenever one of them changes as a secret
+// is rotated, such as to reconnect with new credentials. The
+// templates are expanded as they are now first, and fn is not
+// called for them.
+func (r *Resolver) Watch(ctx context.Context, templates []string, fn func(values []string) error) error {
+	w := &watch{templates: templates, fn: fn}
+	var err error
+	if w.values, err = r.expandAll(ctx, templates); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watches = append(r.watches, w)
+	return nil
+}
+
+func (r *Resolver) expandAll(ctx context.Context, templates []string) ([]string, error) {
+	values := make([]string, len(templates))
+	for i, t := range templates {
+		var err error
+		if values[i], err = r.Expand(ctx, t); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Run checks the watched values for rotated secrets at the given
+// interval until the context is canceled. Values whose callback
+// fails are checked again at the next interval.
+func (r *Resolver) Run(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		r.mu.Lock()
+		watches := slices.Clone(r.watches)
+		r.mu.Unlock()
+		for _, w := range watches {
+			values, err := r.expandAll(ctx, w.templates)
+			if err != nil {
+				logger.ErrorContext(ctx, "Secret refresh error", "error", err)
+				continue
+			}
+			if slices.Equal(values, w.values) {
+				continue
+			}
+			if err := w.fn(values); err != nil {
+				logger.ErrorContext(ctx, "Secret rotation error", "error", err)
+				continue
+			}
+			logger.InfoContext(ctx, "Secret rotated")
+			w.values = values
+		}
+	}
+}
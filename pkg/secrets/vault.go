@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Vault provides the secrets of the keys of a HashiCorp Vault KV
+// version 2 secret, read over the Vault HTTP API.
+type Vault struct {
+	addr  string
+	token string
+	path  string
+}
+
+// NewVault creates a provider of the secret at the given path,
+// such as secret/data/rating, of the Vault server at the given
+// address, authenticated with a token.
+func NewVault(addr string, token string, path string) *Vault {
+	return &Vault{
+		addr:  strings.TrimSuffix(addr, "/"),
+		token: token,
+		path:  strings.Trim(path, "/"),
+	}
+}
+
+// Secret reads the secret and returns the value of the key of the
+// secret name.
+func (v *Vault) Secret(ctx context.Context, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+v.path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrNotFound
+	default:
+		return "", fmt.Errorf("vault %s: status %d", v.path, resp.StatusCode)
+	}
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault %s: %w", v.path, err)
+	}
+	value, ok := body.Data.Data[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("vault %s: key %s not a string", v.path, name)
+}
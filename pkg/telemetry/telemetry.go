@@ -0,0 +1,235 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"movieapp.com/internal/apiversion"
+	"movieapp.com/pkg/tenant"
+)
+
+// otherRoute labels the requests whose route is unknown, so that
+// arbitrary paths do not add label values.
+const otherRoute = "other"
+
+// The metrics follow the RED method: the rate, errors and duration
+// of the requests a service serves and of the calls it makes.
+var (
+	registry = prometheus.NewRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "HTTP requests served by route, method, status code and tenant.",
+	}, []string{"route", "method", "code", "tenant"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "Duration of the HTTP requests served by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "gRPC calls served by method, status code and tenant.",
+	}, []string{"method", "code", "tenant"})
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Duration of the gRPC calls served by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	clientCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_calls_total",
+		Help: "Calls made to other services by protocol, method and status code.",
+	}, []string{"protocol", "method", "code"})
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "client_call_duration_seconds",
+		Help:    "Duration of the calls made to other services by protocol and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"protocol", "method"})
+)
+
+// registered holds the collectors of other packages added by
+// Register, and registerer the registerer of the service once Init
+// is called.
+var (
+	mu         sync.Mutex
+	registered []prometheus.Collector
+	registerer prometheus.Registerer
+)
+
+// Init registers the metrics of a service, labeled by its name,
+// along with the Go runtime and process metrics and the collectors
+// added by Register. Until then the metrics are recorded but not
+// served.
+func Init(serviceName string) error {
+	if err := registry.Register(collectors.NewGoCollector()); err != nil {
+		return err
+	}
+	if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	registerer = prometheus.WrapRegistererWith(prometheus.Labels{"service": serviceName}, registry)
+	for _, c := range append([]prometheus.Collector{httpRequests, httpDuration, grpcRequests, grpcDuration, clientCalls, clientDuration}, registered...) {
+		if err := registerer.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Register adds the collectors of the metrics of another package,
+// such as the stats of its caches, to those of the service. They
+// are served once Init is called, right away if it already was.
+// It panics if a collector cannot be registered, such as one
+// collecting the same metrics as another.
+func Register(cs ...prometheus.Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered = append(registered, cs...)
+	if registerer != nil {
+		registerer.MustRegister(cs...)
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format,
+// to be mounted at /metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// HTTPHandler measures the requests to the next handler, labeled
+// by the pattern of the route they match as returned by route,
+// such as apiversion.Router.Pattern. Requests matching no route,
+// or all requests if route is nil, are labeled as other.
+func HTTPHandler(next http.Handler, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rw, req)
+		pattern := otherRoute
+		if route != nil {
+			if p := route(req); p != "" {
+				pattern = p
+			}
+		}
+		httpRequests.WithLabelValues(pattern, req.Method, strconv.Itoa(rw.code), tenant.FromContext(req.Context())).Inc()
+		httpDuration.WithLabelValues(pattern, req.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ServerOptions measures the unary and streaming calls to a gRPC
+// server, streams over their whole duration.
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryServerInterceptor),
+		grpc.ChainStreamInterceptor(streamServerInterceptor),
+	}
+}
+
+func unaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String(), tenant.FromContext(ctx)).Inc()
+	grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+func streamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String(), tenant.FromContext(ss.Context())).Inc()
+	grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// DialOption measures the unary calls of a gRPC client.
+func DialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(unaryClientInterceptor)
+}
+
+func unaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	clientCalls.WithLabelValues("grpc", method, status.Code(err).String()).Inc()
+	clientDuration.WithLabelValues("grpc", method).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// Transport measures the HTTP calls made through the base
+// transport, labeled by their method and unversioned path, and
+// with an error code if they got no response.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := base.RoundTrip(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		method := req.Method + " " + apiversion.Unversioned(req.URL.Path)
+		clientCalls.WithLabelValues("http", method, code).Inc()
+		clientDuration.WithLabelValues("http", method).Observe(time.Since(start).Seconds())
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered response to the client.
+func (w *statusWriter) Flush() {
+	w.wroteHeader = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the next handler take over the connection.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+	w.wroteHeader = true
+	w.code = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
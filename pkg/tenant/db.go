@@ -0,0 +1,129 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// Placeholder is replaced by the tenant in the data source names of
+// databases per tenant, such as root:password@/movieapp_{tenant}.
+const Placeholder = "{tenant}"
+
+// ErrUnknown is returned by the queries of a tenant without a
+// database.
+var ErrUnknown = errors.New("unknown tenant")
+
+// DB routes the queries of the repositories of a service to the
+// database of the tenant of their context, as *sql.DB does to its
+// only database.
+type DB struct {
+	dbs map[string]*sql.DB
+	// unknown fails the queries of tenants not served.
+	unknown *sql.DB
+}
+
+// OpenDB opens a database of the driver per tenant of the set, named
+// by the data source name with Placeholder replaced by the tenant. A
+// name without Placeholder is shared by the tenants, which is only
+// allowed for a single tenant.
+func OpenDB(driverName string, dsn string, s *Set) (*DB, error) {
+	return NewDB(dsn, s, func(_ string, dsn string) (*sql.DB, error) {
+		return sql.Open(driverName, dsn)
+	})
+}
+
+// NewDB creates a database per tenant of the set like OpenDB, opened
+// by open from the tenant and its data source name, such as with a
+// connector whose data source name can change.
+func NewDB(dsn string, s *Set, open func(id string, dsn string) (*sql.DB, error)) (*DB, error) {
+	ids := s.IDs()
+	if !strings.Contains(dsn, Placeholder) && len(ids) > 1 {
+		return nil, errors.New("data source name without " + Placeholder + " for several tenants")
+	}
+	db := &DB{dbs: make(map[string]*sql.DB, len(ids)), unknown: sql.OpenDB(unknownConnector{})}
+	for _, id := range ids {
+		conn, err := open(id, DSN(dsn, id))
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		db.dbs[id] = conn
+	}
+	return db, nil
+}
+
+// DSN returns the data source name of the database of a tenant.
+func DSN(dsn string, id string) string {
+	return strings.ReplaceAll(dsn, Placeholder, id)
+}
+
+func (db *DB) of(ctx context.Context) *sql.DB {
+	if conn, ok := db.dbs[FromContext(ctx)]; ok {
+		return conn
+	}
+	return db.unknown
+}
+
+// ExecContext executes a query in the database of the tenant.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.of(ctx).ExecContext(ctx, query, args...)
+}
+
+// QueryContext executes a query returning rows in the database of
+// the tenant.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.of(ctx).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query returning a row in the database
+// of the tenant.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.of(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction in the database of the tenant.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.of(ctx).BeginTx(ctx, opts)
+}
+
+// PingContext checks that the databases of all tenants are
+// reachable.
+func (db *DB) PingContext(ctx context.Context) error {
+	for _, conn := range db.dbs {
+		if err := conn.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection pools of the databases.
+func (db *DB) Close() error {
+	var errs []error
+	for _, conn := range db.dbs {
+		errs = append(errs, conn.Close())
+	}
+	errs = append(errs, db.unknown.Close())
+	return errors.Join(errs...)
+}
+
+// unknownConnector fails to connect, for the queries of tenants not
+// served.
+type unknownConnector struct{}
+
+func (unknownConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, ErrUnknown
+}
+
+func (unknownConnector) Driver() driver.Driver {
+	return unknownDriver{}
+}
+
+type unknownDriver struct{}
+
+func (unknownDriver) Open(string) (driver.Conn, error) {
+	return nil, ErrUnknown
+}
@@ -0,0 +1,217 @@
+// Package tenant scopes requests to the tenant they are made for,
+// so that one deployment serves several white-label catalogs with
+// their data kept apart. The tenant of a request is carried in its
+// Header, or its gRPC metadata, checked against the tenants a
+// service serves and passed on in its context to the storage, the
+// calls it makes and the events it publishes.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"movieapp.com/pkg/problem"
+)
+
+// Default is the tenant of the requests carrying none, and the only
+// tenant of single-tenant deployments.
+const Default = "default"
+
+// Header carries the tenant of a request to the HTTP services.
+const Header = "X-Tenant-ID"
+
+// metadataKey carries the tenant of a call to the gRPC services.
+const metadataKey = "x-tenant-id"
+
+// healthMethods prefixes the methods of the gRPC health service.
+const healthMethods = "/grpc.health.v1.Health/"
+
+// maxLength bounds the length of tenant ids, so that they fit in
+// MySQL schema names along with a prefix.
+const maxLength = 32
+
+// Validate returns an error unless the id is made of up to 32
+// lowercase letters, digits and hyphens, starting with a letter, so
+// that it can be part of key prefixes and schema names.
+func Validate(id string) error {
+	if id == "" || len(id) > maxLength || id[0] < 'a' || id[0] > 'z' {
+		return errors.New("invalid tenant " + id)
+	}
+	for _, r := range id {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return errors.New("invalid tenant " + id)
+		}
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+// NewContext returns a context carrying the tenant.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant of the context, Default if none.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok {
+		return id
+	}
+	return Default
+}
+
+// Key returns a key of the tenant of the context in a store shared
+// by the tenants, such as Redis. Keys are prefixed by the tenant
+// even for the Default one, so that no key of a tenant is the key of
+// another, as the tenant ids have no colon.
+func Key(ctx context.Context, key string) string {
+	return FromContext(ctx) + ":" + key
+}
+
+// Set defines the tenants a service serves. A nil set serves the
+// Default tenant only.
+type Set struct {
+	ids []string
+}
+
+// NewSet creates the set of the tenants, or of the Default tenant if
+// none.
+func NewSet(ids []string) *Set {
+	if len(ids) == 0 {
+		return &Set{[]string{Default}}
+	}
+	return &Set{slices.Clone(ids)}
+}
+
+// IDs returns the tenants of the set.
+func (s *Set) IDs() []string {
+	if s == nil {
+		return []string{Default}
+	}
+	return s.ids
+}
+
+// Has reports whether the tenant is in the set.
+func (s *Set) Has(id string) bool {
+	return slices.Contains(s.IDs(), id)
+}
+
+// Each calls fn with a context of each tenant of the set, for work
+// not made on behalf of a request, such as background jobs, and
+// returns the errors joined.
+func (s *Set) Each(ctx context.Context, fn func(ctx context.Context) error) error {
+	var errs []error
+	for _, id := range s.IDs() {
+		if err := fn(NewContext(ctx, id)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handler passes the tenant in the Header of the requests to the
+// next handler in their context, failing those for a tenant not in
+// the set with 400.
+func Handler(next http.Handler, s *Set) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(Header)
+		if id == "" {
+			id = Default
+		}
+		if !s.Has(id) {
+			problem.Write(w, req, problem.BadRequest, "unknown tenant")
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), id)))
+	})
+}
+
+// SetHeader sets the tenant of the context on an outgoing request.
+func SetHeader(req *http.Request) {
+	if id := FromContext(req.Context()); id != Default {
+		req.Header.Set(Header, id)
+	}
+}
+
+// UnaryServerInterceptor passes the tenant in the metadata of
+// incoming gRPC calls in their context like Handler, failing those
+// for a tenant not in the set with InvalidArgument. The calls of the
+// gRPC health service, made by probes without a tenant, are not
+// scoped to one.
+func UnaryServerInterceptor(s *Set) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, healthMethods) {
+			return handler(ctx, req)
+		}
+		ctx, err := incomingContext(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor passes the tenant of incoming gRPC streams
+// like UnaryServerInterceptor.
+func StreamServerInterceptor(s *Set) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthMethods) {
+			return handler(srv, ss)
+		}
+		ctx, err := incomingContext(ss.Context(), s)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ss, ctx})
+	}
+}
+
+func incomingContext(ctx context.Context, s *Set) (context.Context, error) {
+	id := Default
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(metadataKey); len(v) > 0 && v[0] != "" {
+			id = v[0]
+		}
+	}
+	if !s.Has(id) {
+		return nil, status.Error(codes.InvalidArgument, "unknown tenant")
+	}
+	return NewContext(ctx, id), nil
+}
+
+// serverStream overrides the context of a gRPC server stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// DialOptions pass the tenant of the context of the calls and
+// streams of a gRPC client to the called service.
+func DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+		}),
+	}
+}
+
+func outgoingContext(ctx context.Context) context.Context {
+	if id := FromContext(ctx); id != Default {
+		return metadata.AppendToOutgoingContext(ctx, metadataKey, id)
+	}
+	return ctx
+}
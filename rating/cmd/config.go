@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/debug"
+	"movieapp.com/pkg/flags"
+	"movieapp.com/pkg/httpmw"
+	"movieapp.com/pkg/jobs"
+	"movieapp.com/pkg/lifecycle"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/mtls"
+	"movieapp.com/pkg/ratelimit"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/pkg/secrets"
+	"movieapp.com/pkg/tenant"
+	"movieapp.com/rating/internal/repository/mysql"
+)
+
+// Stores of the ratings.
+const (
+	// storeMySQL keeps the current ratings in the ratings table.
+	storeMySQL = "mysql"
+	// storeEventSourced keeps the ratings as a log of events,
+	// serving historical queries.
+	storeEventSourced = "eventsourced"
+)
+
+// serviceConfig defines the settings of the rating service, loaded
+// by config.Load.
+type serviceConfig struct {
+	Host              string                    `yaml:"host"`
+	Port              int                       `yaml:"port"`
+	RESTPort          int                       `yaml:"restPort"`
+	MetricsPort       int                       `yaml:"metricsPort"`
+	DrainTimeout      time.Duration             `yaml:"drainTimeout"`
+	DrainDelay        time.Duration             `yaml:"drainDelay"`
+	ConfigReload      time.Duration             `yaml:"configReload"`
+	RegistryAddr      string                    `yaml:"registryAddr"`
+	RegistryCheck     time.Duration             `yaml:"registryCheck"`
+	MySQLDSN          string                    `yaml:"mysqlDSN"`
+	AuditDSN          string                    `yaml:"auditDSN"`
+	MySQLTimeout      time.Duration             `yaml:"mysqlTimeout"`
+	MySQLBulkhead     resilience.BulkheadConfig `yaml:"mysqlBulkhead"`
+	MySQLFaults       resilience.FaultConfig    `yaml:"mysqlFaults"`
+	Store             string                    `yaml:"store"`
+	SnapshotEvery     int                       `yaml:"snapshotEvery"`
+	SnapshotSchedule  string                    `yaml:"snapshotSchedule"`
+	PurgeSchedule     string                    `yaml:"purgeSchedule"`
+	KafkaBrokers      config.List               `yaml:"kafkaBrokers"`
+	EventsTopic       string                    `yaml:"eventsTopic"`
+	KafkaSASL         kafkautil.SASLConfig      `yaml:"kafkaSASL"`
+	RedisAddr         string                    `yaml:"redisAddr"`
+	WriteLimit        int                       `yaml:"writeLimit"`
+	WriteWindow       time.Duration             `yaml:"writeWindow"`
+	IdempotencyTTL    time.Duration             `yaml:"idempotencyTTL"`
+	AggregateCacheTTL time.Duration             `yaml:"aggregateCacheTTL"`
+	OTLPEndpoint      string                    `yaml:"otlpEndpoint"`
+	SentryDSN         string                    `yaml:"sentryDSN"`
+	HTTPTimeouts      httpmw.TimeoutConfig      `yaml:"httpTimeouts"`
+	LogLevel          string                    `yaml:"logLevel"`
+	Tenants           config.List               `yaml:"tenants"`
+	TLS               mtls.Config               `yaml:"tls"`
+	Auth              auth.Config               `yaml:"auth"`
+	Flags             flags.Config              `yaml:"flags"`
+	Secrets           secrets.Config            `yaml:"secrets"`
+	Debug             debug.Config              `yaml:"debug"`
+}
+
+func defaultConfig() *serviceConfig {
+	return &serviceConfig{
+		Host:              "localhost",
+		Port:              8082,
+		RESTPort:          8072,
+		MetricsPort:       8095,
+		DrainTimeout:      lifecycle.DefaultDrainTimeout,
+		DrainDelay:        lifecycle.DefaultDrainDelay,
+		ConfigReload:      config.DefaultReload,
+		RegistryAddr:      "localhost:8500",
+		MySQLDSN:          "root:password@/movieexample",
+		MySQLTimeout:      5 * time.Second,
+		MySQLBulkhead:     resilience.DefaultBulkheadConfig(),
+		Store:             storeMySQL,
+		SnapshotEvery:     mysql.DefaultSnapshotEvery,
+		KafkaBrokers:      config.List{"localhost:9092"},
+		EventsTopic:       "ratings",
+		KafkaSASL:         kafkautil.SASLConfig{Mechanism: kafkautil.MechanismSCRAMSHA512},
+		WriteWindow:       time.Minute,
+		IdempotencyTTL:    24 * time.Hour,
+		AggregateCacheTTL: 10 * time.Second,
+		LogLevel:          "info",
+		Flags:             flags.Config{Refresh: flags.DefaultRefresh},
+		Secrets:           secrets.DefaultConfig(),
+		HTTPTimeouts:      httpmw.DefaultTimeoutConfig(),
+	}
+}
+
+// Validate validates the config.
+func (c *serviceConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("host: empty"))
+	}
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drainTimeout: not positive"))
+	}
+	if c.DrainDelay < 0 || c.RegistryCheck < 0 || c.ConfigReload < 0 {
+		errs = append(errs, errors.New("drainDelay, registryCheck, configReload: negative"))
+	}
+	errs = append(errs,
+		config.ValidatePort("port", c.Port, false),
+		config.ValidatePort("restPort", c.RESTPort, true),
+		config.ValidatePort("metricsPort", c.MetricsPort, false),
+		config.ValidateAddr("registryAddr", c.RegistryAddr, false),
+		config.ValidateAddrs("kafkaBrokers", c.KafkaBrokers),
+		config.ValidateAddr("redisAddr", c.RedisAddr, true),
+		config.ValidateAddr("otlpEndpoint", c.OTLPEndpoint, true),
+	)
+	if c.MySQLDSN == "" {
+		errs = append(errs, errors.New("mysqlDSN: empty"))
+	}
+	if len(c.Tenants) > 1 && !strings.Contains(c.MySQLDSN, tenant.Placeholder) {
+		errs = append(errs, errors.New("mysqlDSN: no "+tenant.Placeholder+" for several tenants"))
+	}
+	if c.MySQLTimeout <= 0 {
+		errs = append(errs, errors.New("mysqlTimeout: not positive"))
+	}
+	if c.MySQLBulkhead.Limit < 0 || c.MySQLBulkhead.MaxWait < 0 {
+		errs = append(errs, errors.New("mysqlBulkhead: negative"))
+	}
+	if err := c.MySQLFaults.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("mysqlFaults: %w", err))
+	}
+	if c.Store != storeMySQL && c.Store != storeEventSourced {
+		errs = append(errs, fmt.Errorf("store: invalid store %q", c.Store))
+	}
+	if c.SnapshotEvery <= 0 {
+		errs = append(errs, errors.New("snapshotEvery: not positive"))
+	}
+	if _, err := jobs.ParseSchedule(c.SnapshotSchedule); err != nil {
+		errs = append(errs, fmt.Errorf("snapshotSchedule: %w", err))
+	}
+	if _, err := jobs.ParseSchedule(c.PurgeSchedule); err != nil {
+		errs = append(errs, fmt.Errorf("purgeSchedule: %w", err))
+	}
+	if c.EventsTopic == "" {
+		errs = append(errs, errors.New("eventsTopic: empty"))
+	}
+	if c.IdempotencyTTL <= 0 || c.AggregateCacheTTL <= 0 {
+		errs = append(errs, errors.New("idempotencyTTL, aggregateCacheTTL: not positive"))
+	}
+	if err := c.writeRule().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("writeLimit, writeWindow: %w", err))
+	}
+	if _, err := logging.ParseLevels(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if err := c.KafkaSASL.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("kafkaSASL: %w", err))
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	}
+	if err := c.Auth.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth: %w", err))
+	}
+	if err := c.Flags.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("flags: %w", err))
+	}
+	if err := c.Debug.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("debug: %w", err))
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+	if err := c.HTTPTimeouts.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("httpTimeouts: %w", err))
+	}
+	for _, id := range c.Tenants {
+		if err := tenant.Validate(id); err != nil {
+			errs = append(errs, fmt.Errorf("tenants: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeRule returns the rate limit of the writes of a client.
+func (c *serviceConfig) writeRule() ratelimit.Rule {
+	return ratelimit.Rule{Limit: c.WriteLimit, Window: ratelimit.Duration(c.WriteWindow)}
+}
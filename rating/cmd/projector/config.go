@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/debug"
+	"movieapp.com/pkg/lifecycle"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/secrets"
+)
+
+// serviceConfig defines the settings of the rating projector,
+// loaded by config.Load.
+type serviceConfig struct {
+	Host         string               `yaml:"host"`
+	MetricsPort  int                  `yaml:"metricsPort"`
+	DrainTimeout time.Duration        `yaml:"drainTimeout"`
+	ConfigReload time.Duration        `yaml:"configReload"`
+	KafkaBrokers config.List          `yaml:"kafkaBrokers"`
+	EventsTopic  string               `yaml:"eventsTopic"`
+	GroupID      string               `yaml:"groupID"`
+	KafkaSASL    kafkautil.SASLConfig `yaml:"kafkaSASL"`
+	RedisAddr    string               `yaml:"redisAddr"`
+	TopMinCount  int                  `yaml:"topMinCount"`
+	OTLPEndpoint string               `yaml:"otlpEndpoint"`
+	LogLevel     string               `yaml:"logLevel"`
+	Secrets      secrets.Config       `yaml:"secrets"`
+	Debug        debug.Config         `yaml:"debug"`
+}
+
+func defaultConfig() *serviceConfig {
+	return &serviceConfig{
+		Host:         "localhost",
+		MetricsPort:  8102,
+		DrainTimeout: lifecycle.DefaultDrainTimeout,
+		ConfigReload: config.DefaultReload,
+		KafkaBrokers: config.List{"localhost:9092"},
+		EventsTopic:  "ratings",
+		GroupID:      "rating-projector",
+		KafkaSASL:    kafkautil.SASLConfig{Mechanism: kafkautil.MechanismSCRAMSHA512},
+		RedisAddr:    "localhost:6379",
+		TopMinCount:  10,
+		LogLevel:     "info",
+		Secrets:      secrets.DefaultConfig(),
+	}
+}
+
+// Validate validates the config.
+func (c *serviceConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("host: empty"))
+	}
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drainTimeout: not positive"))
+	}
+	if c.ConfigReload < 0 {
+		errs = append(errs, errors.New("configReload: negative"))
+	}
+	errs = append(errs,
+		config.ValidatePort("metricsPort", c.MetricsPort, false),
+		config.ValidateAddrs("kafkaBrokers", c.KafkaBrokers),
+		config.ValidateAddr("redisAddr", c.RedisAddr, false),
+		config.ValidateAddr("otlpEndpoint", c.OTLPEndpoint, true),
+	)
+	if c.EventsTopic == "" || c.GroupID == "" {
+		errs = append(errs, errors.New("eventsTopic, groupID: empty"))
+	}
+	if c.TopMinCount <= 0 {
+		errs = append(errs, errors.New("topMinCount: not positive"))
+	}
+	if _, err := logging.ParseLevels(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if err := c.KafkaSASL.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("kafkaSASL: %w", err))
+	}
+	if err := c.Debug.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("debug: %w", err))
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+	return errors.Join(errs...)
+}
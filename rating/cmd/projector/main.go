@@ -0,0 +1,150 @@
+// Command projector maintains the read models of the ratings in
+// Redis from the rating change events. Run with -rebuild, it
+// instead deletes the read models and rebuilds them from all events
+// retained by the topic, then exits; the topic must retain all
+// events for the rebuilt read models to be complete. Projectors are
+// stopped during a rebuild, and then resume from the offsets of
+// their group, projecting again events already rebuilt.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/debug"
+	"movieapp.com/pkg/health"
+	"movieapp.com/pkg/httpmw"
+	"movieapp.com/pkg/lifecycle"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/secrets"
+	"movieapp.com/pkg/telemetry"
+	"movieapp.com/rating/internal/event/kafka"
+	"movieapp.com/rating/internal/projection"
+)
+
+const serviceName = "rating-projector"
+
+func main() {
+	cfg := defaultConfig()
+	var configPath string
+	var rebuild bool
+	flag.StringVar(&configPath, "config", "", "YAML config file, overridden by RATING_PROJECTOR_* environment variables named after the flags and by the flags")
+	flag.BoolVar(&rebuild, "rebuild", false, "delete the read models and rebuild them from all events retained by the topic, then exit")
+	flag.StringVar(&cfg.Host, "host", cfg.Host, "host the metrics port listens on")
+	flag.IntVar(&cfg.MetricsPort, "metrics-port", cfg.MetricsPort, "port of /metrics Prometheus metrics and /healthz and /readyz probes")
+	flag.DurationVar(&cfg.DrainTimeout, "drain-timeout", cfg.DrainTimeout, "time the event being projected may take to complete on shutdown")
+	flag.DurationVar(&cfg.ConfigReload, "config-reload", cfg.ConfigReload, "interval the config file is checked for changes at, applied as on SIGHUP to the settings that can change at runtime, such as the log level, 0 to reload on SIGHUP only")
+	flag.Var(&cfg.KafkaBrokers, "kafka-brokers", "comma separated Kafka brokers rating events are consumed from")
+	flag.StringVar(&cfg.EventsTopic, "events-topic", cfg.EventsTopic, "Kafka topic of rating events")
+	flag.StringVar(&cfg.GroupID, "group-id", cfg.GroupID, "Kafka consumer group of the projectors, sharing the partitions of the topic")
+	flag.StringVar(&cfg.KafkaSASL.Mechanism, "kafka-sasl-mechanism", cfg.KafkaSASL.Mechanism, "SASL mechanism of the Kafka brokers: plain or scram-sha-512")
+	flag.StringVar(&cfg.KafkaSASL.Username, "kafka-sasl-username", cfg.KafkaSASL.Username, "SASL username of the Kafka brokers, not authenticating if empty")
+	flag.StringVar(&cfg.KafkaSASL.Password, "kafka-sasl-password", cfg.KafkaSASL.Password, "SASL password of the Kafka brokers, such as ${secret:kafka-password}")
+	flag.StringVar(&cfg.RedisAddr, "redis-addr", cfg.RedisAddr, "address of the Redis server of the read models")
+	flag.IntVar(&cfg.TopMinCount, "top-min-count", cfg.TopMinCount, "number of ratings a record needs to enter the top rated leaderboard")
+	flag.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", cfg.OTLPEndpoint, "OTLP/gRPC endpoint of the collector spans are exported to, empty to not export spans")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "minimum level of logged records, followed by comma separated package=level overrides such as projection=debug")
+	flag.StringVar(&cfg.Secrets.Source, "secrets-source", cfg.Secrets.Source, "source of the secrets referenced by settings such as ${secret:kafka-password}: env for RATING_PROJECTOR_SECRET_* environment variables named after the secrets, file, vault or aws")
+	flag.StringVar(&cfg.Secrets.Dir, "secrets-dir", cfg.Secrets.Dir, "directory of the files named after the secrets of the file source")
+	flag.StringVar(&cfg.Secrets.VaultAddr, "secrets-vault-addr", cfg.Secrets.VaultAddr, "address of the Vault server of the vault source, authenticated with the VAULT_TOKEN environment variable")
+	flag.StringVar(&cfg.Secrets.VaultPath, "secrets-vault-path", cfg.Secrets.VaultPath, "path of the Vault KV version 2 secret whose keys are the secrets, such as secret/data/rating-projector")
+	flag.StringVar(&cfg.Secrets.AWSPrefix, "secrets-aws-prefix", cfg.Secrets.AWSPrefix, "prefix of the names of the AWS Secrets Manager secrets of the aws source, such as movieapp/rating-projector/")
+	flag.DurationVar(&cfg.Secrets.Refresh, "secrets-refresh", cfg.Secrets.Refresh, "interval secrets are checked for rotation at")
+	flag.IntVar(&cfg.Debug.Port, "debug-port", cfg.Debug.Port, "internal port of /debug/pprof/ profiles, /debug/vars metrics, /debug/gc garbage collector stats and the /debug/config config, not served if 0")
+	flag.StringVar(&cfg.Debug.Token, "debug-token", cfg.Debug.Token, "bearer token of the requests to the debug port, such as ${secret:debug-token}, not checked if empty")
+	flag.Parse()
+	if err := config.Load(configPath, serviceName, cfg, flag.CommandLine); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	levels, _ := logging.ParseLevels(cfg.LogLevel)
+	logging.Init(serviceName, levels)
+	ctx, stop := lifecycle.Context()
+	defer stop()
+	secretProvider, err := cfg.Secrets.Provider(ctx, config.EnvName(serviceName, "secret"))
+	if err != nil {
+		log.Fatalf("failed to set up secrets: %v", err)
+	}
+	resolver := secrets.NewResolver(secretProvider)
+	// The settings referencing secrets are kept to watch them for
+	// rotation.
+	templates := *cfg
+	expand := func(s string) (string, error) { return resolver.Expand(ctx, s) }
+	if err := config.Expand(cfg, expand); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	// The settings that can change at runtime are applied as the
+	// config file changes.
+	watcher := config.NewWatcher(configPath, serviceName, cfg, flag.CommandLine, defaultConfig, expand)
+	watcher.Subscribe("logging", func(cfg *serviceConfig) error {
+		levels, _ := logging.ParseLevels(cfg.LogLevel)
+		logging.SetLevels(levels)
+		return nil
+	}, "logLevel")
+	go resolver.Run(ctx, cfg.Secrets.Refresh)
+	kafkaCreds, err := kafkautil.NewCredentials(cfg.KafkaSASL)
+	if err != nil {
+		log.Fatalf("invalid Kafka credentials: %v", err)
+	}
+	if kafkaCreds != nil {
+		sasl := templates.KafkaSASL
+		if err := resolver.Watch(ctx, []string{sasl.Username, sasl.Password}, func(v []string) error {
+			return kafkaCreds.Set(v[0], v[1])
+		}); err != nil {
+			log.Fatalf("failed to watch secrets: %v", err)
+		}
+	}
+	projector := projection.New(cfg.RedisAddr, cfg.TopMinCount)
+	defer projector.Close()
+	if rebuild {
+		slog.Info("Rebuilding the rating read models", "topic", cfg.EventsTopic)
+		if err := projector.Reset(ctx); err != nil {
+			log.Fatalf("failed to delete the read models: %v", err)
+		}
+		began := time.Now()
+		n, err := kafka.Replay(ctx, cfg.KafkaBrokers, cfg.EventsTopic, kafkaCreds, projector.Handle)
+		if err != nil {
+			log.Fatalf("rebuild failed after %d events: %v", n, err)
+		}
+		slog.Info("Rebuilt the rating read models", "events", n, "duration", time.Since(began))
+		return
+	}
+
+	slog.Info("Starting the rating projector", "topic", cfg.EventsTopic, "group", cfg.GroupID)
+	runner := lifecycle.New(cfg.DrainTimeout)
+	shutdown, err := tracing.Init(ctx, serviceName, cfg.OTLPEndpoint)
+	if err != nil {
+		log.Fatalf("failed to set up tracing: %v", err)
+	}
+	runner.AfterDrain("tracing", shutdown)
+	if err := telemetry.Init(serviceName); err != nil {
+		log.Fatalf("failed to set up metrics: %v", err)
+	}
+	readiness := health.New()
+	readiness.Register("redis", health.Ping(projector))
+	readiness.RegisterOptional("kafka", health.Dial(cfg.KafkaBrokers))
+	runner.BeforeDrain("readiness", readiness.Shutdown)
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", telemetry.Handler())
+	metricsMux.Handle("/healthz", readiness.LivenessHandler())
+	metricsMux.Handle("/readyz", readiness.ReadinessHandler())
+	runner.HTTP("metrics", httpmw.NewServer(fmt.Sprintf("%s:%d", cfg.Host, cfg.MetricsPort), metricsMux, nil, httpmw.DefaultTimeoutConfig()))
+	if cfg.Debug.Enabled() {
+		runner.HTTP("debug", httpmw.NewServer(fmt.Sprintf("%s:%d", cfg.Host, cfg.Debug.Port), debug.Handler(cfg.Debug, &templates), nil, debug.Timeouts(httpmw.DefaultTimeoutConfig())))
+	}
+	consumer := kafka.NewConsumer(cfg.KafkaBrokers, cfg.EventsTopic, cfg.GroupID, kafkaCreds)
+	runner.AfterDrain("rating event consumer", lifecycle.Close(consumer))
+	go consumer.Run(ctx, projector.Handle)
+	if configPath != "" {
+		go watcher.Run(ctx, cfg.ConfigReload)
+	}
+	if err := runner.Run(ctx); err != nil {
+		log.Fatalf("shutdown error: %v", err)
+	}
+}
@@ -3,48 +3,335 @@ package rating
 import (
 	"context"
 	"errors"
+	"strconv"
+	"time"
 
+	"movieapp.com/pkg/audit"
+	"movieapp.com/pkg/cache"
+	"movieapp.com/pkg/flags"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
 	"movieapp.com/rating/internal/repository"
 	model "movieapp.com/rating/pkg/model"
 )
 
+var logger = logging.New("controller")
+
 // ErrNotFound is returned when no ratings are found for a record.
-var ErrNotFound = errors.New("ratings not found for a record")
+var ErrNotFound = problem.Register(errors.New("ratings not found for a record"), problem.NotFound)
+
+// ErrTooManyIDs is returned when ratings are requested for more
+// than MaxBatchSize records.
+var ErrTooManyIDs = problem.Register(errors.New("too many ids"), problem.BadRequest)
+
+// ErrInvalidRating is returned when a rating value is out of the
+// range of MinRatingValue to MaxRatingValue.
+var ErrInvalidRating = problem.Register(errors.New("rating value out of range"), problem.Invalid)
+
+// ErrNoHistory is returned for ratings as of a past time when the
+// store does not keep the rating history.
+var ErrNoHistory = problem.Register(errors.New("rating history not kept by the store"), problem.BadRequest)
+
+// Bounds of rating values.
+const (
+	MinRatingValue = 1
+	MaxRatingValue = 5
+)
+
+// AggregationFlag is the feature flag selecting the aggregation
+// of ratings, AggregationMean by default.
+const AggregationFlag = "rating-aggregation"
+
+// Aggregations of ratings.
+const (
+	// AggregationMean aggregates ratings by their mean.
+	AggregationMean = "mean"
+	// AggregationBayesian aggregates ratings by their mean with
+	// bayesianWeight ratings of the middle value added, so that
+	// records with few ratings do not rank above well rated ones.
+	AggregationBayesian = "bayesian"
+)
+
+const bayesianWeight = 5
+
+var aggregations = map[string]func([]model.Rating) float64{
+	AggregationMean:     mean,
+	AggregationBayesian: bayesian,
+}
+
+// MaxBatchSize defines the maximum number of records whose
+// ratings can be requested at once.
+const MaxBatchSize = 100
+
+// Bounds of trending requests.
+const (
+	DefaultTrendingWindow = 24 * time.Hour
+	MaxTrendingWindow     = 7 * 24 * time.Hour
+	DefaultTrendingLimit  = 20
+)
 
 type ratingRepository interface {
 	Get(context.Context, model.RecordID, model.RecordType) ([]model.Rating, error)
+	GetAsOf(context.Context, model.RecordID, model.RecordType, time.Time) ([]model.Rating, error)
+	GetMany(context.Context, []model.RecordID, model.RecordType) (map[model.RecordID][]model.Rating, error)
+	GetByUser(context.Context, model.UserID, []model.RecordID, model.RecordType) (map[model.RecordID]model.RatingValue, error)
+	ListByUser(context.Context, model.UserID, model.RecordType) ([]model.Rating, error)
 	Put(context.Context, model.RecordID, model.RecordType, *model.Rating) error
+	Move(context.Context, model.RecordType, model.RecordID, model.RecordID) error
+	IncrementCount(context.Context, model.RecordID, model.RecordType, time.Time) error
+	Trending(ctx context.Context, recordType model.RecordType, since time.Time, halfLife time.Duration, limit int) ([]model.TrendingRecord, error)
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, events []*model.RatingEvent) error
 }
 
 // Controller defines a rating service controller.
 type Controller struct {
-	repo ratingRepository
+	repo       ratingRepository
+	publisher  eventPublisher
+	aggregates *cache.Cache[float64]
+	features   *flags.Set
+	audit      *audit.Logger
 }
 
-// New creates a rating service controller.
-func New(repo ratingRepository) *Controller {
-	return &Controller{repo}
+// New creates a rating service controller publishing rating
+// change events to the publisher, caching aggregated ratings in the
+// given cache and aggregating ratings as selected by
+// AggregationFlag of the feature flags. Writes and moves of ratings
+// are recorded in the audit log, with the verified principal of
+// the caller as actor.
+func New(repo ratingRepository, publisher eventPublisher, aggregates *cache.Cache[float64], features *flags.Set, auditLog *audit.Logger) *Controller {
+	return &Controller{repo, publisher, aggregates, features, auditLog}
+}
 
+// NewAggregateCache creates a cache of aggregated ratings for the
+// ttl in the store.
+func NewAggregateCache(store cache.Store, ttl time.Duration) *cache.Cache[float64] {
+	return cache.New(store, cache.JSON[float64](), cache.Config[float64]{
+		Prefix: "rating:aggregate:",
+		TTL:    ttl,
+		Jitter: 0.1,
+	})
 }
 
 // GetAggregatedRating returns the aggregated rating for a
 // record or ErrNotFound if there are no ratings for it.
 func (c *Controller) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
-	ratings, err := c.repo.Get(ctx, recordID, recordType)
-
-	if err != nil && err == repository.ErrNotFound {
+	aggregation := c.aggregation()
+	res, err := c.aggregates.GetOrLoad(ctx, aggregateKey(recordType, recordID, aggregation), func(ctx context.Context) (float64, error) {
+		ratings, err := c.repo.Get(ctx, recordID, recordType)
+		if err != nil {
+			return 0, err
+		}
+		return aggregations[aggregation](ratings), nil
+	})
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return 0, ErrNotFound
 	} else if err != nil {
 		return 0, err
 	}
+	return res, nil
+}
+
+// GetAggregatedRatingAsOf returns the aggregated rating of a record
+// as it was at the given time, ErrNotFound if it had no ratings then,
+// or ErrNoHistory if the store does not keep the rating history.
+func (c *Controller) GetAggregatedRatingAsOf(ctx context.Context, recordID model.RecordID, recordType model.RecordType, at time.Time) (float64, error) {
+	ratings, err := c.repo.GetAsOf(ctx, recordID, recordType, at)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return 0, ErrNotFound
+	case errors.Is(err, repository.ErrNoHistory):
+		return 0, ErrNoHistory
+	case err != nil:
+		return 0, err
+	}
+	return aggregations[c.aggregation()](ratings), nil
+}
+
+// GetAggregatedRatings returns the aggregated ratings of several
+// records by record id. Records without ratings are left out.
+func (c *Controller) GetAggregatedRatings(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]float64, error) {
+	if len(recordIDs) > MaxBatchSize {
+		return nil, ErrTooManyIDs
+	}
+	aggregation := c.aggregation()
+	keys := make([]string, len(recordIDs))
+	ids := make(map[string]model.RecordID, len(recordIDs))
+	for i, id := range recordIDs {
+		keys[i] = aggregateKey(recordType, id, aggregation)
+		ids[keys[i]] = id
+	}
+	aggregated, err := c.aggregates.GetOrLoadMany(ctx, keys, func(ctx context.Context, keys []string) (map[string]float64, error) {
+		missing := make([]model.RecordID, len(keys))
+		for i, key := range keys {
+			missing[i] = ids[key]
+		}
+		ratings, err := c.repo.GetMany(ctx, missing, recordType)
+		if err != nil {
+			return nil, err
+		}
+		res := make(map[string]float64, len(ratings))
+		for id, rs := range ratings {
+			res[aggregateKey(recordType, id, aggregation)] = aggregations[aggregation](rs)
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[model.RecordID]float64, len(aggregated))
+	for key, v := range aggregated {
+		res[ids[key]] = v
+	}
+	return res, nil
+}
+
+// GetUserRatings returns the ratings a user gave to several
+// records by record id. Records the user has not rated are left
+// out; of repeated ratings of a record the last one counts.
+func (c *Controller) GetUserRatings(ctx context.Context, userID model.UserID, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]model.RatingValue, error) {
+	if len(recordIDs) > MaxBatchSize {
+		return nil, ErrTooManyIDs
+	}
+	return c.repo.GetByUser(ctx, userID, recordIDs, recordType)
+}
+
+// RecordRatings returns the individual ratings of a record, none
+// if it has no ratings.
+func (c *Controller) RecordRatings(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	ratings, err := c.repo.Get(ctx, recordID, recordType)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	res := make([]model.Rating, 0, len(ratings))
+	for _, r := range ratings {
+		r.RecordID, r.RecordType = recordID, recordType
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// UserRatingHistory returns all ratings a user gave to records of
+// the given type.
+func (c *Controller) UserRatingHistory(ctx context.Context, userID model.UserID, recordType model.RecordType) ([]model.Rating, error) {
+	return c.repo.ListByUser(ctx, userID, recordType)
+}
+
+// Trending returns up to limit records of a type trending by the
+// volume of their ratings within the window, recent ratings
+// weighing more than older ones. The window and limit are capped
+// and default when not positive.
+func (c *Controller) Trending(ctx context.Context, recordType model.RecordType, window time.Duration, limit int) ([]model.TrendingRecord, error) {
+	if window <= 0 {
+		window = DefaultTrendingWindow
+	} else if window > MaxTrendingWindow {
+		window = MaxTrendingWindow
+	}
+	if limit <= 0 {
+		limit = DefaultTrendingLimit
+	} else if limit > MaxBatchSize {
+		limit = MaxBatchSize
+	}
+	since := model.TrendingBucket(time.Now().Add(-window))
+	// Ratings a quarter of the window old count half as much as
+	// the latest ones.
+	return c.repo.Trending(ctx, recordType, since, window/4, limit)
+}
+
+// aggregation returns the aggregation selected by the feature
+// flags.
+func (c *Controller) aggregation() string {
+	name := c.features.String(AggregationFlag, "", AggregationMean)
+	if _, ok := aggregations[name]; !ok {
+		logger.Warn("Unknown rating aggregation", "aggregation", name)
+		return AggregationMean
+	}
+	return name
+}
+
+// invalidate drops the cached aggregated ratings of records after
+// their ratings changed.
+func (c *Controller) invalidate(ctx context.Context, recordType model.RecordType, recordIDs ...model.RecordID) {
+	for _, id := range recordIDs {
+		for aggregation := range aggregations {
+			if err := c.aggregates.Delete(ctx, aggregateKey(recordType, id, aggregation)); err != nil {
+				logger.ErrorContext(ctx, "Aggregate cache delete error", "error", err)
+			}
+		}
+	}
+}
+
+func aggregateKey(recordType model.RecordType, recordID model.RecordID, aggregation string) string {
+	return string(recordType) + ":" + string(recordID) + ":" + aggregation
+}
+
+func mean(ratings []model.Rating) float64 {
 	sum := float64(0)
 	for _, r := range ratings {
 		sum += float64(r.Value)
 	}
-	return sum / float64(len(ratings)), nil
+	return sum / float64(len(ratings))
+}
+
+func bayesian(ratings []model.Rating) float64 {
+	sum := float64(bayesianWeight) * (MinRatingValue + MaxRatingValue) / 2
+	for _, r := range ratings {
+		sum += float64(r.Value)
+	}
+	return sum / float64(bayesianWeight+len(ratings))
 }
 
 // PutRating writes a rating for a given record.
 func (c *Controller) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	return c.repo.Put(ctx, recordID, recordType, rating)
+	if rating.Value < MinRatingValue || rating.Value > MaxRatingValue {
+		return ErrInvalidRating
+	}
+	if err := c.repo.Put(ctx, recordID, recordType, rating); err != nil {
+		return err
+	}
+	c.invalidate(ctx, recordType, recordID)
+	c.audit.Record(ctx, audit.Entry{Action: "ratings.put", Resource: string(recordID), Details: map[string]string{"recordType": string(recordType), "user": string(rating.UserID), "value": strconv.Itoa(int(rating.Value))}})
+	now := time.Now().UTC()
+	// A missed count only makes the record trend a little less.
+	if err := c.repo.IncrementCount(ctx, recordID, recordType, model.TrendingBucket(now)); err != nil {
+		logger.ErrorContext(ctx, "Rating count error", "error", err)
+	}
+	c.publish(ctx, &model.RatingEvent{
+		Type:       model.RatingEventTypePut,
+		RecordID:   recordID,
+		RecordType: recordType,
+		UserID:     rating.UserID,
+		Value:      rating.Value,
+		Timestamp:  now,
+	})
+	return nil
+}
+
+// MoveRatings reassigns all ratings of a record to another one,
+// e.g. when duplicate records are merged.
+func (c *Controller) MoveRatings(ctx context.Context, recordType model.RecordType, from model.RecordID, to model.RecordID) error {
+	if err := c.repo.Move(ctx, recordType, from, to); err != nil {
+		return err
+	}
+	c.invalidate(ctx, recordType, from, to)
+	c.audit.Record(ctx, audit.Entry{Action: "ratings.move", Resource: string(from), Details: map[string]string{"recordType": string(recordType), "to": string(to)}})
+	now := time.Now().UTC()
+	c.publish(ctx,
+		&model.RatingEvent{Type: model.RatingEventTypeMoved, RecordID: from, RecordType: recordType, MovedTo: to, Timestamp: now},
+		&model.RatingEvent{Type: model.RatingEventTypeMoved, RecordID: to, RecordType: recordType, Timestamp: now},
+	)
+	return nil
+}
+
+// publish publishes rating change events once the ratings are
+// written. A failure does not fail the write, consumers caching
+// aggregated ratings expire them after a TTL.
+func (c *Controller) publish(ctx context.Context, events ...*model.RatingEvent) {
+	if err := c.publisher.Publish(ctx, events); err != nil {
+		logger.ErrorContext(ctx, "Rating event publish error", "error", err)
+	}
 }
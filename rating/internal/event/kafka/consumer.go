@@ -0,0 +1,158 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/errgroup"
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/rating/pkg/model"
+)
+
+var logger = logging.New("event/kafka")
+
+// maxBackoff bounds the wait between attempts to handle an event.
+const maxBackoff = 30 * time.Second
+
+// Handler defines a handler of rating events.
+type Handler func(context.Context, *model.RatingEvent) error
+
+// Consumer defines a Kafka consumer of rating change events.
+type Consumer struct {
+	reader *kafka.Reader
+}
+
+// NewConsumer creates a Kafka consumer of the group reading the
+// events of the topic, all retained events if the group is new.
+// Connections authenticate with the credentials unless nil.
+func NewConsumer(brokers []string, topic string, groupID string, creds *kafkautil.Credentials) *Consumer {
+	return &Consumer{kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		GroupID:     groupID,
+		StartOffset: kafka.FirstOffset,
+		Dialer:      creds.Dialer(),
+	})}
+}
+
+// Run passes events to the handler until the context is canceled.
+// Events the handler fails on are retried with backoff rather than
+// skipped, so that none is lost while the handler's store is down.
+// Events that cannot be decoded are logged and skipped.
+func (c *Consumer) Run(ctx context.Context, handle Handler) {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.ErrorContext(ctx, "Event fetch error", "error", err)
+			}
+			return
+		}
+		if err := handleMessage(ctx, msg, handle); err != nil {
+			return
+		}
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "Event commit error", "error", err)
+		}
+	}
+}
+
+// Close closes the consumer.
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+// Replay passes all events retained by the topic up to its end at
+// the time of the call to the handler, partition by partition in
+// parallel, outside of any consumer group. Events the handler fails
+// on are retried as by Run.
+func Replay(ctx context.Context, brokers []string, topic string, creds *kafkautil.Credentials, handle Handler) (int, error) {
+	dialer := creds.Dialer()
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+	partitions, err := dialer.LookupPartitions(ctx, "tcp", brokers[0], topic)
+	if err != nil {
+		return 0, err
+	}
+	g, ctx := errgroup.WithContext(ctx)
+	counts := make([]int, len(partitions))
+	for i, p := range partitions {
+		i, p := i, p
+		g.Go(func() error {
+			n, err := replayPartition(ctx, brokers, topic, p.ID, dialer, handle)
+			counts[i] = n
+			return err
+		})
+	}
+	err = g.Wait()
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total, err
+}
+
+func replayPartition(ctx context.Context, brokers []string, topic string, partition int, dialer *kafka.Dialer, handle Handler) (int, error) {
+	conn, err := dialer.DialLeader(ctx, "tcp", brokers[0], topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	first, last, err := conn.ReadOffsets()
+	conn.Close()
+	if err != nil || first >= last {
+		return 0, err
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, Topic: topic, Partition: partition, Dialer: dialer})
+	defer reader.Close()
+	if err := reader.SetOffset(first); err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			return n, err
+		}
+		if err := handleMessage(ctx, msg, handle); err != nil {
+			return n, err
+		}
+		n++
+		if msg.Offset >= last-1 {
+			return n, nil
+		}
+	}
+}
+
+// handleMessage decodes and handles an event, retrying until it is
+// handled or the context is canceled.
+func handleMessage(ctx context.Context, msg kafka.Message, handle Handler) error {
+	spanCtx, span := tracing.StartKafkaConsumer(ctx, msg)
+	defer span.End()
+	var e model.RatingEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		logger.ErrorContext(spanCtx, "Event decode error", "error", err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil
+	}
+	backoff := time.Second
+	for {
+		err := handle(spanCtx, &e)
+		if err == nil {
+			return nil
+		}
+		logger.ErrorContext(spanCtx, "Event handling error, retrying", "recordId", e.RecordID, "error", err, "backoff", backoff)
+		span.SetStatus(codes.Error, err.Error())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
@@ -2,15 +2,25 @@ package grpc
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
 	rating "movieapp.com/rating/internal/controller"
 	"movieapp.com/rating/pkg/model"
 )
 
+// writeScope is the scope of the tokens allowed to write ratings,
+// on behalf of their subject only.
+const writeScope = "ratings:write"
+
+// adminScope is the scope of the callers allowed to move the
+// ratings of a record, such as the metadata service merging movies.
+const adminScope = "ratings:admin"
+
 // Handler defines a gRPC rating API handler.
 type Handler struct {
 	gen.UnimplementedRatingServiceServer
@@ -27,22 +37,132 @@ func (h *Handler) GetAggregatedRating(ctx context.Context, req *gen.GetAggregate
 	if req == nil || req.RecordId == "" || req.RecordType == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	v, err := h.ctrl.GetAggregatedRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType))
-	if err != nil && errors.Is(err, rating.ErrNotFound) {
-		return nil, status.Errorf(codes.NotFound, err.Error())
-	} else if err != nil {
-		return nil, status.Errorf(codes.Internal, err.Error())
+	var v float64
+	var err error
+	if req.AsOf != nil {
+		v, err = h.ctrl.GetAggregatedRatingAsOf(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), req.AsOf.AsTime())
+	} else {
+		v, err = h.ctrl.GetAggregatedRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType))
+	}
+	if err != nil {
+		return nil, problem.Status(err)
 	}
 	return &gen.GetAggregatedRatingResponse{RatingValue: v}, nil
 }
 
+// GetAggregatedRatings returns the aggregated ratings of several
+// records.
+func (h *Handler) GetAggregatedRatings(ctx context.Context, req *gen.GetAggregatedRatingsRequest) (*gen.GetAggregatedRatingsResponse, error) {
+	if req == nil || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty record type")
+	}
+	ids := make([]model.RecordID, 0, len(req.RecordIds))
+	for _, id := range req.RecordIds {
+		ids = append(ids, model.RecordID(id))
+	}
+	res, err := h.ctrl.GetAggregatedRatings(ctx, ids, model.RecordType(req.RecordType))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	values := make(map[string]float64, len(res))
+	for id, v := range res {
+		values[string(id)] = v
+	}
+	return &gen.GetAggregatedRatingsResponse{RatingValues: values}, nil
+}
+
+// GetUserRatings returns the ratings a user gave to several
+// records.
+func (h *Handler) GetUserRatings(ctx context.Context, req *gen.GetUserRatingsRequest) (*gen.GetUserRatingsResponse, error) {
+	if req == nil || req.UserId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record type")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	ids := make([]model.RecordID, 0, len(req.RecordIds))
+	for _, id := range req.RecordIds {
+		ids = append(ids, model.RecordID(id))
+	}
+	res, err := h.ctrl.GetUserRatings(ctx, model.UserID(req.UserId), ids, model.RecordType(req.RecordType))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	values := make(map[string]int32, len(res))
+	for id, v := range res {
+		values[string(id)] = int32(v)
+	}
+	return &gen.GetUserRatingsResponse{RatingValues: values}, nil
+}
+
+// GetTrending returns the records trending by recent ratings.
+func (h *Handler) GetTrending(ctx context.Context, req *gen.GetTrendingRequest) (*gen.GetTrendingResponse, error) {
+	if req == nil || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty record type")
+	}
+	res, err := h.ctrl.Trending(ctx, model.RecordType(req.RecordType), time.Duration(req.WindowHours)*time.Hour, int(req.Limit))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	resp := &gen.GetTrendingResponse{}
+	for _, t := range res {
+		resp.Records = append(resp.Records, &gen.TrendingRecord{RecordId: string(t.RecordID), Count: int64(t.Count), Score: t.Score})
+	}
+	return resp, nil
+}
+
+// ListRecordRatings returns the individual ratings of a record.
+func (h *Handler) ListRecordRatings(ctx context.Context, req *gen.ListRecordRatingsRequest) (*gen.ListRecordRatingsResponse, error) {
+	if req == nil || req.RecordId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	res, err := h.ctrl.RecordRatings(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.ListRecordRatingsResponse{Ratings: model.RatingsToProto(res)}, nil
+}
+
+// ListUserRatings returns all ratings a user gave to records of a
+// type.
+func (h *Handler) ListUserRatings(ctx context.Context, req *gen.ListUserRatingsRequest) (*gen.ListUserRatingsResponse, error) {
+	if req == nil || req.UserId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record type")
+	}
+	res, err := h.ctrl.UserRatingHistory(ctx, model.UserID(req.UserId), model.RecordType(req.RecordType))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.ListUserRatingsResponse{Ratings: model.RatingsToProto(res)}, nil
+}
+
 // PutRating writes a rating for a given record.
 func (h *Handler) PutRating(ctx context.Context, req *gen.PutRatingRequest) (*gen.PutRatingResponse, error) {
 	if req == nil || req.RecordId == "" || req.UserId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record id")
 	}
+	if err := auth.RequireScope(ctx, writeScope); err != nil {
+		return nil, auth.Status(err)
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
 	if err := h.ctrl.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{UserID: model.UserID(req.UserId), Value: model.RatingValue(req.RatingValue)}); err != nil {
-		return nil, err
+		return nil, problem.Status(err)
 	}
 	return &gen.PutRatingResponse{}, nil
 }
+
+// MoveRatings reassigns all ratings of a record to another one.
+func (h *Handler) MoveRatings(ctx context.Context, req *gen.MoveRatingsRequest) (*gen.MoveRatingsResponse, error) {
+	if req == nil || req.RecordType == "" || req.FromRecordId == "" || req.ToRecordId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty record type or ids")
+	}
+	if err := auth.RequireScope(ctx, adminScope); err != nil {
+		return nil, auth.Status(err)
+	}
+	if err := h.ctrl.MoveRatings(ctx, model.RecordType(req.RecordType), model.RecordID(req.FromRecordId), model.RecordID(req.ToRecordId)); err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.MoveRatingsResponse{}, nil
+}
@@ -2,15 +2,18 @@ package http
 
 import (
 	"encoding/json"
-	"errors"
-	"log"
 	"net/http"
-	"strconv"
+	"time"
 
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
 	rating "movieapp.com/rating/internal/controller"
 	model "movieapp.com/rating/pkg/model"
 )
 
+var logger = logging.New("handler/http")
+
 // Handler defines a rating service controller.
 type Handler struct {
 	ctrl *rating.Controller
@@ -22,37 +25,141 @@ func New(ctrl *rating.Controller) *Handler {
 }
 
 func (h *Handler) Handle(w http.ResponseWriter, req *http.Request) {
-	recordID := model.RecordID(req.FormValue("id"))
-	if recordID == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	var params struct {
+		RecordID   model.RecordID    `form:"id" validate:"required"`
+		RecordType model.RecordType  `form:"type" validate:"required"`
+		UserID     model.UserID      `form:"userId"`
+		Value      model.RatingValue `form:"value"`
 	}
-	recordType := model.RecordType(req.FormValue("type"))
-	if recordType == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
 		return
 	}
 	switch req.Method {
 	case http.MethodGet:
-		v, err := h.ctrl.GetAggregatedRating(req.Context(), recordID, recordType)
-		if err != nil && errors.Is(err, rating.ErrNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+		v, err := h.ctrl.GetAggregatedRating(req.Context(), params.RecordID, params.RecordType)
+		if err != nil {
+			problem.Error(w, req, err)
 			return
 		}
 		if err := json.NewEncoder(w).Encode(v); err != nil {
-			log.Printf("Response encode error: %v\n", err)
+			logger.ErrorContext(req.Context(), "Response encode error", "error", err)
 		}
 	case http.MethodPut:
-		userID := model.UserID(req.FormValue("userId"))
-		v, err := strconv.ParseFloat(req.FormValue("value"), 64)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+		if err := h.ctrl.PutRating(req.Context(), params.RecordID, params.RecordType, &model.Rating{UserID: params.UserID, Value: params.Value}); err != nil {
+			problem.Error(w, req, err)
 			return
 		}
-		if err := h.ctrl.PutRating(req.Context(), recordID, recordType, &model.Rating{UserID: userID, Value: model.RatingValue(v)}); err != nil {
-			log.Printf("Repository put error %v\n", err)
-		}
 	default:
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, req, problem.MethodNotAllowed, "")
+	}
+}
+
+// GetAggregatedRatings handles GET /ratings requests with comma
+// separated record ids, returning aggregated ratings by record id.
+func (h *Handler) GetAggregatedRatings(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		RecordType model.RecordType `form:"type" validate:"required"`
+		IDs        []model.RecordID `form:"ids,comma" validate:"required"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.GetAggregatedRatings(req.Context(), params.IDs, params.RecordType)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetUserRatings handles GET /ratings/user requests with comma
+// separated record ids, returning the ratings of the user by
+// record id.
+func (h *Handler) GetUserRatings(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		UserID     model.UserID     `form:"userId" validate:"required"`
+		RecordType model.RecordType `form:"type" validate:"required"`
+		IDs        []model.RecordID `form:"ids,comma" validate:"required"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.GetUserRatings(req.Context(), params.UserID, params.IDs, params.RecordType)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// GetTrending handles GET /ratings/trending requests with the
+// record type, an optional window duration such as 24h and an
+// optional limit.
+func (h *Handler) GetTrending(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		RecordType model.RecordType `form:"type" validate:"required"`
+		Window     time.Duration    `form:"window" validate:"min=0s"`
+		Limit      int              `form:"limit" validate:"min=0"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.Trending(req.Context(), params.RecordType, params.Window, params.Limit)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// ListRecordRatings handles GET /ratings/record requests,
+// returning the individual ratings of a record.
+func (h *Handler) ListRecordRatings(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		RecordID   model.RecordID   `form:"id" validate:"required"`
+		RecordType model.RecordType `form:"type" validate:"required"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.RecordRatings(req.Context(), params.RecordID, params.RecordType)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
+	}
+}
+
+// ListUserRatings handles GET /ratings/history requests,
+// returning all ratings a user gave to records of a type.
+func (h *Handler) ListUserRatings(w http.ResponseWriter, req *http.Request) {
+	var params struct {
+		UserID     model.UserID     `form:"userId" validate:"required"`
+		RecordType model.RecordType `form:"type" validate:"required"`
+	}
+	if err := request.Decode(req, &params); err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	res, err := h.ctrl.UserRatingHistory(req.Context(), params.UserID, params.RecordType)
+	if err != nil {
+		problem.Error(w, req, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.ErrorContext(req.Context(), "Response encode error", "error", err)
 	}
 }
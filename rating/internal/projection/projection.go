@@ -0,0 +1,279 @@
+// Package projection maintains denormalized read models of the
+// ratings in Redis, projected from the rating change events: the
+// aggregate of each record, the ratings of each user, and
+// leaderboards of the records. Projecting an event again leaves the
+// read models as they are, so that events delivered more than once
+// and replays of the topic are harmless.
+package projection
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/rating/pkg/model"
+)
+
+var logger = logging.New("projection")
+
+// ErrNotFound is returned for records without ratings.
+var ErrNotFound = errors.New("not found")
+
+// keyPrefix prefixes all keys of the read models.
+const keyPrefix = "ratingview:"
+
+// Board defines a leaderboard of the records of a type.
+type Board string
+
+// Existing leaderboards.
+const (
+	// BoardTop ranks records by average rating, among those with
+	// at least the minimum number of ratings.
+	BoardTop = Board("top")
+	// BoardPopular ranks records by number of ratings.
+	BoardPopular = Board("popular")
+)
+
+// putScript projects a rating of a user. Unless keep is set, it
+// replaces an existing rating of the user.
+//
+// KEYS: record ratings, record aggregate, user timeline, user
+// ratings, top board, popular board.
+// ARGV: user id, record id, value, rated at in ms, min count, keep.
+var putScript = redis.NewScript(`
+local old = redis.call('HGET', KEYS[1], ARGV[1])
+if old and ARGV[6] == '1' then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+local sum = redis.call('HINCRBY', KEYS[2], 'sum', tonumber(ARGV[3]) - tonumber(old or 0))
+local count
+if old then
+	count = tonumber(redis.call('HGET', KEYS[2], 'count'))
+else
+	count = redis.call('HINCRBY', KEYS[2], 'count', 1)
+end
+redis.call('ZADD', KEYS[3], ARGV[4], ARGV[2])
+redis.call('HSET', KEYS[4], ARGV[2], ARGV[3])
+redis.call('ZADD', KEYS[6], count, ARGV[2])
+if count >= tonumber(ARGV[5]) then
+	redis.call('ZADD', KEYS[5], sum / count, ARGV[2])
+else
+	redis.call('ZREM', KEYS[5], ARGV[2])
+end
+return 1
+`)
+
+// Projector projects rating events into the read models.
+type Projector struct {
+	client   *redis.Client
+	minCount int
+}
+
+// New creates a projector of the read models in the Redis server
+// at the given address. Records need minCount ratings to enter the
+// top leaderboard.
+func New(addr string, minCount int) *Projector {
+	return &Projector{redis.NewClient(&redis.Options{Addr: addr}), minCount}
+}
+
+// Handle projects a rating event.
+func (p *Projector) Handle(ctx context.Context, e *model.RatingEvent) error {
+	switch e.Type {
+	case model.RatingEventTypePut:
+		return p.put(ctx, e.RecordType, e.RecordID, e.UserID, e.Value, e.Timestamp, false)
+	case model.RatingEventTypeMoved:
+		// The moved event of the target record carries nothing
+		// the one of the source does not.
+		if e.MovedTo == "" {
+			return nil
+		}
+		return p.move(ctx, e.RecordType, e.RecordID, e.MovedTo)
+	}
+	logger.WarnContext(ctx, "Unknown rating event type", "type", e.Type)
+	return nil
+}
+
+func (p *Projector) put(ctx context.Context, recordType model.RecordType, recordID model.RecordID, userID model.UserID, value model.RatingValue, ratedAt time.Time, keep bool) error {
+	keys := []string{
+		recordRatingsKey(recordType, recordID),
+		recordKey(recordType, recordID),
+		userTimelineKey(recordType, userID),
+		userRatingsKey(recordType, userID),
+		boardKey(recordType, BoardTop),
+		boardKey(recordType, BoardPopular),
+	}
+	keepArg := "0"
+	if keep {
+		keepArg = "1"
+	}
+	return putScript.Run(ctx, p.client, keys, string(userID), string(recordID), int(value), ratedAt.UnixMilli(), p.minCount, keepArg).Err()
+}
+
+// move projects the ratings of a record moved to another. Ratings
+// are added to the target before being removed from the source, so
+// that projecting the move again after a failure completes it.
+// Users who rated both records keep their rating of the target.
+func (p *Projector) move(ctx context.Context, recordType model.RecordType, from model.RecordID, to model.RecordID) error {
+	ratings, err := p.client.HGetAll(ctx, recordRatingsKey(recordType, from)).Result()
+	if err != nil {
+		return err
+	}
+	for user, v := range ratings {
+		value, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		userID := model.UserID(user)
+		ratedAt := time.Now()
+		if ms, err := p.client.ZScore(ctx, userTimelineKey(recordType, userID), string(from)).Result(); err == nil {
+			ratedAt = time.UnixMilli(int64(ms))
+		} else if !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if err := p.put(ctx, recordType, to, userID, model.RatingValue(value), ratedAt, true); err != nil {
+			return err
+		}
+		if _, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRem(ctx, userTimelineKey(recordType, userID), string(from))
+			pipe.HDel(ctx, userRatingsKey(recordType, userID), string(from))
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	_, err = p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, recordRatingsKey(recordType, from), recordKey(recordType, from))
+		pipe.ZRem(ctx, boardKey(recordType, BoardTop), string(from))
+		pipe.ZRem(ctx, boardKey(recordType, BoardPopular), string(from))
+		return nil
+	})
+	return err
+}
+
+// Reset deletes all read models, before they are rebuilt.
+func (p *Projector) Reset(ctx context.Context) error {
+	iter := p.client.Scan(ctx, 0, keyPrefix+"*", 1000).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 1000 {
+			if err := p.client.Unlink(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return p.client.Unlink(ctx, keys...).Err()
+	}
+	return nil
+}
+
+// Aggregate returns the average rating of a record and its number
+// of ratings, or ErrNotFound if it has none.
+func (p *Projector) Aggregate(ctx context.Context, recordType model.RecordType, recordID model.RecordID) (float64, int, error) {
+	var agg struct {
+		Sum   int `redis:"sum"`
+		Count int `redis:"count"`
+	}
+	if err := p.client.HGetAll(ctx, recordKey(recordType, recordID)).Scan(&agg); err != nil {
+		return 0, 0, err
+	}
+	if agg.Count == 0 {
+		return 0, 0, ErrNotFound
+	}
+	return float64(agg.Sum) / float64(agg.Count), agg.Count, nil
+}
+
+// UserRating defines a rating in the list of a user.
+type UserRating struct {
+	RecordID model.RecordID
+	Value    model.RatingValue
+	RatedAt  time.Time
+}
+
+// UserRatings returns up to limit latest ratings of a user,
+// latest first.
+func (p *Projector) UserRatings(ctx context.Context, recordType model.RecordType, userID model.UserID, limit int) ([]UserRating, error) {
+	latest, err := p.client.ZRevRangeWithScores(ctx, userTimelineKey(recordType, userID), 0, int64(limit)-1).Result()
+	if err != nil || len(latest) == 0 {
+		return nil, err
+	}
+	ids := make([]string, len(latest))
+	for i, z := range latest {
+		ids[i] = z.Member.(string)
+	}
+	values, err := p.client.HMGet(ctx, userRatingsKey(recordType, userID), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]UserRating, 0, len(latest))
+	for i, z := range latest {
+		s, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, UserRating{model.RecordID(ids[i]), model.RatingValue(v), time.UnixMilli(int64(z.Score))})
+	}
+	return res, nil
+}
+
+// Entry defines a record ranked by a leaderboard.
+type Entry struct {
+	RecordID model.RecordID
+	Score    float64
+}
+
+// Leaderboard returns the up to limit first records of a board.
+func (p *Projector) Leaderboard(ctx context.Context, recordType model.RecordType, board Board, limit int) ([]Entry, error) {
+	zs, err := p.client.ZRevRangeWithScores(ctx, boardKey(recordType, board), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Entry, len(zs))
+	for i, z := range zs {
+		res[i] = Entry{model.RecordID(z.Member.(string)), z.Score}
+	}
+	return res, nil
+}
+
+// PingContext checks that the Redis server is reachable.
+func (p *Projector) PingContext(ctx context.Context) error {
+	return p.client.Ping(ctx).Err()
+}
+
+// Close closes the client.
+func (p *Projector) Close() error {
+	return p.client.Close()
+}
+
+func recordKey(recordType model.RecordType, recordID model.RecordID) string {
+	return keyPrefix + string(recordType) + ":record:" + string(recordID)
+}
+
+func recordRatingsKey(recordType model.RecordType, recordID model.RecordID) string {
+	return recordKey(recordType, recordID) + ":users"
+}
+
+func userTimelineKey(recordType model.RecordType, userID model.UserID) string {
+	return keyPrefix + string(recordType) + ":user:" + string(userID)
+}
+
+func userRatingsKey(recordType model.RecordType, userID model.UserID) string {
+	return userTimelineKey(recordType, userID) + ":values"
+}
+
+func boardKey(recordType model.RecordType, board Board) string {
+	return keyPrefix + string(recordType) + ":board:" + string(board)
+}
@@ -4,3 +4,7 @@ import "errors"
 
 // ErrNotFound is returned when a requested record is not found.
 var ErrNotFound = errors.New("not found")
+
+// ErrNoHistory is returned for historical queries of repositories
+// not keeping the history of the ratings.
+var ErrNoHistory = errors.New("rating history not kept")
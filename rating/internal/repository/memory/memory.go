@@ -2,43 +2,198 @@ package memory
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
+	"movieapp.com/pkg/tenant"
 	"movieapp.com/rating/internal/repository"
 	model "movieapp.com/rating/pkg/model"
 )
 
-// Repository defines a rating repository.
+// Repository defines a rating repository, keeping the ratings of
+// each tenant apart.
 type Repository struct {
 	sync.RWMutex
+	tenants map[string]*store
+}
+
+// store holds the ratings of a tenant.
+type store struct {
 	data map[model.RecordType]map[model.RecordID][]model.Rating
+	// counts holds the number of ratings by trending bucket.
+	counts map[model.RecordType]map[model.RecordID]map[time.Time]int
 }
 
 // New creates a new memory repository.
 func New() *Repository {
-	return &Repository{data: map[model.RecordType]map[model.RecordID][]model.Rating{}}
+	return &Repository{tenants: map[string]*store{}}
+}
+
+// of returns the ratings of the tenant of the context, created if
+// missing, which requires the write lock.
+func (r *Repository) of(ctx context.Context, create bool) *store {
+	id := tenant.FromContext(ctx)
+	s, ok := r.tenants[id]
+	if !ok {
+		s = &store{
+			data:   map[model.RecordType]map[model.RecordID][]model.Rating{},
+			counts: map[model.RecordType]map[model.RecordID]map[time.Time]int{},
+		}
+		if create {
+			r.tenants[id] = s
+		}
+	}
+	return s
 }
 
 // Get retrieves all ratings for a given record.
 func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
 	r.RLock()
 	defer r.RUnlock()
-	if _, ok := r.data[recordType]; !ok {
+	s := r.of(ctx, false)
+	if _, ok := s.data[recordType]; !ok {
 		return nil, repository.ErrNotFound
 	}
-	if ratings, ok := r.data[recordType][recordID]; !ok || len(ratings) == 0 {
+	if ratings, ok := s.data[recordType][recordID]; !ok || len(ratings) == 0 {
 		return nil, repository.ErrNotFound
 	}
-	return r.data[recordType][recordID], nil
+	return s.data[recordType][recordID], nil
+}
+
+// GetMany retrieves all ratings of the given records by record
+// id. Records without ratings are left out.
+func (r *Repository) GetMany(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID][]model.Rating, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	res := map[model.RecordID][]model.Rating{}
+	for _, id := range recordIDs {
+		if ratings := s.data[recordType][id]; len(ratings) > 0 {
+			res[id] = ratings
+		}
+	}
+	return res, nil
+}
+
+// GetByUser retrieves the ratings a user gave to the given records
+// by record id. Records the user has not rated are left out; of
+// repeated ratings of a record the last one counts.
+func (r *Repository) GetByUser(ctx context.Context, userID model.UserID, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]model.RatingValue, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	res := map[model.RecordID]model.RatingValue{}
+	for _, id := range recordIDs {
+		for _, rating := range s.data[recordType][id] {
+			if rating.UserID == userID {
+				res[id] = rating.Value
+			}
+		}
+	}
+	return res, nil
+}
+
+// ListByUser retrieves all ratings of a user for records of the
+// given type.
+func (r *Repository) ListByUser(ctx context.Context, userID model.UserID, recordType model.RecordType) ([]model.Rating, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	var res []model.Rating
+	for id, ratings := range s.data[recordType] {
+		for _, rating := range ratings {
+			if rating.UserID == userID {
+				rating.RecordID, rating.RecordType = id, recordType
+				res = append(res, rating)
+			}
+		}
+	}
+	return res, nil
 }
 
 // Put adds a rating for a given record.
 func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
 	r.Lock()
 	defer r.Unlock()
-	if _, ok := r.data[recordType][recordID]; !ok {
-		r.data[recordType] = map[model.RecordID][]model.Rating{}
+	s := r.of(ctx, true)
+	if _, ok := s.data[recordType]; !ok {
+		s.data[recordType] = map[model.RecordID][]model.Rating{}
 	}
-	r.data[recordType][recordID] = append(r.data[recordType][recordID], *rating)
+	s.data[recordType][recordID] = append(s.data[recordType][recordID], *rating)
 	return nil
 }
+
+// Move reassigns all ratings of a record to another record.
+func (r *Repository) Move(ctx context.Context, recordType model.RecordType, from model.RecordID, to model.RecordID) error {
+	r.Lock()
+	defer r.Unlock()
+	s := r.of(ctx, true)
+	records, ok := s.data[recordType]
+	if !ok || len(records[from]) == 0 {
+		return nil
+	}
+	for _, rating := range records[from] {
+		rating.RecordID = to
+		records[to] = append(records[to], rating)
+	}
+	delete(records, from)
+	if counts := s.counts[recordType]; counts[from] != nil {
+		if counts[to] == nil {
+			counts[to] = map[time.Time]int{}
+		}
+		for bucket, n := range counts[from] {
+			counts[to][bucket] += n
+		}
+		delete(counts, from)
+	}
+	return nil
+}
+
+// IncrementCount counts a rating of a record in a trending bucket.
+func (r *Repository) IncrementCount(ctx context.Context, recordID model.RecordID, recordType model.RecordType, bucket time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+	s := r.of(ctx, true)
+	if _, ok := s.counts[recordType]; !ok {
+		s.counts[recordType] = map[model.RecordID]map[time.Time]int{}
+	}
+	if _, ok := s.counts[recordType][recordID]; !ok {
+		s.counts[recordType][recordID] = map[time.Time]int{}
+	}
+	s.counts[recordType][recordID][bucket]++
+	return nil
+}
+
+// Trending returns up to limit records of a type with the highest
+// trending score of the ratings counted since the given time.
+func (r *Repository) Trending(ctx context.Context, recordType model.RecordType, since time.Time, halfLife time.Duration, limit int) ([]model.TrendingRecord, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s := r.of(ctx, false)
+	now := time.Now().UTC()
+	var res []model.TrendingRecord
+	for id, buckets := range s.counts[recordType] {
+		t := model.TrendingRecord{RecordID: id, RecordType: recordType}
+		for bucket, n := range buckets {
+			if bucket.Before(since) {
+				continue
+			}
+			t.Count += n
+			t.Score += float64(n) * model.TrendingWeight(bucket, now, halfLife)
+		}
+		if t.Count > 0 {
+			res = append(res, t)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].RecordID < res[j].RecordID
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
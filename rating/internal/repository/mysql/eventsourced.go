@@ -0,0 +1,377 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/rating/internal/repository"
+	"movieapp.com/rating/pkg/model"
+)
+
+var logger = logging.New("repository/mysql")
+
+// Types of the events of the rating log.
+const (
+	// eventPut sets the rating of a user.
+	eventPut = "put"
+	// eventMovedOut removes the rating of a user moved to another
+	// record.
+	eventMovedOut = "moved_out"
+	// eventMovedIn sets the rating of a user moved from another
+	// record, unless the user rated the record already.
+	eventMovedIn = "moved_in"
+)
+
+// DefaultSnapshotEvery is the default number of events folded into
+// the ratings of a record before they are snapshotted.
+const DefaultSnapshotEvery = 100
+
+// EventSourced defines a rating repository keeping the ratings as
+// an append-only log of events in the rating_events table. The
+// ratings of a record are folded from its events, starting from its
+// latest snapshot in the rating_snapshots table, and those of any
+// past time from the latest snapshot taken before it.
+//
+// Snapshots are only a cache of the fold of the log: deleting them,
+// such as after a change of the fold, recomputes all ratings from
+// the full log. The ratings of the ratings table are not part of the
+// log, and can be appended to it once with:
+//
+//	INSERT INTO rating_events (record_type, record_id, user_id, type, value, occurred_at)
+//	SELECT record_type, record_id, user_id, 'put', value, UTC_TIMESTAMP(6) FROM ratings;
+type EventSourced struct {
+	*Repository
+	snapshotEvery int
+}
+
+// NewEventSourced creates an event-sourced rating repository in the
+// database of the given repository, sharing its connection pool and
+// its trending counts. The ratings of a record are snapshotted when
+// snapshotEvery events were folded since the latest snapshot.
+func NewEventSourced(r *Repository, snapshotEvery int) *EventSourced {
+	return &EventSourced{r, snapshotEvery}
+}
+
+// fold defines the ratings of a record folded up to an event.
+type fold struct {
+	// Seq is the sequence number of the last event folded.
+	Seq int64 `json:"-"`
+	// AsOf is the time of the last event folded.
+	AsOf    time.Time                          `json:"-"`
+	Ratings map[model.UserID]model.RatingValue `json:"ratings"`
+	// folded is the number of events folded since the snapshot the
+	// fold started from.
+	folded int
+}
+
+// apply folds an event into the ratings.
+func (f *fold) apply(eventType string, userID model.UserID, value model.RatingValue) {
+	switch eventType {
+	case eventPut:
+		f.Ratings[userID] = value
+	case eventMovedOut:
+		delete(f.Ratings, userID)
+	case eventMovedIn:
+		if _, ok := f.Ratings[userID]; !ok {
+			f.Ratings[userID] = value
+		}
+	}
+}
+
+// ratings returns the folded ratings ordered by user id.
+func (f *fold) ratings(recordID model.RecordID, recordType model.RecordType) []model.Rating {
+	res := make([]model.Rating, 0, len(f.Ratings))
+	for userID, value := range f.Ratings {
+		res = append(res, model.Rating{RecordID: recordID, RecordType: recordType, UserID: userID, Value: value})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].UserID < res[j].UserID })
+	return res
+}
+
+// querier is implemented by both *tenant.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// load folds the events of a record up to the given time, or all of
+// them if zero. Events are folded in the order they were appended,
+// and times are taken by the appending instances, so that past
+// ratings are exact to within the clock skew of the instances.
+// Locking locks the events of the record until the end of the
+// transaction, so that none is appended meanwhile.
+func (r *EventSourced) load(ctx context.Context, q querier, recordID model.RecordID, recordType model.RecordType, at time.Time, lock bool) (*fold, error) {
+	f := &fold{Ratings: map[model.UserID]model.RatingValue{}}
+	snapshotQuery := "SELECT seq, as_of, ratings FROM rating_snapshots WHERE record_type = ? AND record_id = ?"
+	args := []any{recordType, recordID}
+	if !at.IsZero() {
+		snapshotQuery += " AND as_of <= ?"
+		args = append(args, at)
+	}
+	// Times are scanned as NullTime, so that the data source name
+	// need not set parseTime.
+	var asOf mysql.NullTime
+	var data []byte
+	err := q.QueryRowContext(ctx, snapshotQuery+" ORDER BY seq DESC LIMIT 1", args...).Scan(&f.Seq, &asOf, &data)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil {
+		f.AsOf = asOf.Time
+		if err := json.Unmarshal(data, f); err != nil {
+			return nil, err
+		}
+	}
+	eventsQuery := "SELECT seq, type, user_id, value, occurred_at FROM rating_events WHERE record_type = ? AND record_id = ? AND seq > ?"
+	args = []any{recordType, recordID, f.Seq}
+	if !at.IsZero() {
+		eventsQuery += " AND occurred_at <= ?"
+		args = append(args, at)
+	}
+	eventsQuery += " ORDER BY seq"
+	if lock {
+		eventsQuery += " FOR UPDATE"
+	}
+	rows, err := q.QueryContext(ctx, eventsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var eventType, userID string
+		var value int32
+		if err := rows.Scan(&f.Seq, &eventType, &userID, &value, &asOf); err != nil {
+			return nil, err
+		}
+		f.AsOf = asOf.Time
+		f.apply(eventType, model.UserID(userID), model.RatingValue(value))
+		f.folded++
+	}
+	return f, rows.Err()
+}
+
+// snapshot saves the fold of a record if enough events were folded
+// since the latest snapshot. A failure only makes later reads fold
+// more events, so it is not returned.
+func (r *EventSourced) snapshot(ctx context.Context, recordID model.RecordID, recordType model.RecordType, f *fold) {
+	if f.folded < r.snapshotEvery {
+		return
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	if _, err := r.db.ExecContext(ctx, "INSERT IGNORE INTO rating_snapshots (record_type, record_id, seq, as_of, ratings) VALUES (?, ?, ?, ?, ?)",
+		recordType, recordID, f.Seq, f.AsOf, data); err != nil {
+		logger.WarnContext(ctx, "Rating snapshot error", "recordId", recordID, "error", err)
+	}
+}
+
+// Get retrieves all ratings for a given record.
+func (r *EventSourced) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	f, err := r.load(ctx, r.db, recordID, recordType, time.Time{}, false)
+	if err != nil {
+		return nil, err
+	}
+	r.snapshot(ctx, recordID, recordType, f)
+	if len(f.Ratings) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return f.ratings(recordID, recordType), nil
+}
+
+// GetAsOf retrieves the ratings of a record as they were at the
+// given time.
+func (r *EventSourced) GetAsOf(ctx context.Context, recordID model.RecordID, recordType model.RecordType, at time.Time) ([]model.Rating, error) {
+	f, err := r.load(ctx, r.db, recordID, recordType, at.UTC(), false)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Ratings) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return f.ratings(recordID, recordType), nil
+}
+
+// SaveSnapshots snapshots the ratings of the records with at least
+// as many events since their latest snapshot as the snapshot
+// interval, so that records rarely read are not folded from many
+// events when they are. Returns the number of snapshotted records.
+func (r *EventSourced) SaveSnapshots(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT e.record_type, e.record_id FROM rating_events e
+		LEFT JOIN (SELECT record_type, record_id, MAX(seq) AS seq FROM rating_snapshots GROUP BY record_type, record_id) s
+		ON s.record_type = e.record_type AND s.record_id = e.record_id
+		WHERE e.seq > COALESCE(s.seq, 0) GROUP BY e.record_type, e.record_id HAVING COUNT(*) >= ?`, r.snapshotEvery)
+	if err != nil {
+		return 0, err
+	}
+	type record struct {
+		recordType model.RecordType
+		recordID   model.RecordID
+	}
+	var records []record
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.recordType, &rec.recordID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		records = append(records, rec)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for i, rec := range records {
+		f, err := r.load(ctx, r.db, rec.recordID, rec.recordType, time.Time{}, false)
+		if err != nil {
+			return i, err
+		}
+		r.snapshot(ctx, rec.recordID, rec.recordType, f)
+	}
+	return len(records), nil
+}
+
+// GetMany retrieves all ratings of the given records by record
+// id. Records without ratings are left out.
+func (r *EventSourced) GetMany(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID][]model.Rating, error) {
+	res := map[model.RecordID][]model.Rating{}
+	for _, id := range recordIDs {
+		ratings, err := r.Get(ctx, id, recordType)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res[id] = ratings
+	}
+	return res, nil
+}
+
+// GetByUser retrieves the ratings a user gave to the given records
+// by record id, folded from the events of the user. Records the user
+// has not rated are left out.
+func (r *EventSourced) GetByUser(ctx context.Context, userID model.UserID, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]model.RatingValue, error) {
+	res := map[model.RecordID]model.RatingValue{}
+	if len(recordIDs) == 0 {
+		return res, nil
+	}
+	args := make([]any, 0, len(recordIDs)+2)
+	args = append(args, userID, recordType)
+	for _, id := range recordIDs {
+		args = append(args, id)
+	}
+	query := "SELECT record_id, type, value FROM rating_events WHERE user_id = ? AND record_type = ? AND record_id IN (?" + strings.Repeat(", ?", len(recordIDs)-1) + ") ORDER BY seq"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	folds := map[model.RecordID]*fold{}
+	for rows.Next() {
+		var recordID, eventType string
+		var value int32
+		if err := rows.Scan(&recordID, &eventType, &value); err != nil {
+			return nil, err
+		}
+		f, ok := folds[model.RecordID(recordID)]
+		if !ok {
+			f = &fold{Ratings: map[model.UserID]model.RatingValue{}}
+			folds[model.RecordID(recordID)] = f
+		}
+		f.apply(eventType, userID, model.RatingValue(value))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for id, f := range folds {
+		if value, ok := f.Ratings[userID]; ok {
+			res[id] = value
+		}
+	}
+	return res, nil
+}
+
+// ListByUser retrieves all ratings of a user for records of the
+// given type, folded from the events of the user.
+func (r *EventSourced) ListByUser(ctx context.Context, userID model.UserID, recordType model.RecordType) ([]model.Rating, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT record_id, type, value FROM rating_events WHERE user_id = ? AND record_type = ? ORDER BY seq", userID, recordType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	folds := map[model.RecordID]*fold{}
+	var order []model.RecordID
+	for rows.Next() {
+		var recordID, eventType string
+		var value int32
+		if err := rows.Scan(&recordID, &eventType, &value); err != nil {
+			return nil, err
+		}
+		f, ok := folds[model.RecordID(recordID)]
+		if !ok {
+			f = &fold{Ratings: map[model.UserID]model.RatingValue{}}
+			folds[model.RecordID(recordID)] = f
+			order = append(order, model.RecordID(recordID))
+		}
+		f.apply(eventType, userID, model.RatingValue(value))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var res []model.Rating
+	for _, id := range order {
+		if value, ok := folds[id].Ratings[userID]; ok {
+			res = append(res, model.Rating{RecordID: id, RecordType: recordType, UserID: userID, Value: value})
+		}
+	}
+	return res, nil
+}
+
+// Put appends a rating of a user for a given record, replacing any
+// earlier one.
+func (r *EventSourced) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO rating_events (record_type, record_id, user_id, type, value, occurred_at) VALUES (?, ?, ?, ?, ?, ?)",
+		recordType, recordID, rating.UserID, eventPut, rating.Value, time.Now().UTC())
+	return err
+}
+
+// Move appends events moving each rating of a record to another,
+// along with their trending counts. Users who rated both records
+// keep their rating of the target.
+func (r *EventSourced) Move(ctx context.Context, recordType model.RecordType, from model.RecordID, to model.RecordID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	f, err := r.load(ctx, tx, from, recordType, time.Time{}, true)
+	if err != nil {
+		return err
+	}
+	if ratings := f.ratings(from, recordType); len(ratings) > 0 {
+		now := time.Now().UTC()
+		args := make([]any, 0, 2*6*len(ratings))
+		for _, rating := range ratings {
+			args = append(args, recordType, from, rating.UserID, eventMovedOut, rating.Value, now)
+		}
+		for _, rating := range ratings {
+			args = append(args, recordType, to, rating.UserID, eventMovedIn, rating.Value, now)
+		}
+		query := "INSERT INTO rating_events (record_type, record_id, user_id, type, value, occurred_at) VALUES (?, ?, ?, ?, ?, ?)" + strings.Repeat(", (?, ?, ?, ?, ?, ?)", 2*len(ratings)-1)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	if err := moveCounts(ctx, tx, recordType, from, to); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -3,24 +3,103 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"movieapp.com/pkg/tenant"
 	"movieapp.com/rating/internal/repository"
 	"movieapp.com/rating/pkg/model"
 )
 
 // Repository defines a MySQL-based rating repository.
 type Repository struct {
-	db *sql.DB
+	db *tenant.DB
+	// pools and connectors are the connection pools and connectors of
+	// the databases by tenant.
+	pools      map[string]*sql.DB
+	connectors map[string]*connector
 }
 
-// New creates a new MySQL-based rating repository.
-func New() (*Repository, error) {
-	db, err := sql.Open("mysql", "root:password@/movieexample")
+// New creates a new MySQL-based rating repository of the database
+// with the data source name per tenant of the set, as with
+// tenant.OpenDB.
+func New(dsn string, tenants *tenant.Set) (*Repository, error) {
+	r := &Repository{pools: map[string]*sql.DB{}, connectors: map[string]*connector{}}
+	db, err := tenant.NewDB(dsn, tenants, func(id string, dsn string) (*sql.DB, error) {
+		c := &connector{}
+		if err := c.set(dsn); err != nil {
+			return nil, err
+		}
+		r.pools[id], r.connectors[id] = sql.OpenDB(c), c
+		return r.pools[id], nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &Repository{db}, nil
+	r.db = db
+	return r, nil
+}
+
+// SetDSN changes the data source name of the databases, such as
+// when their password is rotated. Idle connections are closed, so
+// that the pools reconnect with the new one.
+func (r *Repository) SetDSN(dsn string) error {
+	for id, c := range r.connectors {
+		if err := c.set(tenant.DSN(dsn, id)); err != nil {
+			return err
+		}
+	}
+	for _, db := range r.pools {
+		// Not keeping idle connections closes them, after which the
+		// default of the pool is restored.
+		db.SetMaxIdleConns(0)
+		db.SetMaxIdleConns(defaultMaxIdleConns)
+	}
+	return nil
+}
+
+// defaultMaxIdleConns is the default number of idle connections
+// kept by database/sql pools.
+const defaultMaxIdleConns = 2
+
+// connector connects to the database with the current data source
+// name.
+type connector struct {
+	current atomic.Pointer[driver.Connector]
+}
+
+func (c *connector) set(dsn string) error {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return err
+	}
+	conn, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return err
+	}
+	c.current.Store(&conn)
+	return nil
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return (*c.current.Load()).Connect(ctx)
+}
+
+func (c *connector) Driver() driver.Driver {
+	return mysql.MySQLDriver{}
+}
+
+// PingContext checks that the databases are reachable.
+func (r *Repository) PingContext(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the connection pools of the repository.
+func (r *Repository) Close() error {
+	return r.db.Close()
 }
 
 // Get retrieves all ratings for a given record.
@@ -48,9 +127,166 @@ func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordTyp
 	return res, nil
 }
 
+// GetMany retrieves all ratings of the given records by record
+// id. Records without ratings are left out.
+func (r *Repository) GetMany(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID][]model.Rating, error) {
+	res := map[model.RecordID][]model.Rating{}
+	if len(recordIDs) == 0 {
+		return res, nil
+	}
+	args := make([]any, 0, len(recordIDs)+1)
+	for _, id := range recordIDs {
+		args = append(args, id)
+	}
+	args = append(args, recordType)
+	query := "SELECT record_id, user_id, value FROM ratings WHERE record_id IN (?" + strings.Repeat(", ?", len(recordIDs)-1) + ") AND record_type = ?"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var recordID, userID string
+		var value int32
+		if err := rows.Scan(&recordID, &userID, &value); err != nil {
+			return nil, err
+		}
+		res[model.RecordID(recordID)] = append(res[model.RecordID(recordID)], model.Rating{
+			RecordID:   model.RecordID(recordID),
+			RecordType: recordType,
+			UserID:     model.UserID(userID),
+			Value:      model.RatingValue(value),
+		})
+	}
+	return res, rows.Err()
+}
+
+// GetByUser retrieves the ratings a user gave to the given records
+// by record id. Records the user has not rated are left out.
+func (r *Repository) GetByUser(ctx context.Context, userID model.UserID, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]model.RatingValue, error) {
+	res := map[model.RecordID]model.RatingValue{}
+	if len(recordIDs) == 0 {
+		return res, nil
+	}
+	args := make([]any, 0, len(recordIDs)+2)
+	args = append(args, userID, recordType)
+	for _, id := range recordIDs {
+		args = append(args, id)
+	}
+	query := "SELECT record_id, value FROM ratings WHERE user_id = ? AND record_type = ? AND record_id IN (?" + strings.Repeat(", ?", len(recordIDs)-1) + ")"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var recordID string
+		var value int32
+		if err := rows.Scan(&recordID, &value); err != nil {
+			return nil, err
+		}
+		res[model.RecordID(recordID)] = model.RatingValue(value)
+	}
+	return res, rows.Err()
+}
+
+// ListByUser retrieves all ratings of a user for records of the
+// given type.
+func (r *Repository) ListByUser(ctx context.Context, userID model.UserID, recordType model.RecordType) ([]model.Rating, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT record_id, value FROM ratings WHERE user_id = ? AND record_type = ?", userID, recordType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []model.Rating
+	for rows.Next() {
+		var recordID string
+		var value int32
+		if err := rows.Scan(&recordID, &value); err != nil {
+			return nil, err
+		}
+		res = append(res, model.Rating{
+			RecordID:   model.RecordID(recordID),
+			RecordType: recordType,
+			UserID:     userID,
+			Value:      model.RatingValue(value),
+		})
+	}
+	return res, rows.Err()
+}
+
 // Put adds a rating for a given record.
 func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
 	_, err := r.db.ExecContext(ctx, "INSERT INTO ratings (record_id, record_type, user_id, value) VALUES (?, ?, ?, ?)",
 		recordID, recordType, rating.UserID, rating.Value)
 	return err
 }
+
+// Move reassigns all ratings of a record to another record,
+// along with their trending counts.
+func (r *Repository) Move(ctx context.Context, recordType model.RecordType, from model.RecordID, to model.RecordID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "UPDATE ratings SET record_id = ? WHERE record_id = ? AND record_type = ?",
+		to, from, recordType); err != nil {
+		return err
+	}
+	if err := moveCounts(ctx, tx, recordType, from, to); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// moveCounts adds the trending counts of a record to those of
+// another within the transaction.
+func moveCounts(ctx context.Context, tx *sql.Tx, recordType model.RecordType, from model.RecordID, to model.RecordID) error {
+	if _, err := tx.ExecContext(ctx, "INSERT INTO rating_counts (record_type, record_id, bucket, count) SELECT record_type, ?, bucket, count FROM rating_counts WHERE record_type = ? AND record_id = ? ON DUPLICATE KEY UPDATE count = rating_counts.count + VALUES(count)",
+		to, recordType, from); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM rating_counts WHERE record_type = ? AND record_id = ?",
+		recordType, from)
+	return err
+}
+
+// IncrementCount counts a rating of a record in a trending bucket.
+func (r *Repository) IncrementCount(ctx context.Context, recordID model.RecordID, recordType model.RecordType, bucket time.Time) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO rating_counts (record_type, record_id, bucket, count) VALUES (?, ?, ?, 1) ON DUPLICATE KEY UPDATE count = count + 1",
+		recordType, recordID, bucket)
+	return err
+}
+
+// PurgeCounts deletes the trending counts of the buckets before the
+// given time, returning the number of deleted counts.
+func (r *Repository) PurgeCounts(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM rating_counts WHERE bucket < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Trending returns up to limit records of a type with the highest
+// trending score of the ratings counted since the given time.
+func (r *Repository) Trending(ctx context.Context, recordType model.RecordType, since time.Time, halfLife time.Duration, limit int) ([]model.TrendingRecord, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT record_id, SUM(count), SUM(count * POW(0.5, TIMESTAMPDIFF(SECOND, bucket, ?) / ?)) AS score FROM rating_counts WHERE record_type = ? AND bucket >= ? GROUP BY record_id ORDER BY score DESC, record_id LIMIT ?",
+		time.Now().UTC(), halfLife.Seconds(), recordType, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []model.TrendingRecord
+	for rows.Next() {
+		var recordID string
+		t := model.TrendingRecord{RecordType: recordType}
+		if err := rows.Scan(&recordID, &t.Count, &t.Score); err != nil {
+			return nil, err
+		}
+		t.RecordID = model.RecordID(recordID)
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
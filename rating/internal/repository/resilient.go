@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/rating/pkg/model"
+)
+
+// Repository defines a rating repository.
+type Repository interface {
+	Get(context.Context, model.RecordID, model.RecordType) ([]model.Rating, error)
+	GetMany(context.Context, []model.RecordID, model.RecordType) (map[model.RecordID][]model.Rating, error)
+	GetByUser(context.Context, model.UserID, []model.RecordID, model.RecordType) (map[model.RecordID]model.RatingValue, error)
+	ListByUser(context.Context, model.UserID, model.RecordType) ([]model.Rating, error)
+	Put(context.Context, model.RecordID, model.RecordType, *model.Rating) error
+	Move(context.Context, model.RecordType, model.RecordID, model.RecordID) error
+	IncrementCount(context.Context, model.RecordID, model.RecordType, time.Time) error
+	Trending(ctx context.Context, recordType model.RecordType, since time.Time, halfLife time.Duration, limit int) ([]model.TrendingRecord, error)
+}
+
+// History defines a repository keeping the history of the ratings.
+type History interface {
+	// GetAsOf retrieves the ratings of a record as they were at the
+	// given time.
+	GetAsOf(ctx context.Context, recordID model.RecordID, recordType model.RecordType, at time.Time) ([]model.Rating, error)
+}
+
+// Resilient wraps a rating repository with a resilience policy,
+// such as a timeout and a bulkhead. Writes are not idempotent, so
+// the policy must not retry.
+type Resilient struct {
+	repo   Repository
+	policy resilience.Policy
+}
+
+// NewResilient creates a repository calling the given one through
+// the policy.
+func NewResilient(repo Repository, policy resilience.Policy) *Resilient {
+	return &Resilient{repo, policy}
+}
+
+// Get retrieves all ratings for a given record.
+func (r *Resilient) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	return resilience.Call(ctx, r.policy, func(ctx context.Context) ([]model.Rating, error) {
+		return r.repo.Get(ctx, recordID, recordType)
+	})
+}
+
+// GetMany retrieves the ratings of several records of a type.
+func (r *Resilient) GetMany(ctx context.Context, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID][]model.Rating, error) {
+	return resilience.Call(ctx, r.policy, func(ctx context.Context) (map[model.RecordID][]model.Rating, error) {
+		return r.repo.GetMany(ctx, recordIDs, recordType)
+	})
+}
+
+// GetByUser retrieves the ratings a user gave to several records of
+// a type.
+func (r *Resilient) GetByUser(ctx context.Context, userID model.UserID, recordIDs []model.RecordID, recordType model.RecordType) (map[model.RecordID]model.RatingValue, error) {
+	return resilience.Call(ctx, r.policy, func(ctx context.Context) (map[model.RecordID]model.RatingValue, error) {
+		return r.repo.GetByUser(ctx, userID, recordIDs, recordType)
+	})
+}
+
+// ListByUser retrieves all ratings of a user for records of a type.
+func (r *Resilient) ListByUser(ctx context.Context, userID model.UserID, recordType model.RecordType) ([]model.Rating, error) {
+	return resilience.Call(ctx, r.policy, func(ctx context.Context) ([]model.Rating, error) {
+		return r.repo.ListByUser(ctx, userID, recordType)
+	})
+}
+
+// Put adds or replaces the rating of a user for a record.
+func (r *Resilient) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	return r.policy.Do(ctx, func(ctx context.Context) error {
+		return r.repo.Put(ctx, recordID, recordType, rating)
+	})
+}
+
+// Move moves the ratings of a record to another.
+func (r *Resilient) Move(ctx context.Context, recordType model.RecordType, from model.RecordID, to model.RecordID) error {
+	return r.policy.Do(ctx, func(ctx context.Context) error {
+		return r.repo.Move(ctx, recordType, from, to)
+	})
+}
+
+// IncrementCount counts a rating of a record in a trending bucket.
+func (r *Resilient) IncrementCount(ctx context.Context, recordID model.RecordID, recordType model.RecordType, bucket time.Time) error {
+	return r.policy.Do(ctx, func(ctx context.Context) error {
+		return r.repo.IncrementCount(ctx, recordID, recordType, bucket)
+	})
+}
+
+// Trending returns the records trending by recent ratings.
+func (r *Resilient) Trending(ctx context.Context, recordType model.RecordType, since time.Time, halfLife time.Duration, limit int) ([]model.TrendingRecord, error) {
+	return resilience.Call(ctx, r.policy, func(ctx context.Context) ([]model.TrendingRecord, error) {
+		return r.repo.Trending(ctx, recordType, since, halfLife, limit)
+	})
+}
+
+// GetAsOf retrieves the ratings of a record as they were at the
+// given time, or ErrNoHistory if the repository does not keep it.
+func (r *Resilient) GetAsOf(ctx context.Context, recordID model.RecordID, recordType model.RecordType, at time.Time) ([]model.Rating, error) {
+	h, ok := r.repo.(History)
+	if !ok {
+		return nil, ErrNoHistory
+	}
+	return resilience.Call(ctx, r.policy, func(ctx context.Context) ([]model.Rating, error) {
+		return h.GetAsOf(ctx, recordID, recordType, at)
+	})
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// RatingEventType defines the type of a rating change event.
+type RatingEventType string
+
+// Existing rating event types.
+const (
+	RatingEventTypePut = RatingEventType("RatingPut")
+	// RatingEventTypeMoved events are published for both the
+	// source and the target record of moved ratings.
+	RatingEventTypeMoved = RatingEventType("RatingsMoved")
+)
+
+// RatingEvent defines a rating change event published to
+// downstream systems after each write, e.g. to invalidate cached
+// aggregated ratings of the record.
+type RatingEvent struct {
+	Type       RatingEventType `json:"type"`
+	RecordID   RecordID        `json:"recordId"`
+	RecordType RecordType      `json:"recordType"`
+	UserID     UserID          `json:"userId,omitempty"`
+	Value      RatingValue     `json:"value,omitempty"`
+	// MovedTo is the record the ratings moved to, set on the moved
+	// event of the source record.
+	MovedTo   RecordID  `json:"movedTo,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "movieapp.com/gen"
+
+// RatingsToProto converts ratings into their generated proto
+// counterparts.
+func RatingsToProto(ratings []Rating) []*gen.Rating {
+	res := make([]*gen.Rating, 0, len(ratings))
+	for _, r := range ratings {
+		res = append(res, &gen.Rating{
+			RecordId:   string(r.RecordID),
+			RecordType: string(r.RecordType),
+			UserId:     string(r.UserID),
+			Value:      int32(r.Value),
+		})
+	}
+	return res
+}
+
+// RatingsFromProto converts generated proto counterparts into
+// ratings.
+func RatingsFromProto(ratings []*gen.Rating) []Rating {
+	res := make([]Rating, 0, len(ratings))
+	for _, r := range ratings {
+		res = append(res, Rating{
+			RecordID:   RecordID(r.RecordId),
+			RecordType: RecordType(r.RecordType),
+			UserID:     UserID(r.UserId),
+			Value:      RatingValue(r.Value),
+		})
+	}
+	return res
+}
@@ -21,8 +21,8 @@ type RatingValue int
 
 // Rating defines an individual rating created by a user for some record.
 type Rating struct {
-	RecordID   RecordID    `json: "recordId"`
-	RecordType RecordType  `json: "recordType"`
-	UserID     UserID      `json: "userId"`
-	Value      RatingValue `json: "value"`
+	RecordID   RecordID    `json:"recordId"`
+	RecordType RecordType  `json:"recordType"`
+	UserID     UserID      `json:"userId"`
+	Value      RatingValue `json:"value"`
 }
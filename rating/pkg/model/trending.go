@@ -0,0 +1,32 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// TrendingBucketSize defines the period ratings are counted in.
+const TrendingBucketSize = time.Hour
+
+// TrendingRecord defines a record trending by its recent ratings.
+type TrendingRecord struct {
+	RecordID   RecordID   `json:"recordId"`
+	RecordType RecordType `json:"recordType"`
+	// Count is the number of ratings within the trending window.
+	Count int `json:"count"`
+	// Score weighs the ratings by their age, so that a record
+	// gaining ratings ranks above one that got as many earlier.
+	Score float64 `json:"score"`
+}
+
+// TrendingBucket returns the start of the counting period of a
+// rating at the given time.
+func TrendingBucket(t time.Time) time.Time {
+	return t.UTC().Truncate(TrendingBucketSize)
+}
+
+// TrendingWeight returns the weight of the ratings counted in a
+// bucket, halving with every half-life of the bucket's age.
+func TrendingWeight(bucket time.Time, now time.Time, halfLife time.Duration) float64 {
+	return math.Pow(0.5, now.Sub(bucket).Hours()/halfLife.Hours())
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/config"
+	"movieapp.com/pkg/debug"
+	"movieapp.com/pkg/httpmw"
+	"movieapp.com/pkg/lifecycle"
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/mtls"
+	"movieapp.com/pkg/secrets"
+	"movieapp.com/pkg/tenant"
+)
+
+// serviceConfig defines the settings of the recommendation service,
+// loaded by config.Load.
+type serviceConfig struct {
+	Host          string               `yaml:"host"`
+	Port          int                  `yaml:"port"`
+	RESTPort      int                  `yaml:"restPort"`
+	MetricsPort   int                  `yaml:"metricsPort"`
+	DrainTimeout  time.Duration        `yaml:"drainTimeout"`
+	DrainDelay    time.Duration        `yaml:"drainDelay"`
+	ConfigReload  time.Duration        `yaml:"configReload"`
+	RegistryAddr  string               `yaml:"registryAddr"`
+	RegistryCheck time.Duration        `yaml:"registryCheck"`
+	ModelBucket   string               `yaml:"modelBucket"`
+	ModelKey      string               `yaml:"modelKey"`
+	ModelEndpoint string               `yaml:"modelEndpoint"`
+	ModelFile     string               `yaml:"modelFile"`
+	ModelRefresh  time.Duration        `yaml:"modelRefresh"`
+	OTLPEndpoint  string               `yaml:"otlpEndpoint"`
+	SentryDSN     string               `yaml:"sentryDSN"`
+	HTTPTimeouts  httpmw.TimeoutConfig `yaml:"httpTimeouts"`
+	LogLevel      string               `yaml:"logLevel"`
+	Tenants       config.List          `yaml:"tenants"`
+	TLS           mtls.Config          `yaml:"tls"`
+	Auth          auth.Config          `yaml:"auth"`
+	Secrets       secrets.Config       `yaml:"secrets"`
+	Debug         debug.Config         `yaml:"debug"`
+}
+
+func defaultConfig() *serviceConfig {
+	return &serviceConfig{
+		Host:         "localhost",
+		Port:         8089,
+		RESTPort:     8079,
+		MetricsPort:  8100,
+		DrainTimeout: lifecycle.DefaultDrainTimeout,
+		DrainDelay:   lifecycle.DefaultDrainDelay,
+		ConfigReload: config.DefaultReload,
+		RegistryAddr: "localhost:8500",
+		ModelKey:     "recommendations/model.json.gz",
+		ModelRefresh: 10 * time.Minute,
+		LogLevel:     "info",
+		Secrets:      secrets.DefaultConfig(),
+		HTTPTimeouts: httpmw.DefaultTimeoutConfig(),
+	}
+}
+
+// Validate validates the config.
+func (c *serviceConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, errors.New("host: empty"))
+	}
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drainTimeout: not positive"))
+	}
+	if c.DrainDelay < 0 || c.RegistryCheck < 0 || c.ConfigReload < 0 {
+		errs = append(errs, errors.New("drainDelay, registryCheck, configReload: negative"))
+	}
+	errs = append(errs,
+		config.ValidatePort("port", c.Port, false),
+		config.ValidatePort("restPort", c.RESTPort, true),
+		config.ValidatePort("metricsPort", c.MetricsPort, false),
+		config.ValidateAddr("registryAddr", c.RegistryAddr, false),
+		config.ValidateAddr("otlpEndpoint", c.OTLPEndpoint, true),
+	)
+	if (c.ModelBucket == "") == (c.ModelFile == "") {
+		errs = append(errs, errors.New("modelBucket, modelFile: exactly one must be set"))
+	}
+	if c.ModelBucket != "" && c.ModelKey == "" {
+		errs = append(errs, errors.New("modelKey: empty"))
+	}
+	if c.ModelRefresh <= 0 {
+		errs = append(errs, errors.New("modelRefresh: not positive"))
+	}
+	if _, err := logging.ParseLevels(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	}
+	if err := c.Auth.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth: %w", err))
+	}
+	if err := c.Debug.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("debug: %w", err))
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("secrets: %w", err))
+	}
+	if err := c.HTTPTimeouts.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("httpTimeouts: %w", err))
+	}
+	for _, id := range c.Tenants {
+		if err := tenant.Validate(id); err != nil {
+			errs = append(errs, fmt.Errorf("tenants: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
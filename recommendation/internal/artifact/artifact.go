@@ -0,0 +1,99 @@
+package artifact
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"movieapp.com/recommendation/pkg/model"
+)
+
+// ErrNotModified is returned by sources when the artifact is still
+// at the version read last.
+var ErrNotModified = errors.New("artifact not modified")
+
+// maxSize bounds the size of decompressed artifacts.
+const maxSize = 1 << 30
+
+// Source defines a store of the model artifact, such as an object
+// storage bucket.
+type Source interface {
+	// Open returns the artifact and its version, or ErrNotModified
+	// if it is at the given version.
+	Open(ctx context.Context, version string) (io.ReadCloser, string, error)
+}
+
+// Model defines the recommendations precomputed by an offline job:
+// the movies similar to each movie and the movies recommended to
+// each user, by descending score.
+type Model struct {
+	Version string                            `json:"version"`
+	Movies  map[string][]model.Recommendation `json:"movies"`
+	Users   map[string][]model.Recommendation `json:"users"`
+}
+
+// Read decodes a JSON artifact, gzip-compressed if it starts with
+// the gzip header. The recommendations are sorted by descending
+// score, dropping those of a movie for itself. The version defaults
+// to the version of the source.
+func Read(r io.Reader, version string) (*Model, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+	var m Model
+	if err := json.NewDecoder(io.LimitReader(r, maxSize)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode artifact: %w", err)
+	}
+	if m.Version == "" {
+		m.Version = version
+	}
+	for id, recs := range m.Movies {
+		recs, err := clean(recs, id)
+		if err != nil {
+			return nil, fmt.Errorf("movie %s: %w", id, err)
+		}
+		m.Movies[id] = recs
+	}
+	for id, recs := range m.Users {
+		recs, err := clean(recs, "")
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %w", id, err)
+		}
+		m.Users[id] = recs
+	}
+	return &m, nil
+}
+
+// clean sorts recommendations by descending score, dropping those
+// of the excluded movie.
+func clean(recs []model.Recommendation, exclude string) ([]model.Recommendation, error) {
+	res := recs[:0]
+	for _, r := range recs {
+		if r.MovieID == "" {
+			return nil, errors.New("empty movie id")
+		}
+		if math.IsNaN(r.Score) || math.IsInf(r.Score, 0) {
+			return nil, fmt.Errorf("movie %s: score not finite", r.MovieID)
+		}
+		if r.MovieID != exclude {
+			res = append(res, r)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+	return res, nil
+}
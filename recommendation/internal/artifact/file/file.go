@@ -0,0 +1,41 @@
+package file
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+
+	"movieapp.com/recommendation/internal/artifact"
+)
+
+// Source defines a model artifact stored as a local file, e.g. in
+// development, versioned by its modification time and size.
+type Source struct {
+	path string
+}
+
+// New creates a new file source of the artifact at the path.
+func New(path string) *Source {
+	return &Source{path}
+}
+
+// Open returns the file and its version, or artifact.ErrNotModified
+// if its version is the given one.
+func (s *Source) Open(_ context.Context, version string) (io.ReadCloser, string, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, "", err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	v := strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36)
+	if v == version {
+		f.Close()
+		return nil, "", artifact.ErrNotModified
+	}
+	return f, v, nil
+}
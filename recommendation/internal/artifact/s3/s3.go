@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"movieapp.com/recommendation/internal/artifact"
+)
+
+// Source defines a model artifact stored as an object of an
+// S3-compatible storage, versioned by its entity tag.
+type Source struct {
+	client *awss3.Client
+	bucket string
+	key    string
+}
+
+// New creates a new S3 source of the object with the given key
+// using the default AWS configuration. A non-empty endpoint selects
+// an S3-compatible storage with path-style addressing, e.g. MinIO.
+func New(ctx context.Context, bucket string, key string, endpoint string) (*Source, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client := awss3.NewFromConfig(cfg, func(o *awss3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &Source{client, bucket, key}, nil
+}
+
+// Open returns the object and its entity tag, or
+// artifact.ErrNotModified if its entity tag is the given version.
+// The object is only read if it is still at the checked version,
+// so that an artifact replaced in between is read on the next call.
+func (s *Source) Open(ctx context.Context, version string) (io.ReadCloser, string, error) {
+	head, err := s.client.HeadObject(ctx, &awss3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key)})
+	if err != nil {
+		return nil, "", err
+	}
+	etag := aws.ToString(head.ETag)
+	if etag == version {
+		return nil, "", artifact.ErrNotModified
+	}
+	obj, err := s.client.GetObject(ctx, &awss3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key), IfMatch: head.ETag})
+	if err != nil {
+		return nil, "", err
+	}
+	return obj.Body, etag, nil
+}
@@ -0,0 +1,125 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/recommendation/internal/artifact"
+	"movieapp.com/recommendation/pkg/model"
+)
+
+var logger = logging.New("controller")
+
+// ErrNotFound is returned when the model has no recommendations for
+// a movie or a user, such as those newer than the model.
+var ErrNotFound = problem.Register(errors.New("no recommendations"), problem.NotFound)
+
+// ErrNotLoaded is returned until the model is first loaded.
+var ErrNotLoaded = problem.Register(errors.New("model not loaded"), problem.Unavailable)
+
+// Bounds of the number of recommendations returned.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var modelStats = expvar.NewMap("recommendation_model")
+
+// Controller defines a recommendation service controller serving
+// the recommendations of the latest model artifact.
+type Controller struct {
+	source artifact.Source
+	model  atomic.Pointer[artifact.Model]
+	// mu serializes loads. version is the version of the source
+	// loaded last.
+	mu      sync.Mutex
+	version string
+}
+
+// New creates a recommendation service controller loading the
+// model from the source.
+func New(source artifact.Source) *Controller {
+	c := &Controller{source: source}
+	modelStats.Set("version", expvar.Func(func() any { return c.ModelVersion() }))
+	return c
+}
+
+// ModelVersion returns the version of the model being served,
+// empty until it is first loaded.
+func (c *Controller) ModelVersion() string {
+	if m := c.model.Load(); m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+// Load loads the model if the artifact changed since it was loaded
+// last. The model being served is kept if the artifact cannot be
+// read.
+func (c *Controller) Load(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, version, err := c.source.Open(ctx, c.version)
+	if errors.Is(err, artifact.ErrNotModified) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer r.Close()
+	start := time.Now()
+	m, err := artifact.Read(r, version)
+	if err != nil {
+		return err
+	}
+	c.model.Store(m)
+	c.version = version
+	logger.InfoContext(ctx, "Model loaded", "version", m.Version, "movies", len(m.Movies), "users", len(m.Users), "duration", time.Since(start))
+	return nil
+}
+
+// Ready returns ErrNotLoaded until the model is first loaded.
+func (c *Controller) Ready(context.Context) error {
+	if c.model.Load() == nil {
+		return ErrNotLoaded
+	}
+	return nil
+}
+
+// ForMovie returns up to limit movies to watch after the given one
+// by descending score and the version of the model.
+func (c *Controller) ForMovie(_ context.Context, movieID string, limit int) ([]model.Recommendation, string, error) {
+	m := c.model.Load()
+	if m == nil {
+		return nil, "", ErrNotLoaded
+	}
+	return top(m.Movies, movieID, limit, m.Version)
+}
+
+// ForUser returns up to limit movies recommended to a user by
+// descending score and the version of the model. The model leaves
+// out the movies the user had rated when it was computed.
+func (c *Controller) ForUser(_ context.Context, userID string, limit int) ([]model.Recommendation, string, error) {
+	m := c.model.Load()
+	if m == nil {
+		return nil, "", ErrNotLoaded
+	}
+	return top(m.Users, userID, limit, m.Version)
+}
+
+func top(recs map[string][]model.Recommendation, id string, limit int, version string) ([]model.Recommendation, string, error) {
+	res, ok := recs[id]
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit, len(res))
+	return res[:limit:limit], version, nil
+}
@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/recommendation/internal/controller/recommendation"
+	"movieapp.com/recommendation/pkg/model"
+)
+
+// adminScope is the scope of the callers allowed to reload the
+// model.
+const adminScope = "recommendations:admin"
+
+// Handler defines a gRPC recommendation API handler.
+type Handler struct {
+	gen.UnimplementedRecommendationServiceServer
+	ctrl *recommendation.Controller
+}
+
+// New creates a new recommendation gRPC handler.
+func New(ctrl *recommendation.Controller) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// RecommendForMovie returns the movies recommended after a movie.
+func (h *Handler) RecommendForMovie(ctx context.Context, req *gen.RecommendForMovieRequest) (*gen.RecommendResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty movie id")
+	}
+	res, version, err := h.ctrl.ForMovie(ctx, req.MovieId, int(req.Limit))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.RecommendResponse{Recommendations: model.RecommendationsToProto(res), ModelVersion: version}, nil
+}
+
+// RecommendForUser returns the movies recommended to a user, only
+// to the user.
+func (h *Handler) RecommendForUser(ctx context.Context, req *gen.RecommendForUserRequest) (*gen.RecommendResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	res, version, err := h.ctrl.ForUser(ctx, req.UserId, int(req.Limit))
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.RecommendResponse{Recommendations: model.RecommendationsToProto(res), ModelVersion: version}, nil
+}
+
+// ReloadModel loads the latest model artifact into the instance.
+func (h *Handler) ReloadModel(ctx context.Context, req *gen.ReloadModelRequest) (*gen.ReloadModelResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	if err := auth.RequireScope(ctx, adminScope); err != nil {
+		return nil, auth.Status(err)
+	}
+	if err := h.ctrl.Load(ctx); err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.ReloadModelResponse{ModelVersion: h.ctrl.ModelVersion()}, nil
+}
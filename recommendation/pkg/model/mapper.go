@@ -0,0 +1,23 @@
+package model
+
+import "movieapp.com/gen"
+
+// RecommendationsToProto converts recommendations into their
+// generated proto counterparts.
+func RecommendationsToProto(recs []Recommendation) []*gen.Recommendation {
+	res := make([]*gen.Recommendation, 0, len(recs))
+	for _, r := range recs {
+		res = append(res, &gen.Recommendation{MovieId: r.MovieID, Score: r.Score})
+	}
+	return res
+}
+
+// RecommendationsFromProto converts generated proto counterparts
+// into recommendations.
+func RecommendationsFromProto(recs []*gen.Recommendation) []Recommendation {
+	res := make([]Recommendation, 0, len(recs))
+	for _, r := range recs {
+		res = append(res, Recommendation{MovieID: r.MovieId, Score: r.Score})
+	}
+	return res
+}
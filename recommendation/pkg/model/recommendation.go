@@ -0,0 +1,8 @@
+package model
+
+// Recommendation defines a movie recommended with a score, higher
+// scores recommending more strongly.
+type Recommendation struct {
+	MovieID string  `json:"movieId"`
+	Score   float64 `json:"score"`
+}
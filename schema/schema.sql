@@ -1,2 +1,18 @@
-CREATE TABLE IF NOT EXISTS movies (id VARCHAR(255), title VARCHAR(255), description TEXT, director VARCHAR(255));
-CREATE TABLE IF NOT EXISTS ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT);
\ No newline at end of file
+CREATE TABLE IF NOT EXISTS movies (id VARCHAR(255) PRIMARY KEY, title VARCHAR(255), description TEXT, director VARCHAR(255), poster_url VARCHAR(2048), backdrop_url VARCHAR(2048), tagline VARCHAR(255), release_date DATE NULL, runtime_minutes INT, certification VARCHAR(16), original_language VARCHAR(35), version INT, updated_at DATETIME, updated_by VARCHAR(255), deleted_at DATETIME NULL, merged_into VARCHAR(255));
+CREATE TABLE IF NOT EXISTS movie_versions (movie_id VARCHAR(255), version INT, updated_at DATETIME, updated_by VARCHAR(255), data JSON, PRIMARY KEY (movie_id, version));
+CREATE TABLE IF NOT EXISTS metadata_outbox (seq BIGINT AUTO_INCREMENT PRIMARY KEY, message_id VARCHAR(255) UNIQUE, message_key VARCHAR(255), payload LONGBLOB, headers JSON, created_at DATETIME(6), published_at DATETIME(6) NULL, INDEX (published_at, seq));
+CREATE TABLE IF NOT EXISTS movie_genres (movie_id VARCHAR(255), genre VARCHAR(255));
+CREATE TABLE IF NOT EXISTS movie_tags (movie_id VARCHAR(255), tag VARCHAR(255));
+CREATE TABLE IF NOT EXISTS movie_localizations (movie_id VARCHAR(255), locale VARCHAR(35), title VARCHAR(255), description TEXT, tagline VARCHAR(255));
+CREATE TABLE IF NOT EXISTS movie_external_ids (movie_id VARCHAR(255), source VARCHAR(32), external_id VARCHAR(255), PRIMARY KEY (source, external_id));
+CREATE TABLE IF NOT EXISTS people (id VARCHAR(255), name VARCHAR(255));
+CREATE TABLE IF NOT EXISTS credits (movie_id VARCHAR(255), person_id VARCHAR(255), role VARCHAR(255), character_name VARCHAR(255), billing_order INT);
+CREATE TABLE IF NOT EXISTS ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT, INDEX (user_id, record_type, record_id));
+CREATE TABLE IF NOT EXISTS rating_events (seq BIGINT AUTO_INCREMENT PRIMARY KEY, record_type VARCHAR(255), record_id VARCHAR(255), user_id VARCHAR(255), type VARCHAR(16), value INT, occurred_at DATETIME(6), INDEX (record_type, record_id, seq), INDEX (user_id, record_type, seq));
+CREATE TABLE IF NOT EXISTS rating_snapshots (record_type VARCHAR(255), record_id VARCHAR(255), seq BIGINT, as_of DATETIME(6), ratings JSON, PRIMARY KEY (record_type, record_id, seq));
+CREATE TABLE IF NOT EXISTS rating_counts (record_type VARCHAR(255), record_id VARCHAR(255), bucket DATETIME, count INT, PRIMARY KEY (record_type, record_id, bucket));
+CREATE TABLE IF NOT EXISTS users (id VARCHAR(255) PRIMARY KEY, email VARCHAR(320) UNIQUE, display_name VARCHAR(255), avatar_url VARCHAR(2048), created_at DATETIME, updated_at DATETIME);
+CREATE TABLE IF NOT EXISTS watchlist (user_id VARCHAR(255), movie_id VARCHAR(255), added_at DATETIME(6), PRIMARY KEY (user_id, movie_id), INDEX (user_id, added_at, movie_id));
+CREATE TABLE IF NOT EXISTS notification_preferences (user_id VARCHAR(255) PRIMARY KEY, preferences JSON);
+CREATE TABLE IF NOT EXISTS notification_watchers (movie_id VARCHAR(255), user_id VARCHAR(255), PRIMARY KEY (movie_id, user_id));
+CREATE TABLE IF NOT EXISTS audit_log (id BIGINT AUTO_INCREMENT PRIMARY KEY, recorded_at DATETIME(6), service VARCHAR(64), tenant VARCHAR(32), actor VARCHAR(255), action VARCHAR(64), resource VARCHAR(255), request_id VARCHAR(64), details JSON, INDEX (recorded_at), INDEX (actor, id), INDEX (resource, id));
\ No newline at end of file
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	metadatamodel "movieapp.com/metadata/pkg/model"
+	"movieapp.com/pkg/logging"
+	ratingmodel "movieapp.com/rating/pkg/model"
+	"movieapp.com/search/internal/index"
+	"movieapp.com/search/pkg/model"
+)
+
+var logger = logging.New("controller")
+
+// Bounds of search pages. Hits past MaxOffset are not paged to.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	MaxOffset       = 1000
+)
+
+// ratingBatchSize is the number of movies whose ratings are
+// refreshed per call.
+const ratingBatchSize = 100
+
+type ratingGateway interface {
+	GetAggregatedRatings(ctx context.Context, recordIDs []string, recordType ratingmodel.RecordType) (map[string]float64, error)
+}
+
+// Controller defines a search service controller.
+type Controller struct {
+	index         *index.Index
+	ratingGateway ratingGateway
+}
+
+// New creates a search service controller maintaining the index
+// from metadata and rating events, with the aggregated ratings of
+// the rating service.
+func New(index *index.Index, ratingGateway ratingGateway) *Controller {
+	return &Controller{index, ratingGateway}
+}
+
+// Search returns a page of the movies matching a query by
+// descending score, the cursor of the next page, empty on the last
+// page, and the number of matching movies. The page size is capped
+// and defaults when not positive.
+func (c *Controller) Search(_ context.Context, q model.Query, pageSize int, cursor string) ([]model.Hit, string, int, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	} else if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	offset, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	hits, total := c.index.Search(q, offset, pageSize, time.Now())
+	var next string
+	if end := offset + len(hits); end < total && end <= MaxOffset {
+		next = encodeCursor(end)
+	}
+	return hits, next, total, nil
+}
+
+// HandleMetadataEvent indexes the text of changed metadata and
+// removes deleted and merged movies from the index.
+func (c *Controller) HandleMetadataEvent(_ context.Context, e *metadatamodel.Event) error {
+	m := e.Metadata
+	if m == nil || m.Deleted() || m.MergedInto != "" {
+		c.index.Delete(e.MovieID)
+		return nil
+	}
+	doc := model.Document{ID: m.ID, Title: m.Title, Description: m.Description, ReleaseYear: m.ReleaseYear()}
+	for _, g := range m.Genres {
+		doc.Genres = append(doc.Genres, string(g))
+	}
+	c.index.Put(doc)
+	return nil
+}
+
+// HandleRatingEvent counts new ratings towards the popularity of
+// their movies and refreshes the ratings of the movies.
+func (c *Controller) HandleRatingEvent(ctx context.Context, e *ratingmodel.RatingEvent) error {
+	if e.RecordType != ratingmodel.RecordTypeMovie {
+		return nil
+	}
+	if e.Type == ratingmodel.RatingEventTypePut {
+		c.index.AddRating(string(e.RecordID), e.Timestamp)
+	}
+	return c.refreshRatings(ctx, []string{string(e.RecordID)})
+}
+
+// RefreshRatings refreshes the ratings of all indexed movies and
+// returns the number of movies refreshed.
+func (c *Controller) RefreshRatings(ctx context.Context) (int, error) {
+	ids := c.index.IDs()
+	var refreshed int
+	for len(ids) > 0 {
+		n := min(len(ids), ratingBatchSize)
+		if err := c.refreshRatings(ctx, ids[:n]); err != nil {
+			return refreshed, err
+		}
+		refreshed += n
+		ids = ids[n:]
+	}
+	return refreshed, nil
+}
+
+func (c *Controller) refreshRatings(ctx context.Context, ids []string) error {
+	ratings, err := c.ratingGateway.GetAggregatedRatings(ctx, ids, ratingmodel.RecordTypeMovie)
+	if err != nil {
+		return err
+	}
+	res := make(map[string]*float64, len(ids))
+	for _, id := range ids {
+		if v, ok := ratings[id]; ok {
+			res[id] = &v
+		} else {
+			res[id] = nil
+		}
+	}
+	c.index.SetRatings(res)
+	return nil
+}
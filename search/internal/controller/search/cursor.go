@@ -0,0 +1,40 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"movieapp.com/pkg/problem"
+)
+
+// ErrInvalidCursor is returned when a cursor was not issued by
+// Search.
+var ErrInvalidCursor = problem.Register(errors.New("invalid cursor"), problem.BadRequest)
+
+// cursor is the position of a page of Search. Hits are ranked on
+// every search, so a page starts at an offset, which ratings
+// changed meanwhile may shift.
+type cursor struct {
+	Offset int `json:"o"`
+}
+
+func encodeCursor(offset int) string {
+	b, _ := json.Marshal(cursor{offset})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Offset <= 0 || c.Offset > MaxOffset {
+		return 0, ErrInvalidCursor
+	}
+	return c.Offset, nil
+}
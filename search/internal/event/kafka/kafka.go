@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/pkg/logging"
+)
+
+var logger = logging.New("event/kafka")
+
+// Consumer defines a Kafka consumer of the JSON events of type T.
+type Consumer[T any] struct {
+	reader *kafka.Reader
+	id     func(*T) string
+}
+
+// NewConsumer creates a Kafka consumer reading the events of the
+// topic, identified for logging by the id function. A new group
+// reads all retained events if replay is set, or the events
+// published from now on. Consumers with distinct group ids each
+// receive all events. Connections authenticate with the
+// credentials unless nil.
+func NewConsumer[T any](brokers []string, topic string, groupID string, replay bool, creds *kafkautil.Credentials, id func(*T) string) *Consumer[T] {
+	start := kafka.LastOffset
+	if replay {
+		start = kafka.FirstOffset
+	}
+	return &Consumer[T]{kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		GroupID:     groupID,
+		StartOffset: start,
+		Dialer:      creds.Dialer(),
+	}), id}
+}
+
+// Run passes events to the handler until the context is canceled.
+// Events the handler fails on are logged and skipped.
+func (c *Consumer[T]) Run(ctx context.Context, handle func(context.Context, *T) error) {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.ErrorContext(ctx, "Event fetch error", "error", err)
+			}
+			return
+		}
+		spanCtx, span := tracing.StartKafkaConsumer(ctx, msg)
+		var e *T
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			logger.ErrorContext(spanCtx, "Event decode error", "error", err)
+			span.SetStatus(codes.Error, err.Error())
+		} else if err := handle(spanCtx, e); err != nil {
+			logger.ErrorContext(spanCtx, "Event handling error", "id", c.id(e), "error", err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "Event commit error", "error", err)
+		}
+	}
+}
+
+// Close closes the consumer.
+func (c *Consumer[T]) Close() error {
+	return c.reader.Close()
+}
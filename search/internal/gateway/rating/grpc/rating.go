@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"movieapp.com/gen"
+	"movieapp.com/internal/grpcutil"
+	"movieapp.com/pkg/resilience"
+	"movieapp.com/rating/pkg/model"
+)
+
+// Gateway defines a gRPC gateway for a rating service.
+type Gateway struct {
+	client gen.RatingServiceClient
+	retry  resilience.Retry
+}
+
+// New creates a new gRPC gateway for a rating service calling it
+// through the connection. Transient failures are retried.
+func New(conn grpc.ClientConnInterface) *Gateway {
+	return &Gateway{gen.NewRatingServiceClient(conn), resilience.DefaultRetry(grpcutil.Retryable)}
+}
+
+// GetAggregatedRatings returns the aggregated ratings of several
+// records by record id in a single call. Records without ratings
+// are left out.
+func (g *Gateway) GetAggregatedRatings(ctx context.Context, recordIDs []string, recordType model.RecordType) (map[string]float64, error) {
+	var resp *gen.GetAggregatedRatingsResponse
+	err := g.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = g.client.GetAggregatedRatings(ctx, &gen.GetAggregatedRatingsRequest{RecordIds: recordIDs, RecordType: string(recordType)})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.RatingValues, nil
+}
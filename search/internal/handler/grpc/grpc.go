@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/search/internal/controller/search"
+	"movieapp.com/search/pkg/model"
+)
+
+// adminScope is the scope of the callers allowed to refresh the
+// index.
+const adminScope = "search:admin"
+
+// Handler defines a gRPC search API handler.
+type Handler struct {
+	gen.UnimplementedSearchServiceServer
+	ctrl *search.Controller
+}
+
+// New creates a new search gRPC handler.
+func New(ctrl *search.Controller) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// SearchMovies returns a page of the movies matching a query.
+func (h *Handler) SearchMovies(ctx context.Context, req *gen.SearchMoviesRequest) (*gen.SearchMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	q := model.Query{Text: req.Query, Genres: req.Genres, YearFrom: int(req.YearFrom), YearTo: int(req.YearTo)}
+	hits, next, total, err := h.ctrl.Search(ctx, q, int(req.PageSize), req.Cursor)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.SearchMoviesResponse{Hits: model.HitsToProto(hits), NextCursor: next, Total: int32(total)}, nil
+}
+
+// RefreshSearchRatings refreshes the ratings of all the movies
+// indexed by the instance.
+func (h *Handler) RefreshSearchRatings(ctx context.Context, req *gen.RefreshSearchRatingsRequest) (*gen.RefreshSearchRatingsResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	if err := auth.RequireScope(ctx, adminScope); err != nil {
+		return nil, auth.Status(err)
+	}
+	n, err := h.ctrl.RefreshRatings(ctx)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.RefreshSearchRatingsResponse{Refreshed: int32(n)}, nil
+}
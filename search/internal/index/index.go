@@ -0,0 +1,269 @@
+package index
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"movieapp.com/search/pkg/model"
+)
+
+// Weights of the matches of a word by field.
+const (
+	titleWeight       = 3
+	descriptionWeight = 1
+)
+
+// Weights of the rating and the popularity in the score of a hit,
+// relative to its text relevance.
+const (
+	ratingWeight     = 0.5
+	popularityWeight = 0.2
+	maxRating        = 5
+)
+
+// PopularityHalfLife is the age at which a rating counts half as
+// much towards the popularity of a movie.
+const PopularityHalfLife = 72 * time.Hour
+
+// entry defines an indexed movie.
+type entry struct {
+	doc    model.Document
+	rating *float64
+	// popularity is as of popularityAt, decaying since.
+	popularity   float64
+	popularityAt time.Time
+}
+
+// Index defines an in-memory inverted index of movies combining
+// the text of their metadata with their ratings and popularity.
+type Index struct {
+	mu      sync.RWMutex
+	entries map[string]*entry
+	// postings holds the weight of each word in each movie.
+	postings map[string]map[string]float64
+	// words lists the indexed words in order, for prefix matches.
+	words []string
+}
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{entries: map[string]*entry{}, postings: map[string]map[string]float64{}}
+}
+
+// Put indexes a movie or reindexes its text, keeping its rating
+// and popularity.
+func (x *Index) Put(doc model.Document) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	e, ok := x.entries[doc.ID]
+	if ok {
+		x.unindex(e)
+	} else {
+		e = &entry{}
+		x.entries[doc.ID] = e
+	}
+	e.doc = doc
+	for word, weight := range weights(doc) {
+		p, ok := x.postings[word]
+		if !ok {
+			p = map[string]float64{}
+			x.postings[word] = p
+			i, _ := slices.BinarySearch(x.words, word)
+			x.words = slices.Insert(x.words, i, word)
+		}
+		p[doc.ID] = weight
+	}
+}
+
+// Delete removes a movie from the index.
+func (x *Index) Delete(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if e, ok := x.entries[id]; ok {
+		x.unindex(e)
+		delete(x.entries, id)
+	}
+}
+
+func (x *Index) unindex(e *entry) {
+	for word := range weights(e.doc) {
+		delete(x.postings[word], e.doc.ID)
+		if len(x.postings[word]) == 0 {
+			delete(x.postings, word)
+			if i, ok := slices.BinarySearch(x.words, word); ok {
+				x.words = slices.Delete(x.words, i, i+1)
+			}
+		}
+	}
+}
+
+// SetRatings sets the aggregated ratings of movies by id, nil for
+// movies without ratings. Movies not in the index are ignored.
+func (x *Index) SetRatings(ratings map[string]*float64) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for id, r := range ratings {
+		if e, ok := x.entries[id]; ok {
+			e.rating = r
+		}
+	}
+}
+
+// AddRating counts a rating of a movie given at the time towards
+// its popularity.
+func (x *Index) AddRating(id string, at time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	e, ok := x.entries[id]
+	if !ok {
+		return
+	}
+	if at.Before(e.popularityAt) {
+		e.popularity += decay(1, at, e.popularityAt)
+		return
+	}
+	e.popularity = decay(e.popularity, e.popularityAt, at) + 1
+	e.popularityAt = at
+}
+
+// IDs returns the ids of the indexed movies.
+func (x *Index) IDs() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	res := make([]string, 0, len(x.entries))
+	for id := range x.entries {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Search returns up to limit hits of the query after the first
+// offset ones by descending score, and the number of hits.
+func (x *Index) Search(q model.Query, offset int, limit int, now time.Time) ([]model.Hit, int) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	relevance := x.match(tokenize(q.Text))
+	var hits []model.Hit
+	for id, r := range relevance {
+		e := x.entries[id]
+		if !filter(q, &e.doc) {
+			continue
+		}
+		popularity := decay(e.popularity, e.popularityAt, now)
+		score := r * (1 + popularityWeight*math.Log1p(popularity))
+		if e.rating != nil {
+			score *= 1 + ratingWeight**e.rating/maxRating
+		}
+		hits = append(hits, model.Hit{MovieID: id, Title: e.doc.Title, Score: score, Rating: e.rating, Popularity: popularity})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].MovieID < hits[j].MovieID
+	})
+	total := len(hits)
+	if offset >= total {
+		return nil, total
+	}
+	return hits[offset:min(offset+limit, total)], total
+}
+
+// match returns the text relevance of the movies matching all the
+// words, the last one as a prefix. All movies match no words.
+func (x *Index) match(words []string) map[string]float64 {
+	res := map[string]float64{}
+	if len(words) == 0 {
+		for id := range x.entries {
+			res[id] = 1
+		}
+		return res
+	}
+	for i, word := range words {
+		matched := map[string]float64{}
+		for _, w := range x.matching(word, i == len(words)-1) {
+			idf := math.Log1p(float64(len(x.entries)) / float64(len(x.postings[w])))
+			for id, weight := range x.postings[w] {
+				matched[id] = max(matched[id], weight*idf)
+			}
+		}
+		if i == 0 {
+			res = matched
+			continue
+		}
+		for id := range res {
+			if v, ok := matched[id]; ok {
+				res[id] += v
+			} else {
+				delete(res, id)
+			}
+		}
+	}
+	return res
+}
+
+// matching returns the indexed words equal to the word, or
+// starting with it if prefix is set.
+func (x *Index) matching(word string, prefix bool) []string {
+	i, found := slices.BinarySearch(x.words, word)
+	if !prefix {
+		if found {
+			return []string{word}
+		}
+		return nil
+	}
+	j := i
+	for j < len(x.words) && strings.HasPrefix(x.words[j], word) {
+		j++
+	}
+	return x.words[i:j]
+}
+
+func filter(q model.Query, doc *model.Document) bool {
+	for _, g := range q.Genres {
+		if !slices.Contains(doc.Genres, g) {
+			return false
+		}
+	}
+	if q.YearFrom > 0 || q.YearTo > 0 {
+		if doc.ReleaseYear == 0 || (q.YearFrom > 0 && doc.ReleaseYear < q.YearFrom) || (q.YearTo > 0 && doc.ReleaseYear > q.YearTo) {
+			return false
+		}
+	}
+	return true
+}
+
+// weights returns the weight of each word of a movie, the highest
+// of the fields it is in.
+func weights(doc model.Document) map[string]float64 {
+	res := map[string]float64{}
+	for _, w := range tokenize(doc.Description) {
+		res[w] = descriptionWeight
+	}
+	for _, w := range tokenize(doc.Title) {
+		res[w] = titleWeight
+	}
+	return res
+}
+
+// tokenize splits text into lower case words of letters and
+// digits.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// decay returns a popularity as of from decayed until to.
+func decay(popularity float64, from time.Time, to time.Time) float64 {
+	if popularity == 0 || !to.After(from) {
+		return popularity
+	}
+	return popularity * math.Pow(0.5, float64(to.Sub(from))/float64(PopularityHalfLife))
+}
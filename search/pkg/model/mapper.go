@@ -0,0 +1,22 @@
+package model
+
+import "movieapp.com/gen"
+
+// HitsToProto converts hits into their generated proto
+// counterparts.
+func HitsToProto(hits []Hit) []*gen.SearchHit {
+	res := make([]*gen.SearchHit, 0, len(hits))
+	for _, h := range hits {
+		res = append(res, &gen.SearchHit{MovieId: h.MovieID, Title: h.Title, Score: h.Score, Rating: h.Rating, Popularity: h.Popularity})
+	}
+	return res
+}
+
+// HitsFromProto converts generated proto counterparts into hits.
+func HitsFromProto(hits []*gen.SearchHit) []Hit {
+	res := make([]Hit, 0, len(hits))
+	for _, h := range hits {
+		res = append(res, Hit{MovieID: h.MovieId, Title: h.Title, Score: h.Score, Rating: h.Rating, Popularity: h.Popularity})
+	}
+	return res
+}
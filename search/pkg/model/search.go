@@ -0,0 +1,36 @@
+package model
+
+// Document defines the data of a movie indexed for search.
+type Document struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Genres      []string `json:"genres,omitempty"`
+	ReleaseYear int      `json:"releaseYear,omitempty"`
+}
+
+// Query defines a search of movies.
+type Query struct {
+	// Text is matched against the title and the description, its
+	// last word as a prefix. All movies match empty text.
+	Text string
+	// Genres the movies must all have.
+	Genres []string
+	// YearFrom and YearTo bound the release year, inclusive, zero
+	// for no bound.
+	YearFrom int
+	YearTo   int
+}
+
+// Hit defines a movie matching a search.
+type Hit struct {
+	MovieID string `json:"movieId"`
+	Title   string `json:"title"`
+	// Score is the relevance of the text match weighed by rating
+	// and popularity.
+	Score  float64  `json:"score"`
+	Rating *float64 `json:"rating,omitempty"`
+	// Popularity counts the recent ratings, decaying with their
+	// age.
+	Popularity float64 `json:"popularity"`
+}
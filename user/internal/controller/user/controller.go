@@ -0,0 +1,150 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"movieapp.com/pkg/problem"
+	"movieapp.com/pkg/request"
+	"movieapp.com/user/internal/repository"
+	"movieapp.com/user/pkg/model"
+)
+
+// ErrNotFound is returned when a user is not found.
+var ErrNotFound = problem.Register(errors.New("user not found"), problem.NotFound)
+
+// ErrAlreadyRegistered is returned when registering a user whose id
+// or email is already registered.
+var ErrAlreadyRegistered = problem.Register(errors.New("user already registered"), problem.Conflict)
+
+// ErrTooManyIDs is returned when profiles are requested for more
+// than MaxBatchSize users.
+var ErrTooManyIDs = problem.Register(errors.New("too many ids"), problem.BadRequest)
+
+// MaxBatchSize defines the maximum number of users whose profiles
+// can be requested at once.
+const MaxBatchSize = 100
+
+const (
+	maxIDLength          = 255
+	maxDisplayNameLength = 64
+)
+
+type userRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	Get(ctx context.Context, id string) (*model.User, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.User, error)
+	Update(ctx context.Context, user *model.User) error
+}
+
+// Controller defines a user service controller.
+type Controller struct {
+	repo userRepository
+}
+
+// New creates a user service controller.
+func New(repo userRepository) *Controller {
+	return &Controller{repo}
+}
+
+// Register creates the account of a user.
+func (c *Controller) Register(ctx context.Context, user *model.User) (*model.User, error) {
+	user.Email = strings.TrimSpace(user.Email)
+	user.DisplayName = strings.TrimSpace(user.DisplayName)
+	if err := validate(user); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	user.CreatedAt, user.UpdatedAt = now, now
+	if err := c.repo.Create(ctx, user); errors.Is(err, repository.ErrAlreadyExists) {
+		return nil, ErrAlreadyRegistered
+	} else if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Get returns the account of a user.
+func (c *Controller) Get(ctx context.Context, id string) (*model.User, error) {
+	u, err := c.repo.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return u, err
+}
+
+// ProfileUpdate defines the changes of a profile, nil fields being
+// left as they are.
+type ProfileUpdate struct {
+	DisplayName *string
+	AvatarURL   *string
+}
+
+// UpdateProfile changes the profile of a user.
+func (c *Controller) UpdateProfile(ctx context.Context, id string, update ProfileUpdate) (*model.User, error) {
+	u, err := c.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if update.DisplayName != nil {
+		u.DisplayName = strings.TrimSpace(*update.DisplayName)
+	}
+	if update.AvatarURL != nil {
+		u.AvatarURL = *update.AvatarURL
+	}
+	if err := validate(u); err != nil {
+		return nil, err
+	}
+	u.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	if err := c.repo.Update(ctx, u); errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Profiles returns the public profiles of several users by user id.
+// Unknown users are left out.
+func (c *Controller) Profiles(ctx context.Context, ids []string) (map[string]model.Profile, error) {
+	if len(ids) > MaxBatchSize {
+		return nil, ErrTooManyIDs
+	}
+	users, err := c.repo.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]model.Profile, len(users))
+	for _, u := range users {
+		res[u.ID] = u.Profile()
+	}
+	return res, nil
+}
+
+// validate checks a user before it is written.
+func validate(u *model.User) error {
+	var fields []request.FieldError
+	if u.ID == "" || len(u.ID) > maxIDLength {
+		fields = append(fields, request.FieldError{Field: "userId", Message: "must be 1 to 255 bytes long"})
+	}
+	if addr, err := mail.ParseAddress(u.Email); err != nil || addr.Address != u.Email {
+		fields = append(fields, request.FieldError{Field: "email", Message: "must be an email address"})
+	}
+	if n := utf8.RuneCountInString(u.DisplayName); n == 0 || n > maxDisplayNameLength {
+		fields = append(fields, request.FieldError{Field: "displayName", Message: "must be 1 to 64 characters long"})
+	}
+	if u.AvatarURL != "" {
+		if v, err := url.Parse(u.AvatarURL); err != nil || (v.Scheme != "http" && v.Scheme != "https") || v.Host == "" {
+			fields = append(fields, request.FieldError{Field: "avatarUrl", Message: "must be an absolute http or https URL"})
+		}
+	}
+	if len(fields) > 0 {
+		return &request.Error{Fields: fields}
+	}
+	return nil
+}
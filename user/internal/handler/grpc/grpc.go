@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
+	user "movieapp.com/user/internal/controller/user"
+	"movieapp.com/user/pkg/model"
+)
+
+// Handler defines a gRPC user API handler.
+type Handler struct {
+	gen.UnimplementedUserServiceServer
+	ctrl *user.Controller
+}
+
+// New creates a new user gRPC handler.
+func New(ctrl *user.Controller) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// RegisterUser creates the account of the caller. The user id is
+// the subject of the bearer token of the caller if verified.
+func (h *Handler) RegisterUser(ctx context.Context, req *gen.RegisterUserRequest) (*gen.RegisterUserResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	userID := req.UserId
+	if p := auth.FromContext(ctx); p != nil && userID == "" {
+		userID = p.Subject
+	}
+	if err := auth.RequireUser(ctx, userID); err != nil {
+		return nil, auth.Status(err)
+	}
+	u, err := h.ctrl.Register(ctx, &model.User{ID: userID, Email: req.Email, DisplayName: req.DisplayName, AvatarURL: req.AvatarUrl})
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.RegisterUserResponse{User: model.UserToProto(u)}, nil
+}
+
+// GetUser returns the account of a user, only to the user.
+func (h *Handler) GetUser(ctx context.Context, req *gen.GetUserRequest) (*gen.GetUserResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	u, err := h.ctrl.Get(ctx, req.UserId)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.GetUserResponse{User: model.UserToProto(u)}, nil
+}
+
+// UpdateProfile changes the profile of a user, only by the user.
+func (h *Handler) UpdateProfile(ctx context.Context, req *gen.UpdateProfileRequest) (*gen.UpdateProfileResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	u, err := h.ctrl.UpdateProfile(ctx, req.UserId, user.ProfileUpdate{DisplayName: req.DisplayName, AvatarURL: req.AvatarUrl})
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.UpdateProfileResponse{User: model.UserToProto(u)}, nil
+}
+
+// GetProfiles returns the public profiles of several users.
+func (h *Handler) GetProfiles(ctx context.Context, req *gen.GetProfilesRequest) (*gen.GetProfilesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	res, err := h.ctrl.Profiles(ctx, req.UserIds)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.GetProfilesResponse{Profiles: model.ProfilesToProto(res)}, nil
+}
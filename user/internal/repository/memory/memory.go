@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"movieapp.com/pkg/tenant"
+	"movieapp.com/user/internal/repository"
+	"movieapp.com/user/pkg/model"
+)
+
+// Repository defines a memory user repository, keeping the users
+// of each tenant apart.
+type Repository struct {
+	sync.RWMutex
+	// data holds the users by tenant and id.
+	data map[string]map[string]model.User
+}
+
+// New creates a new memory repository.
+func New() *Repository {
+	return &Repository{data: map[string]map[string]model.User{}}
+}
+
+// Create adds a user unless one with the same id or email exists.
+func (r *Repository) Create(ctx context.Context, user *model.User) error {
+	r.Lock()
+	defer r.Unlock()
+	id := tenant.FromContext(ctx)
+	users, ok := r.data[id]
+	if !ok {
+		users = map[string]model.User{}
+		r.data[id] = users
+	}
+	for _, u := range users {
+		if u.ID == user.ID || strings.EqualFold(u.Email, user.Email) {
+			return repository.ErrAlreadyExists
+		}
+	}
+	users[user.ID] = *user
+	return nil
+}
+
+// Get retrieves a user by id.
+func (r *Repository) Get(ctx context.Context, id string) (*model.User, error) {
+	r.RLock()
+	defer r.RUnlock()
+	u, ok := r.data[tenant.FromContext(ctx)][id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &u, nil
+}
+
+// GetMany retrieves several users by id. Unknown ids are left out.
+func (r *Repository) GetMany(ctx context.Context, ids []string) ([]*model.User, error) {
+	r.RLock()
+	defer r.RUnlock()
+	users := r.data[tenant.FromContext(ctx)]
+	var res []*model.User
+	for _, id := range ids {
+		if u, ok := users[id]; ok {
+			res = append(res, &u)
+		}
+	}
+	return res, nil
+}
+
+// Update replaces an existing user.
+func (r *Repository) Update(ctx context.Context, user *model.User) error {
+	r.Lock()
+	defer r.Unlock()
+	users := r.data[tenant.FromContext(ctx)]
+	if _, ok := users[user.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	users[user.ID] = *user
+	return nil
+}
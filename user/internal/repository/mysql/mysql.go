@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"movieapp.com/pkg/tenant"
+	"movieapp.com/user/internal/repository"
+	"movieapp.com/user/pkg/model"
+)
+
+// Repository defines a MySQL-based user repository.
+type Repository struct {
+	db *tenant.DB
+}
+
+// New creates a new MySQL-based repository of the database with
+// the data source name, which must set parseTime=true, per tenant
+// of the set as with tenant.OpenDB.
+func New(dsn string, tenants *tenant.Set) (*Repository, error) {
+	db, err := tenant.OpenDB("mysql", dsn, tenants)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{db}, nil
+}
+
+// PingContext checks that the databases are reachable.
+func (r *Repository) PingContext(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the connection pools of the repository.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// errDupEntry is the MySQL error number of unique key violations.
+const errDupEntry = 1062
+
+// userColumns lists the users table columns read by scanUser.
+const userColumns = "id, email, display_name, avatar_url, created_at, updated_at"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(s scanner) (*model.User, error) {
+	u := &model.User{}
+	if err := s.Scan(&u.ID, &u.Email, &u.DisplayName, &u.AvatarURL, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Create adds a user unless one with the same id or email exists.
+func (r *Repository) Create(ctx context.Context, user *model.User) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO users ("+userColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		user.ID, user.Email, user.DisplayName, user.AvatarURL, user.CreatedAt, user.UpdatedAt)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+		return repository.ErrAlreadyExists
+	}
+	return err
+}
+
+// Get retrieves a user by id.
+func (r *Repository) Get(ctx context.Context, id string) (*model.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	return u, err
+}
+
+// GetMany retrieves several users by id. Unknown ids are left out.
+func (r *Repository) GetMany(ctx context.Context, ids []string) ([]*model.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*model.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, rows.Err()
+}
+
+// Update replaces the profile of an existing user.
+func (r *Repository) Update(ctx context.Context, user *model.User) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET display_name = ?, avatar_url = ?, updated_at = ? WHERE id = ?",
+		user.DisplayName, user.AvatarURL, user.UpdatedAt, user.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
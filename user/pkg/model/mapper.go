@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"movieapp.com/gen"
+)
+
+// UserToProto converts a User struct into a generated proto
+// counterpart.
+func UserToProto(u *User) *gen.User {
+	return &gen.User{
+		Id:          u.ID,
+		Email:       u.Email,
+		DisplayName: u.DisplayName,
+		AvatarUrl:   u.AvatarURL,
+		CreatedAt:   timeToProto(u.CreatedAt),
+		UpdatedAt:   timeToProto(u.UpdatedAt),
+	}
+}
+
+// UserFromProto converts a generated proto counterpart into a
+// User struct.
+func UserFromProto(u *gen.User) *User {
+	return &User{
+		ID:          u.Id,
+		Email:       u.Email,
+		DisplayName: u.DisplayName,
+		AvatarURL:   u.AvatarUrl,
+		CreatedAt:   timeFromProto(u.CreatedAt),
+		UpdatedAt:   timeFromProto(u.UpdatedAt),
+	}
+}
+
+// ProfilesToProto converts profiles keyed by user id into their
+// generated proto counterparts.
+func ProfilesToProto(profiles map[string]Profile) map[string]*gen.Profile {
+	res := make(map[string]*gen.Profile, len(profiles))
+	for id, p := range profiles {
+		res[id] = &gen.Profile{UserId: p.UserID, DisplayName: p.DisplayName, AvatarUrl: p.AvatarURL}
+	}
+	return res
+}
+
+// ProfilesFromProto converts generated proto counterparts into
+// profiles keyed by user id.
+func ProfilesFromProto(profiles map[string]*gen.Profile) map[string]Profile {
+	res := make(map[string]Profile, len(profiles))
+	for id, p := range profiles {
+		res[id] = Profile{UserID: p.UserId, DisplayName: p.DisplayName, AvatarURL: p.AvatarUrl}
+	}
+	return res
+}
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// User defines the account of a user. The id is the subject of the
+// tokens of the user, and the user id of their ratings.
+type User struct {
+	ID          string    `json:"id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"displayName"`
+	AvatarURL   string    `json:"avatarUrl,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Profile defines the public profile of a user, such as shown as
+// the author of a rating.
+type Profile struct {
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
+}
+
+// Profile returns the public profile of the user.
+func (u *User) Profile() Profile {
+	return Profile{UserID: u.ID, DisplayName: u.DisplayName, AvatarURL: u.AvatarURL}
+}
@@ -0,0 +1,137 @@
+package watchlist
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"movieapp.com/pkg/logging"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/watchlist/internal/repository"
+	"movieapp.com/watchlist/pkg/model"
+)
+
+var logger = logging.New("controller")
+
+// ErrInvalidMovieID is returned when a movie id is empty or too
+// long.
+var ErrInvalidMovieID = problem.Register(errors.New("invalid movie id"), problem.Invalid)
+
+// ErrTooManyIDs is returned when more than MaxBatchSize movies are
+// looked up at once.
+var ErrTooManyIDs = problem.Register(errors.New("too many ids"), problem.BadRequest)
+
+// MaxBatchSize defines the maximum number of movies looked up at
+// once, and the maximum page size of List.
+const MaxBatchSize = 100
+
+// DefaultPageSize defines the number of items listed per page
+// unless requested otherwise.
+const DefaultPageSize = 20
+
+const maxIDLength = 255
+
+type watchlistRepository interface {
+	Add(ctx context.Context, item *model.Item) error
+	Get(ctx context.Context, userID string, movieID string) (*model.Item, error)
+	Remove(ctx context.Context, userID string, movieID string) error
+	List(ctx context.Context, userID string, after *model.Position, limit int) ([]*model.Item, error)
+	GetMany(ctx context.Context, userID string, movieIDs []string) ([]*model.Item, error)
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, events []*model.Event) error
+}
+
+// Controller defines a watchlist service controller.
+type Controller struct {
+	repo      watchlistRepository
+	publisher eventPublisher
+}
+
+// New creates a watchlist service controller publishing watchlist
+// change events to the publisher.
+func New(repo watchlistRepository, publisher eventPublisher) *Controller {
+	return &Controller{repo, publisher}
+}
+
+// Add adds a movie to the watchlist of a user. Adding a movie
+// already in the watchlist returns its item as it is.
+func (c *Controller) Add(ctx context.Context, userID string, movieID string) (*model.Item, error) {
+	if movieID == "" || len(movieID) > maxIDLength {
+		return nil, ErrInvalidMovieID
+	}
+	item := &model.Item{UserID: userID, MovieID: movieID, AddedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	if err := c.repo.Add(ctx, item); errors.Is(err, repository.ErrAlreadyExists) {
+		return c.repo.Get(ctx, userID, movieID)
+	} else if err != nil {
+		return nil, err
+	}
+	c.publish(ctx, &model.Event{Type: model.EventTypeAdded, UserID: userID, MovieID: movieID, Timestamp: item.AddedAt})
+	return item, nil
+}
+
+// Remove removes a movie from the watchlist of a user. Removing a
+// movie not in the watchlist does nothing.
+func (c *Controller) Remove(ctx context.Context, userID string, movieID string) error {
+	if err := c.repo.Remove(ctx, userID, movieID); errors.Is(err, repository.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	c.publish(ctx, &model.Event{Type: model.EventTypeRemoved, UserID: userID, MovieID: movieID, Timestamp: time.Now().UTC()})
+	return nil
+}
+
+// List returns a page of the watchlist of a user, the most
+// recently added items first, and the cursor of the next page,
+// empty on the last page. The page size is capped and defaults
+// when not positive.
+func (c *Controller) List(ctx context.Context, userID string, pageSize int, cursor string) ([]*model.Item, string, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	} else if pageSize > MaxBatchSize {
+		pageSize = MaxBatchSize
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	// One more item tells whether there is a next page.
+	items, err := c.repo.List(ctx, userID, after, pageSize+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(items) <= pageSize {
+		return items, "", nil
+	}
+	items = items[:pageSize]
+	return items, encodeCursor(items[pageSize-1].Position()), nil
+}
+
+// Watchlisted returns the times several movies were added to the
+// watchlist of a user by movie id. Movies not in the watchlist are
+// left out.
+func (c *Controller) Watchlisted(ctx context.Context, userID string, movieIDs []string) (map[string]time.Time, error) {
+	if len(movieIDs) > MaxBatchSize {
+		return nil, ErrTooManyIDs
+	}
+	items, err := c.repo.GetMany(ctx, userID, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]time.Time, len(items))
+	for _, item := range items {
+		res[item.MovieID] = item.AddedAt
+	}
+	return res, nil
+}
+
+// publish publishes watchlist change events once the watchlist is
+// written. A failure does not fail the write, recommendations only
+// miss a signal.
+func (c *Controller) publish(ctx context.Context, events ...*model.Event) {
+	if err := c.publisher.Publish(ctx, events); err != nil {
+		logger.ErrorContext(ctx, "Watchlist event publish error", "error", err)
+	}
+}
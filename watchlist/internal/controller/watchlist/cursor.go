@@ -0,0 +1,44 @@
+package watchlist
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"movieapp.com/pkg/problem"
+	"movieapp.com/watchlist/pkg/model"
+)
+
+// ErrInvalidCursor is returned when a cursor was not issued by
+// List.
+var ErrInvalidCursor = problem.Register(errors.New("invalid cursor"), problem.BadRequest)
+
+// cursor is the position of the last item of a page of List, so
+// that items added or removed meanwhile do not shift the next page.
+type cursor struct {
+	AddedAt time.Time `json:"a"`
+	MovieID string    `json:"m"`
+}
+
+func encodeCursor(p model.Position) string {
+	b, _ := json.Marshal(cursor{p.AddedAt, p.MovieID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the position of a cursor, nil for the first
+// page.
+func decodeCursor(s string) (*model.Position, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.MovieID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &model.Position{AddedAt: c.AddedAt, MovieID: c.MovieID}, nil
+}
@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+	"movieapp.com/internal/kafkautil"
+	"movieapp.com/internal/tracing"
+	"movieapp.com/watchlist/pkg/model"
+)
+
+// Publisher defines a Kafka watchlist change event publisher.
+type Publisher struct {
+	writer *kafka.Writer
+}
+
+// NewPublisher creates a Kafka publisher writing events to the
+// topic. Events are keyed by user id, so the events of a user land
+// on the same partition and are consumed in order. Connections
+// authenticate with the credentials unless nil.
+func NewPublisher(brokers []string, topic string, creds *kafkautil.Credentials) *Publisher {
+	return &Publisher{&kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Transport:    creds.Transport(),
+	}}
+}
+
+// Publish writes the events to Kafka.
+func (p *Publisher) Publish(ctx context.Context, events []*model.Event) error {
+	msgs := make([]kafka.Message, 0, len(events))
+	for _, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:     []byte(e.UserID),
+			Value:   value,
+			Headers: tracing.InjectKafka(ctx, []kafka.Header{{Key: "type", Value: []byte(e.Type)}}),
+		})
+	}
+	return p.writer.WriteMessages(ctx, msgs...)
+}
+
+// Close flushes pending writes and closes the publisher.
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
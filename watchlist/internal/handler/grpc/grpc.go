@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"movieapp.com/gen"
+	"movieapp.com/pkg/auth"
+	"movieapp.com/pkg/problem"
+	"movieapp.com/watchlist/internal/controller/watchlist"
+	"movieapp.com/watchlist/pkg/model"
+)
+
+// Handler defines a gRPC watchlist API handler.
+type Handler struct {
+	gen.UnimplementedWatchlistServiceServer
+	ctrl *watchlist.Controller
+}
+
+// New creates a new watchlist gRPC handler.
+func New(ctrl *watchlist.Controller) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// AddToWatchlist adds a movie to the watchlist of a user, only by
+// the user.
+func (h *Handler) AddToWatchlist(ctx context.Context, req *gen.AddToWatchlistRequest) (*gen.AddToWatchlistResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	item, err := h.ctrl.Add(ctx, req.UserId, req.MovieId)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.AddToWatchlistResponse{Item: model.ItemToProto(item)}, nil
+}
+
+// RemoveFromWatchlist removes a movie from the watchlist of a
+// user, only by the user.
+func (h *Handler) RemoveFromWatchlist(ctx context.Context, req *gen.RemoveFromWatchlistRequest) (*gen.RemoveFromWatchlistResponse, error) {
+	if req == nil || req.UserId == "" || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or movie id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	if err := h.ctrl.Remove(ctx, req.UserId, req.MovieId); err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.RemoveFromWatchlistResponse{}, nil
+}
+
+// ListWatchlist returns a page of the watchlist of a user, only to
+// the user.
+func (h *Handler) ListWatchlist(ctx context.Context, req *gen.ListWatchlistRequest) (*gen.ListWatchlistResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	items, next, err := h.ctrl.List(ctx, req.UserId, int(req.PageSize), req.Cursor)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	res := &gen.ListWatchlistResponse{NextCursor: next}
+	for _, item := range items {
+		res.Items = append(res.Items, model.ItemToProto(item))
+	}
+	return res, nil
+}
+
+// GetWatchlisted returns which of several movies are in the
+// watchlist of a user, only to the user.
+func (h *Handler) GetWatchlisted(ctx context.Context, req *gen.GetWatchlistedRequest) (*gen.GetWatchlistedResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	if err := auth.RequireUser(ctx, req.UserId); err != nil {
+		return nil, auth.Status(err)
+	}
+	res, err := h.ctrl.Watchlisted(ctx, req.UserId, req.MovieIds)
+	if err != nil {
+		return nil, problem.Status(err)
+	}
+	return &gen.GetWatchlistedResponse{AddedAt: model.AddedAtToProto(res)}, nil
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "errors"
+
+// ErrNotFound is returned when a requested record is not found.
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is returned when a movie already is in the
+// watchlist.
+var ErrAlreadyExists = errors.New("already exists")
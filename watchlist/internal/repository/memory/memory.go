@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"movieapp.com/pkg/tenant"
+	"movieapp.com/watchlist/internal/repository"
+	"movieapp.com/watchlist/pkg/model"
+)
+
+// Repository defines a memory watchlist repository.
+type Repository struct {
+	sync.RWMutex
+	// data holds the items by user and movie, with users keyed by
+	// tenant.Key so that the watchlists of each tenant are kept
+	// apart.
+	data map[string]map[string]model.Item
+}
+
+// New creates a new memory repository.
+func New() *Repository {
+	return &Repository{data: map[string]map[string]model.Item{}}
+}
+
+// Add adds an item to the watchlist of its user unless the movie
+// already is in it.
+func (r *Repository) Add(ctx context.Context, item *model.Item) error {
+	r.Lock()
+	defer r.Unlock()
+	key := tenant.Key(ctx, item.UserID)
+	items, ok := r.data[key]
+	if !ok {
+		items = map[string]model.Item{}
+		r.data[key] = items
+	}
+	if _, ok := items[item.MovieID]; ok {
+		return repository.ErrAlreadyExists
+	}
+	items[item.MovieID] = *item
+	return nil
+}
+
+// Get retrieves the watchlist item of a movie.
+func (r *Repository) Get(ctx context.Context, userID string, movieID string) (*model.Item, error) {
+	r.RLock()
+	defer r.RUnlock()
+	item, ok := r.data[tenant.Key(ctx, userID)][movieID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &item, nil
+}
+
+// Remove removes a movie from the watchlist of a user.
+func (r *Repository) Remove(ctx context.Context, userID string, movieID string) error {
+	r.Lock()
+	defer r.Unlock()
+	items := r.data[tenant.Key(ctx, userID)]
+	if _, ok := items[movieID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(items, movieID)
+	return nil
+}
+
+// List retrieves up to limit items of the watchlist of a user
+// listed after the position, from the start if nil.
+func (r *Repository) List(ctx context.Context, userID string, after *model.Position, limit int) ([]*model.Item, error) {
+	r.RLock()
+	defer r.RUnlock()
+	var res []*model.Item
+	for _, item := range r.data[tenant.Key(ctx, userID)] {
+		if after == nil || after.Before(item.Position()) {
+			item := item
+			res = append(res, &item)
+		}
+	}
+	slices.SortFunc(res, func(a, b *model.Item) int {
+		if a.Position().Before(b.Position()) {
+			return -1
+		}
+		return 1
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// GetMany retrieves the items of several movies of the watchlist
+// of a user. Movies not in the watchlist are left out.
+func (r *Repository) GetMany(ctx context.Context, userID string, movieIDs []string) ([]*model.Item, error) {
+	r.RLock()
+	defer r.RUnlock()
+	var res []*model.Item
+	for _, id := range movieIDs {
+		if item, ok := r.data[tenant.Key(ctx, userID)][id]; ok {
+			res = append(res, &item)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,132 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"movieapp.com/pkg/tenant"
+	"movieapp.com/watchlist/internal/repository"
+	"movieapp.com/watchlist/pkg/model"
+)
+
+// Repository defines a MySQL-based watchlist repository.
+type Repository struct {
+	db *tenant.DB
+}
+
+// New creates a new MySQL-based repository of the database with
+// the data source name, which must set parseTime=true, per tenant
+// of the set as with tenant.OpenDB.
+func New(dsn string, tenants *tenant.Set) (*Repository, error) {
+	db, err := tenant.OpenDB("mysql", dsn, tenants)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{db}, nil
+}
+
+// PingContext checks that the databases are reachable.
+func (r *Repository) PingContext(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the connection pools of the repository.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// errDupEntry is the MySQL error number of unique key violations.
+const errDupEntry = 1062
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanItem(s scanner) (*model.Item, error) {
+	item := &model.Item{}
+	if err := s.Scan(&item.UserID, &item.MovieID, &item.AddedAt); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Add adds an item to the watchlist of its user unless the movie
+// already is in it.
+func (r *Repository) Add(ctx context.Context, item *model.Item) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO watchlist (user_id, movie_id, added_at) VALUES (?, ?, ?)",
+		item.UserID, item.MovieID, item.AddedAt)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+		return repository.ErrAlreadyExists
+	}
+	return err
+}
+
+// Get retrieves the watchlist item of a movie.
+func (r *Repository) Get(ctx context.Context, userID string, movieID string) (*model.Item, error) {
+	item, err := scanItem(r.db.QueryRowContext(ctx, "SELECT user_id, movie_id, added_at FROM watchlist WHERE user_id = ? AND movie_id = ?", userID, movieID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	return item, err
+}
+
+// Remove removes a movie from the watchlist of a user.
+func (r *Repository) Remove(ctx context.Context, userID string, movieID string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM watchlist WHERE user_id = ? AND movie_id = ?", userID, movieID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// List retrieves up to limit items of the watchlist of a user
+// listed after the position, from the start if nil.
+func (r *Repository) List(ctx context.Context, userID string, after *model.Position, limit int) ([]*model.Item, error) {
+	query := "SELECT user_id, movie_id, added_at FROM watchlist WHERE user_id = ?"
+	args := []any{userID}
+	if after != nil {
+		query += " AND (added_at < ? OR (added_at = ? AND movie_id < ?))"
+		args = append(args, after.AddedAt, after.AddedAt, after.MovieID)
+	}
+	query += " ORDER BY added_at DESC, movie_id DESC LIMIT ?"
+	return r.query(ctx, query, append(args, limit)...)
+}
+
+// GetMany retrieves the items of several movies of the watchlist
+// of a user. Movies not in the watchlist are left out.
+func (r *Repository) GetMany(ctx context.Context, userID string, movieIDs []string) ([]*model.Item, error) {
+	if len(movieIDs) == 0 {
+		return nil, nil
+	}
+	args := []any{userID}
+	for _, id := range movieIDs {
+		args = append(args, id)
+	}
+	return r.query(ctx, "SELECT user_id, movie_id, added_at FROM watchlist WHERE user_id = ? AND movie_id IN (?"+strings.Repeat(", ?", len(movieIDs)-1)+")", args...)
+}
+
+func (r *Repository) query(ctx context.Context, query string, args ...any) ([]*model.Item, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*model.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, item)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// EventType defines the type of a watchlist change event.
+type EventType string
+
+// Existing watchlist event types.
+const (
+	EventTypeAdded   = EventType("WatchlistAdded")
+	EventTypeRemoved = EventType("WatchlistRemoved")
+)
+
+// Event defines a watchlist change event published after each
+// change, e.g. for recommendations to learn the interests of the
+// user.
+type Event struct {
+	Type      EventType `json:"type"`
+	UserID    string    `json:"userId"`
+	MovieID   string    `json:"movieId"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"movieapp.com/gen"
+)
+
+// ItemToProto converts an Item struct into a generated proto
+// counterpart.
+func ItemToProto(i *Item) *gen.WatchlistItem {
+	return &gen.WatchlistItem{UserId: i.UserID, MovieId: i.MovieID, AddedAt: timestamppb.New(i.AddedAt)}
+}
+
+// ItemFromProto converts a generated proto counterpart into an
+// Item struct.
+func ItemFromProto(i *gen.WatchlistItem) *Item {
+	return &Item{UserID: i.UserId, MovieID: i.MovieId, AddedAt: i.AddedAt.AsTime()}
+}
+
+// AddedAtToProto converts the times movies were added by movie id
+// into their generated proto counterparts.
+func AddedAtToProto(addedAt map[string]time.Time) map[string]*timestamppb.Timestamp {
+	res := make(map[string]*timestamppb.Timestamp, len(addedAt))
+	for id, t := range addedAt {
+		res[id] = timestamppb.New(t)
+	}
+	return res
+}
+
+// AddedAtFromProto converts generated proto counterparts into the
+// times movies were added by movie id.
+func AddedAtFromProto(addedAt map[string]*timestamppb.Timestamp) map[string]time.Time {
+	res := make(map[string]time.Time, len(addedAt))
+	for id, t := range addedAt {
+		res[id] = t.AsTime()
+	}
+	return res
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Item defines a movie a user saved to watch later.
+type Item struct {
+	UserID  string    `json:"userId"`
+	MovieID string    `json:"movieId"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// Position defines the position of an item in a watchlist, which
+// lists the most recently added items first.
+type Position struct {
+	AddedAt time.Time
+	MovieID string
+}
+
+// Position returns the position of the item in its watchlist.
+func (i *Item) Position() Position {
+	return Position{i.AddedAt, i.MovieID}
+}
+
+// Before reports whether an item at position p is listed before
+// one at position o.
+func (p Position) Before(o Position) bool {
+	if !p.AddedAt.Equal(o.AddedAt) {
+		return p.AddedAt.After(o.AddedAt)
+	}
+	return p.MovieID > o.MovieID
+}